@@ -1,14 +1,16 @@
 package apollo
 
 import (
+	"context"
 	"crypto/ed25519"
 	"encoding/hex"
 	"errors"
 	"fmt"
-	"strconv"
+	"sort"
 
 	"github.com/Salvionied/apollo/apollotypes"
 	"github.com/Salvionied/apollo/constants"
+	"github.com/Salvionied/apollo/observability"
 	"github.com/Salvionied/apollo/serialization"
 	"github.com/Salvionied/apollo/serialization/Address"
 	"github.com/Salvionied/apollo/serialization/Amount"
@@ -30,50 +32,65 @@ import (
 	"github.com/Salvionied/apollo/serialization/VerificationKeyWitness"
 	"github.com/Salvionied/apollo/serialization/Withdrawal"
 	"github.com/Salvionied/apollo/txBuilding/Backend/Base"
-	"github.com/Salvionied/apollo/txBuilding/Backend/BlockFrostChainContext"
+	"github.com/Salvionied/apollo/txBuilding/Errors"
 	"github.com/Salvionied/apollo/txBuilding/Utils"
 	"github.com/Salvionied/cbor/v2"
 	"golang.org/x/exp/slices"
+	"golang.org/x/exp/slog"
 )
 
 const (
 	EX_MEMORY_BUFFER = 0.2
 	EX_STEP_BUFFER   = 0.2
+	// DEFAULT_MAX_COLLATERAL_INPUTS is used when the protocol
+	// parameters do not set maxCollateralInputs.
+	DEFAULT_MAX_COLLATERAL_INPUTS = 3
 )
 
 type Apollo struct {
-	Context            Base.ChainContext
-	payments           []PaymentI
-	isEstimateRequired bool
-	auxiliaryData      *Metadata.AuxiliaryData
-	utxos              []UTxO.UTxO
-	preselectedUtxos   []UTxO.UTxO
-	inputAddresses     []Address.Address
-	tx                 *Transaction.Transaction
-	datums             []PlutusData.PlutusData
-	requiredSigners    []serialization.PubKeyHash
-	v1scripts          []PlutusData.PlutusV1Script
-	v2scripts          []PlutusData.PlutusV2Script
-	redeemers          []Redeemer.Redeemer
-	redeemersToUTxO    map[string]Redeemer.Redeemer
-	stakeRedeemers     map[string]Redeemer.Redeemer
-	mintRedeemers      map[string]Redeemer.Redeemer
-	mint               []Unit
-	collaterals        []UTxO.UTxO
-	Fee                int64
-	FeePadding         int64
-	Ttl                int64
-	ValidityStart      int64
-	totalCollateral    int
-	referenceInputs    []TransactionInput.TransactionInput
-	collateralReturn   *TransactionOutput.TransactionOutput
-	withdrawals        *Withdrawal.Withdrawal
-	certificates       *Certificate.Certificates
-	nativescripts      []NativeScript.NativeScript
-	usedUtxos          []string
-	referenceScripts   []PlutusData.ScriptHashable
-	wallet             apollotypes.Wallet
-	scriptHashes       []string
+	Context                 Base.ChainContext
+	payments                []PaymentI
+	isEstimateRequired      bool
+	auxiliaryData           *Metadata.AuxiliaryData
+	utxos                   []UTxO.UTxO
+	preselectedUtxos        []UTxO.UTxO
+	inputAddresses          []Address.Address
+	tx                      *Transaction.Transaction
+	datums                  []PlutusData.PlutusData
+	requiredSigners         []serialization.PubKeyHash
+	v1scripts               []PlutusData.PlutusV1Script
+	v2scripts               []PlutusData.PlutusV2Script
+	redeemers               []Redeemer.Redeemer
+	redeemersToUTxO         map[string]Redeemer.Redeemer
+	stakeRedeemers          map[string]Redeemer.Redeemer
+	mintRedeemers           map[string]Redeemer.Redeemer
+	mint                    []Unit
+	collaterals             []UTxO.UTxO
+	Fee                     int64
+	FeePadding              int64
+	Ttl                     int64
+	ValidityStart           int64
+	totalCollateral         int
+	referenceInputs         []TransactionInput.TransactionInput
+	referenceUtxos          map[string]*UTxO.UTxO
+	collateralReturn        *TransactionOutput.TransactionOutput
+	collateralReturnAddress *Address.Address
+	autoCollateral          bool
+	change                  *Payment
+	// pendingErr is an error of a chained method, returned by Complete.
+	pendingErr       error
+	withdrawals      *Withdrawal.Withdrawal
+	certificates     *Certificate.Certificates
+	nativescripts    []NativeScript.NativeScript
+	usedUtxos        []string
+	referenceScripts []PlutusData.ScriptHashable
+	wallet           apollotypes.Wallet
+	scriptHashes     []string
+	instrumentation  observability.Instrumentation
+	logger           *slog.Logger
+	ctx              context.Context
+	notes            *buildNotes
+	report           *BuildReport
 }
 
 /*
@@ -111,6 +128,7 @@ func New(cc Base.ChainContext) *Apollo {
 		FeePadding:         0,
 		usedUtxos:          make([]string, 0),
 		referenceInputs:    make([]TransactionInput.TransactionInput, 0),
+		referenceUtxos:     make(map[string]*UTxO.UTxO),
 		referenceScripts:   make([]PlutusData.ScriptHashable, 0),
 		mintRedeemers:      make(map[string]Redeemer.Redeemer)}
 }
@@ -456,10 +474,11 @@ func (b *Apollo) buildOutputs() []TransactionOutput.TransactionOutput {
 func (b *Apollo) buildWitnessSet() TransactionWitnessSet.TransactionWitnessSet {
 	plutusdata := make([]PlutusData.PlutusData, 0)
 	plutusdata = append(plutusdata, b.datums...)
+	nativescripts, v1scripts, v2scripts := b.witnessScripts()
 	return TransactionWitnessSet.TransactionWitnessSet{
-		NativeScripts:  b.nativescripts,
-		PlutusV1Script: b.v1scripts,
-		PlutusV2Script: b.v2scripts,
+		NativeScripts:  nativescripts,
+		PlutusV1Script: v1scripts,
+		PlutusV2Script: v2scripts,
 		PlutusData:     PlutusData.PlutusIndefArray(plutusdata),
 		Redeemer:       b.redeemers,
 	}
@@ -485,10 +504,11 @@ func (b *Apollo) buildFakeWitnessSet() TransactionWitnessSet.TransactionWitnessS
 			Vkey:      constants.FAKE_VKEY,
 			Signature: constants.FAKE_SIGNATURE})
 	}
+	nativescripts, v1scripts, v2scripts := b.witnessScripts()
 	return TransactionWitnessSet.TransactionWitnessSet{
-		NativeScripts:  b.nativescripts,
-		PlutusV1Script: b.v1scripts,
-		PlutusV2Script: b.v2scripts,
+		NativeScripts:  nativescripts,
+		PlutusV1Script: v1scripts,
+		PlutusV2Script: v2scripts,
 		PlutusData:     PlutusData.PlutusIndefArray(plutusdata),
 		Redeemer:       b.redeemers,
 		VkeyWitnesses:  fakeVkWitnesses,
@@ -510,8 +530,9 @@ func (b *Apollo) scriptDataHash() (*serialization.ScriptDataHash, error) {
 	witnessSet := b.buildWitnessSet()
 	cost_models := map[int]cbor.Marshaler{}
 	redeemers := witnessSet.Redeemer
-	PV1Scripts := witnessSet.PlutusV1Script
-	PV2Scripts := witnessSet.PlutusV2Script
+	// the languages of the scripts provided by reference count too
+	PV1Scripts := b.v1scripts
+	PV2Scripts := b.v2scripts
 	datums := witnessSet.PlutusData
 
 	isV1 := len(PV1Scripts) > 0
@@ -683,9 +704,23 @@ func (b *Apollo) buildFullFakeTx() (*Transaction.Transaction, error) {
 		TransactionWitnessSet: witness,
 		Valid:                 true,
 		AuxiliaryData:         b.auxiliaryData}
+	if account, ok := b.wallet.(*apollotypes.AccountWallet); ok {
+		// an account signs once per address owning an input
+		for i := 1; i < len(account.RequiredSigningPaths(tx)); i++ {
+			tx.TransactionWitnessSet.VkeyWitnesses = append(tx.TransactionWitnessSet.VkeyWitnesses, VerificationKeyWitness.VerificationKeyWitness{
+				Vkey:      constants.FAKE_VKEY,
+				Signature: constants.FAKE_SIGNATURE})
+		}
+	}
 	bytes, _ := tx.Bytes()
-	if len(bytes) > b.Context.GetProtocolParams().MaxTxSize {
-		return nil, errors.New("transaction too large")
+	if maxTxSize := b.Context.GetProtocolParams().MaxTxSize; len(bytes) > maxTxSize {
+		return nil, &Errors.BuildError{
+			Kind:         Errors.TX_SIZE,
+			Msg:          fmt.Sprintf("transaction too large, %d bytes over the %d allowed", len(bytes), maxTxSize),
+			Considered:   b.preselectedUtxos,
+			EstimatedFee: txBody.Fee,
+			Output:       -1,
+		}
 	}
 	return &tx, nil
 }
@@ -709,11 +744,143 @@ func (b *Apollo) estimateFee() int64 {
 	}
 	fakeTxBytes, _ := fftx.Bytes()
 	estimatedFee := Utils.Fee(b.Context, len(fakeTxBytes), pExU.Steps, pExU.Mem)
+	if refScriptSize := b.referenceScriptsSize(); refScriptSize > 0 {
+		estimatedFee += b.Context.GetProtocolParams().RefScriptFee(refScriptSize)
+	}
 	estimatedFee += b.FeePadding
 	return estimatedFee
 
 }
 
+/*
+*
+
+	referenceScriptsSize computes the total size of the reference
+	scripts carried by the spent and reference inputs, which the
+	Conway ledger charges for even if the scripts are not run.
+	Reference inputs are resolved through the chain context unless
+	they were added with AddLoadedReferenceInput.
+
+	Returns:
+		int: The total size in bytes of the reference scripts.
+*/
+func (b *Apollo) referenceScriptsSize() int {
+	size := 0
+	for _, utxo := range b.preselectedUtxos {
+		size += utxo.Output.GetScriptRef().Size()
+	}
+	for _, input := range b.referenceInputs {
+		utxo := b.resolveReferenceInput(input)
+		if utxo != nil {
+			size += utxo.Output.GetScriptRef().Size()
+		}
+	}
+	return size
+}
+
+/*
+*
+
+	referencedScriptHashes returns the hashes of the reference
+	scripts carried by the spent and reference inputs, which the
+	ledger accepts in place of the witness scripts.
+
+	Returns:
+		map[string]bool: The hex encoded hashes of the scripts.
+*/
+func (b *Apollo) referencedScriptHashes() map[string]bool {
+	hashes := make(map[string]bool)
+	add := func(utxo *UTxO.UTxO) {
+		scriptRef := utxo.Output.GetScriptRef()
+		if scriptRef.Size() == 0 {
+			return
+		}
+		hash, err := scriptRef.Hash()
+		if err == nil {
+			hashes[hex.EncodeToString(hash[:])] = true
+		}
+	}
+	for i := range b.preselectedUtxos {
+		add(&b.preselectedUtxos[i])
+	}
+	for _, input := range b.referenceInputs {
+		utxo := b.resolveReferenceInput(input)
+		if utxo != nil {
+			add(utxo)
+		}
+	}
+	return hashes
+}
+
+/*
+*
+
+	witnessScripts returns the attached native and Plutus scripts
+	that have to be in the witness set, leaving out the ones
+	already provided by a spent or reference input.
+
+	Returns:
+		[]NativeScript.NativeScript: The native scripts.
+		[]PlutusData.PlutusV1Script: The PlutusV1 scripts.
+		[]PlutusData.PlutusV2Script: The PlutusV2 scripts.
+*/
+func (b *Apollo) witnessScripts() ([]NativeScript.NativeScript, []PlutusData.PlutusV1Script, []PlutusData.PlutusV2Script) {
+	if len(b.nativescripts) == 0 && len(b.v1scripts) == 0 && len(b.v2scripts) == 0 {
+		return b.nativescripts, b.v1scripts, b.v2scripts
+	}
+	referenced := b.referencedScriptHashes()
+	if len(referenced) == 0 {
+		return b.nativescripts, b.v1scripts, b.v2scripts
+	}
+	nativescripts := make([]NativeScript.NativeScript, 0, len(b.nativescripts))
+	for _, script := range b.nativescripts {
+		hash, err := script.Hash()
+		if err != nil || !referenced[hex.EncodeToString(hash.Bytes())] {
+			nativescripts = append(nativescripts, script)
+		}
+	}
+	v1scripts := make([]PlutusData.PlutusV1Script, 0, len(b.v1scripts))
+	for _, script := range b.v1scripts {
+		hash := PlutusData.PlutusScriptHash(script)
+		if !referenced[hex.EncodeToString(hash.Bytes())] {
+			v1scripts = append(v1scripts, script)
+		}
+	}
+	v2scripts := make([]PlutusData.PlutusV2Script, 0, len(b.v2scripts))
+	for _, script := range b.v2scripts {
+		hash := PlutusData.PlutusScriptHash(script)
+		if !referenced[hex.EncodeToString(hash.Bytes())] {
+			v2scripts = append(v2scripts, script)
+		}
+	}
+	return nativescripts, v1scripts, v2scripts
+}
+
+/*
+*
+
+	resolveReferenceInput returns the UTxO of a reference input,
+	querying the chain context only the first time it is needed.
+
+	Params:
+		input (TransactionInput.TransactionInput): The reference input.
+
+	Returns:
+		*UTxO.UTxO: The resolved UTxO or nil if it cannot be found.
+*/
+func (b *Apollo) resolveReferenceInput(input TransactionInput.TransactionInput) *UTxO.UTxO {
+	if b.referenceUtxos == nil {
+		b.referenceUtxos = make(map[string]*UTxO.UTxO)
+	}
+	key := UTxO.UTxO{Input: input}.GetKey()
+	if utxo, ok := b.referenceUtxos[key]; ok {
+		return utxo
+	}
+	utxo := b.Context.GetUtxoFromRef(hex.EncodeToString(input.TransactionId), input.Index)
+	b.referenceUtxos[key] = utxo
+	return utxo
+}
+
 /*
 *
 
@@ -732,6 +899,76 @@ func (b *Apollo) getAvailableUtxos() []UTxO.UTxO {
 	return availableUtxos
 }
 
+/*
+*
+
+	excludedUtxos returns the loaded UTxOs which are already
+	used but are not inputs of the transaction.
+
+	Returns:
+		[]Errors.ExcludedUTxO: The UTxOs, excluded as used.
+*/
+func (b *Apollo) excludedUtxos() []Errors.ExcludedUTxO {
+	inputs := make(map[string]bool)
+	for _, utxo := range b.preselectedUtxos {
+		inputs[utxo.GetKey()] = true
+	}
+	excluded := make([]Errors.ExcludedUTxO, 0)
+	for _, utxo := range b.utxos {
+		if slices.Contains(b.usedUtxos, utxo.GetKey()) && !inputs[utxo.GetKey()] {
+			excluded = append(excluded, Errors.ExcludedUTxO{UTxO: utxo, Reason: Errors.EXCLUDED_USED})
+		}
+	}
+	return excluded
+}
+
+/*
+*
+
+	shortfall compares a requested value to a provided one.
+
+	Params:
+		requested (Value.Value): The value needed.
+		provided (Value.Value): The value available.
+
+	Returns:
+		[]Errors.Shortfall: The units of which less is provided than
+			requested, lovelace first then by policy and asset name.
+*/
+func shortfall(requested Value.Value, provided Value.Value) []Errors.Shortfall {
+	shortfalls := make([]Errors.Shortfall, 0)
+	if requested.GetCoin() > provided.GetCoin() {
+		shortfalls = append(shortfalls, Errors.Shortfall{Required: requested.GetCoin(), Available: provided.GetCoin()})
+	}
+	providedAssets := provided.GetAssets()
+	assetShortfalls := make([]Errors.Shortfall, 0)
+	for policy, assets := range requested.GetAssets() {
+		for name, amount := range assets {
+			available := providedAssets.GetByPolicyAndId(policy, name)
+			if amount > available {
+				assetShortfalls = append(assetShortfalls, Errors.Shortfall{
+					PolicyId:  policy.String(),
+					AssetName: name.HexString(),
+					Required:  amount,
+					Available: available,
+				})
+			}
+		}
+	}
+	sort.Slice(assetShortfalls, func(i, j int) bool {
+		return assetShortfalls[i].Unit() < assetShortfalls[j].Unit()
+	})
+	return append(shortfalls, assetShortfalls...)
+}
+
+func totalLovelace(utxos []UTxO.UTxO) int64 {
+	total := int64(0)
+	for _, utxo := range utxos {
+		total += utxo.Output.GetValue().GetCoin()
+	}
+	return total
+}
+
 /*
 *
 
@@ -775,74 +1012,275 @@ func (b *Apollo) AttachDatum(datum *PlutusData.PlutusData) *Apollo {
 	return b
 }
 
-/**
-setCollateral function sets collateral for the transaction.
+/*
+*
 
-Returns:
-	*Apollo: A pointer to the Apollo object to support method chaining.
-	error: An error if the setCollateral fails.
+	needsCollateral reports whether the transaction runs Plutus
+	scripts and therefore has to provide collateral. Every script
+	run has a redeemer, wherever the script comes from: attached,
+	in a reference input or in a spent input.
+
+	Returns:
+		bool: true if collateral is required.
 */
+func (b *Apollo) needsCollateral() bool {
+	return len(b.redeemersToUTxO) > 0 ||
+		len(b.mintRedeemers) > 0 ||
+		len(b.stakeRedeemers) > 0
+}
+
+/*
+*
+
+	setCollateral function sets collateral for the transaction.
+	As the fee is not known yet, the collateral is selected for
+	the maximum transaction fee and trimmed by finalizeCollateral.
 
+	Returns:
+		*Apollo: A pointer to the Apollo object to support method chaining.
+		error: An error if no collateral can be found.
+*/
 func (b *Apollo) setCollateral() (*Apollo, error) {
-	if len(b.collaterals) > 0 {
-		collateral_amount := 5_000_000
-		for _, utxo := range b.collaterals {
-			if int(utxo.Output.GetValue().GetCoin()) >= collateral_amount+1_000_000 && len(utxo.Output.GetValue().GetAssets()) <= 5 {
-				b.totalCollateral = collateral_amount
-				return_amount := utxo.Output.GetValue().GetCoin() - int64(collateral_amount)
-				returnOutput := TransactionOutput.SimpleTransactionOutput(b.inputAddresses[0], Value.SimpleValue(return_amount, utxo.Output.GetValue().GetAssets()))
-				b.collateralReturn = &returnOutput
-			}
-		}
+	if len(b.collaterals) == 0 && !b.needsCollateral() {
 		return b, nil
 	}
-	witnesses := b.buildWitnessSet()
-	if len(witnesses.PlutusV1Script) == 0 &&
-		len(witnesses.PlutusV2Script) == 0 &&
-		len(b.referenceInputs) == 0 {
-		return b, nil
+	fee := int64(b.Context.MaxTxFee())
+	if estimated := b.estimateFee(); estimated > fee {
+		fee = estimated
 	}
-	availableUtxos := b.getAvailableUtxos()
-	collateral_amount := 5_000_000
-	for _, utxo := range availableUtxos {
-		if int(utxo.Output.GetValue().GetCoin()) >= collateral_amount && len(utxo.Output.GetValue().GetAssets()) <= 5 {
-			return_amount := utxo.Output.GetValue().GetCoin() - int64(collateral_amount)
-			min_lovelace := Utils.MinLovelacePostAlonzo(TransactionOutput.SimpleTransactionOutput(b.inputAddresses[0], Value.SimpleValue(return_amount, utxo.Output.GetAmount().GetAssets())), b.Context)
-			if min_lovelace > return_amount && return_amount != 0 {
-				continue
-			} else if return_amount == 0 && len(utxo.Output.GetAmount().GetAssets()) == 0 {
-				b.collaterals = append(b.collaterals, utxo)
-				b.totalCollateral = collateral_amount
-				return b, nil
-			} else {
-				returnOutput := TransactionOutput.SimpleTransactionOutput(b.inputAddresses[0], Value.SimpleValue(return_amount, utxo.Output.GetValue().GetAssets()))
-				b.collaterals = append(b.collaterals, utxo)
-				b.collateralReturn = &returnOutput
-				b.totalCollateral = collateral_amount
-				return b, nil
+	return b, b.selectCollateral(fee)
+}
+
+/*
+*
+
+	finalizeCollateral sets the exact collateral for the final
+	fee, selecting collateral again if the provisional one does
+	not cover it, and charges the change output for any size
+	difference.
+
+	Returns:
+		error: An error if no collateral can be found or the change
+			falls below its minimum lovelace.
+*/
+func (b *Apollo) finalizeCollateral() error {
+	if len(b.collaterals) == 0 {
+		return nil
+	}
+	for i := 0; i < 3; i++ {
+		if err := b.selectCollateral(b.Fee); err != nil {
+			return err
+		}
+		newestFee := b.estimateFee()
+		if newestFee <= b.Fee {
+			return nil
+		}
+		if b.change == nil {
+			return errors.New("no change output to charge the collateral size difference to")
+		}
+		b.change.Lovelace -= int(newestFee - b.Fee)
+		minLovelace := Utils.MinLovelacePostAlonzo(*b.change.ToTxOut(), b.Context)
+		if int64(b.change.Lovelace) < minLovelace {
+			output := -1
+			for idx, payment := range b.payments {
+				if payment == PaymentI(b.change) {
+					output = idx
+				}
+			}
+			return &Errors.BuildError{
+				Kind:         Errors.INPUTS,
+				Msg:          "the change falls below its minimum lovelace once the collateral is paid for",
+				Shortfall:    []Errors.Shortfall{{Required: minLovelace, Available: int64(b.change.Lovelace)}},
+				Considered:   b.preselectedUtxos,
+				Excluded:     b.excludedUtxos(),
+				EstimatedFee: newestFee,
+				Output:       output,
 			}
 		}
+		b.Fee = newestFee
 	}
-	for _, utxo := range availableUtxos {
-		if int(utxo.Output.GetValue().GetCoin()) >= collateral_amount {
-			return_amount := utxo.Output.GetValue().GetCoin() - int64(collateral_amount)
-			min_lovelace := Utils.MinLovelacePostAlonzo(TransactionOutput.SimpleTransactionOutput(b.inputAddresses[0], Value.SimpleValue(return_amount, utxo.Output.GetAmount().GetAssets())), b.Context)
-			if min_lovelace > return_amount && return_amount != 0 {
-				continue
-			} else if return_amount == 0 && len(utxo.Output.GetAmount().GetAssets()) == 0 {
-				b.collaterals = append(b.collaterals, utxo)
-				b.totalCollateral = collateral_amount
-				return b, nil
-			} else {
-				returnOutput := TransactionOutput.SimpleTransactionOutput(b.inputAddresses[0], Value.SimpleValue(return_amount, utxo.Output.GetValue().GetAssets()))
-				b.collaterals = append(b.collaterals, utxo)
-				b.collateralReturn = &returnOutput
-				b.totalCollateral = collateral_amount
-				return b, nil
+	return b.selectCollateral(b.Fee)
+}
+
+/*
+*
+
+	selectCollateral selects the collateral of a transaction paying
+	the given fee. Collateral added with AddCollateral is used as is,
+	otherwise up to maxCollateralInputs key locked UTxOs are picked,
+	pure lovelace ones first.
+
+	Params:
+		fee (int64): The fee the collateral has to cover.
+
+	Returns:
+		error: An error explaining why no collateral could be found.
+*/
+func (b *Apollo) selectCollateral(fee int64) error {
+	pp := b.Context.GetProtocolParams()
+	required := pp.MinCollateral(fee)
+	if len(b.collaterals) > 0 && !b.autoCollateral {
+		if needed, err := b.setCollateralReturn(b.collaterals, required); err != nil {
+			return &Errors.BuildError{
+				Kind:         Errors.COLLATERAL,
+				Msg:          "NoCollateral: the added collateral is not enough",
+				Shortfall:    []Errors.Shortfall{{Required: needed, Available: totalLovelace(b.collaterals)}},
+				Considered:   b.collaterals,
+				EstimatedFee: fee,
+				Output:       -1,
+				Err:          err,
 			}
 		}
+		return nil
+	}
+	maxInputs := pp.MaxCollateralInuts
+	if maxInputs <= 0 {
+		maxInputs = DEFAULT_MAX_COLLATERAL_INPUTS
+	}
+	candidates := make([]UTxO.UTxO, 0)
+	excluded := b.excludedUtxos()
+	for _, utxo := range append(b.getAvailableUtxos(), b.preselectedUtxos...) {
+		payment, err := utxo.Output.GetAddress().PaymentCredential()
+		if err != nil || payment.IsScript() {
+			excluded = append(excluded, Errors.ExcludedUTxO{UTxO: utxo, Reason: Errors.EXCLUDED_SCRIPT_LOCKED})
+		} else if b.belowMinAda(utxo) {
+			excluded = append(excluded, Errors.ExcludedUTxO{UTxO: utxo, Reason: Errors.EXCLUDED_BELOW_MIN_ADA})
+		} else {
+			candidates = append(candidates, utxo)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		assetsI := len(candidates[i].Output.GetValue().GetAssets())
+		assetsJ := len(candidates[j].Output.GetValue().GetAssets())
+		if assetsI != assetsJ {
+			return assetsI < assetsJ
+		}
+		return candidates[i].Output.GetValue().GetCoin() > candidates[j].Output.GetValue().GetCoin()
+	})
+	selected := make([]UTxO.UTxO, 0)
+	needed := required
+	var lastErr error
+	for _, utxo := range candidates {
+		if len(selected) == maxInputs {
+			break
+		}
+		selected = append(selected, utxo)
+		needed, lastErr = b.setCollateralReturn(selected, required)
+		if lastErr == nil {
+			b.collaterals = selected
+			b.autoCollateral = true
+			return nil
+		}
+	}
+	for _, utxo := range candidates[len(selected):] {
+		excluded = append(excluded, Errors.ExcludedUTxO{UTxO: utxo, Reason: Errors.EXCLUDED_COLLATERAL_LIMIT})
+	}
+	err := &Errors.BuildError{
+		Kind:         Errors.COLLATERAL,
+		Shortfall:    []Errors.Shortfall{{Required: needed, Available: totalLovelace(selected)}},
+		Considered:   candidates,
+		Excluded:     excluded,
+		EstimatedFee: fee,
+		Output:       -1,
+		Err:          lastErr,
+	}
+	if len(candidates) == 0 {
+		err.Msg = fmt.Sprintf("NoCollateral: %d lovelace of collateral are required but no key locked UTxO is available", required)
+	} else {
+		err.Msg = fmt.Sprintf("NoCollateral: %d lovelace of collateral are required and no %d of the %d key locked UTxOs are enough",
+			required, maxInputs, len(candidates))
+	}
+	return err
+}
+
+/*
+*
+
+	belowMinAda reports whether a UTxO holds less lovelace than
+	the minimum lovelace its assets add to an output, so that
+	spending it as collateral costs more than it brings.
+
+	Params:
+		utxo (UTxO.UTxO): The UTxO.
+
+	Returns:
+		bool: true if the UTxO is below the minimum of its assets.
+*/
+func (b *Apollo) belowMinAda(utxo UTxO.UTxO) bool {
+	value := utxo.Output.GetValue()
+	if len(value.GetAssets()) == 0 {
+		return false
+	}
+	address := utxo.Output.GetAddress()
+	withAssets := Utils.MinLovelacePostAlonzo(TransactionOutput.SimpleTransactionOutput(address, value), b.Context)
+	lovelaceOnly := Utils.MinLovelacePostAlonzo(TransactionOutput.SimpleTransactionOutput(address, Value.PureLovelaceValue(value.GetCoin())), b.Context)
+	return value.GetCoin() < withAssets-lovelaceOnly
+}
+
+/*
+*
+
+	setCollateralReturn sets the total collateral and the collateral
+	return of the given collateral UTxOs, everything but the required
+	lovelace going back to the collateral return address.
+
+	Params:
+		utxos ([]UTxO.UTxO): The collateral UTxOs.
+		required (int64): The lovelace the collateral has to cover.
+
+	Returns:
+		int64: The lovelace the UTxOs need to hold, the required
+			collateral plus the minimum lovelace of the return.
+		error: An error if the UTxOs do not cover the required
+			collateral and the minimum lovelace of the return.
+*/
+func (b *Apollo) setCollateralReturn(utxos []UTxO.UTxO, required int64) (int64, error) {
+	total := Value.Value{}
+	for _, utxo := range utxos {
+		total = total.Add(utxo.Output.GetValue())
+	}
+	if total.GetCoin() < required {
+		return required, fmt.Errorf("%d lovelace do not cover the required %d", total.GetCoin(), required)
+	}
+	returnAmount := total.GetCoin() - required
+	assets := total.GetAssets()
+	if returnAmount == 0 && len(assets) == 0 {
+		b.totalCollateral = int(required)
+		b.collateralReturn = nil
+		return required, nil
+	}
+	returnOutput := TransactionOutput.SimpleTransactionOutput(b.getCollateralReturnAddress(), Value.SimpleValue(returnAmount, assets))
+	minLovelace := Utils.MinLovelacePostAlonzo(returnOutput, b.Context)
+	if returnAmount < minLovelace {
+		return required + minLovelace, fmt.Errorf("the collateral return needs %d lovelace but only %d are left", minLovelace, returnAmount)
 	}
-	return b, errors.New("NoCollateral")
+	b.totalCollateral = int(required)
+	b.collateralReturn = &returnOutput
+	return required + minLovelace, nil
+}
+
+func (b *Apollo) getCollateralReturnAddress() Address.Address {
+	if b.collateralReturnAddress != nil {
+		return *b.collateralReturnAddress
+	}
+	return b.inputAddresses[0]
+}
+
+/*
+*
+
+	SetCollateralReturnAddress sets the address receiving the
+	collateral return, the first input address by default.
+
+	Params:
+		address (Address.Address): The collateral return address.
+
+	Returns:
+		*Apollo: A pointer to the Apollo object to support method chaining.
+*/
+func (b *Apollo) SetCollateralReturnAddress(address Address.Address) *Apollo {
+	b.collateralReturnAddress = &address
+	return b
 }
 
 /*
@@ -865,14 +1303,42 @@ func (b *Apollo) Clone() *Apollo {
 
 	Returns:
 		map[string]Redeemer.ExecutionUnits: A map of estimated execution units.
+		error: The error of building the transaction to evaluate or of its evaluation.
 */
-func (b *Apollo) estimateExunits() map[string]Redeemer.ExecutionUnits {
+func (b *Apollo) estimateExunits() (map[string]Redeemer.ExecutionUnits, error) {
 	cloned_b := b.Clone()
 	cloned_b.isEstimateRequired = false
-	updated_b, _ := cloned_b.Complete()
+	cloned_b.notes = nil
+	updated_b, err := cloned_b.Complete()
+	if err != nil {
+		return nil, err
+	}
 	//updated_b = updated_b.fakeWitness()
-	tx_cbor, _ := cbor.Marshal(updated_b.tx)
-	return b.Context.EvaluateTx(tx_cbor)
+	tx_cbor, err := cbor.Marshal(updated_b.tx)
+	if err != nil {
+		return nil, err
+	}
+	return b.evaluate(tx_cbor)
+}
+
+/*
+*
+
+	evaluate evaluates a transaction with the chain context.
+
+	Params:
+		tx_cbor ([]byte): The CBOR of the transaction.
+
+	Returns:
+		map[string]Redeemer.ExecutionUnits: A map of estimated execution units.
+		error: The error of the evaluation.
+*/
+func (b *Apollo) evaluate(tx_cbor []byte) (map[string]Redeemer.ExecutionUnits, error) {
+	units, err := Base.FetchEvaluation(b.Context, tx_cbor)
+	if err != nil {
+		return nil, fmt.Errorf("evaluating the execution units: %w", err)
+	}
+	return units, nil
 }
 
 /*
@@ -882,10 +1348,14 @@ func (b *Apollo) estimateExunits() map[string]Redeemer.ExecutionUnits {
 
 	Returns:
 		*Apollo: A pointer to the Apollo object to support method chaining.
+		error: The error of the estimation.
 */
-func (b *Apollo) updateExUnits() *Apollo {
+func (b *Apollo) updateExUnits() (*Apollo, error) {
 	if b.isEstimateRequired {
-		estimated_execution_units := b.estimateExunits()
+		estimated_execution_units, err := b.estimateExunits()
+		if err != nil {
+			return nil, err
+		}
 		for k, redeemer := range b.redeemersToUTxO {
 			key := fmt.Sprintf("%s:%d", Redeemer.RdeemerTagNames[redeemer.Tag], redeemer.Index)
 			if _, ok := estimated_execution_units[key]; ok {
@@ -929,7 +1399,7 @@ func (b *Apollo) updateExUnits() *Apollo {
 		}
 
 	}
-	return b
+	return b, nil
 }
 
 /*
@@ -967,6 +1437,35 @@ func CountRequiredAssets(assets MultiAsset.MultiAsset[int64]) int {
 		error: An error if any issues are encountered during the process.
 */
 func (b *Apollo) Complete() (*Apollo, error) {
+	err := b.observe("apollo.Complete", func() error {
+		err := b.complete()
+		if err == nil && b.instrumented() {
+			txBytes, _ := b.tx.Bytes()
+			b.setAttributes(
+				slog.Int64("fee", b.Fee),
+				slog.Int("inputs", len(b.tx.TransactionBody.Inputs)),
+				slog.Int("outputs", len(b.tx.TransactionBody.Outputs)),
+				slog.Int("tx_size", len(txBytes)),
+			)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+/*
+*
+
+	selectInputs selects the UTxOs covering the payments, burns
+	and an estimate of the fee, adding them to the preselected ones.
+
+	Returns:
+		error: An error if the available UTxOs are not enough.
+*/
+func (b *Apollo) selectInputs() error {
 	selectedUtxos := make([]UTxO.UTxO, 0)
 	selectedAmount := Value.Value{}
 	for _, utxo := range b.preselectedUtxos {
@@ -981,10 +1480,28 @@ func (b *Apollo) Complete() (*Apollo, error) {
 		payment.EnsureMinUTXO(b.Context)
 		requestedAmount = requestedAmount.Add(payment.ToValue())
 	}
-	requestedAmount.AddLovelace(b.estimateFee() + constants.MIN_LOVELACE)
+	estimatedFee := b.estimateFee()
+	requestedAmount.AddLovelace(estimatedFee + constants.MIN_LOVELACE)
 	unfulfilledAmount := requestedAmount.Sub(selectedAmount)
 	unfulfilledAmount = unfulfilledAmount.RemoveZeroAssets()
-	available_utxos := SortUtxos(b.getAvailableUtxos())
+	considered := b.getAvailableUtxos()
+	excluded := b.excludedUtxos()
+	available_utxos := SortUtxos(considered)
+	insufficient := func(msg string, required Value.Value) error {
+		provided := selectedAmount
+		for _, utxo := range available_utxos {
+			provided = provided.Add(utxo.Output.GetValue())
+		}
+		return &Errors.BuildError{
+			Kind:         Errors.INPUTS,
+			Msg:          msg,
+			Shortfall:    shortfall(required, provided),
+			Considered:   considered,
+			Excluded:     excluded,
+			EstimatedFee: estimatedFee,
+			Output:       -1,
+		}
+	}
 	//BALANCE TX
 	requiredAssetsCount := CountRequiredAssets(unfulfilledAmount.GetAssets())
 	if unfulfilledAmount.GetCoin() > 0 || requiredAssetsCount > 0 {
@@ -992,13 +1509,20 @@ func (b *Apollo) Complete() (*Apollo, error) {
 		if len(unfulfilledAmount.GetAssets()) > 0 {
 			//BALANCE WITH ASSETS
 			for pol, assets := range unfulfilledAmount.GetAssets() {
-				for asset, amt := range assets {
+				for asset := range assets {
+					// UTxOs selected for the previous assets may hold this one
+					amt := requestedAmount.GetAssets().GetByPolicyAndId(pol, asset) -
+						selectedAmount.GetAssets().GetByPolicyAndId(pol, asset)
+					if amt <= 0 {
+						continue
+					}
 					found := false
 					selectedSoFar := int64(0)
 					usedIdxs := make([]int, 0)
 					for idx, utxo := range available_utxos {
 						ma := utxo.Output.GetValue().GetAssets()
 						if ma.GetByPolicyAndId(pol, asset) >= amt {
+							b.noteSelection(utxo, SELECTED_ASSET, pol.String()+asset.HexString())
 							selectedUtxos = append(selectedUtxos, utxo)
 							selectedAmount = selectedAmount.Add(utxo.Output.GetValue())
 							usedIdxs = append(usedIdxs, idx)
@@ -1006,6 +1530,7 @@ func (b *Apollo) Complete() (*Apollo, error) {
 							found = true
 							break
 						} else if ma.GetByPolicyAndId(pol, asset) > 0 {
+							b.noteSelection(utxo, SELECTED_ASSET, pol.String()+asset.HexString())
 							selectedUtxos = append(selectedUtxos, utxo)
 							selectedAmount = selectedAmount.Add(utxo.Output.GetValue())
 							usedIdxs = append(usedIdxs, idx)
@@ -1025,20 +1550,22 @@ func (b *Apollo) Complete() (*Apollo, error) {
 					}
 					available_utxos = newAvailUtxos
 					if !found {
-						return nil, errors.New("missing required assets")
+						return insufficient("missing required assets", requestedAmount)
 					}
 
 				}
 			}
 		}
 		for {
-			if selectedAmount.Greater(requestedAmount.Add(Value.Value{Am: Amount.Amount{}, Coin: 1_000_000, HasAssets: false})) {
+			required := requestedAmount.Add(Value.Value{Am: Amount.Amount{}, Coin: 1_000_000, HasAssets: false})
+			if selectedAmount.Greater(required) {
 				break
 			}
 			if len(available_utxos) == 0 {
-				return nil, errors.New("not enough funds")
+				return insufficient("not enough funds", required)
 			}
 			utxo := available_utxos[0]
+			b.noteSelection(utxo, SELECTED_LOVELACE, "")
 			selectedUtxos = append(selectedUtxos, utxo)
 			selectedAmount = selectedAmount.Add(utxo.Output.GetValue())
 			available_utxos = available_utxos[1:]
@@ -1048,29 +1575,105 @@ func (b *Apollo) Complete() (*Apollo, error) {
 	}
 	// ADD NEW SELECTED INPUTS TO PRE SELECTION
 	b.preselectedUtxos = append(b.preselectedUtxos, selectedUtxos...)
+	b.setAttributes(slog.Int("selected", len(selectedUtxos)), slog.Int("available", len(b.utxos)))
+	return nil
+}
 
+/*
+*
+
+	complete runs the phases of Complete, each in its own span.
+
+	Returns:
+		error: The error of the failed phase.
+*/
+func (b *Apollo) complete() error {
+	if b.pendingErr != nil {
+		return b.pendingErr
+	}
+	err := b.resolveDatums()
+	if err != nil {
+		return err
+	}
+	err = b.observe("apollo.coinSelection", b.selectInputs)
+	if err != nil {
+		return err
+	}
 	//SET REDEEMER INDEXES
-	b = b.setRedeemerIndexes()
+	b.setRedeemerIndexes()
 	//SET COLLATERAL
-	b, err := b.setCollateral()
+	err = b.observe("apollo.setCollateral", func() error {
+		_, err := b.setCollateral()
+		return err
+	})
 	if err != nil {
-		return nil, err
+		return err
 	}
 	//UPDATE EXUNITS
-	b = b.updateExUnits()
+	err = b.observe("apollo.updateExUnits", func() error {
+		_, err := b.updateExUnits()
+		return err
+	})
+	if err != nil {
+		return err
+	}
 	//ADDCHANGEANDFEE
-	b, err = b.addChangeAndFee()
+	err = b.observe("apollo.addChangeAndFee", func() error {
+		_, err := b.addChangeAndFee()
+		if err == nil {
+			b.setAttributes(slog.Int64("fee", b.Fee))
+		}
+		return err
+	})
 	if err != nil {
-		return nil, err
+		return err
+	}
+	err = b.finalizeCollateral()
+	if err != nil {
+		return err
 	}
 	//FINALIZE TX
 	body, err := b.buildTxBody()
 	if err != nil {
-		return nil, err
+		return err
 	}
 	witnessSet := b.buildWitnessSet()
 	b.tx = &Transaction.Transaction{TransactionBody: body, TransactionWitnessSet: witnessSet, AuxiliaryData: b.auxiliaryData, Valid: true}
-	return b, nil
+	err = b.checkSizes()
+	if err == nil && b.notes != nil {
+		b.report = b.buildReport()
+	}
+	return err
+}
+
+/*
+*
+
+	checkSizes checks the values of the outputs against
+	maxValSize and the transaction, signed with fake witnesses,
+	against maxTxSize.
+
+	Returns:
+		error: A *Errors.BuildError if an output or the transaction is too large.
+*/
+func (b *Apollo) checkSizes() error {
+	maxValSize := b.Context.GetProtocolParams().MaxValSize
+	for i, output := range b.tx.TransactionBody.Outputs {
+		value := output.GetValue()
+		encoded, err := cbor.Marshal(&value)
+		if err != nil || maxValSize <= 0 || len(encoded) <= maxValSize {
+			continue
+		}
+		return &Errors.BuildError{
+			Kind:         Errors.OUTPUT_SIZE,
+			Msg:          fmt.Sprintf("the value of output %d is too large, %d bytes over the %d allowed", i, len(encoded), maxValSize),
+			Considered:   b.preselectedUtxos,
+			EstimatedFee: b.Fee,
+			Output:       i,
+		}
+	}
+	_, err := b.buildFullFakeTx()
+	return err
 }
 
 /*
@@ -1090,8 +1693,7 @@ func (b *Apollo) Complete() (*Apollo, error) {
 func isOverUtxoLimit(change Value.Value, address Address.Address, b Base.ChainContext) bool {
 	txOutput := TransactionOutput.SimpleTransactionOutput(address, Value.SimpleValue(0, change.GetAssets()))
 	encoded, _ := cbor.Marshal(txOutput)
-	maxValSize, _ := strconv.Atoi(b.GetProtocolParams().MaxValSize)
-	return len(encoded) > maxValSize
+	return len(encoded) > b.GetProtocolParams().MaxValSize
 
 }
 
@@ -1205,6 +1807,7 @@ Returns:
 func (b *Apollo) addChangeAndFee() (*Apollo, error) {
 	burns := b.GetBurns()
 	mints := b.getPositiveMints()
+	b.change = nil
 	providedAmount := Value.Value{}
 	for _, utxo := range b.preselectedUtxos {
 		providedAmount = providedAmount.Add(utxo.Output.GetValue())
@@ -1218,17 +1821,24 @@ func (b *Apollo) addChangeAndFee() (*Apollo, error) {
 	b.Fee = b.estimateFee()
 	requestedAmount.AddLovelace(b.Fee)
 	change := providedAmount.Sub(requestedAmount)
-	if change.GetCoin() < Utils.MinLovelacePostAlonzo(
+	minChange := Utils.MinLovelacePostAlonzo(
 		TransactionOutput.SimpleTransactionOutput(b.inputAddresses[0], Value.SimpleValue(0, change.GetAssets())),
 		b.Context,
-	) {
-		if len(b.getAvailableUtxos()) == 0 {
-			return b, errors.New("No Remaining UTxOs")
-		}
+	)
+	if change.GetCoin() < minChange {
 		sortedUtxos := SortUtxos(b.getAvailableUtxos())
 		if len(sortedUtxos) == 0 {
-			return b, errors.New("No Remaining UTxOs")
+			return b, &Errors.BuildError{
+				Kind:         Errors.INPUTS,
+				Msg:          "No Remaining UTxOs to cover the fee and the minimum lovelace of the change",
+				Shortfall:    shortfall(requestedAmount.Add(Value.PureLovelaceValue(minChange)), providedAmount),
+				Considered:   b.preselectedUtxos,
+				Excluded:     b.excludedUtxos(),
+				EstimatedFee: b.Fee,
+				Output:       -1,
+			}
 		}
+		b.noteSelection(sortedUtxos[0], SELECTED_CHANGE, "")
 		b.preselectedUtxos = append(b.preselectedUtxos, sortedUtxos[0])
 		b.usedUtxos = append(b.usedUtxos, sortedUtxos[0].GetKey())
 		return b.addChangeAndFee()
@@ -1236,6 +1846,9 @@ func (b *Apollo) addChangeAndFee() (*Apollo, error) {
 	if isOverUtxoLimit(change, b.inputAddresses[0], b.Context) {
 		adjustedPayments := splitPayments(change, b.inputAddresses[0], b.Context)
 		pp := b.payments[:]
+		b.noteChange(len(pp), len(adjustedPayments), fmt.Sprintf(
+			"the change exceeds the maxValSize of %d bytes, split in %d outputs",
+			b.Context.GetProtocolParams().MaxValSize, len(adjustedPayments)))
 		for _, payment := range adjustedPayments {
 			b.payments = append(b.payments, payment)
 		}
@@ -1249,6 +1862,7 @@ func (b *Apollo) addChangeAndFee() (*Apollo, error) {
 				b.payments = append(b.payments, payment)
 			}
 		}
+		b.change = adjustedPayments[len(adjustedPayments)-1]
 
 	} else {
 		payment := Payment{
@@ -1269,6 +1883,7 @@ func (b *Apollo) addChangeAndFee() (*Apollo, error) {
 		}
 		pp := b.payments[:]
 		b.payments = append(b.payments, &payment)
+		b.noteChange(len(pp), 1, "")
 
 		newestFee := b.estimateFee()
 		if newestFee > b.Fee {
@@ -1277,6 +1892,7 @@ func (b *Apollo) addChangeAndFee() (*Apollo, error) {
 			b.payments = append(pp, &payment)
 			b.Fee = newestFee
 		}
+		b.change = &payment
 	}
 	return b, nil
 }
@@ -1285,6 +1901,9 @@ func (b *Apollo) addChangeAndFee() (*Apollo, error) {
 *
 
 	Collect a UTXO and its associated redeemer for inclusion in the transaction.
+	If the UTxO carries only the hash of its datum and no datum with that
+	hash is attached, the datum is resolved when completing the
+	transaction, through the chain context if it is a Base.DatumResolver.
 
 	Params:
 		inputUtxo: The UTXO to collect.
@@ -1304,6 +1923,83 @@ func (b *Apollo) CollectFrom(
 	return b
 }
 
+/*
+*
+
+	datumHash returns the hash of the datum of an output, if it
+	carries only the hash.
+
+	Params:
+		output (TransactionOutput.TransactionOutput): The output.
+
+	Returns:
+		string: The hex encoded hash, empty if the output has no
+			datum or an inline one.
+*/
+func datumHash(output TransactionOutput.TransactionOutput) string {
+	if !output.IsPostAlonzo {
+		if !output.PreAlonzo.HasDatum {
+			return ""
+		}
+		return hex.EncodeToString(output.PreAlonzo.DatumHash.Payload)
+	}
+	datum := output.PostAlonzo.Datum
+	if datum == nil || datum.DatumType != PlutusData.DatumTypeHash {
+		return ""
+	}
+	return hex.EncodeToString(datum.Hash)
+}
+
+/*
+*
+
+	resolveDatums attaches the missing datums of the UTxOs
+	collected with CollectFrom, resolving them through the
+	chain context. Nothing is done if it is not a
+	Base.DatumResolver.
+
+	Returns:
+		error: An error if a datum cannot be resolved or does not
+			match the hash of its UTxO.
+*/
+func (b *Apollo) resolveDatums() error {
+	resolver, ok := b.Context.(Base.DatumResolver)
+	if !ok {
+		return nil
+	}
+	attached := make(map[string]bool)
+	for _, datum := range b.datums {
+		datum := datum
+		hash, err := PlutusData.PlutusDataHash(&datum)
+		if err == nil {
+			attached[hex.EncodeToString(hash.Payload)] = true
+		}
+	}
+	for _, utxo := range b.preselectedUtxos {
+		if _, ok := b.redeemersToUTxO[hex.EncodeToString(utxo.Input.TransactionId)+fmt.Sprint(utxo.Input.Index)]; !ok {
+			continue
+		}
+		hash := datumHash(utxo.Output)
+		if hash == "" || attached[hash] {
+			continue
+		}
+		datum, err := resolver.ResolveDatum(hash)
+		if errors.Is(err, Base.ErrNotSupported) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("cannot resolve the datum of %s: %w", utxo.GetKey(), err)
+		}
+		resolved, err := PlutusData.PlutusDataHash(datum)
+		if err != nil || hex.EncodeToString(resolved.Payload) != hash {
+			return fmt.Errorf("the datum resolved for %s does not match its hash %s", utxo.GetKey(), hash)
+		}
+		b.datums = append(b.datums, *datum)
+		attached[hash] = true
+	}
+	return nil
+}
+
 /*
 *
 
@@ -1351,6 +2047,35 @@ func (b *Apollo) AttachV2Script(script PlutusData.PlutusV2Script) *Apollo {
 	return b
 }
 
+/*
+*
+
+	Attach a native script to the Apollo transaction. It is left
+	out of the witness set when an input or a reference input
+	provides it.
+
+	Params:
+		script: The native script to attach.
+
+	Returns:
+		*Apollo: A pointer to the Apollo object with the attached script.
+		error: An error if the script cannot be hashed.
+*/
+func (b *Apollo) AttachNativeScript(script NativeScript.NativeScript) (*Apollo, error) {
+	hash, err := script.Hash()
+	if err != nil {
+		return b, err
+	}
+	for _, scriptHash := range b.scriptHashes {
+		if scriptHash == hex.EncodeToString(hash.Bytes()) {
+			return b, nil
+		}
+	}
+	b.nativescripts = append(b.nativescripts, script)
+	b.scriptHashes = append(b.scriptHashes, hex.EncodeToString(hash.Bytes()))
+	return b, nil
+}
+
 /**
 Set the wallet for the Apollo transaction using a mnemonic.
 
@@ -1363,9 +2088,29 @@ Returns:
 */
 
 func (a *Apollo) SetWalletFromMnemonic(mnemonic string, network constants.Network) (*Apollo, error) {
+	return a.SetWalletFromMnemonicWithScheme(mnemonic, network, HDWallet.ICARUS)
+}
+
+/*
+*
+
+	Set the wallet for the Apollo transaction using a mnemonic, with
+	the master key derivation of the wallet or hardware device the
+	mnemonic comes from.
+
+	Params:
+		mnemonic (string): The mnemonic phrase used to generate the wallet.
+		network (constants.Network): The network of the address.
+		scheme (HDWallet.DerivationScheme): ICARUS, ICARUS_TREZOR or LEDGER.
+
+	Returns:
+		*Apollo: A pointer to the Apollo object with the wallet set.
+		error: An error if the mnemonic is invalid.
+*/
+func (a *Apollo) SetWalletFromMnemonicWithScheme(mnemonic string, network constants.Network, scheme HDWallet.DerivationScheme) (*Apollo, error) {
 	paymentPath := "m/1852'/1815'/0'/0/0"
 	stakingPath := "m/1852'/1815'/0'/2/0"
-	hdWall, err := HDWallet.NewHDWalletFromMnemonic(mnemonic, "")
+	hdWall, err := HDWallet.NewHDWalletFromMnemonicWithScheme(mnemonic, "", scheme)
 	if err != nil {
 		return a, err
 	}
@@ -1401,6 +2146,66 @@ func (a *Apollo) SetWalletFromMnemonic(mnemonic string, network constants.Networ
 	return a, nil
 }
 
+/*
+*
+
+	Set the wallet for the Apollo transaction to a CIP-1852 account
+	of a mnemonic, discovering its addresses through the chain context
+	and loading the UTxOs of all of them for coin selection.
+
+	Params:
+		mnemonic (string): The mnemonic phrase used to generate the wallet.
+		network (constants.Network): The network of the addresses.
+		account (uint32): The account index.
+		gapLimit (int): The address gap limit, apollotypes.DEFAULT_GAP_LIMIT if not positive.
+
+	Returns:
+		*Apollo: A pointer to the Apollo object with the wallet set.
+		error: An error if the wallet cannot be derived or discovered.
+*/
+func (a *Apollo) SetAccountWalletFromMnemonic(mnemonic string, network constants.Network, account uint32, gapLimit int) (*Apollo, error) {
+	wallet, err := apollotypes.NewAccountWalletFromMnemonic(mnemonic, "", account, network, gapLimit)
+	if err != nil {
+		return a, err
+	}
+	return a.setAccountWallet(wallet)
+}
+
+/*
+*
+
+	Set a watch-only wallet for the Apollo transaction from a bech32
+	account extended public key (acct_xvk). Transactions are built
+	unsigned: the wallet's RequiredSigningPaths lists the keys an
+	external signer must use.
+
+	Params:
+		acctXvk (string): The bech32 account extended public key.
+		network (constants.Network): The network of the addresses.
+		account (uint32): The account index, used for the derivation paths.
+		gapLimit (int): The address gap limit, apollotypes.DEFAULT_GAP_LIMIT if not positive.
+
+	Returns:
+		*Apollo: A pointer to the Apollo object with the wallet set.
+		error: An error if the key is invalid or the discovery fails.
+*/
+func (a *Apollo) SetWatchOnlyWallet(acctXvk string, network constants.Network, account uint32, gapLimit int) (*Apollo, error) {
+	wallet, err := apollotypes.NewWatchOnlyWallet(acctXvk, account, network, gapLimit)
+	if err != nil {
+		return a, err
+	}
+	return a.setAccountWallet(wallet)
+}
+
+func (a *Apollo) setAccountWallet(wallet *apollotypes.AccountWallet) (*Apollo, error) {
+	err := wallet.Discover(a.Context)
+	if err != nil {
+		return a, err
+	}
+	a.wallet = wallet
+	return a.AddLoadedUTxOs(wallet.Utxos()...), nil
+}
+
 // For use with key pairs generated by cardano-cli
 func (a *Apollo) SetWalletFromKeypair(vkey string, skey string, network constants.Network) *Apollo {
 	verificationKey_bytes, err := hex.DecodeString(vkey)
@@ -1456,10 +2261,29 @@ func (a *Apollo) SetWalletFromBech32(address string) *Apollo {
 	return a
 }
 
+/*
+*
+
+	Set the wallet for the Apollo transaction, for wallets
+	implemented outside of Apollo such as remote signers.
+
+	Params:
+		wallet (apollotypes.Wallet): The wallet to use.
+
+	Returns:
+		*Apollo: A pointer to the Apollo object with the wallet set.
+*/
+func (a *Apollo) SetWallet(wallet apollotypes.Wallet) *Apollo {
+	a.wallet = wallet
+	return a
+}
+
 /*
 *
 
 	Set the wallet as the change address for the Apollo transaction.
+	When the chain context loads the UTxOs of the wallet and
+	fetching them fails, the error is returned by Complete.
 
 	Returns:
 		*Apollo: A pointer to the Apollo object with the wallet set as the change address.
@@ -1469,12 +2293,17 @@ func (b *Apollo) SetWalletAsChangeAddress() *Apollo {
 		panic("wallet not set")
 		return b
 	}
-	switch b.Context.(type) {
-	case *BlockFrostChainContext.BlockFrostChainContext:
-
-		utxos := b.Context.Utxos(*b.wallet.GetAddress())
+	if account, ok := b.wallet.(*apollotypes.AccountWallet); ok {
+		b.inputAddresses = append(b.inputAddresses, account.ChangeAddress())
+		return b
+	}
+	if loader, ok := b.Context.(Base.WalletUtxoLoader); ok && loader.LoadsWalletUtxos() {
+		utxos, err := Base.FetchUtxos(b.Context, *b.wallet.GetAddress())
+		if err != nil {
+			b.pendingErr = fmt.Errorf("fetching the UTxOs of the wallet: %w", err)
+			return b
+		}
 		b = b.AddLoadedUTxOs(utxos...)
-	default:
 	}
 	b.inputAddresses = append(b.inputAddresses, *b.wallet.GetAddress())
 	return b
@@ -1738,6 +2567,28 @@ func (b *Apollo) AddReferenceInput(txHash string, index int) *Apollo {
 	return b
 }
 
+/*
+*
+
+	AddLoadedReferenceInput adds an already resolved UTxO as a
+	reference input, so that the size of its reference script
+	can be charged without querying the chain context.
+
+	Params:
+		utxo (UTxO.UTxO): The UTxO to reference.
+
+	Returns:
+		*Apollo: A pointer to the modified Apollo instance with the added reference input.
+*/
+func (b *Apollo) AddLoadedReferenceInput(utxo UTxO.UTxO) *Apollo {
+	if b.referenceUtxos == nil {
+		b.referenceUtxos = make(map[string]*UTxO.UTxO)
+	}
+	b.referenceUtxos[utxo.GetKey()] = &utxo
+	b.referenceInputs = append(b.referenceInputs, utxo.Input)
+	return b
+}
+
 /*
 *
 
@@ -1756,13 +2607,12 @@ func (b *Apollo) AddWithdrawal(address Address.Address, amount int, redeemerData
 		newWithdrawal := Withdrawal.New()
 		b.withdrawals = &newWithdrawal
 	}
-	var stakeAddr [29]byte
-	stakeAddr[0] = address.HeaderByte
-	if len(address.StakingPart) != 28 {
-		fmt.Printf("AddWithdrawal: address has invalid or missing staking part: %v\n", address.StakingPart)
+	stakeAddr, err := address.RewardAccount()
+	if err != nil {
+		fmt.Printf("AddWithdrawal: %v\n", err)
+		return b
 	}
-	copy(stakeAddr[1:], address.StakingPart)
-	err := b.withdrawals.Add(stakeAddr, amount)
+	err = b.withdrawals.Add(stakeAddr, amount)
 	if err != nil {
 		fmt.Printf("AddWithdrawal: %v\n", err)
 		return b
@@ -1783,15 +2633,24 @@ func (b *Apollo) AddCollateral(utxo UTxO.UTxO) *Apollo {
 }
 
 func (b *Apollo) CompleteExact(fee int) (*Apollo, error) {
+	err := b.resolveDatums()
+	if err != nil {
+		return nil, err
+	}
 	//SET REDEEMER INDEXES
 	b = b.setRedeemerIndexes()
 	//SET COLLATERAL
-	b, err := b.setCollateral()
+	if len(b.collaterals) > 0 || b.needsCollateral() {
+		err := b.selectCollateral(int64(fee))
+		if err != nil {
+			return nil, err
+		}
+	}
+	//UPDATE EXUNITS
+	b, err = b.updateExUnitsExact(fee)
 	if err != nil {
 		return nil, err
 	}
-	//UPDATE EXUNITS
-	b = b.updateExUnitsExact(fee)
 	//ADDCHANGEANDFEE
 	b.Fee = int64(fee)
 	//FINALIZE TX
@@ -1804,18 +2663,27 @@ func (b *Apollo) CompleteExact(fee int) (*Apollo, error) {
 	return b, nil
 }
 
-func (b *Apollo) estimateExunitsExact(fee int) map[string]Redeemer.ExecutionUnits {
+func (b *Apollo) estimateExunitsExact(fee int) (map[string]Redeemer.ExecutionUnits, error) {
 	cloned_b := b.Clone()
 	cloned_b.isEstimateRequired = false
-	updated_b, _ := cloned_b.CompleteExact(fee)
+	updated_b, err := cloned_b.CompleteExact(fee)
+	if err != nil {
+		return nil, err
+	}
 	//updated_b = updated_b.fakeWitness()
-	tx_cbor, _ := cbor.Marshal(updated_b.tx)
-	return b.Context.EvaluateTx(tx_cbor)
+	tx_cbor, err := cbor.Marshal(updated_b.tx)
+	if err != nil {
+		return nil, err
+	}
+	return b.evaluate(tx_cbor)
 }
 
-func (b *Apollo) updateExUnitsExact(fee int) *Apollo {
+func (b *Apollo) updateExUnitsExact(fee int) (*Apollo, error) {
 	if b.isEstimateRequired {
-		estimated_execution_units := b.estimateExunitsExact(fee)
+		estimated_execution_units, err := b.estimateExunitsExact(fee)
+		if err != nil {
+			return nil, err
+		}
 		for k, redeemer := range b.redeemersToUTxO {
 			key := fmt.Sprintf("%s:%d", Redeemer.RdeemerTagNames[redeemer.Tag], redeemer.Index)
 			if _, ok := estimated_execution_units[key]; ok {
@@ -1859,5 +2727,5 @@ func (b *Apollo) updateExUnitsExact(fee int) *Apollo {
 		}
 
 	}
-	return b
+	return b, nil
 }
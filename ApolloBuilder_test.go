@@ -1,18 +1,24 @@
 package apollo_test
 
 import (
+	"bytes"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/big"
+	"strings"
 	"testing"
 
 	"github.com/Salvionied/apollo"
+	"github.com/Salvionied/apollo/constants"
+	"github.com/Salvionied/apollo/observability"
 	"github.com/Salvionied/apollo/serialization"
 	"github.com/Salvionied/apollo/serialization/Address"
 	"github.com/Salvionied/apollo/serialization/Asset"
 	"github.com/Salvionied/apollo/serialization/AssetName"
 	"github.com/Salvionied/apollo/serialization/MultiAsset"
+	"github.com/Salvionied/apollo/serialization/NativeScript"
 	"github.com/Salvionied/apollo/serialization/PlutusData"
 	"github.com/Salvionied/apollo/serialization/Policy"
 	"github.com/Salvionied/apollo/serialization/Redeemer"
@@ -22,9 +28,13 @@ import (
 	"github.com/Salvionied/apollo/serialization/UTxO"
 	"github.com/Salvionied/apollo/serialization/Value"
 	testutils "github.com/Salvionied/apollo/testUtils"
+	"github.com/Salvionied/apollo/txBuilding/Backend/Base"
 	"github.com/Salvionied/apollo/txBuilding/Backend/BlockFrostChainContext"
 	"github.com/Salvionied/apollo/txBuilding/Backend/FixedChainContext"
+	"github.com/Salvionied/apollo/txBuilding/Errors"
+	"github.com/Salvionied/apollo/txBuilding/Utils"
 	"github.com/Salvionied/cbor/v2"
+	"golang.org/x/exp/slog"
 )
 
 type Network int
@@ -481,7 +491,7 @@ func TestBurnPlutus(t *testing.T) {
 		).
 		Complete()
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
 	txBytes, err := apollob.GetTx().Bytes()
 	if hex.EncodeToString(txBytes) != "84a5008182584064356431663763323233646338386262343134373461663233623638356530323437333037653934653731356566356536326633323561633934663733303536000181825839010a59337f7b3a913424d7f7a151401e052642b68e948d8cacadc6372016a9999419cc5a61ca62da81e378d7538213a3715a6b858c948c69c91a00e2117b021a0002d04509a1581c279c909f348e533da5808898f87f9a14bb2c3dfbbacccd631d927a3fa14454455354200b5820aed726f17f6c88739b6d5ba2e104b948bb81f6c46e8fc0809c120021c1e6e88ba203800581840000f6820000f5f6" {
@@ -882,7 +892,7 @@ func TestSetCollateral(t *testing.T) {
 }
 
 func TestCollateralwithReturn(t *testing.T) {
-	// full 5 ada collateral
+	// collateralPercent of the fee, the rest is returned
 	cc := BlockFrostChainContext.NewBlockfrostChainContext(BLOCKFROST_BASE_URL_MAINNET, int(MAINNET), "mainnetVueasSgKfYhM4PQBq0UGipAyHBpbX4oT")
 	decoded_addr, _ := Address.DecodeAddress("addr1qy99jvml0vafzdpy6lm6z52qrczjvs4k362gmr9v4hrrwgqk4xvegxwvtfsu5ck6s83h346nsgf6xu26dwzce9yvd8ysd2seyu")
 	apollob := apollo.New(&cc)
@@ -891,10 +901,11 @@ func TestCollateralwithReturn(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
-	if built.GetTx().TransactionBody.TotalCollateral != 5_000_000 {
-		t.Error("Tx is not correct")
+	body := built.GetTx().TransactionBody
+	if int64(body.TotalCollateral) != cc.GetProtocolParams().MinCollateral(body.Fee) {
+		t.Error("Tx is not correct", body.TotalCollateral)
 	}
-	if built.GetTx().TransactionBody.CollateralReturn.Lovelace() != 5_000_000 {
+	if body.CollateralReturn.Lovelace() != 10_000_000-int64(body.TotalCollateral) {
 		t.Error("Tx is not correct", built.GetTx().TransactionBody.CollateralReturn)
 	}
 	if !built.GetTx().TransactionBody.Collateral[0].EqualTo(collateralUtxo2.Input) {
@@ -968,7 +979,7 @@ func TestRedeemerCollect(t *testing.T) {
 	if wts.Redeemer[0].ExUnits.Steps == 0 {
 		t.Error("Tx is not correct", wts.Redeemer[0].ExUnits.Steps)
 	}
-	if built.GetTx().TransactionBody.Fee != 228771 {
+	if built.GetTx().TransactionBody.Fee != 227144 {
 		t.Error("Tx is not correct", built.GetTx().TransactionBody.Fee)
 	}
 	if built.GetTx().TransactionBody.Collateral == nil {
@@ -1140,6 +1151,240 @@ func TestCongestedBuild(t *testing.T) {
 	}
 }
 
+type walletUtxoContext struct {
+	FixedChainContext.FixedChainContext
+}
+
+func (c *walletUtxoContext) LoadsWalletUtxos() bool {
+	return true
+}
+
+type failingEvaluationContext struct {
+	FixedChainContext.FixedChainContext
+}
+
+var errEvaluation = errors.New("evaluation failed")
+
+func (c *failingEvaluationContext) FetchEvaluation(tx []byte) (map[string]Redeemer.ExecutionUnits, error) {
+	return nil, errEvaluation
+}
+
+func TestEvaluationErrors(t *testing.T) {
+	cc := &failingEvaluationContext{FixedChainContext.InitFixedChainContext()}
+	redeemer := Redeemer.Redeemer{
+		Tag: Redeemer.SPEND,
+		Data: PlutusData.PlutusData{
+			TagNr:          121,
+			PlutusDataType: PlutusData.PlutusBytes,
+			Value:          []byte("Hello, World!")},
+	}
+	build := func() *apollo.Apollo {
+		return apollo.New(cc).SetChangeAddress(decoded_addr).AddLoadedUTxOs(testutils.InitUtxosDifferentiated()...).
+			CollectFrom(InputUtxo, redeemer).AttachV1Script([]byte("Hello, World!")).SetEstimationExUnitsRequired()
+	}
+	if _, err := build().Complete(); !errors.Is(err, errEvaluation) {
+		t.Errorf("expected the evaluation error, got %v", err)
+	}
+	if _, err := build().CompleteExact(200_000); !errors.Is(err, errEvaluation) {
+		t.Errorf("expected the evaluation error, got %v", err)
+	}
+}
+
+func TestSetWalletAsChangeAddressThroughWrappers(t *testing.T) {
+	build := func(cc Base.ChainContext) error {
+		_, err := apollo.New(observability.NewChainContext(cc, &observability.Recorder{}, "wrapped")).
+			SetWalletFromBech32("addr1qy99jvml0vafzdpy6lm6z52qrczjvs4k362gmr9v4hrrwgqk4xvegxwvtfsu5ck6s83h346nsgf6xu26dwzce9yvd8ysd2seyu").
+			SetWalletAsChangeAddress().
+			PayToAddress(decoded_addr, 1_000_000).
+			Complete()
+		return err
+	}
+	if err := build(&walletUtxoContext{FixedChainContext.InitFixedChainContext()}); err != nil {
+		t.Error("wallet UTxOs not loaded through the wrapper", err)
+	}
+	fixed := FixedChainContext.InitFixedChainContext()
+	if err := build(&fixed); err == nil {
+		t.Error("wallet UTxOs loaded from a context that does not load them")
+	}
+}
+
+// legacyContext only has the methods of Base.ChainContext, as
+// the contexts implemented outside of apollo.
+type legacyContext struct {
+	Base.ChainContext
+}
+
+func (c legacyContext) LoadsWalletUtxos() bool {
+	return true
+}
+
+func TestSetWalletAsChangeAddressWithoutFetchUtxos(t *testing.T) {
+	fixed := FixedChainContext.InitFixedChainContext()
+	cc := legacyContext{&fixed}
+	if _, ok := Base.ChainContext(cc).(Base.UtxoFetcher); ok {
+		t.Fatal("the legacy context must not fetch UTxOs")
+	}
+	_, err := apollo.New(cc).
+		SetWalletFromBech32("addr1qy99jvml0vafzdpy6lm6z52qrczjvs4k362gmr9v4hrrwgqk4xvegxwvtfsu5ck6s83h346nsgf6xu26dwzce9yvd8ysd2seyu").
+		SetWalletAsChangeAddress().
+		PayToAddress(decoded_addr, 1_000_000).
+		Complete()
+	if err != nil {
+		t.Error("wallet UTxOs not loaded with Utxos", err)
+	}
+}
+
+func TestInstrumentation(t *testing.T) {
+	fixed := apollo.NewEmptyBackend()
+	recorder := &observability.Recorder{}
+	cc := observability.NewChainContext(&fixed, recorder, "fixed")
+	var logs bytes.Buffer
+	decoded_addr, _ := Address.DecodeAddress("addr1qy99jvml0vafzdpy6lm6z52qrczjvs4k362gmr9v4hrrwgqk4xvegxwvtfsu5ck6s83h346nsgf6xu26dwzce9yvd8ysd2seyu")
+	apollob := apollo.New(cc).SetInstrumentation(recorder).SetLogger(slog.New(slog.NewJSONHandler(&logs, nil)))
+	apollob = apollob.SetChangeAddress(decoded_addr).AddLoadedUTxOs(testutils.InitUtxosCongested()...).
+		AddPayment(apollo.NewPayment("addr1qy99jvml0vafzdpy6lm6z52qrczjvs4k362gmr9v4hrrwgqk4xvegxwvtfsu5ck6s83h346nsgf6xu26dwzce9yvd8ysd2seya", 150_000_000, nil))
+	built, err := apollob.Complete()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	completes := recorder.Named("apollo.Complete")
+	if len(completes) != 1 || completes[0].Err != nil {
+		t.Fatalf("expected one Complete span, got %v", completes)
+	}
+	if fee, ok := completes[0].Attr("fee"); !ok || fee.Int64() != built.Fee {
+		t.Errorf("expected the fee on the Complete span, got %v", fee)
+	}
+	for _, phase := range []string{"apollo.coinSelection", "apollo.setCollateral", "apollo.updateExUnits", "apollo.addChangeAndFee"} {
+		spans := recorder.Named(phase)
+		if len(spans) != 1 || spans[0].Parent != "apollo.Complete" {
+			t.Errorf("expected %s to run in Complete, got %v", phase, spans)
+		}
+	}
+	params := recorder.Named("chain.GetProtocolParams")
+	if len(params) == 0 {
+		t.Error("expected the chain context calls to be recorded")
+	}
+	for _, span := range params {
+		if !strings.HasPrefix(span.Parent, "apollo.") {
+			t.Errorf("expected the chain context calls to run in the phases, got %v", span)
+		}
+	}
+	if !strings.Contains(logs.String(), `"msg":"apollo.addChangeAndFee"`) {
+		t.Errorf("expected the phases to be logged, got %s", logs.String())
+	}
+
+	// failed phases are reported
+	recorder = &observability.Recorder{}
+	_, err = apollo.New(&fixed).SetInstrumentation(recorder).SetChangeAddress(decoded_addr).
+		AddPayment(apollo.NewPayment("addr1qy99jvml0vafzdpy6lm6z52qrczjvs4k362gmr9v4hrrwgqk4xvegxwvtfsu5ck6s83h346nsgf6xu26dwzce9yvd8ysd2seya", 150_000_000, nil)).
+		Complete()
+	if spans := recorder.Named("apollo.coinSelection"); err == nil || len(spans) != 1 || spans[0].Err == nil {
+		t.Errorf("expected the coin selection to fail, got %v", spans)
+	}
+	recorder = &observability.Recorder{}
+	_, err = apollo.New(&failingEvaluationContext{FixedChainContext.InitFixedChainContext()}).SetInstrumentation(recorder).
+		SetChangeAddress(decoded_addr).AddLoadedUTxOs(testutils.InitUtxosDifferentiated()...).
+		CollectFrom(InputUtxo, Redeemer.Redeemer{Tag: Redeemer.SPEND}).AttachV1Script([]byte("Hello, World!")).
+		SetEstimationExUnitsRequired().Complete()
+	if spans := recorder.Named("apollo.updateExUnits"); !errors.Is(err, errEvaluation) || len(spans) == 0 || !errors.Is(spans[len(spans)-1].Err, errEvaluation) {
+		t.Errorf("expected the evaluation to fail, got %v", spans)
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	cc := apollo.NewEmptyBackend()
+	utxos := testutils.InitUtxos()
+	build := func(lovelace int, units ...apollo.Unit) *Errors.BuildError {
+		_, err := apollo.New(&cc).SetChangeAddress(decoded_addr).AddLoadedUTxOs(utxos...).
+			PayToAddress(decoded_addr, lovelace, units...).Complete()
+		var buildErr *Errors.BuildError
+		if !errors.As(err, &buildErr) {
+			t.Fatal("expected a BuildError", err)
+		}
+		return buildErr
+	}
+
+	buildErr := build(100_000_000)
+	if buildErr.Kind != Errors.INPUTS || !errors.Is(buildErr, Errors.ErrInsufficientInputs) || buildErr.Output != -1 {
+		t.Error("expected an inputs error", buildErr)
+	}
+	if len(buildErr.Shortfall) != 1 || buildErr.Shortfall[0].Unit() != "lovelace" || buildErr.Shortfall[0].Available != 55_000_000 ||
+		buildErr.Shortfall[0].Missing() < 45_000_000 {
+		t.Error("unexpected lovelace shortfall", buildErr.Shortfall)
+	}
+	if len(buildErr.Considered) != len(utxos) || buildErr.EstimatedFee <= 0 || !strings.Contains(buildErr.Error(), "not enough funds, missing") {
+		t.Error("unexpected error details", buildErr)
+	}
+
+	policy := "00000000000000000000000000000000000000000000000000000000"
+	buildErr = build(2_000_000, apollo.NewUnit(policy, "token0", 1000))
+	if len(buildErr.Shortfall) != 1 {
+		t.Fatal("expected a single asset shortfall", buildErr.Shortfall)
+	}
+	missing := buildErr.Shortfall[0]
+	if missing.PolicyId != policy || missing.AssetName != hex.EncodeToString([]byte("token0")) || missing.Required != 1000 || missing.Available != 100 {
+		t.Error("unexpected asset shortfall", missing)
+	}
+
+	// a single output carrying too many assets
+	assets := Asset.Asset[int64]{}
+	units := make([]apollo.Unit, 0)
+	for i := 0; i < 200; i++ {
+		name := fmt.Sprintf("token%027d", i)
+		assets[AssetName.NewAssetNameFromString(name)] = 1
+		units = append(units, apollo.NewUnit(policy, name, 1))
+	}
+	utxos = []UTxO.UTxO{{
+		Input: TransactionInput.TransactionInput{TransactionId: make([]byte, 32)},
+		Output: TransactionOutput.SimpleTransactionOutput(decoded_addr, Value.SimpleValue(100_000_000,
+			MultiAsset.MultiAsset[int64]{Policy.PolicyId{Value: policy}: assets})),
+	}}
+	buildErr = build(20_000_000, units...)
+	if buildErr.Kind != Errors.OUTPUT_SIZE || !errors.Is(buildErr, Errors.ErrOutputTooLarge) || buildErr.Output != 0 {
+		t.Error("expected the payment to be too large", buildErr)
+	}
+}
+
+func TestSelectAssetsHeldTogether(t *testing.T) {
+	cc := apollo.NewEmptyBackend()
+	policy := Policy.PolicyId{Value: "00000000000000000000000000000000000000000000000000000000"}
+	assetUtxo := func(index int, amounts map[string]int64) UTxO.UTxO {
+		assets := Asset.Asset[int64]{}
+		for name, amount := range amounts {
+			assets[AssetName.NewAssetNameFromString(name)] = amount
+		}
+		return UTxO.UTxO{
+			Input: TransactionInput.TransactionInput{TransactionId: make([]byte, 32), Index: index},
+			Output: TransactionOutput.SimpleTransactionOutput(decoded_addr,
+				Value.SimpleValue(5_000_000, MultiAsset.MultiAsset[int64]{policy: assets})),
+		}
+	}
+	units := []apollo.Unit{apollo.NewUnit(policy.Value, "tokenA", 5), apollo.NewUnit(policy.Value, "tokenB", 5)}
+
+	// the UTxO selected for the first asset also covers the second one
+	built, err := apollo.New(&cc).SetChangeAddress(decoded_addr).
+		AddLoadedUTxOs(assetUtxo(0, map[string]int64{"tokenA": 10, "tokenB": 10})).
+		PayToAddress(decoded_addr, 2_000_000, units...).Complete()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inputs := built.GetTx().TransactionBody.Inputs; len(inputs) != 1 {
+		t.Errorf("expected a single input, got %d", len(inputs))
+	}
+
+	// only the part of an asset not held by the selected UTxOs is looked for
+	built, err = apollo.New(&cc).SetChangeAddress(decoded_addr).
+		AddLoadedUTxOs(assetUtxo(0, map[string]int64{"tokenA": 5, "tokenB": 2}), assetUtxo(1, map[string]int64{"tokenB": 3})).
+		PayToAddress(decoded_addr, 2_000_000, units...).Complete()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inputs := built.GetTx().TransactionBody.Inputs; len(inputs) != 2 {
+		t.Errorf("expected both UTxOs as inputs, got %d", len(inputs))
+	}
+}
+
 func TestMapPDDecodeEncode(t *testing.T) {
 	val := "84ac00828258205af098c47e6539e03dce07fb9cd83ba630bd901e6943db894c70f656a480fa5a01825820ca0ebc40b02aeeaa0c8a84be94c9146bad85f249d6b98f840fd70f894a1de370020185a30058391155ff0e63efa0694e8065122c552e80c7b51768b7f20917af25752a7c3b8c8a100c16cf62b9c2bacc40453aaa67ced633993f2b4eec5b88e401821a006acfc0a1581c3c468b2a275a7df4b33625335232d4cfb45e651d289b2e0737856184a147457a43464f4e4101028201d81858b9d8799fa200a140a1401a001e848001a09fd8799f4040ffd8799f581c420000029ad9527271b1b1e3c27ee065c18df70a4a4cfc3093a41a444341584fffffd8799f581c3c468b2a275a7df4b33625335232d4cfb45e651d289b2e073785618447457a43464f4e41ffa3457072696365d87b9fd8799f1b00102a3f18d9852f1b06f05b59d3b20000ffff47656e6444617465d905009f1b000003bb2cc3d418ff49737461727444617465d905009f1b00000092f3973818ffa0ff82583901bb2ff620c0dd8b0adc19e6ffadea1a150c85d1b22d05e2db10c55c613b8c8a100c16cf62b9c2bacc40453aaa67ced633993f2b4eec5b88e4821a001e8480a1581c3c468b2a275a7df4b33625335232d4cfb45e651d289b2e0737856184a147457a43464f4e410182583901bb2ff620c0dd8b0adc19e6ffadea1a150c85d1b22d05e2db10c55c613b8c8a100c16cf62b9c2bacc40453aaa67ced633993f2b4eec5b88e4821a00198ef8a1581c95a427e384527065f2f8946f5e86320d0117839a5e98ea2c0b55fb00a14448554e541a01040cc3825839018b0fac6777891b925d646af1727d1ef288338f7966455a93ce31dfbc3120b7074a8521bcc9ea2f05e38cf8924ffb7bc871ebfaa35ae98bf21a000f424082583901bb2ff620c0dd8b0adc19e6ffadea1a150c85d1b22d05e2db10c55c613b8c8a100c16cf62b9c2bacc40453aaa67ced633993f2b4eec5b88e41a1d6593a1021a0004eec9031a0743ce5a075820411feee1738c31e1ac0e860abd4afd3c8288b6cd1abb051f21bff91a7a796f59081a0743cad609a1581c3c468b2a275a7df4b33625335232d4cfb45e651d289b2e0737856184a147457a43464f4e41020b5820041cf2ae5f43362e977280abbb0e9871c1c829a01bc59560d06d384b4d1bfc2c0d81825820b4bf6f7a29915cdf1aaac9d2112fc986bb3227d9cd04d7af418991cee23b07ed010e82581cbb2ff620c0dd8b0adc19e6ffadea1a150c85d1b22d05e2db10c55c61581c3b8c8a100c16cf62b9c2bacc40453aaa67ced633993f2b4eec5b88e41082583901bb2ff620c0dd8b0adc19e6ffadea1a150c85d1b22d05e2db10c55c613b8c8a100c16cf62b9c2bacc40453aaa67ced633993f2b4eec5b88e4821a03efae66b3581c078eafce5cd7edafdf63900edef2c1ea759e77f30ca81d6bbdeec924a14579756d6d691904f3581c115a3b670ea8b6b99d1c3d1d8041d7da9bd0b45532c24481cdbd9818a144746573741a000f4240581c1ddcb9c9de95361565392c5bdff64767492d61a96166cb16094e54bea1434f50541a02c5e3be581c279c909f348e533da5808898f87f9a14bb2c3dfbbacccd631d927a3fa144534e454b191585581c29d222ce763455e3d7a09a665ce554f00ac89d2e99a1a83d267170c6a1434d494e1a15b511cd581c32335fbb01744e526da8b9f97d759c2c07457c4c55eab98c372cdad6a14953505f494c7530634a02581c420000029ad9527271b1b1e3c27ee065c18df70a4a4cfc3093a41a44a14341584f1a133d12a2581c52162581184a457fad70470161179c5766f00237d4b67e0f1df1b4e6a1445452544c01581c562c0e6da43a062dde7d05b494d6b1a9d0d06a9d36131956c327b127a14953505f447a4a61657601581c5d16cc1a177b5d9ba9cfa9793b07e60f1fb70fea1f8aef064415d114a1434941471a018ece34581c826733e8e5d12c797f795be56b348982397e235d57074a992cb86b6ca14553544556451a0032b0bd581c8640914f83348a1b68d4b32205e2e4741455897e3e9edfe270d9069fa151576f6c6653796e6469636174653132343501581c8a1cfae21368b8bebbbed9800fec304e95cce39a2a57dc35e2e3ebaaa1444d494c4b01581c8fef2d34078659493ce161a6c7fba4b56afefa8535296a5743f69587a144414144411a0001e582581c95a427e384527065f2f8946f5e86320d0117839a5e98ea2c0b55fb00a14448554e541a0e6d983e581caf2e27f580f7f08e93190a81f72462f153026d06450924726645891ba144445249501a89e84f7a581cb3ad8b975d24235a43cb2a54d58c717ed9dd11560b4deba2273ffb1da1480014df104b5749431a3a06b367581cc0ee29a85b13209423b10447d3c2e6a50641a15c57770e27cb9d5073a14a57696e675269646572731a00470943581cdda5fdb1002f7389b33e036b6afee82a8189becb6cba852e8b79b4fba1480014df1047454e531a0047165c111a004c4b40a30380068159022659022301000032323232323232323232323232223232323232533300c3370e9000001099999199111980711299980a80089128008a99980919baf301630180010041300530180011300230170010012322230020033756602c0026ea4004dd7180900099199180511998011bab001232223002003374c002244a002464a66601e6ae8c0044894004488c00800ccc02894ccc03ccdd78009ba8480004894004488c00800c004004dd598091918091809180900098088021299980699807180780099111801001a4008264649319999806111299980a00089128008a9998089801180b800899111801001980b800899801801180b0009199119baf374e60300046e9cc06000530012bd8799fd8799f58205af098c47e6539e03dce07fb9cd83ba630bd901e6943db894c70f656a480fa5aff01ff00001001200116332300c22533301300114bd700998079801980b0009801180a8009180a180a8009bac30130051533300d3300e300f0013222300200332337029000000a40082930b0b0b180980118070009baa300f300e002300f300e001300e001223300422533300b00110051323330053011300f00223300933760601c602000600200420026004601a00200297adef6c602323002233002002001230022330020020015740ae6888cc0088cc0088cdc38010008a5013300124a0294494ccc00800448940044c94ccc00c0044c888c00800cdd69804180300109128009802000aab9f5573aae895d0918011baa0015573d0581840100d87980821a00014c221a01da9e55f5a21902a2a1636d7367816f44657868756e7465722054726164651902d1a178383363343638623261323735613764663462333336323533333532333264346366623435653635316432383962326530373337383536313834a167457a43464f4e41a664616c676fa3646c696e6b82784068747470733a2f2f6170702e61786f2e74726164652f636f6d706f7365722f61326634383739302d393432362d343361372d383134372d61656135313761386363663761646e616d656c536d617274204d61726b65746b6465736372697074696f6e60646e616d6577536d617274204d61726b6574203c4144412c2041584f3e657374726174a3646c696e6b782d68747470733a2f2f6170702e61786f2e74726164652f737472617465676965732f766965772f457a43464f4e41646e616d65606b6465736372697074696f6e60666173736574738260783e343230303030303239616439353237323731623162316533633237656530363563313864663730613461346366633330393361343161343434313538346667776562736974657168747470733a2f2f61786f2e7472616465696d656469615479706569696d6167652f706e67"
 	tx := Transaction.Transaction{}
@@ -1251,3 +1496,513 @@ func TestPlutusBigInt(t *testing.T) {
 		t.Error("Tx is not correct")
 	}
 }
+
+func TestReferenceScriptFee(t *testing.T) {
+	scriptRef := PlutusData.ScriptRef{}
+	scriptRef.Script.Script = make([]byte, 30000)
+	refUtxo := UTxO.UTxO{
+		Input: TransactionInput.TransactionInput{
+			TransactionId: []byte("a5d1f7c223dc88bb41474af23b685e0247307e94e715ef5e62f325ac94f73056"),
+			Index:         0,
+		},
+		Output: TransactionOutput.TransactionOutput{
+			IsPostAlonzo: true,
+			PostAlonzo: TransactionOutput.TransactionOutputAlonzo{
+				Address:   decoded_addr,
+				Amount:    Value.PureLovelaceValue(20_000_000).ToAlonzoValue(),
+				ScriptRef: &scriptRef,
+			},
+		},
+	}
+	build := func(reference func(*apollo.Apollo) *apollo.Apollo) int64 {
+		cc := apollo.NewEmptyBackend()
+		apollob := apollo.New(&cc)
+		apollob = apollob.SetWalletFromBech32("addr1qy99jvml0vafzdpy6lm6z52qrczjvs4k362gmr9v4hrrwgqk4xvegxwvtfsu5ck6s83h346nsgf6xu26dwzce9yvd8ysd2seyu").SetWalletAsChangeAddress().AddInput(InputUtxo).AddCollateral(collateralUtxo)
+		built, err := reference(apollob).Complete()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return built.GetTx().TransactionBody.Fee
+	}
+	withoutScript := build(func(b *apollo.Apollo) *apollo.Apollo {
+		return b.AddReferenceInput(hex.EncodeToString(refUtxo.Input.TransactionId), 0)
+	})
+	withScript := build(func(b *apollo.Apollo) *apollo.Apollo {
+		return b.AddLoadedReferenceInput(refUtxo)
+	})
+	// 25600 bytes at 15 lovelace plus 4400 bytes at 18 lovelace
+	if diff := withScript - withoutScript; diff < 463200 || diff > 463200+1000 {
+		t.Error("Reference script fee not charged", withoutScript, withScript)
+	}
+}
+
+func collateralTestUtxo(addr Address.Address, index int, lovelace int64) UTxO.UTxO {
+	return UTxO.UTxO{
+		Input: TransactionInput.TransactionInput{
+			TransactionId: make([]byte, 32),
+			Index:         index,
+		},
+		Output: TransactionOutput.SimpleTransactionOutput(addr, Value.PureLovelaceValue(lovelace)),
+	}
+}
+
+func buildWithCollateral(cc *FixedChainContext.FixedChainContext, utxos []UTxO.UTxO, configure func(*apollo.Apollo) *apollo.Apollo) (*apollo.Apollo, error) {
+	scriptAddr, _ := Address.NewEnterpriseAddress(Address.NewScriptCredential(make([]byte, 28)), constants.MAINNET)
+	scriptUtxo := collateralTestUtxo(scriptAddr, 99, 15_000_000)
+	redeemer := Redeemer.Redeemer{
+		Tag:  Redeemer.SPEND,
+		Data: PlutusData.PlutusData{TagNr: 121, PlutusDataType: PlutusData.PlutusArray, Value: PlutusData.PlutusIndefArray{}},
+	}
+	apollob := apollo.New(cc).
+		SetChangeAddress(decoded_addr).
+		AddLoadedUTxOs(utxos...).
+		CollectFrom(scriptUtxo, redeemer).
+		AttachV2Script([]byte("collateral"))
+	if configure != nil {
+		apollob = configure(apollob)
+	}
+	return apollob.Complete()
+}
+
+func TestSkipReferencedWitnessScripts(t *testing.T) {
+	scriptRef := PlutusData.NewScriptRef(2, []byte("collateral"))
+	refUtxo := collateralTestUtxo(decoded_addr, 7, 5_000_000)
+	refUtxo.Output = TransactionOutput.TransactionOutput{
+		IsPostAlonzo: true,
+		PostAlonzo: TransactionOutput.TransactionOutputAlonzo{
+			Address:   decoded_addr,
+			Amount:    Value.PureLovelaceValue(5_000_000).ToAlonzoValue(),
+			ScriptRef: &scriptRef,
+		},
+	}
+	utxos := []UTxO.UTxO{collateralTestUtxo(decoded_addr, 0, 8_000_000)}
+	cc := apollo.NewEmptyBackend()
+	attached, err := buildWithCollateral(&cc, utxos, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	referenced, err := buildWithCollateral(&cc, utxos, func(b *apollo.Apollo) *apollo.Apollo {
+		return b.AddLoadedReferenceInput(refUtxo)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attached.GetTx().TransactionWitnessSet.PlutusV2Script) != 1 {
+		t.Fatal("attached script missing from the witness set")
+	}
+	tx := referenced.GetTx()
+	if len(tx.TransactionWitnessSet.PlutusV2Script) != 0 {
+		t.Error("script provided by reference left in the witness set")
+	}
+	if len(tx.TransactionBody.Collateral) == 0 {
+		t.Error("collateral dropped with the witness script")
+	}
+	if !bytes.Equal(tx.TransactionBody.ScriptDataHash, attached.GetTx().TransactionBody.ScriptDataHash) {
+		t.Error("script data hash changed", tx.TransactionBody.ScriptDataHash, attached.GetTx().TransactionBody.ScriptDataHash)
+	}
+}
+
+func TestCollateralFollowsRedeemers(t *testing.T) {
+	cc := apollo.NewEmptyBackend()
+	utxo := collateralTestUtxo(decoded_addr, 0, 8_000_000)
+	// a reference input only read for its datum runs no script
+	datumRead, err := apollo.New(&cc).
+		SetChangeAddress(decoded_addr).
+		AddLoadedUTxOs(utxo).
+		PayToAddress(decoded_addr, 2_000_000).
+		AddLoadedReferenceInput(collateralTestUtxo(decoded_addr, 7, 5_000_000)).
+		Complete()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(datumRead.GetTx().TransactionBody.Collateral) != 0 {
+		t.Error("collateral added without a script to run")
+	}
+
+	// a script spent with its own ScriptRef, neither attached nor referenced
+	scriptRef := PlutusData.NewScriptRef(2, []byte("collateral"))
+	scriptAddr, _ := Address.NewEnterpriseAddress(Address.NewScriptCredential(make([]byte, 28)), constants.MAINNET)
+	scriptUtxo := collateralTestUtxo(scriptAddr, 99, 15_000_000)
+	scriptUtxo.Output = TransactionOutput.TransactionOutput{
+		IsPostAlonzo: true,
+		PostAlonzo: TransactionOutput.TransactionOutputAlonzo{
+			Address:   scriptAddr,
+			Amount:    Value.PureLovelaceValue(15_000_000).ToAlonzoValue(),
+			ScriptRef: &scriptRef,
+		},
+	}
+	selfReferenced, err := apollo.New(&cc).
+		SetChangeAddress(decoded_addr).
+		AddLoadedUTxOs(utxo).
+		CollectFrom(scriptUtxo, Redeemer.Redeemer{
+			Tag:  Redeemer.SPEND,
+			Data: PlutusData.PlutusData{TagNr: 121, PlutusDataType: PlutusData.PlutusArray, Value: PlutusData.PlutusIndefArray{}},
+		}).
+		Complete()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(selfReferenced.GetTx().TransactionBody.Collateral) == 0 {
+		t.Error("no collateral for a script spent with its own ScriptRef")
+	}
+}
+
+func TestSkipReferencedNativeScripts(t *testing.T) {
+	script := NativeScript.NewScriptPubKey(decoded_addr.PaymentPart)
+	encoded, err := cbor.Marshal(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scriptRef := PlutusData.NewScriptRef(0, encoded)
+	refUtxo := collateralTestUtxo(decoded_addr, 7, 5_000_000)
+	refUtxo.Output = TransactionOutput.TransactionOutput{
+		IsPostAlonzo: true,
+		PostAlonzo: TransactionOutput.TransactionOutputAlonzo{
+			Address:   decoded_addr,
+			Amount:    Value.PureLovelaceValue(5_000_000).ToAlonzoValue(),
+			ScriptRef: &scriptRef,
+		},
+	}
+	cc := apollo.NewEmptyBackend()
+	build := func(configure func(*apollo.Apollo) *apollo.Apollo) *apollo.Apollo {
+		apollob, err := apollo.New(&cc).
+			SetChangeAddress(decoded_addr).
+			AddLoadedUTxOs(collateralTestUtxo(decoded_addr, 0, 8_000_000)).
+			PayToAddress(decoded_addr, 2_000_000).
+			AttachNativeScript(script)
+		if err != nil {
+			t.Fatal(err)
+		}
+		built, err := configure(apollob).Complete()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return built
+	}
+	attached := build(func(b *apollo.Apollo) *apollo.Apollo { return b })
+	if len(attached.GetTx().TransactionWitnessSet.NativeScripts) != 1 {
+		t.Fatal("attached native script missing from the witness set")
+	}
+	referenced := build(func(b *apollo.Apollo) *apollo.Apollo { return b.AddLoadedReferenceInput(refUtxo) })
+	if len(referenced.GetTx().TransactionWitnessSet.NativeScripts) != 0 {
+		t.Error("native script provided by reference left in the witness set")
+	}
+}
+
+func TestDeployReferenceScript(t *testing.T) {
+	cc := apollo.NewEmptyBackend()
+	script := PlutusData.NewScriptRef(2, []byte("collateral"))
+	deployed, input, err := apollo.New(&cc).
+		SetChangeAddress(decoded_addr).
+		AddLoadedUTxOs(collateralTestUtxo(decoded_addr, 0, 20_000_000)).
+		DeployReferenceScript(script, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := deployed.GetTx().TransactionBody
+	txHash, _ := body.Hash()
+	if !bytes.Equal(input.TransactionId, txHash) {
+		t.Fatal("input does not point to the deploying transaction")
+	}
+	output := body.Outputs[input.Index]
+	burnAddress, _ := apollo.AlwaysFailsAddress(constants.MAINNET)
+	if output.GetAddress().String() != burnAddress.String() {
+		t.Error("script not locked at the burn address", output.GetAddress().String())
+	}
+	if output.GetScriptRef().Size() != script.Size() {
+		t.Error("output does not carry the script")
+	}
+	if minLovelace := Utils.MinLovelacePostAlonzo(output, &cc); output.GetValue().GetCoin() != minLovelace {
+		t.Error("output lovelace is not the minimum", output.GetValue().GetCoin(), minLovelace)
+	}
+
+	utxos := []UTxO.UTxO{collateralTestUtxo(decoded_addr, 0, 8_000_000)}
+	built, err := buildWithCollateral(&cc, utxos, func(b *apollo.Apollo) *apollo.Apollo {
+		return b.AddLoadedReferenceInput(UTxO.UTxO{Input: input, Output: output})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(built.GetTx().TransactionWitnessSet.PlutusV2Script) != 0 {
+		t.Error("deployed script left in the witness set")
+	}
+}
+
+func TestCollateralFromProtocolParameters(t *testing.T) {
+	cc := apollo.NewEmptyBackend()
+	utxos := []UTxO.UTxO{collateralTestUtxo(decoded_addr, 0, 8_000_000)}
+	built, err := buildWithCollateral(&cc, utxos, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := built.GetTx().TransactionBody
+	if len(body.Collateral) != 1 || !body.Collateral[0].EqualTo(utxos[0].Input) {
+		t.Fatal("unexpected collateral", body.Collateral)
+	}
+	if int64(body.TotalCollateral) != cc.GetProtocolParams().MinCollateral(body.Fee) {
+		t.Error("total collateral is not collateralPercent of the fee", body.TotalCollateral, body.Fee)
+	}
+	if body.CollateralReturn == nil || body.CollateralReturn.Lovelace() != 8_000_000-int64(body.TotalCollateral) {
+		t.Error("unexpected collateral return", body.CollateralReturn)
+	}
+}
+
+func TestMultiUtxoCollateral(t *testing.T) {
+	cc := apollo.NewEmptyBackend()
+	returnAddr, _ := Address.DecodeAddress("addr1vx2fxv2umyhttkxyxp8x0dlpdt3k6cwng5pxj3jhsydzers66hrl8")
+	utxos := []UTxO.UTxO{
+		collateralTestUtxo(decoded_addr, 0, 1_000_000),
+		collateralTestUtxo(decoded_addr, 1, 1_000_000),
+		collateralTestUtxo(decoded_addr, 2, 1_000_000),
+	}
+	built, err := buildWithCollateral(&cc, utxos, func(b *apollo.Apollo) *apollo.Apollo {
+		return b.SetCollateralReturnAddress(returnAddr)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := built.GetTx().TransactionBody
+	if len(body.Collateral) != 2 {
+		t.Fatal("expected two collateral inputs", body.Collateral)
+	}
+	if body.CollateralReturn == nil || body.CollateralReturn.Lovelace() != 2_000_000-int64(body.TotalCollateral) {
+		t.Fatal("unexpected collateral return", body.CollateralReturn)
+	}
+	if addr := body.CollateralReturn.GetAddress(); !addr.Equal(&returnAddr) {
+		t.Error("collateral return not sent to the configured address", addr.String())
+	}
+
+	cc.ProtocolParams.MaxCollateralInuts = 1
+	_, err = buildWithCollateral(&cc, utxos, nil)
+	if err == nil || !strings.Contains(err.Error(), "NoCollateral") {
+		t.Error("expected a collateral error with a single collateral input", err)
+	}
+}
+
+func TestCollateralKeepsChangeMinimum(t *testing.T) {
+	cc := apollo.NewEmptyBackend()
+	utxos := []UTxO.UTxO{
+		collateralTestUtxo(decoded_addr, 0, 1_500_000),
+		collateralTestUtxo(decoded_addr, 1, 1_500_000),
+	}
+	// the script input alone leaves a change close to its minimum,
+	// which the fee of the final collateral pushes below it
+	_, err := buildWithCollateral(&cc, utxos, func(b *apollo.Apollo) *apollo.Apollo {
+		return b.PayToAddress(decoded_addr, 13_590_000)
+	})
+	var buildErr *Errors.BuildError
+	if !errors.As(err, &buildErr) || !errors.Is(err, Errors.ErrInsufficientInputs) {
+		t.Fatal("expected an inputs BuildError", err)
+	}
+	if buildErr.Output != 1 || len(buildErr.Shortfall) != 1 || buildErr.Shortfall[0].Missing() <= 0 {
+		t.Error("unexpected change shortfall", buildErr.Output, buildErr.Shortfall)
+	}
+
+	built, err := buildWithCollateral(&cc, utxos, func(b *apollo.Apollo) *apollo.Apollo {
+		return b.PayToAddress(decoded_addr, 13_000_000)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	change := built.GetTx().TransactionBody.Outputs[1]
+	if change.Lovelace() < Utils.MinLovelacePostAlonzo(change, &cc) {
+		t.Error("the change is below its minimum lovelace", change.Lovelace())
+	}
+}
+
+func TestNoCollateral(t *testing.T) {
+	cc := apollo.NewEmptyBackend()
+	_, err := buildWithCollateral(&cc, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "no key locked UTxO") {
+		t.Error("expected a collateral error without key locked UTxOs", err)
+	}
+	var buildErr *Errors.BuildError
+	if !errors.As(err, &buildErr) || !errors.Is(err, Errors.ErrNoCollateral) || buildErr.Kind != Errors.COLLATERAL {
+		t.Fatal("expected a collateral BuildError", err)
+	}
+	if len(buildErr.Shortfall) != 1 || buildErr.Shortfall[0].Available != 0 || buildErr.Shortfall[0].Missing() <= 0 || buildErr.EstimatedFee <= 0 {
+		t.Error("unexpected collateral shortfall", buildErr.Shortfall, buildErr.EstimatedFee)
+	}
+	if len(buildErr.Excluded) != 1 || buildErr.Excluded[0].Reason != Errors.EXCLUDED_SCRIPT_LOCKED {
+		t.Error("expected the script input to be excluded from the collateral", buildErr.Excluded)
+	}
+	// a UTxO whose lovelace does not cover the minimum of its assets
+	assets := Asset.Asset[int64]{}
+	for i := 0; i < 20; i++ {
+		assets[AssetName.NewAssetNameFromString(fmt.Sprintf("token%027d", i))] = 1
+	}
+	dust := collateralTestUtxo(decoded_addr, 0, 300_000)
+	dust.Output = TransactionOutput.SimpleTransactionOutput(decoded_addr, Value.SimpleValue(300_000,
+		MultiAsset.MultiAsset[int64]{Policy.PolicyId{Value: "00000000000000000000000000000000000000000000000000000000"}: assets}))
+	_, err = buildWithCollateral(&cc, []UTxO.UTxO{dust, collateralTestUtxo(decoded_addr, 1, 200_000)}, nil)
+	if !errors.As(err, &buildErr) || buildErr.Kind != Errors.COLLATERAL {
+		t.Fatal("expected a collateral BuildError", err)
+	}
+	reasons := map[string]string{}
+	for _, excluded := range buildErr.Excluded {
+		reasons[excluded.UTxO.GetKey()] = excluded.Reason
+	}
+	if reasons[dust.GetKey()] != Errors.EXCLUDED_BELOW_MIN_ADA || len(buildErr.Considered) != 1 {
+		t.Error("expected the UTxO below the minimum of its assets to be excluded", buildErr.Excluded)
+	}
+	_, err = buildWithCollateral(&cc, nil, func(b *apollo.Apollo) *apollo.Apollo {
+		return b.AddCollateral(collateralTestUtxo(decoded_addr, 0, 200_000))
+	})
+	if err == nil || !strings.Contains(err.Error(), "added collateral") {
+		t.Error("expected an error for insufficient added collateral", err)
+	}
+}
+
+func TestBuildReport(t *testing.T) {
+	cc := apollo.NewEmptyBackend()
+	utxos := []UTxO.UTxO{collateralTestUtxo(decoded_addr, 0, 8_000_000)}
+	built, err := buildWithCollateral(&cc, utxos, func(b *apollo.Apollo) *apollo.Apollo {
+		return b.EnableBuildReport()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := built.GetBuildReport()
+	if report == nil {
+		t.Fatal("expected a build report")
+	}
+	if len(report.Inputs) != 1 || report.Inputs[0].Rule != apollo.SELECTED_PRESELECTED || report.Inputs[0].Index != 99 {
+		t.Error("expected the collected UTxO as preselected input", report.Inputs)
+	}
+	if len(report.Collateral) != 1 || report.Collateral[0].Rule != apollo.SELECTED_AUTO_COLLATERAL {
+		t.Error("expected the automatically selected collateral", report.Collateral)
+	}
+	if len(report.Redeemers) != 1 || report.Redeemers[0].Purpose != "spend" || report.Redeemers[0].ExUnits.MemShare <= 0 ||
+		report.ExUnits.Steps != report.Redeemers[0].ExUnits.Steps {
+		t.Error("unexpected execution units", report.Redeemers, report.ExUnits)
+	}
+	fee := report.Fee
+	if fee.Fee != built.GetTx().TransactionBody.Fee || fee.ScriptFee <= 0 || fee.Margin != 10_000 ||
+		fee.SizeFee+fee.ScriptFee+fee.ReferenceScriptFee+fee.Margin+fee.Padding != fee.Fee {
+		t.Error("the fee breakdown does not add up", fee)
+	}
+	if report.TxSize <= 0 || report.TxSize > report.MaxTxSize {
+		t.Error("unexpected transaction size", report.TxSize, report.MaxTxSize)
+	}
+	if len(report.Outputs) != 1 || !report.Outputs[0].Change || report.Outputs[0].Lovelace < report.Outputs[0].MinLovelace ||
+		report.Change.Split || len(report.Change.Outputs) != 1 {
+		t.Error("expected a single change output", report.Outputs, report.Change)
+	}
+	encoded, err := report.JSON()
+	if err != nil || !strings.Contains(string(encoded), `"rule": "auto_collateral"`) {
+		t.Error("unexpected JSON report", string(encoded), err)
+	}
+
+	// no report unless enabled
+	if built, _ = buildWithCollateral(&cc, utxos, nil); built.GetBuildReport() != nil {
+		t.Error("expected no report without EnableBuildReport")
+	}
+
+	policy := "00000000000000000000000000000000000000000000000000000000"
+	built, err = apollo.New(&cc).EnableBuildReport().SetChangeAddress(decoded_addr).AddLoadedUTxOs(testutils.InitUtxos()...).
+		PayToAddress(decoded_addr, 2_000_000, apollo.NewUnit(policy, "token0", 50)).Complete()
+	if err != nil {
+		t.Fatal(err)
+	}
+	report = built.GetBuildReport()
+	if report.Inputs[0].Rule != apollo.SELECTED_ASSET || report.Inputs[0].Unit != policy+hex.EncodeToString([]byte("token0")) {
+		t.Error("expected the UTxO holding token0 to be selected for it", report.Inputs)
+	}
+
+	// change with too many assets is split
+	assets := Asset.Asset[int64]{}
+	for i := 0; i < 200; i++ {
+		assets[AssetName.NewAssetNameFromString(fmt.Sprintf("token%027d", i))] = 1
+	}
+	utxos = []UTxO.UTxO{{
+		Input: TransactionInput.TransactionInput{TransactionId: make([]byte, 32)},
+		Output: TransactionOutput.SimpleTransactionOutput(decoded_addr, Value.SimpleValue(100_000_000,
+			MultiAsset.MultiAsset[int64]{Policy.PolicyId{Value: policy}: assets})),
+	}}
+	built, err = apollo.New(&cc).EnableBuildReport().SetChangeAddress(decoded_addr).AddLoadedUTxOs(utxos...).
+		PayToAddress(decoded_addr, 2_000_000).Complete()
+	if err != nil {
+		t.Fatal(err)
+	}
+	report = built.GetBuildReport()
+	if report.Inputs[0].Rule != apollo.SELECTED_LOVELACE || !report.Change.Split || len(report.Change.Outputs) < 2 ||
+		len(report.Outputs) != len(report.Change.Outputs)+1 || report.Outputs[0].Change {
+		t.Error("expected the change to be split", report.Inputs, report.Change)
+	}
+}
+
+// datumContext resolves the datums it knows by their hash.
+type datumContext struct {
+	FixedChainContext.FixedChainContext
+	datums   map[string]PlutusData.PlutusData
+	resolved int
+}
+
+func (c *datumContext) ResolveDatum(datumHash string) (*PlutusData.PlutusData, error) {
+	c.resolved++
+	datum, ok := c.datums[datumHash]
+	if !ok {
+		return nil, Base.ErrDatumNotFound
+	}
+	return &datum, nil
+}
+
+func TestResolveDatums(t *testing.T) {
+	datum := PlutusData.PlutusData{TagNr: 121, PlutusDataType: PlutusData.PlutusArray, Value: PlutusData.PlutusIndefArray{}}
+	hash, _ := PlutusData.PlutusDataHash(&datum)
+	datumHash := hex.EncodeToString(hash.Payload)
+	scriptAddr, _ := Address.NewEnterpriseAddress(Address.NewScriptCredential(make([]byte, 28)), constants.MAINNET)
+	datumOption := PlutusData.DatumOptionHash(hash.Payload)
+	scriptUtxo := UTxO.UTxO{
+		Input: TransactionInput.TransactionInput{TransactionId: make([]byte, 32), Index: 99},
+		Output: TransactionOutput.TransactionOutput{IsPostAlonzo: true, PostAlonzo: TransactionOutput.TransactionOutputAlonzo{
+			Address: scriptAddr,
+			Amount:  Value.PureLovelaceValue(15_000_000).ToAlonzoValue(),
+			Datum:   &datumOption,
+		}},
+	}
+	redeemer := Redeemer.Redeemer{
+		Tag:  Redeemer.SPEND,
+		Data: PlutusData.PlutusData{TagNr: 121, PlutusDataType: PlutusData.PlutusArray, Value: PlutusData.PlutusIndefArray{}},
+	}
+	build := func(cc *datumContext, attached ...PlutusData.PlutusData) (*apollo.Apollo, error) {
+		apollob := apollo.New(cc).SetChangeAddress(decoded_addr).
+			AddLoadedUTxOs(collateralTestUtxo(decoded_addr, 0, 8_000_000)).
+			CollectFrom(scriptUtxo, redeemer).
+			AttachV2Script([]byte("datum"))
+		for _, datum := range attached {
+			datum := datum
+			apollob = apollob.AttachDatum(&datum)
+		}
+		return apollob.Complete()
+	}
+
+	cc := &datumContext{FixedChainContext: apollo.NewEmptyBackend(), datums: map[string]PlutusData.PlutusData{datumHash: datum}}
+	built, err := build(cc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	datums := built.GetTx().TransactionWitnessSet.PlutusData
+	if len(datums) != 1 || cc.resolved != 1 {
+		t.Fatal("expected the resolved datum in the witness set", datums, cc.resolved)
+	}
+	if witnessHash, _ := PlutusData.PlutusDataHash(&datums[0]); hex.EncodeToString(witnessHash.Payload) != datumHash {
+		t.Error("unexpected datum in the witness set")
+	}
+
+	// an attached datum is not resolved again
+	cc.resolved = 0
+	built, err = build(cc, datum)
+	if err != nil || cc.resolved != 0 || len(built.GetTx().TransactionWitnessSet.PlutusData) != 1 {
+		t.Error("expected the attached datum only", err, cc.resolved)
+	}
+
+	cc.datums = map[string]PlutusData.PlutusData{}
+	if _, err = build(cc); !errors.Is(err, Base.ErrDatumNotFound) {
+		t.Error("expected the datum not to be found", err)
+	}
+	cc.datums[datumHash] = PlutusData.PlutusData{TagNr: 122, PlutusDataType: PlutusData.PlutusArray, Value: PlutusData.PlutusIndefArray{}}
+	if _, err = build(cc); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Error("expected a datum not matching its hash to be rejected", err)
+	}
+}
@@ -0,0 +1,304 @@
+package apollo
+
+import (
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/Salvionied/apollo/serialization/Redeemer"
+	"github.com/Salvionied/apollo/serialization/UTxO"
+	"github.com/Salvionied/apollo/txBuilding/Utils"
+)
+
+/*
+*
+
+	Rules by which the inputs and the collateral of a
+	transaction are selected.
+*/
+const (
+	// SELECTED_PRESELECTED: added with AddInput, ConsumeUTxO or CollectFrom.
+	SELECTED_PRESELECTED = "preselected"
+	// SELECTED_ASSET: selected to cover a requested asset.
+	SELECTED_ASSET = "asset"
+	// SELECTED_LOVELACE: selected, largest first, to cover the
+	// requested lovelace and the estimated fee.
+	SELECTED_LOVELACE = "lovelace"
+	// SELECTED_CHANGE: selected to cover the fee and the minimum
+	// lovelace of the change.
+	SELECTED_CHANGE = "change"
+	// SELECTED_COLLATERAL: added with AddCollateral.
+	SELECTED_COLLATERAL = "collateral"
+	// SELECTED_AUTO_COLLATERAL: key locked UTxO selected as
+	// collateral, pure lovelace ones first.
+	SELECTED_AUTO_COLLATERAL = "auto_collateral"
+)
+
+type SelectedUTxO struct {
+	TransactionId string `json:"transaction_id"`
+	Index         int    `json:"index"`
+	Lovelace      int64  `json:"lovelace"`
+	Rule          string `json:"rule"`
+	// Unit is the asset the UTxO was selected for, with SELECTED_ASSET.
+	Unit string `json:"unit,omitempty"`
+}
+
+type FeeReport struct {
+	Fee int64 `json:"fee"`
+	// TxSize is the size of the transaction with fake witnesses
+	// the fee is computed for.
+	TxSize  int   `json:"tx_size"`
+	SizeFee int64 `json:"size_fee"`
+	// ScriptFee is the cost of the execution units.
+	ScriptFee           int64 `json:"script_fee"`
+	ReferenceScriptSize int   `json:"reference_script_size"`
+	ReferenceScriptFee  int64 `json:"reference_script_fee"`
+	// Margin is added to every estimate by Utils.Fee.
+	Margin  int64 `json:"margin"`
+	Padding int64 `json:"padding"`
+}
+
+type ExUnitsReport struct {
+	Mem        int64   `json:"mem"`
+	Steps      int64   `json:"steps"`
+	MemShare   float64 `json:"mem_share"`
+	StepsShare float64 `json:"steps_share"`
+}
+
+type RedeemerReport struct {
+	Purpose string        `json:"purpose"`
+	Index   int           `json:"index"`
+	ExUnits ExUnitsReport `json:"ex_units"`
+}
+
+type OutputReport struct {
+	Index       int    `json:"index"`
+	Address     string `json:"address"`
+	Lovelace    int64  `json:"lovelace"`
+	MinLovelace int64  `json:"min_lovelace"`
+	Change      bool   `json:"change"`
+}
+
+type ChangeReport struct {
+	// Outputs are the indexes of the change outputs.
+	Outputs []int `json:"outputs"`
+	// Split is true when the change exceeded maxValSize and was
+	// split by splitPayments.
+	Split  bool   `json:"split"`
+	Reason string `json:"reason"`
+}
+
+/*
+*
+
+	BuildReport explains how a transaction was built: the rules
+	by which its inputs and collateral were selected, the parts
+	of its fee, the execution units of its redeemers against the
+	transaction limits, the minimum lovelace of its outputs, how
+	the change was made and its size against maxTxSize.
+*/
+type BuildReport struct {
+	Inputs     []SelectedUTxO   `json:"inputs"`
+	Collateral []SelectedUTxO   `json:"collateral,omitempty"`
+	Fee        FeeReport        `json:"fee"`
+	Redeemers  []RedeemerReport `json:"redeemers,omitempty"`
+	ExUnits    ExUnitsReport    `json:"ex_units"`
+	Outputs    []OutputReport   `json:"outputs"`
+	Change     ChangeReport     `json:"change"`
+	// TxSize is the size of the transaction with fake witnesses.
+	TxSize    int `json:"tx_size"`
+	MaxTxSize int `json:"max_tx_size"`
+}
+
+/*
+*
+
+	JSON returns the indented JSON encoding of the report.
+
+	Returns:
+		[]byte: The JSON-encoded report.
+		error: An error if the encoding fails.
+*/
+func (r *BuildReport) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+// buildNotes are the decisions recorded while building, nil
+// when no report is requested.
+type buildNotes struct {
+	selections map[string]SelectedUTxO
+	change     ChangeReport
+}
+
+/*
+*
+
+	EnableBuildReport records how the next transaction is built,
+	the report being returned by GetBuildReport once Complete
+	succeeds.
+
+	Returns:
+		*Apollo: A pointer to the Apollo object to support method chaining.
+*/
+func (b *Apollo) EnableBuildReport() *Apollo {
+	b.notes = &buildNotes{selections: make(map[string]SelectedUTxO)}
+	return b
+}
+
+/*
+*
+
+	GetBuildReport returns the report of the completed transaction.
+
+	Returns:
+		*BuildReport: The report, nil if it was not enabled with
+			EnableBuildReport or Complete did not succeed.
+*/
+func (b *Apollo) GetBuildReport() *BuildReport {
+	return b.report
+}
+
+/*
+*
+
+	noteSelection records the rule by which a UTxO was selected.
+
+	Params:
+		utxo (UTxO.UTxO): The selected UTxO.
+		rule (string): The rule, one of the SELECTED_ constants.
+		unit (string): The asset it was selected for, if any.
+*/
+func (b *Apollo) noteSelection(utxo UTxO.UTxO, rule string, unit string) {
+	if b.notes == nil {
+		return
+	}
+	b.notes.selections[utxo.GetKey()] = SelectedUTxO{Rule: rule, Unit: unit}
+}
+
+/*
+*
+
+	noteChange records the change outputs added by addChangeAndFee.
+
+	Params:
+		first (int): The index of the first change output.
+		count (int): The number of change outputs.
+		reason (string): Why the change was split, empty if it was not.
+*/
+func (b *Apollo) noteChange(first int, count int, reason string) {
+	if b.notes == nil {
+		return
+	}
+	outputs := make([]int, 0, count)
+	for i := first; i < first+count; i++ {
+		outputs = append(outputs, i)
+	}
+	b.notes.change = ChangeReport{Outputs: outputs, Split: reason != "", Reason: reason}
+}
+
+func (b *Apollo) selectedUtxos(utxos []UTxO.UTxO, defaultRule string) []SelectedUTxO {
+	selected := make([]SelectedUTxO, 0, len(utxos))
+	for _, utxo := range utxos {
+		entry, ok := b.notes.selections[utxo.GetKey()]
+		if !ok {
+			entry.Rule = defaultRule
+		}
+		entry.TransactionId = hex.EncodeToString(utxo.Input.TransactionId)
+		entry.Index = utxo.Input.Index
+		entry.Lovelace = utxo.Output.GetValue().GetCoin()
+		selected = append(selected, entry)
+	}
+	return selected
+}
+
+func share(used int64, max int64) float64 {
+	if max <= 0 {
+		return 0
+	}
+	return float64(used) / float64(max)
+}
+
+/*
+*
+
+	buildReport assembles the report of the completed transaction
+	from the recorded decisions.
+
+	Returns:
+		*BuildReport: The report.
+*/
+func (b *Apollo) buildReport() *BuildReport {
+	pp := b.Context.GetProtocolParams()
+	report := BuildReport{
+		Inputs:    b.selectedUtxos(b.preselectedUtxos, SELECTED_PRESELECTED),
+		Redeemers: make([]RedeemerReport, 0, len(b.redeemers)),
+		Outputs:   make([]OutputReport, 0, len(b.tx.TransactionBody.Outputs)),
+		Change:    b.notes.change,
+		MaxTxSize: pp.MaxTxSize,
+	}
+	collateralRule := SELECTED_COLLATERAL
+	if b.autoCollateral {
+		collateralRule = SELECTED_AUTO_COLLATERAL
+	}
+	if len(b.collaterals) > 0 {
+		report.Collateral = b.selectedUtxos(b.collaterals, collateralRule)
+		// collateral may also be an input, selected by another rule
+		for i := range report.Collateral {
+			report.Collateral[i].Rule = collateralRule
+			report.Collateral[i].Unit = ""
+		}
+	}
+
+	total := Redeemer.ExecutionUnits{}
+	for _, redeemer := range b.redeemers {
+		total.Sum(redeemer.ExUnits)
+		report.Redeemers = append(report.Redeemers, RedeemerReport{
+			Purpose: Redeemer.RdeemerTagNames[redeemer.Tag],
+			Index:   redeemer.Index,
+			ExUnits: ExUnitsReport{
+				Mem:        redeemer.ExUnits.Mem,
+				Steps:      redeemer.ExUnits.Steps,
+				MemShare:   share(redeemer.ExUnits.Mem, pp.MaxTxExMem),
+				StepsShare: share(redeemer.ExUnits.Steps, pp.MaxTxExSteps),
+			},
+		})
+	}
+	report.ExUnits = ExUnitsReport{
+		Mem:        total.Mem,
+		Steps:      total.Steps,
+		MemShare:   share(total.Mem, pp.MaxTxExMem),
+		StepsShare: share(total.Steps, pp.MaxTxExSteps),
+	}
+
+	if fakeTx, err := b.buildFullFakeTx(); err == nil {
+		fakeTxBytes, _ := fakeTx.Bytes()
+		report.TxSize = len(fakeTxBytes)
+	}
+	refScriptSize := b.referenceScriptsSize()
+	report.Fee = FeeReport{
+		Fee:                 b.Fee,
+		TxSize:              report.TxSize,
+		SizeFee:             pp.MinFee(report.TxSize, 0, 0),
+		ScriptFee:           pp.ScriptFee(total.Mem, total.Steps),
+		ReferenceScriptSize: refScriptSize,
+		Margin:              Utils.Fee(b.Context, 0, 0, 0) - pp.MinFee(0, 0, 0),
+		Padding:             b.FeePadding,
+	}
+	if refScriptSize > 0 {
+		report.Fee.ReferenceScriptFee = pp.RefScriptFee(refScriptSize)
+	}
+
+	change := make(map[int]bool)
+	for _, idx := range report.Change.Outputs {
+		change[idx] = true
+	}
+	for i, output := range b.tx.TransactionBody.Outputs {
+		report.Outputs = append(report.Outputs, OutputReport{
+			Index:       i,
+			Address:     output.GetAddress().String(),
+			Lovelace:    output.GetValue().GetCoin(),
+			MinLovelace: Utils.MinLovelacePostAlonzo(output, b.Context),
+			Change:      change[i],
+		})
+	}
+	return &report
+}
@@ -0,0 +1,106 @@
+package apollo
+
+import (
+	"context"
+
+	"github.com/Salvionied/apollo/observability"
+	"github.com/Salvionied/apollo/txBuilding/Backend/Base"
+	"golang.org/x/exp/slog"
+)
+
+/*
+*
+
+	SetInstrumentation runs Complete and its phases, coin
+	selection, collateral, execution units and change and fee,
+	in spans of an instrumentation. The chain context calls are
+	instrumented by wrapping the context with
+	observability.NewChainContext, their spans being children
+	of the phases making them.
+
+	Params:
+		instrumentation (observability.Instrumentation): The instrumentation, nil to disable it.
+
+	Returns:
+		*Apollo: A pointer to the Apollo object to support method chaining.
+*/
+func (b *Apollo) SetInstrumentation(instrumentation observability.Instrumentation) *Apollo {
+	b.instrumentation = instrumentation
+	return b
+}
+
+/*
+*
+
+	SetLogger logs Complete and its phases to a structured
+	logger, along with any instrumentation.
+
+	Params:
+		logger (*slog.Logger): The logger, nil to disable logging.
+
+	Returns:
+		*Apollo: A pointer to the Apollo object to support method chaining.
+*/
+func (b *Apollo) SetLogger(logger *slog.Logger) *Apollo {
+	b.logger = logger
+	return b
+}
+
+func (b *Apollo) instrumented() bool {
+	return b.instrumentation != nil || b.logger != nil
+}
+
+/*
+*
+
+	observe runs a phase of the build in a span, child of the
+	phase running it.
+
+	Params:
+		name (string): The name of the phase.
+		phase (func() error): The phase.
+
+	Returns:
+		error: The error of the phase.
+*/
+func (b *Apollo) observe(name string, phase func() error) error {
+	if !b.instrumented() {
+		return phase()
+	}
+	var logging observability.Instrumentation
+	if b.logger != nil {
+		logging = observability.NewSlogInstrumentation(b.logger)
+	}
+	parent := b.ctx
+	ctx := parent
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, span := observability.Multi(b.instrumentation, logging).Start(ctx, name)
+	b.ctx = observability.ContextWithSpan(ctx, span)
+	// the calls of the chain context are children of the phase
+	chainContext := b.Context
+	b.Context = Base.WithContext(chainContext, b.ctx)
+	err := phase()
+	span.End(err)
+	b.Context = chainContext
+	b.ctx = parent
+	return err
+}
+
+/*
+*
+
+	setAttributes adds attributes to the span of the running phase.
+
+	Params:
+		attrs (...slog.Attr): The attributes.
+*/
+func (b *Apollo) setAttributes(attrs ...slog.Attr) {
+	if b.ctx == nil {
+		return
+	}
+	if span := observability.SpanFromContext(b.ctx); span != nil {
+		span.SetAttributes(attrs...)
+	}
+}
@@ -0,0 +1,168 @@
+package apollo
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Salvionied/apollo/constants"
+	"github.com/Salvionied/apollo/serialization/Address"
+	"github.com/Salvionied/apollo/serialization/NativeScript"
+	"github.com/Salvionied/apollo/serialization/PlutusData"
+	"github.com/Salvionied/apollo/serialization/TransactionInput"
+	"github.com/Salvionied/apollo/serialization/TransactionOutput"
+	"github.com/Salvionied/apollo/serialization/Value"
+	"github.com/Salvionied/apollo/txBuilding/Backend/Base"
+	"github.com/Salvionied/apollo/txBuilding/Utils"
+)
+
+/*
+*
+
+	ReferenceScriptPayment is an output carrying a reference
+	script. Its lovelace is raised to the minimum of the output,
+	the size of the script included.
+*/
+type ReferenceScriptPayment struct {
+	Lovelace  int
+	Receiver  Address.Address
+	ScriptRef PlutusData.ScriptRef
+}
+
+/*
+*
+
+	ToValue converts a ReferenceScriptPayment to a Value object.
+
+	Returns:
+		Value.Value: The lovelace of the payment.
+*/
+func (p *ReferenceScriptPayment) ToValue() Value.Value {
+	return Value.PureLovelaceValue(int64(p.Lovelace))
+}
+
+/*
+*
+
+	EnsureMinUTXO raises the lovelace of the payment to the
+	minimum of its output. As the minimum depends on the size of
+	the encoded lovelace it is recomputed until it is stable.
+
+	Params:
+		cc (Base.ChainContext): The chain context.
+*/
+func (p *ReferenceScriptPayment) EnsureMinUTXO(cc Base.ChainContext) {
+	for {
+		coins := Utils.MinLovelacePostAlonzo(*p.ToTxOut(), cc)
+		if int64(p.Lovelace) >= coins {
+			return
+		}
+		p.Lovelace = int(coins)
+	}
+}
+
+/*
+*
+
+	ToTxOut converts a ReferenceScriptPayment to a TransactionOutput.
+
+	Returns:
+		*TransactionOutput.TransactionOutput: The output carrying the script.
+*/
+func (p *ReferenceScriptPayment) ToTxOut() *TransactionOutput.TransactionOutput {
+	scriptRef := p.ScriptRef
+	return &TransactionOutput.TransactionOutput{
+		IsPostAlonzo: true,
+		PostAlonzo: TransactionOutput.TransactionOutputAlonzo{
+			Address:   p.Receiver,
+			Amount:    p.ToValue().ToAlonzoValue(),
+			ScriptRef: &scriptRef,
+		},
+	}
+}
+
+/*
+*
+
+	AlwaysFailsScript returns the native script requiring any of
+	no scripts, which can never be satisfied.
+
+	Returns:
+		NativeScript.NativeScript: The always failing script.
+*/
+func AlwaysFailsScript() NativeScript.NativeScript {
+	return NativeScript.NewScriptAny([]NativeScript.NativeScript{})
+}
+
+/*
+*
+
+	AlwaysFailsAddress returns the address of AlwaysFailsScript.
+	The outputs sent to it can never be spent, which makes it a
+	burn address for reference scripts.
+
+	Params:
+		network (constants.Network): The network of the address.
+
+	Returns:
+		Address.Address: The burn address.
+		error: An error if the script cannot be hashed.
+*/
+func AlwaysFailsAddress(network constants.Network) (Address.Address, error) {
+	hash, err := AlwaysFailsScript().Hash()
+	if err != nil {
+		return Address.Address{}, err
+	}
+	return Address.NewEnterpriseAddress(Address.NewScriptCredential(hash.Bytes()), network)
+}
+
+/*
+*
+
+	DeployReferenceScript adds an output carrying the script with
+	the minimum lovelace and completes the transaction. Other
+	payments have to be added before. Once the transaction is
+	submitted the returned input can be passed to AddReferenceInput
+	by later builders, which then leave the script out of their
+	witness set.
+
+	Params:
+		script (PlutusData.ScriptRef): The script to deploy, as returned by NewScriptRef or GetScript.
+		lockAddress (*Address.Address): The address locking the output, nil to
+			lock it forever at the AlwaysFailsAddress of the change address network.
+
+	Returns:
+		*Apollo: A pointer to the Apollo object with the completed transaction.
+		TransactionInput.TransactionInput: The input of the output carrying the script.
+		error: An error if the transaction cannot be completed.
+*/
+func (b *Apollo) DeployReferenceScript(script PlutusData.ScriptRef, lockAddress *Address.Address) (*Apollo, TransactionInput.TransactionInput, error) {
+	if script.Size() == 0 {
+		return nil, TransactionInput.TransactionInput{}, errors.New("cannot deploy an empty script")
+	}
+	if lockAddress == nil {
+		if len(b.inputAddresses) == 0 {
+			return nil, TransactionInput.TransactionInput{}, errors.New("no change address to take the network of the burn address from")
+		}
+		network := constants.TESTNET
+		if b.inputAddresses[0].Network == Address.MAINNET {
+			network = constants.MAINNET
+		}
+		burnAddress, err := AlwaysFailsAddress(network)
+		if err != nil {
+			return nil, TransactionInput.TransactionInput{}, err
+		}
+		lockAddress = &burnAddress
+	}
+	// payments come first in the outputs, the change after them
+	index := len(b.payments)
+	b.AddPayment(&ReferenceScriptPayment{Receiver: *lockAddress, ScriptRef: script})
+	built, err := b.Complete()
+	if err != nil {
+		return nil, TransactionInput.TransactionInput{}, err
+	}
+	txHash, err := built.GetTx().TransactionBody.Hash()
+	if err != nil {
+		return nil, TransactionInput.TransactionInput{}, fmt.Errorf("cannot hash the transaction: %w", err)
+	}
+	return built, TransactionInput.TransactionInput{TransactionId: txHash, Index: index}, nil
+}
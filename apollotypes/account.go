@@ -0,0 +1,507 @@
+package apollotypes
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/Salvionied/apollo/constants"
+	"github.com/Salvionied/apollo/crypto/bech32"
+	"github.com/Salvionied/apollo/crypto/bip32"
+	"github.com/Salvionied/apollo/serialization"
+	serAddress "github.com/Salvionied/apollo/serialization/Address"
+	"github.com/Salvionied/apollo/serialization/HDWallet"
+	"github.com/Salvionied/apollo/serialization/Key"
+	"github.com/Salvionied/apollo/serialization/Transaction"
+	"github.com/Salvionied/apollo/serialization/TransactionInput"
+	"github.com/Salvionied/apollo/serialization/TransactionWitnessSet"
+	"github.com/Salvionied/apollo/serialization/UTxO"
+	"github.com/Salvionied/apollo/serialization/VerificationKeyWitness"
+	"github.com/Salvionied/apollo/txBuilding/Backend/Base"
+)
+
+const (
+	EXTERNAL_CHAIN uint32 = 0
+	INTERNAL_CHAIN uint32 = 1
+	STAKING_CHAIN  uint32 = 2
+)
+
+// DEFAULT_GAP_LIMIT is the address gap limit recommended by CIP-1852.
+const DEFAULT_GAP_LIMIT = 20
+
+// MAX_DISCOVERY_INDEX bounds the scan of a chain, so that a backend
+// reporting every address as used cannot make discovery loop forever.
+const MAX_DISCOVERY_INDEX = 10000
+
+const ACCOUNT_XVK_HRP = "acct_xvk"
+
+type DerivedAddress struct {
+	Chain           uint32
+	Index           uint32
+	Path            string
+	Address         serAddress.Address
+	SigningKey      Key.SigningKey
+	VerificationKey Key.VerificationKey
+	Used            bool
+}
+
+/*
+*
+
+	AccountWallet is a CIP-1852 account (m/1852'/1815'/account')
+	with its external and internal address chains. Its UTxOs are
+	found by Discover and it signs with every key owning an input.
+	A watch-only account, created from its extended public key,
+	has no signing keys and only reports the keys needed to sign.
+*/
+type AccountWallet struct {
+	Account              uint32
+	Network              constants.Network
+	GapLimit             int
+	External             []DerivedAddress
+	Internal             []DerivedAddress
+	StakeSigningKey      Key.StakeSigningKey
+	StakeVerificationKey Key.StakeVerificationKey
+	accountKey           *HDWallet.HDWallet
+	accountXPub          bip32.XPub
+	stakeHash            serialization.PubKeyHash
+	utxos                []UTxO.UTxO
+	owners               map[string]DerivedAddress
+}
+
+/*
+*
+
+	NewAccountWallet derives an account of an HD wallet.
+
+	Params:
+		hd (*HDWallet.HDWallet): The root wallet.
+		account (uint32): The account index.
+		network (constants.Network): The network of the addresses.
+		gapLimit (int): The number of consecutive unused addresses
+			ending the scan of a chain, DEFAULT_GAP_LIMIT if not positive.
+
+	Returns:
+		*AccountWallet: The account wallet, with the first external address derived.
+		error: An error if the derivation fails.
+*/
+func NewAccountWallet(hd *HDWallet.HDWallet, account uint32, network constants.Network, gapLimit int) (*AccountWallet, error) {
+	accountKey, err := hd.DerivePath(fmt.Sprintf("m/1852'/1815'/%d'", account))
+	if err != nil {
+		return nil, err
+	}
+	if gapLimit <= 0 {
+		gapLimit = DEFAULT_GAP_LIMIT
+	}
+	return NewAccountWalletFromAccountKey(accountKey, account, network, gapLimit)
+}
+
+/*
+*
+
+	NewWatchOnlyWallet creates a watch-only account from its bech32
+	extended public key (acct_xvk), as exported by wallets.
+
+	Params:
+		acctXvk (string): The bech32 account extended public key.
+		account (uint32): The account index, used for the derivation paths.
+		network (constants.Network): The network of the addresses.
+		gapLimit (int): The address gap limit.
+
+	Returns:
+		*AccountWallet: The watch-only account wallet.
+		error: An error if the key is invalid.
+*/
+func NewWatchOnlyWallet(acctXvk string, account uint32, network constants.Network, gapLimit int) (*AccountWallet, error) {
+	hrp, data, err := bech32.Decode(acctXvk)
+	if err != nil {
+		return nil, err
+	}
+	if hrp != ACCOUNT_XVK_HRP {
+		return nil, fmt.Errorf("invalid account key prefix %s, expected %s", hrp, ACCOUNT_XVK_HRP)
+	}
+	raw, err := bech32.ConvertBits(data, 5, 8, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) != bip32.XPubSize {
+		return nil, fmt.Errorf("invalid account key size %d", len(raw))
+	}
+	return newAccountWallet(bip32.NewXPub(raw), nil, account, network, gapLimit)
+}
+
+func newAccountWallet(accountXPub bip32.XPub, accountKey *HDWallet.HDWallet, account uint32, network constants.Network, gapLimit int) (*AccountWallet, error) {
+	if gapLimit <= 0 {
+		gapLimit = DEFAULT_GAP_LIMIT
+	}
+	stakeVerificationKey := Key.VerificationKey{Payload: accountXPub.Derive(STAKING_CHAIN).Derive(0).PublicKey()}
+	stakeHash, err := stakeVerificationKey.Hash()
+	if err != nil {
+		return nil, err
+	}
+	aw := &AccountWallet{
+		Account:              account,
+		Network:              network,
+		GapLimit:             gapLimit,
+		External:             make([]DerivedAddress, 0),
+		Internal:             make([]DerivedAddress, 0),
+		StakeVerificationKey: Key.StakeVerificationKey{Payload: stakeVerificationKey.Payload},
+		accountKey:           accountKey,
+		accountXPub:          accountXPub,
+		stakeHash:            stakeHash,
+		utxos:                make([]UTxO.UTxO, 0),
+		owners:               make(map[string]DerivedAddress),
+	}
+	_, err = aw.derive(EXTERNAL_CHAIN, 0)
+	if err != nil {
+		return nil, err
+	}
+	return aw, nil
+}
+
+/*
+*
+
+	IsWatchOnly reports whether the account has no signing keys.
+
+	Returns:
+		bool: true for a watch-only account.
+*/
+func (aw *AccountWallet) IsWatchOnly() bool {
+	return aw.accountKey == nil
+}
+
+/*
+*
+
+	AccountXvk returns the bech32 extended public key of the account,
+	from which a watch-only wallet can be created.
+
+	Returns:
+		string: The acct_xvk key.
+		error: An error if the encoding fails.
+*/
+func (aw *AccountWallet) AccountXvk() (string, error) {
+	data, err := bech32.ConvertBits(aw.accountXPub.Bytes(), 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	return bech32.Encode(ACCOUNT_XVK_HRP, data)
+}
+
+/*
+*
+
+	NewAccountWalletFromAccountKey creates an account wallet from
+	the extended private key of the account (m/1852'/1815'/account').
+
+	Params:
+		accountKey (*HDWallet.HDWallet): The account key.
+		account (uint32): The account index, used for the derivation paths.
+		network (constants.Network): The network of the addresses.
+		gapLimit (int): The address gap limit.
+
+	Returns:
+		*AccountWallet: The account wallet.
+		error: An error if the derivation fails.
+*/
+func NewAccountWalletFromAccountKey(accountKey *HDWallet.HDWallet, account uint32, network constants.Network, gapLimit int) (*AccountWallet, error) {
+	aw, err := newAccountWallet(accountKey.XPrivKey.XPub(), accountKey, account, network, gapLimit)
+	if err != nil {
+		return nil, err
+	}
+	stakeKey := accountKey.Derive(STAKING_CHAIN, false).Derive(0, false)
+	aw.StakeSigningKey = Key.StakeSigningKey{Payload: stakeKey.XPrivKey.Bytes()}
+	return aw, nil
+}
+
+/*
+*
+
+	NewAccountWalletFromMnemonic derives an account from a mnemonic.
+
+	Params:
+		mnemonic (string): The mnemonic of the wallet.
+		passphrase (string): The passphrase of the wallet.
+		account (uint32): The account index.
+		network (constants.Network): The network of the addresses.
+		gapLimit (int): The address gap limit.
+
+	Returns:
+		*AccountWallet: The account wallet.
+		error: An error if the mnemonic is invalid.
+*/
+func NewAccountWalletFromMnemonic(mnemonic string, passphrase string, account uint32, network constants.Network, gapLimit int) (*AccountWallet, error) {
+	hd, err := HDWallet.NewHDWalletFromMnemonic(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return NewAccountWallet(hd, account, network, gapLimit)
+}
+
+func baseAddress(paymentHash serialization.PubKeyHash, stakeHash serialization.PubKeyHash, network constants.Network) serAddress.Address {
+	if network == constants.MAINNET {
+		return serAddress.Address{StakingPart: stakeHash[:], PaymentPart: paymentHash[:], Network: 1, AddressType: serAddress.KEY_KEY, HeaderByte: 0b00000001, Hrp: "addr"}
+	}
+	return serAddress.Address{StakingPart: stakeHash[:], PaymentPart: paymentHash[:], Network: 0, AddressType: serAddress.KEY_KEY, HeaderByte: 0b00000000, Hrp: "addr_test"}
+}
+
+func (aw *AccountWallet) chain(chain uint32) *[]DerivedAddress {
+	if chain == INTERNAL_CHAIN {
+		return &aw.Internal
+	}
+	return &aw.External
+}
+
+/*
+*
+
+	derive returns the address at an index of a chain, deriving
+	it and every address before it if needed.
+*/
+func (aw *AccountWallet) derive(chain uint32, index uint32) (*DerivedAddress, error) {
+	addresses := aw.chain(chain)
+	for uint32(len(*addresses)) <= index {
+		next := uint32(len(*addresses))
+		verificationKey := Key.VerificationKey{Payload: aw.accountXPub.Derive(chain).Derive(next).PublicKey()}
+		vkh, err := verificationKey.Hash()
+		if err != nil {
+			return nil, err
+		}
+		derived := DerivedAddress{
+			Chain:           chain,
+			Index:           next,
+			Path:            fmt.Sprintf("m/1852'/1815'/%d'/%d/%d", aw.Account, chain, next),
+			Address:         baseAddress(vkh, aw.stakeHash, aw.Network),
+			VerificationKey: verificationKey,
+		}
+		if aw.accountKey != nil {
+			key := aw.accountKey.Derive(chain, false).Derive(next, false)
+			derived.SigningKey = Key.SigningKey{Payload: key.XPrivKey.Bytes()}
+		}
+		*addresses = append(*addresses, derived)
+	}
+	return &(*addresses)[index], nil
+}
+
+/*
+*
+
+	DeriveAddress returns the address at an index of a chain.
+
+	Params:
+		chain (uint32): EXTERNAL_CHAIN or INTERNAL_CHAIN.
+		index (uint32): The address index.
+
+	Returns:
+		serAddress.Address: The address.
+		error: An error if the address cannot be derived.
+*/
+func (aw *AccountWallet) DeriveAddress(chain uint32, index uint32) (serAddress.Address, error) {
+	if chain != EXTERNAL_CHAIN && chain != INTERNAL_CHAIN {
+		return serAddress.Address{}, fmt.Errorf("invalid address chain %d", chain)
+	}
+	derived, err := aw.derive(chain, index)
+	if err != nil {
+		return serAddress.Address{}, err
+	}
+	return derived.Address, nil
+}
+
+/*
+*
+
+	Discover scans the external and internal chains through the
+	chain context, stopping each chain after GapLimit consecutive
+	addresses without UTxOs, and collects the UTxOs found.
+
+	Params:
+		cc (Base.ChainContext): The chain context to query.
+
+	Returns:
+		error: An error if an address cannot be derived or its
+			UTxOs cannot be fetched.
+*/
+func (aw *AccountWallet) Discover(cc Base.ChainContext) error {
+	aw.utxos = make([]UTxO.UTxO, 0)
+	aw.owners = make(map[string]DerivedAddress)
+	for idx := range aw.External {
+		aw.External[idx].Used = false
+	}
+	for idx := range aw.Internal {
+		aw.Internal[idx].Used = false
+	}
+	for _, chain := range []uint32{EXTERNAL_CHAIN, INTERNAL_CHAIN} {
+		gap := 0
+		for index := uint32(0); gap < aw.GapLimit && index < MAX_DISCOVERY_INDEX; index++ {
+			derived, err := aw.derive(chain, index)
+			if err != nil {
+				return err
+			}
+			utxos, err := Base.FetchUtxos(cc, derived.Address)
+			if err != nil {
+				return fmt.Errorf("fetching the UTxOs of %s: %w", derived.Path, err)
+			}
+			if len(utxos) == 0 {
+				gap++
+				continue
+			}
+			gap = 0
+			derived.Used = true
+			for _, utxo := range utxos {
+				aw.utxos = append(aw.utxos, utxo)
+				aw.owners[utxo.GetKey()] = *derived
+			}
+		}
+	}
+	return nil
+}
+
+/*
+*
+
+	Utxos returns the UTxOs found by the last discovery, across
+	all the addresses of the account.
+
+	Returns:
+		[]UTxO.UTxO: The UTxOs of the account.
+*/
+func (aw *AccountWallet) Utxos() []UTxO.UTxO {
+	return aw.utxos
+}
+
+/*
+*
+
+	UsedAddresses returns the addresses holding UTxOs at the last discovery.
+
+	Returns:
+		[]serAddress.Address: The used addresses, external chain first.
+*/
+func (aw *AccountWallet) UsedAddresses() []serAddress.Address {
+	used := make([]serAddress.Address, 0)
+	for _, derived := range append(append([]DerivedAddress{}, aw.External...), aw.Internal...) {
+		if derived.Used {
+			used = append(used, derived.Address)
+		}
+	}
+	return used
+}
+
+/*
+*
+
+	ChangeAddress returns the first internal address without UTxOs.
+
+	Returns:
+		serAddress.Address: The change address.
+*/
+func (aw *AccountWallet) ChangeAddress() serAddress.Address {
+	for _, derived := range aw.Internal {
+		if !derived.Used {
+			return derived.Address
+		}
+	}
+	derived, _ := aw.derive(INTERNAL_CHAIN, uint32(len(aw.Internal)))
+	return derived.Address
+}
+
+/*
+*
+
+	GetAddress returns the first external address of the account.
+
+	Returns:
+		*serAddress.Address: A pointer to the address.
+*/
+func (aw *AccountWallet) GetAddress() *serAddress.Address {
+	return &aw.External[0].Address
+}
+
+/*
+*
+
+	PkeyHash returns the hash of the first external payment key.
+
+	Returns:
+		serialization.PubKeyHash: The public key hash.
+*/
+func (aw *AccountWallet) PkeyHash() serialization.PubKeyHash {
+	res, _ := aw.External[0].VerificationKey.Hash()
+	return res
+}
+
+func (aw *AccountWallet) signers(tx Transaction.Transaction) []DerivedAddress {
+	signers := make([]DerivedAddress, 0)
+	seen := make(map[string]bool)
+	addSigner := func(derived DerivedAddress) {
+		vkh, _ := derived.VerificationKey.Hash()
+		key := hex.EncodeToString(vkh[:])
+		if !seen[key] {
+			seen[key] = true
+			signers = append(signers, derived)
+		}
+	}
+	inputs := append(append([]TransactionInput.TransactionInput{}, tx.TransactionBody.Inputs...), tx.TransactionBody.Collateral...)
+	for _, input := range inputs {
+		if derived, ok := aw.owners[UTxO.UTxO{Input: input}.GetKey()]; ok {
+			addSigner(derived)
+		}
+	}
+	for _, required := range tx.TransactionBody.RequiredSigners {
+		for _, derived := range append(append([]DerivedAddress{}, aw.External...), aw.Internal...) {
+			vkh, _ := derived.VerificationKey.Hash()
+			if vkh == required {
+				addSigner(derived)
+			}
+		}
+	}
+	return signers
+}
+
+/*
+*
+
+	RequiredSigningPaths returns the derivation paths of the keys
+	needed to sign a transaction, so that an external signer can
+	witness a transaction built with a watch-only account.
+
+	Params:
+		tx (Transaction.Transaction): The transaction to be signed.
+
+	Returns:
+		[]string: The derivation paths, in signing order.
+*/
+func (aw *AccountWallet) RequiredSigningPaths(tx Transaction.Transaction) []string {
+	paths := make([]string, 0)
+	for _, signer := range aw.signers(tx) {
+		paths = append(paths, signer.Path)
+	}
+	return paths
+}
+
+/*
+*
+
+	SignTx signs a transaction with the key of every address of the
+	account owning one of its inputs or collateral inputs, and with
+	the keys listed as required signers. A watch-only account leaves
+	the witness set unchanged.
+
+	Params:
+		tx (Transaction.Transaction): The transaction to be signed.
+
+	Returns:
+		TransactionWitnessSet.TransactionWitnessSet: The updated witness set.
+*/
+func (aw *AccountWallet) SignTx(tx Transaction.Transaction) TransactionWitnessSet.TransactionWitnessSet {
+	witness_set := tx.TransactionWitnessSet
+	if aw.IsWatchOnly() {
+		return witness_set
+	}
+	txHash, _ := tx.TransactionBody.Hash()
+	for _, signer := range aw.signers(tx) {
+		signature, _ := signer.SigningKey.Sign(txHash)
+		witness_set.VkeyWitnesses = append(witness_set.VkeyWitnesses, VerificationKeyWitness.VerificationKeyWitness{Vkey: signer.VerificationKey, Signature: signature})
+	}
+	return witness_set
+}
@@ -0,0 +1,181 @@
+package apollotypes_test
+
+import (
+	"crypto/ed25519"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Salvionied/apollo"
+	"github.com/Salvionied/apollo/apollotypes"
+	"github.com/Salvionied/apollo/constants"
+	"github.com/Salvionied/apollo/serialization/Address"
+	"github.com/Salvionied/apollo/serialization/HDWallet"
+	"github.com/Salvionied/apollo/serialization/Key"
+	"github.com/Salvionied/apollo/serialization/TransactionInput"
+	"github.com/Salvionied/apollo/serialization/TransactionOutput"
+	"github.com/Salvionied/apollo/serialization/UTxO"
+	"github.com/Salvionied/apollo/serialization/Value"
+	"github.com/Salvionied/apollo/txBuilding/Backend/FixedChainContext"
+)
+
+var MNEMONIC_12 = "test walk nut penalty hip pave soap entry language right filter choice"
+
+type addressChainContext struct {
+	FixedChainContext.FixedChainContext
+	utxos map[string][]UTxO.UTxO
+}
+
+func (cc addressChainContext) Utxos(address Address.Address) []UTxO.UTxO {
+	return cc.utxos[address.String()]
+}
+
+func (cc addressChainContext) FetchUtxos(address Address.Address) ([]UTxO.UTxO, error) {
+	return cc.Utxos(address), nil
+}
+
+func fund(cc addressChainContext, address Address.Address, txId byte, lovelace int64) {
+	utxo := UTxO.UTxO{
+		Input:  TransactionInput.TransactionInput{TransactionId: []byte{txId, 31: 0}, Index: 0},
+		Output: TransactionOutput.SimpleTransactionOutput(address, Value.PureLovelaceValue(lovelace)),
+	}
+	cc.utxos[address.String()] = append(cc.utxos[address.String()], utxo)
+}
+
+func TestAccountDiscovery(t *testing.T) {
+	aw, err := apollotypes.NewAccountWalletFromMnemonic(MNEMONIC_12, "", 0, constants.MAINNET, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	single, _ := apollo.New(FixedChainContext.InitFixedChainContext()).SetWalletFromMnemonic(MNEMONIC_12, constants.MAINNET)
+	if aw.GetAddress().String() != single.GetWallet().GetAddress().String() {
+		t.Error("The first external address must match the single address wallet", aw.GetAddress().String())
+	}
+
+	cc := addressChainContext{FixedChainContext.InitFixedChainContext(), map[string][]UTxO.UTxO{}}
+	external3 := derivedAddress(t, aw, apollotypes.EXTERNAL_CHAIN, 3)
+	internal1 := derivedAddress(t, aw, apollotypes.INTERNAL_CHAIN, 1)
+	beyondGap := derivedAddress(t, aw, apollotypes.EXTERNAL_CHAIN, 9)
+	fund(cc, *aw.GetAddress(), 1, 2_000_000)
+	fund(cc, external3, 2, 3_000_000)
+	fund(cc, internal1, 3, 4_000_000)
+	fund(cc, beyondGap, 4, 5_000_000)
+
+	err = aw.Discover(cc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(aw.Utxos()) != 3 || len(aw.UsedAddresses()) != 3 {
+		t.Error("Invalid discovered utxos", len(aw.Utxos()), len(aw.UsedAddresses()))
+	}
+	if len(aw.Internal) != 7 {
+		t.Error("The scan must stop at the gap limit", len(aw.Internal))
+	}
+	if aw.ChangeAddress().String() != aw.Internal[0].Address.String() {
+		t.Error("Invalid change address")
+	}
+
+	apollob, err := apollo.New(cc).SetAccountWalletFromMnemonic(MNEMONIC_12, constants.MAINNET, 0, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	apollob, err = apollob.SetWalletAsChangeAddress().
+		PayToAddressBech32("addr1qxajla3qcrwckzkur8n0lt02rg2sepw3kgkstckmzrz4ccfm3j9pqrqkea3tns46e3qy2w42vl8dvvue8u45amzm3rjqvv2nxh", 6_000_000).
+		Complete()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := apollob.Sign().GetTx()
+	if len(tx.TransactionBody.Inputs) != 3 {
+		t.Fatal("Invalid inputs", len(tx.TransactionBody.Inputs))
+	}
+	if len(tx.TransactionWitnessSet.VkeyWitnesses) != 3 {
+		t.Fatal("Every input owner must sign", len(tx.TransactionWitnessSet.VkeyWitnesses))
+	}
+	txHash, _ := tx.TransactionBody.Hash()
+	for _, witness := range tx.TransactionWitnessSet.VkeyWitnesses {
+		if !ed25519.Verify(witness.Vkey.Payload, txHash, witness.Signature) {
+			t.Error("Invalid signature")
+		}
+	}
+}
+
+func derivedAddress(t *testing.T, aw *apollotypes.AccountWallet, chain uint32, index uint32) Address.Address {
+	address, err := aw.DeriveAddress(chain, index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return address
+}
+
+func TestWatchOnlyWallet(t *testing.T) {
+	aw, _ := apollotypes.NewAccountWalletFromMnemonic(MNEMONIC_12, "", 0, constants.MAINNET, 5)
+	acctXvk, err := aw.AccountXvk()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(acctXvk, "acct_xvk1") {
+		t.Error("Invalid account key", acctXvk)
+	}
+	if _, err := apollotypes.NewWatchOnlyWallet("addr1vx2fxv2umyhttkxyxp8x0dlpdt3k6cwng5pxj3jhsydzers66hrl8", 0, constants.MAINNET, 5); err == nil {
+		t.Error("Expected an error for a non account key")
+	}
+	watch, err := apollotypes.NewWatchOnlyWallet(acctXvk, 0, constants.MAINNET, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !watch.IsWatchOnly() || aw.IsWatchOnly() {
+		t.Error("Invalid watch-only flag")
+	}
+	for _, chain := range []uint32{apollotypes.EXTERNAL_CHAIN, apollotypes.INTERNAL_CHAIN} {
+		for idx := uint32(0); idx < 4; idx++ {
+			if derivedAddress(t, aw, chain, idx).String() != derivedAddress(t, watch, chain, idx).String() {
+				t.Error("Watch-only addresses differ", chain, idx)
+			}
+		}
+	}
+	if watch.StakeVerificationKey.Payload == nil || string(watch.StakeVerificationKey.Payload) != string(aw.StakeVerificationKey.Payload) {
+		t.Error("Invalid stake key")
+	}
+
+	cc := addressChainContext{FixedChainContext.InitFixedChainContext(), map[string][]UTxO.UTxO{}}
+	fund(cc, *aw.GetAddress(), 1, 2_000_000)
+	fund(cc, derivedAddress(t, aw, apollotypes.EXTERNAL_CHAIN, 2), 2, 3_000_000)
+	fund(cc, derivedAddress(t, aw, apollotypes.INTERNAL_CHAIN, 0), 3, 4_000_000)
+	apollob, err := apollo.New(cc).SetWatchOnlyWallet(acctXvk, constants.MAINNET, 0, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	apollob, err = apollob.SetWalletAsChangeAddress().
+		PayToAddressBech32("addr1qxajla3qcrwckzkur8n0lt02rg2sepw3kgkstckmzrz4ccfm3j9pqrqkea3tns46e3qy2w42vl8dvvue8u45amzm3rjqvv2nxh", 6_000_000).
+		Complete()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := apollob.Sign().GetTx()
+	if len(tx.TransactionWitnessSet.VkeyWitnesses) != 0 {
+		t.Error("A watch-only wallet must not sign")
+	}
+	paths := apollob.GetWallet().(*apollotypes.AccountWallet).RequiredSigningPaths(*tx)
+	expected := []string{"m/1852'/1815'/0'/0/0", "m/1852'/1815'/0'/0/2", "m/1852'/1815'/0'/1/0"}
+	sort.Strings(paths)
+	if strings.Join(paths, ",") != strings.Join(expected, ",") {
+		t.Fatal("Invalid signing paths", paths)
+	}
+
+	hd, _ := HDWallet.NewHDWalletFromMnemonic(MNEMONIC_12, "")
+	for _, path := range paths {
+		key, err := hd.DerivePath(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = apollob.SignWithSkey(Key.VerificationKey{Payload: key.XPrivKey.PublicKey()}, Key.SigningKey{Payload: key.XPrivKey.Bytes()})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	signed, _ := apollob.GetTx().Bytes()
+	if apollob.GetTx().TransactionBody.Fee < int64(cc.GetProtocolParams().MinFeeConstant+cc.GetProtocolParams().MinFeeCoefficient*len(signed)) {
+		t.Error("The fee must cover every witness", apollob.GetTx().TransactionBody.Fee, len(signed))
+	}
+}
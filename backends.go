@@ -7,6 +7,7 @@ import (
 
 	"github.com/Salvionied/apollo/txBuilding/Backend/BlockFrostChainContext"
 	"github.com/Salvionied/apollo/txBuilding/Backend/FixedChainContext"
+	"github.com/Salvionied/apollo/txBuilding/Backend/HttpClient"
 	"github.com/Salvionied/apollo/txBuilding/Backend/MaestroChainContext"
 )
 
@@ -32,43 +33,49 @@ func NewEmptyBackend() FixedChainContext.FixedChainContext {
 	Params:
 		projectId (string): The project ID to authenticate with BlockFrost.
 		network (Network): The network to configure the BlockFrost context for.
+		opts (...HttpClient.Option): The http client, retry and rate limit options.
 
 	Returns:
 		BlockFrostChainContext.BlockFrostChainContext: A BlockFrostChainContext instance configured for the specified network.
+		error: An error if the network is invalid or the initial requests fail.
 */
 func NewBlockfrostBackend(
 	projectId string,
 	network constants.Network,
-
+	opts ...HttpClient.Option,
 ) (BlockFrostChainContext.BlockFrostChainContext, error) {
 	switch network {
 	case constants.MAINNET:
-		return BlockFrostChainContext.NewBlockfrostChainContext(
+		return BlockFrostChainContext.New(
 			constants.BLOCKFROST_BASE_URL_MAINNET,
 			int(constants.MAINNET),
 			projectId,
-		), nil
+			opts...,
+		)
 	case constants.TESTNET:
 
-		return BlockFrostChainContext.NewBlockfrostChainContext(
+		return BlockFrostChainContext.New(
 			constants.BLOCKFROST_BASE_URL_TESTNET,
 			int(constants.TESTNET),
 			projectId,
-		), nil
+			opts...,
+		)
 	case constants.PREVIEW:
 
-		return BlockFrostChainContext.NewBlockfrostChainContext(
+		return BlockFrostChainContext.New(
 			constants.BLOCKFROST_BASE_URL_PREVIEW,
 			int(constants.TESTNET),
 			projectId,
-		), nil
+			opts...,
+		)
 	case constants.PREPROD:
 
-		return BlockFrostChainContext.NewBlockfrostChainContext(
+		return BlockFrostChainContext.New(
 			constants.BLOCKFROST_BASE_URL_PREPROD,
 			int(constants.TESTNET),
 			projectId,
-		), nil
+			opts...,
+		)
 	default:
 		return BlockFrostChainContext.BlockFrostChainContext{}, fmt.Errorf("Invalid network")
 	}
@@ -79,15 +86,18 @@ func NewBlockfrostBackend(
 // Params:
 // projectId (string): The project ID to authenticate with Maestro.
 // network (Network): The network to configure the Maestro context for.
+// opts (...HttpClient.Option): The http client, retry and rate limit options.
 // Returns:
 // MaestroChainContext.MaestroChainContext: A MaestroChainContext instance configured for the specified network.
 func NewMaestroBackend(
 	projectId string,
 	network constants.Network,
+	opts ...HttpClient.Option,
 ) (MaestroChainContext.MaestroChainContext, error) {
 	return MaestroChainContext.NewMaestroChainContext(
 		int(network),
 		projectId,
+		opts...,
 	)
 
 }
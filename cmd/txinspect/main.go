@@ -0,0 +1,90 @@
+// Command txinspect prints a human-readable JSON report of a
+// transaction given as CBOR hex (or raw CBOR) in a file or on stdin.
+//
+// Usage:
+//
+//	txinspect [-diag] [-diag-only] [file]
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Salvionied/apollo/inspector"
+)
+
+func main() {
+	diag := flag.Bool("diag", false, "include the CBOR diagnostic notation in the report")
+	diagOnly := flag.Bool("diag-only", false, "only print the CBOR diagnostic notation")
+	flag.Parse()
+
+	err := run(flag.Args(), *diag, *diagOnly, os.Stdin, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "txinspect:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, diag bool, diagOnly bool, stdin io.Reader, stdout io.Writer) error {
+	var input []byte
+	var err error
+	switch len(args) {
+	case 0:
+		input, err = io.ReadAll(stdin)
+	case 1:
+		input, err = os.ReadFile(args[0])
+	default:
+		return fmt.Errorf("expected at most one file, got %d", len(args))
+	}
+	if err != nil {
+		return err
+	}
+	data, err := decodeInput(input)
+	if err != nil {
+		return err
+	}
+	if diagOnly {
+		res, err := inspector.Diagnose(data)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(stdout, res)
+		return err
+	}
+	report, err := inspector.InspectCbor(data, inspector.Options{Diagnostic: diag})
+	if err != nil {
+		return err
+	}
+	res, err := report.JSON()
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, string(res))
+	return err
+}
+
+// decodeInput accepts raw CBOR, CBOR hex, or a cardano-cli
+// text envelope ({"type": ..., "cborHex": ...}).
+func decodeInput(input []byte) ([]byte, error) {
+	trimmed := bytes.TrimSpace(input)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		var envelope struct {
+			CborHex string `json:"cborHex"`
+		}
+		err := json.Unmarshal(trimmed, &envelope)
+		if err != nil {
+			return nil, err
+		}
+		trimmed = []byte(envelope.CborHex)
+	}
+	decoded, err := hex.DecodeString(string(trimmed))
+	if err != nil {
+		return input, nil
+	}
+	return decoded, nil
+}
@@ -0,0 +1,432 @@
+package inspector
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+const (
+	MAJOR_UNSIGNED = 0
+	MAJOR_NEGATIVE = 1
+	MAJOR_BYTES    = 2
+	MAJOR_TEXT     = 3
+	MAJOR_ARRAY    = 4
+	MAJOR_MAP      = 5
+	MAJOR_TAG      = 6
+	MAJOR_SIMPLE   = 7
+)
+
+const MAX_NESTING = 512
+
+/*
+*
+
+	Item is a generic CBOR data item that keeps every detail
+	of the encoding needed to print it back in diagnostic
+	notation (indefinite lengths, chunks and float widths).
+*/
+type Item struct {
+	Major      byte
+	Value      uint64
+	Bytes      []byte
+	Chunks     []Item
+	Items      []Item
+	Indefinite bool
+	Float      float64
+	FloatSize  int
+}
+
+type itemReader struct {
+	data  []byte
+	pos   int
+	depth int
+}
+
+/*
+*
+
+	ParseItem decodes a single CBOR data item, failing if
+	the data contains trailing bytes.
+
+	Params:
+		data ([]byte): The CBOR-encoded data item.
+
+	Returns:
+		Item: The decoded data item.
+		error: An error if the data is not well-formed CBOR.
+*/
+func ParseItem(data []byte) (Item, error) {
+	reader := itemReader{data: data}
+	item, err := reader.read()
+	if err != nil {
+		return Item{}, err
+	}
+	if reader.pos != len(data) {
+		return Item{}, fmt.Errorf("unexpected %d trailing bytes", len(data)-reader.pos)
+	}
+	return item, nil
+}
+
+func (r *itemReader) next() (byte, error) {
+	if r.pos >= len(r.data) {
+		return 0, errors.New("unexpected end of cbor data")
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *itemReader) take(n uint64) ([]byte, error) {
+	if n > uint64(len(r.data)-r.pos) {
+		return nil, errors.New("unexpected end of cbor data")
+	}
+	res := r.data[r.pos : r.pos+int(n)]
+	r.pos += int(n)
+	return res, nil
+}
+
+func (r *itemReader) argument(info byte) (uint64, error) {
+	switch {
+	case info < 24:
+		return uint64(info), nil
+	case info == 24:
+		b, err := r.take(1)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(b[0]), nil
+	case info == 25:
+		b, err := r.take(2)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(binary.BigEndian.Uint16(b)), nil
+	case info == 26:
+		b, err := r.take(4)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	case info == 27:
+		b, err := r.take(8)
+		if err != nil {
+			return 0, err
+		}
+		return binary.BigEndian.Uint64(b), nil
+	}
+	return 0, fmt.Errorf("invalid additional information %d", info)
+}
+
+func (r *itemReader) read() (Item, error) {
+	r.depth++
+	defer func() { r.depth-- }()
+	if r.depth > MAX_NESTING {
+		return Item{}, errors.New("cbor data is nested too deeply")
+	}
+	initial, err := r.next()
+	if err != nil {
+		return Item{}, err
+	}
+	item := Item{Major: initial >> 5}
+	info := initial & 0x1f
+	if info == 31 {
+		return r.readIndefinite(item)
+	}
+	if item.Major == MAJOR_SIMPLE {
+		return r.readSimple(item, info)
+	}
+	item.Value, err = r.argument(info)
+	if err != nil {
+		return Item{}, err
+	}
+	switch item.Major {
+	case MAJOR_BYTES, MAJOR_TEXT:
+		item.Bytes, err = r.take(item.Value)
+		if err != nil {
+			return Item{}, err
+		}
+	case MAJOR_ARRAY, MAJOR_MAP:
+		count := item.Value
+		if item.Major == MAJOR_MAP {
+			count *= 2
+		}
+		if count > uint64(len(r.data)-r.pos) {
+			return Item{}, errors.New("unexpected end of cbor data")
+		}
+		item.Items = make([]Item, 0, count)
+		for i := uint64(0); i < count; i++ {
+			child, err := r.read()
+			if err != nil {
+				return Item{}, err
+			}
+			item.Items = append(item.Items, child)
+		}
+	case MAJOR_TAG:
+		child, err := r.read()
+		if err != nil {
+			return Item{}, err
+		}
+		item.Items = []Item{child}
+	}
+	return item, nil
+}
+
+func (r *itemReader) readIndefinite(item Item) (Item, error) {
+	if item.Major < MAJOR_BYTES || item.Major == MAJOR_TAG {
+		return Item{}, fmt.Errorf("invalid indefinite length for major type %d", item.Major)
+	}
+	if item.Major == MAJOR_SIMPLE {
+		return Item{}, errors.New("unexpected break")
+	}
+	item.Indefinite = true
+	for {
+		if r.pos < len(r.data) && r.data[r.pos] == 0xff {
+			r.pos++
+			break
+		}
+		child, err := r.read()
+		if err != nil {
+			return Item{}, err
+		}
+		switch item.Major {
+		case MAJOR_BYTES, MAJOR_TEXT:
+			if child.Major != item.Major || child.Indefinite {
+				return Item{}, errors.New("invalid chunk in indefinite length string")
+			}
+			item.Chunks = append(item.Chunks, child)
+			item.Bytes = append(item.Bytes, child.Bytes...)
+		default:
+			item.Items = append(item.Items, child)
+		}
+	}
+	if item.Major == MAJOR_MAP && len(item.Items)%2 != 0 {
+		return Item{}, errors.New("indefinite length map with odd number of items")
+	}
+	return item, nil
+}
+
+func (r *itemReader) readSimple(item Item, info byte) (Item, error) {
+	switch info {
+	case 25:
+		b, err := r.take(2)
+		if err != nil {
+			return Item{}, err
+		}
+		item.Float = halfToFloat(binary.BigEndian.Uint16(b))
+		item.FloatSize = 2
+	case 26:
+		b, err := r.take(4)
+		if err != nil {
+			return Item{}, err
+		}
+		item.Float = float64(math.Float32frombits(binary.BigEndian.Uint32(b)))
+		item.FloatSize = 4
+	case 27:
+		b, err := r.take(8)
+		if err != nil {
+			return Item{}, err
+		}
+		item.Float = math.Float64frombits(binary.BigEndian.Uint64(b))
+		item.FloatSize = 8
+	default:
+		value, err := r.argument(info)
+		if err != nil {
+			return Item{}, err
+		}
+		item.Value = value
+	}
+	return item, nil
+}
+
+func halfToFloat(half uint16) float64 {
+	exponent := int(half>>10) & 0x1f
+	mantissa := float64(half & 0x3ff)
+	var value float64
+	switch exponent {
+	case 0:
+		value = math.Ldexp(mantissa, -24)
+	case 31:
+		if mantissa == 0 {
+			value = math.Inf(1)
+		} else {
+			value = math.NaN()
+		}
+	default:
+		value = math.Ldexp(mantissa+1024, exponent-25)
+	}
+	if half&0x8000 != 0 {
+		return -value
+	}
+	return value
+}
+
+/*
+*
+
+	BigInt returns the integer value of an unsigned, negative
+	or bignum (tags 2 and 3) item.
+
+	Returns:
+		*big.Int: The integer value.
+		bool: Whether the item is an integer.
+*/
+func (item Item) BigInt() (*big.Int, bool) {
+	switch item.Major {
+	case MAJOR_UNSIGNED:
+		return new(big.Int).SetUint64(item.Value), true
+	case MAJOR_NEGATIVE:
+		value := new(big.Int).SetUint64(item.Value)
+		return value.Neg(value.Add(value, big.NewInt(1))), true
+	case MAJOR_TAG:
+		if (item.Value != 2 && item.Value != 3) || item.Items[0].Major != MAJOR_BYTES {
+			return nil, false
+		}
+		value := new(big.Int).SetBytes(item.Items[0].Bytes)
+		if item.Value == 3 {
+			value.Neg(value.Add(value, big.NewInt(1)))
+		}
+		return value, true
+	}
+	return nil, false
+}
+
+/*
+*
+
+	Diagnose returns the CBOR diagnostic notation (RFC 8949,
+	section 8) of a CBOR-encoded data item.
+
+	Params:
+		data ([]byte): The CBOR-encoded data item.
+
+	Returns:
+		string: The diagnostic notation.
+		error: An error if the data is not well-formed CBOR.
+*/
+func Diagnose(data []byte) (string, error) {
+	item, err := ParseItem(data)
+	if err != nil {
+		return "", err
+	}
+	return item.Diagnostic(), nil
+}
+
+/*
+*
+
+	Diagnostic returns the CBOR diagnostic notation of the item.
+
+	Returns:
+		string: The diagnostic notation.
+*/
+func (item Item) Diagnostic() string {
+	var sb strings.Builder
+	item.writeDiagnostic(&sb)
+	return sb.String()
+}
+
+func (item Item) writeDiagnostic(sb *strings.Builder) {
+	switch item.Major {
+	case MAJOR_UNSIGNED, MAJOR_NEGATIVE:
+		value, _ := item.BigInt()
+		sb.WriteString(value.String())
+	case MAJOR_BYTES:
+		if item.Indefinite {
+			writeChunks(sb, item.Chunks)
+			return
+		}
+		sb.WriteString("h'" + hex.EncodeToString(item.Bytes) + "'")
+	case MAJOR_TEXT:
+		if item.Indefinite {
+			writeChunks(sb, item.Chunks)
+			return
+		}
+		sb.WriteString(strconv.Quote(string(item.Bytes)))
+	case MAJOR_ARRAY:
+		sb.WriteString("[")
+		if item.Indefinite {
+			sb.WriteString("_ ")
+		}
+		for idx, child := range item.Items {
+			if idx > 0 {
+				sb.WriteString(", ")
+			}
+			child.writeDiagnostic(sb)
+		}
+		sb.WriteString("]")
+	case MAJOR_MAP:
+		sb.WriteString("{")
+		if item.Indefinite {
+			sb.WriteString("_ ")
+		}
+		for idx := 0; idx < len(item.Items); idx += 2 {
+			if idx > 0 {
+				sb.WriteString(", ")
+			}
+			item.Items[idx].writeDiagnostic(sb)
+			sb.WriteString(": ")
+			item.Items[idx+1].writeDiagnostic(sb)
+		}
+		sb.WriteString("}")
+	case MAJOR_TAG:
+		sb.WriteString(strconv.FormatUint(item.Value, 10) + "(")
+		item.Items[0].writeDiagnostic(sb)
+		sb.WriteString(")")
+	case MAJOR_SIMPLE:
+		sb.WriteString(item.simpleDiagnostic())
+	}
+}
+
+func writeChunks(sb *strings.Builder, chunks []Item) {
+	sb.WriteString("(_ ")
+	for idx, chunk := range chunks {
+		if idx > 0 {
+			sb.WriteString(", ")
+		}
+		chunk.writeDiagnostic(sb)
+	}
+	sb.WriteString(")")
+}
+
+func (item Item) simpleDiagnostic() string {
+	if item.FloatSize != 0 {
+		var res string
+		switch {
+		case math.IsNaN(item.Float):
+			res = "NaN"
+		case math.IsInf(item.Float, 1):
+			res = "Infinity"
+		case math.IsInf(item.Float, -1):
+			res = "-Infinity"
+		default:
+			res = strconv.FormatFloat(item.Float, 'g', -1, 64)
+			if !strings.ContainsAny(res, ".eN") {
+				res += ".0"
+			}
+		}
+		switch item.FloatSize {
+		case 2:
+			return res + "_1"
+		case 4:
+			return res + "_2"
+		}
+		return res + "_3"
+	}
+	switch item.Value {
+	case 20:
+		return "false"
+	case 21:
+		return "true"
+	case 22:
+		return "null"
+	case 23:
+		return "undefined"
+	}
+	return "simple(" + strconv.FormatUint(item.Value, 10) + ")"
+}
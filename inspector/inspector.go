@@ -0,0 +1,647 @@
+package inspector
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Salvionied/apollo/crypto/bech32"
+	"github.com/Salvionied/apollo/serialization/Address"
+	"github.com/Salvionied/apollo/serialization/AssetName"
+	"github.com/Salvionied/apollo/serialization/Certificate"
+	"github.com/Salvionied/apollo/serialization/Fingerprint"
+	"github.com/Salvionied/apollo/serialization/Metadata"
+	"github.com/Salvionied/apollo/serialization/MultiAsset"
+	"github.com/Salvionied/apollo/serialization/PlutusData"
+	"github.com/Salvionied/apollo/serialization/Policy"
+	"github.com/Salvionied/apollo/serialization/Redeemer"
+	"github.com/Salvionied/apollo/serialization/Transaction"
+	"github.com/Salvionied/apollo/serialization/TransactionInput"
+	"github.com/Salvionied/apollo/serialization/TransactionOutput"
+	"github.com/Salvionied/cbor/v2"
+	"golang.org/x/crypto/blake2b"
+)
+
+var CERTIFICATE_NAMES = map[int]string{
+	Certificate.STAKE_REGISTRATION:         "stake_registration",
+	Certificate.STAKE_DEREGISTRATION:       "stake_deregistration",
+	Certificate.STAKE_DELEGATION:           "stake_delegation",
+	Certificate.POOL_REGISTRATION:          "pool_registration",
+	Certificate.POOL_RETIREMENT:            "pool_retirement",
+	Certificate.GENESIS_KEY_DELEGATION:     "genesis_key_delegation",
+	Certificate.MOVE_INSTANTANEOUS_REWARDS: "move_instantaneous_rewards",
+	Certificate.REG_CERT:                   "reg_cert",
+	Certificate.UNREG_CERT:                 "unreg_cert",
+	Certificate.VOTE_DELEG_CERT:            "vote_deleg_cert",
+	Certificate.STAKE_VOTE_DELEG_CERT:      "stake_vote_deleg_cert",
+	Certificate.STAKE_REG_DELEG_CERT:       "stake_reg_deleg_cert",
+	Certificate.VOTE_REG_DELEG_CERT:        "vote_reg_deleg_cert",
+	Certificate.STAKE_VOTE_REG_DELEG_CERT:  "stake_vote_reg_deleg_cert",
+	Certificate.AUTH_COMMITTEE_HOT_CERT:    "auth_committee_hot_cert",
+	Certificate.RESIGN_COMMITTEE_COLD_CERT: "resign_committee_cold_cert",
+	Certificate.REG_DREP_CERT:              "reg_drep_cert",
+	Certificate.UNREG_DREP_CERT:            "unreg_drep_cert",
+	Certificate.UPDATE_DREP_CERT:           "update_drep_cert",
+}
+
+var SCRIPT_LANGUAGES = map[uint64]string{
+	0: "native",
+	1: "plutus_v1",
+	2: "plutus_v2",
+	3: "plutus_v3",
+}
+
+type Options struct {
+	Diagnostic bool
+}
+
+type InputReport struct {
+	TransactionId string `json:"transaction_id"`
+	Index         int    `json:"index"`
+}
+
+type AssetReport struct {
+	PolicyId    string `json:"policy_id"`
+	AssetName   string `json:"asset_name"`
+	AssetAscii  string `json:"asset_name_ascii,omitempty"`
+	Fingerprint string `json:"fingerprint"`
+	Quantity    int64  `json:"quantity"`
+}
+
+type ScriptReport struct {
+	Language string `json:"language"`
+	Hash     string `json:"hash"`
+	Size     int    `json:"size"`
+}
+
+type OutputReport struct {
+	Address     string        `json:"address"`
+	Lovelace    int64         `json:"lovelace"`
+	Assets      []AssetReport `json:"assets,omitempty"`
+	DatumHash   string        `json:"datum_hash,omitempty"`
+	InlineDatum any           `json:"inline_datum,omitempty"`
+	ScriptRef   *ScriptReport `json:"script_ref,omitempty"`
+}
+
+type CredentialReport struct {
+	Type string `json:"type"`
+	Hash string `json:"hash"`
+}
+
+type CertificateReport struct {
+	Type       string            `json:"type"`
+	Credential *CredentialReport `json:"credential,omitempty"`
+	Fields     []string          `json:"fields,omitempty"`
+}
+
+type WithdrawalReport struct {
+	Address  string `json:"address"`
+	Lovelace int    `json:"lovelace"`
+}
+
+type VkeyWitnessReport struct {
+	Vkey      string `json:"vkey"`
+	KeyHash   string `json:"key_hash"`
+	Signature string `json:"signature"`
+}
+
+type ExUnitsReport struct {
+	Mem   int64 `json:"mem"`
+	Steps int64 `json:"steps"`
+}
+
+type RedeemerReport struct {
+	Purpose string        `json:"purpose"`
+	Index   int           `json:"index"`
+	Data    any           `json:"data"`
+	ExUnits ExUnitsReport `json:"ex_units"`
+}
+
+type DatumReport struct {
+	Hash  string `json:"hash"`
+	Value any    `json:"value"`
+}
+
+type WitnessReport struct {
+	VkeyWitnesses      []VkeyWitnessReport `json:"vkey_witnesses,omitempty"`
+	BootstrapWitnesses int                 `json:"bootstrap_witnesses,omitempty"`
+	Scripts            []ScriptReport      `json:"scripts,omitempty"`
+	Datums             []DatumReport       `json:"datums,omitempty"`
+	Redeemers          []RedeemerReport    `json:"redeemers,omitempty"`
+}
+
+type MetadataReport struct {
+	Label uint64 `json:"label"`
+	Value any    `json:"value"`
+}
+
+/*
+*
+
+	Report is the human-readable representation of a
+	transaction produced by Inspect.
+*/
+type Report struct {
+	Id                   string              `json:"id"`
+	Era                  string              `json:"era"`
+	Size                 int                 `json:"size"`
+	Valid                bool                `json:"valid"`
+	Fee                  int64               `json:"fee"`
+	Ttl                  int64               `json:"ttl,omitempty"`
+	ValidityStart        int64               `json:"validity_start,omitempty"`
+	Inputs               []InputReport       `json:"inputs"`
+	ReferenceInputs      []InputReport       `json:"reference_inputs,omitempty"`
+	Collateral           []InputReport       `json:"collateral,omitempty"`
+	Outputs              []OutputReport      `json:"outputs"`
+	CollateralReturn     *OutputReport       `json:"collateral_return,omitempty"`
+	TotalCollateral      int                 `json:"total_collateral,omitempty"`
+	Certificates         []CertificateReport `json:"certificates,omitempty"`
+	Withdrawals          []WithdrawalReport  `json:"withdrawals,omitempty"`
+	Mint                 []AssetReport       `json:"mint,omitempty"`
+	RequiredSigners      []string            `json:"required_signers,omitempty"`
+	ScriptDataHash       string              `json:"script_data_hash,omitempty"`
+	AuxiliaryDataHash    string              `json:"auxiliary_data_hash,omitempty"`
+	NetworkId            string              `json:"network_id,omitempty"`
+	VotingProcedures     string              `json:"voting_procedures,omitempty"`
+	ProposalProcedures   string              `json:"proposal_procedures,omitempty"`
+	CurrentTreasuryValue int64               `json:"current_treasury_value,omitempty"`
+	Donation             int64               `json:"donation,omitempty"`
+	Witnesses            WitnessReport       `json:"witnesses"`
+	Metadata             []MetadataReport    `json:"metadata,omitempty"`
+	Diagnostic           string              `json:"cbor_diagnostic,omitempty"`
+}
+
+/*
+*
+
+	InspectCbor decodes a CBOR-encoded transaction and
+	builds its Report.
+
+	Params:
+		data ([]byte): The CBOR-encoded transaction.
+		options (Options): The inspection options.
+
+	Returns:
+		*Report: The report of the transaction.
+		error: An error if the transaction cannot be decoded.
+*/
+func InspectCbor(data []byte, options Options) (*Report, error) {
+	tx := Transaction.Transaction{}
+	err := cbor.Unmarshal(data, &tx)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding transaction, %s", err)
+	}
+	return Inspect(&tx, options)
+}
+
+/*
+*
+
+	Inspect builds the Report of a transaction: inputs, outputs
+	with bech32 addresses and decoded assets, datums and
+	redeemers as detailed schema JSON, certificates, mint,
+	required signers, hashes and metadata.
+
+	Params:
+		tx (*Transaction.Transaction): The transaction to inspect.
+		options (Options): The inspection options.
+
+	Returns:
+		*Report: The report of the transaction.
+		error: An error if a part of the transaction cannot be encoded.
+*/
+func Inspect(tx *Transaction.Transaction, options Options) (*Report, error) {
+	encoded, err := tx.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	body := tx.TransactionBody
+	id, err := body.Id()
+	if err != nil {
+		return nil, err
+	}
+	report := Report{
+		Id:                   hex.EncodeToString(id.Payload),
+		Era:                  tx.Era.String(),
+		Size:                 len(encoded),
+		Valid:                tx.Valid,
+		Fee:                  body.Fee,
+		Ttl:                  body.Ttl,
+		ValidityStart:        body.ValidityStart,
+		Inputs:               inputReports(body.Inputs),
+		ReferenceInputs:      inputReports(body.ReferenceInputs),
+		Collateral:           inputReports(body.Collateral),
+		TotalCollateral:      body.TotalCollateral,
+		Mint:                 assetReports(body.Mint),
+		ScriptDataHash:       hex.EncodeToString(body.ScriptDataHash),
+		AuxiliaryDataHash:    hex.EncodeToString(body.AuxiliaryDataHash),
+		NetworkId:            hex.EncodeToString(body.NetworkId),
+		CurrentTreasuryValue: body.CurrentTreasuryValue,
+		Donation:             body.Donation,
+	}
+	report.Outputs = make([]OutputReport, 0, len(body.Outputs))
+	for idx := range body.Outputs {
+		output, err := outputReport(&body.Outputs[idx])
+		if err != nil {
+			return nil, fmt.Errorf("output %d: %s", idx, err)
+		}
+		report.Outputs = append(report.Outputs, output)
+	}
+	if body.CollateralReturn != nil {
+		output, err := outputReport(body.CollateralReturn)
+		if err != nil {
+			return nil, fmt.Errorf("collateral return: %s", err)
+		}
+		report.CollateralReturn = &output
+	}
+	if body.Certificates != nil {
+		for _, cert := range *body.Certificates {
+			report.Certificates = append(report.Certificates, certificateReport(cert))
+		}
+	}
+	if body.Withdrawals != nil {
+		report.Withdrawals = withdrawalReports(*body.Withdrawals)
+	}
+	for _, signer := range body.RequiredSigners {
+		report.RequiredSigners = append(report.RequiredSigners, hex.EncodeToString(signer[:]))
+	}
+	if len(body.VotingProcedures) > 0 {
+		report.VotingProcedures, err = Diagnose(body.VotingProcedures)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(body.ProposalProcedures) > 0 {
+		report.ProposalProcedures, err = Diagnose(body.ProposalProcedures)
+		if err != nil {
+			return nil, err
+		}
+	}
+	report.Witnesses, err = witnessReport(tx)
+	if err != nil {
+		return nil, err
+	}
+	if tx.AuxiliaryData != nil {
+		report.Metadata, err = metadataReports(tx.AuxiliaryData)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if options.Diagnostic {
+		report.Diagnostic, err = Diagnose(encoded)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &report, nil
+}
+
+/*
+*
+
+	JSON returns the indented JSON encoding of the report.
+
+	Returns:
+		[]byte: The JSON-encoded report.
+		error: An error if the encoding fails.
+*/
+func (r *Report) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+func inputReports(inputs []TransactionInput.TransactionInput) []InputReport {
+	if len(inputs) == 0 {
+		return nil
+	}
+	res := make([]InputReport, 0, len(inputs))
+	for _, input := range inputs {
+		res = append(res, InputReport{
+			TransactionId: hex.EncodeToString(input.TransactionId),
+			Index:         input.Index,
+		})
+	}
+	return res
+}
+
+func assetReports(assets MultiAsset.MultiAsset[int64]) []AssetReport {
+	res := make([]AssetReport, 0)
+	for policy, tokens := range assets {
+		for name, quantity := range tokens {
+			policyId := Policy.PolicyId{Value: policy.Value}
+			assetName := name
+			report := AssetReport{
+				PolicyId:    policy.Value,
+				AssetName:   name.HexString(),
+				Fingerprint: Fingerprint.New(&policyId, &assetName).String(),
+				Quantity:    quantity,
+			}
+			if isReadable(name) {
+				report.AssetAscii = name.String()
+			}
+			res = append(res, report)
+		}
+	}
+	if len(res) == 0 {
+		return nil
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].PolicyId != res[j].PolicyId {
+			return res[i].PolicyId < res[j].PolicyId
+		}
+		return res[i].AssetName < res[j].AssetName
+	})
+	return res
+}
+
+func isReadable(name AssetName.AssetName) bool {
+	value := name.String()
+	if value == "" || !utf8.ValidString(value) {
+		return false
+	}
+	for _, r := range value {
+		if !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func outputReport(output *TransactionOutput.TransactionOutput) (OutputReport, error) {
+	value := output.GetValue()
+	res := OutputReport{
+		Address:  AddressString(output.GetAddress()),
+		Lovelace: value.GetCoin(),
+		Assets:   assetReports(value.GetAssets()),
+	}
+	if datum := output.GetDatumOption(); datum != nil {
+		switch datum.DatumType {
+		case PlutusData.DatumTypeHash:
+			res.DatumHash = hex.EncodeToString(datum.Hash)
+		case PlutusData.DatumTypeInline:
+			inline, err := plutusDataJSON(datum.Inline)
+			if err != nil {
+				return res, err
+			}
+			res.InlineDatum = inline
+		}
+	} else if hash := output.GetDatumHash(); hash != nil && len(hash.Payload) > 0 {
+		res.DatumHash = hex.EncodeToString(hash.Payload)
+	}
+	if output.IsPostAlonzo && output.PostAlonzo.ScriptRef != nil {
+		scriptRef := output.PostAlonzo.ScriptRef
+		hash, err := scriptRef.Hash()
+		if err != nil {
+			return res, err
+		}
+		res.ScriptRef = &ScriptReport{
+			Language: SCRIPT_LANGUAGES[scriptRef.Type()],
+			Hash:     hex.EncodeToString(hash[:]),
+			Size:     len(scriptRef.Script.Script),
+		}
+	}
+	return res, nil
+}
+
+func plutusDataJSON(pd *PlutusData.PlutusData) (any, error) {
+	encoded, err := cbor.Marshal(pd)
+	if err != nil {
+		return nil, err
+	}
+	return PlutusDataJSON(encoded)
+}
+
+/*
+*
+
+	AddressString returns the textual form of an address:
+	base58 for Byron addresses and bech32 for every other type.
+
+	Params:
+		addr (Address.Address): The address.
+
+	Returns:
+		string: The encoded address.
+*/
+func AddressString(addr Address.Address) string {
+	raw := append([]byte{addr.HeaderByte}, addr.PaymentPart...)
+	raw = append(raw, addr.StakingPart...)
+	if addr.AddressType == Address.BYRON {
+		return base58(raw)
+	}
+	words, err := bech32.ConvertBits(raw, 8, 5, true)
+	if err != nil {
+		return hex.EncodeToString(raw)
+	}
+	res, err := bech32.Encode(Address.ComputeHrp(addr.AddressType, addr.Network), words)
+	if err != nil {
+		return hex.EncodeToString(raw)
+	}
+	return res
+}
+
+const BASE58_ALPHABET = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+func base58(data []byte) string {
+	value := new(big.Int).SetBytes(data)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+	res := make([]byte, 0, len(data)*138/100+1)
+	for value.Sign() > 0 {
+		value.DivMod(value, radix, mod)
+		res = append(res, BASE58_ALPHABET[mod.Int64()])
+	}
+	for _, b := range data {
+		if b != 0 {
+			break
+		}
+		res = append(res, BASE58_ALPHABET[0])
+	}
+	for i, j := 0, len(res)-1; i < j; i, j = i+1, j-1 {
+		res[i], res[j] = res[j], res[i]
+	}
+	return string(res)
+}
+
+func certificateReport(cert *Certificate.Certificate) CertificateReport {
+	name, ok := CERTIFICATE_NAMES[cert.Kind]
+	if !ok {
+		name = fmt.Sprintf("unknown_%d", cert.Kind)
+	}
+	res := CertificateReport{Type: name}
+	if cert.StakeCredential != nil {
+		credType := "key_hash"
+		if cert.StakeCredential.Code == 1 {
+			credType = "script_hash"
+		}
+		res.Credential = &CredentialReport{
+			Type: credType,
+			Hash: hex.EncodeToString(cert.StakeCredential.Credential.Payload),
+		}
+	}
+	for _, field := range cert.Fields {
+		diag, err := Diagnose(field)
+		if err != nil {
+			diag = hex.EncodeToString(field)
+		}
+		res.Fields = append(res.Fields, diag)
+	}
+	return res
+}
+
+func withdrawalReports(withdrawals map[[29]byte]int) []WithdrawalReport {
+	res := make([]WithdrawalReport, 0, len(withdrawals))
+	for rewardAddress, amount := range withdrawals {
+		hrp := "stake_test"
+		if rewardAddress[0]&0x0f == Address.MAINNET {
+			hrp = "stake"
+		}
+		address := hex.EncodeToString(rewardAddress[:])
+		words, err := bech32.ConvertBits(rewardAddress[:], 8, 5, true)
+		if err == nil {
+			encoded, err := bech32.Encode(hrp, words)
+			if err == nil {
+				address = encoded
+			}
+		}
+		res = append(res, WithdrawalReport{Address: address, Lovelace: amount})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Address < res[j].Address
+	})
+	return res
+}
+
+func witnessReport(tx *Transaction.Transaction) (WitnessReport, error) {
+	witnesses := tx.TransactionWitnessSet
+	res := WitnessReport{BootstrapWitnesses: len(witnesses.BootstrapWitnesses)}
+	for _, witness := range witnesses.VkeyWitnesses {
+		keyHash, err := witness.Vkey.Hash()
+		if err != nil {
+			return res, err
+		}
+		res.VkeyWitnesses = append(res.VkeyWitnesses, VkeyWitnessReport{
+			Vkey:      hex.EncodeToString(witness.Vkey.Payload),
+			KeyHash:   hex.EncodeToString(keyHash[:]),
+			Signature: hex.EncodeToString(witness.Signature),
+		})
+	}
+	for _, script := range witnesses.NativeScripts {
+		hash, err := script.Hash()
+		if err != nil {
+			return res, err
+		}
+		encoded, err := cbor.Marshal(&script)
+		if err != nil {
+			return res, err
+		}
+		res.Scripts = append(res.Scripts, ScriptReport{Language: SCRIPT_LANGUAGES[0], Hash: hex.EncodeToString(hash[:]), Size: len(encoded)})
+	}
+	for _, script := range witnesses.PlutusV1Script {
+		res.Scripts = append(res.Scripts, plutusScriptReport(1, script))
+	}
+	for _, script := range witnesses.PlutusV2Script {
+		res.Scripts = append(res.Scripts, plutusScriptReport(2, script))
+	}
+	for _, script := range witnesses.PlutusV3Script {
+		res.Scripts = append(res.Scripts, plutusScriptReport(3, script))
+	}
+	for idx := range witnesses.PlutusData {
+		datum := &witnesses.PlutusData[idx]
+		hash, err := PlutusData.PlutusDataHash(datum)
+		if err != nil {
+			return res, err
+		}
+		value, err := plutusDataJSON(datum)
+		if err != nil {
+			return res, err
+		}
+		res.Datums = append(res.Datums, DatumReport{Hash: hex.EncodeToString(hash.Payload), Value: value})
+	}
+	for idx := range witnesses.Redeemer {
+		redeemer := &witnesses.Redeemer[idx]
+		data, err := plutusDataJSON(&redeemer.Data)
+		if err != nil {
+			return res, err
+		}
+		purpose, ok := Redeemer.RdeemerTagNames[redeemer.Tag]
+		if !ok {
+			purpose = fmt.Sprintf("unknown_%d", redeemer.Tag)
+		}
+		res.Redeemers = append(res.Redeemers, RedeemerReport{
+			Purpose: purpose,
+			Index:   redeemer.Index,
+			Data:    data,
+			ExUnits: ExUnitsReport{Mem: redeemer.ExUnits.Mem, Steps: redeemer.ExUnits.Steps},
+		})
+	}
+	return res, nil
+}
+
+func plutusScriptReport[T ~[]byte](language uint64, script T) ScriptReport {
+	hash, _ := blake2b.New(28, nil)
+	hash.Write(append([]byte{byte(language)}, script...))
+	return ScriptReport{
+		Language: SCRIPT_LANGUAGES[language],
+		Hash:     hex.EncodeToString(hash.Sum(nil)),
+		Size:     len(script),
+	}
+}
+
+func metadataReports(aux *Metadata.AuxiliaryData) ([]MetadataReport, error) {
+	encoded, err := cbor.Marshal(aux)
+	if err != nil {
+		return nil, err
+	}
+	item, err := ParseItem(encoded)
+	if err != nil {
+		return nil, err
+	}
+	metadata, ok, err := metadataMap(item)
+	if err != nil || !ok {
+		return nil, err
+	}
+	res := make([]MetadataReport, 0, len(metadata.Items)/2)
+	for idx := 0; idx < len(metadata.Items); idx += 2 {
+		label := metadata.Items[idx]
+		if label.Major != MAJOR_UNSIGNED {
+			return nil, errors.New("invalid metadata label")
+		}
+		value, err := metadatumItemJSON(metadata.Items[idx+1])
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, MetadataReport{Label: label.Value, Value: value})
+	}
+	return res, nil
+}
+
+func metadataMap(item Item) (Item, bool, error) {
+	switch item.Major {
+	case MAJOR_MAP:
+		return item, true, nil
+	case MAJOR_ARRAY:
+		if len(item.Items) == 0 || item.Items[0].Major != MAJOR_MAP {
+			return Item{}, false, errors.New("invalid auxiliary data")
+		}
+		return item.Items[0], true, nil
+	case MAJOR_TAG:
+		content := item.Items[0]
+		if item.Value != 259 || content.Major != MAJOR_MAP {
+			return Item{}, false, errors.New("invalid auxiliary data")
+		}
+		for idx := 0; idx < len(content.Items); idx += 2 {
+			if content.Items[idx].Major == MAJOR_UNSIGNED && content.Items[idx].Value == 0 {
+				return content.Items[idx+1], content.Items[idx+1].Major == MAJOR_MAP, nil
+			}
+		}
+		return Item{}, false, nil
+	case MAJOR_SIMPLE:
+		return Item{}, false, nil
+	}
+	return Item{}, false, errors.New("invalid auxiliary data")
+}
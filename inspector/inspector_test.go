@@ -0,0 +1,242 @@
+package inspector_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Salvionied/apollo/inspector"
+	"github.com/Salvionied/apollo/serialization/Address"
+	"github.com/Salvionied/apollo/serialization/Block"
+)
+
+func loadHex(t *testing.T, path string) []byte {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := hex.DecodeString(strings.TrimSpace(string(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+func TestDiagnose(t *testing.T) {
+	testCases := map[string]string{
+		"00":                         "0",
+		"20":                         "-1",
+		"3903e7":                     "-1000",
+		"1bffffffffffffffff":         "18446744073709551615",
+		"3bffffffffffffffff":         "-18446744073709551616",
+		"c249010000000000000000":     "2(h'010000000000000000')",
+		"40":                         "h''",
+		"4401020304":                 "h'01020304'",
+		"6161":                       `"a"`,
+		"62225c":                     `"\"\\"`,
+		"83010203":                   "[1, 2, 3]",
+		"9f018202039f0405ffff":       "[_ 1, [2, 3], [_ 4, 5]]",
+		"a201020304":                 "{1: 2, 3: 4}",
+		"bf61610161629f0203ffff":     `{_ "a": 1, "b": [_ 2, 3]}`,
+		"5f42010243030405ff":         "(_ h'0102', h'030405')",
+		"7f657374726561646d696e67ff": `(_ "strea", "ming")`,
+		"d8799f4180ff":               "121([_ h'80'])",
+		"f4":                         "false",
+		"f5":                         "true",
+		"f6":                         "null",
+		"f7":                         "undefined",
+		"f0":                         "simple(16)",
+		"f93e00":                     "1.5_1",
+		"fa47c35000":                 "100000.0_2",
+		"fb3ff199999999999a":         "1.1_3",
+		"f97c00":                     "Infinity_1",
+	}
+	for input, expected := range testCases {
+		decoded, _ := hex.DecodeString(input)
+		res, err := inspector.Diagnose(decoded)
+		if err != nil {
+			t.Error(input, err)
+			continue
+		}
+		if res != expected {
+			t.Error("Invalid diagnostic for", input, "got", res, "expected", expected)
+		}
+	}
+	for _, input := range []string{"", "830102", "1a0000", "ff", "0000", "5f01ff"} {
+		decoded, _ := hex.DecodeString(input)
+		_, err := inspector.Diagnose(decoded)
+		if err == nil {
+			t.Error("Expected error for malformed cbor", input)
+		}
+	}
+}
+
+func TestPlutusDataJSON(t *testing.T) {
+	decoded, _ := hex.DecodeString("d87a9f1864c2490100000000000000003a000f423f9f4100ffa1410102d905029f0280ffff")
+	res, err := inspector.PlutusDataJSON(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	marshaled, err := json.Marshal(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"constructor":1,"fields":[{"int":100},{"int":18446744073709551616},{"int":-1000000},{"list":[{"bytes":"00"}]},{"map":[{"k":{"bytes":"01"},"v":{"int":2}}]},{"constructor":9,"fields":[{"int":2},{"list":[]}]}]}`
+	if string(marshaled) != expected {
+		t.Error("Invalid detailed schema", string(marshaled))
+	}
+	general, _ := hex.DecodeString("d866821903e88100")
+	res, err = inspector.PlutusDataJSON(general)
+	if err != nil {
+		t.Fatal(err)
+	}
+	marshaled, _ = json.Marshal(res)
+	if string(marshaled) != `{"constructor":1000,"fields":[{"int":0}]}` {
+		t.Error("Invalid general constructor", string(marshaled))
+	}
+	text, _ := hex.DecodeString("6161")
+	_, err = inspector.PlutusDataJSON(text)
+	if err == nil {
+		t.Error("Text strings are not plutus data")
+	}
+}
+
+func TestMetadatumJSON(t *testing.T) {
+	decoded, _ := hex.DecodeString("a2636b657983012041ff6161a0")
+	res, err := inspector.MetadatumJSON(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	marshaled, _ := json.Marshal(res)
+	expected := `{"map":[{"k":{"string":"key"},"v":{"list":[{"int":1},{"int":-1},{"bytes":"ff"}]}},{"k":{"string":"a"},"v":{"map":[]}}]}`
+	if string(marshaled) != expected {
+		t.Error("Invalid metadatum", string(marshaled))
+	}
+}
+
+func TestInspectBabbageTransaction(t *testing.T) {
+	data := loadHex(t, "testdata/babbage_tx.hex")
+	report, err := inspector.InspectCbor(data, inspector.Options{Diagnostic: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Id != "9c382d8b315d62f882590c90324956bb0da412872988f5491607aaca07406658" {
+		t.Error("Invalid id", report.Id)
+	}
+	if report.Era != "babbage" || report.Size != len(data) || !report.Valid || report.Fee != 213725 {
+		t.Error("Invalid report header", report.Era, report.Size, report.Valid, report.Fee)
+	}
+	if len(report.Inputs) != 3 || report.Inputs[0].TransactionId != "5628043acaccaf3e07ce6d93bec8da6ae013d2546aa1f491c68dfa2942e6aab4" || report.Inputs[0].Index != 1 {
+		t.Error("Invalid inputs", report.Inputs)
+	}
+	if len(report.Outputs) != 3 || report.Outputs[0].Address != "addr1wynp362vmvr8jtc946d3a3utqgclfdl5y9d3kn849e359hsskr20n" {
+		t.Error("Invalid outputs", report.Outputs)
+	}
+	datum, ok := report.Outputs[0].InlineDatum.(map[string]any)
+	if !ok || datum["constructor"] != uint64(0) {
+		t.Error("Invalid inline datum", report.Outputs[0].InlineDatum)
+	}
+	if report.Outputs[1].ScriptRef != nil {
+		t.Error("Unexpected script ref")
+	}
+	found := false
+	for _, asset := range report.Outputs[2].Assets {
+		if asset.AssetAscii == "GERO" && asset.Quantity == 2000000 && asset.Fingerprint == "asset13ld64lsa4xrwk8ws7kjhtwzyefpag0p88gw6jp" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Missing decoded asset", report.Outputs[2].Assets)
+	}
+	if !strings.HasPrefix(report.Diagnostic, "[{0: [[h'5628043acaccaf3e07ce6d93bec8da6ae013d2546aa1f491c68dfa2942e6aab4', 1]") {
+		t.Error("Invalid diagnostic", report.Diagnostic[:100])
+	}
+	marshaled, err := report.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parsed map[string]any
+	err = json.Unmarshal(marshaled, &parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := parsed["cbor_diagnostic"]; !ok {
+		t.Error("Missing diagnostic in json")
+	}
+	noDiag, _ := inspector.InspectCbor(data, inspector.Options{})
+	if noDiag.Diagnostic != "" {
+		t.Error("Diagnostic should be opt-in")
+	}
+}
+
+func TestInspectBlockTransactions(t *testing.T) {
+	conway, err := Block.DecodeBlock(loadHex(t, "../serialization/Block/testdata/synthetic/conway.hex"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	txs := conway.Transactions()
+	report, err := inspector.Inspect(&txs[0], inspector.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Certificates) != 2 || report.Certificates[0].Type != "reg_cert" || report.Certificates[1].Type != "vote_deleg_cert" {
+		t.Error("Invalid certificates", report.Certificates)
+	}
+	if report.Certificates[0].Credential == nil || report.Certificates[0].Credential.Type != "key_hash" {
+		t.Error("Invalid certificate credential", report.Certificates[0])
+	}
+	if report.Donation != 1000000 || report.Era != "conway" {
+		t.Error("Invalid conway report")
+	}
+	report, err = inspector.Inspect(&txs[1], inspector.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	redeemers := report.Witnesses.Redeemers
+	if len(redeemers) != 1 || redeemers[0].Purpose != "mint" || redeemers[0].ExUnits.Mem != 2000 {
+		t.Error("Invalid redeemers", redeemers)
+	}
+	if len(report.Witnesses.Scripts) != 1 || report.Witnesses.Scripts[0].Language != "plutus_v3" || len(report.Witnesses.VkeyWitnesses) != 1 {
+		t.Error("Invalid witnesses", report.Witnesses)
+	}
+	if report.ScriptDataHash == "" || len(report.Collateral) != 1 {
+		t.Error("Invalid script fields", report.ScriptDataHash, report.Collateral)
+	}
+
+	alonzo, err := Block.DecodeBlock(loadHex(t, "../serialization/Block/testdata/synthetic/alonzo.hex"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	alonzoTx := alonzo.Transactions()[0]
+	report, err = inspector.Inspect(&alonzoTx, inspector.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Metadata) != 1 || report.Metadata[0].Label != 674 {
+		t.Error("Missing metadata", report.Metadata)
+	}
+	if report.Outputs[0].DatumHash == "" || len(report.Witnesses.Datums) != 1 {
+		t.Error("Missing datums", report.Outputs[0], report.Witnesses.Datums)
+	}
+}
+
+func TestAddressString(t *testing.T) {
+	byron := Address.Address{
+		HeaderByte:  'h',
+		PaymentPart: []byte("ello world"),
+		AddressType: Address.BYRON,
+	}
+	if res := inspector.AddressString(byron); res != "StV1DL6CwTryKyV" {
+		t.Error("Invalid base58 encoding", res)
+	}
+	addr, err := Address.DecodeAddress("addr1qxajla3qcrwckzkur8n0lt02rg2sepw3kgkstckmzrz4ccfm3j9pqrqkea3tns46e3qy2w42vl8dvvue8u45amzm3rjqvv2nxh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := inspector.AddressString(addr); res != addr.String() || !bytes.HasPrefix([]byte(res), []byte("addr1")) {
+		t.Error("Invalid bech32 encoding", res)
+	}
+}
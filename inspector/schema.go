@@ -0,0 +1,169 @@
+package inspector
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+/*
+*
+
+	PlutusDataJSON converts CBOR-encoded plutus data into the
+	detailed schema JSON used by cardano-cli and the blueprint
+	tooling (constructor/fields, int, bytes, list and map).
+
+	Params:
+		data ([]byte): The CBOR-encoded plutus data.
+
+	Returns:
+		any: A value ready to be marshaled with encoding/json.
+		error: An error if the data is not valid plutus data.
+*/
+func PlutusDataJSON(data []byte) (any, error) {
+	item, err := ParseItem(data)
+	if err != nil {
+		return nil, err
+	}
+	return plutusItemJSON(item)
+}
+
+func plutusItemJSON(item Item) (any, error) {
+	if value, ok := item.BigInt(); ok {
+		return map[string]any{"int": json.Number(value.String())}, nil
+	}
+	switch item.Major {
+	case MAJOR_BYTES:
+		return map[string]any{"bytes": hex.EncodeToString(item.Bytes)}, nil
+	case MAJOR_ARRAY:
+		list, err := plutusListJSON(item.Items)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"list": list}, nil
+	case MAJOR_MAP:
+		entries := make([]any, 0, len(item.Items)/2)
+		for idx := 0; idx < len(item.Items); idx += 2 {
+			key, err := plutusItemJSON(item.Items[idx])
+			if err != nil {
+				return nil, err
+			}
+			value, err := plutusItemJSON(item.Items[idx+1])
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, map[string]any{"k": key, "v": value})
+		}
+		return map[string]any{"map": entries}, nil
+	case MAJOR_TAG:
+		constructor, fields, err := constructorFields(item)
+		if err != nil {
+			return nil, err
+		}
+		list, err := plutusListJSON(fields)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"constructor": constructor, "fields": list}, nil
+	}
+	return nil, fmt.Errorf("invalid plutus data item with major type %d", item.Major)
+}
+
+func constructorFields(item Item) (uint64, []Item, error) {
+	content := item.Items[0]
+	switch {
+	case item.Value >= 121 && item.Value <= 127:
+		if content.Major != MAJOR_ARRAY {
+			return 0, nil, errors.New("constructor fields must be an array")
+		}
+		return item.Value - 121, content.Items, nil
+	case item.Value >= 1280 && item.Value <= 1400:
+		if content.Major != MAJOR_ARRAY {
+			return 0, nil, errors.New("constructor fields must be an array")
+		}
+		return item.Value - 1280 + 7, content.Items, nil
+	case item.Value == 102:
+		if content.Major != MAJOR_ARRAY || len(content.Items) != 2 ||
+			content.Items[0].Major != MAJOR_UNSIGNED || content.Items[1].Major != MAJOR_ARRAY {
+			return 0, nil, errors.New("invalid general constructor")
+		}
+		return content.Items[0].Value, content.Items[1].Items, nil
+	}
+	return 0, nil, fmt.Errorf("invalid plutus data tag %d", item.Value)
+}
+
+func plutusListJSON(items []Item) ([]any, error) {
+	list := make([]any, 0, len(items))
+	for _, child := range items {
+		value, err := plutusItemJSON(child)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, value)
+	}
+	return list, nil
+}
+
+/*
+*
+
+	MetadatumJSON converts a CBOR-encoded transaction metadatum
+	into the detailed schema JSON used by cardano-cli
+	(int, bytes, string, list and map).
+
+	Params:
+		data ([]byte): The CBOR-encoded metadatum.
+
+	Returns:
+		any: A value ready to be marshaled with encoding/json.
+		error: An error if the data is not a valid metadatum.
+*/
+func MetadatumJSON(data []byte) (any, error) {
+	item, err := ParseItem(data)
+	if err != nil {
+		return nil, err
+	}
+	return metadatumItemJSON(item)
+}
+
+func metadatumItemJSON(item Item) (any, error) {
+	switch item.Major {
+	case MAJOR_UNSIGNED, MAJOR_NEGATIVE:
+		value, _ := item.BigInt()
+		return map[string]any{"int": json.Number(value.String())}, nil
+	case MAJOR_BYTES:
+		return map[string]any{"bytes": hex.EncodeToString(item.Bytes)}, nil
+	case MAJOR_TEXT:
+		if !utf8.Valid(item.Bytes) {
+			return nil, errors.New("invalid utf-8 metadata string")
+		}
+		return map[string]any{"string": string(item.Bytes)}, nil
+	case MAJOR_ARRAY:
+		list := make([]any, 0, len(item.Items))
+		for _, child := range item.Items {
+			value, err := metadatumItemJSON(child)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return map[string]any{"list": list}, nil
+	case MAJOR_MAP:
+		entries := make([]any, 0, len(item.Items)/2)
+		for idx := 0; idx < len(item.Items); idx += 2 {
+			key, err := metadatumItemJSON(item.Items[idx])
+			if err != nil {
+				return nil, err
+			}
+			value, err := metadatumItemJSON(item.Items[idx+1])
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, map[string]any{"k": key, "v": value})
+		}
+		return map[string]any{"map": entries}, nil
+	}
+	return nil, fmt.Errorf("invalid metadatum with major type %d", item.Major)
+}
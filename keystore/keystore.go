@@ -0,0 +1,715 @@
+package keystore
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/Salvionied/apollo/apollotypes"
+	"github.com/Salvionied/apollo/constants"
+	"github.com/Salvionied/apollo/crypto/bip32"
+	serAddress "github.com/Salvionied/apollo/serialization/Address"
+	"github.com/Salvionied/apollo/serialization/HDWallet"
+	"github.com/Salvionied/apollo/serialization/Key"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+const KEYSTORE_VERSION = 2
+
+const (
+	KDF_SCRYPT   = "scrypt"
+	KDF_ARGON2ID = "argon2id"
+)
+
+const CIPHER_XCHACHA20_POLY1305 = "xchacha20-poly1305"
+
+// The KDF parameters of an entry are read from the file, so they
+// are bounded before running the KDF.
+const (
+	MAX_SCRYPT_N       = 1 << 20
+	MAX_SCRYPT_R       = 32
+	MAX_SCRYPT_P       = 16
+	MAX_ARGON2_TIME    = 16
+	MAX_ARGON2_THREADS = 16
+	// MAX_KDF_MEMORY is the memory a KDF may use, in bytes (1 GiB).
+	MAX_KDF_MEMORY = 1 << 30
+	MIN_SALT_SIZE  = 16
+	MAX_SALT_SIZE  = 64
+)
+
+// ADDITIONAL_DATA_TAG starts the authenticated header of an entry.
+const ADDITIONAL_DATA_TAG = "apollo-keystore"
+
+type Kind string
+
+const (
+	KIND_MNEMONIC    Kind = "mnemonic"
+	KIND_ROOT_KEY    Kind = "root_key"
+	KIND_ACCOUNT_KEY Kind = "account_key"
+	KIND_SIGNING_KEY Kind = "signing_key"
+)
+
+var ErrInvalidPassword = errors.New("invalid password or corrupted keystore entry")
+
+/*
+*
+
+	KdfParams are the parameters of the key derivation function
+	turning a password into an encryption key.
+*/
+type KdfParams struct {
+	Name    string `json:"name"`
+	N       int    `json:"n,omitempty"`
+	R       int    `json:"r,omitempty"`
+	P       int    `json:"p,omitempty"`
+	Time    uint32 `json:"time,omitempty"`
+	Memory  uint32 `json:"memory,omitempty"`
+	Threads uint8  `json:"threads,omitempty"`
+	Salt    string `json:"salt"`
+}
+
+// DefaultScryptParams are the scrypt parameters used by new entries.
+func DefaultScryptParams() KdfParams {
+	return KdfParams{Name: KDF_SCRYPT, N: 1 << 18, R: 8, P: 1}
+}
+
+// DefaultArgon2Params are the recommended argon2id parameters (64 MiB).
+func DefaultArgon2Params() KdfParams {
+	return KdfParams{Name: KDF_ARGON2ID, Time: 3, Memory: 64 * 1024, Threads: 4}
+}
+
+/*
+*
+
+	Validate checks the parameters against the limits of the
+	keystore, so that a crafted file cannot make the KDF use
+	unbounded memory or time.
+
+	Returns:
+		error: An error if a parameter is missing or above its limit.
+*/
+func (params KdfParams) Validate() error {
+	switch params.Name {
+	case KDF_SCRYPT:
+		if params.N <= 1 || params.N&(params.N-1) != 0 || params.N > MAX_SCRYPT_N {
+			return fmt.Errorf("invalid scrypt n %d, must be a power of two up to %d", params.N, MAX_SCRYPT_N)
+		}
+		if params.R <= 0 || params.R > MAX_SCRYPT_R {
+			return fmt.Errorf("invalid scrypt r %d, must be between 1 and %d", params.R, MAX_SCRYPT_R)
+		}
+		if params.P <= 0 || params.P > MAX_SCRYPT_P {
+			return fmt.Errorf("invalid scrypt p %d, must be between 1 and %d", params.P, MAX_SCRYPT_P)
+		}
+		if 128*uint64(params.N)*uint64(params.R) > MAX_KDF_MEMORY {
+			return fmt.Errorf("scrypt parameters need more than %d bytes", MAX_KDF_MEMORY)
+		}
+	case KDF_ARGON2ID:
+		if params.Time == 0 || params.Time > MAX_ARGON2_TIME {
+			return fmt.Errorf("invalid argon2id time %d, must be between 1 and %d", params.Time, MAX_ARGON2_TIME)
+		}
+		if params.Memory == 0 || uint64(params.Memory)*1024 > MAX_KDF_MEMORY {
+			return fmt.Errorf("invalid argon2id memory %d KiB, must be between 1 and %d", params.Memory, MAX_KDF_MEMORY/1024)
+		}
+		if params.Threads == 0 || params.Threads > MAX_ARGON2_THREADS {
+			return fmt.Errorf("invalid argon2id threads %d, must be between 1 and %d", params.Threads, MAX_ARGON2_THREADS)
+		}
+	default:
+		return fmt.Errorf("unsupported kdf %s", params.Name)
+	}
+	return nil
+}
+
+func (params KdfParams) deriveKey(password string) ([]byte, error) {
+	err := params.Validate()
+	if err != nil {
+		return nil, err
+	}
+	salt, err := hex.DecodeString(params.Salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(salt) < MIN_SALT_SIZE || len(salt) > MAX_SALT_SIZE {
+		return nil, fmt.Errorf("invalid salt size %d", len(salt))
+	}
+	switch params.Name {
+	case KDF_SCRYPT:
+		return scrypt.Key([]byte(password), salt, params.N, params.R, params.P, chacha20poly1305.KeySize)
+	case KDF_ARGON2ID:
+		return argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, chacha20poly1305.KeySize), nil
+	default:
+		return nil, fmt.Errorf("unsupported kdf %s", params.Name)
+	}
+}
+
+type Crypto struct {
+	Kdf        KdfParams `json:"kdf"`
+	Cipher     string    `json:"cipher"`
+	Nonce      string    `json:"nonce"`
+	Ciphertext string    `json:"ciphertext"`
+}
+
+/*
+*
+
+	Entry is an encrypted key with its metadata. The metadata is
+	authenticated with the key, so it cannot be altered without
+	the password.
+*/
+type Entry struct {
+	Version   int                       `json:"version"`
+	Id        string                    `json:"id"`
+	Name      string                    `json:"name"`
+	Kind      Kind                      `json:"kind"`
+	Network   constants.Network         `json:"network"`
+	Account   uint32                    `json:"account"`
+	Scheme    HDWallet.DerivationScheme `json:"scheme"`
+	CreatedAt time.Time                 `json:"created_at"`
+	Metadata  map[string]string         `json:"metadata,omitempty"`
+	Crypto    Crypto                    `json:"crypto"`
+}
+
+// secret is the plaintext of an entry.
+type secret struct {
+	Mnemonic        string `json:"mnemonic,omitempty"`
+	Passphrase      string `json:"passphrase,omitempty"`
+	Key             string `json:"key,omitempty"`
+	VerificationKey string `json:"verification_key,omitempty"`
+}
+
+/*
+*
+
+	Options are the metadata of a new entry.
+*/
+type Options struct {
+	Name     string
+	Network  constants.Network
+	Account  uint32
+	Scheme   HDWallet.DerivationScheme
+	Metadata map[string]string
+	// Kdf defaults to DefaultScryptParams, its salt is always generated.
+	Kdf *KdfParams
+}
+
+func randomHex(size int) (string, error) {
+	buf := make([]byte, size)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// appendField appends a field prefixed by its big endian uint32 length.
+func appendField(buf []byte, field []byte) []byte {
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(field)))
+	return append(buf, field...)
+}
+
+func appendUint(buf []byte, value uint64) []byte {
+	return binary.BigEndian.AppendUint64(buf, value)
+}
+
+/*
+*
+
+	additionalData binds the metadata of the entry to its ciphertext.
+	The header is a concatenation of fields, strings prefixed by their
+	length as a big endian uint32 and numbers as big endian uint64:
+
+		tag "apollo-keystore", version, id, name, kind, network,
+		account, scheme, created_at (unix seconds), metadata count,
+		then each key and value sorted by key, kdf name, n, r, p,
+		time, memory, threads, salt, cipher and nonce.
+
+	Hex fields (id, salt and nonce) are taken as written in the file.
+
+	Returns:
+		[]byte: The additional data of the entry.
+*/
+func (e Entry) additionalData() []byte {
+	buf := appendField(nil, []byte(ADDITIONAL_DATA_TAG))
+	buf = appendUint(buf, uint64(e.Version))
+	buf = appendField(buf, []byte(e.Id))
+	buf = appendField(buf, []byte(e.Name))
+	buf = appendField(buf, []byte(e.Kind))
+	buf = appendUint(buf, uint64(e.Network))
+	buf = appendUint(buf, uint64(e.Account))
+	buf = appendUint(buf, uint64(e.Scheme))
+	buf = appendUint(buf, uint64(e.CreatedAt.Unix()))
+	keys := make([]string, 0, len(e.Metadata))
+	for key := range e.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	buf = appendUint(buf, uint64(len(keys)))
+	for _, key := range keys {
+		buf = appendField(buf, []byte(key))
+		buf = appendField(buf, []byte(e.Metadata[key]))
+	}
+	kdf := e.Crypto.Kdf
+	buf = appendField(buf, []byte(kdf.Name))
+	buf = appendUint(buf, uint64(kdf.N))
+	buf = appendUint(buf, uint64(kdf.R))
+	buf = appendUint(buf, uint64(kdf.P))
+	buf = appendUint(buf, uint64(kdf.Time))
+	buf = appendUint(buf, uint64(kdf.Memory))
+	buf = appendUint(buf, uint64(kdf.Threads))
+	buf = appendField(buf, []byte(kdf.Salt))
+	buf = appendField(buf, []byte(e.Crypto.Cipher))
+	return appendField(buf, []byte(e.Crypto.Nonce))
+}
+
+func (e *Entry) seal(plaintext secret, password string, kdf KdfParams) error {
+	salt, err := randomHex(32)
+	if err != nil {
+		return err
+	}
+	nonce, err := randomHex(chacha20poly1305.NonceSizeX)
+	if err != nil {
+		return err
+	}
+	kdf.Salt = salt
+	e.Crypto = Crypto{Kdf: kdf, Cipher: CIPHER_XCHACHA20_POLY1305, Nonce: nonce}
+	key, err := kdf.deriveKey(password)
+	if err != nil {
+		return err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return err
+	}
+	ad := e.additionalData()
+	encoded, err := json.Marshal(plaintext)
+	if err != nil {
+		return err
+	}
+	nonceBytes, _ := hex.DecodeString(nonce)
+	e.Crypto.Ciphertext = hex.EncodeToString(aead.Seal(nil, nonceBytes, encoded, ad))
+	return nil
+}
+
+func (e Entry) open(password string) (secret, error) {
+	if e.Version != KEYSTORE_VERSION {
+		return secret{}, fmt.Errorf("unsupported keystore version %d", e.Version)
+	}
+	if e.Crypto.Cipher != CIPHER_XCHACHA20_POLY1305 {
+		return secret{}, fmt.Errorf("unsupported cipher %s", e.Crypto.Cipher)
+	}
+	key, err := e.Crypto.Kdf.deriveKey(password)
+	if err != nil {
+		return secret{}, err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return secret{}, err
+	}
+	ad := e.additionalData()
+	nonce, err := hex.DecodeString(e.Crypto.Nonce)
+	if err != nil || len(nonce) != aead.NonceSize() {
+		return secret{}, errors.New("invalid nonce")
+	}
+	ciphertext, err := hex.DecodeString(e.Crypto.Ciphertext)
+	if err != nil {
+		return secret{}, err
+	}
+	plaintext, err := aead.Open(nil, nonce, ciphertext, ad)
+	if err != nil {
+		return secret{}, ErrInvalidPassword
+	}
+	res := secret{}
+	err = json.Unmarshal(plaintext, &res)
+	if err != nil {
+		return secret{}, err
+	}
+	return res, nil
+}
+
+func newEntry(kind Kind, plaintext secret, password string, options Options) (*Entry, error) {
+	id, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	kdf := DefaultScryptParams()
+	if options.Kdf != nil {
+		kdf = *options.Kdf
+	}
+	entry := &Entry{
+		Version:   KEYSTORE_VERSION,
+		Id:        id,
+		Name:      options.Name,
+		Kind:      kind,
+		Network:   options.Network,
+		Account:   options.Account,
+		Scheme:    options.Scheme,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+		Metadata:  options.Metadata,
+	}
+	err = entry.seal(plaintext, password, kdf)
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+/*
+*
+
+	NewMnemonicEntry encrypts a mnemonic and its passphrase.
+
+	Params:
+		mnemonic (string): The mnemonic.
+		passphrase (string): The mnemonic passphrase, usually empty.
+		password (string): The password of the entry.
+		options (Options): The metadata of the entry.
+
+	Returns:
+		*Entry: The encrypted entry.
+		error: An error if the mnemonic is invalid or the encryption fails.
+*/
+func NewMnemonicEntry(mnemonic string, passphrase string, password string, options Options) (*Entry, error) {
+	if !HDWallet.IsMnemonic(mnemonic) {
+		return nil, errors.New("invalid mnemonic")
+	}
+	return newEntry(KIND_MNEMONIC, secret{Mnemonic: mnemonic, Passphrase: passphrase}, password, options)
+}
+
+/*
+*
+
+	NewRootKeyEntry encrypts the root extended private key of a wallet.
+
+	Params:
+		hd (*HDWallet.HDWallet): The wallet.
+		password (string): The password of the entry.
+		options (Options): The metadata of the entry.
+
+	Returns:
+		*Entry: The encrypted entry.
+		error: An error if the encryption fails.
+*/
+func NewRootKeyEntry(hd *HDWallet.HDWallet, password string, options Options) (*Entry, error) {
+	return newEntry(KIND_ROOT_KEY, secret{Key: hex.EncodeToString(hd.RootXprivKey.Bytes())}, password, options)
+}
+
+/*
+*
+
+	NewAccountKeyEntry encrypts the extended private key of an account
+	(m/1852'/1815'/account'), whose index is taken from the options.
+
+	Params:
+		accountKey (*HDWallet.HDWallet): The account key.
+		password (string): The password of the entry.
+		options (Options): The metadata of the entry.
+
+	Returns:
+		*Entry: The encrypted entry.
+		error: An error if the encryption fails.
+*/
+func NewAccountKeyEntry(accountKey *HDWallet.HDWallet, password string, options Options) (*Entry, error) {
+	return newEntry(KIND_ACCOUNT_KEY, secret{Key: hex.EncodeToString(accountKey.XPrivKey.Bytes())}, password, options)
+}
+
+/*
+*
+
+	NewSigningKeyEntry encrypts a single signing key, as used by
+	SetWalletFromKeypair.
+
+	Params:
+		vkey (Key.VerificationKey): The verification key.
+		skey (Key.SigningKey): The signing key.
+		password (string): The password of the entry.
+		options (Options): The metadata of the entry.
+
+	Returns:
+		*Entry: The encrypted entry.
+		error: An error if the encryption fails.
+*/
+func NewSigningKeyEntry(vkey Key.VerificationKey, skey Key.SigningKey, password string, options Options) (*Entry, error) {
+	return newEntry(KIND_SIGNING_KEY, secret{
+		Key:             hex.EncodeToString(skey.Payload),
+		VerificationKey: hex.EncodeToString(vkey.Payload),
+	}, password, options)
+}
+
+/*
+*
+
+	Mnemonic decrypts the mnemonic of a mnemonic entry.
+
+	Params:
+		password (string): The password of the entry.
+
+	Returns:
+		string: The mnemonic.
+		string: The mnemonic passphrase.
+		error: An error if the password is wrong or the entry is not a mnemonic.
+*/
+func (e Entry) Mnemonic(password string) (string, string, error) {
+	if e.Kind != KIND_MNEMONIC {
+		return "", "", fmt.Errorf("entry %s is a %s", e.Name, e.Kind)
+	}
+	plaintext, err := e.open(password)
+	if err != nil {
+		return "", "", err
+	}
+	return plaintext.Mnemonic, plaintext.Passphrase, nil
+}
+
+/*
+*
+
+	Unlock decrypts the entry into a wallet: an account wallet for
+	mnemonics, root keys and account keys, and a wallet with an
+	enterprise address for single signing keys.
+
+	Params:
+		password (string): The password of the entry.
+
+	Returns:
+		apollotypes.Wallet: The unlocked wallet.
+		error: An error if the password is wrong or the key invalid.
+*/
+func (e Entry) Unlock(password string) (apollotypes.Wallet, error) {
+	plaintext, err := e.open(password)
+	if err != nil {
+		return nil, err
+	}
+	switch e.Kind {
+	case KIND_MNEMONIC:
+		hd, err := HDWallet.NewHDWalletFromMnemonicWithScheme(plaintext.Mnemonic, plaintext.Passphrase, e.Scheme)
+		if err != nil {
+			return nil, err
+		}
+		return apollotypes.NewAccountWallet(hd, e.Account, e.Network, 0)
+	case KIND_ROOT_KEY, KIND_ACCOUNT_KEY:
+		raw, err := hex.DecodeString(plaintext.Key)
+		if err != nil {
+			return nil, err
+		}
+		xprv, err := bip32.NewXPrv(raw)
+		if err != nil {
+			return nil, err
+		}
+		hd := &HDWallet.HDWallet{RootXprivKey: xprv, XPrivKey: xprv, Path: "m"}
+		if e.Kind == KIND_ROOT_KEY {
+			return apollotypes.NewAccountWallet(hd, e.Account, e.Network, 0)
+		}
+		return apollotypes.NewAccountWalletFromAccountKey(hd, e.Account, e.Network, 0)
+	case KIND_SIGNING_KEY:
+		skey, err := hex.DecodeString(plaintext.Key)
+		if err != nil {
+			return nil, err
+		}
+		vkey, err := hex.DecodeString(plaintext.VerificationKey)
+		if err != nil {
+			return nil, err
+		}
+		verificationKey := Key.VerificationKey{Payload: vkey}
+		vkh, err := verificationKey.Hash()
+		if err != nil {
+			return nil, err
+		}
+		addr := serAddress.Address{PaymentPart: vkh[:], Network: 0, AddressType: serAddress.KEY_NONE, HeaderByte: 0b01100000, Hrp: "addr_test"}
+		if e.Network == constants.MAINNET {
+			addr = serAddress.Address{PaymentPart: vkh[:], Network: 1, AddressType: serAddress.KEY_NONE, HeaderByte: 0b01100001, Hrp: "addr"}
+		}
+		return &apollotypes.GenericWallet{
+			SigningKey:      Key.SigningKey{Payload: skey},
+			VerificationKey: verificationKey,
+			Address:         addr,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported entry kind %s", e.Kind)
+	}
+}
+
+/*
+*
+
+	Rotate re-encrypts the entry under a new password, with a fresh
+	salt and nonce and the given KDF parameters.
+
+	Params:
+		oldPassword (string): The current password.
+		newPassword (string): The new password.
+		kdf (*KdfParams): The new KDF parameters, the current ones if nil.
+
+	Returns:
+		*Entry: The re-encrypted entry, with the same id and metadata.
+		error: An error if the current password is wrong.
+*/
+func (e Entry) Rotate(oldPassword string, newPassword string, kdf *KdfParams) (*Entry, error) {
+	plaintext, err := e.open(oldPassword)
+	if err != nil {
+		return nil, err
+	}
+	params := e.Crypto.Kdf
+	if kdf != nil {
+		params = *kdf
+	}
+	rotated := e
+	err = rotated.seal(plaintext, newPassword, params)
+	if err != nil {
+		return nil, err
+	}
+	return &rotated, nil
+}
+
+/*
+*
+
+	Keystore is a versioned file of encrypted entries.
+*/
+type Keystore struct {
+	Version int     `json:"version"`
+	Entries []Entry `json:"entries"`
+}
+
+// New creates an empty keystore.
+func New() *Keystore {
+	return &Keystore{Version: KEYSTORE_VERSION, Entries: make([]Entry, 0)}
+}
+
+/*
+*
+
+	Load reads a keystore file.
+
+	Params:
+		path (string): The path of the keystore.
+
+	Returns:
+		*Keystore: The keystore.
+		error: An error if the file cannot be read or has an unsupported version.
+*/
+func Load(path string) (*Keystore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ks := &Keystore{}
+	err = json.Unmarshal(data, ks)
+	if err != nil {
+		return nil, err
+	}
+	if ks.Version != KEYSTORE_VERSION {
+		return nil, fmt.Errorf("unsupported keystore version %d", ks.Version)
+	}
+	return ks, nil
+}
+
+/*
+*
+
+	Save writes the keystore to a file readable only by its owner.
+
+	Params:
+		path (string): The path of the keystore.
+
+	Returns:
+		error: An error if the file cannot be written.
+*/
+func (ks *Keystore) Save(path string) error {
+	data, err := json.MarshalIndent(ks, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+/*
+*
+
+	Add adds an entry, replacing the entry with the same name.
+
+	Params:
+		entry (*Entry): The entry to add.
+*/
+func (ks *Keystore) Add(entry *Entry) {
+	for idx, existing := range ks.Entries {
+		if existing.Name == entry.Name {
+			ks.Entries[idx] = *entry
+			return
+		}
+	}
+	ks.Entries = append(ks.Entries, *entry)
+}
+
+/*
+*
+
+	Get returns the entry with a name.
+
+	Params:
+		name (string): The name of the entry.
+
+	Returns:
+		*Entry: The entry or nil if there is none.
+*/
+func (ks *Keystore) Get(name string) *Entry {
+	for idx := range ks.Entries {
+		if ks.Entries[idx].Name == name {
+			return &ks.Entries[idx]
+		}
+	}
+	return nil
+}
+
+/*
+*
+
+	Remove removes the entry with a name.
+
+	Params:
+		name (string): The name of the entry.
+
+	Returns:
+		bool: true if an entry was removed.
+*/
+func (ks *Keystore) Remove(name string) bool {
+	for idx, existing := range ks.Entries {
+		if existing.Name == name {
+			ks.Entries = append(ks.Entries[:idx], ks.Entries[idx+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+/*
+*
+
+	Rotate re-encrypts every entry under a new password. The keystore
+	is left unchanged if any entry cannot be decrypted.
+
+	Params:
+		oldPassword (string): The current password.
+		newPassword (string): The new password.
+		kdf (*KdfParams): The new KDF parameters, the current ones if nil.
+
+	Returns:
+		error: An error if an entry cannot be decrypted.
+*/
+func (ks *Keystore) Rotate(oldPassword string, newPassword string, kdf *KdfParams) error {
+	rotated := make([]Entry, 0, len(ks.Entries))
+	for _, entry := range ks.Entries {
+		res, err := entry.Rotate(oldPassword, newPassword, kdf)
+		if err != nil {
+			return fmt.Errorf("entry %s: %w", entry.Name, err)
+		}
+		rotated = append(rotated, *res)
+	}
+	ks.Entries = rotated
+	return nil
+}
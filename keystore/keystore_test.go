@@ -0,0 +1,171 @@
+package keystore_test
+
+import (
+	"encoding/hex"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/Salvionied/apollo/constants"
+	"github.com/Salvionied/apollo/keystore"
+	"github.com/Salvionied/apollo/serialization/HDWallet"
+	"github.com/Salvionied/apollo/serialization/Key"
+)
+
+const MNEMONIC_12 = "test walk nut penalty hip pave soap entry language right filter choice"
+const PASSWORD = "correct horse battery staple"
+
+var lightScrypt = keystore.KdfParams{Name: keystore.KDF_SCRYPT, N: 1024, R: 8, P: 1}
+var lightArgon2 = keystore.KdfParams{Name: keystore.KDF_ARGON2ID, Time: 1, Memory: 1024, Threads: 1}
+
+func firstAddress(t *testing.T) string {
+	hd, _ := HDWallet.NewHDWalletFromMnemonic(MNEMONIC_12, "")
+	key, _ := hd.DerivePath("m/1852'/1815'/0'/0/0")
+	vkh, _ := Key.VerificationKey{Payload: key.XPrivKey.PublicKey()}.Hash()
+	return hex.EncodeToString(vkh[:])
+}
+
+func TestKeystoreVectors(t *testing.T) {
+	ks, err := keystore.Load("testdata/keystore_v2.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mnemonic, passphrase, err := ks.Get("scrypt").Mnemonic(PASSWORD)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mnemonic != MNEMONIC_12 || passphrase != "" {
+		t.Error("Invalid mnemonic", mnemonic)
+	}
+	for _, name := range []string{"scrypt", "argon2id"} {
+		wallet, err := ks.Get(name).Unlock(PASSWORD)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		if hex.EncodeToString(wallet.GetAddress().PaymentPart) != firstAddress(t) {
+			t.Error("Invalid unlocked wallet", name)
+		}
+	}
+	if ks.Get("argon2id").Metadata["owner"] != "treasury" {
+		t.Error("Invalid metadata")
+	}
+	if _, err := ks.Get("scrypt").Unlock("wrong"); !errors.Is(err, keystore.ErrInvalidPassword) {
+		t.Error("Expected an invalid password error", err)
+	}
+	tampered := *ks.Get("argon2id")
+	tampered.Account = 1
+	if _, err := tampered.Unlock(PASSWORD); !errors.Is(err, keystore.ErrInvalidPassword) {
+		t.Error("Metadata must be authenticated", err)
+	}
+	tampered = *ks.Get("argon2id")
+	tampered.Metadata = map[string]string{"owner": "attacker"}
+	if _, err := tampered.Unlock(PASSWORD); !errors.Is(err, keystore.ErrInvalidPassword) {
+		t.Error("Metadata must be authenticated", err)
+	}
+}
+
+func TestKeystoreKinds(t *testing.T) {
+	hd, _ := HDWallet.NewHDWalletFromMnemonic(MNEMONIC_12, "")
+	accountKey, _ := hd.DerivePath("m/1852'/1815'/0'")
+	paymentKey, _ := hd.DerivePath("m/1852'/1815'/0'/0/0")
+	vkey := Key.VerificationKey{Payload: paymentKey.XPrivKey.PublicKey()}
+	skey := Key.SigningKey{Payload: paymentKey.XPrivKey.Bytes()}
+
+	ks := keystore.New()
+	account, err := keystore.NewAccountKeyEntry(accountKey, PASSWORD, keystore.Options{Name: "account", Kdf: &lightScrypt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks.Add(account)
+	single, err := keystore.NewSigningKeyEntry(vkey, skey, PASSWORD, keystore.Options{Name: "single", Network: constants.TESTNET, Kdf: &lightArgon2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks.Add(single)
+	if _, err := keystore.NewMnemonicEntry("not a mnemonic", "", PASSWORD, keystore.Options{}); err == nil {
+		t.Error("Expected an error for an invalid mnemonic")
+	}
+
+	path := filepath.Join(t.TempDir(), "keystore.json")
+	if err := ks.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := keystore.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"account", "single"} {
+		wallet, err := loaded.Get(name).Unlock(PASSWORD)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		if hex.EncodeToString(wallet.GetAddress().PaymentPart) != firstAddress(t) {
+			t.Error("Invalid unlocked wallet", name)
+		}
+	}
+	if loaded.Get("single").Kind != keystore.KIND_SIGNING_KEY {
+		t.Error("Invalid kind")
+	}
+	if _, _, err := loaded.Get("single").Mnemonic(PASSWORD); err == nil {
+		t.Error("Expected an error for a non mnemonic entry")
+	}
+
+	err = loaded.Rotate(PASSWORD, "new password", &lightArgon2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loaded.Get("account").Unlock(PASSWORD); err == nil {
+		t.Error("The old password must not unlock rotated entries")
+	}
+	wallet, err := loaded.Get("account").Unlock("new password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(wallet.GetAddress().PaymentPart) != firstAddress(t) {
+		t.Error("Invalid rotated wallet")
+	}
+	if loaded.Get("account").Id != account.Id || loaded.Get("account").Crypto.Kdf.Name != keystore.KDF_ARGON2ID {
+		t.Error("Rotation must keep the id and apply the new kdf")
+	}
+	if err := loaded.Rotate("wrong", "other", nil); err == nil {
+		t.Error("Expected an error for a wrong password")
+	}
+	if !loaded.Remove("single") || loaded.Get("single") != nil {
+		t.Error("Invalid remove")
+	}
+}
+
+func TestKeystoreKdfLimits(t *testing.T) {
+	ks, err := keystore.Load("testdata/keystore_v2.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oversized := []keystore.KdfParams{
+		{Name: keystore.KDF_SCRYPT, N: 1 << 30, R: 8, P: 1},
+		{Name: keystore.KDF_SCRYPT, N: 1000, R: 8, P: 1},
+		{Name: keystore.KDF_SCRYPT, N: 1 << 20, R: 32, P: 1},
+		{Name: keystore.KDF_ARGON2ID, Time: 1, Memory: 1 << 30, Threads: 1},
+		{Name: keystore.KDF_ARGON2ID, Time: 1 << 20, Memory: 1024, Threads: 1},
+		{Name: keystore.KDF_ARGON2ID, Time: 1, Memory: 1024, Threads: 0},
+	}
+	for _, params := range oversized {
+		if params.Validate() == nil {
+			t.Error("Expected the parameters to be rejected", params)
+		}
+		entry := *ks.Get("scrypt")
+		params.Salt = entry.Crypto.Kdf.Salt
+		entry.Crypto.Kdf = params
+		if _, _, err := entry.Mnemonic(PASSWORD); err == nil || errors.Is(err, keystore.ErrInvalidPassword) {
+			t.Error("Expected the kdf to be refused before running", params, err)
+		}
+	}
+	if _, err := keystore.NewMnemonicEntry(MNEMONIC_12, "", PASSWORD, keystore.Options{Kdf: &oversized[0]}); err == nil {
+		t.Error("Expected an error for oversized kdf parameters")
+	}
+	if err := keystore.DefaultScryptParams().Validate(); err != nil {
+		t.Error(err)
+	}
+	if err := keystore.DefaultArgon2Params().Validate(); err != nil {
+		t.Error(err)
+	}
+}
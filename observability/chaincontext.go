@@ -0,0 +1,299 @@
+package observability
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+
+	"github.com/Salvionied/apollo/serialization"
+	"github.com/Salvionied/apollo/serialization/Address"
+	"github.com/Salvionied/apollo/serialization/PlutusData"
+	"github.com/Salvionied/apollo/serialization/Redeemer"
+	"github.com/Salvionied/apollo/serialization/Transaction"
+	"github.com/Salvionied/apollo/serialization/UTxO"
+	"github.com/Salvionied/apollo/txBuilding/Backend/Base"
+	"golang.org/x/exp/slog"
+)
+
+/*
+*
+
+	statusError is implemented by the errors of HTTP backends,
+	such as HttpClient.APIError.
+*/
+type statusError interface {
+	error
+	HTTPStatus() int
+}
+
+/*
+*
+
+	ChainContext wraps a Base.ChainContext, running each of its
+	calls in a span named after the method, such as
+	chain.Utxos. The errors of the backends implementing the
+	Fetch methods of the Blockfrost and Maestro contexts are
+	recorded along with their HTTP status. The spans are children
+	of the context set with WithContext, and the wrapped context
+	is bound to each span when it implements Base.ContextBinder.
+*/
+type ChainContext struct {
+	context         Base.ChainContext
+	instrumentation Instrumentation
+	backend         string
+	ctx             context.Context
+}
+
+/*
+*
+
+	NewChainContext instruments a chain context.
+
+	Params:
+		cc (Base.ChainContext): The wrapped context.
+		instrumentation (Instrumentation): The instrumentation receiving the calls.
+		backend (string): The name of the backend, the backend attribute of the spans.
+
+	Returns:
+		*ChainContext: The instrumented context.
+*/
+func NewChainContext(cc Base.ChainContext, instrumentation Instrumentation, backend string) *ChainContext {
+	return &ChainContext{context: cc, instrumentation: instrumentation, backend: backend}
+}
+
+/*
+*
+
+	WithContext implements Base.ContextBinder: the spans of the
+	returned context are children of the operation of ctx.
+
+	Params:
+		ctx (context.Context): The parent context of the spans.
+
+	Returns:
+		Base.ChainContext: The bound context.
+*/
+func (c *ChainContext) WithContext(ctx context.Context) Base.ChainContext {
+	bound := *c
+	bound.ctx = ctx
+	return &bound
+}
+
+func observe[T any](c *ChainContext, method string, call func(cc Base.ChainContext) (T, error), result func(T) []slog.Attr) (T, error) {
+	ctx := c.ctx
+	if c.instrumentation == nil {
+		return call(Base.WithContext(c.context, ctx))
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, span := c.instrumentation.Start(ctx, "chain."+method, slog.String("backend", c.backend))
+	value, err := call(Base.WithContext(c.context, ctx))
+	if err != nil {
+		var status statusError
+		if errors.As(err, &status) {
+			span.SetAttributes(slog.Int("http.status_code", status.HTTPStatus()))
+		}
+	} else if result != nil {
+		span.SetAttributes(result(value)...)
+	}
+	span.End(err)
+	return value, err
+}
+
+func succeed[T any](call func(cc Base.ChainContext) T) func(cc Base.ChainContext) (T, error) {
+	return func(cc Base.ChainContext) (T, error) {
+		return call(cc), nil
+	}
+}
+
+func (c *ChainContext) GetProtocolParams() Base.ProtocolParameters {
+	params, _ := observe(c, "GetProtocolParams", succeed(Base.ChainContext.GetProtocolParams), nil)
+	return params
+}
+
+func (c *ChainContext) GetGenesisParams() Base.GenesisParameters {
+	genesis, _ := observe(c, "GetGenesisParams", succeed(Base.ChainContext.GetGenesisParams), nil)
+	return genesis
+}
+
+func (c *ChainContext) Network() int {
+	return c.context.Network()
+}
+
+// LoadsWalletUtxos implements Base.WalletUtxoLoader for the wrapped context.
+func (c *ChainContext) LoadsWalletUtxos() bool {
+	loader, ok := c.context.(Base.WalletUtxoLoader)
+	return ok && loader.LoadsWalletUtxos()
+}
+
+func (c *ChainContext) Epoch() int {
+	epoch, _ := observe(c, "Epoch", succeed(Base.ChainContext.Epoch), func(epoch int) []slog.Attr {
+		return []slog.Attr{slog.Int("epoch", epoch)}
+	})
+	return epoch
+}
+
+func (c *ChainContext) MaxTxFee() int {
+	protocol_param := c.GetProtocolParams()
+	return Base.Fee(c, protocol_param.MaxTxSize, int(protocol_param.MaxTxExSteps), int(protocol_param.MaxTxExMem))
+}
+
+func (c *ChainContext) LastBlockSlot() int {
+	slot, _ := observe(c, "LastBlockSlot", succeed(Base.ChainContext.LastBlockSlot), func(slot int) []slog.Attr {
+		return []slog.Attr{slog.Int("slot", slot)}
+	})
+	return slot
+}
+
+/*
+*
+
+	FetchUtxos returns the UTxOs of an address, with the error
+	of the wrapped context.
+
+	Params:
+		address (Address.Address): The address.
+
+	Returns:
+		[]UTxO.UTxO: The UTxOs.
+		error: The error of the wrapped context.
+*/
+func (c *ChainContext) FetchUtxos(address Address.Address) ([]UTxO.UTxO, error) {
+	return observe(c, "Utxos", func(cc Base.ChainContext) ([]UTxO.UTxO, error) {
+		return Base.FetchUtxos(cc, address)
+	}, func(utxos []UTxO.UTxO) []slog.Attr {
+		return []slog.Attr{slog.Int("utxos", len(utxos))}
+	})
+}
+
+func (c *ChainContext) Utxos(address Address.Address) []UTxO.UTxO {
+	utxos, _ := c.FetchUtxos(address)
+	return utxos
+}
+
+func (c *ChainContext) SubmitTx(tx Transaction.Transaction) (serialization.TransactionId, error) {
+	return observe(c, "SubmitTx", func(cc Base.ChainContext) (serialization.TransactionId, error) {
+		return cc.SubmitTx(tx)
+	}, func(txId serialization.TransactionId) []slog.Attr {
+		return []slog.Attr{slog.String("tx_id", hex.EncodeToString(txId.Payload))}
+	})
+}
+
+/*
+*
+
+	FetchEvaluation evaluates a transaction, with the error of
+	the wrapped context if it reports them.
+
+	Params:
+		tx ([]byte): The CBOR of the transaction.
+
+	Returns:
+		map[string]Redeemer.ExecutionUnits: The execution units by redeemer.
+		error: The error of the wrapped context.
+*/
+func (c *ChainContext) FetchEvaluation(tx []byte) (map[string]Redeemer.ExecutionUnits, error) {
+	call := func(cc Base.ChainContext) (map[string]Redeemer.ExecutionUnits, error) {
+		return Base.FetchEvaluation(cc, tx)
+	}
+	return observe(c, "EvaluateTx", call, func(units map[string]Redeemer.ExecutionUnits) []slog.Attr {
+		return []slog.Attr{slog.Int("tx_size", len(tx)), slog.Int("redeemers", len(units))}
+	})
+}
+
+func (c *ChainContext) EvaluateTx(tx []uint8) map[string]Redeemer.ExecutionUnits {
+	units, _ := c.FetchEvaluation(tx)
+	return units
+}
+
+func (c *ChainContext) GetUtxoFromRef(txHash string, txIndex int) *UTxO.UTxO {
+	utxo, _ := observe(c, "GetUtxoFromRef", succeed(func(cc Base.ChainContext) *UTxO.UTxO {
+		return cc.GetUtxoFromRef(txHash, txIndex)
+	}), func(utxo *UTxO.UTxO) []slog.Attr {
+		return []slog.Attr{slog.Bool("found", utxo != nil)}
+	})
+	return utxo
+}
+
+/*
+*
+
+	FetchContractCbor returns the CBOR of a script, with the
+	error of the wrapped context if it reports them.
+
+	Params:
+		scriptHash (string): The hash of the script.
+
+	Returns:
+		string: The hex encoded CBOR of the script.
+		error: The error of the wrapped context.
+*/
+func (c *ChainContext) FetchContractCbor(scriptHash string) (string, error) {
+	call := func(cc Base.ChainContext) (string, error) {
+		if fetcher, ok := cc.(interface {
+			FetchContractCbor(string) (string, error)
+		}); ok {
+			return fetcher.FetchContractCbor(scriptHash)
+		}
+		return cc.GetContractCbor(scriptHash), nil
+	}
+	return observe(c, "GetContractCbor", call, func(script string) []slog.Attr {
+		return []slog.Attr{slog.Bool("found", script != "")}
+	})
+}
+
+func (c *ChainContext) GetContractCbor(scriptHash string) string {
+	script, _ := c.FetchContractCbor(scriptHash)
+	return script
+}
+
+/*
+*
+
+	ResolveDatum resolves a datum through the wrapped context
+	when it implements Base.DatumResolver.
+
+	Params:
+		datumHash (string): The hex encoded hash of the datum.
+
+	Returns:
+		*PlutusData.PlutusData: The datum.
+		error: The error of the wrapped context, Base.ErrNotSupported
+			if it cannot resolve datums.
+*/
+func (c *ChainContext) ResolveDatum(datumHash string) (*PlutusData.PlutusData, error) {
+	if _, ok := c.context.(Base.DatumResolver); !ok {
+		return nil, Base.ErrNotSupported
+	}
+	return observe(c, "ResolveDatum", func(cc Base.ChainContext) (*PlutusData.PlutusData, error) {
+		return cc.(Base.DatumResolver).ResolveDatum(datumHash)
+	}, func(datum *PlutusData.PlutusData) []slog.Attr {
+		return []slog.Attr{slog.String("datum_hash", datumHash)}
+	})
+}
+
+/*
+*
+
+	GetScript fetches a typed script through the wrapped context
+	when it implements Base.ScriptResolver.
+
+	Params:
+		scriptHash (string): The hex encoded hash of the script.
+
+	Returns:
+		*PlutusData.ScriptRef: The script.
+		error: The error of the wrapped context, Base.ErrNotSupported
+			if it cannot fetch typed scripts.
+*/
+func (c *ChainContext) GetScript(scriptHash string) (*PlutusData.ScriptRef, error) {
+	if _, ok := c.context.(Base.ScriptResolver); !ok {
+		return nil, Base.ErrNotSupported
+	}
+	return observe(c, "GetScript", func(cc Base.ChainContext) (*PlutusData.ScriptRef, error) {
+		return cc.(Base.ScriptResolver).GetScript(scriptHash)
+	}, func(scriptRef *PlutusData.ScriptRef) []slog.Attr {
+		return []slog.Attr{slog.String("script_hash", scriptHash)}
+	})
+}
@@ -0,0 +1,147 @@
+package observability
+
+import (
+	"context"
+
+	"golang.org/x/exp/slog"
+)
+
+/*
+*
+
+	Span is a running operation, such as a chain context call
+	or a build phase. End must be called exactly once.
+*/
+type Span interface {
+	// SetAttributes adds attributes known during the operation.
+	SetAttributes(attrs ...slog.Attr)
+	// End ends the operation, err being nil on success.
+	End(err error)
+}
+
+/*
+*
+
+	Instrumentation receives the operations of the backends
+	and of the builder, to trace, measure or log them.
+	Implementations are safe for concurrent use.
+*/
+type Instrumentation interface {
+	// Start begins an operation, the returned context being
+	// the parent of the operations started with it.
+	Start(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, Span)
+}
+
+type multiSpan []Span
+
+func (spans multiSpan) SetAttributes(attrs ...slog.Attr) {
+	for _, span := range spans {
+		span.SetAttributes(attrs...)
+	}
+}
+
+func (spans multiSpan) End(err error) {
+	for _, span := range spans {
+		span.End(err)
+	}
+}
+
+type multi []Instrumentation
+
+func (instrumentations multi) Start(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, Span) {
+	spans := make(multiSpan, 0, len(instrumentations))
+	for _, instrumentation := range instrumentations {
+		var span Span
+		ctx, span = instrumentation.Start(ctx, name, attrs...)
+		spans = append(spans, span)
+	}
+	return ctx, spans
+}
+
+/*
+*
+
+	Multi combines instrumentations, every operation being
+	sent to each of them.
+
+	Params:
+		instrumentations (...Instrumentation): The instrumentations, nil ones are skipped.
+
+	Returns:
+		Instrumentation: The combined instrumentation, nil if there is none.
+*/
+func Multi(instrumentations ...Instrumentation) Instrumentation {
+	combined := make(multi, 0, len(instrumentations))
+	for _, instrumentation := range instrumentations {
+		if instrumentation != nil {
+			combined = append(combined, instrumentation)
+		}
+	}
+	switch len(combined) {
+	case 0:
+		return nil
+	case 1:
+		return combined[0]
+	}
+	return combined
+}
+
+/*
+*
+
+	Observe runs an operation in a span.
+
+	Params:
+		ctx (context.Context): The parent context.
+		instrumentation (Instrumentation): The instrumentation, nil to only run the operation.
+		name (string): The name of the operation.
+		operation (func(context.Context) error): The operation.
+		attrs (...slog.Attr): The attributes of the operation.
+
+	Returns:
+		error: The error of the operation.
+*/
+func Observe(ctx context.Context, instrumentation Instrumentation, name string, operation func(context.Context) error, attrs ...slog.Attr) error {
+	if instrumentation == nil {
+		return operation(ctx)
+	}
+	ctx, span := instrumentation.Start(ctx, name, attrs...)
+	err := operation(ctx)
+	span.End(err)
+	return err
+}
+
+type spanKey struct{}
+
+/*
+*
+
+	ContextWithSpan returns a context carrying a span, so that
+	the code it is passed to can add attributes to it.
+
+	Params:
+		ctx (context.Context): The parent context.
+		span (Span): The span.
+
+	Returns:
+		context.Context: The context carrying the span.
+*/
+func ContextWithSpan(ctx context.Context, span Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+/*
+*
+
+	SpanFromContext returns the span carried by a context.
+
+	Params:
+		ctx (context.Context): The context.
+
+	Returns:
+		Span: The span, nil if there is none.
+*/
+func SpanFromContext(ctx context.Context) Span {
+	span, _ := ctx.Value(spanKey{}).(Span)
+	return span
+}
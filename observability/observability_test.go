@@ -0,0 +1,107 @@
+package observability_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/Salvionied/apollo/observability"
+	"github.com/Salvionied/apollo/serialization/Address"
+	"github.com/Salvionied/apollo/serialization/UTxO"
+	"github.com/Salvionied/apollo/txBuilding/Backend/Base"
+	"github.com/Salvionied/apollo/txBuilding/Backend/FixedChainContext"
+	"github.com/Salvionied/apollo/txBuilding/Backend/HttpClient"
+	"golang.org/x/exp/slog"
+)
+
+func TestRecorderAndMulti(t *testing.T) {
+	first, second := &observability.Recorder{}, &observability.Recorder{}
+	instrumentation := observability.Multi(nil, first, second)
+	err := observability.Observe(context.Background(), instrumentation, "build", func(ctx context.Context) error {
+		return observability.Observe(ctx, instrumentation, "fetch", func(ctx context.Context) error {
+			return errors.New("down")
+		}, slog.String("backend", "a"))
+	})
+	if err == nil {
+		t.Fatal("expected the error of the operation")
+	}
+	for _, recorder := range []*observability.Recorder{first, second} {
+		spans := recorder.Spans()
+		if len(spans) != 2 || spans[0].Name != "fetch" || spans[0].Parent != "build" || spans[1].Err == nil {
+			t.Fatalf("unexpected spans %+v", spans)
+		}
+		if backend, ok := spans[0].Attr("backend"); !ok || backend.String() != "a" {
+			t.Errorf("expected the backend attribute, got %v", backend)
+		}
+	}
+	if observability.Multi(nil) != nil || observability.Multi(first) != first {
+		t.Error("expected Multi to skip nil instrumentations")
+	}
+}
+
+func TestSlogInstrumentation(t *testing.T) {
+	var logs bytes.Buffer
+	instrumentation := observability.NewSlogInstrumentation(slog.New(slog.NewJSONHandler(&logs, nil)))
+	_, span := instrumentation.Start(context.Background(), "chain.Utxos", slog.String("backend", "a"))
+	span.SetAttributes(slog.Int("utxos", 2))
+	span.End(nil)
+	_, span = instrumentation.Start(context.Background(), "chain.SubmitTx")
+	span.End(errors.New("rejected"))
+
+	decoder := json.NewDecoder(&logs)
+	var success, failure map[string]any
+	if decoder.Decode(&success) != nil || decoder.Decode(&failure) != nil {
+		t.Fatalf("expected two records")
+	}
+	if success["level"] != "INFO" || success["msg"] != "chain.Utxos" || success["utxos"] != 2.0 || success["duration"] == nil {
+		t.Errorf("unexpected record %v", success)
+	}
+	if failure["level"] != "ERROR" || failure["error"] != "rejected" {
+		t.Errorf("unexpected record %v", failure)
+	}
+}
+
+// failingContext fails its UTxO reads as an HTTP backend would.
+type failingContext struct {
+	FixedChainContext.FixedChainContext
+}
+
+func (f failingContext) FetchUtxos(address Address.Address) ([]UTxO.UTxO, error) {
+	return nil, &HttpClient.APIError{StatusCode: 503}
+}
+
+func TestChainContext(t *testing.T) {
+	recorder := &observability.Recorder{}
+	fixed := FixedChainContext.InitFixedChainContext()
+	cc := observability.NewChainContext(fixed, recorder, "fixed")
+	var _ Base.ChainContext = cc
+	addr, _ := Address.DecodeAddress("addr1qy99jvml0vafzdpy6lm6z52qrczjvs4k362gmr9v4hrrwgqk4xvegxwvtfsu5ck6s83h346nsgf6xu26dwzce9yvd8ysd2seyu")
+
+	if len(cc.Utxos(addr)) != 2 || len(cc.EvaluateTx([]byte{0})) != 1 {
+		t.Fatal("expected the results of the wrapped context")
+	}
+	spans := recorder.Spans()
+	if len(spans) != 2 || spans[0].Name != "chain.Utxos" || spans[1].Name != "chain.EvaluateTx" {
+		t.Fatalf("unexpected spans %+v", spans)
+	}
+	if utxos, _ := spans[0].Attr("utxos"); utxos.Int64() != 2 {
+		t.Errorf("expected the number of UTxOs, got %v", utxos)
+	}
+
+	recorder = &observability.Recorder{}
+	cc = observability.NewChainContext(failingContext{fixed}, recorder, "http")
+	if _, err := cc.FetchUtxos(addr); err == nil {
+		t.Fatal("expected the error of the wrapped context")
+	}
+	span := recorder.Named("chain.Utxos")[0]
+	if status, ok := span.Attr("http.status_code"); span.Err == nil || !ok || status.Int64() != 503 {
+		t.Errorf("expected the HTTP status of the error, got %+v", span)
+	}
+
+	// a nil instrumentation only forwards the calls
+	if len(observability.NewChainContext(fixed, nil, "").Utxos(addr)) != 2 {
+		t.Error("expected the UTxOs without instrumentation")
+	}
+}
@@ -0,0 +1,10 @@
+/*
+*
+
+	Package otel adapts observability.Instrumentation to
+	OpenTelemetry. It is a module of its own, so that Apollo
+	does not depend on OpenTelemetry unless it is imported:
+
+		go get github.com/Salvionied/apollo/observability/otel
+*/
+package otel
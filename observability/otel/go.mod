@@ -0,0 +1,25 @@
+module github.com/Salvionied/apollo/observability/otel
+
+go 1.20
+
+require (
+	github.com/Salvionied/apollo v0.0.0
+	go.opentelemetry.io/otel v1.21.0
+	go.opentelemetry.io/otel/metric v1.21.0
+	go.opentelemetry.io/otel/sdk v1.21.0
+	go.opentelemetry.io/otel/sdk/metric v1.21.0
+	go.opentelemetry.io/otel/trace v1.21.0
+	golang.org/x/exp v0.0.0-20230522175609-2e198f4a06a1
+)
+
+require (
+	filippo.io/edwards25519 v1.0.0 // indirect
+	github.com/Salvionied/cbor/v2 v2.6.0 // indirect
+	github.com/go-logr/logr v1.3.0 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/x448/float16 v0.8.4 // indirect
+	golang.org/x/crypto v0.8.0 // indirect
+	golang.org/x/sys v0.14.0 // indirect
+)
+
+replace github.com/Salvionied/apollo => ../..
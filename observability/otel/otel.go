@@ -0,0 +1,147 @@
+package otel
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Salvionied/apollo/observability"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/exp/slog"
+)
+
+const (
+	DURATION_METRIC = "apollo.operation.duration"
+	ERRORS_METRIC   = "apollo.operation.errors"
+)
+
+// metricKeys are the attributes of the spans also set on the
+// metrics, the others having too many values.
+var metricKeys = map[string]bool{
+	"backend":          true,
+	"http.method":      true,
+	"http.status_code": true,
+}
+
+/*
+*
+
+	Instrumentation sends the operations to OpenTelemetry: a
+	span for each operation, its duration in the
+	apollo.operation.duration histogram and its failures in
+	the apollo.operation.errors counter, by operation name.
+*/
+type Instrumentation struct {
+	tracer   trace.Tracer
+	duration metric.Float64Histogram
+	errors   metric.Int64Counter
+}
+
+/*
+*
+
+	New creates an instrumentation from a tracer and a meter.
+
+	Params:
+		tracer (trace.Tracer): The tracer of the spans.
+		meter (metric.Meter): The meter of the duration and error metrics.
+
+	Returns:
+		*Instrumentation: The instrumentation.
+		error: An error if the metrics cannot be created.
+*/
+func New(tracer trace.Tracer, meter metric.Meter) (*Instrumentation, error) {
+	duration, err := meter.Float64Histogram(DURATION_METRIC,
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of the chain context calls and build phases."),
+	)
+	if err != nil {
+		return nil, err
+	}
+	errors, err := meter.Int64Counter(ERRORS_METRIC,
+		metric.WithDescription("Failed chain context calls and build phases."),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &Instrumentation{tracer: tracer, duration: duration, errors: errors}, nil
+}
+
+func convert(attrs []slog.Attr) []attribute.KeyValue {
+	converted := make([]attribute.KeyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		value := attr.Value.Resolve()
+		switch value.Kind() {
+		case slog.KindString:
+			converted = append(converted, attribute.String(attr.Key, value.String()))
+		case slog.KindInt64:
+			converted = append(converted, attribute.Int64(attr.Key, value.Int64()))
+		case slog.KindUint64:
+			converted = append(converted, attribute.Int64(attr.Key, int64(value.Uint64())))
+		case slog.KindFloat64:
+			converted = append(converted, attribute.Float64(attr.Key, value.Float64()))
+		case slog.KindBool:
+			converted = append(converted, attribute.Bool(attr.Key, value.Bool()))
+		case slog.KindDuration:
+			converted = append(converted, attribute.Float64(attr.Key, value.Duration().Seconds()))
+		default:
+			converted = append(converted, attribute.String(attr.Key, value.String()))
+		}
+	}
+	return converted
+}
+
+type span struct {
+	instrumentation *Instrumentation
+	ctx             context.Context
+	span            trace.Span
+	start           time.Time
+	mu              sync.Mutex
+	metricAttrs     []attribute.KeyValue
+}
+
+func (s *span) addMetricAttrs(attrs []attribute.KeyValue) {
+	for _, attr := range attrs {
+		if metricKeys[string(attr.Key)] {
+			s.metricAttrs = append(s.metricAttrs, attr)
+		}
+	}
+}
+
+func (i *Instrumentation) Start(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, observability.Span) {
+	converted := convert(attrs)
+	ctx, traceSpan := i.tracer.Start(ctx, name, trace.WithAttributes(converted...))
+	s := &span{
+		instrumentation: i,
+		ctx:             ctx,
+		span:            traceSpan,
+		start:           time.Now(),
+		metricAttrs:     []attribute.KeyValue{attribute.String("operation", name)},
+	}
+	s.addMetricAttrs(converted)
+	return ctx, s
+}
+
+func (s *span) SetAttributes(attrs ...slog.Attr) {
+	converted := convert(attrs)
+	s.span.SetAttributes(converted...)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addMetricAttrs(converted)
+}
+
+func (s *span) End(err error) {
+	s.mu.Lock()
+	options := metric.WithAttributes(s.metricAttrs...)
+	s.mu.Unlock()
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+		s.instrumentation.errors.Add(s.ctx, 1, options)
+	}
+	s.instrumentation.duration.Record(s.ctx, time.Since(s.start).Seconds(), options)
+	s.span.End()
+}
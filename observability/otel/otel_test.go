@@ -0,0 +1,135 @@
+package otel_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Salvionied/apollo/observability"
+	"github.com/Salvionied/apollo/observability/otel"
+	"github.com/Salvionied/apollo/serialization/Address"
+	"github.com/Salvionied/apollo/serialization/UTxO"
+	"github.com/Salvionied/apollo/txBuilding/Backend/FixedChainContext"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"golang.org/x/exp/slog"
+)
+
+const testAddress = "addr1qy99jvml0vafzdpy6lm6z52qrczjvs4k362gmr9v4hrrwgqk4xvegxwvtfsu5ck6s83h346nsgf6xu26dwzce9yvd8ysd2seyu"
+
+type failingContext struct {
+	FixedChainContext.FixedChainContext
+}
+
+func (f failingContext) FetchUtxos(address Address.Address) ([]UTxO.UTxO, error) {
+	return nil, errors.New("backend down")
+}
+
+func newInstrumentation(t *testing.T) (*otel.Instrumentation, *tracetest.SpanRecorder, *sdkmetric.ManualReader) {
+	spans := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)).Tracer("apollo")
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("apollo")
+	instrumentation, err := otel.New(tracer, meter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return instrumentation, spans, reader
+}
+
+func attr(attrs []attribute.KeyValue, key string) (attribute.Value, bool) {
+	for _, kv := range attrs {
+		if string(kv.Key) == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestSpans(t *testing.T) {
+	instrumentation, recorder, _ := newInstrumentation(t)
+	var _ observability.Instrumentation = instrumentation
+	addr, _ := Address.DecodeAddress(testAddress)
+	fixed := FixedChainContext.InitFixedChainContext()
+
+	if len(observability.NewChainContext(fixed, instrumentation, "fixed").Utxos(addr)) != 2 {
+		t.Fatal("expected the results of the wrapped context")
+	}
+	if _, err := observability.NewChainContext(failingContext{fixed}, instrumentation, "fixed").FetchUtxos(addr); err == nil {
+		t.Fatal("expected the error of the wrapped context")
+	}
+	_, span := instrumentation.Start(context.Background(), "apollo.coinSelection", slog.Duration("budget", 1500000000))
+	span.SetAttributes(slog.Bool("exact", true), slog.Uint64("inputs", 3))
+	span.End(nil)
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(spans))
+	}
+	if spans[0].Name() != "chain.Utxos" || spans[0].Status().Code != codes.Unset {
+		t.Errorf("unexpected span %s %v", spans[0].Name(), spans[0].Status())
+	}
+	if backend, _ := attr(spans[0].Attributes(), "backend"); backend.AsString() != "fixed" {
+		t.Errorf("expected the backend attribute, got %v", backend)
+	}
+	if utxos, _ := attr(spans[0].Attributes(), "utxos"); utxos.AsInt64() != 2 {
+		t.Errorf("expected the number of UTxOs, got %v", utxos)
+	}
+	if spans[1].Status().Code != codes.Error || spans[1].Status().Description != "backend down" || len(spans[1].Events()) != 1 {
+		t.Errorf("expected the error to be recorded, got %v", spans[1].Status())
+	}
+	attrs := spans[2].Attributes()
+	budget, _ := attr(attrs, "budget")
+	exact, _ := attr(attrs, "exact")
+	inputs, _ := attr(attrs, "inputs")
+	if budget.AsFloat64() != 1.5 || !exact.AsBool() || inputs.AsInt64() != 3 {
+		t.Errorf("unexpected converted attributes %v", attrs)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	instrumentation, _, reader := newInstrumentation(t)
+	for _, err := range []error{nil, nil, errors.New("down")} {
+		_, span := instrumentation.Start(context.Background(), "chain.Utxos", slog.String("backend", "blockfrost"), slog.String("address", testAddress))
+		span.SetAttributes(slog.Int("http.status_code", 200))
+		span.End(err)
+	}
+
+	var data metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &data); err != nil {
+		t.Fatal(err)
+	}
+	metrics := map[string]metricdata.Aggregation{}
+	for _, scope := range data.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			metrics[m.Name] = m.Data
+		}
+	}
+
+	duration, ok := metrics[otel.DURATION_METRIC].(metricdata.Histogram[float64])
+	if !ok || len(duration.DataPoints) != 1 || duration.DataPoints[0].Count != 3 {
+		t.Fatalf("expected 3 durations in one series, got %+v", metrics[otel.DURATION_METRIC])
+	}
+	set := duration.DataPoints[0].Attributes
+	if operation, _ := set.Value("operation"); operation.AsString() != "chain.Utxos" {
+		t.Errorf("expected the operation attribute, got %v", operation)
+	}
+	if backend, _ := set.Value("backend"); backend.AsString() != "blockfrost" {
+		t.Errorf("expected the backend attribute, got %v", backend)
+	}
+	if status, _ := set.Value("http.status_code"); status.AsInt64() != 200 {
+		t.Errorf("expected the status attribute, got %v", status)
+	}
+	if set.HasValue("address") {
+		t.Error("the address must not be a metric attribute")
+	}
+
+	errs, ok := metrics[otel.ERRORS_METRIC].(metricdata.Sum[int64])
+	if !ok || len(errs.DataPoints) != 1 || errs.DataPoints[0].Value != 1 {
+		t.Errorf("expected one error, got %+v", metrics[otel.ERRORS_METRIC])
+	}
+}
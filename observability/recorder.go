@@ -0,0 +1,128 @@
+package observability
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/slog"
+)
+
+/*
+*
+
+	RecordedSpan is an operation ended in a Recorder.
+*/
+type RecordedSpan struct {
+	Name     string
+	Parent   string
+	Attrs    []slog.Attr
+	Err      error
+	Duration time.Duration
+}
+
+/*
+*
+
+	Attr returns the value of an attribute of the span.
+
+	Params:
+		key (string): The key of the attribute.
+
+	Returns:
+		slog.Value: The value of the last attribute with the key.
+		bool: false if there is none.
+*/
+func (span RecordedSpan) Attr(key string) (slog.Value, bool) {
+	for i := len(span.Attrs) - 1; i >= 0; i-- {
+		if span.Attrs[i].Key == key {
+			return span.Attrs[i].Value, true
+		}
+	}
+	return slog.Value{}, false
+}
+
+/*
+*
+
+	Recorder keeps the ended operations in memory, to check
+	them in tests.
+*/
+type Recorder struct {
+	mu    sync.Mutex
+	spans []RecordedSpan
+}
+
+// recorderKey scopes the parent span to each recorder.
+type recorderKey struct {
+	recorder *Recorder
+}
+
+type recorderSpan struct {
+	recorder *Recorder
+	start    time.Time
+	mu       sync.Mutex
+	span     RecordedSpan
+}
+
+func (r *Recorder) Start(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, Span) {
+	parent, _ := ctx.Value(recorderKey{r}).(string)
+	span := &recorderSpan{
+		recorder: r,
+		start:    time.Now(),
+		span:     RecordedSpan{Name: name, Parent: parent, Attrs: append([]slog.Attr{}, attrs...)},
+	}
+	return context.WithValue(ctx, recorderKey{r}, name), span
+}
+
+func (span *recorderSpan) SetAttributes(attrs ...slog.Attr) {
+	span.mu.Lock()
+	defer span.mu.Unlock()
+	span.span.Attrs = append(span.span.Attrs, attrs...)
+}
+
+func (span *recorderSpan) End(err error) {
+	span.mu.Lock()
+	recorded := span.span
+	span.mu.Unlock()
+	recorded.Err = err
+	recorded.Duration = time.Since(span.start)
+	span.recorder.mu.Lock()
+	defer span.recorder.mu.Unlock()
+	span.recorder.spans = append(span.recorder.spans, recorded)
+}
+
+/*
+*
+
+	Spans returns the ended operations, in the order they ended.
+
+	Returns:
+		[]RecordedSpan: The operations.
+*/
+func (r *Recorder) Spans() []RecordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]RecordedSpan{}, r.spans...)
+}
+
+/*
+*
+
+	Named returns the ended operations with a name.
+
+	Params:
+		name (string): The name of the operations.
+
+	Returns:
+		[]RecordedSpan: The operations.
+*/
+func (r *Recorder) Named(name string) []RecordedSpan {
+	spans := make([]RecordedSpan, 0)
+	for _, span := range r.Spans() {
+		if span.Name == name {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
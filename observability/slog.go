@@ -0,0 +1,77 @@
+package observability
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/slog"
+)
+
+/*
+*
+
+	SlogInstrumentation logs every operation once it ends,
+	with its attributes, duration and error.
+*/
+type SlogInstrumentation struct {
+	Logger *slog.Logger
+	// Level is the level of the successful operations,
+	// failed ones are logged as errors.
+	Level slog.Level
+}
+
+/*
+*
+
+	NewSlogInstrumentation logs the operations to a logger.
+
+	Params:
+		logger (*slog.Logger): The logger, slog.Default() if nil.
+
+	Returns:
+		*SlogInstrumentation: The instrumentation, logging successes at the info level.
+*/
+func NewSlogInstrumentation(logger *slog.Logger) *SlogInstrumentation {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &SlogInstrumentation{Logger: logger, Level: slog.LevelInfo}
+}
+
+type slogSpan struct {
+	instrumentation *SlogInstrumentation
+	ctx             context.Context
+	name            string
+	start           time.Time
+	mu              sync.Mutex
+	attrs           []slog.Attr
+}
+
+func (s *SlogInstrumentation) Start(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, Span) {
+	return ctx, &slogSpan{
+		instrumentation: s,
+		ctx:             ctx,
+		name:            name,
+		start:           time.Now(),
+		attrs:           append([]slog.Attr{}, attrs...),
+	}
+}
+
+func (span *slogSpan) SetAttributes(attrs ...slog.Attr) {
+	span.mu.Lock()
+	defer span.mu.Unlock()
+	span.attrs = append(span.attrs, attrs...)
+}
+
+func (span *slogSpan) End(err error) {
+	span.mu.Lock()
+	attrs := append(span.attrs, slog.Duration("duration", time.Since(span.start)))
+	span.mu.Unlock()
+	level := span.instrumentation.Level
+	if err != nil {
+		level = slog.LevelError
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	span.instrumentation.Logger.LogAttrs(span.ctx, level, span.name, attrs...)
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"math/big"
 	"reflect"
 	"strconv"
 
@@ -13,6 +14,25 @@ import (
 	"github.com/Salvionied/cbor/v2"
 )
 
+var (
+	addressType    = reflect.TypeOf(Address.Address{})
+	plutusDataType = reflect.TypeOf(PlutusData.PlutusData{})
+	bigIntType     = reflect.TypeOf(big.Int{})
+	bigIntPtrType  = reflect.TypeOf(&big.Int{})
+)
+
+var (
+	errExpectedInt     = errors.New("expected int")
+	errExpectedBytes   = errors.New("expected bytes")
+	errExpectedBool    = errors.New("expected bool")
+	errExpectedList    = errors.New("expected list")
+	errExpectedMap     = errors.New("expected map")
+	errExpectedConstr  = errors.New("expected constr")
+	errExpectedOption  = errors.New("expected Option")
+	errExpectedUnit    = errors.New("expected unit")
+	errExpectedAddress = errors.New("expected address")
+)
+
 func GetAddressPlutusData(address Address.Address) (*PlutusData.PlutusData, error) {
 	switch address.AddressType {
 	case Address.KEY_KEY:
@@ -227,378 +247,413 @@ func GetAddressPlutusData(address Address.Address) (*PlutusData.PlutusData, erro
 	}
 }
 
-func MarshalPlutus(v interface{}) (*PlutusData.PlutusData, error) {
-	var overallContainer interface{}
-	var containerConstr = uint64(0)
-	var isMap = false
-	var isIndef = true
-	types := reflect.TypeOf(v)
-	values := reflect.ValueOf(v)
-	//get Container type
-	ok := types.Kind() == reflect.Struct
-	if ok {
-		fields, _ := types.FieldByName("_")
-		typeOfStruct := fields.Tag.Get("plutusType")
-		if typeOfStruct == "Map" {
-			isMap = true
-		}
-		Constr := fields.Tag.Get("plutusConstr")
-		if Constr != "" {
-			parsedConstr, err := strconv.Atoi(Constr)
-			if err != nil {
-				return nil, fmt.Errorf("error parsing constructor: %v", err)
-			}
-			if parsedConstr < 7 {
-				containerConstr = 121 + uint64(parsedConstr)
-			} else if 7 <= parsedConstr && parsedConstr <= 1400 {
-				containerConstr = 1280 + uint64(parsedConstr-7)
-			} else {
-				return nil, fmt.Errorf("parsedConstr value is above 1400")
-			}
-		}
-		switch typeOfStruct {
-		case "IndefList":
-			overallContainer = PlutusData.PlutusIndefArray{}
-		case "Map":
-			overallContainer = map[serialization.CustomBytes]PlutusData.PlutusData{}
-			isMap = true
-		case "DefList":
-			overallContainer = PlutusData.PlutusDefArray{}
-			isIndef = false
-		default:
-			fmt.Println(typeOfStruct)
-			return nil, fmt.Errorf("error: unknown type")
-		}
-		//get fields
-		for i := 0; i < types.NumField(); i++ {
-			f := types.Field(i)
-			if !f.IsExported() {
-				continue
-			}
-			tag := f.Tag
-			name := f.Name
-			constr := uint64(0)
-			typeOfField := tag.Get("plutusType")
-			constrOfField := tag.Get("plutusConstr")
-			if constrOfField != "" {
-				parsedConstr, err := strconv.Atoi(constrOfField)
-				if err != nil {
-					return nil, fmt.Errorf("error parsing constructor: %v", err)
-				}
-				if parsedConstr < 7 {
-					constr = 121 + uint64(parsedConstr)
-				} else if 7 <= parsedConstr && parsedConstr <= 1400 {
-					constr = 1280 + uint64(parsedConstr-7)
-				} else {
-					return nil, fmt.Errorf("parsedConstr value is above 1400")
-				}
-			}
-			switch typeOfField {
-			case "IndefBool":
-				if values.Field(i).Kind() != reflect.Bool {
-					return nil, fmt.Errorf("error: Bool field is not bool")
-				}
-				var boolPD PlutusData.PlutusData
-				switch values.Field(i).Bool() {
-				case true:
-					boolPD = PlutusData.PlutusData{
-						TagNr:          122,
-						PlutusDataType: PlutusData.PlutusArray,
-						Value:          PlutusData.PlutusIndefArray{},
-					}
-				case false:
-					boolPD = PlutusData.PlutusData{
-						TagNr:          121,
-						PlutusDataType: PlutusData.PlutusArray,
-						Value:          PlutusData.PlutusIndefArray{},
-					}
-				}
-				if isMap {
-					nameBytes := serialization.NewCustomBytes(name)
-					overallContainer.(map[serialization.CustomBytes]PlutusData.PlutusData)[nameBytes] = boolPD
-				} else {
-					if isIndef {
-						overallContainer = append(overallContainer.(PlutusData.PlutusIndefArray), boolPD)
-					} else {
-						overallContainer = append(overallContainer.(PlutusData.PlutusDefArray), boolPD)
-					}
-				}
-			case "Bool":
-				if values.Field(i).Kind() != reflect.Bool {
-					return nil, fmt.Errorf("error: Bool field is not bool")
-				}
-				var boolPD PlutusData.PlutusData
-				switch values.Field(i).Bool() {
-				case true:
-					boolPD = PlutusData.PlutusData{
-						TagNr:          122,
-						PlutusDataType: PlutusData.PlutusArray,
-						Value:          PlutusData.PlutusDefArray{},
-					}
-				case false:
-					boolPD = PlutusData.PlutusData{
-						TagNr:          121,
-						PlutusDataType: PlutusData.PlutusArray,
-						Value:          PlutusData.PlutusDefArray{},
-					}
-				}
-				if isMap {
-					nameBytes := serialization.NewCustomBytes(name)
-					overallContainer.(map[serialization.CustomBytes]PlutusData.PlutusData)[nameBytes] = boolPD
-				} else {
-					if isIndef {
-						overallContainer = append(overallContainer.(PlutusData.PlutusIndefArray), boolPD)
-					} else {
-						overallContainer = append(overallContainer.(PlutusData.PlutusDefArray), boolPD)
-					}
-				}
-			case "Bytes":
-				if values.Field(i).Kind() != reflect.Slice {
-					return nil, fmt.Errorf("error: Bytes field is not a slice")
-				}
-				pdb := PlutusData.PlutusData{
-					PlutusDataType: PlutusData.PlutusBytes,
-					Value:          values.Field(i).Interface().([]byte),
-					TagNr:          constr,
-				}
-				if isMap {
-					nameBytes := serialization.NewCustomBytes(name)
-					overallContainer.(map[serialization.CustomBytes]PlutusData.PlutusData)[nameBytes] = pdb
-				} else {
-					if isIndef {
-						overallContainer = append(overallContainer.(PlutusData.PlutusIndefArray), pdb)
-					} else {
-						overallContainer = append(overallContainer.(PlutusData.PlutusDefArray), pdb)
-					}
-				}
-			case "Int":
-				if values.Field(i).Kind() != reflect.Int64 {
-					return nil, fmt.Errorf("error: Int field is not int64")
-				}
-				pdi := PlutusData.PlutusData{
-					PlutusDataType: PlutusData.PlutusInt,
-					Value:          values.Field(i).Interface().(int64),
-					TagNr:          constr,
-				}
-				if isMap {
-					nameBytes := serialization.NewCustomBytes(name)
+/*
+*
 
-					overallContainer.(map[serialization.CustomBytes]PlutusData.PlutusData)[nameBytes] = pdi
-				} else {
-					if isIndef {
-						overallContainer = append(overallContainer.(PlutusData.PlutusIndefArray), pdi)
-					} else {
-						overallContainer = append(overallContainer.(PlutusData.PlutusDefArray), pdi)
-					}
-				}
-			case "StringBytes":
-				if values.Field(i).Kind() != reflect.String {
-					return nil, fmt.Errorf("error: StringBytes field is not string")
-				}
-				pdsb := PlutusData.PlutusData{
-					PlutusDataType: PlutusData.PlutusBytes,
-					Value:          []byte(values.Field(i).Interface().(string)),
-					TagNr:          constr,
-				}
-				if isMap {
-					nameBytes := serialization.NewCustomBytes(name)
-					overallContainer.(map[serialization.CustomBytes]PlutusData.PlutusData)[nameBytes] = pdsb
-				} else {
-					if isIndef {
-						overallContainer = append(overallContainer.(PlutusData.PlutusIndefArray), pdsb)
-					} else {
-						overallContainer = append(overallContainer.(PlutusData.PlutusDefArray), pdsb)
-					}
-				}
-			case "HexString":
-				if values.Field(i).Kind() != reflect.String {
-					return nil, fmt.Errorf("error: HexString field is not string")
-				}
-				hexString, err := hex.DecodeString(values.Field(i).Interface().(string))
-				if err != nil {
-					return nil, fmt.Errorf("error: HexString field is not string")
-				}
-				pdsb := PlutusData.PlutusData{
-					PlutusDataType: PlutusData.PlutusBytes,
-					Value:          hexString,
-					TagNr:          constr,
-				}
-				if isMap {
-					nameBytes := serialization.NewCustomBytes(name)
-					overallContainer.(map[serialization.CustomBytes]PlutusData.PlutusData)[nameBytes] = pdsb
-				} else {
-					if isIndef {
-						overallContainer = append(overallContainer.(PlutusData.PlutusIndefArray), pdsb)
-					} else {
-						overallContainer = append(overallContainer.(PlutusData.PlutusDefArray), pdsb)
-					}
-				}
-			case "Address":
-				addpd, err := GetAddressPlutusData(values.Field(i).Interface().(Address.Address))
-				if err != nil {
-					return nil, fmt.Errorf("error marshalling: %v", err)
-				}
-				if isMap {
-					nameBytes := serialization.NewCustomBytes(name)
-					overallContainer.(map[serialization.CustomBytes]PlutusData.PlutusData)[nameBytes] = *addpd
-				} else {
-					if isIndef {
-						overallContainer = append(overallContainer.(PlutusData.PlutusIndefArray), *addpd)
-					} else {
-						overallContainer = append(overallContainer.(PlutusData.PlutusDefArray), *addpd)
-					}
-				}
+	parseConstr converts a plutusConstr tag into the CBOR tag used
+	to encode the constructor. An empty tag yields 0 (no constructor).
 
-			case "IndefList":
-				container := PlutusData.PlutusIndefArray{}
-				for j := 0; j < values.Field(i).Len(); j++ {
-					pd, err := MarshalPlutus(values.Field(i).Index(j).Interface())
-					if err != nil {
-						return nil, fmt.Errorf("error marshalling: %v", err)
-					}
-					container = append(container, *pd)
-				}
-				if isMap {
-					nameBytes := serialization.NewCustomBytes(name)
-					overallContainer.(map[serialization.CustomBytes]PlutusData.PlutusData)[nameBytes] = PlutusData.PlutusData{
-						PlutusDataType: PlutusData.PlutusArray,
-						Value:          container,
-						TagNr:          constr,
-					}
-				} else {
-					if isIndef {
-						overallContainer = append(overallContainer.(PlutusData.PlutusIndefArray), PlutusData.PlutusData{
-							PlutusDataType: PlutusData.PlutusArray,
-							Value:          container,
-							TagNr:          constr,
-						})
-					} else {
-						overallContainer = append(overallContainer.(PlutusData.PlutusDefArray), PlutusData.PlutusData{
-							PlutusDataType: PlutusData.PlutusArray,
-							Value:          container,
-							TagNr:          constr,
-						})
+	Params:
+		constr (string): The value of the plutusConstr struct tag.
 
-					}
-				}
-			case "DefList":
-				container := PlutusData.PlutusDefArray{}
-				for j := 0; j < values.Field(i).Len(); j++ {
-					pd, err := MarshalPlutus(values.Field(i).Index(j).Interface())
-					if err != nil {
-						return nil, fmt.Errorf("error marshalling: %v", err)
-					}
-					container = append(container, *pd)
-				}
-				if isMap {
-					nameBytes := serialization.NewCustomBytes(name)
-					overallContainer.(map[serialization.CustomBytes]PlutusData.PlutusData)[nameBytes] = PlutusData.PlutusData{
-						PlutusDataType: PlutusData.PlutusArray,
-						Value:          container,
-						TagNr:          constr,
-					}
-				} else {
-					if isIndef {
-						overallContainer = append(overallContainer.(PlutusData.PlutusIndefArray), PlutusData.PlutusData{
-							PlutusDataType: PlutusData.PlutusArray,
-							Value:          container,
-							TagNr:          constr,
-						})
-					} else {
-						overallContainer = append(overallContainer.(PlutusData.PlutusDefArray), PlutusData.PlutusData{
-							PlutusDataType: PlutusData.PlutusArray,
-							Value:          container,
-							TagNr:          constr,
-						})
-					}
-				}
-			case "Map":
-				container := map[serialization.CustomBytes]PlutusData.PlutusData{}
-				for j := 0; j < values.Field(i).Len(); j++ {
-					pd, err := MarshalPlutus(values.Field(i).Index(j).Interface())
-					if err != nil {
-						return nil, fmt.Errorf("error marshalling: %v", err)
-					}
-					nameBytes := serialization.NewCustomBytes(values.Field(i).Index(j).Field(0).String())
-					container[nameBytes] = *pd
-				}
-				if isMap {
-					nameBytes := serialization.NewCustomBytes(name)
-					overallContainer.(map[serialization.CustomBytes]PlutusData.PlutusData)[nameBytes] = PlutusData.PlutusData{
-						PlutusDataType: PlutusData.PlutusMap,
-						Value:          container,
-						TagNr:          constr,
-					}
-				} else {
-					if isIndef {
-						overallContainer = append(overallContainer.(PlutusData.PlutusIndefArray), PlutusData.PlutusData{
-							PlutusDataType: PlutusData.PlutusMap,
-							Value:          container,
-							TagNr:          constr,
-						})
-					} else {
-						overallContainer = append(overallContainer.(PlutusData.PlutusDefArray), PlutusData.PlutusData{
-							PlutusDataType: PlutusData.PlutusMap,
-							Value:          container,
-							TagNr:          constr,
-						})
-					}
-				}
+	Returns:
+		uint64: The CBOR tag number of the constructor.
+		error: An error if the tag is not a valid constructor.
+*/
+func parseConstr(constr string) (uint64, error) {
+	if constr == "" {
+		return 0, nil
+	}
+	parsedConstr, err := strconv.Atoi(constr)
+	if err != nil {
+		return 0, fmt.Errorf("error parsing constructor: %v", err)
+	}
+	if parsedConstr < 0 {
+		return 0, fmt.Errorf("parsedConstr value is negative")
+	}
+	if parsedConstr < 7 {
+		return 121 + uint64(parsedConstr), nil
+	} else if parsedConstr <= 1400 {
+		return 1280 + uint64(parsedConstr-7), nil
+	}
+	return 0, fmt.Errorf("parsedConstr value is above 1400")
+}
 
-			default:
-				pd, err := MarshalPlutus(values.Field(i).Interface())
-				if err != nil {
-					return nil, fmt.Errorf("error marshalling: %v", err)
-				}
-				if isMap {
-					nameBytes := serialization.NewCustomBytes(name)
+/*
+*
 
-					overallContainer.(map[serialization.CustomBytes]PlutusData.PlutusData)[nameBytes] = *pd
-				} else {
-					if isIndef {
-						overallContainer = append(overallContainer.(PlutusData.PlutusIndefArray), *pd)
-					} else {
-						overallContainer = append(overallContainer.(PlutusData.PlutusDefArray), *pd)
-					}
-				}
-			}
-		}
+	MarshalPlutus encodes a Go value into PlutusData.
 
+	Structs are described by their `_` field tags (plutusType and
+	plutusConstr), fields by their own plutusType tag. Besides the tagged
+	types, ints, uints, *big.Int, bools, strings, byte slices, slices,
+	maps with any key type, empty structs (unit), pointers tagged as
+	`plutusType:"Option"` and interfaces holding a registered sum type
+	variant are supported.
+
+	Params:
+		v (interface{}): The value to encode.
+
+	Returns:
+		*PlutusData.PlutusData: The encoded PlutusData.
+		error: An error describing the path to the field that failed.
+*/
+func MarshalPlutus(v interface{}) (*PlutusData.PlutusData, error) {
+	if v == nil {
+		return nil, errors.New("error: cannot marshal nil value")
 	}
-	if !ok {
-		switch types.Kind() {
-		case reflect.String:
-			return &PlutusData.PlutusData{
-				PlutusDataType: PlutusData.PlutusBytes,
-				Value:          []byte(values.Interface().(string)),
-				TagNr:          containerConstr,
-			}, nil
-		case reflect.Int:
-			return &PlutusData.PlutusData{
-				PlutusDataType: PlutusData.PlutusInt,
-				Value:          values.Interface().(int),
-				TagNr:          containerConstr,
-			}, nil
-		case reflect.Slice:
-			return &PlutusData.PlutusData{
+	pd, err := marshalValue(reflect.ValueOf(v), "", 0)
+	if err != nil {
+		return nil, err
+	}
+	return &pd, nil
+}
+
+func marshalValue(v reflect.Value, plutusType string, constr uint64) (PlutusData.PlutusData, error) {
+	switch v.Type() {
+	case addressType:
+		pd, err := GetAddressPlutusData(v.Interface().(Address.Address))
+		if err != nil {
+			return PlutusData.PlutusData{}, err
+		}
+		return *pd, nil
+	case plutusDataType:
+		return v.Interface().(PlutusData.PlutusData), nil
+	case bigIntType:
+		bi := v.Interface().(big.Int)
+		return bigIntPlutusData(&bi, constr), nil
+	case bigIntPtrType:
+		if v.IsNil() {
+			return PlutusData.PlutusData{}, errors.New("nil *big.Int")
+		}
+		return bigIntPlutusData(v.Interface().(*big.Int), constr), nil
+	}
+	switch plutusType {
+	case "Option":
+		return marshalOption(v)
+	case "Bool", "IndefBool":
+		if v.Kind() != reflect.Bool {
+			return PlutusData.PlutusData{}, errExpectedBool
+		}
+		return boolPlutusData(v.Bool(), plutusType == "IndefBool"), nil
+	case "Int":
+		return marshalInt(v, constr)
+	case "Bytes":
+		if v.Kind() != reflect.Slice || v.Type().Elem().Kind() != reflect.Uint8 {
+			return PlutusData.PlutusData{}, errExpectedBytes
+		}
+		return PlutusData.PlutusData{
+			PlutusDataType: PlutusData.PlutusBytes,
+			Value:          v.Bytes(),
+			TagNr:          constr,
+		}, nil
+	case "StringBytes":
+		if v.Kind() != reflect.String {
+			return PlutusData.PlutusData{}, errors.New("expected string")
+		}
+		return PlutusData.PlutusData{
+			PlutusDataType: PlutusData.PlutusBytes,
+			Value:          []byte(v.String()),
+			TagNr:          constr,
+		}, nil
+	case "HexString":
+		if v.Kind() != reflect.String {
+			return PlutusData.PlutusData{}, errors.New("expected string")
+		}
+		decoded, err := hex.DecodeString(v.String())
+		if err != nil {
+			return PlutusData.PlutusData{}, fmt.Errorf("expected hex string: %v", err)
+		}
+		return PlutusData.PlutusData{
+			PlutusDataType: PlutusData.PlutusBytes,
+			Value:          decoded,
+			TagNr:          constr,
+		}, nil
+	case "Address":
+		return PlutusData.PlutusData{}, errors.New("expected Address.Address")
+	case "IndefList", "DefList":
+		return marshalList(v, plutusType == "IndefList", constr)
+	case "Map":
+		if v.Kind() == reflect.Slice {
+			return marshalStructSliceMap(v, constr)
+		}
+		return marshalMap(v, constr)
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return PlutusData.PlutusData{}, errors.New("unexpected nil pointer, use plutusType:\"Option\" for optional values")
+		}
+		return marshalValue(v.Elem(), plutusType, constr)
+	case reflect.Interface:
+		if v.IsNil() {
+			return PlutusData.PlutusData{}, fmt.Errorf("nil value for sum type %s", v.Type())
+		}
+		return marshalValue(v.Elem(), plutusType, constr)
+	case reflect.Bool:
+		return boolPlutusData(v.Bool(), false), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return marshalInt(v, constr)
+	case reflect.String:
+		return PlutusData.PlutusData{
+			PlutusDataType: PlutusData.PlutusBytes,
+			Value:          []byte(v.String()),
+			TagNr:          constr,
+		}, nil
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			bytes := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(bytes), v)
+			return PlutusData.PlutusData{
 				PlutusDataType: PlutusData.PlutusBytes,
-				Value:          values.Interface().([]byte),
-				TagNr:          containerConstr,
+				Value:          bytes,
+				TagNr:          constr,
 			}, nil
-		default:
-			return nil, fmt.Errorf("error: unknown type")
+		}
+		return marshalList(v, true, constr)
+	case reflect.Map:
+		return marshalMap(v, constr)
+	case reflect.Struct:
+		if v.NumField() == 0 {
+			return unitPlutusData(), nil
+		}
+		return marshalStruct(v)
+	default:
+		return PlutusData.PlutusData{}, fmt.Errorf("unsupported type %s", v.Type())
+	}
+}
+
+func marshalStruct(v reflect.Value) (PlutusData.PlutusData, error) {
+	types := v.Type()
+	header, _ := types.FieldByName("_")
+	containerConstr, err := parseConstr(header.Tag.Get("plutusConstr"))
+	if err != nil {
+		return PlutusData.PlutusData{}, err
+	}
+	isMap := false
+	isIndef := false
+	switch header.Tag.Get("plutusType") {
+	case "IndefList":
+		isIndef = true
+	case "DefList":
+	case "Map":
+		isMap = true
+	default:
+		return PlutusData.PlutusData{}, fmt.Errorf("error: unknown type %q for struct %s", header.Tag.Get("plutusType"), types)
+	}
+	mapContainer := map[serialization.CustomBytes]PlutusData.PlutusData{}
+	listContainer := make([]PlutusData.PlutusData, 0)
+	for i := 0; i < types.NumField(); i++ {
+		f := types.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		constr, err := parseConstr(f.Tag.Get("plutusConstr"))
+		if err != nil {
+			return PlutusData.PlutusData{}, fmt.Errorf("field %s.%s: %w", types.Name(), f.Name, err)
+		}
+		pd, err := marshalValue(v.Field(i), f.Tag.Get("plutusType"), constr)
+		if err != nil {
+			return PlutusData.PlutusData{}, fmt.Errorf("field %s.%s: %w", types.Name(), f.Name, err)
+		}
+		if isMap {
+			mapContainer[serialization.NewCustomBytes(f.Name)] = pd
+		} else {
+			listContainer = append(listContainer, pd)
 		}
 	}
-	ptype := PlutusData.PlutusArray
 	if isMap {
-		ptype = PlutusData.PlutusMap
+		return PlutusData.PlutusData{
+			PlutusDataType: PlutusData.PlutusMap,
+			Value:          mapContainer,
+			TagNr:          containerConstr,
+		}, nil
+	}
+	var container any = PlutusData.PlutusDefArray(listContainer)
+	if isIndef {
+		container = PlutusData.PlutusIndefArray(listContainer)
 	}
-	pd := PlutusData.PlutusData{
-		PlutusDataType: ptype,
-		Value:          overallContainer,
+	return PlutusData.PlutusData{
+		PlutusDataType: PlutusData.PlutusArray,
+		Value:          container,
 		TagNr:          containerConstr,
+	}, nil
+}
+
+func marshalList(v reflect.Value, isIndef bool, constr uint64) (PlutusData.PlutusData, error) {
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return PlutusData.PlutusData{}, errExpectedList
+	}
+	items := make([]PlutusData.PlutusData, 0, v.Len())
+	for j := 0; j < v.Len(); j++ {
+		pd, err := marshalValue(v.Index(j), "", 0)
+		if err != nil {
+			return PlutusData.PlutusData{}, fmt.Errorf("index %d: %w", j, err)
+		}
+		items = append(items, pd)
+	}
+	var container any = PlutusData.PlutusDefArray(items)
+	if isIndef {
+		container = PlutusData.PlutusIndefArray(items)
+	}
+	return PlutusData.PlutusData{
+		PlutusDataType: PlutusData.PlutusArray,
+		Value:          container,
+		TagNr:          constr,
+	}, nil
+}
+
+// marshalStructSliceMap keeps the historical behaviour of slices tagged as
+// Map, where every element is keyed by the string form of its first field.
+func marshalStructSliceMap(v reflect.Value, constr uint64) (PlutusData.PlutusData, error) {
+	container := map[serialization.CustomBytes]PlutusData.PlutusData{}
+	for j := 0; j < v.Len(); j++ {
+		elem := v.Index(j)
+		if elem.Kind() != reflect.Struct || elem.NumField() == 0 {
+			return PlutusData.PlutusData{}, fmt.Errorf("index %d: expected struct", j)
+		}
+		pd, err := marshalValue(elem, "", 0)
+		if err != nil {
+			return PlutusData.PlutusData{}, fmt.Errorf("index %d: %w", j, err)
+		}
+		container[serialization.NewCustomBytes(elem.Field(0).String())] = pd
+	}
+	return PlutusData.PlutusData{
+		PlutusDataType: PlutusData.PlutusMap,
+		Value:          container,
+		TagNr:          constr,
+	}, nil
+}
+
+func marshalMap(v reflect.Value, constr uint64) (PlutusData.PlutusData, error) {
+	if v.Kind() != reflect.Map {
+		return PlutusData.PlutusData{}, errExpectedMap
+	}
+	if v.Type().Key().Kind() == reflect.String {
+		container := map[serialization.CustomBytes]PlutusData.PlutusData{}
+		iter := v.MapRange()
+		for iter.Next() {
+			pd, err := marshalValue(iter.Value(), "", 0)
+			if err != nil {
+				return PlutusData.PlutusData{}, fmt.Errorf("value for key %q: %w", iter.Key().String(), err)
+			}
+			container[serialization.NewCustomBytes(iter.Key().String())] = pd
+		}
+		return PlutusData.PlutusData{
+			PlutusDataType: PlutusData.PlutusMap,
+			Value:          container,
+			TagNr:          constr,
+		}, nil
+	}
+	container := map[PlutusData.PlutusDataKey]PlutusData.PlutusData{}
+	iter := v.MapRange()
+	for iter.Next() {
+		keyPd, err := marshalValue(iter.Key(), "", 0)
+		if err != nil {
+			return PlutusData.PlutusData{}, fmt.Errorf("key %v: %w", iter.Key().Interface(), err)
+		}
+		encodedKey, err := cbor.Marshal(&keyPd)
+		if err != nil {
+			return PlutusData.PlutusData{}, fmt.Errorf("key %v: %w", iter.Key().Interface(), err)
+		}
+		pd, err := marshalValue(iter.Value(), "", 0)
+		if err != nil {
+			return PlutusData.PlutusData{}, fmt.Errorf("value for key %v: %w", iter.Key().Interface(), err)
+		}
+		container[PlutusData.PlutusDataKey{CborHexValue: hex.EncodeToString(encodedKey)}] = pd
+	}
+	return PlutusData.PlutusData{
+		PlutusDataType: PlutusData.PlutusMap,
+		Value:          container,
+		TagNr:          constr,
+	}, nil
+}
+
+func marshalInt(v reflect.Value, constr uint64) (PlutusData.PlutusData, error) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return PlutusData.PlutusData{
+			PlutusDataType: PlutusData.PlutusInt,
+			Value:          v.Int(),
+			TagNr:          constr,
+		}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return PlutusData.PlutusData{
+			PlutusDataType: PlutusData.PlutusInt,
+			Value:          v.Uint(),
+			TagNr:          constr,
+		}, nil
+	default:
+		return PlutusData.PlutusData{}, errExpectedInt
+	}
+}
+
+func marshalOption(v reflect.Value) (PlutusData.PlutusData, error) {
+	if v.Kind() != reflect.Ptr {
+		return PlutusData.PlutusData{}, errors.New("Option field is not a pointer")
+	}
+	if v.IsNil() {
+		return PlutusData.PlutusData{
+			PlutusDataType: PlutusData.PlutusArray,
+			Value:          PlutusData.PlutusDefArray{},
+			TagNr:          122,
+		}, nil
+	}
+	inner, err := marshalValue(v.Elem(), "", 0)
+	if err != nil {
+		return PlutusData.PlutusData{}, fmt.Errorf("Some: %w", err)
+	}
+	return PlutusData.PlutusData{
+		PlutusDataType: PlutusData.PlutusArray,
+		Value:          PlutusData.PlutusIndefArray{inner},
+		TagNr:          121,
+	}, nil
+}
+
+func bigIntPlutusData(bi *big.Int, constr uint64) PlutusData.PlutusData {
+	if bi.IsInt64() && bi.Sign() < 0 {
+		return PlutusData.PlutusData{
+			PlutusDataType: PlutusData.PlutusInt,
+			Value:          bi.Int64(),
+			TagNr:          constr,
+		}
+	}
+	if bi.IsUint64() {
+		return PlutusData.PlutusData{
+			PlutusDataType: PlutusData.PlutusInt,
+			Value:          bi.Uint64(),
+			TagNr:          constr,
+		}
+	}
+	return PlutusData.PlutusData{
+		PlutusDataType: PlutusData.PlutusBigInt,
+		Value:          *new(big.Int).Set(bi),
+	}
+}
+
+func boolPlutusData(value bool, isIndef bool) PlutusData.PlutusData {
+	tag := uint64(121)
+	if value {
+		tag = 122
+	}
+	var container any = PlutusData.PlutusDefArray{}
+	if isIndef {
+		container = PlutusData.PlutusIndefArray{}
+	}
+	return PlutusData.PlutusData{
+		TagNr:          tag,
+		PlutusDataType: PlutusData.PlutusArray,
+		Value:          container,
+	}
+}
+
+func unitPlutusData() PlutusData.PlutusData {
+	return PlutusData.PlutusData{
+		TagNr:          121,
+		PlutusDataType: PlutusData.PlutusArray,
+		Value:          PlutusData.PlutusDefArray{},
 	}
-	return &pd, nil
 }
 
 func CborUnmarshal(data string, v interface{}, network byte) error {
@@ -618,458 +673,474 @@ func CborUnmarshal(data string, v interface{}, network byte) error {
 	return nil
 }
 
-func UnmarshalPlutus(data *PlutusData.PlutusData, v interface{}, network byte) (ret error) {
-	defer func() {
-		if r := recover(); r != nil {
-			fmt.Println("Recovered in UnmarshalPlutus", r)
-			ret = errors.New("error unmarshalling")
-		}
-	}()
+/*
+*
+
+	UnmarshalPlutus decodes PlutusData into the value pointed to by v,
+	following the same rules as MarshalPlutus.
+
+	Params:
+		data (*PlutusData.PlutusData): The PlutusData to decode.
+		v (interface{}): A pointer to the value to fill.
+		network (byte): The network used for decoded addresses.
 
-	ret = unmarshalPlutus(data, v, data.TagNr, data.PlutusDataType, network)
-	return ret
+	Returns:
+		error: An error describing the path to the field that failed.
+*/
+func UnmarshalPlutus(data *PlutusData.PlutusData, v interface{}, network byte) error {
+	if data == nil {
+		return errors.New("error: data is nil")
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("error: v is not a pointer %v", v)
+	}
+	return unmarshalValue(*data, rv.Elem(), "", network)
 }
 
+/*
+*
+
+	DecodePlutusAddress decodes the PlutusData representation of an
+	address. An empty address is returned if the data is not an address.
+
+	Params:
+		data (PlutusData.PlutusData): The PlutusData to decode.
+		network (byte): The network of the decoded address.
+
+	Returns:
+		Address.Address: The decoded address.
+*/
 func DecodePlutusAddress(data PlutusData.PlutusData, network byte) Address.Address {
-	if data.PlutusDataType != PlutusData.PlutusArray && data.TagNr != 121 && len(data.Value.(PlutusData.PlutusIndefArray)) != 2 {
+	addr, err := decodePlutusAddress(data, network)
+	if err != nil {
 		return Address.Address{}
 	}
-	isIndef := true
-	switch data.Value.(type) {
-	case PlutusData.PlutusDefArray:
-		isIndef = false
+	return addr
+}
 
+func decodePlutusAddress(data PlutusData.PlutusData, network byte) (Address.Address, error) {
+	parts, ok := listItems(data)
+	if !ok || data.TagNr != 121 || len(parts) != 2 {
+		return Address.Address{}, errExpectedAddress
 	}
-	if isIndef {
-		pkh := data.Value.(PlutusData.PlutusIndefArray)[0].Value.(PlutusData.PlutusIndefArray)[0].Value.([]byte)
-		is_script := data.Value.(PlutusData.PlutusIndefArray)[0].TagNr == 122
-		skh := []byte{}
-		skh_exists := data.Value.(PlutusData.PlutusIndefArray)[1].TagNr == 121
-		is_skh_script := false
+	pkh, is_script, err := decodeCredential(parts[0])
+	if err != nil {
+		return Address.Address{}, fmt.Errorf("payment credential: %w", err)
+	}
+	skh := []byte{}
+	is_skh_script := false
+	stakingItems, ok := listItems(parts[1])
+	if !ok {
+		return Address.Address{}, errExpectedAddress
+	}
+	skh_exists := false
+	switch {
+	case parts[1].TagNr == 122 && len(stakingItems) == 0:
+	case parts[1].TagNr == 121 && len(stakingItems) == 1:
+		stakingCredential, ok := listItems(stakingItems[0])
+		if !ok || stakingItems[0].TagNr != 121 || len(stakingCredential) != 1 {
+			return Address.Address{}, errors.New("error: Pointer Addresses are not supported")
+		}
+		skh, is_skh_script, err = decodeCredential(stakingCredential[0])
+		if err != nil {
+			return Address.Address{}, fmt.Errorf("staking credential: %w", err)
+		}
+		skh_exists = true
+	default:
+		return Address.Address{}, errExpectedAddress
+	}
+	var addrType byte
+	if is_script {
 		if skh_exists {
-			is_skh_script = data.Value.(PlutusData.PlutusIndefArray)[1].Value.(PlutusData.PlutusIndefArray)[0].Value.(PlutusData.PlutusIndefArray)[0].Value.(PlutusData.PlutusIndefArray)[0].TagNr == 122
-			skh = data.Value.(PlutusData.PlutusIndefArray)[1].Value.(PlutusData.PlutusIndefArray)[0].Value.(PlutusData.PlutusIndefArray)[0].Value.(PlutusData.PlutusIndefArray)[0].Value.([]byte)
-		}
-		var addrType byte
-		if is_script {
-			if skh_exists {
-				if is_skh_script {
-					addrType = Address.SCRIPT_SCRIPT
-				} else {
-					addrType = Address.SCRIPT_KEY
-				}
+			if is_skh_script {
+				addrType = Address.SCRIPT_SCRIPT
 			} else {
-				addrType = Address.SCRIPT_NONE
+				addrType = Address.SCRIPT_KEY
 			}
 		} else {
-			if skh_exists {
-				if is_skh_script {
-					addrType = Address.KEY_SCRIPT
-				} else {
-					addrType = Address.KEY_KEY
-				}
-			} else {
-				addrType = Address.KEY_NONE
-			}
+			addrType = Address.SCRIPT_NONE
 		}
-		hrp := Address.ComputeHrp(addrType, network)
-		header := addrType<<4 | network
-		addr := Address.Address{
-			PaymentPart: pkh,
-			StakingPart: skh,
-			AddressType: addrType,
-			Network:     network,
-			HeaderByte:  header,
-			Hrp:         hrp}
-		return addr
 	} else {
-		pkh := data.Value.(PlutusData.PlutusDefArray)[0].Value.(PlutusData.PlutusDefArray)[0].Value.([]byte)
-		is_script := data.Value.(PlutusData.PlutusDefArray)[0].TagNr == 122
-		skh := []byte{}
-		skh_exists := data.Value.(PlutusData.PlutusDefArray)[1].TagNr == 121
-		is_skh_script := false
 		if skh_exists {
-			is_skh_script = data.Value.(PlutusData.PlutusDefArray)[1].Value.(PlutusData.PlutusDefArray)[0].Value.(PlutusData.PlutusDefArray)[0].Value.(PlutusData.PlutusDefArray)[0].TagNr == 122
-			skh = data.Value.(PlutusData.PlutusDefArray)[1].Value.(PlutusData.PlutusDefArray)[0].Value.(PlutusData.PlutusDefArray)[0].Value.(PlutusData.PlutusDefArray)[0].Value.([]byte)
-		}
-		var addrType byte
-		if is_script {
-			if skh_exists {
-				if is_skh_script {
-					addrType = Address.SCRIPT_SCRIPT
-				} else {
-					addrType = Address.SCRIPT_KEY
-				}
+			if is_skh_script {
+				addrType = Address.KEY_SCRIPT
 			} else {
-				addrType = Address.SCRIPT_NONE
+				addrType = Address.KEY_KEY
 			}
 		} else {
-			if skh_exists {
-				if is_skh_script {
-					addrType = Address.KEY_SCRIPT
-				} else {
-					addrType = Address.KEY_KEY
-				}
-			} else {
-				addrType = Address.KEY_NONE
-			}
+			addrType = Address.KEY_NONE
 		}
-		hrp := Address.ComputeHrp(addrType, network)
-		header := addrType<<4 | network
-		addr := Address.Address{
-			PaymentPart: pkh,
-			StakingPart: skh,
-			AddressType: addrType,
-			Network:     network,
-			HeaderByte:  header,
-			Hrp:         hrp}
-		return addr
 	}
+	hrp := Address.ComputeHrp(addrType, network)
+	header := addrType<<4 | network
+	return Address.Address{
+		PaymentPart: pkh,
+		StakingPart: skh,
+		AddressType: addrType,
+		Network:     network,
+		HeaderByte:  header,
+		Hrp:         hrp}, nil
 }
 
-func unmarshalPlutus(data *PlutusData.PlutusData, v interface{}, Plutusconstr uint64, PlutusType PlutusData.PlutusType, network byte) error {
-	types := reflect.TypeOf(v)
-	if types.Kind() != reflect.Ptr {
-		return fmt.Errorf("error: v is not a pointer %v", v)
+func decodeCredential(data PlutusData.PlutusData) ([]byte, bool, error) {
+	items, ok := listItems(data)
+	if !ok || len(items) != 1 || (data.TagNr != 121 && data.TagNr != 122) {
+		return nil, false, errors.New("expected credential")
 	}
-	constr := data.TagNr
-	//get Container type
-	tps := types.Elem()
-	//values := reflect.ValueOf(tps)
-	//isStruct := tps.Kind() == reflect.Struct
-	ok := tps.Kind() == reflect.Struct
-	if ok {
-		fields, _ := tps.FieldByName("_")
-		switch data.PlutusDataType {
-		case PlutusData.PlutusArray:
-			if reflect.TypeOf(v).Kind() != reflect.Ptr {
-				return fmt.Errorf("error: v is not a pointer")
-			}
-			if fields.Tag.Get("plutusType") != "IndefList" && fields.Tag.Get("plutusType") != "DefList" && fields.Tag.Get("plutusType") != "" {
-				return fmt.Errorf("error: v is not a PlutusList")
-			}
-			plutusConstr := fields.Tag.Get("plutusConstr")
-			if constr != 0 && constr > 1400 && (plutusConstr != fmt.Sprint(constr-121) || plutusConstr != fmt.Sprint(constr-1280)) {
-				return fmt.Errorf("error: constructorTag does not match, got %s, expected %d", plutusConstr, constr)
-			}
-
-			arrayType := reflect.TypeOf(data.Value).String()
-			switch arrayType {
-			case "PlutusData.PlutusDefArray":
-				plutusValues, ok := data.Value.(PlutusData.PlutusDefArray)
-				if !ok {
-					return fmt.Errorf("error: value is not a PlutusDefArray")
-				}
-				for idx, pAEl := range plutusValues {
-					if tps.Field(idx+1).Type.String() == "Address.Address" {
-						addr := DecodePlutusAddress(pAEl, network)
-						reflect.ValueOf(v).Elem().Field(idx + 1).Set(reflect.ValueOf(addr))
-						continue
-					}
-					if tps.Field(idx+1).Type.String() == "bool" {
-						if tps.Field(idx+1).Type.String() != "bool" {
-							return fmt.Errorf("error: Bool field is not bool")
-						}
-						reflect.ValueOf(v).Elem().Field(idx + 1).SetBool(pAEl.TagNr == 122)
-						continue
-					}
-					switch pAEl.PlutusDataType {
-					case PlutusData.PlutusBytes:
-						if tps.Field(idx+1).Type.String() != "[]uint8" {
-							if tps.Field(idx+1).Type.String() != "string" {
-								return fmt.Errorf("error: Bytes field is not a slice")
-							} else {
-								if reflect.TypeOf(v).Elem().Field(idx+1).Tag.Get("plutusType") == "HexString" {
-									reflect.ValueOf(v).Elem().Field(idx + 1).SetString(hex.EncodeToString(pAEl.Value.([]byte)))
-									continue
-								}
-								reflect.ValueOf(v).Elem().Field(idx + 1).SetString(string(pAEl.Value.([]byte)))
-								continue
-							}
-						}
-						reflect.ValueOf(v).Elem().Field(idx + 1).Set(reflect.ValueOf(pAEl.Value))
-					case PlutusData.PlutusInt:
-						if tps.Field(idx+1).Type.String() != "int64" {
-							return fmt.Errorf("error: Int field is not int64")
-						}
-						x, ok := pAEl.Value.(uint64)
-						if !ok {
-							return fmt.Errorf("error: Int field is not int64")
-						}
-
-						reflect.ValueOf(v).Elem().Field(idx + 1).SetInt(int64(x))
-					case PlutusData.PlutusArray:
-						if reflect.TypeOf(v).Elem().Field(idx+1).Type.Kind() == reflect.Slice {
-							pa, ok := pAEl.Value.(PlutusData.PlutusIndefArray)
-							if ok {
-								val := reflect.ValueOf(v).Elem().Field(idx + 1)
-								val.Grow(len(pa))
-								val.SetLen(len(pa))
-								for secIdx, arrayElement := range pa {
-									err := unmarshalPlutus(&arrayElement, val.Index(secIdx).Addr().Interface(), pAEl.TagNr, pAEl.PlutusDataType, network)
-									if err != nil {
-										return fmt.Errorf("error at index %d.%d: %v:", idx, secIdx, err)
-									}
-								}
-								reflect.ValueOf(v).Elem().Field(idx + 1).Set(val)
-							} else {
-								pa2, ok := pAEl.Value.(PlutusData.PlutusDefArray)
-								if !ok {
-									return fmt.Errorf("error: value is not a PlutusArray")
-								}
-								val2 := reflect.ValueOf(v).Elem().Field(idx + 1)
-								val2.Grow(len(pa2))
-								val2.SetLen(len(pa2))
-								for secIdx, arrayElement := range pa2 {
-									err := unmarshalPlutus(&arrayElement, val2.Index(secIdx).Addr().Interface(), pAEl.TagNr, pAEl.PlutusDataType, network)
-									if err != nil {
-										return fmt.Errorf("error at index %d.%d: %v:", idx, secIdx, err)
-									}
-								}
-								reflect.ValueOf(v).Elem().Field(idx + 1).Set(val2)
-							}
-						} else {
-							err := unmarshalPlutus(&pAEl, reflect.ValueOf(v).Elem().Field(idx+1).Addr().Interface(), pAEl.TagNr, pAEl.PlutusDataType, network)
-							if err != nil {
-								return fmt.Errorf("error at index %d: %v", idx, err)
-							}
-						}
-					case PlutusData.PlutusMap:
-						err := unmarshalPlutus(&pAEl, reflect.ValueOf(v).Elem().Field(idx+1).Addr().Interface(), pAEl.TagNr, pAEl.PlutusDataType, network)
-						if err != nil {
-							return fmt.Errorf("error at index %d: %v", idx, err)
-						}
-					default:
-						return fmt.Errorf("error: unknown type")
-					}
-				}
-			case "PlutusData.PlutusIndefArray":
-				plutusValues, ok := data.Value.(PlutusData.PlutusIndefArray)
-				if !ok {
-					return fmt.Errorf("error: value is not a PlutusIndefArray")
-				}
-				for idx, pAEl := range plutusValues {
-					if tps.Field(idx+1).Type.String() == "Address.Address" {
-						addr := DecodePlutusAddress(pAEl, network)
-						reflect.ValueOf(v).Elem().Field(idx + 1).Set(reflect.ValueOf(addr))
-						continue
-					}
-					if tps.Field(idx+1).Type.String() == "bool" {
-						if tps.Field(idx+1).Type.String() != "bool" {
-							return fmt.Errorf("error: Bool field is not bool")
-						}
-						reflect.ValueOf(v).Elem().Field(idx + 1).SetBool(pAEl.TagNr == 122)
-						continue
-					}
-					switch pAEl.PlutusDataType {
-					case PlutusData.PlutusBytes:
-						if tps.Field(idx+1).Type.String() != "[]uint8" {
-							if tps.Field(idx+1).Type.String() != "string" {
-								return fmt.Errorf("error: Bytes field is not a slice")
-							} else {
-								if reflect.TypeOf(v).Elem().Field(idx+1).Tag.Get("plutusType") == "HexString" {
-									reflect.ValueOf(v).Elem().Field(idx + 1).SetString(hex.EncodeToString(pAEl.Value.([]byte)))
-									continue
-								}
-								reflect.ValueOf(v).Elem().Field(idx + 1).SetString(string(pAEl.Value.([]byte)))
-								continue
-							}
-						}
-						reflect.ValueOf(v).Elem().Field(idx + 1).Set(reflect.ValueOf(pAEl.Value))
-					case PlutusData.PlutusInt:
-						if tps.Field(idx+1).Type.String() != "int64" {
-							return fmt.Errorf("error: Int field is not int64")
-						}
-						x, ok := pAEl.Value.(uint64)
-						if !ok {
-							return fmt.Errorf("error: Int field is not int64")
-						}
+	hash, ok := items[0].Value.([]byte)
+	if !ok || items[0].PlutusDataType != PlutusData.PlutusBytes {
+		return nil, false, errExpectedBytes
+	}
+	return hash, data.TagNr == 122, nil
+}
 
-						reflect.ValueOf(v).Elem().Field(idx + 1).SetInt(int64(x))
-					case PlutusData.PlutusArray:
-						if reflect.TypeOf(v).Elem().Field(idx+1).Type.Kind() == reflect.Slice {
-							pa, ok := pAEl.Value.(PlutusData.PlutusIndefArray)
-							if ok {
-								val := reflect.ValueOf(v).Elem().Field(idx + 1)
-								val.Grow(len(pa))
-								val.SetLen(len(pa))
-								for secIdx, arrayElement := range pa {
-									err := unmarshalPlutus(&arrayElement, val.Index(secIdx).Addr().Interface(), pAEl.TagNr, pAEl.PlutusDataType, network)
-									if err != nil {
-										return fmt.Errorf("error at index %d.%d: %v:", idx, secIdx, err)
-									}
-								}
-								reflect.ValueOf(v).Elem().Field(idx + 1).Set(val)
-							} else {
-								pa2, ok := pAEl.Value.(PlutusData.PlutusDefArray)
-								if !ok {
-									return fmt.Errorf("error: value is not a PlutusArray")
-								}
-								val2 := reflect.ValueOf(v).Elem().Field(idx + 1)
-								val2.Grow(len(pa2))
-								val2.SetLen(len(pa2))
-								for secIdx, arrayElement := range pa2 {
-									err := unmarshalPlutus(&arrayElement, val2.Index(secIdx).Addr().Interface(), pAEl.TagNr, pAEl.PlutusDataType, network)
-									if err != nil {
-										return fmt.Errorf("error at index %d.%d: %v:", idx, secIdx, err)
-									}
-								}
-								reflect.ValueOf(v).Elem().Field(idx + 1).Set(val2)
-							}
-						} else {
-							err := unmarshalPlutus(&pAEl, reflect.ValueOf(v).Elem().Field(idx+1).Addr().Interface(), pAEl.TagNr, pAEl.PlutusDataType, network)
-							if err != nil {
-								return fmt.Errorf("error at index %d: %v", idx, err)
-							}
-						}
-					case PlutusData.PlutusMap:
-						err := unmarshalPlutus(&pAEl, reflect.ValueOf(v).Elem().Field(idx+1).Addr().Interface(), pAEl.TagNr, pAEl.PlutusDataType, network)
-						if err != nil {
-							return fmt.Errorf("error at index %d: %v", idx, err)
-						}
-					default:
-						return fmt.Errorf("error: unknown type")
-					}
-				}
-			default:
-				return fmt.Errorf("error: unknown type")
+func unmarshalValue(data PlutusData.PlutusData, dst reflect.Value, plutusType string, network byte) error {
+	types := dst.Type()
+	switch types {
+	case addressType:
+		addr, err := decodePlutusAddress(data, network)
+		if err != nil {
+			return err
+		}
+		dst.Set(reflect.ValueOf(addr))
+		return nil
+	case plutusDataType:
+		dst.Set(reflect.ValueOf(data))
+		return nil
+	case bigIntType:
+		bi, err := plutusInt(data)
+		if err != nil {
+			return err
+		}
+		dst.Set(reflect.ValueOf(*bi))
+		return nil
+	case bigIntPtrType:
+		bi, err := plutusInt(data)
+		if err != nil {
+			return err
+		}
+		dst.Set(reflect.ValueOf(bi))
+		return nil
+	}
+	if plutusType == "Option" {
+		return unmarshalOption(data, dst, network)
+	}
+	switch types.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(types.Elem())
+		err := unmarshalValue(data, elem.Elem(), plutusType, network)
+		if err != nil {
+			return err
+		}
+		dst.Set(elem)
+	case reflect.Interface:
+		return unmarshalSumType(data, dst, network)
+	case reflect.Bool:
+		items, ok := listItems(data)
+		if !ok || len(items) != 0 || (data.TagNr != 121 && data.TagNr != 122) {
+			return errExpectedBool
+		}
+		dst.SetBool(data.TagNr == 122)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		bi, err := plutusInt(data)
+		if err != nil {
+			return err
+		}
+		if !bi.IsInt64() || dst.OverflowInt(bi.Int64()) {
+			return fmt.Errorf("integer %s overflows %s", bi, types)
+		}
+		dst.SetInt(bi.Int64())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		bi, err := plutusInt(data)
+		if err != nil {
+			return err
+		}
+		if !bi.IsUint64() || dst.OverflowUint(bi.Uint64()) {
+			return fmt.Errorf("integer %s overflows %s", bi, types)
+		}
+		dst.SetUint(bi.Uint64())
+	case reflect.String:
+		bytes, err := plutusBytes(data)
+		if err != nil {
+			return err
+		}
+		if plutusType == "HexString" {
+			dst.SetString(hex.EncodeToString(bytes))
+		} else {
+			dst.SetString(string(bytes))
+		}
+	case reflect.Slice:
+		if types.Elem().Kind() == reflect.Uint8 {
+			bytes, err := plutusBytes(data)
+			if err != nil {
+				return err
 			}
-		case PlutusData.PlutusMap:
-			values, ok := data.Value.(*map[serialization.CustomBytes]PlutusData.PlutusData)
-			if !ok {
-				return fmt.Errorf("error: value is not a PlutusMap")
+			dst.SetBytes(bytes)
+			return nil
+		}
+		items, ok := listItems(data)
+		if !ok {
+			return errExpectedList
+		}
+		list := reflect.MakeSlice(types, len(items), len(items))
+		for idx, item := range items {
+			err := unmarshalValue(item, list.Index(idx), "", network)
+			if err != nil {
+				return fmt.Errorf("index %d: %w", idx, err)
 			}
-			for idxStringHex, pAEl := range *values {
-				idxBytes, _ := hex.DecodeString(idxStringHex.HexString())
-				idx := string(idxBytes)
-				field, ok := tps.FieldByName(idx)
-				if !ok {
-					fmt.Println(idxBytes, idxStringHex.String(), idxStringHex)
-					fmt.Println(idx)
-					return fmt.Errorf("error: field %s does not exist", idx)
-				}
-				switch field.Type.String() {
-				case "Address.Address":
-					addr := DecodePlutusAddress(pAEl, network)
-					reflect.ValueOf(v).Elem().FieldByName(idx).Set(reflect.ValueOf(addr))
-					continue
-				case "bool":
-					reflect.ValueOf(v).Elem().FieldByName(idx).SetBool(pAEl.TagNr == 122)
-					continue
-				case "[]uint8":
-					if pAEl.PlutusDataType != PlutusData.PlutusBytes {
-						return fmt.Errorf("error: Bytes field is not a slice")
-					}
-					reflect.ValueOf(v).Elem().FieldByName(idx).Set(reflect.ValueOf(pAEl.Value))
-				case "string":
-					if pAEl.PlutusDataType != PlutusData.PlutusBytes {
-						return fmt.Errorf("error: Bytes field is not a slice")
-					}
-					tp, _ := reflect.TypeOf(v).Elem().FieldByName(idx)
-					if tp.Tag.Get("plutusType") == "HexString" {
-						reflect.ValueOf(v).Elem().FieldByName(string(idx)).SetString(hex.EncodeToString(pAEl.Value.([]byte)))
-						continue
-					}
-					reflect.ValueOf(v).Elem().FieldByName(idx).SetString(string(pAEl.Value.([]byte)))
-				case "int64":
-					if pAEl.PlutusDataType != PlutusData.PlutusInt {
-						return fmt.Errorf("error: Int field is not int64")
-					}
-					x, ok := pAEl.Value.(uint64)
-					if !ok {
-						return fmt.Errorf("error: Int field is not int64")
-					}
-					reflect.ValueOf(v).Elem().FieldByName(idx).SetInt(int64(x))
-				default:
-					switch pAEl.PlutusDataType {
-					case PlutusData.PlutusArray:
-						tp, _ := reflect.TypeOf(v).Elem().FieldByName(idx)
-
-						switch tp.Tag.Get("plutusType") {
-						case "IndefList":
-							pa, ok := pAEl.Value.(PlutusData.PlutusIndefArray)
-							if !ok {
-								return fmt.Errorf("error: value is not a PlutusArray")
-							}
-							val := reflect.ValueOf(v).Elem().FieldByName(idx)
-							val.Grow(len(pa))
-							val.SetLen(len(pa))
-							for secIdx, arrayElement := range pa {
-								err := unmarshalPlutus(&arrayElement, val.Index(secIdx).Addr().Interface(), pAEl.TagNr, pAEl.PlutusDataType, network)
-								if err != nil {
-									return fmt.Errorf("error at index %s.%d: %v:", idx, secIdx, err)
-								}
-							}
-							reflect.ValueOf(v).Elem().FieldByName(idx).Set(val)
-						case "DefList":
-							pa, ok := pAEl.Value.(PlutusData.PlutusDefArray)
-							if !ok {
-								return fmt.Errorf("error: value is not a PlutusArray")
-							}
-							val := reflect.ValueOf(v).Elem().FieldByName(idx)
-							val.Grow(len(pa))
-							val.SetLen(len(pa))
-							for secIdx, arrayElement := range pa {
-								err := unmarshalPlutus(&arrayElement, val.Index(secIdx).Addr().Interface(), pAEl.TagNr, pAEl.PlutusDataType, network)
-								if err != nil {
-									return fmt.Errorf("error at index %s.%d: %v:", idx, secIdx, err)
-								}
-							}
-							reflect.ValueOf(v).Elem().FieldByName(idx).Set(val)
-						}
-					case PlutusData.PlutusMap:
-						err := unmarshalPlutus(&pAEl, reflect.ValueOf(v).Elem().FieldByName(idx).Addr().Interface(), pAEl.TagNr, pAEl.PlutusDataType, network)
-						if err != nil {
-							return fmt.Errorf("error at index %s: %v", idx, err)
-						}
-					}
-				}
+		}
+		dst.Set(list)
+	case reflect.Array:
+		if types.Elem().Kind() == reflect.Uint8 {
+			bytes, err := plutusBytes(data)
+			if err != nil {
+				return err
+			}
+			if len(bytes) != types.Len() {
+				return fmt.Errorf("expected %d bytes, got %d", types.Len(), len(bytes))
 			}
-		default:
-			return fmt.Errorf("error: unknown type")
+			reflect.Copy(dst, reflect.ValueOf(bytes))
+			return nil
 		}
-	} else {
-		if types.Kind() == reflect.Ptr {
-			types = types.Elem()
+		items, ok := listItems(data)
+		if !ok {
+			return errExpectedList
 		}
-		switch types.Kind() {
-		case reflect.String:
-			if data.PlutusDataType != PlutusData.PlutusBytes {
-				return fmt.Errorf("error: Bytes field is not a slice")
-			}
-			if reflect.TypeOf(v).Kind() != reflect.Ptr {
-				return fmt.Errorf("error: v is not a pointer")
+		if len(items) != types.Len() {
+			return fmt.Errorf("expected %d items, got %d", types.Len(), len(items))
+		}
+		for idx, item := range items {
+			err := unmarshalValue(item, dst.Index(idx), "", network)
+			if err != nil {
+				return fmt.Errorf("index %d: %w", idx, err)
 			}
-			if reflect.TypeOf(v).Elem().Kind() != reflect.String {
-				return fmt.Errorf("error: v is not a string")
+		}
+	case reflect.Map:
+		entries, err := mapEntries(data)
+		if err != nil {
+			return err
+		}
+		result := reflect.MakeMapWithSize(types, len(entries))
+		for _, entry := range entries {
+			key := reflect.New(types.Key()).Elem()
+			err := unmarshalValue(entry.key, key, "", network)
+			if err != nil {
+				return fmt.Errorf("map key: %w", err)
 			}
-			reflect.ValueOf(v).Elem().SetString(string(data.Value.([]byte)))
-		case reflect.Int:
-			if data.PlutusDataType != PlutusData.PlutusInt {
-				return fmt.Errorf("error: Int field is not int64")
+			value := reflect.New(types.Elem()).Elem()
+			err = unmarshalValue(entry.value, value, "", network)
+			if err != nil {
+				return fmt.Errorf("value for key %v: %w", key.Interface(), err)
 			}
-			if reflect.TypeOf(v).Kind() != reflect.Ptr {
-				return fmt.Errorf("error: v is not a pointer")
+			result.SetMapIndex(key, value)
+		}
+		dst.Set(result)
+	case reflect.Struct:
+		if types.NumField() == 0 {
+			items, ok := listItems(data)
+			if !ok || data.TagNr != 121 || len(items) != 0 {
+				return errExpectedUnit
 			}
-			if reflect.TypeOf(v).Elem().Kind() != reflect.Int {
-				return fmt.Errorf("error: v is not an int")
+			return nil
+		}
+		return unmarshalStruct(data, dst, network)
+	default:
+		return fmt.Errorf("unsupported type %s", types)
+	}
+	return nil
+}
+
+func unmarshalStruct(data PlutusData.PlutusData, dst reflect.Value, network byte) error {
+	types := dst.Type()
+	header, _ := types.FieldByName("_")
+	switch data.PlutusDataType {
+	case PlutusData.PlutusArray:
+		structType := header.Tag.Get("plutusType")
+		if structType != "IndefList" && structType != "DefList" && structType != "" {
+			return errExpectedMap
+		}
+		items, ok := listItems(data)
+		if !ok {
+			return errExpectedConstr
+		}
+		expectedConstr, err := parseConstr(header.Tag.Get("plutusConstr"))
+		if err != nil {
+			return err
+		}
+		if expectedConstr != 0 && expectedConstr != data.TagNr {
+			return fmt.Errorf("expected constructor %s, got tag %d", header.Tag.Get("plutusConstr"), data.TagNr)
+		}
+		fields := exportedFields(types)
+		if len(items) != len(fields) {
+			return fmt.Errorf("expected %d fields for %s, got %d", len(fields), types.Name(), len(items))
+		}
+		for idx, f := range fields {
+			err := unmarshalValue(items[idx], dst.FieldByIndex(f.Index), f.Tag.Get("plutusType"), network)
+			if err != nil {
+				return fmt.Errorf("field %s.%s: %w", types.Name(), f.Name, err)
 			}
-			reflect.ValueOf(v).Elem().SetInt(int64(data.Value.(uint64)))
-		case reflect.Slice:
-			if data.PlutusDataType != PlutusData.PlutusBytes {
-				return fmt.Errorf("error: Bytes field is not a slice")
+		}
+	case PlutusData.PlutusMap:
+		entries, err := mapEntries(data)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			name, err := plutusBytes(entry.key)
+			if err != nil {
+				return fmt.Errorf("map key: %w", err)
 			}
-			if reflect.TypeOf(v).Kind() != reflect.Ptr {
-				return fmt.Errorf("error: v is not a pointer")
+			f, ok := types.FieldByName(string(name))
+			if !ok || !f.IsExported() {
+				return fmt.Errorf("error: field %s does not exist", string(name))
 			}
-			if reflect.TypeOf(v).Elem().Kind() != reflect.Slice {
-				return fmt.Errorf("error: v is not a slice")
+			err = unmarshalValue(entry.value, dst.FieldByIndex(f.Index), f.Tag.Get("plutusType"), network)
+			if err != nil {
+				return fmt.Errorf("field %s.%s: %w", types.Name(), f.Name, err)
 			}
-			reflect.ValueOf(v).Elem().Set(reflect.ValueOf(data.Value))
-		default:
-			return fmt.Errorf("error: unknown type")
 		}
+	default:
+		return errExpectedConstr
 	}
+	return nil
+}
 
+func unmarshalOption(data PlutusData.PlutusData, dst reflect.Value, network byte) error {
+	if dst.Kind() != reflect.Ptr {
+		return errors.New("Option field is not a pointer")
+	}
+	items, ok := listItems(data)
+	switch {
+	case ok && data.TagNr == 121 && len(items) == 1:
+		elem := reflect.New(dst.Type().Elem())
+		err := unmarshalValue(items[0], elem.Elem(), "", network)
+		if err != nil {
+			return fmt.Errorf("Some: %w", err)
+		}
+		dst.Set(elem)
+	case ok && data.TagNr == 122 && len(items) == 0:
+		dst.Set(reflect.Zero(dst.Type()))
+	default:
+		return errExpectedOption
+	}
 	return nil
 }
+
+func exportedFields(types reflect.Type) []reflect.StructField {
+	fields := make([]reflect.StructField, 0, types.NumField())
+	for i := 0; i < types.NumField(); i++ {
+		f := types.Field(i)
+		if f.IsExported() {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+func listItems(data PlutusData.PlutusData) ([]PlutusData.PlutusData, bool) {
+	if data.PlutusDataType != PlutusData.PlutusArray {
+		return nil, false
+	}
+	switch items := data.Value.(type) {
+	case PlutusData.PlutusIndefArray:
+		return items, true
+	case PlutusData.PlutusDefArray:
+		return items, true
+	case []PlutusData.PlutusData:
+		return items, true
+	}
+	return nil, false
+}
+
+func plutusBytes(data PlutusData.PlutusData) ([]byte, error) {
+	bytes, ok := data.Value.([]byte)
+	if data.PlutusDataType != PlutusData.PlutusBytes || !ok {
+		return nil, errExpectedBytes
+	}
+	return bytes, nil
+}
+
+func plutusInt(data PlutusData.PlutusData) (*big.Int, error) {
+	if data.PlutusDataType != PlutusData.PlutusInt && data.PlutusDataType != PlutusData.PlutusBigInt {
+		return nil, errExpectedInt
+	}
+	switch value := data.Value.(type) {
+	case uint64:
+		return new(big.Int).SetUint64(value), nil
+	case int64:
+		return big.NewInt(value), nil
+	case int:
+		return big.NewInt(int64(value)), nil
+	case big.Int:
+		return new(big.Int).Set(&value), nil
+	case *big.Int:
+		return new(big.Int).Set(value), nil
+	}
+	return nil, errExpectedInt
+}
+
+type mapEntry struct {
+	key   PlutusData.PlutusData
+	value PlutusData.PlutusData
+}
+
+func mapEntries(data PlutusData.PlutusData) ([]mapEntry, error) {
+	if data.PlutusDataType != PlutusData.PlutusMap && data.PlutusDataType != PlutusData.PlutusIntMap {
+		return nil, errExpectedMap
+	}
+	entries := make([]mapEntry, 0)
+	switch m := data.Value.(type) {
+	case *map[serialization.CustomBytes]PlutusData.PlutusData:
+		return customBytesEntries(*m)
+	case map[serialization.CustomBytes]PlutusData.PlutusData:
+		return customBytesEntries(m)
+	case *map[PlutusData.PlutusDataKey]PlutusData.PlutusData:
+		return plutusDataKeyEntries(*m)
+	case map[PlutusData.PlutusDataKey]PlutusData.PlutusData:
+		return plutusDataKeyEntries(m)
+	case map[uint64]PlutusData.PlutusData:
+		for k, v := range m {
+			entries = append(entries, mapEntry{
+				key:   PlutusData.PlutusData{PlutusDataType: PlutusData.PlutusInt, Value: k},
+				value: v,
+			})
+		}
+		return entries, nil
+	}
+	return nil, errExpectedMap
+}
+
+func customBytesEntries(m map[serialization.CustomBytes]PlutusData.PlutusData) ([]mapEntry, error) {
+	entries := make([]mapEntry, 0, len(m))
+	for k, v := range m {
+		encodedKey, err := k.MarshalCBOR()
+		if err != nil {
+			return nil, fmt.Errorf("map key: %w", err)
+		}
+		key := PlutusData.PlutusData{}
+		err = cbor.Unmarshal(encodedKey, &key)
+		if err != nil {
+			return nil, fmt.Errorf("map key: %w", err)
+		}
+		entries = append(entries, mapEntry{key: key, value: v})
+	}
+	return entries, nil
+}
+
+func plutusDataKeyEntries(m map[PlutusData.PlutusDataKey]PlutusData.PlutusData) ([]mapEntry, error) {
+	entries := make([]mapEntry, 0, len(m))
+	for k, v := range m {
+		encodedKey, err := hex.DecodeString(k.CborHexValue)
+		if err != nil {
+			return nil, fmt.Errorf("map key: %w", err)
+		}
+		key := PlutusData.PlutusData{}
+		err = cbor.Unmarshal(encodedKey, &key)
+		if err != nil {
+			return nil, fmt.Errorf("map key: %w", err)
+		}
+		entries = append(entries, mapEntry{key: key, value: v})
+	}
+	return entries, nil
+}
@@ -3,6 +3,7 @@ package plutusencoder_test
 import (
 	"encoding/hex"
 	"fmt"
+	"math/big"
 	"testing"
 
 	"github.com/Salvionied/apollo/plutusencoder"
@@ -926,3 +927,291 @@ func TestMap(t *testing.T) {
 	}
 
 }
+
+type BigIntDatum struct {
+	_        struct{} `plutusType:"IndefList" plutusConstr:"0"`
+	Small    *big.Int
+	Huge     *big.Int `plutusType:"Int"`
+	Negative big.Int
+	Counter  int
+}
+
+func TestBigIntRoundTrip(t *testing.T) {
+	huge, _ := new(big.Int).SetString("340282366920938463463374607431768211457", 10)
+	d := BigIntDatum{
+		Small:    big.NewInt(42),
+		Huge:     huge,
+		Negative: *big.NewInt(-1000),
+		Counter:  -5,
+	}
+	marshaled, err := plutusencoder.MarshalPlutus(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := cbor.Marshal(marshaled)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(encoded) != "d8799f182ac25101000000000000000000000000000000013903e724ff" {
+		t.Error("encoding error", hex.EncodeToString(encoded))
+	}
+	decoded := BigIntDatum{}
+	err = plutusencoder.CborUnmarshal(hex.EncodeToString(encoded), &decoded, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Small.Cmp(big.NewInt(42)) != 0 {
+		t.Error("small not correct", decoded.Small)
+	}
+	if decoded.Huge.Cmp(huge) != 0 {
+		t.Error("huge not correct", decoded.Huge)
+	}
+	if decoded.Negative.Cmp(big.NewInt(-1000)) != 0 {
+		t.Error("negative not correct", decoded.Negative.String())
+	}
+	if decoded.Counter != -5 {
+		t.Error("counter not correct", decoded.Counter)
+	}
+}
+
+type Int8Datum struct {
+	_     struct{} `plutusType:"DefList" plutusConstr:"0"`
+	Value int8
+}
+
+func TestBigIntOverflow(t *testing.T) {
+	decoded := Int8Datum{}
+	err := plutusencoder.CborUnmarshal("d879811901f4", &decoded, 1)
+	if err == nil {
+		t.Fatal("should have thrown error")
+	}
+	if err.Error() != "error unmarshalling: field Int8Datum.Value: integer 500 overflows int8" {
+		t.Error(err)
+	}
+}
+
+type OptionDatum struct {
+	_        struct{}    `plutusType:"DefList" plutusConstr:"0"`
+	Deadline *int64      `plutusType:"Option"`
+	Buyer    *BuyerDatum `plutusType:"Option"`
+}
+
+func TestOptionRoundTrip(t *testing.T) {
+	deadline := int64(1000)
+	d := OptionDatum{Deadline: &deadline}
+	marshaled, err := plutusencoder.MarshalPlutus(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := cbor.Marshal(marshaled)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(encoded) != "d87982d8799f1903e8ffd87a80" {
+		t.Error("encoding error", hex.EncodeToString(encoded))
+	}
+	decoded := OptionDatum{Buyer: &BuyerDatum{}}
+	err = plutusencoder.CborUnmarshal(hex.EncodeToString(encoded), &decoded, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Deadline == nil || *decoded.Deadline != 1000 {
+		t.Error("deadline not correct", decoded.Deadline)
+	}
+	if decoded.Buyer != nil {
+		t.Error("buyer should be None")
+	}
+}
+
+type Action interface {
+	isAction()
+}
+
+type Buy struct {
+	_      struct{} `plutusType:"DefList" plutusConstr:"0"`
+	Amount int64
+}
+
+type Sell struct {
+	_      struct{} `plutusType:"DefList" plutusConstr:"1"`
+	Amount int64
+	Inner  Action
+}
+
+type Cancel struct {
+	_ struct{} `plutusType:"DefList" plutusConstr:"2"`
+}
+
+func (Buy) isAction()    {}
+func (*Sell) isAction()  {}
+func (Cancel) isAction() {}
+
+type Order struct {
+	_      struct{} `plutusType:"DefList" plutusConstr:"0"`
+	Owner  []byte
+	Action Action
+	Price  int64
+}
+
+func TestSumTypeRoundTrip(t *testing.T) {
+	err := plutusencoder.RegisterSumType((*Action)(nil), Buy{}, &Sell{}, Cancel{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := Order{
+		Owner:  []byte{0x01, 0x02},
+		Action: &Sell{Amount: 5, Inner: Cancel{}},
+		Price:  10,
+	}
+	marshaled, err := plutusencoder.MarshalPlutus(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := cbor.Marshal(marshaled)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(encoded) != "d87983420102d87a8205d87b800a" {
+		t.Error("encoding error", hex.EncodeToString(encoded))
+	}
+	decoded := Order{}
+	err = plutusencoder.CborUnmarshal(hex.EncodeToString(encoded), &decoded, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sell, ok := decoded.Action.(*Sell)
+	if !ok {
+		t.Fatalf("expected *Sell, got %T", decoded.Action)
+	}
+	if sell.Amount != 5 {
+		t.Error("amount not correct", sell.Amount)
+	}
+	if _, ok := sell.Inner.(Cancel); !ok {
+		t.Errorf("expected Cancel, got %T", sell.Inner)
+	}
+	if decoded.Price != 10 {
+		t.Error("price not correct", decoded.Price)
+	}
+}
+
+func TestRegisterSumTypeDuplicateConstr(t *testing.T) {
+	err := plutusencoder.RegisterSumType((*Action)(nil), Buy{}, Buy{})
+	if err == nil {
+		t.Error("should have thrown error")
+	}
+	err = plutusencoder.RegisterSumType(Buy{}, Buy{})
+	if err == nil {
+		t.Error("should have thrown error")
+	}
+}
+
+type AssetKey struct {
+	_      struct{} `plutusType:"DefList" plutusConstr:"0"`
+	Policy string   `plutusType:"HexString"`
+	Name   string
+}
+
+type GenericMaps struct {
+	_       struct{} `plutusType:"DefList" plutusConstr:"0"`
+	ByIndex map[int64][]byte
+	ByName  map[string]int64
+	ByAsset map[AssetKey]int64 `plutusType:"Map"`
+}
+
+func TestGenericMapRoundTrip(t *testing.T) {
+	d := GenericMaps{
+		ByIndex: map[int64][]byte{-1: {0x01}, 2: {0x02}},
+		ByName:  map[string]int64{"a": 1, "b": 2},
+		ByAsset: map[AssetKey]int64{{Name: "x"}: 7},
+	}
+	marshaled, err := plutusencoder.MarshalPlutus(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := cbor.Marshal(marshaled)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(encoded) != "d87983a2024102204101a2416101416202a1d8798240417807" {
+		t.Error("encoding error", hex.EncodeToString(encoded))
+	}
+	decoded := GenericMaps{}
+	err = plutusencoder.CborUnmarshal(hex.EncodeToString(encoded), &decoded, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(decoded.ByIndex[-1]) != "01" || hex.EncodeToString(decoded.ByIndex[2]) != "02" {
+		t.Error("ByIndex not correct", decoded.ByIndex)
+	}
+	if decoded.ByName["a"] != 1 || decoded.ByName["b"] != 2 {
+		t.Error("ByName not correct", decoded.ByName)
+	}
+	if len(decoded.ByAsset) != 1 {
+		t.Fatal("ByAsset not correct", decoded.ByAsset)
+	}
+	for k, v := range decoded.ByAsset {
+		if k.Name != "x" || k.Policy != "" || v != 7 {
+			t.Error("ByAsset not correct", k, v)
+		}
+	}
+}
+
+type BoolAndUnit struct {
+	_       struct{} `plutusType:"DefList" plutusConstr:"0"`
+	Enabled bool
+	Nothing struct{}
+}
+
+func TestBoolAndUnitRoundTrip(t *testing.T) {
+	marshaled, err := plutusencoder.MarshalPlutus(BoolAndUnit{Enabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := cbor.Marshal(marshaled)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(encoded) != "d87982d87a80d87980" {
+		t.Error("encoding error", hex.EncodeToString(encoded))
+	}
+	decoded := BoolAndUnit{}
+	err = plutusencoder.CborUnmarshal(hex.EncodeToString(encoded), &decoded, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Enabled {
+		t.Error("enabled not correct")
+	}
+}
+
+func TestErrorPaths(t *testing.T) {
+	_, err := plutusencoder.MarshalPlutus(InvalidInt{Value: "test"})
+	if err == nil || err.Error() != "field InvalidInt.Value: expected int" {
+		t.Error(err)
+	}
+	err = plutusencoder.RegisterSumType((*Action)(nil), Buy{}, &Sell{}, Cancel{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := Order{}
+	err = plutusencoder.UnmarshalPlutus(&PlutusData.PlutusData{
+		TagNr:          121,
+		PlutusDataType: PlutusData.PlutusArray,
+		Value: PlutusData.PlutusDefArray{
+			{PlutusDataType: PlutusData.PlutusBytes, Value: []byte{}},
+			{TagNr: 123, PlutusDataType: PlutusData.PlutusArray, Value: PlutusData.PlutusDefArray{}},
+			{PlutusDataType: PlutusData.PlutusBytes, Value: []byte{}},
+		},
+	}, &decoded, 1)
+	if err == nil || err.Error() != "field Order.Price: expected int" {
+		t.Error(err)
+	}
+	err = plutusencoder.UnmarshalPlutus(&PlutusData.PlutusData{
+		TagNr:          122,
+		PlutusDataType: PlutusData.PlutusArray,
+		Value:          PlutusData.PlutusDefArray{},
+	}, &decoded, 1)
+	if err == nil || err.Error() != "expected constructor 0, got tag 122" {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,110 @@
+package plutusencoder
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/Salvionied/apollo/serialization/PlutusData"
+)
+
+type sumTypeVariant struct {
+	variant reflect.Type
+	tag     uint64
+}
+
+var (
+	sumTypesMu sync.RWMutex
+	sumTypes   = map[reflect.Type][]sumTypeVariant{}
+)
+
+/*
+*
+
+	RegisterSumType registers the variants of an Aiken-style enum
+	represented in Go as an interface, so that fields of that interface
+	type can be unmarshaled. Every variant must be a struct (or a pointer
+	to a struct) implementing the interface whose `_` field declares a
+	distinct plutusConstr.
+
+	Params:
+		iface (interface{}): A nil pointer to the interface, e.g. (*Action)(nil).
+		variants (...interface{}): A zero value of every variant.
+
+	Returns:
+		error: An error if the interface or any of the variants are invalid.
+*/
+func RegisterSumType(iface interface{}, variants ...interface{}) error {
+	ifaceType := reflect.TypeOf(iface)
+	if ifaceType == nil || ifaceType.Kind() != reflect.Ptr || ifaceType.Elem().Kind() != reflect.Interface {
+		return errors.New("error: iface must be a pointer to an interface")
+	}
+	ifaceType = ifaceType.Elem()
+	registered := make([]sumTypeVariant, 0, len(variants))
+	for _, variant := range variants {
+		variantType := reflect.TypeOf(variant)
+		if variantType == nil || !variantType.Implements(ifaceType) {
+			return fmt.Errorf("error: %v does not implement %s", variantType, ifaceType)
+		}
+		structType := variantType
+		if structType.Kind() == reflect.Ptr {
+			structType = structType.Elem()
+		}
+		if structType.Kind() != reflect.Struct {
+			return fmt.Errorf("error: variant %s is not a struct", variantType)
+		}
+		header, ok := structType.FieldByName("_")
+		if !ok || header.Tag.Get("plutusConstr") == "" {
+			return fmt.Errorf("error: variant %s has no plutusConstr", variantType)
+		}
+		if header.Tag.Get("plutusType") == "Map" {
+			return fmt.Errorf("error: variant %s must be a list", variantType)
+		}
+		tag, err := parseConstr(header.Tag.Get("plutusConstr"))
+		if err != nil {
+			return fmt.Errorf("error: variant %s: %w", variantType, err)
+		}
+		for _, other := range registered {
+			if other.tag == tag {
+				return fmt.Errorf("error: variants %s and %s share constructor %s", other.variant, variantType, header.Tag.Get("plutusConstr"))
+			}
+		}
+		registered = append(registered, sumTypeVariant{variant: variantType, tag: tag})
+	}
+	sumTypesMu.Lock()
+	defer sumTypesMu.Unlock()
+	sumTypes[ifaceType] = registered
+	return nil
+}
+
+func unmarshalSumType(data PlutusData.PlutusData, dst reflect.Value, network byte) error {
+	sumTypesMu.RLock()
+	variants, ok := sumTypes[dst.Type()]
+	sumTypesMu.RUnlock()
+	if !ok {
+		return fmt.Errorf("no variants registered for %s", dst.Type())
+	}
+	for _, v := range variants {
+		if v.tag != data.TagNr {
+			continue
+		}
+		isPtr := v.variant.Kind() == reflect.Ptr
+		structType := v.variant
+		if isPtr {
+			structType = structType.Elem()
+		}
+		value := reflect.New(structType)
+		err := unmarshalValue(data, value.Elem(), "", network)
+		if err != nil {
+			return fmt.Errorf("variant %s: %w", structType.Name(), err)
+		}
+		if isPtr {
+			dst.Set(value)
+		} else {
+			dst.Set(value.Elem())
+		}
+		return nil
+	}
+	return fmt.Errorf("no variant of %s matches constructor tag %d", dst.Type(), data.TagNr)
+}
@@ -0,0 +1,242 @@
+package remotesigner_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Salvionied/apollo"
+	"github.com/Salvionied/apollo/apollotypes"
+	"github.com/Salvionied/apollo/constants"
+	"github.com/Salvionied/apollo/remotesigner"
+	"github.com/Salvionied/apollo/serialization/Address"
+	"github.com/Salvionied/apollo/serialization/AssetName"
+	"github.com/Salvionied/apollo/serialization/Certificate"
+	"github.com/Salvionied/apollo/serialization/MultiAsset"
+	"github.com/Salvionied/apollo/serialization/Policy"
+	"github.com/Salvionied/apollo/serialization/Transaction"
+	"github.com/Salvionied/apollo/serialization/TransactionBody"
+	"github.com/Salvionied/apollo/serialization/TransactionInput"
+	"github.com/Salvionied/apollo/serialization/TransactionOutput"
+	"github.com/Salvionied/apollo/serialization/Value"
+	"github.com/Salvionied/apollo/txBuilding/Backend/FixedChainContext"
+	"github.com/Salvionied/cbor/v2"
+)
+
+const MNEMONIC_12 = "test walk nut penalty hip pave soap entry language right filter choice"
+const RECEIVER = "addr1qxajla3qcrwckzkur8n0lt02rg2sepw3kgkstckmzrz4ccfm3j9pqrqkea3tns46e3qy2w42vl8dvvue8u45amzm3rjqvv2nxh"
+const OTHER = "addr1qymaeeefs9ff08cdplm3lvkscavm9x9vd7nmc44e9rlur08k3pj2xw9w3mvp7cg3fkzhed4zzhywdpd2t3pmc8u8nn8qm5ur5w"
+
+func newSigner(t *testing.T) *httptest.Server {
+	local, err := apollo.New(FixedChainContext.InitFixedChainContext()).SetWalletFromMnemonic(MNEMONIC_12, constants.MAINNET)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := remotesigner.NewServer(local.GetWallet(),
+		remotesigner.MaxSpendPolicy{MaxLovelace: 5_000_000},
+		remotesigner.AllowedDestinationsPolicy{Addresses: []string{RECEIVER}},
+	)
+	server.Token = "secret"
+	srv := httptest.NewServer(server)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func build(t *testing.T, rw *remotesigner.RemoteWallet, receiver string, lovelace int) *apollo.Apollo {
+	cc := FixedChainContext.InitFixedChainContext()
+	built, err := apollo.New(cc).
+		SetWallet(rw).
+		AddLoadedUTxOs(cc.Utxos(*rw.GetAddress())...).
+		SetWalletAsChangeAddress().
+		PayToAddressBech32(receiver, lovelace).
+		Complete()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return built
+}
+
+func TestRemoteWallet(t *testing.T) {
+	srv := newSigner(t)
+	if _, err := remotesigner.NewRemoteWallet(srv.URL, "wrong", srv.Client()); err == nil {
+		t.Error("Expected an error for a wrong token")
+	}
+	rw, err := remotesigner.NewRemoteWallet(srv.URL, "secret", srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	local, _ := apollo.New(FixedChainContext.InitFixedChainContext()).SetWalletFromMnemonic(MNEMONIC_12, constants.MAINNET)
+	if rw.GetAddress().String() != local.GetWallet().GetAddress().String() || rw.PkeyHash() != local.GetWallet().PkeyHash() {
+		t.Error("Invalid remote wallet address")
+	}
+
+	tx := build(t, rw, RECEIVER, 2_000_000).Sign().GetTx()
+	if rw.LastError != nil {
+		t.Fatal(rw.LastError)
+	}
+	if len(tx.TransactionWitnessSet.VkeyWitnesses) != 1 {
+		t.Fatal("Invalid witnesses", len(tx.TransactionWitnessSet.VkeyWitnesses))
+	}
+	txHash, _ := tx.TransactionBody.Hash()
+	witness := tx.TransactionWitnessSet.VkeyWitnesses[0]
+	if !ed25519.Verify(witness.Vkey.Payload, txHash, witness.Signature) {
+		t.Error("Invalid signature")
+	}
+	witness_set, err := rw.RequestSignature(*tx)
+	if err != nil || len(witness_set.VkeyWitnesses) != 1 {
+		t.Error("Witnesses must not be duplicated", err)
+	}
+}
+
+func TestSignerPolicies(t *testing.T) {
+	srv := newSigner(t)
+	rw, err := remotesigner.NewRemoteWallet(srv.URL, "secret", srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var policyError *remotesigner.PolicyError
+	tx := build(t, rw, RECEIVER, 6_000_000).Sign().GetTx()
+	if !errors.As(rw.LastError, &policyError) || len(tx.TransactionWitnessSet.VkeyWitnesses) != 0 {
+		t.Error("Expected the max spend policy to reject the transaction", rw.LastError)
+	}
+	tx = build(t, rw, OTHER, 2_000_000).Sign().GetTx()
+	if !errors.As(rw.LastError, &policyError) || len(tx.TransactionWitnessSet.VkeyWitnesses) != 0 {
+		t.Error("Expected the destination policy to reject the transaction", rw.LastError)
+	}
+
+	txBytes, _ := build(t, rw, RECEIVER, 2_000_000).GetTx().Bytes()
+	body, _ := json.Marshal(remotesigner.SignRequest{TxHash: hex.EncodeToString(make([]byte, 32)), TxCbor: hex.EncodeToString(txBytes)})
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+remotesigner.SIGN_PATH, bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Error("A hash not matching the body must be refused", resp.StatusCode)
+	}
+}
+
+func TestSignerOwnAddresses(t *testing.T) {
+	account, err := apollotypes.NewAccountWalletFromMnemonic(MNEMONIC_12, "", 0, constants.MAINNET, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := remotesigner.NewServer(account, remotesigner.MaxSpendPolicy{MaxLovelace: 1_000_000})
+	srv := httptest.NewServer(server)
+	t.Cleanup(srv.Close)
+	rw, err := remotesigner.NewRemoteWallet(srv.URL, "", srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// change sent to an address the signer has not derived yet is own
+	other, _ := apollotypes.NewAccountWalletFromMnemonic(MNEMONIC_12, "", 0, constants.MAINNET, 0)
+	internal, _ := other.DeriveAddress(apollotypes.INTERNAL_CHAIN, 5)
+	build(t, rw, internal.String(), 2_000_000).Sign()
+	if rw.LastError != nil {
+		t.Error("Expected a payment to an own address to be accepted", rw.LastError)
+	}
+
+	// the own payment key with another stake credential is not own
+	receiver, _ := Address.DecodeAddress(RECEIVER)
+	hijacked := internal
+	hijacked.StakingPart = receiver.StakingPart
+	var policyError *remotesigner.PolicyError
+	build(t, rw, hijacked.String(), 2_000_000).Sign()
+	if !errors.As(rw.LastError, &policyError) {
+		t.Error("Expected a payment to another stake credential to be refused", rw.LastError)
+	}
+}
+
+func TestMaxSpendPolicy(t *testing.T) {
+	own, _ := Address.DecodeAddress(RECEIVER)
+	other, _ := Address.DecodeAddress(OTHER)
+	isOwn := func(address Address.Address) bool {
+		return address.Equal(&own)
+	}
+	policy, _ := Policy.New("00000000000000000000000000000000000000000000000000000001")
+	token := MultiAsset.MultiAsset[int64]{*policy: {AssetName.NewAssetNameFromString("token"): 10}}
+	unit := policy.String() + AssetName.NewAssetNameFromString("token").HexString()
+	limits := remotesigner.MaxSpendPolicy{MaxLovelace: 3_000_000, MaxAssets: map[string]int64{unit: 10}, KeyDeposit: 2_000_000}
+	newTx := func(change int64, payment Value.Value) Transaction.Transaction {
+		return Transaction.Transaction{TransactionBody: TransactionBody.TransactionBody{
+			Fee: 200_000,
+			Outputs: []TransactionOutput.TransactionOutput{
+				TransactionOutput.SimpleTransactionOutput(own, Value.PureLovelaceValue(change)),
+				TransactionOutput.SimpleTransactionOutput(other, payment),
+			},
+		}}
+	}
+	if err := limits.Check(newTx(50_000_000, Value.SimpleValue(2_000_000, token)), isOwn); err != nil {
+		t.Error("Expected the transaction to be allowed", err)
+	}
+	if err := limits.Check(newTx(50_000_000, Value.SimpleValue(2_000_000, token.Add(token))), isOwn); err == nil {
+		t.Error("Expected the asset limit to refuse the transaction")
+	}
+	noAssets := remotesigner.MaxSpendPolicy{MaxLovelace: 3_000_000}
+	if err := noAssets.Check(newTx(50_000_000, Value.SimpleValue(2_000_000, token)), isOwn); err == nil {
+		t.Error("Expected assets without a limit to be refused")
+	}
+
+	donation := newTx(50_000_000, Value.PureLovelaceValue(2_000_000))
+	donation.TransactionBody.Donation = 1_000_000
+	if err := limits.Check(donation, isOwn); err == nil {
+		t.Error("Expected the donation to be counted")
+	}
+
+	registration := newTx(50_000_000, Value.PureLovelaceValue(1_000_000))
+	registration.TransactionBody.Certificates = &Certificate.Certificates{{Kind: Certificate.STAKE_REGISTRATION, StakeCredential: &Certificate.StakeCredential{}}}
+	if err := limits.Check(registration, isOwn); err == nil {
+		t.Error("Expected the key deposit to be counted")
+	}
+	deposit, _ := cbor.Marshal(int64(2_000_000))
+	registration.TransactionBody.Certificates = &Certificate.Certificates{{Kind: Certificate.REG_CERT, StakeCredential: &Certificate.StakeCredential{}, Fields: []cbor.RawMessage{deposit}}}
+	if err := limits.Check(registration, isOwn); err == nil {
+		t.Error("Expected the certificate deposit to be counted")
+	}
+	registration.TransactionBody.Certificates = &Certificate.Certificates{{Kind: Certificate.MOVE_INSTANTANEOUS_REWARDS}}
+	if err := limits.Check(registration, isOwn); err == nil {
+		t.Error("Expected instantaneous rewards to be refused")
+	}
+
+	proposal, _ := cbor.Marshal([]any{[]any{int64(100_000_000_000), make([]byte, 29), []any{6}, []any{"https://example.com", make([]byte, 32)}}})
+	governance := newTx(50_000_000, Value.PureLovelaceValue(1_000_000))
+	governance.TransactionBody.ProposalProcedures = proposal
+	if err := limits.Check(governance, isOwn); err == nil {
+		t.Error("Expected governance fields to be refused by default")
+	}
+	allowed := limits
+	allowed.AllowGovernance = true
+	if err := allowed.Check(governance, isOwn); err == nil {
+		t.Error("Expected the proposal deposit to be counted")
+	}
+	governance.TransactionBody.ProposalProcedures = nil
+	governance.TransactionBody.CurrentTreasuryValue = 1
+	if err := limits.Check(governance, isOwn); err == nil {
+		t.Error("Expected the treasury value to be refused by default")
+	}
+
+	collateral := newTx(50_000_000, Value.PureLovelaceValue(1_000_000))
+	collateral.TransactionBody.Collateral = []TransactionInput.TransactionInput{{TransactionId: make([]byte, 32)}}
+	if err := limits.Check(collateral, isOwn); err == nil {
+		t.Error("Expected collateral without a total to be refused")
+	}
+	collateral.TransactionBody.TotalCollateral = 300_000
+	stolen := TransactionOutput.SimpleTransactionOutput(other, Value.PureLovelaceValue(5_000_000))
+	collateral.TransactionBody.CollateralReturn = &stolen
+	if err := limits.Check(collateral, isOwn); err == nil {
+		t.Error("Expected a collateral return to another address to be counted")
+	}
+	destinations := remotesigner.AllowedDestinationsPolicy{Addresses: []string{}}
+	collateral.TransactionBody.Outputs = collateral.TransactionBody.Outputs[:1]
+	if err := destinations.Check(collateral, isOwn); err == nil {
+		t.Error("Expected the collateral return destination to be checked")
+	}
+}
@@ -0,0 +1,415 @@
+package remotesigner
+
+import (
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/Salvionied/apollo/apollotypes"
+	serAddress "github.com/Salvionied/apollo/serialization/Address"
+	"github.com/Salvionied/apollo/serialization/Certificate"
+	"github.com/Salvionied/apollo/serialization/MultiAsset"
+	"github.com/Salvionied/apollo/serialization/Transaction"
+	"github.com/Salvionied/apollo/serialization/TransactionOutput"
+	"github.com/Salvionied/apollo/serialization/TransactionWitnessSet"
+	"github.com/Salvionied/cbor/v2"
+)
+
+/*
+*
+
+	Policy decides whether the signer may sign a transaction.
+	isOwn reports whether an address belongs to the signer, so
+	that change outputs can be told apart from payments.
+*/
+type Policy interface {
+	Check(tx Transaction.Transaction, isOwn func(serAddress.Address) bool) error
+}
+
+// PolicyFunc adapts a function to a Policy.
+type PolicyFunc func(tx Transaction.Transaction, isOwn func(serAddress.Address) bool) error
+
+func (f PolicyFunc) Check(tx Transaction.Transaction, isOwn func(serAddress.Address) bool) error {
+	return f(tx, isOwn)
+}
+
+/*
+*
+
+	MaxSpendPolicy limits what leaves the signer's addresses: the
+	lovelace of the outputs to other addresses, the fee, the
+	donation and the deposits of certificates and proposals, or the
+	collateral when the scripts fail. Withdrawals only move rewards
+	into the outputs, where they are counted. Native assets sent to
+	other addresses or burnt are limited per asset.
+
+	Transactions with governance or treasury fields are refused
+	unless AllowGovernance is set, and certificates the policy
+	cannot price are always refused.
+*/
+type MaxSpendPolicy struct {
+	MaxLovelace int64
+	// MaxAssets limits each native asset, keyed by its unit (policy
+	// id followed by the hex asset name). Assets without a limit
+	// cannot leave the signer.
+	MaxAssets map[string]int64
+	// KeyDeposit and PoolDeposit are the deposits of stake and pool
+	// registrations, which their certificates do not carry. These
+	// registrations are refused while the deposit is zero.
+	KeyDeposit  int64
+	PoolDeposit int64
+	// AllowGovernance accepts votes, proposals, committee and DRep
+	// certificates and the treasury value.
+	AllowGovernance bool
+}
+
+type proposalProcedure struct {
+	_             struct{} `cbor:",toarray"`
+	Deposit       int64
+	RewardAccount []byte
+	GovAction     cbor.RawMessage
+	Anchor        cbor.RawMessage
+}
+
+// proposalDeposits sums the deposits of the proposal procedures (body key 20).
+func proposalDeposits(value cbor.RawMessage) (int64, error) {
+	var tag cbor.RawTag
+	if len(value) > 0 && value[0]>>5 == 6 && cbor.Unmarshal(value, &tag) == nil && tag.Number == 258 {
+		value = tag.Content
+	}
+	var proposals []proposalProcedure
+	err := cbor.Unmarshal(value, &proposals)
+	if err != nil {
+		return 0, fmt.Errorf("invalid proposal procedures: %w", err)
+	}
+	total := int64(0)
+	for _, proposal := range proposals {
+		total += proposal.Deposit
+	}
+	return total, nil
+}
+
+// certificateDeposit returns the deposit paid by a certificate.
+func (p MaxSpendPolicy) certificateDeposit(certificate *Certificate.Certificate) (int64, error) {
+	// index of the deposit among the fields following the credential
+	depositField := -1
+	switch certificate.Kind {
+	case Certificate.STAKE_REGISTRATION:
+		if p.KeyDeposit == 0 {
+			return 0, errors.New("stake registrations need the key deposit of the policy")
+		}
+		return p.KeyDeposit, nil
+	case Certificate.POOL_REGISTRATION:
+		if p.PoolDeposit == 0 {
+			return 0, errors.New("pool registrations need the pool deposit of the policy")
+		}
+		return p.PoolDeposit, nil
+	case Certificate.STAKE_DEREGISTRATION, Certificate.STAKE_DELEGATION, Certificate.POOL_RETIREMENT,
+		Certificate.UNREG_CERT, Certificate.VOTE_DELEG_CERT, Certificate.STAKE_VOTE_DELEG_CERT:
+		return 0, nil
+	case Certificate.REG_CERT:
+		depositField = 0
+	case Certificate.STAKE_REG_DELEG_CERT, Certificate.VOTE_REG_DELEG_CERT:
+		depositField = 1
+	case Certificate.STAKE_VOTE_REG_DELEG_CERT:
+		depositField = 2
+	case Certificate.AUTH_COMMITTEE_HOT_CERT, Certificate.RESIGN_COMMITTEE_COLD_CERT,
+		Certificate.UNREG_DREP_CERT, Certificate.UPDATE_DREP_CERT:
+		if !p.AllowGovernance {
+			return 0, fmt.Errorf("governance certificate %d is not allowed", certificate.Kind)
+		}
+		return 0, nil
+	case Certificate.REG_DREP_CERT:
+		if !p.AllowGovernance {
+			return 0, fmt.Errorf("governance certificate %d is not allowed", certificate.Kind)
+		}
+		depositField = 0
+	default:
+		return 0, fmt.Errorf("certificate %d is not supported", certificate.Kind)
+	}
+	if depositField >= len(certificate.Fields) {
+		return 0, fmt.Errorf("certificate %d has no deposit", certificate.Kind)
+	}
+	deposit := int64(0)
+	err := cbor.Unmarshal(certificate.Fields[depositField], &deposit)
+	if err != nil {
+		return 0, fmt.Errorf("invalid deposit of certificate %d: %w", certificate.Kind, err)
+	}
+	return deposit, nil
+}
+
+func addAssets(spent map[string]int64, assets MultiAsset.MultiAsset[int64], sign int64) {
+	for policy, tokens := range assets {
+		for name, quantity := range tokens {
+			spent[policy.String()+name.HexString()] += sign * quantity
+		}
+	}
+}
+
+func (p MaxSpendPolicy) Check(tx Transaction.Transaction, isOwn func(serAddress.Address) bool) error {
+	body := tx.TransactionBody
+	if !p.AllowGovernance {
+		switch {
+		case len(body.VotingProcedures) > 0:
+			return errors.New("voting procedures are not allowed")
+		case len(body.ProposalProcedures) > 0:
+			return errors.New("proposal procedures are not allowed")
+		case body.CurrentTreasuryValue != 0:
+			return errors.New("the treasury value is not allowed")
+		}
+	}
+	if len(body.UpdateProposals) > 0 {
+		return errors.New("update proposals are not supported")
+	}
+	spent := body.Fee + body.Donation
+	assets := make(map[string]int64)
+	for _, output := range body.Outputs {
+		if !isOwn(output.GetAddress()) {
+			spent += output.GetValue().GetCoin()
+			addAssets(assets, output.GetValue().GetAssets(), 1)
+		}
+	}
+	// burnt assets leave the signer too, minted ones are ignored
+	for policy, tokens := range body.Mint {
+		for name, quantity := range tokens {
+			if quantity < 0 {
+				assets[policy.String()+name.HexString()] -= quantity
+			}
+		}
+	}
+	if body.Certificates != nil {
+		for _, certificate := range *body.Certificates {
+			deposit, err := p.certificateDeposit(certificate)
+			if err != nil {
+				return err
+			}
+			spent += deposit
+		}
+	}
+	if len(body.ProposalProcedures) > 0 {
+		deposits, err := proposalDeposits(body.ProposalProcedures)
+		if err != nil {
+			return err
+		}
+		spent += deposits
+	}
+	if spent > p.MaxLovelace {
+		return fmt.Errorf("spends %d lovelace, more than the %d allowed", spent, p.MaxLovelace)
+	}
+	if len(body.Collateral) > 0 {
+		if body.TotalCollateral == 0 {
+			return errors.New("collateral without a total collateral cannot be checked")
+		}
+		// when the scripts fail only the collateral is spent
+		lost := int64(body.TotalCollateral)
+		if body.CollateralReturn != nil && !isOwn(body.CollateralReturn.GetAddress()) {
+			lost += body.CollateralReturn.GetValue().GetCoin()
+			addAssets(assets, body.CollateralReturn.GetValue().GetAssets(), 1)
+		}
+		if lost > p.MaxLovelace {
+			return fmt.Errorf("risks %d lovelace of collateral, more than the %d allowed", lost, p.MaxLovelace)
+		}
+	}
+	for unit, quantity := range assets {
+		if quantity > p.MaxAssets[unit] {
+			return fmt.Errorf("spends %d of %s, more than the %d allowed", quantity, unit, p.MaxAssets[unit])
+		}
+	}
+	return nil
+}
+
+/*
+*
+
+	AllowedDestinationsPolicy only allows payments to the given
+	bech32 addresses, besides the signer's own addresses. The
+	collateral return is checked like the outputs.
+*/
+type AllowedDestinationsPolicy struct {
+	Addresses []string
+}
+
+func (p AllowedDestinationsPolicy) Check(tx Transaction.Transaction, isOwn func(serAddress.Address) bool) error {
+	outputs := tx.TransactionBody.Outputs
+	if tx.TransactionBody.CollateralReturn != nil {
+		outputs = append(append([]TransactionOutput.TransactionOutput{}, outputs...), *tx.TransactionBody.CollateralReturn)
+	}
+	for _, output := range outputs {
+		address := output.GetAddress()
+		if isOwn(address) {
+			continue
+		}
+		allowed := false
+		for _, destination := range p.Addresses {
+			if destination == address.String() {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("destination %s is not allowed", address.String())
+		}
+	}
+	return nil
+}
+
+/*
+*
+
+	Server is a reference signer service: it holds a wallet and
+	signs the transactions accepted by all its policies.
+*/
+type Server struct {
+	Wallet   apollotypes.Wallet
+	Policies []Policy
+	// Token, when set, must be sent as a bearer token.
+	Token string
+	// mu serializes the requests, as own addresses are derived on demand.
+	mu sync.Mutex
+}
+
+/*
+*
+
+	NewServer creates a signer for a wallet.
+
+	Params:
+		wallet (apollotypes.Wallet): The wallet holding the keys.
+		policies (...Policy): The policies every transaction must satisfy.
+
+	Returns:
+		*Server: The signer, to be served with net/http.
+*/
+func NewServer(wallet apollotypes.Wallet, policies ...Policy) *Server {
+	return &Server{Wallet: wallet, Policies: policies}
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+/*
+*
+
+	ownAddresses returns the full addresses of the signer. For an
+	account wallet the addresses are derived GapLimit past the last
+	used one on both chains, so that change sent to an address not
+	derived yet is still recognised.
+*/
+func (s *Server) ownAddresses() []serAddress.Address {
+	own := []serAddress.Address{*s.Wallet.GetAddress()}
+	if account, ok := s.Wallet.(*apollotypes.AccountWallet); ok {
+		for _, chain := range []uint32{apollotypes.EXTERNAL_CHAIN, apollotypes.INTERNAL_CHAIN} {
+			addresses := account.External
+			if chain == apollotypes.INTERNAL_CHAIN {
+				addresses = account.Internal
+			}
+			end := 0
+			for idx, derived := range addresses {
+				if derived.Used {
+					end = idx + 1
+				}
+			}
+			end += account.GapLimit
+			if end < len(addresses) {
+				end = len(addresses)
+			}
+			for idx := 0; idx < end; idx++ {
+				address, err := account.DeriveAddress(chain, uint32(idx))
+				if err != nil {
+					break
+				}
+				own = append(own, address)
+			}
+		}
+	}
+	return own
+}
+
+/*
+*
+
+	isOwn reports whether an address is one of the signer's,
+	comparing the whole address so that an own payment key with
+	another stake credential is not taken as own.
+*/
+func (s *Server) isOwn(own []serAddress.Address) func(serAddress.Address) bool {
+	return func(address serAddress.Address) bool {
+		for idx := range own {
+			if own[idx].Equal(&address) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.Token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+s.Token)) != 1 {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+		return
+	}
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == INFO_PATH:
+		pkh := s.Wallet.PkeyHash()
+		writeJSON(w, http.StatusOK, InfoResponse{
+			Address:    s.Wallet.GetAddress().String(),
+			PubKeyHash: hex.EncodeToString(pkh[:]),
+		})
+	case r.Method == http.MethodPost && r.URL.Path == SIGN_PATH:
+		s.sign(w, r)
+	default:
+		writeJSON(w, http.StatusNotFound, errorResponse{Error: "not found"})
+	}
+}
+
+func (s *Server) sign(w http.ResponseWriter, r *http.Request) {
+	req := SignRequest{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+	txBytes, err := hex.DecodeString(req.TxCbor)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+	tx := Transaction.Transaction{}
+	err = cbor.Unmarshal(txBytes, &tx)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+	txHash, err := tx.TransactionBody.Hash()
+	if err != nil || hex.EncodeToString(txHash) != req.TxHash {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "tx_hash does not match the transaction body"})
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	isOwn := s.isOwn(s.ownAddresses())
+	for _, policy := range s.Policies {
+		err = policy.Check(tx, isOwn)
+		if err != nil {
+			writeJSON(w, http.StatusForbidden, errorResponse{Error: err.Error()})
+			return
+		}
+	}
+	tx.TransactionWitnessSet = TransactionWitnessSet.TransactionWitnessSet{}
+	witness_set := s.Wallet.SignTx(tx)
+	res := SignResponse{Witnesses: make([]Witness, 0)}
+	for _, witness := range witness_set.VkeyWitnesses {
+		res.Witnesses = append(res.Witnesses, Witness{
+			Vkey:      hex.EncodeToString(witness.Vkey.Payload),
+			Signature: hex.EncodeToString(witness.Signature),
+		})
+	}
+	writeJSON(w, http.StatusOK, res)
+}
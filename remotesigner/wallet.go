@@ -0,0 +1,243 @@
+package remotesigner
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Salvionied/apollo/serialization"
+	serAddress "github.com/Salvionied/apollo/serialization/Address"
+	"github.com/Salvionied/apollo/serialization/Key"
+	"github.com/Salvionied/apollo/serialization/Transaction"
+	"github.com/Salvionied/apollo/serialization/TransactionWitnessSet"
+	"github.com/Salvionied/apollo/serialization/VerificationKeyWitness"
+)
+
+const (
+	INFO_PATH = "/v1/info"
+	SIGN_PATH = "/v1/sign"
+)
+
+type InfoResponse struct {
+	Address    string `json:"address"`
+	PubKeyHash string `json:"pub_key_hash"`
+}
+
+/*
+*
+
+	SignRequest carries the hash the signer signs and the full
+	transaction, so that the signer checks its policies against
+	the body it is actually signing.
+*/
+type SignRequest struct {
+	TxHash string `json:"tx_hash"`
+	TxCbor string `json:"tx_cbor"`
+}
+
+type Witness struct {
+	Vkey      string `json:"vkey"`
+	Signature string `json:"signature"`
+}
+
+type SignResponse struct {
+	Witnesses []Witness `json:"witnesses"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+/*
+*
+
+	PolicyError is returned when the signer refuses a transaction.
+*/
+type PolicyError struct {
+	Reason string
+}
+
+func (e *PolicyError) Error() string {
+	return "signer policy rejected the transaction: " + e.Reason
+}
+
+/*
+*
+
+	RemoteWallet is a wallet whose keys live in a signer service,
+	reached over HTTP.
+*/
+type RemoteWallet struct {
+	URL     string
+	Token   string
+	Client  *http.Client
+	address serAddress.Address
+	pkh     serialization.PubKeyHash
+	// LastError is the error of the last SignTx call, if any.
+	LastError error
+}
+
+/*
+*
+
+	NewRemoteWallet connects to a signer and fetches its address.
+
+	Params:
+		url (string): The base URL of the signer.
+		token (string): The bearer token of the signer, if any.
+		client (*http.Client): The HTTP client, http.DefaultClient if nil.
+
+	Returns:
+		*RemoteWallet: The remote wallet.
+		error: An error if the signer cannot be reached.
+*/
+func NewRemoteWallet(url string, token string, client *http.Client) (*RemoteWallet, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	rw := &RemoteWallet{URL: strings.TrimRight(url, "/"), Token: token, Client: client}
+	info := InfoResponse{}
+	err := rw.call(http.MethodGet, INFO_PATH, nil, &info)
+	if err != nil {
+		return nil, err
+	}
+	rw.address, err = serAddress.DecodeAddress(info.Address)
+	if err != nil {
+		return nil, err
+	}
+	pkh, err := hex.DecodeString(info.PubKeyHash)
+	if err != nil || len(pkh) != len(rw.pkh) {
+		return nil, fmt.Errorf("invalid signer key hash %s", info.PubKeyHash)
+	}
+	copy(rw.pkh[:], pkh)
+	return rw, nil
+}
+
+func (rw *RemoteWallet) call(method string, path string, body any, res any) error {
+	var reader *bytes.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(encoded)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req, err := http.NewRequest(method, rw.URL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if rw.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+rw.Token)
+	}
+	resp, err := rw.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		errRes := errorResponse{}
+		_ = json.NewDecoder(resp.Body).Decode(&errRes)
+		if resp.StatusCode == http.StatusForbidden {
+			return &PolicyError{Reason: errRes.Error}
+		}
+		return fmt.Errorf("signer returned %d: %s", resp.StatusCode, errRes.Error)
+	}
+	return json.NewDecoder(resp.Body).Decode(res)
+}
+
+func (rw *RemoteWallet) GetAddress() *serAddress.Address {
+	return &rw.address
+}
+
+func (rw *RemoteWallet) PkeyHash() serialization.PubKeyHash {
+	return rw.pkh
+}
+
+/*
+*
+
+	RequestSignature asks the signer to witness a transaction and
+	merges the returned witnesses into its witness set.
+
+	Params:
+		tx (Transaction.Transaction): The transaction to be signed.
+
+	Returns:
+		TransactionWitnessSet.TransactionWitnessSet: The updated witness set.
+		error: An error if the signer refuses or cannot be reached.
+*/
+func (rw *RemoteWallet) RequestSignature(tx Transaction.Transaction) (TransactionWitnessSet.TransactionWitnessSet, error) {
+	witness_set := tx.TransactionWitnessSet
+	txHash, err := tx.TransactionBody.Hash()
+	if err != nil {
+		return witness_set, err
+	}
+	txBytes, err := tx.Bytes()
+	if err != nil {
+		return witness_set, err
+	}
+	res := SignResponse{}
+	err = rw.call(http.MethodPost, SIGN_PATH, SignRequest{
+		TxHash: hex.EncodeToString(txHash),
+		TxCbor: hex.EncodeToString(txBytes),
+	}, &res)
+	if err != nil {
+		return witness_set, err
+	}
+	for _, witness := range res.Witnesses {
+		vkey, err := hex.DecodeString(witness.Vkey)
+		if err != nil {
+			return tx.TransactionWitnessSet, err
+		}
+		signature, err := hex.DecodeString(witness.Signature)
+		if err != nil {
+			return tx.TransactionWitnessSet, err
+		}
+		if len(vkey) != ed25519.PublicKeySize || !ed25519.Verify(vkey, txHash, signature) {
+			return tx.TransactionWitnessSet, fmt.Errorf("invalid signature from signer for key %s", witness.Vkey)
+		}
+		if hasWitness(witness_set, vkey) {
+			continue
+		}
+		witness_set.VkeyWitnesses = append(witness_set.VkeyWitnesses, VerificationKeyWitness.VerificationKeyWitness{
+			Vkey:      Key.VerificationKey{Payload: vkey},
+			Signature: signature,
+		})
+	}
+	return witness_set, nil
+}
+
+func hasWitness(witness_set TransactionWitnessSet.TransactionWitnessSet, vkey []byte) bool {
+	for _, existing := range witness_set.VkeyWitnesses {
+		if bytes.Equal(existing.Vkey.Payload, vkey) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+*
+
+	SignTx signs a transaction through the signer. On failure the
+	witness set is returned unchanged and the error is kept in
+	LastError.
+
+	Params:
+		tx (Transaction.Transaction): The transaction to be signed.
+
+	Returns:
+		TransactionWitnessSet.TransactionWitnessSet: The updated witness set.
+*/
+func (rw *RemoteWallet) SignTx(tx Transaction.Transaction) TransactionWitnessSet.TransactionWitnessSet {
+	witness_set, err := rw.RequestSignature(tx)
+	rw.LastError = err
+	return witness_set
+}
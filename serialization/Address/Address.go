@@ -127,7 +127,21 @@ func (addr *Address) MarshalCBOR() ([]byte, error) {
 func (addr *Address) UnmarshalCBOR(value []byte) error {
 	res := make([]byte, 0)
 	err := cbor.Unmarshal(value, &res)
+	if err != nil {
+		return err
+	}
+	if len(res) < serialization.VERIFICATION_KEY_HASH_SIZE+1 {
+		return errors.New("invalid address length")
+	}
 	header := res[0]
+	if (header&0xF0)>>4 != BYRON {
+		decoded, err := AddressFromBytes(res)
+		if err != nil {
+			return err
+		}
+		*addr = decoded
+		return nil
+	}
 	payload := res[1:]
 	addr.PaymentPart = payload[:serialization.VERIFICATION_KEY_HASH_SIZE]
 	addr.StakingPart = payload[serialization.VERIFICATION_KEY_HASH_SIZE:]
@@ -150,7 +164,7 @@ func (addr Address) Bytes() []byte {
 	var payment []byte
 	var staking []byte
 	payment = addr.PaymentPart
-	if len(addr.StakingPart) == 28 {
+	if len(addr.StakingPart) == 28 || addr.AddressType == BYRON || addr.AddressType == KEY_POINTER || addr.AddressType == SCRIPT_POINTER {
 		staking = addr.StakingPart
 	} else {
 		staking = make([]byte, 0)
@@ -222,30 +236,9 @@ func DecodeAddress(value string) (Address, error) {
 		return Address{}, err
 	}
 
-	decoded_value, _ := bech32.ConvertBits(data, 5, 8, false)
-
-	header := decoded_value[0]
-	payload := decoded_value[1:]
-	network := (header & 0x0F)
-	addr_type := (header & 0xF0) >> 4
-	if !(network == 0b0000 || network == 0b0001) {
-		return Address{}, errors.New("invalid network tag")
-	}
-	if addr_type == KEY_KEY {
-		return Address{payload[:serialization.VERIFICATION_KEY_HASH_SIZE], payload[serialization.VERIFICATION_KEY_HASH_SIZE:], network, addr_type, header, ComputeHrp(addr_type, network)}, nil
-	} else if addr_type == SCRIPT_KEY {
-		return Address{payload[:serialization.VERIFICATION_KEY_HASH_SIZE], payload[serialization.VERIFICATION_KEY_HASH_SIZE:], network, addr_type, header, ComputeHrp(addr_type, network)}, nil
-	} else if addr_type == KEY_SCRIPT {
-		return Address{payload[:serialization.VERIFICATION_KEY_HASH_SIZE], payload[serialization.VERIFICATION_KEY_HASH_SIZE:], network, addr_type, header, ComputeHrp(addr_type, network)}, nil
-	} else if addr_type == KEY_NONE {
-		return Address{payload[:serialization.VERIFICATION_KEY_HASH_SIZE], make([]byte, 0), network, addr_type, header, ComputeHrp(addr_type, network)}, nil
-	} else if addr_type == SCRIPT_SCRIPT {
-		return Address{payload[:serialization.VERIFICATION_KEY_HASH_SIZE], payload[serialization.VERIFICATION_KEY_HASH_SIZE:], network, addr_type, header, ComputeHrp(addr_type, network)}, nil
-	} else if addr_type == SCRIPT_NONE {
-		return Address{payload[:serialization.VERIFICATION_KEY_HASH_SIZE], make([]byte, 0), network, addr_type, header, ComputeHrp(addr_type, network)}, nil
-	} else if addr_type == NONE_KEY {
-		return Address{make([]byte, 0), payload[:serialization.VERIFICATION_KEY_HASH_SIZE], network, addr_type, header, ComputeHrp(addr_type, network)}, nil
-	} else {
-		return Address{make([]byte, 0), payload[:serialization.VERIFICATION_KEY_HASH_SIZE], network, addr_type, header, ComputeHrp(addr_type, network)}, nil
+	decoded_value, err := bech32.ConvertBits(data, 5, 8, false)
+	if err != nil {
+		return Address{}, err
 	}
+	return AddressFromBytes(decoded_value)
 }
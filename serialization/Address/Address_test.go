@@ -26,8 +26,6 @@ func TestDecodeAddress(t *testing.T) {
 	  stake_vk1px4j0r2fk7ux5p23shz8f3y5y2qam7s954rgf3lg5merqcj6aetsft99wu
 	  script1cda3khwqv60360rp5m7akt50m6ttapacs8rqhn5w342z7r35m37
 	  (2498243, 27, 3)
-
-	  There is no pointer implementation in the decoding function therefore there are no test cases for it
 	  **/
 	cases := map[string]testDecodeCase{
 		"Valid KEY_KEY mainnet address": {
@@ -254,6 +252,62 @@ func TestDecodeAddress(t *testing.T) {
 				IsError: false,
 			},
 		},
+		"Valid KEY_POINTER mainnet Address": {
+			input: "addr1gx2fxv2umyhttkxyxp8x0dlpdt3k6cwng5pxj3jhsydzer5pnz75xxcrzqf96k",
+			expected: expectedResult{
+				Result: Address.Address{
+					PaymentPart: []byte{148, 147, 49, 92, 217, 46, 181, 216, 196, 48, 78, 103, 183, 225, 106, 227, 109, 97, 211, 69, 2, 105, 70, 87, 129, 26, 44, 142},
+					StakingPart: []byte{0x81, 0x98, 0xbd, 0x43, 0x1b, 0x03},
+					Network:     Address.MAINNET,
+					AddressType: Address.KEY_POINTER,
+					HeaderByte:  0b01000001,
+					Hrp:         "addr",
+				},
+				IsError: false,
+			},
+		},
+		"Valid SCRIPT_POINTER mainnet Address": {
+			input: "addr128phkx6acpnf78fuvxn0mkew3l0fd058hzquvz7w36x4gtupnz75xxcrtw79hu",
+			expected: expectedResult{
+				Result: Address.Address{
+					PaymentPart: []byte{195, 123, 27, 93, 192, 102, 159, 29, 60, 97, 166, 253, 219, 46, 143, 222, 150, 190, 135, 184, 129, 198, 11, 206, 142, 141, 84, 47},
+					StakingPart: []byte{0x81, 0x98, 0xbd, 0x43, 0x1b, 0x03},
+					Network:     Address.MAINNET,
+					AddressType: Address.SCRIPT_POINTER,
+					HeaderByte:  0b01010001,
+					Hrp:         "addr",
+				},
+				IsError: false,
+			},
+		},
+		"Valid KEY_POINTER testnet Address": {
+			input: "addr_test1gz2fxv2umyhttkxyxp8x0dlpdt3k6cwng5pxj3jhsydzer5pnz75xxcrdw5vky",
+			expected: expectedResult{
+				Result: Address.Address{
+					PaymentPart: []byte{148, 147, 49, 92, 217, 46, 181, 216, 196, 48, 78, 103, 183, 225, 106, 227, 109, 97, 211, 69, 2, 105, 70, 87, 129, 26, 44, 142},
+					StakingPart: []byte{0x81, 0x98, 0xbd, 0x43, 0x1b, 0x03},
+					Network:     Address.TESTNET,
+					AddressType: Address.KEY_POINTER,
+					HeaderByte:  0b01000000,
+					Hrp:         "addr_test",
+				},
+				IsError: false,
+			},
+		},
+		"Valid SCRIPT_POINTER testnet Address": {
+			input: "addr_test12rphkx6acpnf78fuvxn0mkew3l0fd058hzquvz7w36x4gtupnz75xxcryqrvmw",
+			expected: expectedResult{
+				Result: Address.Address{
+					PaymentPart: []byte{195, 123, 27, 93, 192, 102, 159, 29, 60, 97, 166, 253, 219, 46, 143, 222, 150, 190, 135, 184, 129, 198, 11, 206, 142, 141, 84, 47},
+					StakingPart: []byte{0x81, 0x98, 0xbd, 0x43, 0x1b, 0x03},
+					Network:     Address.TESTNET,
+					AddressType: Address.SCRIPT_POINTER,
+					HeaderByte:  0b01010000,
+					Hrp:         "addr_test",
+				},
+				IsError: false,
+			},
+		},
 
 		"Invalid bech32 address": {
 			input:    "TEST",
@@ -0,0 +1,375 @@
+package Address
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/Salvionied/apollo/constants"
+	"github.com/Salvionied/apollo/serialization"
+)
+
+const (
+	KEY_CREDENTIAL    = 0
+	SCRIPT_CREDENTIAL = 1
+)
+
+/*
+*
+
+	Credential is the hash of a verification key or of a
+	script, as found in the payment and staking parts of an address.
+	Kind uses the same codes as certificate credentials.
+*/
+type Credential struct {
+	Kind byte
+	Hash []byte
+}
+
+/*
+*
+
+	NewKeyCredential creates the credential of a verification key hash.
+
+	Params:
+		hash ([]byte): The verification key hash.
+
+	Returns:
+		Credential: The key credential.
+*/
+func NewKeyCredential(hash []byte) Credential {
+	return Credential{Kind: KEY_CREDENTIAL, Hash: hash}
+}
+
+/*
+*
+
+	NewScriptCredential creates the credential of a script hash.
+
+	Params:
+		hash ([]byte): The script hash.
+
+	Returns:
+		Credential: The script credential.
+*/
+func NewScriptCredential(hash []byte) Credential {
+	return Credential{Kind: SCRIPT_CREDENTIAL, Hash: hash}
+}
+
+/*
+*
+
+	IsScript reports whether the credential is a script hash.
+
+	Returns:
+		bool: true for a script credential, false for a key credential.
+*/
+func (c Credential) IsScript() bool {
+	return c.Kind == SCRIPT_CREDENTIAL
+}
+
+/*
+*
+
+	Equal reports whether two credentials have the same kind and hash.
+
+	Params:
+		other (Credential): The credential to compare with.
+
+	Returns:
+		bool: true if the credentials are equal.
+*/
+func (c Credential) Equal(other Credential) bool {
+	return c.Kind == other.Kind && bytes.Equal(c.Hash, other.Hash)
+}
+
+func (c Credential) validate() error {
+	if c.Kind != KEY_CREDENTIAL && c.Kind != SCRIPT_CREDENTIAL {
+		return fmt.Errorf("invalid credential kind %d", c.Kind)
+	}
+	if len(c.Hash) != serialization.VERIFICATION_KEY_HASH_SIZE {
+		return fmt.Errorf("invalid credential hash length %d", len(c.Hash))
+	}
+	return nil
+}
+
+func networkByte(network constants.Network) byte {
+	if network == constants.MAINNET {
+		return MAINNET
+	}
+	return TESTNET
+}
+
+func newAddress(addressType byte, payment []byte, staking []byte, network constants.Network) Address {
+	netByte := networkByte(network)
+	return Address{
+		PaymentPart: payment,
+		StakingPart: staking,
+		Network:     netByte,
+		AddressType: addressType,
+		HeaderByte:  addressType<<4 | netByte,
+		Hrp:         ComputeHrp(addressType, netByte),
+	}
+}
+
+/*
+*
+
+	NewBaseAddress creates an address with a payment and a stake credential.
+
+	Params:
+		payment (Credential): The payment credential.
+		stake (Credential): The stake credential.
+		network (constants.Network): The network of the address.
+
+	Returns:
+		Address: The base address.
+		error: An error if a credential is invalid.
+*/
+func NewBaseAddress(payment Credential, stake Credential, network constants.Network) (Address, error) {
+	if err := payment.validate(); err != nil {
+		return Address{}, fmt.Errorf("payment credential: %w", err)
+	}
+	if err := stake.validate(); err != nil {
+		return Address{}, fmt.Errorf("stake credential: %w", err)
+	}
+	addressType := payment.Kind | stake.Kind<<1
+	return newAddress(addressType, payment.Hash, stake.Hash, network), nil
+}
+
+/*
+*
+
+	NewPointerAddress creates an address whose stake credential is
+	referenced by the pointer to its registration certificate.
+
+	Params:
+		payment (Credential): The payment credential.
+		pointer (Pointer): The pointer to the stake registration.
+		network (constants.Network): The network of the address.
+
+	Returns:
+		Address: The pointer address.
+		error: An error if the payment credential is invalid.
+*/
+func NewPointerAddress(payment Credential, pointer Pointer, network constants.Network) (Address, error) {
+	if err := payment.validate(); err != nil {
+		return Address{}, fmt.Errorf("payment credential: %w", err)
+	}
+	return newAddress(KEY_POINTER|payment.Kind, payment.Hash, pointer.Bytes(), network), nil
+}
+
+/*
+*
+
+	NewEnterpriseAddress creates an address without stake credential.
+
+	Params:
+		payment (Credential): The payment credential.
+		network (constants.Network): The network of the address.
+
+	Returns:
+		Address: The enterprise address.
+		error: An error if the payment credential is invalid.
+*/
+func NewEnterpriseAddress(payment Credential, network constants.Network) (Address, error) {
+	if err := payment.validate(); err != nil {
+		return Address{}, fmt.Errorf("payment credential: %w", err)
+	}
+	return newAddress(KEY_NONE|payment.Kind, payment.Hash, make([]byte, 0), network), nil
+}
+
+/*
+*
+
+	NewRewardAddress creates the stake (reward) address of a stake credential.
+
+	Params:
+		stake (Credential): The stake credential.
+		network (constants.Network): The network of the address.
+
+	Returns:
+		Address: The reward address.
+		error: An error if the stake credential is invalid.
+*/
+func NewRewardAddress(stake Credential, network constants.Network) (Address, error) {
+	if err := stake.validate(); err != nil {
+		return Address{}, fmt.Errorf("stake credential: %w", err)
+	}
+	return newAddress(NONE_KEY|stake.Kind, make([]byte, 0), stake.Hash, network), nil
+}
+
+/*
+*
+
+	IsRewardAddress reports whether the address is a stake (reward) address.
+
+	Returns:
+		bool: true for NONE_KEY and NONE_SCRIPT addresses.
+*/
+func (addr Address) IsRewardAddress() bool {
+	return addr.AddressType == NONE_KEY || addr.AddressType == NONE_SCRIPT
+}
+
+/*
+*
+
+	PaymentCredential returns the payment credential of a Shelley address.
+
+	Returns:
+		Credential: The payment credential.
+		error: An error for reward and Byron addresses.
+*/
+func (addr Address) PaymentCredential() (Credential, error) {
+	if addr.AddressType >= BYRON {
+		return Credential{}, errors.New("address has no payment credential")
+	}
+	return Credential{Kind: addr.AddressType & 1, Hash: addr.PaymentPart}, nil
+}
+
+/*
+*
+
+	StakeCredential returns the stake credential of a base or reward address.
+
+	Returns:
+		Credential: The stake credential.
+		error: An error if the address does not embed a stake credential.
+*/
+func (addr Address) StakeCredential() (Credential, error) {
+	switch addr.AddressType {
+	case KEY_KEY, SCRIPT_KEY:
+		return NewKeyCredential(addr.StakingPart), nil
+	case KEY_SCRIPT, SCRIPT_SCRIPT:
+		return NewScriptCredential(addr.StakingPart), nil
+	case NONE_KEY:
+		return NewKeyCredential(addr.StakingPart), nil
+	case NONE_SCRIPT:
+		return NewScriptCredential(addr.StakingPart), nil
+	}
+	return Credential{}, errors.New("address has no stake credential")
+}
+
+/*
+*
+
+	Pointer returns the stake pointer of a pointer address.
+
+	Returns:
+		Pointer: The pointer to the stake registration.
+		error: An error if the address is not a pointer address.
+*/
+func (addr Address) Pointer() (Pointer, error) {
+	if addr.AddressType != KEY_POINTER && addr.AddressType != SCRIPT_POINTER {
+		return Pointer{}, errors.New("not a pointer address")
+	}
+	return DecodePointer(addr.StakingPart)
+}
+
+/*
+*
+
+	StakeAddress returns the reward address of the stake credential
+	of the address, on the same network.
+
+	Returns:
+		Address: The reward address.
+		error: An error if the address does not embed a stake credential.
+*/
+func (addr Address) StakeAddress() (Address, error) {
+	stake, err := addr.StakeCredential()
+	if err != nil {
+		return Address{}, err
+	}
+	if err := stake.validate(); err != nil {
+		return Address{}, err
+	}
+	addressType := NONE_KEY | stake.Kind
+	return Address{
+		PaymentPart: make([]byte, 0),
+		StakingPart: stake.Hash,
+		Network:     addr.Network,
+		AddressType: addressType,
+		HeaderByte:  addressType<<4 | addr.Network,
+		Hrp:         ComputeHrp(addressType, addr.Network),
+	}, nil
+}
+
+/*
+*
+
+	RewardAccount returns the 29 bytes reward account of the
+	address, as used in withdrawals.
+
+	Returns:
+		[29]byte: The header and stake credential of the reward address.
+		error: An error if the address does not embed a stake credential.
+*/
+func (addr Address) RewardAccount() ([29]byte, error) {
+	var res [29]byte
+	reward, err := addr.StakeAddress()
+	if err != nil {
+		return res, err
+	}
+	copy(res[:], reward.Bytes())
+	return res, nil
+}
+
+/*
+*
+
+	AddressFromBytes decodes the binary representation of a Shelley address.
+
+	Params:
+		value ([]byte): The header byte followed by the address payload.
+
+	Returns:
+		Address: The decoded address.
+		error: An error if the header or the payload is invalid.
+*/
+func AddressFromBytes(value []byte) (Address, error) {
+	if len(value) == 0 {
+		return Address{}, errors.New("empty address")
+	}
+	header := value[0]
+	payload := value[1:]
+	network := header & 0x0F
+	addrType := (header & 0xF0) >> 4
+	if !(network == 0b0000 || network == 0b0001) {
+		return Address{}, errors.New("invalid network tag")
+	}
+	hashSize := serialization.VERIFICATION_KEY_HASH_SIZE
+	addr := Address{Network: network, AddressType: addrType, HeaderByte: header, Hrp: ComputeHrp(addrType, network)}
+	switch addrType {
+	case KEY_KEY, SCRIPT_KEY, KEY_SCRIPT, SCRIPT_SCRIPT:
+		if len(payload) != 2*hashSize {
+			return Address{}, errors.New("invalid address length")
+		}
+		addr.PaymentPart = payload[:hashSize]
+		addr.StakingPart = payload[hashSize:]
+	case KEY_POINTER, SCRIPT_POINTER:
+		if len(payload) <= hashSize {
+			return Address{}, errors.New("invalid address length")
+		}
+		if _, err := DecodePointer(payload[hashSize:]); err != nil {
+			return Address{}, err
+		}
+		addr.PaymentPart = payload[:hashSize]
+		addr.StakingPart = payload[hashSize:]
+	case KEY_NONE, SCRIPT_NONE:
+		if len(payload) != hashSize {
+			return Address{}, errors.New("invalid address length")
+		}
+		addr.PaymentPart = payload
+		addr.StakingPart = make([]byte, 0)
+	case NONE_KEY, NONE_SCRIPT:
+		if len(payload) != hashSize {
+			return Address{}, errors.New("invalid address length")
+		}
+		addr.PaymentPart = make([]byte, 0)
+		addr.StakingPart = payload
+	default:
+		return Address{}, fmt.Errorf("unsupported address type %d", addrType)
+	}
+	return addr, nil
+}
@@ -0,0 +1,223 @@
+package Address_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/Salvionied/apollo/constants"
+	"github.com/Salvionied/apollo/serialization/Address"
+	"github.com/Salvionied/cbor/v2"
+)
+
+// CIP-19 test vectors
+var (
+	paymentKeyHash, _ = hex.DecodeString("9493315cd92eb5d8c4304e67b7e16ae36d61d34502694657811a2c8e")
+	stakeKeyHash, _   = hex.DecodeString("337b62cfff6403a06a3acbc34f8c46003c69fe79a3628cefa9c47251")
+	scriptHash, _     = hex.DecodeString("c37b1b5dc0669f1d3c61a6fddb2e8fde96be87b881c60bce8e8d542f")
+	cip19Pointer      = Address.Pointer{Slot: 2498243, TxIndex: 27, CertIndex: 3}
+)
+
+type cip19Vector struct {
+	name    string
+	build   func(network constants.Network) (Address.Address, error)
+	mainnet string
+	testnet string
+}
+
+func cip19Vectors() []cip19Vector {
+	paymentKey := Address.NewKeyCredential(paymentKeyHash)
+	stakeKey := Address.NewKeyCredential(stakeKeyHash)
+	script := Address.NewScriptCredential(scriptHash)
+	return []cip19Vector{
+		{
+			name: "type-0",
+			build: func(n constants.Network) (Address.Address, error) {
+				return Address.NewBaseAddress(paymentKey, stakeKey, n)
+			},
+			mainnet: "addr1qx2fxv2umyhttkxyxp8x0dlpdt3k6cwng5pxj3jhsydzer3n0d3vllmyqwsx5wktcd8cc3sq835lu7drv2xwl2wywfgse35a3x",
+			testnet: "addr_test1qz2fxv2umyhttkxyxp8x0dlpdt3k6cwng5pxj3jhsydzer3n0d3vllmyqwsx5wktcd8cc3sq835lu7drv2xwl2wywfgs68faae",
+		},
+		{
+			name: "type-1",
+			build: func(n constants.Network) (Address.Address, error) {
+				return Address.NewBaseAddress(script, stakeKey, n)
+			},
+			mainnet: "addr1z8phkx6acpnf78fuvxn0mkew3l0fd058hzquvz7w36x4gten0d3vllmyqwsx5wktcd8cc3sq835lu7drv2xwl2wywfgs9yc0hh",
+			testnet: "addr_test1zrphkx6acpnf78fuvxn0mkew3l0fd058hzquvz7w36x4gten0d3vllmyqwsx5wktcd8cc3sq835lu7drv2xwl2wywfgsxj90mg",
+		},
+		{
+			name: "type-2",
+			build: func(n constants.Network) (Address.Address, error) {
+				return Address.NewBaseAddress(paymentKey, script, n)
+			},
+			mainnet: "addr1yx2fxv2umyhttkxyxp8x0dlpdt3k6cwng5pxj3jhsydzerkr0vd4msrxnuwnccdxlhdjar77j6lg0wypcc9uar5d2shs2z78ve",
+			testnet: "addr_test1yz2fxv2umyhttkxyxp8x0dlpdt3k6cwng5pxj3jhsydzerkr0vd4msrxnuwnccdxlhdjar77j6lg0wypcc9uar5d2shsf5r8qx",
+		},
+		{
+			name: "type-3",
+			build: func(n constants.Network) (Address.Address, error) {
+				return Address.NewBaseAddress(script, script, n)
+			},
+			mainnet: "addr1x8phkx6acpnf78fuvxn0mkew3l0fd058hzquvz7w36x4gt7r0vd4msrxnuwnccdxlhdjar77j6lg0wypcc9uar5d2shskhj42g",
+			testnet: "addr_test1xrphkx6acpnf78fuvxn0mkew3l0fd058hzquvz7w36x4gt7r0vd4msrxnuwnccdxlhdjar77j6lg0wypcc9uar5d2shs4p04xh",
+		},
+		{
+			name: "type-4",
+			build: func(n constants.Network) (Address.Address, error) {
+				return Address.NewPointerAddress(paymentKey, cip19Pointer, n)
+			},
+			mainnet: "addr1gx2fxv2umyhttkxyxp8x0dlpdt3k6cwng5pxj3jhsydzer5pnz75xxcrzqf96k",
+			testnet: "addr_test1gz2fxv2umyhttkxyxp8x0dlpdt3k6cwng5pxj3jhsydzer5pnz75xxcrdw5vky",
+		},
+		{
+			name: "type-5",
+			build: func(n constants.Network) (Address.Address, error) {
+				return Address.NewPointerAddress(script, cip19Pointer, n)
+			},
+			mainnet: "addr128phkx6acpnf78fuvxn0mkew3l0fd058hzquvz7w36x4gtupnz75xxcrtw79hu",
+			testnet: "addr_test12rphkx6acpnf78fuvxn0mkew3l0fd058hzquvz7w36x4gtupnz75xxcryqrvmw",
+		},
+		{
+			name: "type-6",
+			build: func(n constants.Network) (Address.Address, error) {
+				return Address.NewEnterpriseAddress(paymentKey, n)
+			},
+			mainnet: "addr1vx2fxv2umyhttkxyxp8x0dlpdt3k6cwng5pxj3jhsydzers66hrl8",
+			testnet: "addr_test1vz2fxv2umyhttkxyxp8x0dlpdt3k6cwng5pxj3jhsydzerspjrlsz",
+		},
+		{
+			name: "type-7",
+			build: func(n constants.Network) (Address.Address, error) {
+				return Address.NewEnterpriseAddress(script, n)
+			},
+			mainnet: "addr1w8phkx6acpnf78fuvxn0mkew3l0fd058hzquvz7w36x4gtcyjy7wx",
+			testnet: "addr_test1wrphkx6acpnf78fuvxn0mkew3l0fd058hzquvz7w36x4gtcl6szpr",
+		},
+		{
+			name: "type-14",
+			build: func(n constants.Network) (Address.Address, error) {
+				return Address.NewRewardAddress(stakeKey, n)
+			},
+			mainnet: "stake1uyehkck0lajq8gr28t9uxnuvgcqrc6070x3k9r8048z8y5gh6ffgw",
+			testnet: "stake_test1uqehkck0lajq8gr28t9uxnuvgcqrc6070x3k9r8048z8y5gssrtvn",
+		},
+		{
+			name: "type-15",
+			build: func(n constants.Network) (Address.Address, error) {
+				return Address.NewRewardAddress(script, n)
+			},
+			mainnet: "stake178phkx6acpnf78fuvxn0mkew3l0fd058hzquvz7w36x4gtcccycj5",
+			testnet: "stake_test17rphkx6acpnf78fuvxn0mkew3l0fd058hzquvz7w36x4gtcljw6kf",
+		},
+	}
+}
+
+func TestCip19Constructors(t *testing.T) {
+	for _, vector := range cip19Vectors() {
+		for network, expected := range map[constants.Network]string{
+			constants.MAINNET: vector.mainnet,
+			constants.PREPROD: vector.testnet,
+		} {
+			addr, err := vector.build(network)
+			if err != nil {
+				t.Fatalf("%s: %v", vector.name, err)
+			}
+			if addr.String() != expected {
+				t.Errorf("%s: expected %s, got %s", vector.name, expected, addr.String())
+			}
+			decoded, err := Address.DecodeAddress(expected)
+			if err != nil {
+				t.Fatalf("%s: %v", vector.name, err)
+			}
+			if !decoded.Equal(&addr) || decoded.Debug() != addr.Debug() {
+				t.Errorf("%s: decoded %s, built %s", vector.name, decoded.Debug(), addr.Debug())
+			}
+
+			encoded, err := cbor.Marshal(&addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var unmarshaled Address.Address
+			if err := cbor.Unmarshal(encoded, &unmarshaled); err != nil {
+				t.Fatalf("%s: %v", vector.name, err)
+			}
+			if unmarshaled.Debug() != addr.Debug() {
+				t.Errorf("%s: unmarshaled %s, built %s", vector.name, unmarshaled.Debug(), addr.Debug())
+			}
+		}
+	}
+}
+
+func TestCredentials(t *testing.T) {
+	base, _ := Address.DecodeAddress("addr1yx2fxv2umyhttkxyxp8x0dlpdt3k6cwng5pxj3jhsydzerkr0vd4msrxnuwnccdxlhdjar77j6lg0wypcc9uar5d2shs2z78ve")
+	payment, err := base.PaymentCredential()
+	if err != nil || payment.IsScript() || hex.EncodeToString(payment.Hash) != hex.EncodeToString(paymentKeyHash) {
+		t.Errorf("unexpected payment credential %v %v", payment, err)
+	}
+	stake, err := base.StakeCredential()
+	if err != nil || !stake.Equal(Address.NewScriptCredential(scriptHash)) {
+		t.Errorf("unexpected stake credential %v %v", stake, err)
+	}
+	stakeAddress, err := base.StakeAddress()
+	if err != nil || stakeAddress.String() != "stake178phkx6acpnf78fuvxn0mkew3l0fd058hzquvz7w36x4gtcccycj5" {
+		t.Errorf("unexpected stake address %s %v", stakeAddress.String(), err)
+	}
+	if !stakeAddress.IsRewardAddress() || base.IsRewardAddress() {
+		t.Error("wrong reward address detection")
+	}
+	account, err := base.RewardAccount()
+	if err != nil || account[0] != 0xf1 || hex.EncodeToString(account[1:]) != hex.EncodeToString(scriptHash) {
+		t.Errorf("unexpected reward account %x %v", account, err)
+	}
+
+	testnet, _ := Address.DecodeAddress("addr_test1qz2fxv2umyhttkxyxp8x0dlpdt3k6cwng5pxj3jhsydzer3n0d3vllmyqwsx5wktcd8cc3sq835lu7drv2xwl2wywfgs68faae")
+	stakeAddress, err = testnet.StakeAddress()
+	if err != nil || stakeAddress.String() != "stake_test1uqehkck0lajq8gr28t9uxnuvgcqrc6070x3k9r8048z8y5gssrtvn" {
+		t.Errorf("unexpected stake address %s %v", stakeAddress.String(), err)
+	}
+
+	enterprise, _ := Address.DecodeAddress("addr1w8phkx6acpnf78fuvxn0mkew3l0fd058hzquvz7w36x4gtcyjy7wx")
+	if _, err := enterprise.StakeAddress(); err == nil {
+		t.Error("enterprise addresses have no stake address")
+	}
+	pointer, _ := Address.DecodeAddress("addr1gx2fxv2umyhttkxyxp8x0dlpdt3k6cwng5pxj3jhsydzer5pnz75xxcrzqf96k")
+	if _, err := pointer.StakeAddress(); err == nil {
+		t.Error("pointer addresses have no stake address")
+	}
+	p, err := pointer.Pointer()
+	if err != nil || p != cip19Pointer {
+		t.Errorf("unexpected pointer %v %v", p, err)
+	}
+
+	if _, err := Address.NewBaseAddress(Address.NewKeyCredential([]byte{1, 2, 3}), Address.NewKeyCredential(stakeKeyHash), constants.MAINNET); err == nil {
+		t.Error("expected an error for a short credential")
+	}
+}
+
+func TestPointerEncoding(t *testing.T) {
+	pointers := []Address.Pointer{
+		{},
+		{Slot: 127, TxIndex: 128, CertIndex: 16384},
+		cip19Pointer,
+		{Slot: ^uint64(0), TxIndex: 1, CertIndex: 0},
+	}
+	for _, pointer := range pointers {
+		decoded, err := Address.DecodePointer(pointer.Bytes())
+		if err != nil || decoded != pointer {
+			t.Errorf("expected %v, got %v %v", pointer, decoded, err)
+		}
+	}
+	if hex.EncodeToString(cip19Pointer.Bytes()) != "8198bd431b03" {
+		t.Errorf("unexpected encoding %x", cip19Pointer.Bytes())
+	}
+	for _, invalid := range []string{"", "8198bd43", "8198bd431b0300", "ffffffffffffffffffff7f0000"} {
+		value, _ := hex.DecodeString(invalid)
+		if _, err := Address.DecodePointer(value); err == nil {
+			t.Errorf("expected an error decoding %s", invalid)
+		}
+	}
+	truncated, _ := hex.DecodeString("41" + hex.EncodeToString(paymentKeyHash) + "8198bd43")
+	if _, err := Address.AddressFromBytes(truncated); err == nil {
+		t.Error("expected an error for a truncated pointer address")
+	}
+}
@@ -0,0 +1,87 @@
+package Address
+
+import (
+	"errors"
+)
+
+/*
+*
+
+	Pointer locates the stake registration certificate of a
+	pointer address by slot, transaction index and certificate index.
+*/
+type Pointer struct {
+	Slot      uint64
+	TxIndex   uint64
+	CertIndex uint64
+}
+
+/*
+*
+
+	Bytes encodes the pointer as three variable length naturals,
+	as in CIP-19.
+
+	Returns:
+		[]byte: The encoded pointer.
+*/
+func (p Pointer) Bytes() []byte {
+	res := encodeNat(p.Slot)
+	res = append(res, encodeNat(p.TxIndex)...)
+	return append(res, encodeNat(p.CertIndex)...)
+}
+
+/*
+*
+
+	DecodePointer decodes the pointer part of a pointer address.
+
+	Params:
+		value ([]byte): The encoded pointer.
+
+	Returns:
+		Pointer: The decoded pointer.
+		error: An error if the value is not exactly three naturals.
+*/
+func DecodePointer(value []byte) (Pointer, error) {
+	slot, rest, err := decodeNat(value)
+	if err != nil {
+		return Pointer{}, err
+	}
+	txIndex, rest, err := decodeNat(rest)
+	if err != nil {
+		return Pointer{}, err
+	}
+	certIndex, rest, err := decodeNat(rest)
+	if err != nil {
+		return Pointer{}, err
+	}
+	if len(rest) != 0 {
+		return Pointer{}, errors.New("trailing bytes after pointer")
+	}
+	return Pointer{Slot: slot, TxIndex: txIndex, CertIndex: certIndex}, nil
+}
+
+// encodeNat writes n in big endian groups of 7 bits, the high bit
+// of every byte but the last one being set.
+func encodeNat(n uint64) []byte {
+	res := []byte{byte(n & 0x7f)}
+	for n >>= 7; n > 0; n >>= 7 {
+		res = append([]byte{byte(n&0x7f) | 0x80}, res...)
+	}
+	return res
+}
+
+func decodeNat(value []byte) (uint64, []byte, error) {
+	var n uint64
+	for i, b := range value {
+		if n > (^uint64(0))>>7 {
+			return 0, nil, errors.New("pointer value overflows")
+		}
+		n = n<<7 | uint64(b&0x7f)
+		if b&0x80 == 0 {
+			return n, value[i+1:], nil
+		}
+	}
+	return 0, nil, errors.New("truncated pointer")
+}
@@ -0,0 +1,525 @@
+package Block
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Salvionied/apollo/serialization"
+	"github.com/Salvionied/apollo/serialization/Era"
+	"github.com/Salvionied/apollo/serialization/Metadata"
+	"github.com/Salvionied/apollo/serialization/Transaction"
+	"github.com/Salvionied/apollo/serialization/TransactionBody"
+	"github.com/Salvionied/apollo/serialization/TransactionWitnessSet"
+	"github.com/Salvionied/cbor/v2"
+)
+
+type VrfCert struct {
+	_      struct{} `cbor:",toarray"`
+	Output []byte
+	Proof  []byte
+}
+
+type OperationalCert struct {
+	_              struct{} `cbor:",toarray"`
+	HotVkey        []byte
+	SequenceNumber uint64
+	KesPeriod      uint64
+	Sigma          []byte
+}
+
+type ProtocolVersion struct {
+	_     struct{} `cbor:",toarray"`
+	Major uint64
+	Minor uint64
+}
+
+/*
+*
+
+	HeaderBody holds the fields of a block header from Shelley
+	to Conway. Blocks up to Alonzo (TPraos) carry both NonceVrf and
+	LeaderVrf, blocks from Babbage onwards (Praos) carry VrfResult.
+*/
+type HeaderBody struct {
+	BlockNumber     uint64
+	Slot            uint64
+	PrevHash        []byte
+	IssuerVkey      []byte
+	VrfVkey         []byte
+	NonceVrf        *VrfCert
+	LeaderVrf       *VrfCert
+	VrfResult       *VrfCert
+	BlockBodySize   uint64
+	BlockBodyHash   []byte
+	OperationalCert OperationalCert
+	ProtocolVersion ProtocolVersion
+}
+
+type tpraosHeaderBody struct {
+	_              struct{} `cbor:",toarray"`
+	BlockNumber    uint64
+	Slot           uint64
+	PrevHash       []byte
+	IssuerVkey     []byte
+	VrfVkey        []byte
+	NonceVrf       VrfCert
+	LeaderVrf      VrfCert
+	BlockBodySize  uint64
+	BlockBodyHash  []byte
+	HotVkey        []byte
+	SequenceNumber uint64
+	KesPeriod      uint64
+	Sigma          []byte
+	ProtocolMajor  uint64
+	ProtocolMinor  uint64
+}
+
+type praosHeaderBody struct {
+	_               struct{} `cbor:",toarray"`
+	BlockNumber     uint64
+	Slot            uint64
+	PrevHash        []byte
+	IssuerVkey      []byte
+	VrfVkey         []byte
+	VrfResult       VrfCert
+	BlockBodySize   uint64
+	BlockBodyHash   []byte
+	OperationalCert OperationalCert
+	ProtocolVersion ProtocolVersion
+}
+
+/*
+*
+
+	UnmarshalCBOR decodes a TPraos (15 fields) or
+	Praos (10 fields) header body.
+
+	Params:
+		value ([]byte): The CBOR-encoded header body.
+
+	Returns:
+		error: An error if the decoding fails.
+*/
+func (hb *HeaderBody) UnmarshalCBOR(value []byte) error {
+	var items []cbor.RawMessage
+	err := cbor.Unmarshal(value, &items)
+	if err != nil {
+		return err
+	}
+	switch len(items) {
+	case 15:
+		tpraos := tpraosHeaderBody{}
+		err = cbor.Unmarshal(value, &tpraos)
+		if err != nil {
+			return err
+		}
+		*hb = HeaderBody{
+			BlockNumber:   tpraos.BlockNumber,
+			Slot:          tpraos.Slot,
+			PrevHash:      tpraos.PrevHash,
+			IssuerVkey:    tpraos.IssuerVkey,
+			VrfVkey:       tpraos.VrfVkey,
+			NonceVrf:      &tpraos.NonceVrf,
+			LeaderVrf:     &tpraos.LeaderVrf,
+			BlockBodySize: tpraos.BlockBodySize,
+			BlockBodyHash: tpraos.BlockBodyHash,
+			OperationalCert: OperationalCert{
+				HotVkey:        tpraos.HotVkey,
+				SequenceNumber: tpraos.SequenceNumber,
+				KesPeriod:      tpraos.KesPeriod,
+				Sigma:          tpraos.Sigma,
+			},
+			ProtocolVersion: ProtocolVersion{
+				Major: tpraos.ProtocolMajor,
+				Minor: tpraos.ProtocolMinor,
+			},
+		}
+	case 10:
+		praos := praosHeaderBody{}
+		err = cbor.Unmarshal(value, &praos)
+		if err != nil {
+			return err
+		}
+		*hb = HeaderBody{
+			BlockNumber:     praos.BlockNumber,
+			Slot:            praos.Slot,
+			PrevHash:        praos.PrevHash,
+			IssuerVkey:      praos.IssuerVkey,
+			VrfVkey:         praos.VrfVkey,
+			VrfResult:       &praos.VrfResult,
+			BlockBodySize:   praos.BlockBodySize,
+			BlockBodyHash:   praos.BlockBodyHash,
+			OperationalCert: praos.OperationalCert,
+			ProtocolVersion: praos.ProtocolVersion,
+		}
+	default:
+		return fmt.Errorf("invalid header body, expected 10 or 15 elements got %d", len(items))
+	}
+	return nil
+}
+
+/*
+*
+
+	MarshalCBOR encodes the header body in the Praos layout when
+	VrfResult is set and in the TPraos layout otherwise.
+
+	Returns:
+		[]byte: The CBOR-encoded header body.
+		error: An error if the encoding fails.
+*/
+func (hb *HeaderBody) MarshalCBOR() ([]byte, error) {
+	if hb.VrfResult != nil {
+		return cbor.Marshal(praosHeaderBody{
+			BlockNumber:     hb.BlockNumber,
+			Slot:            hb.Slot,
+			PrevHash:        hb.PrevHash,
+			IssuerVkey:      hb.IssuerVkey,
+			VrfVkey:         hb.VrfVkey,
+			VrfResult:       *hb.VrfResult,
+			BlockBodySize:   hb.BlockBodySize,
+			BlockBodyHash:   hb.BlockBodyHash,
+			OperationalCert: hb.OperationalCert,
+			ProtocolVersion: hb.ProtocolVersion,
+		})
+	}
+	if hb.NonceVrf == nil || hb.LeaderVrf == nil {
+		return nil, errors.New("header body has no vrf certificates")
+	}
+	return cbor.Marshal(tpraosHeaderBody{
+		BlockNumber:    hb.BlockNumber,
+		Slot:           hb.Slot,
+		PrevHash:       hb.PrevHash,
+		IssuerVkey:     hb.IssuerVkey,
+		VrfVkey:        hb.VrfVkey,
+		NonceVrf:       *hb.NonceVrf,
+		LeaderVrf:      *hb.LeaderVrf,
+		BlockBodySize:  hb.BlockBodySize,
+		BlockBodyHash:  hb.BlockBodyHash,
+		HotVkey:        hb.OperationalCert.HotVkey,
+		SequenceNumber: hb.OperationalCert.SequenceNumber,
+		KesPeriod:      hb.OperationalCert.KesPeriod,
+		Sigma:          hb.OperationalCert.Sigma,
+		ProtocolMajor:  hb.ProtocolVersion.Major,
+		ProtocolMinor:  hb.ProtocolVersion.Minor,
+	})
+}
+
+type Header struct {
+	Body      HeaderBody
+	Signature []byte
+	original  *serialization.OriginalCbor
+}
+
+type cborHeader struct {
+	_         struct{} `cbor:",toarray"`
+	Body      *HeaderBody
+	Signature []byte
+}
+
+/*
+*
+
+	UnmarshalCBOR decodes a block header and keeps its
+	original bytes, which the block hash is computed on.
+
+	Params:
+		value ([]byte): The CBOR-encoded header.
+
+	Returns:
+		error: An error if the decoding fails.
+*/
+func (h *Header) UnmarshalCBOR(value []byte) error {
+	var raw struct {
+		_         struct{} `cbor:",toarray"`
+		Body      cbor.RawMessage
+		Signature []byte
+	}
+	err := cbor.Unmarshal(value, &raw)
+	if err != nil {
+		return err
+	}
+	decoded := Header{Signature: raw.Signature}
+	err = cbor.Unmarshal(raw.Body, &decoded.Body)
+	if err != nil {
+		return err
+	}
+	*h = decoded
+	encoded, err := h.encode()
+	if err == nil {
+		h.original = serialization.NewOriginalCbor(value, encoded)
+	}
+	return nil
+}
+
+/*
+*
+
+	MarshalCBOR encodes the block header. A decoded header
+	that was not modified is encoded with its original bytes.
+
+	Returns:
+		[]byte: The CBOR-encoded header.
+		error: An error if the encoding fails.
+*/
+func (h *Header) MarshalCBOR() ([]byte, error) {
+	encoded, err := h.encode()
+	if err != nil {
+		return nil, err
+	}
+	return h.original.Resolve(encoded), nil
+}
+
+func (h *Header) encode() ([]byte, error) {
+	return cbor.Marshal(cborHeader{Body: &h.Body, Signature: h.Signature})
+}
+
+/*
+*
+
+	Hash computes the block hash, the blake2b-256 hash
+	of the encoded header.
+
+	Returns:
+		[]byte: The block hash.
+		error: An error if the hashing fails.
+*/
+func (h *Header) Hash() ([]byte, error) {
+	encoded, err := h.MarshalCBOR()
+	if err != nil {
+		return nil, err
+	}
+	return serialization.Blake2bHash(encoded)
+}
+
+/*
+*
+
+	Block is a Shelley to Conway block. Transactions are kept in
+	the ledger layout (bodies, witness sets and auxiliary data in
+	separate collections), use Transactions to assemble them.
+*/
+type Block struct {
+	Era                    Era.Era
+	Header                 Header
+	TransactionBodies      []TransactionBody.TransactionBody
+	TransactionWitnessSets []TransactionWitnessSet.TransactionWitnessSet
+	AuxiliaryData          map[uint64]*Metadata.AuxiliaryData
+	InvalidTransactions    []uint64
+}
+
+type shelleyBlock struct {
+	_                      struct{} `cbor:",toarray"`
+	Header                 *Header
+	TransactionBodies      *[]TransactionBody.TransactionBody
+	TransactionWitnessSets *[]TransactionWitnessSet.TransactionWitnessSet
+	AuxiliaryData          *map[uint64]*Metadata.AuxiliaryData
+}
+
+type alonzoBlock struct {
+	_                      struct{} `cbor:",toarray"`
+	Header                 *Header
+	TransactionBodies      *[]TransactionBody.TransactionBody
+	TransactionWitnessSets *[]TransactionWitnessSet.TransactionWitnessSet
+	AuxiliaryData          *map[uint64]*Metadata.AuxiliaryData
+	InvalidTransactions    *[]uint64
+}
+
+/*
+*
+
+	DecodeBlock decodes a block wrapped with its hard fork
+	combinator era index ([era, block]), as served by a node.
+	The block, or the whole envelope, can also be wrapped in
+	a tag 24 byte string.
+
+	Params:
+		data ([]byte): The CBOR-encoded wrapped block.
+
+	Returns:
+		*Block: The decoded block.
+		error: An error if the era is not supported or the decoding fails.
+*/
+func DecodeBlock(data []byte) (*Block, error) {
+	data, err := unwrapCborInCbor(data)
+	if err != nil {
+		return nil, err
+	}
+	var wrapped struct {
+		_     struct{} `cbor:",toarray"`
+		Era   uint64
+		Block cbor.RawMessage
+	}
+	err = cbor.Unmarshal(data, &wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("invalid era wrapped block: %w", err)
+	}
+	era, err := Era.FromHardForkTag(wrapped.Era)
+	if err != nil {
+		return nil, err
+	}
+	blockBytes, err := unwrapCborInCbor(wrapped.Block)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeEraBlock(era, blockBytes)
+}
+
+/*
+*
+
+	DecodeEraBlock decodes an unwrapped block of the given era.
+
+	Params:
+		era (Era.Era): The era of the block.
+		data ([]byte): The CBOR-encoded block.
+
+	Returns:
+		*Block: The decoded block.
+		error: An error if the era is not supported or the decoding fails.
+*/
+func DecodeEraBlock(era Era.Era, data []byte) (*Block, error) {
+	if era < Era.SHELLEY || era > Era.CONWAY {
+		return nil, fmt.Errorf("unsupported block era %s", era)
+	}
+	block := Block{Era: era}
+	var err error
+	if era.HasPlutus() {
+		err = cbor.Unmarshal(data, &alonzoBlock{
+			Header:                 &block.Header,
+			TransactionBodies:      &block.TransactionBodies,
+			TransactionWitnessSets: &block.TransactionWitnessSets,
+			AuxiliaryData:          &block.AuxiliaryData,
+			InvalidTransactions:    &block.InvalidTransactions,
+		})
+	} else {
+		err = cbor.Unmarshal(data, &shelleyBlock{
+			Header:                 &block.Header,
+			TransactionBodies:      &block.TransactionBodies,
+			TransactionWitnessSets: &block.TransactionWitnessSets,
+			AuxiliaryData:          &block.AuxiliaryData,
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s block: %w", era, err)
+	}
+	if len(block.TransactionBodies) != len(block.TransactionWitnessSets) {
+		return nil, fmt.Errorf("invalid %s block: %d transaction bodies but %d witness sets", era, len(block.TransactionBodies), len(block.TransactionWitnessSets))
+	}
+	return &block, nil
+}
+
+/*
+*
+
+	MarshalCBOR encodes the block (without the era wrapper)
+	in the layout of its era.
+
+	Returns:
+		[]byte: The CBOR-encoded block.
+		error: An error if the encoding fails.
+*/
+func (b *Block) MarshalCBOR() ([]byte, error) {
+	auxiliaryData := b.AuxiliaryData
+	if auxiliaryData == nil {
+		auxiliaryData = map[uint64]*Metadata.AuxiliaryData{}
+	}
+	if b.Era.HasPlutus() {
+		invalid := b.InvalidTransactions
+		if invalid == nil {
+			invalid = []uint64{}
+		}
+		return cbor.Marshal(alonzoBlock{
+			Header:                 &b.Header,
+			TransactionBodies:      &b.TransactionBodies,
+			TransactionWitnessSets: &b.TransactionWitnessSets,
+			AuxiliaryData:          &auxiliaryData,
+			InvalidTransactions:    &invalid,
+		})
+	}
+	return cbor.Marshal(shelleyBlock{
+		Header:                 &b.Header,
+		TransactionBodies:      &b.TransactionBodies,
+		TransactionWitnessSets: &b.TransactionWitnessSets,
+		AuxiliaryData:          &auxiliaryData,
+	})
+}
+
+/*
+*
+
+	Hash returns the hash of the block header.
+
+	Returns:
+		[]byte: The block hash.
+		error: An error if the hashing fails.
+*/
+func (b *Block) Hash() ([]byte, error) {
+	return b.Header.Hash()
+}
+
+/*
+*
+
+	Transactions assembles the transactions of the block,
+	tagged with the block era. Transactions listed as invalid
+	(failed phase-2 validation) have Valid set to false.
+
+	Returns:
+		[]Transaction.Transaction: The transactions in block order.
+*/
+func (b *Block) Transactions() []Transaction.Transaction {
+	invalid := make(map[uint64]bool, len(b.InvalidTransactions))
+	for _, idx := range b.InvalidTransactions {
+		invalid[idx] = true
+	}
+	txs := make([]Transaction.Transaction, 0, len(b.TransactionBodies))
+	for idx, body := range b.TransactionBodies {
+		tx := Transaction.Transaction{
+			TransactionBody:       body,
+			TransactionWitnessSet: b.TransactionWitnessSets[idx],
+			Valid:                 !invalid[uint64(idx)],
+			AuxiliaryData:         b.AuxiliaryData[uint64(idx)],
+			Era:                   b.Era,
+		}
+		txs = append(txs, tx)
+	}
+	return txs
+}
+
+/*
+*
+
+	TransactionIds returns the ids of the transactions of the block.
+
+	Returns:
+		[]serialization.TransactionId: The transaction ids in block order.
+		error: An error if hashing any body fails.
+*/
+func (b *Block) TransactionIds() ([]serialization.TransactionId, error) {
+	ids := make([]serialization.TransactionId, 0, len(b.TransactionBodies))
+	for idx := range b.TransactionBodies {
+		id, err := b.TransactionBodies[idx].Id()
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func unwrapCborInCbor(data []byte) ([]byte, error) {
+	if len(data) == 0 || data[0]>>5 != 6 {
+		return data, nil
+	}
+	var tag cbor.Tag
+	err := cbor.Unmarshal(data, &tag)
+	if err != nil {
+		return nil, err
+	}
+	content, ok := tag.Content.([]byte)
+	if tag.Number != 24 || !ok {
+		return nil, fmt.Errorf("unexpected tag %d", tag.Number)
+	}
+	return content, nil
+}
@@ -0,0 +1,243 @@
+package Block_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Salvionied/apollo/serialization"
+	"github.com/Salvionied/apollo/serialization/Block"
+	"github.com/Salvionied/apollo/serialization/Certificate"
+	"github.com/Salvionied/apollo/serialization/Era"
+	"github.com/Salvionied/apollo/serialization/Redeemer"
+	"github.com/Salvionied/apollo/serialization/Transaction"
+	"github.com/Salvionied/cbor/v2"
+)
+
+// loadFixture reads a synthetic block, built following the CDDL of
+// its era to cover its fields, not taken from the chain.
+func loadFixture(t *testing.T, era string) []byte {
+	return readHex(t, "testdata/synthetic/"+era+".hex")
+}
+
+func readHex(t *testing.T, path string) []byte {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := hex.DecodeString(strings.TrimSpace(string(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+// blockParts returns the raw header and transaction bodies of a wrapped block.
+func blockParts(t *testing.T, data []byte) ([]byte, [][]byte) {
+	var wrapped []cbor.RawMessage
+	if err := cbor.Unmarshal(data, &wrapped); err != nil {
+		t.Fatal(err)
+	}
+	var block []cbor.RawMessage
+	if err := cbor.Unmarshal(wrapped[1], &block); err != nil {
+		t.Fatal(err)
+	}
+	var bodies []cbor.RawMessage
+	if err := cbor.Unmarshal(block[1], &bodies); err != nil {
+		t.Fatal(err)
+	}
+	res := make([][]byte, 0)
+	for _, body := range bodies {
+		res = append(res, body)
+	}
+	return block[0], res
+}
+
+func TestDecodeSyntheticBlocks(t *testing.T) {
+	testCases := []struct {
+		name        string
+		era         Era.Era
+		blockNumber uint64
+		txCount     int
+		invalid     []uint64
+		praos       bool
+	}{
+		{"shelley", Era.SHELLEY, 4490511, 2, nil, false},
+		{"allegra", Era.ALLEGRA, 5086523, 1, nil, false},
+		{"mary", Era.MARY, 5406746, 1, nil, false},
+		{"alonzo", Era.ALONZO, 6236060, 2, []uint64{1}, false},
+		{"babbage", Era.BABBAGE, 8000000, 2, []uint64{}, true},
+		{"conway", Era.CONWAY, 11000000, 2, []uint64{}, true},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			data := loadFixture(t, testCase.name)
+			block, err := Block.DecodeBlock(data)
+			if err != nil {
+				t.Fatal("Failed decoding block", err)
+			}
+			if block.Era != testCase.era {
+				t.Error("Invalid era", block.Era, "Expected", testCase.era)
+			}
+			if block.Header.Body.BlockNumber != testCase.blockNumber {
+				t.Error("Invalid block number", block.Header.Body.BlockNumber, "Expected", testCase.blockNumber)
+			}
+			if (block.Header.Body.VrfResult != nil) != testCase.praos {
+				t.Error("Invalid header layout")
+			}
+			if len(block.TransactionBodies) != testCase.txCount {
+				t.Error("Invalid transaction count", len(block.TransactionBodies), "Expected", testCase.txCount)
+			}
+			// the fixtures are synthetic: this only checks that the
+			// hashes are computed over the original bytes
+			rawHeader, rawBodies := blockParts(t, data)
+			expectedHash, _ := serialization.Blake2bHash(rawHeader)
+			hash, err := block.Hash()
+			if err != nil || !bytes.Equal(hash, expectedHash) {
+				t.Error("Invalid block hash", hex.EncodeToString(hash), "Expected", hex.EncodeToString(expectedHash))
+			}
+			ids, err := block.TransactionIds()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for idx, rawBody := range rawBodies {
+				expectedId, _ := serialization.Blake2bHash(rawBody)
+				if !bytes.Equal(ids[idx].Payload, expectedId) {
+					t.Error("Invalid transaction id", hex.EncodeToString(ids[idx].Payload), "Expected", hex.EncodeToString(expectedId))
+				}
+			}
+			txs := block.Transactions()
+			for idx, tx := range txs {
+				if tx.Era != testCase.era {
+					t.Error("Invalid transaction era", tx.Era)
+				}
+				expectedValid := true
+				for _, invalid := range testCase.invalid {
+					if invalid == uint64(idx) {
+						expectedValid = false
+					}
+				}
+				if tx.Valid != expectedValid {
+					t.Error("Invalid validity for transaction", idx)
+				}
+			}
+			var wrapped []cbor.RawMessage
+			_ = cbor.Unmarshal(data, &wrapped)
+			marshaled, err := cbor.Marshal(block)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(marshaled, wrapped[1]) {
+				t.Error("Invalid block reserialization", hex.EncodeToString(marshaled))
+			}
+		})
+	}
+}
+
+func TestDecodeBlockDetails(t *testing.T) {
+	shelley, err := Block.DecodeBlock(loadFixture(t, "shelley"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	byronOutput := shelley.TransactionBodies[0].Outputs[0]
+	if byronOutput.GetAddress().AddressType != 0b1000 || byronOutput.Lovelace() != 5000000 {
+		t.Error("Invalid byron output", byronOutput.GetAddress())
+	}
+	certs := *shelley.TransactionBodies[1].Certificates
+	if len(certs) != 2 || certs[0].Kind != Certificate.STAKE_REGISTRATION || certs[1].Kind != Certificate.STAKE_DELEGATION || len(certs[1].Fields) != 1 {
+		t.Error("Invalid certificates", certs)
+	}
+	if shelley.AuxiliaryData[1] == nil || shelley.AuxiliaryData[1].Hash() == nil {
+		t.Error("Missing auxiliary data")
+	}
+
+	mary, err := Block.DecodeBlock(loadFixture(t, "mary"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mary.TransactionBodies[0].Mint) != 1 || len(mary.TransactionWitnessSets[0].NativeScripts) != 1 {
+		t.Error("Invalid mary transaction")
+	}
+
+	alonzo, err := Block.DecodeBlock(loadFixture(t, "alonzo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	alonzoTxs := alonzo.Transactions()
+	if alonzoTxs[0].AuxiliaryData == nil || alonzoTxs[0].AuxiliaryData.Hash() == nil {
+		t.Error("Missing alonzo auxiliary data")
+	}
+	if len(alonzoTxs[0].TransactionWitnessSet.Redeemer) != 1 || len(alonzoTxs[0].TransactionWitnessSet.PlutusV1Script) != 1 {
+		t.Error("Invalid alonzo witness set")
+	}
+	if alonzoTxs[0].TransactionBody.Outputs[0].GetDatumHash() == nil {
+		t.Error("Missing datum hash")
+	}
+
+	babbage, err := Block.DecodeBlock(loadFixture(t, "babbage"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	babbageOutput := babbage.TransactionBodies[1].Outputs[0]
+	if babbageOutput.GetDatum() == nil || len(babbageOutput.GetScriptRef().Script.Script) != 60 {
+		t.Error("Invalid babbage output")
+	}
+	if babbage.TransactionBodies[1].CollateralReturn == nil || len(babbage.TransactionBodies[1].ReferenceInputs) != 1 {
+		t.Error("Invalid babbage body")
+	}
+
+	conway, err := Block.DecodeBlock(loadFixture(t, "conway"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conwayBody := conway.TransactionBodies[0]
+	if len(conwayBody.Inputs) != 2 || conwayBody.Donation != 1000000 || len(*conwayBody.Certificates) != 2 {
+		t.Error("Invalid conway body")
+	}
+	if (*conwayBody.Certificates)[0].Kind != Certificate.REG_CERT || (*conwayBody.Certificates)[1].Kind != Certificate.VOTE_DELEG_CERT {
+		t.Error("Invalid conway certificates")
+	}
+	witnessSet := conway.TransactionWitnessSets[1]
+	if len(witnessSet.Redeemer) != 1 || witnessSet.Redeemer[0].Tag != Redeemer.MINT || witnessSet.Redeemer[0].ExUnits.Mem != 2000 {
+		t.Error("Invalid conway redeemers", witnessSet.Redeemer)
+	}
+	if len(witnessSet.PlutusV3Script) != 1 || len(witnessSet.VkeyWitnesses) != 1 {
+		t.Error("Invalid conway witness set")
+	}
+}
+
+func TestTransactionEraInference(t *testing.T) {
+	for _, name := range []string{"shelley", "allegra", "mary", "alonzo", "babbage", "conway"} {
+		block, err := Block.DecodeBlock(loadFixture(t, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, tx := range block.Transactions() {
+			encoded, err := tx.Bytes()
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded := Transaction.Transaction{}
+			err = cbor.Unmarshal(encoded, &decoded)
+			if err != nil {
+				t.Fatal(name, err)
+			}
+			if decoded.Era > block.Era || decoded.Era.HasPlutus() != block.Era.HasPlutus() {
+				t.Error("Invalid inferred era", decoded.Era, "for", block.Era)
+			}
+			reencoded, _ := decoded.Bytes()
+			if !bytes.Equal(encoded, reencoded) {
+				t.Error("Invalid transaction reserialization", name)
+			}
+		}
+	}
+}
+
+func TestDecodeUnsupportedEra(t *testing.T) {
+	_, err := Block.DecodeBlock([]byte{0x82, 0x01, 0x80})
+	if err == nil {
+		t.Error("Expected error for byron block")
+	}
+}
@@ -1,18 +1,134 @@
 package Certificate
 
-import "github.com/Salvionied/apollo/serialization"
+import (
+	"errors"
+
+	"github.com/Salvionied/apollo/serialization"
+	"github.com/Salvionied/cbor/v2"
+)
+
+const (
+	STAKE_REGISTRATION = iota
+	STAKE_DEREGISTRATION
+	STAKE_DELEGATION
+	POOL_REGISTRATION
+	POOL_RETIREMENT
+	GENESIS_KEY_DELEGATION
+	MOVE_INSTANTANEOUS_REWARDS
+	REG_CERT
+	UNREG_CERT
+	VOTE_DELEG_CERT
+	STAKE_VOTE_DELEG_CERT
+	STAKE_REG_DELEG_CERT
+	VOTE_REG_DELEG_CERT
+	STAKE_VOTE_REG_DELEG_CERT
+	AUTH_COMMITTEE_HOT_CERT
+	RESIGN_COMMITTEE_COLD_CERT
+	REG_DREP_CERT
+	UNREG_DREP_CERT
+	UPDATE_DREP_CERT
+)
 
 type StakeCredential struct {
-	_          struct{} `cbor:"toarray"`
-	_CODE      int      `cbor:",omitempty"`
+	_          struct{} `cbor:",toarray"`
+	Code       int
 	Credential serialization.ConstrainedBytes
 }
 
-// TODO
+/*
+*
+
+	Certificate holds any certificate from Shelley to Conway.
+	Certificates whose first field is a credential expose it as
+	StakeCredential, every other field is kept as raw CBOR in Fields.
+*/
 type Certificate struct {
-	_               struct{} `cbor:"toarray"`
-	_CODE           int
+	Kind            int
 	StakeCredential *StakeCredential
+	Fields          []cbor.RawMessage
 }
 
 type Certificates []*Certificate
+
+/*
+*
+
+	HasCredential reports whether certificates of the given
+	kind start with a (stake, committee or drep) credential.
+
+	Params:
+		kind (int): The certificate kind.
+
+	Returns:
+		bool: True if the first field of the certificate is a credential.
+*/
+func HasCredential(kind int) bool {
+	switch kind {
+	case POOL_REGISTRATION, POOL_RETIREMENT, GENESIS_KEY_DELEGATION, MOVE_INSTANTANEOUS_REWARDS:
+		return false
+	}
+	return true
+}
+
+/*
+*
+
+	UnmarshalCBOR decodes a certificate of any kind.
+
+	Params:
+		value ([]byte): The CBOR-encoded certificate.
+
+	Returns:
+		error: An error if the decoding fails.
+*/
+func (c *Certificate) UnmarshalCBOR(value []byte) error {
+	var items []cbor.RawMessage
+	err := cbor.Unmarshal(value, &items)
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return errors.New("empty certificate")
+	}
+	err = cbor.Unmarshal(items[0], &c.Kind)
+	if err != nil {
+		return err
+	}
+	items = items[1:]
+	c.StakeCredential = nil
+	if HasCredential(c.Kind) {
+		if len(items) == 0 {
+			return errors.New("missing certificate credential")
+		}
+		cred := StakeCredential{}
+		err = cbor.Unmarshal(items[0], &cred)
+		if err != nil {
+			return err
+		}
+		c.StakeCredential = &cred
+		items = items[1:]
+	}
+	c.Fields = items
+	return nil
+}
+
+/*
+*
+
+	MarshalCBOR encodes the certificate as a flat array
+	of its kind, credential and remaining fields.
+
+	Returns:
+		[]byte: The CBOR-encoded certificate.
+		error: An error if the encoding fails.
+*/
+func (c *Certificate) MarshalCBOR() ([]byte, error) {
+	items := []any{c.Kind}
+	if c.StakeCredential != nil {
+		items = append(items, c.StakeCredential)
+	}
+	for _, field := range c.Fields {
+		items = append(items, field)
+	}
+	return cbor.Marshal(items)
+}
@@ -0,0 +1,108 @@
+package Era
+
+import "fmt"
+
+type Era int
+
+const (
+	UNKNOWN Era = iota
+	BYRON
+	SHELLEY
+	ALLEGRA
+	MARY
+	ALONZO
+	BABBAGE
+	CONWAY
+)
+
+var eraNames = map[Era]string{
+	UNKNOWN: "unknown",
+	BYRON:   "byron",
+	SHELLEY: "shelley",
+	ALLEGRA: "allegra",
+	MARY:    "mary",
+	ALONZO:  "alonzo",
+	BABBAGE: "babbage",
+	CONWAY:  "conway",
+}
+
+/*
+*
+
+	String returns the lowercase name of the era.
+
+	Returns:
+		string: The name of the era.
+*/
+func (e Era) String() string {
+	name, ok := eraNames[e]
+	if !ok {
+		return fmt.Sprintf("era(%d)", int(e))
+	}
+	return name
+}
+
+/*
+*
+
+	FromHardForkTag converts the era index used by the
+	hard fork combinator (the first element of an era-wrapped
+	block or transaction) into an Era.
+
+	Params:
+		tag (uint64): The hard fork combinator era index.
+
+	Returns:
+		Era: The corresponding era.
+		error: An error if the index is unknown.
+*/
+func FromHardForkTag(tag uint64) (Era, error) {
+	switch tag {
+	case 0, 1:
+		return BYRON, nil
+	case 2:
+		return SHELLEY, nil
+	case 3:
+		return ALLEGRA, nil
+	case 4:
+		return MARY, nil
+	case 5:
+		return ALONZO, nil
+	case 6:
+		return BABBAGE, nil
+	case 7:
+		return CONWAY, nil
+	}
+	return UNKNOWN, fmt.Errorf("unknown hard fork era tag %d", tag)
+}
+
+/*
+*
+
+	HardForkTag returns the hard fork combinator era index of the era.
+	Byron maps to the index of regular (non boundary) blocks.
+
+	Returns:
+		uint64: The hard fork combinator era index.
+		error: An error if the era is unknown.
+*/
+func (e Era) HardForkTag() (uint64, error) {
+	if e < BYRON || e > CONWAY {
+		return 0, fmt.Errorf("era %s has no hard fork tag", e)
+	}
+	return uint64(e), nil
+}
+
+/*
+*
+
+	HasPlutus reports whether transactions of the era use the
+	four elements layout (body, witnesses, validity, auxiliary data)
+	introduced with Alonzo.
+
+	Returns:
+		bool: True from Alonzo onwards.
+*/
+func (e Era) HasPlutus() bool {
+	return e >= ALONZO
+}
@@ -0,0 +1,27 @@
+package Era_test
+
+import (
+	"testing"
+
+	"github.com/Salvionied/apollo/serialization/Era"
+)
+
+func TestHardForkTags(t *testing.T) {
+	for tag := uint64(1); tag <= 7; tag++ {
+		era, err := Era.FromHardForkTag(tag)
+		if err != nil {
+			t.Fatal(err)
+		}
+		back, err := era.HardForkTag()
+		if err != nil || back != tag {
+			t.Error("Invalid hard fork tag", back, "Expected", tag)
+		}
+	}
+	era, _ := Era.FromHardForkTag(7)
+	if era != Era.CONWAY || era.String() != "conway" {
+		t.Error("Invalid era", era)
+	}
+	if _, err := Era.FromHardForkTag(8); err == nil {
+		t.Error("Expected error for unknown tag")
+	}
+}
@@ -1,6 +1,8 @@
 package HDWallet
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
 	"crypto/sha512"
 	"encoding/hex"
 	"errors"
@@ -14,6 +16,22 @@ import (
 	"golang.org/x/text/unicode/norm"
 )
 
+// DerivationScheme selects how the master key is derived from
+// a mnemonic, as described in CIP-3.
+type DerivationScheme int
+
+const (
+	// ICARUS is the scheme of Daedalus, Yoroi and most software wallets.
+	ICARUS DerivationScheme = iota
+	// ICARUS_TREZOR is ICARUS, except that Trezor includes the checksum
+	// in the entropy of 24 word mnemonics.
+	ICARUS_TREZOR
+	// LEDGER is the HMAC based scheme of Ledger devices.
+	LEDGER
+)
+
+const LEDGER_HMAC_KEY = "ed25519 seed"
+
 type HDWallet struct {
 	RootXprivKey bip32.XPrv
 	XPrivKey     bip32.XPrv
@@ -93,7 +111,7 @@ func generateSeedFromEntropy(passphrase string, entropy []byte) string {
 
 /**
 NewHDWalletFromMnemonic creates a new HDWallet instance from a
-mnemonic and passphrase.
+mnemonic and passphrase, with the Icarus master key derivation.
 
 Params:
 	mnemonic (string): The mnemonic for wallet generation.
@@ -105,20 +123,59 @@ Returns:
 */
 
 func NewHDWalletFromMnemonic(mnemonic string, passphrase string) (*HDWallet, error) {
+	return NewHDWalletFromMnemonicWithScheme(mnemonic, passphrase, ICARUS)
+}
+
+/*
+*
+
+	NewHDWalletFromMnemonicWithScheme creates a new HDWallet instance
+	from a mnemonic and passphrase, deriving the master key like the
+	wallet or device the mnemonic comes from.
+
+	Params:
+		mnemonic (string): The mnemonic for wallet generation.
+		passphrase (string): The passphrase for wallet generation.
+		scheme (DerivationScheme): The master key derivation scheme.
+
+	Returns:
+		*HDWallet: A new HDWallet instance.
+		error: An error if the mnemonic is invalid.
+*/
+func NewHDWalletFromMnemonicWithScheme(mnemonic string, passphrase string, scheme DerivationScheme) (*HDWallet, error) {
 	mnemo := norm.NFKD.String(mnemonic)
-	entropy, error := bip39.EntropyFromMnemonic(mnemonic)
-	if error != nil {
-		return nil, error
+	entropy, err := bip39.EntropyFromMnemonic(mnemonic)
+	if err != nil {
+		return nil, err
 	}
 	if !bip39.IsMnemonicValid(mnemo) {
-		return nil, error
+		return nil, errors.New("Invalid mnemonic")
 	}
-	seed := generateSeedFromEntropy(passphrase, entropy)
-	wallet, err := NewHDWalletFromSeed(seed)
-	if err != nil {
-		return nil, err
+	var wallet *HDWallet
+	switch scheme {
+	case ICARUS, ICARUS_TREZOR:
+		seedEntropy := entropy
+		if scheme == ICARUS_TREZOR && len(strings.Fields(mnemo)) == 24 {
+			seedEntropy, err = bip39.MnemonicToByteArray(mnemo)
+			if err != nil {
+				return nil, err
+			}
+		}
+		seed := generateSeedFromEntropy(passphrase, seedEntropy)
+		wallet, err = NewHDWalletFromSeed(seed)
+		if err != nil {
+			return nil, err
+		}
+		wallet.Seed = []byte(seed)
+	case LEDGER:
+		seed := bip39.NewSeed(mnemo, passphrase)
+		wallet, err = newHDWalletFromLedgerSeed(seed)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("Invalid derivation scheme")
 	}
-	wallet.Seed = []byte(seed)
 	wallet.Mnemonic = mnemonic
 	wallet.Passphrase = passphrase
 
@@ -126,6 +183,33 @@ func NewHDWalletFromMnemonic(mnemonic string, passphrase string) (*HDWallet, err
 	return wallet, nil
 }
 
+func newHDWalletFromLedgerSeed(seed []byte) (*HDWallet, error) {
+	mac := hmac.New(sha512.New, []byte(LEDGER_HMAC_KEY))
+	mac.Write(seed)
+	digest := mac.Sum(nil)
+	for digest[31]&0b00100000 != 0 {
+		mac = hmac.New(sha512.New, []byte(LEDGER_HMAC_KEY))
+		mac.Write(digest)
+		digest = mac.Sum(nil)
+	}
+	digest[0] &= 0b11111000
+	digest[31] &= 0b01111111
+	digest[31] |= 0b01000000
+	ccMac := hmac.New(sha256.New, []byte(LEDGER_HMAC_KEY))
+	ccMac.Write([]byte{0x01})
+	ccMac.Write(seed)
+	privKey, err := bip32.NewXPrv(append(digest, ccMac.Sum(nil)...))
+	if err != nil {
+		return nil, err
+	}
+	return &HDWallet{
+		RootXprivKey: privKey,
+		XPrivKey:     privKey,
+		Path:         "m",
+		Seed:         seed,
+	}, nil
+}
+
 func (hd *HDWallet) copy() *HDWallet {
 	return &HDWallet{
 		RootXprivKey: hd.RootXprivKey,
@@ -67,3 +67,47 @@ func TestInvalidDerivationPath(t *testing.T) {
 		t.Errorf("DerivePath() failed")
 	}
 }
+
+func TestMasterKeySchemes(t *testing.T) {
+	icarusMnemonic := "eight country switch draw meat scout mystery blade tip drift useless good keep usage title"
+	ledgerMnemonic := "recall grace sport punch exhibit mad harbor stand obey short width stem awkward used stairs wool ugly trap season stove worth toward congress jaguar"
+	testCases := []struct {
+		mnemonic   string
+		passphrase string
+		scheme     HDWallet.DerivationScheme
+		expected   string
+	}{
+		{icarusMnemonic, "", HDWallet.ICARUS, "c065afd2832cd8b087c4d9ab7011f481ee1e0721e78ea5dd609f3ab3f156d245d176bd8fd4ec60b4731c3918a2a72a0226c0cd119ec35b47e4d55884667f552a23f7fdcd4a10c6cd2c7393ac61d877873e248f417634aa3d812af327ffe9d620"},
+		{icarusMnemonic, "foo", HDWallet.ICARUS, "70531039904019351e1afb361cd1b312a4d0565d4ff9f8062d38acf4b15cce41d7b5738d9c893feea55512a3004acb0d222c35d3e3d5cde943a15a9824cbac59443cf67e589614076ba01e354b1a432e0e6db3b59e37fc56b5fb0222970a010e"},
+		{ledgerMnemonic, "", HDWallet.LEDGER, "a08cf85b564ecf3b947d8d4321fb96d70ee7bb760877e371899b14e2ccf88658104b884682b57efd97decbb318a45c05a527b9cc5c2f64f7352935a049ceea60680d52308194ccef2a18e6812b452a5815fbd7f5babc083856919aaf668fe7e4"},
+		{ledgerMnemonic, "", HDWallet.ICARUS_TREZOR, "50c6d186945d3f7afd3fabd1bc293d2ef49547f13711c4e61d1b17ba03b3495cb98df4a9700e2c1d12e4a6489aba9a8c33600c3517a8790b4edb6de6740711fb809e33d80d453c874b5d7ea405008308c703ca612408c316522b6e0a2a13eae8"},
+	}
+	for _, testCase := range testCases {
+		hd, err := HDWallet.NewHDWalletFromMnemonicWithScheme(testCase.mnemonic, testCase.passphrase, testCase.scheme)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hd.RootXprivKey.String() != testCase.expected {
+			t.Error("Invalid master key", testCase.scheme, testCase.passphrase, hd.RootXprivKey.String())
+		}
+	}
+}
+
+func TestIcarusTrezorShortMnemonic(t *testing.T) {
+	icarus, _ := HDWallet.NewHDWalletFromMnemonic(MNEMONIC_15, "")
+	trezor, err := HDWallet.NewHDWalletFromMnemonicWithScheme(MNEMONIC_15, "", HDWallet.ICARUS_TREZOR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if icarus.RootXprivKey.String() != trezor.RootXprivKey.String() {
+		t.Error("Icarus and Icarus-Trezor only differ for 24 word mnemonics")
+	}
+	icarus, _ = HDWallet.NewHDWalletFromMnemonic(MNEMONIC_24, "")
+	trezor, _ = HDWallet.NewHDWalletFromMnemonicWithScheme(MNEMONIC_24, "", HDWallet.ICARUS_TREZOR)
+	if icarus.RootXprivKey.String() == trezor.RootXprivKey.String() {
+		t.Error("Icarus-Trezor must include the checksum for 24 word mnemonics")
+	}
+	if _, err := HDWallet.NewHDWalletFromMnemonicWithScheme(MNEMONIC_12, "", HDWallet.DerivationScheme(7)); err == nil {
+		t.Error("Expected an error for an unknown scheme")
+	}
+}
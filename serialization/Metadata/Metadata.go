@@ -34,22 +34,25 @@ type ShelleyMaryMetadata struct {
 func (smm *ShelleyMaryMetadata) MarshalCBOR() ([]byte, error) {
 	enc, _ := cbor.EncOptions{Sort: cbor.SortLengthFirst}.EncMode()
 	if len(smm.NativeScripts) > 0 {
-		return enc.Marshal(smm)
+		return enc.Marshal([]any{smm.Metadata, smm.NativeScripts})
 	} else {
 		return enc.Marshal(smm.Metadata)
 	}
 }
 
 type AlonzoMetadata struct {
-	Metadata      Metadata                    `cbor:"0,keyasint,omitempty"`
-	NativeScripts []NativeScript.NativeScript `cbor:"1,keyasint,omitempty"`
-	PlutusScripts []uint8                     `cbor:"2,keyasint,omitempty"`
+	Metadata        Metadata                    `cbor:"0,keyasint,omitempty"`
+	NativeScripts   []NativeScript.NativeScript `cbor:"1,keyasint,omitempty"`
+	PlutusScripts   [][]byte                    `cbor:"2,keyasint,omitempty"`
+	PlutusV2Scripts [][]byte                    `cbor:"3,keyasint,omitempty"`
+	PlutusV3Scripts [][]byte                    `cbor:"4,keyasint,omitempty"`
 }
 
 type AuxiliaryData struct {
 	_basicMeta   Metadata
 	_ShelleyMeta ShelleyMaryMetadata
 	_AlonzoMeta  AlonzoMetadata
+	original     *serialization.OriginalCbor
 }
 
 /*
@@ -97,7 +100,7 @@ func (ad *AuxiliaryData) SetShelleyMetadata(value ShelleyMaryMetadata) {
 		[]byte: The computed hash or nil if all metadata fileds are empty.
 */
 func (ad *AuxiliaryData) Hash() []byte {
-	if len(ad._basicMeta) != 0 || len(ad._ShelleyMeta.Metadata) != 0 || len(ad._AlonzoMeta.Metadata) != 0 {
+	if ad.original.Raw() != nil || len(ad._basicMeta) != 0 || len(ad._ShelleyMeta.Metadata) != 0 || len(ad._AlonzoMeta.Metadata) != 0 {
 		marshaled, _ := cbor.Marshal(ad)
 		hash, err := serialization.Blake2bHash(marshaled)
 		if err != nil {
@@ -121,13 +124,23 @@ func (ad *AuxiliaryData) Hash() []byte {
 		error: An error if deserialization fails.
 */
 func (ad *AuxiliaryData) UnmarshalCBOR(value []byte) error {
-	err_shelley := cbor.Unmarshal(value, &ad._ShelleyMeta)
-	if err_shelley != nil {
+	ad.original = nil
+	var tagged cbor.RawTag
+	if cbor.Unmarshal(value, &tagged) == nil && tagged.Number == 259 {
+		err := cbor.Unmarshal(tagged.Content, &ad._AlonzoMeta)
+		if err != nil {
+			return err
+		}
+	} else if err_shelley := cbor.Unmarshal(value, &ad._ShelleyMeta); err_shelley != nil {
 		err_basic_meta := cbor.Unmarshal(value, &ad._basicMeta)
 		if err_basic_meta != nil {
 			return err_basic_meta
 		}
 	}
+	encoded, err := ad.encode()
+	if err == nil {
+		ad.original = serialization.NewOriginalCbor(value, encoded)
+	}
 	return nil
 }
 
@@ -135,17 +148,27 @@ func (ad *AuxiliaryData) UnmarshalCBOR(value []byte) error {
 *
 
 	MarshalCBOR serializes the AUxiliaryData to a CBOR byte slice.
+	Decoded AuxiliaryData that was not modified is serialized
+	with the exact bytes it was decoded from.
 
 	Returns:
 		[]byte: The CBOR-serialized AuxiliaryData.
 		error: An error if serialization fails.
 */
 func (ad *AuxiliaryData) MarshalCBOR() ([]byte, error) {
+	encoded, err := ad.encode()
+	if err != nil {
+		return nil, err
+	}
+	return ad.original.Resolve(encoded), nil
+}
+
+func (ad *AuxiliaryData) encode() ([]byte, error) {
 	enc, _ := cbor.EncOptions{Sort: cbor.SortLengthFirst}.EncMode()
 	if len(ad._basicMeta) != 0 {
 		return enc.Marshal(ad._basicMeta)
 	}
-	if len(ad._AlonzoMeta.Metadata) != 0 || len(ad._AlonzoMeta.NativeScripts) != 0 || len(ad._AlonzoMeta.PlutusScripts) != 0 {
+	if len(ad._AlonzoMeta.Metadata) != 0 || len(ad._AlonzoMeta.NativeScripts) != 0 || len(ad._AlonzoMeta.PlutusScripts) != 0 || len(ad._AlonzoMeta.PlutusV2Scripts) != 0 || len(ad._AlonzoMeta.PlutusV3Scripts) != 0 {
 		return enc.Marshal(ad._AlonzoMeta)
 	}
 	if len(ad._ShelleyMeta.Metadata) == 0 && len(ad._ShelleyMeta.NativeScripts) == 0 {
@@ -4,6 +4,7 @@ import (
 	"encoding/hex"
 	"testing"
 
+	"github.com/Salvionied/apollo/serialization"
 	"github.com/Salvionied/apollo/serialization/Metadata"
 	"github.com/Salvionied/apollo/serialization/NativeScript"
 	"github.com/Salvionied/cbor/v2"
@@ -130,3 +131,22 @@ func TestMarshalEmptyAux(t *testing.T) {
 		t.Errorf("InvalidReserialization got %s expected %s", hex.EncodeToString(marshaled), `f6`)
 	}
 }
+
+func TestAuxiliaryDataKeepsOriginalCbor(t *testing.T) {
+	// keys not sorted length first
+	original := "a21902a26161016162"
+	decoded, _ := hex.DecodeString(original)
+	aux := Metadata.AuxiliaryData{}
+	err := cbor.Unmarshal(decoded, &aux)
+	if err != nil {
+		t.Fatal("Unmarshal failed", err)
+	}
+	marshaled, _ := cbor.Marshal(&aux)
+	if hex.EncodeToString(marshaled) != original {
+		t.Error("Invalid marshaling", hex.EncodeToString(marshaled), "Expected", original)
+	}
+	expectedHash, _ := serialization.Blake2bHash(decoded)
+	if hex.EncodeToString(aux.Hash()) != hex.EncodeToString(expectedHash) {
+		t.Error("Invalid hash", hex.EncodeToString(aux.Hash()), "Expected", hex.EncodeToString(expectedHash))
+	}
+}
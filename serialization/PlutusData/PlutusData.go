@@ -131,7 +131,171 @@ func (d DatumOption) MarshalCBOR() ([]byte, error) {
 }
 
 type ScriptRef struct {
-	Script _Script
+	Script     _Script
+	scriptType uint64
+	hasType    bool
+	original   *serialization.OriginalCbor
+}
+
+/*
+*
+
+	NewScriptRef creates a reference script of the given type.
+
+	Params:
+		scriptType (uint64): 0 for native scripts and 1, 2 or 3 for PlutusV1, V2 and V3.
+		script ([]byte): The script bytes as hashed by the ledger, that
+			is the CBOR of native scripts or the content of the byte
+			string of Plutus scripts.
+
+	Returns:
+		ScriptRef: The reference script.
+*/
+func NewScriptRef(scriptType uint64, script []byte) ScriptRef {
+	sr := ScriptRef{scriptType: scriptType, hasType: true}
+	sr.Script.Script = script
+	return sr
+}
+
+/*
+*
+
+	UnmarshalCBOR decodes a reference script, which is
+	the CBOR encoding of [script_type, script] wrapped in
+	a tag 24 byte string. For native scripts the raw CBOR of
+	the script is kept in Script.Script.
+
+	Params:
+		value ([]byte): The CBOR-encoded reference script.
+
+	Returns:
+		error: An error if the decoding fails.
+*/
+func (sr *ScriptRef) UnmarshalCBOR(value []byte) error {
+	var wrapped cbor.Tag
+	err := cbor.Unmarshal(value, &wrapped)
+	if err != nil {
+		return fmt.Errorf("ScriptRef: UnmarshalCBOR: %v", err)
+	}
+	content, ok := wrapped.Content.([]byte)
+	if wrapped.Number != 24 || !ok {
+		return fmt.Errorf("ScriptRef: UnmarshalCBOR: expected tag 24 byte string")
+	}
+	var script struct {
+		_      struct{} `cbor:",toarray"`
+		Type   uint64
+		Script cbor.RawMessage
+	}
+	err = cbor.Unmarshal(content, &script)
+	if err != nil {
+		return fmt.Errorf("ScriptRef: UnmarshalCBOR: %v", err)
+	}
+	sr.scriptType = script.Type
+	sr.hasType = true
+	if script.Type == 0 {
+		sr.Script.Script = script.Script
+	} else {
+		err = cbor.Unmarshal(script.Script, &sr.Script.Script)
+		if err != nil {
+			return fmt.Errorf("ScriptRef: UnmarshalCBOR: %v", err)
+		}
+	}
+	encoded, err := sr.encode()
+	if err == nil {
+		sr.original = serialization.NewOriginalCbor(value, encoded)
+	}
+	return nil
+}
+
+/*
+*
+
+	MarshalCBOR encodes the reference script. Scripts without
+	a known type are encoded as PlutusV2 scripts.
+
+	Returns:
+		[]byte: The CBOR-encoded reference script.
+		error: An error if the encoding fails.
+*/
+func (sr *ScriptRef) MarshalCBOR() ([]byte, error) {
+	encoded, err := sr.encode()
+	if err != nil {
+		return nil, err
+	}
+	return sr.original.Resolve(encoded), nil
+}
+
+func (sr *ScriptRef) encode() ([]byte, error) {
+	scriptType := sr.scriptType
+	if !sr.hasType {
+		scriptType = 2
+	}
+	var script any = sr.Script.Script
+	if scriptType == 0 {
+		script = cbor.RawMessage(sr.Script.Script)
+	}
+	content, err := cbor.Marshal([]any{scriptType, script})
+	if err != nil {
+		return nil, err
+	}
+	return cbor.Marshal(cbor.Tag{Number: 24, Content: content})
+}
+
+/*
+*
+
+	Size returns the size in bytes of the reference script,
+	which is what the Conway ledger charges for when the
+	script is used by a transaction.
+
+	Returns:
+		int: The size of the script, 0 for a nil reference.
+*/
+func (sr *ScriptRef) Size() int {
+	if sr == nil {
+		return 0
+	}
+	return len(sr.Script.Script)
+}
+
+/*
+*
+
+	Type returns the language of the reference script:
+	0 for native scripts and 1, 2 or 3 for PlutusV1, V2 and V3.
+	Scripts without a known type are reported as PlutusV2.
+
+	Returns:
+		uint64: The script type.
+*/
+func (sr *ScriptRef) Type() uint64 {
+	if !sr.hasType {
+		return 2
+	}
+	return sr.scriptType
+}
+
+/*
+*
+
+	Hash computes the script hash of the reference script.
+
+	Returns:
+		serialization.ScriptHash: The hash of the script.
+		error: An error if the hashing fails.
+*/
+func (sr *ScriptRef) Hash() (serialization.ScriptHash, error) {
+	hash, err := blake2b.New(28, nil)
+	if err != nil {
+		return serialization.ScriptHash{}, err
+	}
+	_, err = hash.Write(append([]byte{byte(sr.Type())}, sr.Script.Script...))
+	if err != nil {
+		return serialization.ScriptHash{}, err
+	}
+	r := serialization.ScriptHash{}
+	copy(r[:], hash.Sum(nil))
+	return r, nil
 }
 
 type CostModels map[serialization.CustomBytes]CM
@@ -767,6 +931,7 @@ type PlutusData struct {
 	PlutusDataType PlutusType
 	TagNr          uint64
 	Value          any
+	original       *serialization.OriginalCbor
 }
 
 func (pd *PlutusData) String() string {
@@ -841,7 +1006,7 @@ func (pd *PlutusData) String() string {
 			res += "}"
 		}
 	case PlutusInt:
-		res += fmt.Sprintf("Int(%d)", pd.Value.(uint64))
+		res += fmt.Sprintf("Int(%v)", pd.Value)
 	case PlutusBytes:
 		res += fmt.Sprintf("Bytes(%s)", hex.EncodeToString(pd.Value.([]uint8)))
 	default:
@@ -899,6 +1064,7 @@ func (pd *PlutusData) Clone() PlutusData {
 		PlutusDataType: pd.PlutusDataType,
 		TagNr:          pd.TagNr,
 		Value:          pd.Value,
+		original:       pd.original,
 	}
 }
 
@@ -906,12 +1072,22 @@ func (pd *PlutusData) Clone() PlutusData {
 *
 
 	MarshalCBOR encodes the PlutusData into a CBOR byte slice.
+	Decoded PlutusData that was not modified is encoded
+	with the exact bytes it was decoded from.
 
 	Returns:
 		[]uint8: The CBOR-encoded byte slice.
 		error: An error, if any, during ecoding.
 */
 func (pd *PlutusData) MarshalCBOR() ([]uint8, error) {
+	encoded, err := pd.encode()
+	if err != nil {
+		return nil, err
+	}
+	return pd.original.Resolve(encoded), nil
+}
+
+func (pd *PlutusData) encode() ([]uint8, error) {
 	//enc, _ := cbor.CanonicalEncOptions().EncMode()
 	if pd.PlutusDataType == PlutusMap {
 		customEnc, _ := cbor.EncOptions{Sort: cbor.SortBytewiseLexical}.EncMode()
@@ -1138,9 +1314,27 @@ func (pdk *PlutusDataKey) MarshalCBOR() ([]uint8, error) {
 	   		error: An error, if any, during unmarshaling.
 */
 func (pd *PlutusData) UnmarshalCBOR(value []uint8) error {
+	pd.original = nil
+	err := pd.decode(value)
+	if err != nil {
+		return err
+	}
+	encoded, err := pd.encode()
+	if err == nil {
+		pd.original = serialization.NewOriginalCbor(value, encoded)
+	}
+	return nil
+}
+
+func (pd *PlutusData) decode(value []uint8) error {
 	var x any
 	err := cbor.Unmarshal(value, &x)
 	if err != nil {
+		// Maps keyed by constructors or lists cannot be decoded into
+		// a generic Go map, decode the containers one level at a time.
+		if pd.unmarshalContainer(value) == nil {
+			return nil
+		}
 		return err
 	}
 	//fmt.Println(hex.EncodeToString(value))
@@ -1220,6 +1414,10 @@ func (pd *PlutusData) UnmarshalCBOR(value []uint8) error {
 			pd.PlutusDataType = PlutusInt
 			pd.Value = x
 			pd.TagNr = 0
+		case int64:
+			pd.PlutusDataType = PlutusInt
+			pd.Value = x
+			pd.TagNr = 0
 
 		case []uint8:
 			pd.PlutusDataType = PlutusBytes
@@ -1263,6 +1461,71 @@ func (pd *PlutusData) UnmarshalCBOR(value []uint8) error {
 	return nil
 }
 
+/*
+*
+
+	unmarshalContainer decodes a tag, array or map without decoding
+	its content into generic Go values first. Map keys that are not
+	bytes or integers are kept as raw cbor in a PlutusDataKey.
+
+	Params:
+		value ([]uint8): The CBOR-encoded data to unmarshal.
+
+	Returns:
+		error: An error, if any, during unmarshaling.
+*/
+func (pd *PlutusData) unmarshalContainer(value []uint8) error {
+	if len(value) == 0 {
+		return errors.New("empty plutus data")
+	}
+	switch value[0] >> 5 {
+	case 4:
+		if value[0] == 0x9f {
+			y := PlutusIndefArray{}
+			err := cbor.Unmarshal(value, &y)
+			if err != nil {
+				return err
+			}
+			pd.Value = y
+		} else {
+			y := PlutusDefArray{}
+			err := cbor.Unmarshal(value, &y)
+			if err != nil {
+				return err
+			}
+			pd.Value = y
+		}
+		pd.PlutusDataType = PlutusArray
+		pd.TagNr = 0
+	case 5:
+		y := map[PlutusDataKey]PlutusData{}
+		err := cbor.Unmarshal(value, &y)
+		if err != nil {
+			return err
+		}
+		pd.PlutusDataType = PlutusMap
+		pd.Value = &y
+		pd.TagNr = 0
+	case 6:
+		var tag cbor.RawTag
+		err := cbor.Unmarshal(value, &tag)
+		if err != nil {
+			return err
+		}
+		content := PlutusData{}
+		err = content.UnmarshalCBOR(tag.Content)
+		if err != nil {
+			return err
+		}
+		pd.PlutusDataType = content.PlutusDataType
+		pd.Value = content.Value
+		pd.TagNr = tag.Number
+	default:
+		return errors.New("not a plutus data container")
+	}
+	return nil
+}
+
 type RawPlutusData struct {
 	//TODO
 }
@@ -1401,6 +1664,8 @@ func (ps *PlutusV1Script) ToAddress(stakingCredential []byte) Address.Address {
 
 type PlutusV2Script []byte
 
+type PlutusV3Script []byte
+
 /*
 *
 
@@ -1498,3 +1763,27 @@ func (ps PlutusV2Script) Hash() (serialization.ScriptHash, error) {
 	copy(r[:], hash.Sum(nil))
 	return r, nil
 }
+
+/*
+*
+
+	 	Hash computes the script hash for a PlutusV3Script.
+
+	 	Returns:
+	   		serialization.ScriptHash: The script hash of the PlutusV3Script.
+			error: An error if the Hashing fails.
+*/
+func (ps PlutusV3Script) Hash() (serialization.ScriptHash, error) {
+	finalbytes := append([]byte{0x03}, ps...)
+	hash, err := blake2b.New(28, nil)
+	if err != nil {
+		return serialization.ScriptHash{}, err
+	}
+	_, err = hash.Write(finalbytes)
+	if err != nil {
+		return serialization.ScriptHash{}, err
+	}
+	r := serialization.ScriptHash{}
+	copy(r[:], hash.Sum(nil))
+	return r, nil
+}
@@ -86,21 +86,21 @@ func TestSerializeAndDeserializePlutusData(t *testing.T) {
 func GetMinSwapPlutusData() PlutusData.PlutusData {
 	// PkhStruct :=
 	SkhStruct := PlutusData.PlutusData{
-		PlutusData.PlutusArray,
-		121,
-		PlutusData.PlutusIndefArray{
+		PlutusDataType: PlutusData.PlutusArray,
+		TagNr:          121,
+		Value: PlutusData.PlutusIndefArray{
 			PlutusData.PlutusData{
-				PlutusData.PlutusArray,
-				121,
-				PlutusData.PlutusIndefArray{
+				PlutusDataType: PlutusData.PlutusArray,
+				TagNr:          121,
+				Value: PlutusData.PlutusIndefArray{
 					PlutusData.PlutusData{
-						PlutusData.PlutusArray,
-						121,
-						PlutusData.PlutusIndefArray{
+						PlutusDataType: PlutusData.PlutusArray,
+						TagNr:          121,
+						Value: PlutusData.PlutusIndefArray{
 							PlutusData.PlutusData{
-								PlutusData.PlutusBytes,
-								0,
-								[]byte{}}},
+								PlutusDataType: PlutusData.PlutusBytes,
+								TagNr:          0,
+								Value:          []byte{}}},
 					},
 				},
 			},
@@ -108,17 +108,17 @@ func GetMinSwapPlutusData() PlutusData.PlutusData {
 	}
 
 	pkhStruct := PlutusData.PlutusData{
-		PlutusData.PlutusArray,
-		121,
-		PlutusData.PlutusIndefArray{
+		PlutusDataType: PlutusData.PlutusArray,
+		TagNr:          121,
+		Value: PlutusData.PlutusIndefArray{
 			PlutusData.PlutusData{
-				PlutusData.PlutusArray,
-				121,
-				PlutusData.PlutusIndefArray{
+				PlutusDataType: PlutusData.PlutusArray,
+				TagNr:          121,
+				Value: PlutusData.PlutusIndefArray{
 					PlutusData.PlutusData{
-						PlutusData.PlutusBytes,
-						0,
-						[]byte{},
+						PlutusDataType: PlutusData.PlutusBytes,
+						TagNr:          0,
+						Value:          []byte{},
 					},
 				},
 			},
@@ -131,14 +131,14 @@ func GetMinSwapPlutusData() PlutusData.PlutusData {
 		PlutusDataType: PlutusData.PlutusArray,
 		Value: PlutusData.PlutusIndefArray{
 			PlutusData.PlutusData{
-				PlutusData.PlutusBytes,
-				0,
-				policy_bytes,
+				PlutusDataType: PlutusData.PlutusBytes,
+				TagNr:          0,
+				Value:          policy_bytes,
 			},
 			PlutusData.PlutusData{
-				PlutusData.PlutusBytes,
-				0,
-				asset_bytes,
+				PlutusDataType: PlutusData.PlutusBytes,
+				TagNr:          0,
+				Value:          asset_bytes,
 			},
 		},
 		TagNr: 121,
@@ -148,21 +148,21 @@ func GetMinSwapPlutusData() PlutusData.PlutusData {
 		Value: PlutusData.PlutusIndefArray{
 			AssetStruct,
 			PlutusData.PlutusData{
-				PlutusData.PlutusInt,
-				0,
-				0}},
+				PlutusDataType: PlutusData.PlutusInt,
+				TagNr:          0,
+				Value:          0}},
 		TagNr: 121,
 	}
 
 	Fee := PlutusData.PlutusData{
-		PlutusData.PlutusInt,
-		0,
-		2_000_000,
+		PlutusDataType: PlutusData.PlutusInt,
+		TagNr:          0,
+		Value:          2_000_000,
 	}
 	Bribe := PlutusData.PlutusData{
-		PlutusData.PlutusInt,
-		0,
-		0,
+		PlutusDataType: PlutusData.PlutusInt,
+		TagNr:          0,
+		Value:          0,
 	}
 
 	FullStruct := PlutusData.PlutusData{
@@ -171,9 +171,9 @@ func GetMinSwapPlutusData() PlutusData.PlutusData {
 			pkhStruct,
 			pkhStruct,
 			PlutusData.PlutusData{
-				PlutusData.PlutusArray,
-				122,
-				[]PlutusData.PlutusData{},
+				PlutusDataType: PlutusData.PlutusArray,
+				TagNr:          122,
+				Value:          []PlutusData.PlutusData{},
 			},
 			BuyOrderStruct,
 			Bribe,
@@ -301,3 +301,84 @@ func TestPlutusDataFromJson(t *testing.T) {
 	//t.Error("test")
 
 }
+
+func TestPlutusDataKeepsOriginalCbor(t *testing.T) {
+	// non minimal integer and map keys out of order
+	for _, original := range []string{"d8799f1800ff", "a2410201410101", "9f4102d8799f1800ffff"} {
+		decoded, _ := hex.DecodeString(original)
+		pd := PlutusData.PlutusData{}
+		err := cbor.Unmarshal(decoded, &pd)
+		if err != nil {
+			t.Fatal("Unmarshal failed", err)
+		}
+		marshaled, _ := cbor.Marshal(&pd)
+		if hex.EncodeToString(marshaled) != original {
+			t.Error("Invalid marshaling", hex.EncodeToString(marshaled), "Expected", original)
+		}
+	}
+	decoded, _ := hex.DecodeString("d8799f1800ff")
+	pd := PlutusData.PlutusData{}
+	_ = cbor.Unmarshal(decoded, &pd)
+	pd.Value.(PlutusData.PlutusIndefArray)[0].Value = uint64(1)
+	marshaled, _ := cbor.Marshal(&pd)
+	if hex.EncodeToString(marshaled) != "d8799f01ff" {
+		t.Error("Invalid marshaling", hex.EncodeToString(marshaled), "Expected", "d8799f01ff")
+	}
+}
+
+func TestOriginalCborNotShared(t *testing.T) {
+	decoded, _ := hex.DecodeString("d8799f1800ff")
+	pd := PlutusData.PlutusData{}
+	_ = cbor.Unmarshal(decoded, &pd)
+	fresh := PlutusData.PlutusData{
+		PlutusDataType: PlutusData.PlutusArray,
+		TagNr:          121,
+		Value: PlutusData.PlutusIndefArray{
+			PlutusData.PlutusData{
+				PlutusDataType: PlutusData.PlutusInt,
+				Value:          uint64(0),
+			},
+		},
+	}
+	marshaled, _ := cbor.Marshal(&fresh)
+	if hex.EncodeToString(marshaled) != "d8799f00ff" {
+		t.Error("Invalid marshaling", hex.EncodeToString(marshaled), "Expected", "d8799f00ff")
+	}
+	clone := pd.Clone()
+	marshaled, _ = cbor.Marshal(&clone)
+	if hex.EncodeToString(marshaled) != "d8799f1800ff" {
+		t.Error("Invalid marshaling", hex.EncodeToString(marshaled), "Expected", "d8799f1800ff")
+	}
+}
+
+func TestNewScriptRef(t *testing.T) {
+	script, _ := hex.DecodeString("4d01000033222220051200120011")
+	scriptRef := PlutusData.NewScriptRef(3, script)
+	if scriptRef.Size() != len(script) || scriptRef.Type() != 3 {
+		t.Error("Invalid script ref", scriptRef.Size(), scriptRef.Type())
+	}
+	encoded, err := cbor.Marshal(&scriptRef)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(encoded) != "d8185182034e4d01000033222220051200120011" {
+		t.Error("Invalid script ref encoding", hex.EncodeToString(encoded))
+	}
+	native, _ := hex.DecodeString("8200581c0000000000000000000000000000000000000000000000000000000a")
+	nativeRef := PlutusData.NewScriptRef(0, native)
+	encoded, err = cbor.Marshal(&nativeRef)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := PlutusData.ScriptRef{}
+	err = cbor.Unmarshal(encoded, &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Type() != 0 || decoded.Size() != len(native) {
+		t.Error("Invalid native script ref", decoded.Type(), decoded.Size())
+	}
+	if (*PlutusData.ScriptRef)(nil).Size() != 0 {
+		t.Error("Nil script ref should be empty")
+	}
+}
@@ -0,0 +1,326 @@
+package Rational
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/Salvionied/cbor/v2"
+)
+
+const RATIONAL_TAG = 30
+
+/*
+*
+
+	Rational is an exact non-negative fraction, as used by
+	the ledger for execution prices and the monetary
+	parameters (unit_interval and nonnegative_interval).
+	The zero value is 0.
+*/
+type Rational struct {
+	Num uint64
+	Den uint64
+}
+
+/*
+*
+
+	New creates a Rational reduced to its lowest terms.
+
+	Params:
+		num (uint64): The numerator.
+		den (uint64): The denominator.
+
+	Returns:
+		Rational: The fraction num/den.
+*/
+func New(num uint64, den uint64) Rational {
+	if den == 0 {
+		return Rational{Num: num, Den: 0}
+	}
+	r, _ := FromRat(new(big.Rat).SetFrac(new(big.Int).SetUint64(num), new(big.Int).SetUint64(den)))
+	return r
+}
+
+/*
+*
+
+	FromRat converts a big.Rat into a Rational.
+
+	Params:
+		value (*big.Rat): The value to convert.
+
+	Returns:
+		Rational: The converted value.
+		error: An error if the value is negative or does not fit in 64 bits.
+*/
+func FromRat(value *big.Rat) (Rational, error) {
+	if value.Sign() < 0 {
+		return Rational{}, fmt.Errorf("negative rational %s", value.RatString())
+	}
+	if !value.Num().IsUint64() || !value.Denom().IsUint64() {
+		return Rational{}, fmt.Errorf("rational %s out of range", value.RatString())
+	}
+	return Rational{Num: value.Num().Uint64(), Den: value.Denom().Uint64()}, nil
+}
+
+/*
+*
+
+	Parse reads a Rational from either a fraction ("577/10000")
+	or an exact decimal ("0.0577", "7.21e-05").
+
+	Params:
+		value (string): The string to parse.
+
+	Returns:
+		Rational: The parsed value.
+		error: An error if the string is not a valid non-negative number.
+*/
+func Parse(value string) (Rational, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return Rational{}, nil
+	}
+	rat, ok := new(big.Rat).SetString(value)
+	if !ok {
+		return Rational{}, fmt.Errorf("invalid rational %q", value)
+	}
+	return FromRat(rat)
+}
+
+/*
+*
+
+	Rat returns the value as a big.Rat. A zero denominator
+	(the zero value) is treated as 0.
+
+	Returns:
+		*big.Rat: The value of the fraction.
+*/
+func (r Rational) Rat() *big.Rat {
+	if r.Den == 0 {
+		return new(big.Rat)
+	}
+	return new(big.Rat).SetFrac(new(big.Int).SetUint64(r.Num), new(big.Int).SetUint64(r.Den))
+}
+
+/*
+*
+
+	IsZero reports whether the fraction is 0.
+
+	Returns:
+		bool: True if the fraction is 0.
+*/
+func (r Rational) IsZero() bool {
+	return r.Num == 0 || r.Den == 0
+}
+
+/*
+*
+
+	Float64 returns the nearest float64 to the fraction.
+	It must only be used for display purposes.
+
+	Returns:
+		float64: The approximated value.
+*/
+func (r Rational) Float64() float64 {
+	f, _ := r.Rat().Float64()
+	return f
+}
+
+/*
+*
+
+	String returns the fraction in the "num/den" form.
+
+	Returns:
+		string: The string representation.
+*/
+func (r Rational) String() string {
+	if r.Den == 0 {
+		return "0/1"
+	}
+	return fmt.Sprintf("%d/%d", r.Num, r.Den)
+}
+
+/*
+*
+
+	Mul multiplies the fraction by an integer without
+	any rounding.
+
+	Params:
+		value (int64): The integer to multiply.
+
+	Returns:
+		*big.Rat: The exact product.
+*/
+func (r Rational) Mul(value int64) *big.Rat {
+	return new(big.Rat).Mul(r.Rat(), new(big.Rat).SetInt64(value))
+}
+
+/*
+*
+
+	Ceil rounds an exact value up to the next integer.
+
+	Params:
+		value (*big.Rat): The value to round.
+
+	Returns:
+		int64: The smallest integer greater or equal to value.
+*/
+func Ceil(value *big.Rat) int64 {
+	quo, rem := new(big.Int).QuoRem(value.Num(), value.Denom(), new(big.Int))
+	if rem.Sign() > 0 {
+		quo.Add(quo, big.NewInt(1))
+	}
+	return quo.Int64()
+}
+
+/*
+*
+
+	MarshalJSON encodes the fraction as a JSON number when it
+	has a finite decimal expansion, and as a "num/den" string
+	otherwise, so that no precision is ever lost.
+
+	Returns:
+		[]byte: The JSON encoding.
+		error: An error if the encoding fails.
+*/
+func (r Rational) MarshalJSON() ([]byte, error) {
+	if r.Den == 0 {
+		return []byte("0"), nil
+	}
+	if digits, ok := decimalDigits(r.Den); ok {
+		return []byte(r.Rat().FloatString(digits)), nil
+	}
+	return json.Marshal(r.String())
+}
+
+func decimalDigits(den uint64) (int, bool) {
+	twos, fives := 0, 0
+	for den%2 == 0 {
+		den /= 2
+		twos++
+	}
+	for den%5 == 0 {
+		den /= 5
+		fives++
+	}
+	if den != 1 {
+		return 0, false
+	}
+	if twos > fives {
+		return twos, true
+	}
+	return fives, true
+}
+
+/*
+*
+
+	UnmarshalJSON decodes a fraction from a JSON number, a
+	string holding a decimal or a "num/den" fraction, or an
+	object with numerator and denominator fields.
+
+	Params:
+		data ([]byte): The JSON value.
+
+	Returns:
+		error: An error if the value is not a valid fraction.
+*/
+func (r *Rational) UnmarshalJSON(data []byte) error {
+	var raw any
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.UseNumber()
+	err := decoder.Decode(&raw)
+	if err != nil {
+		return err
+	}
+	var parsed Rational
+	switch value := raw.(type) {
+	case nil:
+		parsed = Rational{}
+	case json.Number:
+		parsed, err = Parse(value.String())
+	case string:
+		parsed, err = Parse(value)
+	case map[string]any:
+		num, okNum := value["numerator"].(json.Number)
+		den, okDen := value["denominator"].(json.Number)
+		if !okNum || !okDen {
+			return errors.New("invalid rational object")
+		}
+		parsed, err = Parse(num.String() + "/" + den.String())
+	default:
+		return fmt.Errorf("invalid rational %s", string(data))
+	}
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+type cborRational struct {
+	_   struct{} `cbor:",toarray"`
+	Num uint64
+	Den uint64
+}
+
+/*
+*
+
+	MarshalCBOR encodes the fraction as tag 30 [num, den].
+
+	Returns:
+		[]byte: The CBOR encoding.
+		error: An error if the encoding fails.
+*/
+func (r Rational) MarshalCBOR() ([]byte, error) {
+	den := r.Den
+	if den == 0 {
+		den = 1
+	}
+	return cbor.Marshal(cbor.Tag{Number: RATIONAL_TAG, Content: cborRational{Num: r.Num, Den: den}})
+}
+
+/*
+*
+
+	UnmarshalCBOR decodes a fraction encoded as tag 30 [num, den].
+
+	Params:
+		data ([]byte): The CBOR encoding.
+
+	Returns:
+		error: An error if the data is not a valid fraction.
+*/
+func (r *Rational) UnmarshalCBOR(data []byte) error {
+	var tag cbor.RawTag
+	err := cbor.Unmarshal(data, &tag)
+	if err != nil {
+		return err
+	}
+	if tag.Number != RATIONAL_TAG {
+		return fmt.Errorf("invalid rational tag %d", tag.Number)
+	}
+	var value cborRational
+	err = cbor.Unmarshal(tag.Content, &value)
+	if err != nil {
+		return err
+	}
+	if value.Den == 0 {
+		return errors.New("rational with zero denominator")
+	}
+	*r = Rational{Num: value.Num, Den: value.Den}
+	return nil
+}
@@ -0,0 +1,98 @@
+package Rational_test
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/Salvionied/apollo/serialization/Rational"
+	"github.com/Salvionied/cbor/v2"
+)
+
+func TestParse(t *testing.T) {
+	testCases := map[string]Rational.Rational{
+		"577/10000": {Num: 577, Den: 10000},
+		"0.0577":    {Num: 577, Den: 10000},
+		"7.21e-05":  {Num: 721, Den: 10000000},
+		"0.3":       {Num: 3, Den: 10},
+		"2/10":      {Num: 1, Den: 5},
+		"0":         {Num: 0, Den: 1},
+	}
+	for input, expected := range testCases {
+		res, err := Rational.Parse(input)
+		if err != nil {
+			t.Error(input, err)
+			continue
+		}
+		if res != expected {
+			t.Error("Invalid rational for", input, res)
+		}
+	}
+	for _, input := range []string{"-1/2", "abc", "1/0"} {
+		if _, err := Rational.Parse(input); err == nil {
+			t.Error("Expected error for", input)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var params struct {
+		PriceMem  Rational.Rational `json:"price_mem"`
+		PriceStep Rational.Rational `json:"price_step"`
+		A0        Rational.Rational `json:"a0"`
+		Rho       Rational.Rational `json:"rho"`
+		Tau       Rational.Rational `json:"tau"`
+	}
+	err := json.Unmarshal([]byte(`{"price_mem": 0.0577, "price_step": 7.21e-05, "a0": "3/10", "rho": {"numerator": 3, "denominator": 1000}, "tau": null}`), &params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.PriceMem != Rational.New(577, 10000) || params.PriceStep != Rational.New(721, 10000000) ||
+		params.A0 != Rational.New(3, 10) || params.Rho != Rational.New(3, 1000) || !params.Tau.IsZero() {
+		t.Error("Invalid decoded params", params)
+	}
+	marshaled, err := json.Marshal(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(marshaled) != `{"price_mem":0.0577,"price_step":0.0000721,"a0":0.3,"rho":0.003,"tau":0}` {
+		t.Error("Invalid json", string(marshaled))
+	}
+	third, _ := json.Marshal(Rational.New(1, 3))
+	if string(third) != `"1/3"` {
+		t.Error("Invalid json for 1/3", string(third))
+	}
+}
+
+func TestCBOR(t *testing.T) {
+	value := Rational.New(577, 10000)
+	encoded, err := cbor.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(encoded) != "d81e82190241192710" {
+		t.Error("Invalid cbor", hex.EncodeToString(encoded))
+	}
+	decoded := Rational.Rational{}
+	err = cbor.Unmarshal(encoded, &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded != value {
+		t.Error("Invalid decoded value", decoded)
+	}
+}
+
+func TestCeil(t *testing.T) {
+	price := Rational.New(721, 10000000)
+	if Rational.Ceil(price.Mul(10000000)) != 721 {
+		t.Error("Exact products must not be rounded")
+	}
+	if Rational.Ceil(price.Mul(1)) != 1 {
+		t.Error("Fractions must be rounded up")
+	}
+	if Rational.Ceil(big.NewRat(0, 1)) != 0 {
+		t.Error("Invalid ceil of zero")
+	}
+}
@@ -1,6 +1,10 @@
 package Redeemer
 
-import "github.com/Salvionied/apollo/serialization/PlutusData"
+import (
+	"github.com/Salvionied/apollo/serialization"
+	"github.com/Salvionied/apollo/serialization/PlutusData"
+	"github.com/Salvionied/cbor/v2"
+)
 
 type RedeemerTag int
 
@@ -9,6 +13,8 @@ const (
 	MINT
 	CERT
 	REWARD
+	VOTING
+	PROPOSING
 )
 
 // See https://ogmios.dev/mini-protocols/local-tx-submission/#evaluatetx
@@ -17,6 +23,8 @@ var RdeemerTagNames = map[RedeemerTag]string{
 	1: "mint",
 	2: "certificate",
 	3: "withdrawal",
+	4: "vote",
+	5: "propose",
 }
 
 type ExecutionUnits struct {
@@ -56,6 +64,15 @@ func (eu *ExecutionUnits) Sum(other ExecutionUnits) {
 
 // TODO
 type Redeemer struct {
+	_        struct{} `cbor:",toarray"`
+	Tag      RedeemerTag
+	Index    int
+	Data     PlutusData.PlutusData
+	ExUnits  ExecutionUnits
+	original *serialization.OriginalCbor
+}
+
+type cborRedeemer struct {
 	_       struct{} `cbor:",toarray"`
 	Tag     RedeemerTag
 	Index   int
@@ -73,9 +90,69 @@ type Redeemer struct {
 */
 func (r Redeemer) Clone() Redeemer {
 	return Redeemer{
+		Tag:      r.Tag,
+		Index:    r.Index,
+		Data:     r.Data.Clone(),
+		ExUnits:  r.ExUnits.Clone(),
+		original: r.original,
+	}
+}
+
+/*
+*
+
+	MarshalCBOR encodes the Redeemer into CBOR.
+	A decoded Redeemer that was not modified is encoded
+	with the exact bytes it was decoded from.
+
+	Returns:
+		[]byte: The CBOR-encoded Redeemer.
+		error: An error if the encoding fails.
+*/
+func (r *Redeemer) MarshalCBOR() ([]byte, error) {
+	encoded, err := r.encode()
+	if err != nil {
+		return nil, err
+	}
+	return r.original.Resolve(encoded), nil
+}
+
+/*
+*
+
+	UnmarshalCBOR decodes a Redeemer and keeps the
+	original bytes around for re-encoding.
+
+	Params:
+		value ([]byte): The CBOR-encoded Redeemer.
+
+	Returns:
+		error: An error if the decoding fails.
+*/
+func (r *Redeemer) UnmarshalCBOR(value []byte) error {
+	cborRed := cborRedeemer{}
+	err := cbor.Unmarshal(value, &cborRed)
+	if err != nil {
+		return err
+	}
+	*r = Redeemer{
+		Tag:     cborRed.Tag,
+		Index:   cborRed.Index,
+		Data:    cborRed.Data,
+		ExUnits: cborRed.ExUnits,
+	}
+	encoded, err := r.encode()
+	if err == nil {
+		r.original = serialization.NewOriginalCbor(value, encoded)
+	}
+	return nil
+}
+
+func (r *Redeemer) encode() ([]byte, error) {
+	return cbor.Marshal(cborRedeemer{
 		Tag:     r.Tag,
 		Index:   r.Index,
-		Data:    r.Data.Clone(),
-		ExUnits: r.ExUnits.Clone(),
-	}
+		Data:    r.Data,
+		ExUnits: r.ExUnits,
+	})
 }
@@ -65,3 +65,29 @@ func TestMarshalUnmarshalRedeemer(t *testing.T) {
 		t.Error("Invalid unmarshaling", red2.Tag, red2.Index, red2.Data, red2.ExUnits, "Expected", red.Tag, red.Index, red.Data, red.ExUnits)
 	}
 }
+
+func TestRedeemerKeepsOriginalCbor(t *testing.T) {
+	original := "840000d8799f1800ff821864186e"
+	decoded, _ := hex.DecodeString(original)
+	var red Redeemer.Redeemer
+	err := cbor.Unmarshal(decoded, &red)
+	if err != nil {
+		t.Fatal("Unmarshal failed", err)
+	}
+	marshaled, _ := cbor.Marshal([]Redeemer.Redeemer{red})
+	if hex.EncodeToString(marshaled) != "81"+original {
+		t.Error("Invalid marshaling", hex.EncodeToString(marshaled), "Expected", "81"+original)
+	}
+	red.ExUnits.Mem = 1
+	marshaled, _ = cbor.Marshal(&red)
+	if hex.EncodeToString(marshaled) != "840000d8799f1800ff8201186e" {
+		t.Error("Invalid marshaling", hex.EncodeToString(marshaled), "Expected", "840000d8799f1800ff8201186e")
+	}
+}
+
+func TestRedeemerIsComparable(t *testing.T) {
+	red := Redeemer.Redeemer{Tag: Redeemer.SPEND, Index: 1, ExUnits: Redeemer.ExecutionUnits{Mem: 1, Steps: 2}}
+	if red != (Redeemer.Redeemer{Tag: Redeemer.SPEND, Index: 1, ExUnits: Redeemer.ExecutionUnits{Mem: 1, Steps: 2}}) {
+		t.Error("equal redeemers compare different")
+	}
+}
@@ -1,9 +1,11 @@
 package Transaction
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/Salvionied/apollo/serialization"
+	"github.com/Salvionied/apollo/serialization/Era"
 	"github.com/Salvionied/apollo/serialization/Metadata"
 	"github.com/Salvionied/apollo/serialization/TransactionBody"
 	"github.com/Salvionied/apollo/serialization/TransactionWitnessSet"
@@ -16,8 +18,23 @@ type Transaction struct {
 	TransactionWitnessSet TransactionWitnessSet.TransactionWitnessSet
 	Valid                 bool
 	AuxiliaryData         *Metadata.AuxiliaryData
+	Era                   Era.Era `cbor:"-"`
 }
 
+type shelleyTransaction struct {
+	_                     struct{} `cbor:",toarray"`
+	TransactionBody       *TransactionBody.TransactionBody
+	TransactionWitnessSet *TransactionWitnessSet.TransactionWitnessSet
+	AuxiliaryData         *Metadata.AuxiliaryData
+}
+
+type alonzoTransaction struct {
+	_                     struct{} `cbor:",toarray"`
+	TransactionBody       *TransactionBody.TransactionBody
+	TransactionWitnessSet *TransactionWitnessSet.TransactionWitnessSet
+	Valid                 bool
+	AuxiliaryData         *Metadata.AuxiliaryData
+}
 
 /**
 	Bytes returns the CBOR-encoded byte representation
@@ -45,3 +62,149 @@ func (tx *Transaction) Id() serialization.TransactionId {
 	txId, _ := tx.TransactionBody.Id()
 	return txId
 }
+
+/**
+	MarshalCBOR encodes the Transaction. Shelley, Allegra and Mary
+	transactions are encoded without the validity flag, every
+	other era (including unknown) uses the Alonzo layout.
+
+	Returns:
+		[]byte: The CBOR-encoded transaction.
+		error: An error if the encoding fails.
+*/
+func (tx *Transaction) MarshalCBOR() ([]byte, error) {
+	if tx.Era >= Era.SHELLEY && !tx.Era.HasPlutus() {
+		return cbor.Marshal(shelleyTransaction{
+			TransactionBody:       &tx.TransactionBody,
+			TransactionWitnessSet: &tx.TransactionWitnessSet,
+			AuxiliaryData:         tx.AuxiliaryData,
+		})
+	}
+	return cbor.Marshal(alonzoTransaction{
+		TransactionBody:       &tx.TransactionBody,
+		TransactionWitnessSet: &tx.TransactionWitnessSet,
+		Valid:                 tx.Valid,
+		AuxiliaryData:         tx.AuxiliaryData,
+	})
+}
+
+/**
+	UnmarshalCBOR decodes a transaction of any era from Shelley
+	onwards. Since the encoding does not carry the era, Era is set
+	to the earliest era able to contain the transaction.
+
+	Params:
+		value ([]byte): The CBOR-encoded transaction.
+
+	Returns:
+		error: An error if the decoding fails.
+*/
+func (tx *Transaction) UnmarshalCBOR(value []byte) error {
+	var items []cbor.RawMessage
+	err := cbor.Unmarshal(value, &items)
+	if err != nil {
+		return err
+	}
+	decoded := Transaction{Valid: true}
+	if len(items) != 3 && len(items) != 4 {
+		return fmt.Errorf("invalid transaction, expected 3 or 4 elements got %d", len(items))
+	}
+	err = cbor.Unmarshal(items[0], &decoded.TransactionBody)
+	if err != nil {
+		return err
+	}
+	err = cbor.Unmarshal(items[1], &decoded.TransactionWitnessSet)
+	if err != nil {
+		return err
+	}
+	if len(items) == 4 {
+		err = cbor.Unmarshal(items[2], &decoded.Valid)
+		if err != nil {
+			return err
+		}
+	}
+	err = cbor.Unmarshal(items[len(items)-1], &decoded.AuxiliaryData)
+	if err != nil {
+		return err
+	}
+	decoded.Era, err = InferEra(items[0], items[1], len(items) == 4)
+	if err != nil {
+		return err
+	}
+	*tx = decoded
+	return nil
+}
+
+/**
+	InferEra returns the earliest era able to contain a transaction
+	with the given body and witness set.
+
+	Params:
+		body ([]byte): The CBOR-encoded transaction body.
+		witnessSet ([]byte): The CBOR-encoded witness set.
+		hasValidity (bool): Whether the transaction uses the Alonzo layout.
+
+	Returns:
+		Era.Era: The inferred era.
+		error: An error if the body or the witness set are not maps.
+*/
+func InferEra(body []byte, witnessSet []byte, hasValidity bool) (Era.Era, error) {
+	var bodyKeys map[uint64]cbor.RawMessage
+	err := cbor.Unmarshal(body, &bodyKeys)
+	if err != nil {
+		return Era.UNKNOWN, err
+	}
+	var witnessKeys map[uint64]cbor.RawMessage
+	err = cbor.Unmarshal(witnessSet, &witnessKeys)
+	if err != nil {
+		return Era.UNKNOWN, err
+	}
+	if !hasValidity {
+		if _, ok := bodyKeys[9]; ok {
+			return Era.MARY, nil
+		}
+		_, hasTtl := bodyKeys[3]
+		if _, ok := bodyKeys[8]; ok || !hasTtl {
+			return Era.ALLEGRA, nil
+		}
+		return Era.SHELLEY, nil
+	}
+	for key := uint64(19); key <= 22; key++ {
+		if _, ok := bodyKeys[key]; ok {
+			return Era.CONWAY, nil
+		}
+	}
+	if _, ok := witnessKeys[7]; ok {
+		return Era.CONWAY, nil
+	}
+	if isSet(bodyKeys[0]) {
+		return Era.CONWAY, nil
+	}
+	if redeemers, ok := witnessKeys[5]; ok && len(redeemers) > 0 && redeemers[0]>>5 == 5 {
+		return Era.CONWAY, nil
+	}
+	for _, key := range []uint64{16, 17, 18} {
+		if _, ok := bodyKeys[key]; ok {
+			return Era.BABBAGE, nil
+		}
+	}
+	if _, ok := witnessKeys[6]; ok {
+		return Era.BABBAGE, nil
+	}
+	var outputs []cbor.RawMessage
+	err = cbor.Unmarshal(bodyKeys[1], &outputs)
+	if err != nil {
+		return Era.UNKNOWN, errors.New("invalid transaction outputs")
+	}
+	for _, output := range outputs {
+		if len(output) > 0 && output[0]>>5 == 5 {
+			return Era.BABBAGE, nil
+		}
+	}
+	return Era.ALONZO, nil
+}
+
+func isSet(value cbor.RawMessage) bool {
+	var tag cbor.RawTag
+	return len(value) > 0 && value[0]>>5 == 6 && cbor.Unmarshal(value, &tag) == nil && tag.Number == 258
+}
@@ -0,0 +1,350 @@
+package Transaction
+
+import (
+	"encoding/hex"
+	"sort"
+
+	"github.com/Salvionied/apollo/crypto/ed25519"
+	"github.com/Salvionied/apollo/serialization/Address"
+	"github.com/Salvionied/apollo/serialization/Certificate"
+	"github.com/Salvionied/apollo/serialization/Key"
+	"github.com/Salvionied/apollo/serialization/NativeScript"
+	"github.com/Salvionied/apollo/serialization/TransactionInput"
+	"github.com/Salvionied/apollo/serialization/UTxO"
+	"github.com/Salvionied/cbor/v2"
+)
+
+/*
+*
+
+	WitnessReport is the result of VerifyWitnesses. Key hashes
+	are hex encoded and sorted.
+*/
+type WitnessReport struct {
+	// Valid are the key hashes of the witnesses with a valid signature.
+	Valid []string
+	// InvalidSignatures are the key hashes of the witnesses whose
+	// signature does not match the body.
+	InvalidSignatures []string
+	// Missing are the key hashes required by the body without a
+	// valid witness.
+	Missing []string
+	// Extraneous are the key hashes of witnesses neither required
+	// by the body nor used by a native script.
+	Extraneous []string
+	// UnresolvedInputs are the inputs whose UTxO was not provided,
+	// so their required witness could not be checked.
+	UnresolvedInputs []string
+	// UnsatisfiedScripts are the hashes of the native scripts of the
+	// witness set not satisfied by the witnesses and validity interval.
+	UnsatisfiedScripts []string
+}
+
+/*
+*
+
+	Ok reports whether the witnesses are complete and valid.
+
+	Returns:
+		bool: true if no signature is invalid, no witness is missing,
+			every input was resolved and every native script is satisfied.
+*/
+func (r WitnessReport) Ok() bool {
+	return len(r.InvalidSignatures) == 0 && len(r.Missing) == 0 &&
+		len(r.UnresolvedInputs) == 0 && len(r.UnsatisfiedScripts) == 0
+}
+
+// BATCH_VERIFY_THRESHOLD is the number of vkey witnesses from
+// which VerifySignatures checks them with one batch first.
+const BATCH_VERIFY_THRESHOLD = 8
+
+type hashSet map[string]bool
+
+func (s hashSet) add(hash []byte) {
+	if len(hash) == 28 {
+		s[hex.EncodeToString(hash)] = true
+	}
+}
+
+func (s hashSet) sorted() []string {
+	res := make([]string, 0, len(s))
+	for hash := range s {
+		res = append(res, hash)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func isKeyPayment(address Address.Address) bool {
+	switch address.AddressType {
+	case Address.KEY_KEY, Address.KEY_SCRIPT, Address.KEY_POINTER, Address.KEY_NONE:
+		return true
+	}
+	return false
+}
+
+/*
+*
+
+	RequiredKeyHashes returns the key hashes whose witness the body
+	requires: key locked inputs and collateral, required signers,
+	certificates and reward withdrawals.
+
+	Params:
+		utxos ([]UTxO.UTxO): The UTxOs spent by the transaction.
+
+	Returns:
+		[]string: The required key hashes, hex encoded and sorted.
+		[]string: The inputs missing from utxos.
+*/
+func (tx *Transaction) RequiredKeyHashes(utxos []UTxO.UTxO) ([]string, []string) {
+	required, unresolved := tx.requiredKeyHashes(utxos)
+	return required.sorted(), unresolved.sorted()
+}
+
+func (tx *Transaction) requiredKeyHashes(utxos []UTxO.UTxO) (hashSet, hashSet) {
+	required := hashSet{}
+	unresolved := hashSet{}
+	resolved := make(map[string]UTxO.UTxO, len(utxos))
+	for _, utxo := range utxos {
+		resolved[utxo.GetKey()] = utxo
+	}
+	body := tx.TransactionBody
+	inputs := append(append([]TransactionInput.TransactionInput{}, body.Inputs...), body.Collateral...)
+	for _, input := range inputs {
+		utxo, ok := resolved[UTxO.UTxO{Input: input}.GetKey()]
+		if !ok {
+			unresolved[UTxO.UTxO{Input: input}.GetKey()] = true
+			continue
+		}
+		address := utxo.Output.GetAddress()
+		if isKeyPayment(address) {
+			required.add(address.PaymentPart)
+		}
+	}
+	for _, signer := range body.RequiredSigners {
+		required.add(signer[:])
+	}
+	if body.Certificates != nil {
+		for _, certificate := range *body.Certificates {
+			certificateKeyHashes(certificate, required)
+		}
+	}
+	if body.Withdrawals != nil {
+		for rewardAddress := range *body.Withdrawals {
+			// the header of key reward addresses is 0b1110xxxx
+			if rewardAddress[0]>>4 == Address.NONE_KEY {
+				required.add(rewardAddress[1:])
+			}
+		}
+	}
+	return required, unresolved
+}
+
+func certificateKeyHashes(certificate *Certificate.Certificate, required hashSet) {
+	if certificate == nil {
+		return
+	}
+	switch certificate.Kind {
+	case Certificate.STAKE_REGISTRATION:
+		// the legacy registration certificate needs no witness
+		return
+	case Certificate.POOL_REGISTRATION:
+		if len(certificate.Fields) > 6 {
+			var operator []byte
+			if cbor.Unmarshal(certificate.Fields[0], &operator) == nil {
+				required.add(operator)
+			}
+			var owners [][]byte
+			if cbor.Unmarshal(certificate.Fields[6], &owners) == nil {
+				for _, owner := range owners {
+					required.add(owner)
+				}
+			}
+		}
+	case Certificate.POOL_RETIREMENT:
+		if len(certificate.Fields) > 0 {
+			var operator []byte
+			if cbor.Unmarshal(certificate.Fields[0], &operator) == nil {
+				required.add(operator)
+			}
+		}
+	default:
+		if certificate.StakeCredential != nil && certificate.StakeCredential.Code == 0 {
+			required.add(certificate.StakeCredential.Credential.Payload)
+		}
+	}
+}
+
+func nativeScriptKeyHashes(script NativeScript.NativeScript, keys hashSet) {
+	if script.Tag == NativeScript.ScriptPubKey {
+		keys.add(script.KeyHash)
+	}
+	for _, inner := range script.NativeScripts {
+		nativeScriptKeyHashes(inner, keys)
+	}
+}
+
+func (tx *Transaction) satisfies(script NativeScript.NativeScript, signed hashSet) bool {
+	switch script.Tag {
+	case NativeScript.ScriptPubKey:
+		return signed[hex.EncodeToString(script.KeyHash)]
+	case NativeScript.ScriptAll:
+		for _, inner := range script.NativeScripts {
+			if !tx.satisfies(inner, signed) {
+				return false
+			}
+		}
+		return true
+	case NativeScript.ScriptAny:
+		for _, inner := range script.NativeScripts {
+			if tx.satisfies(inner, signed) {
+				return true
+			}
+		}
+		return false
+	case NativeScript.ScriptNofK:
+		count := 0
+		for _, inner := range script.NativeScripts {
+			if tx.satisfies(inner, signed) {
+				count++
+			}
+		}
+		return count >= script.NoK
+	case NativeScript.InvalidBefore:
+		return tx.TransactionBody.ValidityStart != 0 && tx.TransactionBody.ValidityStart >= script.Before
+	case NativeScript.InvalidHereafter:
+		return tx.TransactionBody.Ttl != 0 && tx.TransactionBody.Ttl <= script.After
+	}
+	return false
+}
+
+/*
+*
+
+	VerifyWitnesses checks the vkey witnesses of the transaction:
+	every signature against the body hash, the witnesses required
+	by the body, the native scripts of the witness set and the
+	witnesses nothing asks for. Each signature is checked with the
+	cofactorless ed25519.Verify, as the ledger does: the cofactored
+	batch equation accepts signatures with small-order components
+	that the ledger rejects, so it cannot mark a witness valid.
+	VerifySignatures answers faster whether every signature is
+	valid, without telling which ones are not.
+
+	Params:
+		utxos ([]UTxO.UTxO): The UTxOs spent by the transaction,
+			used to find the keys locking its inputs.
+
+	Returns:
+		WitnessReport: The result of the verification.
+		error: An error if the body cannot be hashed.
+*/
+func (tx *Transaction) VerifyWitnesses(utxos []UTxO.UTxO) (WitnessReport, error) {
+	txHash, err := tx.TransactionBody.Hash()
+	if err != nil {
+		return WitnessReport{}, err
+	}
+	witnesses := tx.TransactionWitnessSet.VkeyWitnesses
+	valid := make([]bool, len(witnesses))
+	for i, witness := range witnesses {
+		valid[i] = len(witness.Vkey.Payload) == ed25519.PublicKeySize &&
+			ed25519.Verify(witness.Vkey.Payload, txHash, witness.Signature)
+	}
+
+	signed := hashSet{}
+	invalid := hashSet{}
+	witnessed := hashSet{}
+	for i, witness := range witnesses {
+		vkh, err := Key.VerificationKey{Payload: witness.Vkey.Payload}.Hash()
+		if err != nil {
+			continue
+		}
+		witnessed.add(vkh[:])
+		if valid[i] {
+			signed.add(vkh[:])
+		} else {
+			invalid.add(vkh[:])
+		}
+	}
+
+	required, unresolved := tx.requiredKeyHashes(utxos)
+	missing := hashSet{}
+	for hash := range required {
+		if !signed[hash] {
+			missing[hash] = true
+		}
+	}
+
+	scriptKeys := hashSet{}
+	unsatisfied := hashSet{}
+	for _, script := range tx.TransactionWitnessSet.NativeScripts {
+		nativeScriptKeyHashes(script, scriptKeys)
+		if !tx.satisfies(script, signed) {
+			hash, err := script.Hash()
+			if err == nil {
+				unsatisfied.add(hash.Bytes())
+			}
+		}
+	}
+	extraneous := hashSet{}
+	for hash := range witnessed {
+		if !required[hash] && !scriptKeys[hash] {
+			extraneous[hash] = true
+		}
+	}
+	return WitnessReport{
+		Valid:              signed.sorted(),
+		InvalidSignatures:  invalid.sorted(),
+		Missing:            missing.sorted(),
+		Extraneous:         extraneous.sorted(),
+		UnresolvedInputs:   unresolved.sorted(),
+		UnsatisfiedScripts: unsatisfied.sorted(),
+	}, nil
+}
+
+/*
+*
+
+	VerifySignatures reports whether every vkey witness signs the
+	body. From BATCH_VERIFY_THRESHOLD witnesses they are first
+	checked with one ed25519.VerifyBatch, whose failure rejects the
+	transaction at once. A passing batch is not conclusive, since
+	its equation is cofactored, and each signature is then
+	confirmed with the cofactorless ed25519.Verify.
+
+	Returns:
+		bool: true if every signature is valid.
+		error: An error if the body cannot be hashed.
+*/
+func (tx *Transaction) VerifySignatures() (bool, error) {
+	txHash, err := tx.TransactionBody.Hash()
+	if err != nil {
+		return false, err
+	}
+	witnesses := tx.TransactionWitnessSet.VkeyWitnesses
+	for _, witness := range witnesses {
+		if len(witness.Vkey.Payload) != ed25519.PublicKeySize {
+			return false, nil
+		}
+	}
+	if len(witnesses) >= BATCH_VERIFY_THRESHOLD {
+		publicKeys := make([]ed25519.PublicKey, len(witnesses))
+		messages := make([][]byte, len(witnesses))
+		signatures := make([][]byte, len(witnesses))
+		for i, witness := range witnesses {
+			publicKeys[i] = witness.Vkey.Payload
+			messages[i] = txHash
+			signatures[i] = witness.Signature
+		}
+		if !ed25519.VerifyBatch(publicKeys, messages, signatures) {
+			return false, nil
+		}
+	}
+	for _, witness := range witnesses {
+		if !ed25519.Verify(witness.Vkey.Payload, txHash, witness.Signature) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
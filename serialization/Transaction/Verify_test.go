@@ -0,0 +1,236 @@
+package Transaction_test
+
+import (
+	stded25519 "crypto/ed25519"
+	"encoding/hex"
+	"testing"
+
+	"github.com/Salvionied/apollo/constants"
+	"github.com/Salvionied/apollo/serialization"
+	"github.com/Salvionied/apollo/serialization/Address"
+	"github.com/Salvionied/apollo/serialization/Key"
+	"github.com/Salvionied/apollo/serialization/NativeScript"
+	"github.com/Salvionied/apollo/serialization/Transaction"
+	"github.com/Salvionied/apollo/serialization/TransactionBody"
+	"github.com/Salvionied/apollo/serialization/TransactionInput"
+	"github.com/Salvionied/apollo/serialization/TransactionOutput"
+	"github.com/Salvionied/apollo/serialization/TransactionWitnessSet"
+	"github.com/Salvionied/apollo/serialization/UTxO"
+	"github.com/Salvionied/apollo/serialization/Value"
+	"github.com/Salvionied/apollo/serialization/VerificationKeyWitness"
+)
+
+type testKey struct {
+	sk   Key.SigningKey
+	vk   Key.VerificationKey
+	hash serialization.PubKeyHash
+}
+
+func newTestKey(t *testing.T, seed byte) testKey {
+	private := stded25519.NewKeyFromSeed(append([]byte{seed}, make([]byte, 31)...))
+	vk := Key.VerificationKey{Payload: private.Public().(stded25519.PublicKey)}
+	hash, err := vk.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testKey{sk: Key.SigningKey{Payload: private}, vk: vk, hash: hash}
+}
+
+func (k testKey) hex() string {
+	return hex.EncodeToString(k.hash[:])
+}
+
+func (k testKey) witness(t *testing.T, tx *Transaction.Transaction) VerificationKeyWitness.VerificationKeyWitness {
+	txHash, err := tx.TransactionBody.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := k.sk.Sign(txHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return VerificationKeyWitness.VerificationKeyWitness{Vkey: k.vk, Signature: signature}
+}
+
+func keyUtxo(k testKey, index int) UTxO.UTxO {
+	address := Address.WalletAddressFromBytes(k.hash[:], nil, constants.TESTNET)
+	return UTxO.UTxO{
+		Input: TransactionInput.TransactionInput{
+			TransactionId: make([]byte, 32),
+			Index:         index,
+		},
+		Output: TransactionOutput.SimpleTransactionOutput(*address, Value.PureLovelaceValue(5_000_000)),
+	}
+}
+
+func equalHashes(got []string, expected ...string) bool {
+	if len(got) != len(expected) {
+		return false
+	}
+	set := map[string]bool{}
+	for _, hash := range expected {
+		set[hash] = true
+	}
+	for _, hash := range got {
+		if !set[hash] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestVerifyWitnesses(t *testing.T) {
+	keys := make([]testKey, 6)
+	for i := range keys {
+		keys[i] = newTestKey(t, byte(i+1))
+	}
+	utxos := []UTxO.UTxO{keyUtxo(keys[0], 0), keyUtxo(keys[1], 1)}
+	script := NativeScript.NewScriptAll([]NativeScript.NativeScript{
+		NativeScript.NewScriptPubKey(keys[3].hash[:]),
+		NativeScript.NewInvalidHereafter(1000),
+	})
+	tx := Transaction.Transaction{
+		TransactionBody: TransactionBody.TransactionBody{
+			Inputs:          []TransactionInput.TransactionInput{utxos[0].Input, utxos[1].Input},
+			Fee:             200_000,
+			Ttl:             900,
+			RequiredSigners: []serialization.PubKeyHash{keys[2].hash},
+		},
+		TransactionWitnessSet: TransactionWitnessSet.TransactionWitnessSet{
+			NativeScripts: []NativeScript.NativeScript{script},
+		},
+	}
+	for _, k := range keys[:5] {
+		tx.TransactionWitnessSet.VkeyWitnesses = append(tx.TransactionWitnessSet.VkeyWitnesses, k.witness(t, &tx))
+	}
+
+	report, err := tx.VerifyWitnesses(utxos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Ok() {
+		t.Errorf("expected a valid report, got %+v", report)
+	}
+	if !equalHashes(report.Valid, keys[0].hex(), keys[1].hex(), keys[2].hex(), keys[3].hex(), keys[4].hex()) {
+		t.Errorf("unexpected valid witnesses %v", report.Valid)
+	}
+	if !equalHashes(report.Extraneous, keys[4].hex()) {
+		t.Errorf("expected %s to be extraneous, got %v", keys[4].hex(), report.Extraneous)
+	}
+
+	// tamper with a signature and drop the required signer
+	tx.TransactionWitnessSet.VkeyWitnesses[0].Signature[0] ^= 0xff
+	tx.TransactionWitnessSet.VkeyWitnesses = append(
+		tx.TransactionWitnessSet.VkeyWitnesses[:2],
+		tx.TransactionWitnessSet.VkeyWitnesses[3:]...,
+	)
+	report, err = tx.VerifyWitnesses(utxos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Ok() {
+		t.Error("expected an invalid report")
+	}
+	if !equalHashes(report.InvalidSignatures, keys[0].hex()) {
+		t.Errorf("expected %s to be invalid, got %v", keys[0].hex(), report.InvalidSignatures)
+	}
+	if !equalHashes(report.Missing, keys[0].hex(), keys[2].hex()) {
+		t.Errorf("unexpected missing witnesses %v", report.Missing)
+	}
+}
+
+func TestVerifyWitnessesScriptsAndInputs(t *testing.T) {
+	keys := []testKey{newTestKey(t, 1), newTestKey(t, 2)}
+	utxo := keyUtxo(keys[0], 0)
+	unknown := TransactionInput.TransactionInput{TransactionId: make([]byte, 32), Index: 7}
+	script := NativeScript.NewScriptAll([]NativeScript.NativeScript{
+		NativeScript.NewScriptPubKey(keys[1].hash[:]),
+		NativeScript.NewInvalidHereafter(1000),
+	})
+	tx := Transaction.Transaction{
+		TransactionBody: TransactionBody.TransactionBody{
+			Inputs: []TransactionInput.TransactionInput{utxo.Input, unknown},
+			Fee:    200_000,
+			Ttl:    2000,
+		},
+		TransactionWitnessSet: TransactionWitnessSet.TransactionWitnessSet{
+			NativeScripts: []NativeScript.NativeScript{script},
+		},
+	}
+	tx.TransactionWitnessSet.VkeyWitnesses = append(tx.TransactionWitnessSet.VkeyWitnesses,
+		keys[0].witness(t, &tx), keys[1].witness(t, &tx))
+
+	report, err := tx.VerifyWitnesses([]UTxO.UTxO{utxo})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.UnresolvedInputs) != 1 {
+		t.Errorf("expected one unresolved input, got %v", report.UnresolvedInputs)
+	}
+	scriptHash, _ := script.Hash()
+	if !equalHashes(report.UnsatisfiedScripts, hex.EncodeToString(scriptHash.Bytes())) {
+		t.Errorf("expected the script to be unsatisfied past its ttl, got %v", report.UnsatisfiedScripts)
+	}
+	if len(report.Extraneous) != 0 || len(report.Missing) != 0 {
+		t.Errorf("unexpected report %+v", report)
+	}
+}
+
+func TestVerifyWitnessesSmallOrder(t *testing.T) {
+	keys := make([]testKey, 4)
+	for i := range keys {
+		keys[i] = newTestKey(t, byte(i+1))
+	}
+	tx := Transaction.Transaction{TransactionBody: TransactionBody.TransactionBody{Fee: 200_000}}
+	for _, k := range keys {
+		tx.TransactionWitnessSet.VkeyWitnesses = append(tx.TransactionWitnessSet.VkeyWitnesses, k.witness(t, &tx))
+	}
+	// the identity as key and a point of order 8 as R with S = 0
+	// satisfy the cofactored batch equation but not Verify
+	identity, _ := hex.DecodeString("0100000000000000000000000000000000000000000000000000000000000000")
+	smallOrder, _ := hex.DecodeString("c7176a703d4dd84fba3c0b760d10670f2a2053fa2c39ccc64ec7fd7792ac037a")
+	signature := append(smallOrder, make([]byte, 32)...)
+	tx.TransactionWitnessSet.VkeyWitnesses = append(tx.TransactionWitnessSet.VkeyWitnesses,
+		VerificationKeyWitness.VerificationKeyWitness{Vkey: Key.VerificationKey{Payload: identity}, Signature: signature})
+
+	report, err := tx.VerifyWitnesses(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, _ := Key.VerificationKey{Payload: identity}.Hash()
+	if !equalHashes(report.InvalidSignatures, hex.EncodeToString(forged[:])) {
+		t.Errorf("expected the small order signature to be invalid, got %v", report.InvalidSignatures)
+	}
+	if len(report.Valid) != len(keys) {
+		t.Errorf("unexpected valid witnesses %v", report.Valid)
+	}
+}
+
+func TestVerifySignatures(t *testing.T) {
+	tx := Transaction.Transaction{TransactionBody: TransactionBody.TransactionBody{Fee: 200_000}}
+	for i := 0; i < Transaction.BATCH_VERIFY_THRESHOLD+2; i++ {
+		tx.TransactionWitnessSet.VkeyWitnesses = append(tx.TransactionWitnessSet.VkeyWitnesses, newTestKey(t, byte(i+1)).witness(t, &tx))
+	}
+	if ok, err := tx.VerifySignatures(); err != nil || !ok {
+		t.Errorf("expected the signatures to be valid, got %v %v", ok, err)
+	}
+
+	witnesses := tx.TransactionWitnessSet.VkeyWitnesses
+	tampered := append([]byte{}, witnesses[3].Signature...)
+	tampered[0] ^= 1
+	tx.TransactionWitnessSet.VkeyWitnesses = append(append([]VerificationKeyWitness.VerificationKeyWitness{}, witnesses[:3]...),
+		VerificationKeyWitness.VerificationKeyWitness{Vkey: witnesses[3].Vkey, Signature: tampered})
+	tx.TransactionWitnessSet.VkeyWitnesses = append(tx.TransactionWitnessSet.VkeyWitnesses, witnesses[4:]...)
+	if ok, _ := tx.VerifySignatures(); ok {
+		t.Error("expected the batch to reject a tampered signature")
+	}
+
+	// passes the cofactored batch, must be rejected by Verify
+	identity, _ := hex.DecodeString("0100000000000000000000000000000000000000000000000000000000000000")
+	smallOrder, _ := hex.DecodeString("c7176a703d4dd84fba3c0b760d10670f2a2053fa2c39ccc64ec7fd7792ac037a")
+	tx.TransactionWitnessSet.VkeyWitnesses = append(append([]VerificationKeyWitness.VerificationKeyWitness{}, witnesses...),
+		VerificationKeyWitness.VerificationKeyWitness{Vkey: Key.VerificationKey{Payload: identity}, Signature: append(smallOrder, make([]byte, 32)...)})
+	if ok, _ := tx.VerifySignatures(); ok {
+		t.Error("expected the small order signature to be rejected")
+	}
+}
@@ -13,43 +13,52 @@ import (
 )
 
 type TransactionBody struct {
-	Inputs            []TransactionInput.TransactionInput   `cbor:"0,keyasint"`
-	Outputs           []TransactionOutput.TransactionOutput `cbor:"1,keyasint"`
-	Fee               int64                                 `cbor:"2,keyasint"`
-	Ttl               int64                                 `cbor:"3,keyasint,omitempty"`
-	Certificates      *Certificate.Certificates             `cbor:"4,keyasint,omitempty"`
-	Withdrawals       *Withdrawal.Withdrawal                `cbor:"5,keyasint,omitempty"`
-	UpdateProposals   []any                                 `cbor:"6,keyasint,omitempty"`
-	AuxiliaryDataHash []byte                                `cbor:"7,keyasint,omitempty"`
-	ValidityStart     int64                                 `cbor:"8,keyasint,omitempty"`
-	Mint              MultiAsset.MultiAsset[int64]          `cbor:"9,keyasint,omitempty"`
-	ScriptDataHash    []byte                                `cbor:"11,keyasint,omitempty"`
-	Collateral        []TransactionInput.TransactionInput   `cbor:"13,keyasint,omitempty"`
-	RequiredSigners   []serialization.PubKeyHash            `cbor:"14,keyasint,omitempty"`
-	NetworkId         []byte                                `cbor:"15,keyasint,omitempty"`
-	CollateralReturn  *TransactionOutput.TransactionOutput  `cbor:"16,keyasint,omitempty"`
-	TotalCollateral   int                                   `cbor:"17,keyasint,omitempty"`
-	ReferenceInputs   []TransactionInput.TransactionInput   `cbor:"18,keyasint,omitempty"`
+	Inputs               []TransactionInput.TransactionInput   `cbor:"0,keyasint"`
+	Outputs              []TransactionOutput.TransactionOutput `cbor:"1,keyasint"`
+	Fee                  int64                                 `cbor:"2,keyasint"`
+	Ttl                  int64                                 `cbor:"3,keyasint,omitempty"`
+	Certificates         *Certificate.Certificates             `cbor:"4,keyasint,omitempty"`
+	Withdrawals          *Withdrawal.Withdrawal                `cbor:"5,keyasint,omitempty"`
+	UpdateProposals      []any                                 `cbor:"6,keyasint,omitempty"`
+	AuxiliaryDataHash    []byte                                `cbor:"7,keyasint,omitempty"`
+	ValidityStart        int64                                 `cbor:"8,keyasint,omitempty"`
+	Mint                 MultiAsset.MultiAsset[int64]          `cbor:"9,keyasint,omitempty"`
+	ScriptDataHash       []byte                                `cbor:"11,keyasint,omitempty"`
+	Collateral           []TransactionInput.TransactionInput   `cbor:"13,keyasint,omitempty"`
+	RequiredSigners      []serialization.PubKeyHash            `cbor:"14,keyasint,omitempty"`
+	NetworkId            []byte                                `cbor:"15,keyasint,omitempty"`
+	CollateralReturn     *TransactionOutput.TransactionOutput  `cbor:"16,keyasint,omitempty"`
+	TotalCollateral      int                                   `cbor:"17,keyasint,omitempty"`
+	ReferenceInputs      []TransactionInput.TransactionInput   `cbor:"18,keyasint,omitempty"`
+	VotingProcedures     cbor.RawMessage                       `cbor:"19,keyasint,omitempty"`
+	ProposalProcedures   cbor.RawMessage                       `cbor:"20,keyasint,omitempty"`
+	CurrentTreasuryValue int64                                 `cbor:"21,keyasint,omitempty"`
+	Donation             int64                                 `cbor:"22,keyasint,omitempty"`
+	original             *serialization.OriginalCbor
 }
 
 type CborBody struct {
-	Inputs            []TransactionInput.TransactionInput   `cbor:"0,keyasint"`
-	Outputs           []TransactionOutput.TransactionOutput `cbor:"1,keyasint"`
-	Fee               int64                                 `cbor:"2,keyasint"`
-	Ttl               int64                                 `cbor:"3,keyasint,omitempty"`
-	Certificates      *Certificate.Certificates             `cbor:"4,keyasint,omitempty"`
-	Withdrawals       *Withdrawal.Withdrawal                `cbor:"5,keyasint,omitempty"`
-	UpdateProposals   []any                                 `cbor:"6,keyasint,omitempty"`
-	AuxiliaryDataHash []byte                                `cbor:"7,keyasint,omitempty"`
-	ValidityStart     int64                                 `cbor:"8,keyasint,omitempty"`
-	Mint              MultiAsset.MultiAsset[int64]          `cbor:"9,keyasint,omitempty"`
-	ScriptDataHash    []byte                                `cbor:"11,keyasint,omitempty"`
-	Collateral        []TransactionInput.TransactionInput   `cbor:"13,keyasint,omitempty"`
-	RequiredSigners   []serialization.PubKeyHash            `cbor:"14,keyasint,omitempty"`
-	NetworkId         []byte                                `cbor:"15,keyasint,omitempty"`
-	CollateralReturn  *TransactionOutput.TransactionOutput  `cbor:"16,keyasint,omitempty"`
-	TotalCollateral   int                                   `cbor:"17,keyasint,omitempty"`
-	ReferenceInputs   []TransactionInput.TransactionInput   `cbor:"18,keyasint,omitempty"`
+	Inputs               []TransactionInput.TransactionInput   `cbor:"0,keyasint"`
+	Outputs              []TransactionOutput.TransactionOutput `cbor:"1,keyasint"`
+	Fee                  int64                                 `cbor:"2,keyasint"`
+	Ttl                  int64                                 `cbor:"3,keyasint,omitempty"`
+	Certificates         *Certificate.Certificates             `cbor:"4,keyasint,omitempty"`
+	Withdrawals          *Withdrawal.Withdrawal                `cbor:"5,keyasint,omitempty"`
+	UpdateProposals      []any                                 `cbor:"6,keyasint,omitempty"`
+	AuxiliaryDataHash    []byte                                `cbor:"7,keyasint,omitempty"`
+	ValidityStart        int64                                 `cbor:"8,keyasint,omitempty"`
+	Mint                 MultiAsset.MultiAsset[int64]          `cbor:"9,keyasint,omitempty"`
+	ScriptDataHash       []byte                                `cbor:"11,keyasint,omitempty"`
+	Collateral           []TransactionInput.TransactionInput   `cbor:"13,keyasint,omitempty"`
+	RequiredSigners      []serialization.PubKeyHash            `cbor:"14,keyasint,omitempty"`
+	NetworkId            []byte                                `cbor:"15,keyasint,omitempty"`
+	CollateralReturn     *TransactionOutput.TransactionOutput  `cbor:"16,keyasint,omitempty"`
+	TotalCollateral      int                                   `cbor:"17,keyasint,omitempty"`
+	ReferenceInputs      []TransactionInput.TransactionInput   `cbor:"18,keyasint,omitempty"`
+	VotingProcedures     cbor.RawMessage                       `cbor:"19,keyasint,omitempty"`
+	ProposalProcedures   cbor.RawMessage                       `cbor:"20,keyasint,omitempty"`
+	CurrentTreasuryValue int64                                 `cbor:"21,keyasint,omitempty"`
+	Donation             int64                                 `cbor:"22,keyasint,omitempty"`
 }
 
 func (tx *TransactionBody) Hash() ([]byte, error) {
@@ -77,25 +86,97 @@ func (tx *TransactionBody) Id() (serialization.TransactionId, error) {
 	return serialization.TransactionId{bytes}, nil
 }
 
+/*
+*
+
+	MarshalCBOR encodes the TransactionBody canonically.
+	A decoded body that was not modified is encoded with the
+	exact bytes it was decoded from, so its hash (and the
+	signatures over it) stay valid.
+
+	Returns:
+		[]byte: The CBOR-encoded body.
+		error: An error if the encoding fails.
+*/
 func (tx *TransactionBody) MarshalCBOR() ([]byte, error) {
+	encoded, err := tx.encode()
+	if err != nil {
+		return nil, err
+	}
+	return tx.original.Resolve(encoded), nil
+}
+
+/*
+*
+
+	UnmarshalCBOR decodes a TransactionBody and keeps the
+	original bytes around for re-encoding.
+
+	Params:
+		value ([]byte): The CBOR-encoded body.
+
+	Returns:
+		error: An error if the decoding fails.
+*/
+func (tx *TransactionBody) UnmarshalCBOR(value []byte) error {
+	cborBody := CborBody{}
+	err := cbor.Unmarshal(value, &cborBody)
+	if err != nil {
+		return err
+	}
+	*tx = TransactionBody{
+		Inputs:               cborBody.Inputs,
+		Outputs:              cborBody.Outputs,
+		Fee:                  cborBody.Fee,
+		Ttl:                  cborBody.Ttl,
+		Certificates:         cborBody.Certificates,
+		Withdrawals:          cborBody.Withdrawals,
+		UpdateProposals:      cborBody.UpdateProposals,
+		AuxiliaryDataHash:    cborBody.AuxiliaryDataHash,
+		ValidityStart:        cborBody.ValidityStart,
+		Mint:                 cborBody.Mint,
+		ScriptDataHash:       cborBody.ScriptDataHash,
+		Collateral:           cborBody.Collateral,
+		RequiredSigners:      cborBody.RequiredSigners,
+		NetworkId:            cborBody.NetworkId,
+		CollateralReturn:     cborBody.CollateralReturn,
+		TotalCollateral:      cborBody.TotalCollateral,
+		ReferenceInputs:      cborBody.ReferenceInputs,
+		VotingProcedures:     cborBody.VotingProcedures,
+		ProposalProcedures:   cborBody.ProposalProcedures,
+		CurrentTreasuryValue: cborBody.CurrentTreasuryValue,
+		Donation:             cborBody.Donation,
+	}
+	encoded, err := tx.encode()
+	if err == nil {
+		tx.original = serialization.NewOriginalCbor(value, encoded)
+	}
+	return nil
+}
+
+func (tx *TransactionBody) encode() ([]byte, error) {
 	cborBody := CborBody{
-		Inputs:            tx.Inputs,
-		Outputs:           tx.Outputs,
-		Fee:               tx.Fee,
-		Ttl:               tx.Ttl,
-		Certificates:      tx.Certificates,
-		Withdrawals:       tx.Withdrawals,
-		UpdateProposals:   tx.UpdateProposals,
-		AuxiliaryDataHash: tx.AuxiliaryDataHash,
-		ValidityStart:     tx.ValidityStart,
-		Mint:              tx.Mint,
-		ScriptDataHash:    tx.ScriptDataHash,
-		Collateral:        tx.Collateral,
-		RequiredSigners:   tx.RequiredSigners,
-		NetworkId:         tx.NetworkId,
-		CollateralReturn:  tx.CollateralReturn,
-		TotalCollateral:   tx.TotalCollateral,
-		ReferenceInputs:   tx.ReferenceInputs,
+		Inputs:               tx.Inputs,
+		Outputs:              tx.Outputs,
+		Fee:                  tx.Fee,
+		Ttl:                  tx.Ttl,
+		Certificates:         tx.Certificates,
+		Withdrawals:          tx.Withdrawals,
+		UpdateProposals:      tx.UpdateProposals,
+		AuxiliaryDataHash:    tx.AuxiliaryDataHash,
+		ValidityStart:        tx.ValidityStart,
+		Mint:                 tx.Mint,
+		ScriptDataHash:       tx.ScriptDataHash,
+		Collateral:           tx.Collateral,
+		RequiredSigners:      tx.RequiredSigners,
+		NetworkId:            tx.NetworkId,
+		CollateralReturn:     tx.CollateralReturn,
+		TotalCollateral:      tx.TotalCollateral,
+		ReferenceInputs:      tx.ReferenceInputs,
+		VotingProcedures:     tx.VotingProcedures,
+		ProposalProcedures:   tx.ProposalProcedures,
+		CurrentTreasuryValue: tx.CurrentTreasuryValue,
+		Donation:             tx.Donation,
 	}
 	em, _ := cbor.CanonicalEncOptions().EncMode()
 	return em.Marshal(cborBody)
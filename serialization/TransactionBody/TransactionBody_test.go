@@ -4,6 +4,7 @@ import (
 	"encoding/hex"
 	"testing"
 
+	"github.com/Salvionied/apollo/serialization"
 	"github.com/Salvionied/apollo/serialization/Address"
 	"github.com/Salvionied/apollo/serialization/TransactionBody"
 	"github.com/Salvionied/apollo/serialization/TransactionInput"
@@ -77,3 +78,28 @@ func TestId(t *testing.T) {
 		t.Error("Invalid Id", hex.EncodeToString(txId.Payload), "Expected", "49289fa2198208f49f62303aab86d06fb1ff960c812ee98d88c7a5cebb29b615")
 	}
 }
+
+func TestTransactionBodyKeepsOriginalCbor(t *testing.T) {
+	// keys out of canonical order
+	original := "a3021a000f424000818243010203000180"
+	decoded, _ := hex.DecodeString(original)
+	txBody := TransactionBody.TransactionBody{}
+	err := cbor.Unmarshal(decoded, &txBody)
+	if err != nil {
+		t.Fatal("Unmarshal failed", err)
+	}
+	marshaled, _ := cbor.Marshal(&txBody)
+	if hex.EncodeToString(marshaled) != original {
+		t.Error("Invalid marshaling", hex.EncodeToString(marshaled), "Expected", original)
+	}
+	hash, _ := txBody.Hash()
+	expectedHash, _ := serialization.Blake2bHash(decoded)
+	if hex.EncodeToString(hash) != hex.EncodeToString(expectedHash) {
+		t.Error("Invalid hash", hex.EncodeToString(hash), "Expected", hex.EncodeToString(expectedHash))
+	}
+	txBody.Fee = 5
+	marshaled, _ = cbor.Marshal(&txBody)
+	if hex.EncodeToString(marshaled) != "a3008182430102030001800205" {
+		t.Error("Invalid marshaling", hex.EncodeToString(marshaled), "Expected", "a3008182430102030001800205")
+	}
+}
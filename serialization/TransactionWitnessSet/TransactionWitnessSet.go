@@ -1,6 +1,10 @@
 package TransactionWitnessSet
 
 import (
+	"errors"
+	"sort"
+
+	"github.com/Salvionied/apollo/serialization"
 	"github.com/Salvionied/apollo/serialization/NativeScript"
 	"github.com/Salvionied/apollo/serialization/PlutusData"
 	"github.com/Salvionied/apollo/serialization/Redeemer"
@@ -14,8 +18,9 @@ type normaltws struct {
 	BootstrapWitnesses []any                                           `cbor:"2,keyasint,omitempty"`
 	PlutusV1Script     []PlutusData.PlutusV1Script                     `cbor:"3,keyasint,omitempty"`
 	PlutusV2Script     []PlutusData.PlutusV2Script                     `cbor:"6,keyasint,omitempty"`
+	PlutusV3Script     []PlutusData.PlutusV3Script                     `cbor:"7,keyasint,omitempty"`
 	PlutusData         *PlutusData.PlutusIndefArray                    `cbor:"4,keyasint,omitempty"`
-	Redeemer           []Redeemer.Redeemer                             `cbor:"5,keyasint,omitempty"`
+	Redeemer           any                                             `cbor:"5,keyasint,omitempty"`
 }
 type TransactionWitnessSet struct {
 	VkeyWitnesses      []VerificationKeyWitness.VerificationKeyWitness `cbor:"0,keyasint,omitempty"`
@@ -23,8 +28,11 @@ type TransactionWitnessSet struct {
 	BootstrapWitnesses []any                                           `cbor:"2,keyasint,omitempty"`
 	PlutusV1Script     []PlutusData.PlutusV1Script                     `cbor:"3,keyasint,omitempty"`
 	PlutusV2Script     []PlutusData.PlutusV2Script                     `cbor:"6,keyasint,omitempty"`
+	PlutusV3Script     []PlutusData.PlutusV3Script                     `cbor:"7,keyasint,omitempty"`
 	PlutusData         PlutusData.PlutusIndefArray                     `cbor:"4,keyasint,omitempty"`
 	Redeemer           []Redeemer.Redeemer                             `cbor:"5,keyasint,omitempty"`
+	original           *serialization.OriginalCbor
+	redeemers          *serialization.OriginalCbor
 }
 
 type WithRedeemerNoScripts struct {
@@ -33,20 +41,40 @@ type WithRedeemerNoScripts struct {
 	BootstrapWitnesses []any                                           `cbor:"2,keyasint,omitempty"`
 	PlutusV1Script     []PlutusData.PlutusV1Script                     `cbor:"3,keyasint,"`
 	PlutusV2Script     []PlutusData.PlutusV2Script                     `cbor:"6,keyasint,omitempty"`
+	PlutusV3Script     []PlutusData.PlutusV3Script                     `cbor:"7,keyasint,omitempty"`
 	PlutusData         *PlutusData.PlutusIndefArray                    `cbor:"4,keyasint,omitempty"`
-	Redeemer           []Redeemer.Redeemer                             `cbor:"5,keyasint,omitempty"`
+	Redeemer           any                                             `cbor:"5,keyasint,omitempty"`
 }
 
 /*
 *
 
 	MarshalCBOR serializes the TransactionWitnessSet to a CBOR byte slice.
+	A decoded witness set that was not modified is serialized
+	with the exact bytes it was decoded from, and unmodified
+	redeemers keep their original (array or map) format.
 
 	Returns:
 		[]byte: The CBOR-serialized TransactionWitnessSet.
 		error: An error if serialization fails.
 */
 func (tws *TransactionWitnessSet) MarshalCBOR() ([]byte, error) {
+	encoded, err := tws.encode()
+	if err != nil {
+		return nil, err
+	}
+	return tws.original.Resolve(encoded), nil
+}
+
+func (tws *TransactionWitnessSet) encode() ([]byte, error) {
+	var redeemers any
+	if len(tws.Redeemer) > 0 {
+		encoded, err := cbor.Marshal(tws.Redeemer)
+		if err != nil {
+			return nil, err
+		}
+		redeemers = cbor.RawMessage(tws.redeemers.Resolve(encoded))
+	}
 	if len(tws.PlutusV1Script) == 0 && len(tws.Redeemer) > 0 && len(tws.PlutusData) == 0 {
 		return cbor.Marshal(WithRedeemerNoScripts{
 			VkeyWitnesses:      tws.VkeyWitnesses,
@@ -54,8 +82,9 @@ func (tws *TransactionWitnessSet) MarshalCBOR() ([]byte, error) {
 			BootstrapWitnesses: tws.BootstrapWitnesses,
 			PlutusV1Script:     tws.PlutusV1Script,
 			PlutusV2Script:     tws.PlutusV2Script,
+			PlutusV3Script:     tws.PlutusV3Script,
 			PlutusData:         nil,
-			Redeemer:           tws.Redeemer,
+			Redeemer:           redeemers,
 		})
 	}
 	if len(tws.PlutusData) > 0 {
@@ -65,8 +94,9 @@ func (tws *TransactionWitnessSet) MarshalCBOR() ([]byte, error) {
 			BootstrapWitnesses: tws.BootstrapWitnesses,
 			PlutusV1Script:     tws.PlutusV1Script,
 			PlutusV2Script:     tws.PlutusV2Script,
+			PlutusV3Script:     tws.PlutusV3Script,
 			PlutusData:         &tws.PlutusData,
-			Redeemer:           tws.Redeemer,
+			Redeemer:           redeemers,
 		})
 	} else {
 		return cbor.Marshal(normaltws{
@@ -75,9 +105,110 @@ func (tws *TransactionWitnessSet) MarshalCBOR() ([]byte, error) {
 			BootstrapWitnesses: tws.BootstrapWitnesses,
 			PlutusV1Script:     tws.PlutusV1Script,
 			PlutusV2Script:     tws.PlutusV2Script,
+			PlutusV3Script:     tws.PlutusV3Script,
 			PlutusData:         nil,
-			Redeemer:           tws.Redeemer,
+			Redeemer:           redeemers,
 		})
 	}
 
 }
+
+type cborTws struct {
+	VkeyWitnesses      []VerificationKeyWitness.VerificationKeyWitness `cbor:"0,keyasint,omitempty"`
+	NativeScripts      []NativeScript.NativeScript                     `cbor:"1,keyasint,omitempty"`
+	BootstrapWitnesses []any                                           `cbor:"2,keyasint,omitempty"`
+	PlutusV1Script     []PlutusData.PlutusV1Script                     `cbor:"3,keyasint,omitempty"`
+	PlutusData         PlutusData.PlutusIndefArray                     `cbor:"4,keyasint,omitempty"`
+	Redeemer           cbor.RawMessage                                 `cbor:"5,keyasint,omitempty"`
+	PlutusV2Script     []PlutusData.PlutusV2Script                     `cbor:"6,keyasint,omitempty"`
+	PlutusV3Script     []PlutusData.PlutusV3Script                     `cbor:"7,keyasint,omitempty"`
+}
+
+type redeemerKey struct {
+	_     struct{} `cbor:",toarray"`
+	Tag   Redeemer.RedeemerTag
+	Index int
+}
+
+type redeemerValue struct {
+	_       struct{} `cbor:",toarray"`
+	Data    PlutusData.PlutusData
+	ExUnits Redeemer.ExecutionUnits
+}
+
+/*
+*
+
+	UnmarshalCBOR deserializes a TransactionWitnessSet of any era.
+	Redeemers can be either in the array format or in the map
+	format introduced with Conway.
+
+	Params:
+		value ([]byte): The CBOR-serialized TransactionWitnessSet.
+
+	Returns:
+		error: An error if deserialization fails.
+*/
+func (tws *TransactionWitnessSet) UnmarshalCBOR(value []byte) error {
+	decoded := cborTws{}
+	err := cbor.Unmarshal(value, &decoded)
+	if err != nil {
+		return err
+	}
+	*tws = TransactionWitnessSet{
+		VkeyWitnesses:      decoded.VkeyWitnesses,
+		NativeScripts:      decoded.NativeScripts,
+		BootstrapWitnesses: decoded.BootstrapWitnesses,
+		PlutusV1Script:     decoded.PlutusV1Script,
+		PlutusV2Script:     decoded.PlutusV2Script,
+		PlutusV3Script:     decoded.PlutusV3Script,
+		PlutusData:         decoded.PlutusData,
+	}
+	if len(decoded.Redeemer) > 0 {
+		tws.Redeemer, err = decodeRedeemers(decoded.Redeemer)
+		if err != nil {
+			return err
+		}
+		encoded, err := cbor.Marshal(tws.Redeemer)
+		if err == nil {
+			tws.redeemers = serialization.NewOriginalCbor(decoded.Redeemer, encoded)
+		}
+	}
+	encoded, err := tws.encode()
+	if err == nil {
+		tws.original = serialization.NewOriginalCbor(value, encoded)
+	}
+	return nil
+}
+
+func decodeRedeemers(value []byte) ([]Redeemer.Redeemer, error) {
+	if value[0]>>5 != 5 {
+		redeemers := make([]Redeemer.Redeemer, 0)
+		err := cbor.Unmarshal(value, &redeemers)
+		return redeemers, err
+	}
+	redeemerMap := make(map[redeemerKey]redeemerValue)
+	err := cbor.Unmarshal(value, &redeemerMap)
+	if err != nil {
+		return nil, err
+	}
+	if len(redeemerMap) == 0 {
+		return nil, errors.New("empty redeemers map")
+	}
+	redeemers := make([]Redeemer.Redeemer, 0, len(redeemerMap))
+	for key, val := range redeemerMap {
+		redeemers = append(redeemers, Redeemer.Redeemer{
+			Tag:     key.Tag,
+			Index:   key.Index,
+			Data:    val.Data,
+			ExUnits: val.ExUnits,
+		})
+	}
+	sort.Slice(redeemers, func(i, j int) bool {
+		if redeemers[i].Tag != redeemers[j].Tag {
+			return redeemers[i].Tag < redeemers[j].Tag
+		}
+		return redeemers[i].Index < redeemers[j].Index
+	})
+	return redeemers, nil
+}
@@ -1,6 +1,7 @@
 package serialization
 
 import (
+	"bytes"
 	"encoding/hex"
 	"fmt"
 	"reflect"
@@ -217,3 +218,69 @@ func (cb *CustomBytes) UnmarshalCBOR(value []byte) error {
 	}
 	return nil
 }
+
+/*
+*
+
+	OriginalCbor keeps the bytes a value was decoded from, together
+	with the encoding apollo produced for it right after decoding.
+	As long as the value re-encodes to the same bytes it is considered
+	unmodified and the original bytes are emitted instead, so that
+	non-canonical encodings (and the hashes computed over them)
+	survive a decode/encode round trip. It is held by pointer so that
+	the structs keeping it stay comparable.
+*/
+type OriginalCbor struct {
+	raw     []byte
+	encoded []byte
+}
+
+/*
+*
+
+	NewOriginalCbor records the original bytes of a decoded value.
+
+	Params:
+		raw ([]byte): The bytes the value was decoded from.
+		encoded ([]byte): The encoding of the freshly decoded value.
+
+	Returns:
+		*OriginalCbor: The original bytes of the value.
+*/
+func NewOriginalCbor(raw []byte, encoded []byte) *OriginalCbor {
+	return &OriginalCbor{raw: append([]byte{}, raw...), encoded: encoded}
+}
+
+/*
+*
+
+	Raw returns the bytes the value was decoded from.
+
+	Returns:
+		[]byte: The original bytes, nil if the value was not decoded.
+*/
+func (oc *OriginalCbor) Raw() []byte {
+	if oc == nil {
+		return nil
+	}
+	return oc.raw
+}
+
+/*
+*
+
+	Resolve chooses the bytes to emit for a value.
+
+	Params:
+		encoded ([]byte): The current encoding of the value.
+
+	Returns:
+		[]byte: The original bytes if the value was not modified
+		since it was decoded, the current encoding otherwise.
+*/
+func (oc *OriginalCbor) Resolve(encoded []byte) []byte {
+	if oc != nil && oc.raw != nil && bytes.Equal(encoded, oc.encoded) {
+		return oc.raw
+	}
+	return encoded
+}
@@ -1,7 +1,11 @@
 package Base
 
 import (
+	"context"
 	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
 	"strconv"
 
 	"github.com/Salvionied/apollo/serialization"
@@ -12,6 +16,7 @@ import (
 	"github.com/Salvionied/apollo/serialization/MultiAsset"
 	"github.com/Salvionied/apollo/serialization/PlutusData"
 	"github.com/Salvionied/apollo/serialization/Policy"
+	"github.com/Salvionied/apollo/serialization/Rational"
 	"github.com/Salvionied/apollo/serialization/Redeemer"
 	"github.com/Salvionied/apollo/serialization/Transaction"
 	"github.com/Salvionied/apollo/serialization/TransactionInput"
@@ -35,41 +40,153 @@ type GenesisParameters struct {
 	SecurityParam          int     `json:"security_param"`
 }
 
+const REF_SCRIPT_FEE_SIZE_INCREMENT = 25600
+
+var REF_SCRIPT_FEE_MULTIPLIER = Rational.New(6, 5)
+
 type ProtocolParameters struct {
-	MinFeeConstant        int     `json:"min_fee_b"`
-	MinFeeCoefficient     int     `json:"min_fee_a"`
-	MaxBlockSize          int     `json:"max_block_size"`
-	MaxTxSize             int     `json:"max_tx_size"`
-	MaxBlockHeaderSize    int     `json:"max_block_header_size"`
-	KeyDeposits           string  `json:"key_deposit"`
-	PoolDeposits          string  `json:"pool_deposit"`
-	PooolInfluence        float32 `json:"a0"`
-	MonetaryExpansion     float32 `json:"rho"`
-	TreasuryExpansion     float32 `json:"tau"`
-	DecentralizationParam float32 `json:"decentralisation_param"`
-	ExtraEntropy          string  `json:"extra_entropy"`
-	ProtocolMajorVersion  int     `json:"protocol_major_ver"`
-	ProtocolMinorVersion  int     `json:"protocol_minor_ver"`
-	MinUtxo               string  `json:"min_utxo"`
-	MinPoolCost           string  `json:"min_pool_cost"`
-	PriceMem              float32 `json:"price_mem"`
-	PriceStep             float32 `json:"price_step"`
-	MaxTxExMem            string  `json:"max_tx_ex_mem"`
-	MaxTxExSteps          string  `json:"max_tx_ex_steps"`
-	MaxBlockExMem         string  `json:"max_block_ex_mem"`
-	MaxBlockExSteps       string  `json:"max_block_ex_steps"`
-	MaxValSize            string  `json:"max_val_size"`
-	CollateralPercent     int     `json:"collateral_percent"`
-	MaxCollateralInuts    int     `json:"max_collateral_inputs"`
-	CoinsPerUtxoWord      string  `json:"coins_per_utxo_word"`
-	CoinsPerUtxoByte      string  `json:"coins_per_utxo_byte"`
-	//CostModels            map[string]map[string]any
+	MinFeeConstant        int               `json:"min_fee_b"`
+	MinFeeCoefficient     int               `json:"min_fee_a"`
+	MaxBlockSize          int               `json:"max_block_size"`
+	MaxTxSize             int               `json:"max_tx_size"`
+	MaxBlockHeaderSize    int               `json:"max_block_header_size"`
+	KeyDeposits           int64             `json:"key_deposit"`
+	PoolDeposits          int64             `json:"pool_deposit"`
+	MaxEpoch              int64             `json:"e_max"`
+	NOpt                  int64             `json:"n_opt"`
+	PooolInfluence        Rational.Rational `json:"a0"`
+	MonetaryExpansion     Rational.Rational `json:"rho"`
+	TreasuryExpansion     Rational.Rational `json:"tau"`
+	DecentralizationParam Rational.Rational `json:"decentralisation_param"`
+	ExtraEntropy          string            `json:"extra_entropy"`
+	ProtocolMajorVersion  int               `json:"protocol_major_ver"`
+	ProtocolMinorVersion  int               `json:"protocol_minor_ver"`
+	MinUtxo               int64             `json:"min_utxo"`
+	MinPoolCost           int64             `json:"min_pool_cost"`
+	PriceMem              Rational.Rational `json:"price_mem"`
+	PriceStep             Rational.Rational `json:"price_step"`
+	MaxTxExMem            int64             `json:"max_tx_ex_mem"`
+	MaxTxExSteps          int64             `json:"max_tx_ex_steps"`
+	MaxBlockExMem         int64             `json:"max_block_ex_mem"`
+	MaxBlockExSteps       int64             `json:"max_block_ex_steps"`
+	MaxValSize            int               `json:"max_val_size"`
+	CollateralPercent     int               `json:"collateral_percent"`
+	MaxCollateralInuts    int               `json:"max_collateral_inputs"`
+	CoinsPerUtxoWord      int64             `json:"coins_per_utxo_word"`
+	CoinsPerUtxoByte      int64             `json:"coins_per_utxo_byte"`
+	// CostModels holds the cost model of each Plutus language,
+	// keyed by PLUTUS_V1, PLUTUS_V2 and PLUTUS_V3.
+	CostModels map[string][]int64 `json:"cost_models_raw"`
+
+	// Conway parameters.
+	PoolVotingThresholds   PoolVotingThresholds `json:"pool_voting_thresholds"`
+	DRepVotingThresholds   DRepVotingThresholds `json:"drep_voting_thresholds"`
+	CommitteeMinSize       int64                `json:"committee_min_size"`
+	CommitteeMaxTermLength int64                `json:"committee_max_term_length"`
+	GovActionLifetime      int64                `json:"gov_action_lifetime"`
+	GovActionDeposit       int64                `json:"gov_action_deposit"`
+	DRepDeposit            int64                `json:"drep_deposit"`
+	DRepActivity           int64                `json:"drep_activity"`
+	// MinFeeRefScriptCostPerByte is the Conway base price per byte
+	// of the reference scripts used by a transaction.
+	MinFeeRefScriptCostPerByte Rational.Rational `json:"min_fee_ref_script_cost_per_byte"`
 }
 
 func (p ProtocolParameters) GetCoinsPerUtxoByte() int {
+	if p.CoinsPerUtxoByte > 0 {
+		return int(p.CoinsPerUtxoByte)
+	}
 	return 4310
 }
 
+/*
+*
+
+	ScriptFee computes the fee due for the given execution
+	units, rounding up the exact sum of the memory and
+	step costs as the ledger does.
+
+	Params:
+		mem (int64): The memory units.
+		steps (int64): The CPU steps.
+
+	Returns:
+		int64: The fee for the execution units.
+*/
+func (p ProtocolParameters) ScriptFee(mem int64, steps int64) int64 {
+	cost := p.PriceMem.Mul(mem)
+	cost.Add(cost, p.PriceStep.Mul(steps))
+	return Rational.Ceil(cost)
+}
+
+/*
+*
+
+	MinFee computes the minimum fee of a transaction of the
+	given size and execution units: a * size + b plus the
+	script fee.
+
+	Params:
+		size (int): The size of the transaction in bytes.
+		mem (int64): The total memory units of the transaction.
+		steps (int64): The total CPU steps of the transaction.
+
+	Returns:
+		int64: The minimum fee of the transaction.
+*/
+func (p ProtocolParameters) MinFee(size int, mem int64, steps int64) int64 {
+	return int64(size)*int64(p.MinFeeCoefficient) + int64(p.MinFeeConstant) + p.ScriptFee(mem, steps)
+}
+
+/*
+*
+
+	RefScriptFee computes the Conway fee due for the reference
+	scripts of a transaction. Every REF_SCRIPT_FEE_SIZE_INCREMENT
+	bytes the price per byte is multiplied by
+	REF_SCRIPT_FEE_MULTIPLIER, and the exact total is rounded
+	down as the ledger does.
+
+	Params:
+		size (int): The total size in bytes of the reference scripts
+			in the spent and reference inputs.
+
+	Returns:
+		int64: The reference script fee.
+*/
+func (p ProtocolParameters) RefScriptFee(size int) int64 {
+	if size <= 0 || p.MinFeeRefScriptCostPerByte.IsZero() {
+		return 0
+	}
+	total := new(big.Rat)
+	price := p.MinFeeRefScriptCostPerByte.Rat()
+	remaining := int64(size)
+	for remaining >= REF_SCRIPT_FEE_SIZE_INCREMENT {
+		total.Add(total, new(big.Rat).Mul(price, big.NewRat(REF_SCRIPT_FEE_SIZE_INCREMENT, 1)))
+		price = new(big.Rat).Mul(price, REF_SCRIPT_FEE_MULTIPLIER.Rat())
+		remaining -= REF_SCRIPT_FEE_SIZE_INCREMENT
+	}
+	total.Add(total, new(big.Rat).Mul(price, big.NewRat(remaining, 1)))
+	return new(big.Int).Quo(total.Num(), total.Denom()).Int64()
+}
+
+/*
+*
+
+	MinCollateral computes the minimum collateral required
+	for a transaction paying the given fee, that is the fee
+	times the collateral percentage rounded up.
+
+	Params:
+		fee (int64): The fee of the transaction.
+
+	Returns:
+		int64: The minimum amount of collateral.
+*/
+func (p ProtocolParameters) MinCollateral(fee int64) int64 {
+	return Rational.Ceil(big.NewRat(fee*int64(p.CollateralPercent), 100))
+}
+
 type Input struct {
 	Address             string          `json:"address"`
 	Amount              []AddressAmount `json:"amount"`
@@ -89,6 +206,9 @@ type Output struct {
 	InlineDatum         string          `json:"inline_datum"`
 	Collateral          bool            `json:"collateral"`
 	ReferenceScriptHash string          `json:"reference_script_hash"`
+	// ReferenceScript is the resolved script of ReferenceScriptHash,
+	// when the backend fetched it.
+	ReferenceScript *PlutusData.ScriptRef `json:"-"`
 }
 
 func (o Output) ToUTxO(txHash string) *UTxO.UTxO {
@@ -153,11 +273,27 @@ func (o Output) ToTransactionOutput() TransactionOutput.TransactionOutput {
 					Inline:    &datum,
 					DatumType: 1,
 				},
+				ScriptRef: o.ReferenceScript,
 			},
 			IsPostAlonzo: true,
 		}
 		return tx_out
 	}
+	if o.ReferenceScript != nil {
+		tx_out := TransactionOutput.TransactionOutput{
+			PostAlonzo: TransactionOutput.TransactionOutputAlonzo{
+				Address:   address,
+				Amount:    final_amount.ToAlonzoValue(),
+				ScriptRef: o.ReferenceScript,
+			},
+			IsPostAlonzo: true,
+		}
+		if len(datum_hash.Payload) > 0 {
+			datumOption := PlutusData.DatumOptionHash(datum_hash.Payload)
+			tx_out.PostAlonzo.Datum = &datumOption
+		}
+		return tx_out
+	}
 	tx_out := TransactionOutput.TransactionOutput{PreAlonzo: TransactionOutput.TransactionOutputShelley{
 		Address:   address,
 		Amount:    final_amount,
@@ -186,6 +322,201 @@ type ChainContext interface {
 	GetContractCbor(scriptHash string) string
 }
 
+var (
+	// ErrNotSupported is returned by the optional capabilities of a
+	// wrapping context when the wrapped one does not implement them.
+	ErrNotSupported   = errors.New("not supported by the chain context")
+	ErrDatumNotFound  = errors.New("datum not found")
+	ErrScriptNotFound = errors.New("script not found")
+	// ErrScriptHashMismatch is returned when a backend answers
+	// with a script whose hash is not the requested one.
+	ErrScriptHashMismatch = errors.New("script hash mismatch")
+)
+
+/*
+*
+
+	DatumResolver is implemented by the chain contexts able to
+	find a datum by its hash, needed to spend the UTxOs carrying
+	only the hash of their datum. ResolveDatum returns an error
+	matching ErrDatumNotFound when the backend does not know it.
+*/
+type DatumResolver interface {
+	ResolveDatum(datumHash string) (*PlutusData.PlutusData, error)
+}
+
+/*
+*
+
+	ScriptResolver is implemented by the chain contexts able to
+	find a script by its hash together with its language, which
+	GetContractCbor does not return. GetScript returns an error
+	matching ErrScriptNotFound when the backend does not know it
+	and ErrScriptHashMismatch when it returns another script.
+*/
+type ScriptResolver interface {
+	GetScript(scriptHash string) (*PlutusData.ScriptRef, error)
+}
+
+/*
+*
+
+	WalletUtxoLoader is implemented by the chain contexts whose
+	Utxos the builder loads for the wallet set as change address.
+	Wrapping contexts report whether the wrapped ones do.
+*/
+type WalletUtxoLoader interface {
+	LoadsWalletUtxos() bool
+}
+
+/*
+*
+
+	ContextBinder is implemented by the chain contexts able to run
+	their calls in a context, so that they are cancelled with it
+	and their spans are children of the operation it carries.
+	WithContext returns a bound copy, the context itself being
+	left unchanged.
+*/
+type ContextBinder interface {
+	WithContext(ctx context.Context) ChainContext
+}
+
+/*
+*
+
+	WithContext binds a chain context to a context when it
+	implements ContextBinder.
+
+	Params:
+		cc (ChainContext): The chain context.
+		ctx (context.Context): The context of its calls.
+
+	Returns:
+		ChainContext: The bound context, cc itself if it cannot be bound.
+*/
+func WithContext(cc ChainContext, ctx context.Context) ChainContext {
+	if binder, ok := cc.(ContextBinder); ok && ctx != nil {
+		return binder.WithContext(ctx)
+	}
+	return cc
+}
+
+/*
+*
+
+	UtxoFetcher is implemented by the chain contexts able to tell
+	a failed UTxO lookup from an address without UTxOs, which
+	the empty list of Utxos does not.
+*/
+type UtxoFetcher interface {
+	FetchUtxos(address Address.Address) ([]UTxO.UTxO, error)
+}
+
+/*
+*
+
+	FetchUtxos returns the UTxOs of an address with FetchUtxos
+	when the context implements UtxoFetcher, Utxos otherwise.
+
+	Params:
+		cc (ChainContext): The chain context.
+		address (Address.Address): The address.
+
+	Returns:
+		[]UTxO.UTxO: The UTxOs of the address.
+		error: The error of the backend.
+*/
+func FetchUtxos(cc ChainContext, address Address.Address) ([]UTxO.UTxO, error) {
+	if fetcher, ok := cc.(UtxoFetcher); ok {
+		return fetcher.FetchUtxos(address)
+	}
+	return cc.Utxos(address), nil
+}
+
+/*
+*
+
+	EvaluationFetcher is implemented by the chain contexts able to
+	tell why the evaluation of a transaction failed, which the
+	empty map of EvaluateTx does not.
+*/
+type EvaluationFetcher interface {
+	FetchEvaluation(tx []byte) (map[string]Redeemer.ExecutionUnits, error)
+}
+
+/*
+*
+
+	FetchEvaluation evaluates a transaction with FetchEvaluation
+	when the context implements EvaluationFetcher, EvaluateTx
+	otherwise.
+
+	Params:
+		cc (ChainContext): The chain context.
+		tx ([]byte): The CBOR of the transaction.
+
+	Returns:
+		map[string]Redeemer.ExecutionUnits: The execution units by redeemer.
+		error: The error of the evaluation.
+*/
+func FetchEvaluation(cc ChainContext, tx []byte) (map[string]Redeemer.ExecutionUnits, error) {
+	if fetcher, ok := cc.(EvaluationFetcher); ok {
+		return fetcher.FetchEvaluation(tx)
+	}
+	return cc.EvaluateTx(tx), nil
+}
+
+/*
+*
+
+	DecodeDatum decodes a datum returned by a backend.
+
+	Params:
+		datumCbor (string): The hex encoded cbor of the datum.
+
+	Returns:
+		*PlutusData.PlutusData: The datum.
+		error: An error if the datum is not valid plutus data.
+*/
+func DecodeDatum(datumCbor string) (*PlutusData.PlutusData, error) {
+	decoded, err := hex.DecodeString(datumCbor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid datum hex: %w", err)
+	}
+	datum := PlutusData.PlutusData{}
+	err = cbor.Unmarshal(decoded, &datum)
+	if err != nil {
+		return nil, fmt.Errorf("invalid datum cbor: %w", err)
+	}
+	return &datum, nil
+}
+
+/*
+*
+
+	CheckScriptHash checks that a script returned by a backend
+	is the requested one.
+
+	Params:
+		scriptRef (*PlutusData.ScriptRef): The returned script.
+		scriptHash (string): The hex encoded hash requested.
+
+	Returns:
+		error: An error matching ErrScriptHashMismatch if the
+			script hashes to something else.
+*/
+func CheckScriptHash(scriptRef *PlutusData.ScriptRef, scriptHash string) error {
+	hash, err := scriptRef.Hash()
+	if err != nil {
+		return err
+	}
+	if got := hex.EncodeToString(hash[:]); got != scriptHash {
+		return fmt.Errorf("%w: requested %s, got %s", ErrScriptHashMismatch, scriptHash, got)
+	}
+	return nil
+}
+
 type Epoch struct {
 	// Sum of all the active stakes within the epoch in Lovelaces
 	ActiveStake string `json:"active_stake"`
@@ -287,10 +618,27 @@ type AddressAmount struct {
 }
 
 func Fee(context ChainContext, length int, exec_steps int, max_mem_unit int) int {
-	protocol_param := context.GetProtocolParams()
-	return int(length*protocol_param.MinFeeCoefficient) +
-		int(protocol_param.MinFeeConstant) +
-		int(exec_steps*int(protocol_param.PriceStep)) +
-		int(max_mem_unit*int(protocol_param.PriceMem))
+	return FeeWithReferenceScripts(context, length, exec_steps, max_mem_unit, 0)
+}
+
+/*
+*
+
+	FeeWithReferenceScripts computes the minimum fee of a
+	transaction including the Conway reference script fee.
 
+	Params:
+		context (ChainContext): The chain context providing the protocol parameters.
+		length (int): The size of the transaction in bytes.
+		exec_steps (int): The total CPU steps of the transaction.
+		max_mem_unit (int): The total memory units of the transaction.
+		ref_script_size (int): The total size of the reference scripts
+			in the spent and reference inputs.
+
+	Returns:
+		int: The minimum fee of the transaction.
+*/
+func FeeWithReferenceScripts(context ChainContext, length int, exec_steps int, max_mem_unit int, ref_script_size int) int {
+	protocol_param := context.GetProtocolParams()
+	return int(protocol_param.MinFee(length, int64(max_mem_unit), int64(exec_steps)) + protocol_param.RefScriptFee(ref_script_size))
 }
@@ -0,0 +1,203 @@
+package Base_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/Salvionied/apollo/serialization/Rational"
+	"github.com/Salvionied/apollo/txBuilding/Backend/Base"
+)
+
+func TestScriptFee(t *testing.T) {
+	pp := Base.ProtocolParameters{
+		MinFeeConstant:    155381,
+		MinFeeCoefficient: 44,
+		PriceMem:          Rational.New(577, 10000),
+		PriceStep:         Rational.New(721, 10000000),
+		CollateralPercent: 150,
+	}
+	// 0.0577 * 1000 + 0.0000721 * 1000000 = 57.7 + 72.1 = 129.8
+	if fee := pp.ScriptFee(1000, 1000000); fee != 130 {
+		t.Error("Invalid script fee", fee)
+	}
+	// The sum is rounded, not each term: 0.0577 * 10 + 0.0000721 * 10000 = 1.298
+	if fee := pp.ScriptFee(10, 10000); fee != 2 {
+		t.Error("Invalid script fee", fee)
+	}
+	if fee := pp.ScriptFee(0, 0); fee != 0 {
+		t.Error("Invalid script fee", fee)
+	}
+	if fee := pp.MinFee(300, 1000, 1000000); fee != 300*44+155381+130 {
+		t.Error("Invalid min fee", fee)
+	}
+	if collateral := pp.MinCollateral(200001); collateral != 300002 {
+		t.Error("Invalid min collateral", collateral)
+	}
+	if collateral := pp.MinCollateral(200003); collateral != 300005 {
+		t.Error("Invalid min collateral", collateral)
+	}
+}
+
+func TestProtocolParametersFromBlockfrost(t *testing.T) {
+	body := `{"min_fee_a": 44, "min_fee_b": 155381, "a0": 0.3, "rho": 0.003, "tau": 0.2,
+	"decentralisation_param": 0, "price_mem": 0.0577, "price_step": 0.0000721, "collateral_percent": 150}`
+	pp := Base.ProtocolParameters{}
+	err := json.Unmarshal([]byte(body), &pp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pp.PriceMem != Rational.New(577, 10000) || pp.PriceStep != Rational.New(721, 10000000) {
+		t.Error("Invalid prices", pp.PriceMem, pp.PriceStep)
+	}
+	if pp.PooolInfluence != Rational.New(3, 10) || pp.MonetaryExpansion != Rational.New(3, 1000) || pp.TreasuryExpansion != Rational.New(1, 5) {
+		t.Error("Invalid monetary parameters")
+	}
+	if pp.ScriptFee(14000000, 10000000000) != 1528800 {
+		t.Error("Invalid max script fee", pp.ScriptFee(14000000, 10000000000))
+	}
+}
+
+func TestRefScriptFee(t *testing.T) {
+	pp := Base.ProtocolParameters{MinFeeRefScriptCostPerByte: Rational.New(15, 1)}
+	testCases := map[int]int64{
+		0:     0,
+		1000:  15000,
+		25599: 383985,
+		25600: 384000,
+		30000: 384000 + 4400*18,
+		// 25600 * 15 + 25600 * 18 + 1 * 21.6, rounded down
+		51201:  844821,
+		204800: 6335648,
+	}
+	for size, expected := range testCases {
+		if fee := pp.RefScriptFee(size); fee != expected {
+			t.Error("Invalid ref script fee for", size, "got", fee, "expected", expected)
+		}
+	}
+	if fee := (Base.ProtocolParameters{}).RefScriptFee(30000); fee != 0 {
+		t.Error("Ref script fee should be 0 before Conway", fee)
+	}
+	body := `{"min_fee_a": 44, "min_fee_b": 155381, "min_fee_ref_script_cost_per_byte": 15}`
+	parsed := Base.ProtocolParameters{}
+	err := json.Unmarshal([]byte(body), &parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.MinFeeRefScriptCostPerByte != Rational.New(15, 1) {
+		t.Error("Invalid ref script cost", parsed.MinFeeRefScriptCostPerByte)
+	}
+}
+
+func TestProtocolParametersFromBlockfrostConway(t *testing.T) {
+	body := `{"epoch": 507, "min_fee_a": 44, "min_fee_b": 155381, "key_deposit": "2000000", "pool_deposit": "500000000",
+	"e_max": 18, "n_opt": 500, "min_utxo": "4310", "min_pool_cost": "170000000", "extra_entropy": null,
+	"max_tx_ex_mem": "14000000", "max_tx_ex_steps": "10000000000", "max_block_ex_mem": "62000000",
+	"max_block_ex_steps": "20000000000", "max_val_size": "5000", "coins_per_utxo_size": "4310", "coins_per_utxo_word": "4310",
+	"cost_models_raw": {"PlutusV1": [100788, 420], "PlutusV3": [100788, 420, 1]},
+	"pvt_motion_no_confidence": 0.51, "pvt_committee_normal": 0.51, "pvt_committee_no_confidence": 0.51,
+	"pvt_hard_fork_initiation": 0.51, "pvt_p_p_security_group": 0.51, "dvt_motion_no_confidence": 0.67,
+	"dvt_committee_normal": 0.67, "dvt_committee_no_confidence": 0.6, "dvt_update_to_constitution": 0.75,
+	"dvt_hard_fork_initiation": 0.6, "dvt_p_p_network_group": 0.67, "dvt_p_p_economic_group": 0.67,
+	"dvt_p_p_technical_group": 0.67, "dvt_p_p_gov_group": 0.75, "dvt_treasury_withdrawal": 0.67,
+	"committee_min_size": "7", "committee_max_term_length": "146", "gov_action_lifetime": "6",
+	"gov_action_deposit": "100000000000", "drep_deposit": "500000000", "drep_activity": "20",
+	"min_fee_ref_script_cost_per_byte": 15}`
+	pp := Base.ProtocolParameters{}
+	err := json.Unmarshal([]byte(body), &pp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pp.KeyDeposits != 2000000 || pp.MaxTxExSteps != 10000000000 || pp.MaxValSize != 5000 || pp.CoinsPerUtxoByte != 4310 || pp.NOpt != 500 {
+		t.Error("Invalid integer parameters", pp)
+	}
+	if pp.GetCoinsPerUtxoByte() != 4310 {
+		t.Error("Invalid coins per utxo byte", pp.GetCoinsPerUtxoByte())
+	}
+	if pp.PoolVotingThresholds.PPSecurityGroup != Rational.New(51, 100) || pp.DRepVotingThresholds.UpdateToConstitution != Rational.New(3, 4) {
+		t.Error("Invalid voting thresholds", pp.PoolVotingThresholds, pp.DRepVotingThresholds)
+	}
+	if pp.GovActionDeposit != 100000000000 || pp.DRepDeposit != 500000000 || pp.DRepActivity != 20 || pp.CommitteeMaxTermLength != 146 {
+		t.Error("Invalid governance parameters", pp)
+	}
+	if len(pp.CostModel(Base.PLUTUS_V3)) != 3 || pp.CostModel(Base.PLUTUS_V2) != nil {
+		t.Error("Invalid cost models", pp.CostModels)
+	}
+	// The cached copy must decode to the same parameters.
+	encoded, err := json.Marshal(pp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cached := Base.ProtocolParameters{}
+	err = json.Unmarshal(encoded, &cached)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recoded, _ := json.Marshal(cached)
+	if string(recoded) != string(encoded) || cached.PoolVotingThresholds != pp.PoolVotingThresholds || cached.KeyDeposits != pp.KeyDeposits {
+		t.Error("Protocol parameters do not round trip", string(encoded))
+	}
+}
+
+func TestLoadCliProtocolParameters(t *testing.T) {
+	pp, err := Base.LoadCliProtocolParameters("testdata/cli_protocol_parameters.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pp.MinFeeCoefficient != 44 || pp.MinFeeConstant != 155381 || pp.MaxTxSize != 16384 || pp.MaxBlockSize != 90112 {
+		t.Error("Invalid fee parameters", pp)
+	}
+	if pp.PriceMem != Rational.New(577, 10000) || pp.PriceStep != Rational.New(721, 10000000) {
+		t.Error("Invalid prices", pp.PriceMem, pp.PriceStep)
+	}
+	if pp.MaxTxExMem != 14000000 || pp.MaxTxExSteps != 10000000000 || pp.MaxBlockExSteps != 20000000000 {
+		t.Error("Invalid execution units", pp)
+	}
+	if pp.KeyDeposits != 2000000 || pp.CoinsPerUtxoByte != 4310 || pp.ProtocolMajorVersion != 9 || pp.ProtocolMinorVersion != 1 {
+		t.Error("Invalid parameters", pp)
+	}
+	if pp.DRepVotingThresholds.PPGovGroup != Rational.New(3, 4) || pp.PoolVotingThresholds.HardForkInitiation != Rational.New(51, 100) {
+		t.Error("Invalid voting thresholds")
+	}
+	if pp.GovActionLifetime != 6 || pp.CommitteeMinSize != 7 || pp.MinFeeRefScriptCostPerByte != Rational.New(15, 1) {
+		t.Error("Invalid governance parameters")
+	}
+	if len(pp.CostModel(Base.PLUTUS_V1)) != 8 || len(pp.CostModel(Base.PLUTUS_V3)) != 10 {
+		t.Error("Invalid cost models", pp.CostModels)
+	}
+	_, err = Base.ParseCliProtocolParameters([]byte(`{"costModels": {"PlutusV9": [1]}}`))
+	if err == nil {
+		t.Error("Unknown languages should be rejected")
+	}
+}
+
+func TestLoadGenesis(t *testing.T) {
+	pp, gp, err := Base.LoadGenesis("testdata/shelley-genesis.json", "testdata/alonzo-genesis.json", "testdata/conway-genesis.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gp.NetworkMagic != 764824073 || gp.SystemStart != 1506203091 || gp.MaxLovelaceSupply != "45000000000000000" || gp.SecurityParam != 2160 {
+		t.Error("Invalid genesis parameters", gp)
+	}
+	if pp.MinFeeCoefficient != 44 || pp.MinUtxo != 1000000 || pp.NOpt != 150 || pp.DecentralizationParam != Rational.New(1, 1) || pp.ExtraEntropy != "" {
+		t.Error("Invalid shelley parameters", pp)
+	}
+	if pp.PriceStep != Rational.New(721, 10000000) || pp.MaxBlockExMem != 50000000 || pp.CoinsPerUtxoWord != 34482 || pp.CoinsPerUtxoByte != 4310 {
+		t.Error("Invalid alonzo parameters", pp)
+	}
+	// Named cost models are ordered by parameter name.
+	v1 := pp.CostModel(Base.PLUTUS_V1)
+	if !reflect.DeepEqual(v1, []int64{197209, 0, 1, 4}) {
+		t.Error("Invalid PlutusV1 cost model", v1)
+	}
+	if pp.DRepDeposit != 500000000 || pp.DRepVotingThresholds.CommitteeNoConfidence != Rational.New(3, 5) || len(pp.CostModel(Base.PLUTUS_V3)) != 10 {
+		t.Error("Invalid conway parameters", pp)
+	}
+	shelleyOnly, _, err := Base.LoadGenesis("testdata/shelley-genesis.json", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shelleyOnly.CostModels != nil || !shelleyOnly.PriceMem.IsZero() {
+		t.Error("Missing genesis files should be skipped")
+	}
+}
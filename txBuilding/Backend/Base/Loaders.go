@@ -0,0 +1,368 @@
+package Base
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Salvionied/apollo/serialization/Rational"
+)
+
+type cliExUnits struct {
+	Memory int64 `json:"memory"`
+	Steps  int64 `json:"steps"`
+}
+
+type cliProtocolVersion struct {
+	Major int `json:"major"`
+	Minor int `json:"minor"`
+}
+
+type cliProtocolParameters struct {
+	CollateralPercentage   int                        `json:"collateralPercentage"`
+	CommitteeMaxTermLength int64                      `json:"committeeMaxTermLength"`
+	CommitteeMinSize       int64                      `json:"committeeMinSize"`
+	CostModels             map[string]json.RawMessage `json:"costModels"`
+	DRepActivity           int64                      `json:"dRepActivity"`
+	DRepDeposit            int64                      `json:"dRepDeposit"`
+	DRepVotingThresholds   DRepVotingThresholds       `json:"dRepVotingThresholds"`
+	Decentralization       Rational.Rational          `json:"decentralization"`
+	ExecutionUnitPrices    struct {
+		PriceMemory Rational.Rational `json:"priceMemory"`
+		PriceSteps  Rational.Rational `json:"priceSteps"`
+	} `json:"executionUnitPrices"`
+	ExtraPraosEntropy          json.RawMessage      `json:"extraPraosEntropy"`
+	GovActionDeposit           int64                `json:"govActionDeposit"`
+	GovActionLifetime          int64                `json:"govActionLifetime"`
+	MaxBlockBodySize           int                  `json:"maxBlockBodySize"`
+	MaxBlockExecutionUnits     cliExUnits           `json:"maxBlockExecutionUnits"`
+	MaxBlockHeaderSize         int                  `json:"maxBlockHeaderSize"`
+	MaxCollateralInputs        int                  `json:"maxCollateralInputs"`
+	MaxTxExecutionUnits        cliExUnits           `json:"maxTxExecutionUnits"`
+	MaxTxSize                  int                  `json:"maxTxSize"`
+	MaxValueSize               int                  `json:"maxValueSize"`
+	MinFeeRefScriptCostPerByte Rational.Rational    `json:"minFeeRefScriptCostPerByte"`
+	MinPoolCost                int64                `json:"minPoolCost"`
+	MinUTxOValue               int64                `json:"minUTxOValue"`
+	MonetaryExpansion          Rational.Rational    `json:"monetaryExpansion"`
+	PoolPledgeInfluence        Rational.Rational    `json:"poolPledgeInfluence"`
+	PoolRetireMaxEpoch         int64                `json:"poolRetireMaxEpoch"`
+	PoolVotingThresholds       PoolVotingThresholds `json:"poolVotingThresholds"`
+	ProtocolVersion            cliProtocolVersion   `json:"protocolVersion"`
+	StakeAddressDeposit        int64                `json:"stakeAddressDeposit"`
+	StakePoolDeposit           int64                `json:"stakePoolDeposit"`
+	StakePoolTargetNum         int64                `json:"stakePoolTargetNum"`
+	TreasuryCut                Rational.Rational    `json:"treasuryCut"`
+	TxFeeFixed                 int                  `json:"txFeeFixed"`
+	TxFeePerByte               int                  `json:"txFeePerByte"`
+	UtxoCostPerByte            int64                `json:"utxoCostPerByte"`
+	UtxoCostPerWord            int64                `json:"utxoCostPerWord"`
+}
+
+/*
+*
+
+	ParseCliProtocolParameters reads the protocol parameters
+	printed by `cardano-cli query protocol-parameters`.
+
+	Params:
+		data ([]byte): The JSON output of cardano-cli.
+
+	Returns:
+		ProtocolParameters: The protocol parameters.
+		error: An error if the JSON is invalid.
+*/
+func ParseCliProtocolParameters(data []byte) (ProtocolParameters, error) {
+	var cli cliProtocolParameters
+	err := json.Unmarshal(data, &cli)
+	if err != nil {
+		return ProtocolParameters{}, fmt.Errorf("ParseCliProtocolParameters: %v", err)
+	}
+	costModels, err := ParseCostModels(cli.CostModels)
+	if err != nil {
+		return ProtocolParameters{}, fmt.Errorf("ParseCliProtocolParameters: %v", err)
+	}
+	return ProtocolParameters{
+		MinFeeConstant:             cli.TxFeeFixed,
+		MinFeeCoefficient:          cli.TxFeePerByte,
+		MaxBlockSize:               cli.MaxBlockBodySize,
+		MaxTxSize:                  cli.MaxTxSize,
+		MaxBlockHeaderSize:         cli.MaxBlockHeaderSize,
+		KeyDeposits:                cli.StakeAddressDeposit,
+		PoolDeposits:               cli.StakePoolDeposit,
+		MaxEpoch:                   cli.PoolRetireMaxEpoch,
+		NOpt:                       cli.StakePoolTargetNum,
+		PooolInfluence:             cli.PoolPledgeInfluence,
+		MonetaryExpansion:          cli.MonetaryExpansion,
+		TreasuryExpansion:          cli.TreasuryCut,
+		DecentralizationParam:      cli.Decentralization,
+		ExtraEntropy:               parseNonce(cli.ExtraPraosEntropy),
+		ProtocolMajorVersion:       cli.ProtocolVersion.Major,
+		ProtocolMinorVersion:       cli.ProtocolVersion.Minor,
+		MinUtxo:                    cli.MinUTxOValue,
+		MinPoolCost:                cli.MinPoolCost,
+		PriceMem:                   cli.ExecutionUnitPrices.PriceMemory,
+		PriceStep:                  cli.ExecutionUnitPrices.PriceSteps,
+		MaxTxExMem:                 cli.MaxTxExecutionUnits.Memory,
+		MaxTxExSteps:               cli.MaxTxExecutionUnits.Steps,
+		MaxBlockExMem:              cli.MaxBlockExecutionUnits.Memory,
+		MaxBlockExSteps:            cli.MaxBlockExecutionUnits.Steps,
+		MaxValSize:                 cli.MaxValueSize,
+		CollateralPercent:          cli.CollateralPercentage,
+		MaxCollateralInuts:         cli.MaxCollateralInputs,
+		CoinsPerUtxoWord:           cli.UtxoCostPerWord,
+		CoinsPerUtxoByte:           cli.UtxoCostPerByte,
+		CostModels:                 costModels,
+		PoolVotingThresholds:       cli.PoolVotingThresholds,
+		DRepVotingThresholds:       cli.DRepVotingThresholds,
+		CommitteeMinSize:           cli.CommitteeMinSize,
+		CommitteeMaxTermLength:     cli.CommitteeMaxTermLength,
+		GovActionLifetime:          cli.GovActionLifetime,
+		GovActionDeposit:           cli.GovActionDeposit,
+		DRepDeposit:                cli.DRepDeposit,
+		DRepActivity:               cli.DRepActivity,
+		MinFeeRefScriptCostPerByte: cli.MinFeeRefScriptCostPerByte,
+	}, nil
+}
+
+/*
+*
+
+	LoadCliProtocolParameters reads a file written with
+	`cardano-cli query protocol-parameters --out-file`.
+
+	Params:
+		path (string): The path of the file.
+
+	Returns:
+		ProtocolParameters: The protocol parameters.
+		error: An error if the file cannot be read or is invalid.
+*/
+func LoadCliProtocolParameters(path string) (ProtocolParameters, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ProtocolParameters{}, err
+	}
+	return ParseCliProtocolParameters(data)
+}
+
+// parseNonce reads a nonce either as a hex string or in the
+// genesis layout ({"tag": "NeutralNonce"} or {"tag": "Nonce", "contents": hex}).
+func parseNonce(raw json.RawMessage) string {
+	var nonce string
+	if json.Unmarshal(raw, &nonce) == nil {
+		return nonce
+	}
+	var tagged struct {
+		Contents string `json:"contents"`
+	}
+	if json.Unmarshal(raw, &tagged) == nil {
+		return tagged.Contents
+	}
+	return ""
+}
+
+type shelleyGenesis struct {
+	ActiveSlotsCoeff  float32     `json:"activeSlotsCoeff"`
+	EpochLength       int         `json:"epochLength"`
+	MaxKESEvolutions  int         `json:"maxKESEvolutions"`
+	MaxLovelaceSupply json.Number `json:"maxLovelaceSupply"`
+	NetworkMagic      int         `json:"networkMagic"`
+	SecurityParam     int         `json:"securityParam"`
+	SlotLength        float64     `json:"slotLength"`
+	SlotsPerKESPeriod int         `json:"slotsPerKESPeriod"`
+	SystemStart       time.Time   `json:"systemStart"`
+	UpdateQuorum      int         `json:"updateQuorum"`
+	ProtocolParams    struct {
+		A0                    Rational.Rational  `json:"a0"`
+		DecentralisationParam Rational.Rational  `json:"decentralisationParam"`
+		EMax                  int64              `json:"eMax"`
+		ExtraEntropy          json.RawMessage    `json:"extraEntropy"`
+		KeyDeposit            int64              `json:"keyDeposit"`
+		MaxBlockBodySize      int                `json:"maxBlockBodySize"`
+		MaxBlockHeaderSize    int                `json:"maxBlockHeaderSize"`
+		MaxTxSize             int                `json:"maxTxSize"`
+		MinFeeA               int                `json:"minFeeA"`
+		MinFeeB               int                `json:"minFeeB"`
+		MinPoolCost           int64              `json:"minPoolCost"`
+		MinUTxOValue          int64              `json:"minUTxOValue"`
+		NOpt                  int64              `json:"nOpt"`
+		PoolDeposit           int64              `json:"poolDeposit"`
+		ProtocolVersion       cliProtocolVersion `json:"protocolVersion"`
+		Rho                   Rational.Rational  `json:"rho"`
+		Tau                   Rational.Rational  `json:"tau"`
+	} `json:"protocolParams"`
+}
+
+type genesisExUnits struct {
+	Memory int64 `json:"exUnitsMem"`
+	Steps  int64 `json:"exUnitsSteps"`
+}
+
+type alonzoGenesis struct {
+	LovelacePerUTxOWord int64 `json:"lovelacePerUTxOWord"`
+	ExecutionPrices     struct {
+		PrSteps Rational.Rational `json:"prSteps"`
+		PrMem   Rational.Rational `json:"prMem"`
+	} `json:"executionPrices"`
+	MaxTxExUnits         genesisExUnits             `json:"maxTxExUnits"`
+	MaxBlockExUnits      genesisExUnits             `json:"maxBlockExUnits"`
+	MaxValueSize         int                        `json:"maxValueSize"`
+	CollateralPercentage int                        `json:"collateralPercentage"`
+	MaxCollateralInputs  int                        `json:"maxCollateralInputs"`
+	CostModels           map[string]json.RawMessage `json:"costModels"`
+}
+
+type conwayGenesis struct {
+	PoolVotingThresholds       PoolVotingThresholds `json:"poolVotingThresholds"`
+	DRepVotingThresholds       DRepVotingThresholds `json:"dRepVotingThresholds"`
+	CommitteeMinSize           int64                `json:"committeeMinSize"`
+	CommitteeMaxTermLength     int64                `json:"committeeMaxTermLength"`
+	GovActionLifetime          int64                `json:"govActionLifetime"`
+	GovActionDeposit           int64                `json:"govActionDeposit"`
+	DRepDeposit                int64                `json:"dRepDeposit"`
+	DRepActivity               int64                `json:"dRepActivity"`
+	MinFeeRefScriptCostPerByte Rational.Rational    `json:"minFeeRefScriptCostPerByte"`
+	PlutusV3CostModel          json.RawMessage      `json:"plutusV3CostModel"`
+}
+
+/*
+*
+
+	ParseGenesis reads the initial protocol parameters and the
+	genesis parameters of a network from its genesis files.
+	The Alonzo and Conway genesis are optional and may be nil.
+
+	Params:
+		shelley ([]byte): The Shelley genesis.
+		alonzo ([]byte): The Alonzo genesis.
+		conway ([]byte): The Conway genesis.
+
+	Returns:
+		ProtocolParameters: The protocol parameters.
+		GenesisParameters: The genesis parameters.
+		error: An error if one of the files is invalid.
+*/
+func ParseGenesis(shelley []byte, alonzo []byte, conway []byte) (ProtocolParameters, GenesisParameters, error) {
+	var sg shelleyGenesis
+	err := json.Unmarshal(shelley, &sg)
+	if err != nil {
+		return ProtocolParameters{}, GenesisParameters{}, fmt.Errorf("ParseGenesis: shelley: %v", err)
+	}
+	sp := sg.ProtocolParams
+	pp := ProtocolParameters{
+		MinFeeConstant:        sp.MinFeeB,
+		MinFeeCoefficient:     sp.MinFeeA,
+		MaxBlockSize:          sp.MaxBlockBodySize,
+		MaxTxSize:             sp.MaxTxSize,
+		MaxBlockHeaderSize:    sp.MaxBlockHeaderSize,
+		KeyDeposits:           sp.KeyDeposit,
+		PoolDeposits:          sp.PoolDeposit,
+		MaxEpoch:              sp.EMax,
+		NOpt:                  sp.NOpt,
+		PooolInfluence:        sp.A0,
+		MonetaryExpansion:     sp.Rho,
+		TreasuryExpansion:     sp.Tau,
+		DecentralizationParam: sp.DecentralisationParam,
+		ExtraEntropy:          parseNonce(sp.ExtraEntropy),
+		ProtocolMajorVersion:  sp.ProtocolVersion.Major,
+		ProtocolMinorVersion:  sp.ProtocolVersion.Minor,
+		MinUtxo:               sp.MinUTxOValue,
+		MinPoolCost:           sp.MinPoolCost,
+	}
+	gp := GenesisParameters{
+		ActiveSlotsCoefficient: sg.ActiveSlotsCoeff,
+		UpdateQuorum:           sg.UpdateQuorum,
+		MaxLovelaceSupply:      sg.MaxLovelaceSupply.String(),
+		NetworkMagic:           sg.NetworkMagic,
+		EpochLength:            sg.EpochLength,
+		SystemStart:            int(sg.SystemStart.Unix()),
+		SlotsPerKesPeriod:      sg.SlotsPerKESPeriod,
+		SlotLength:             int(sg.SlotLength),
+		MaxKesEvolutions:       sg.MaxKESEvolutions,
+		SecurityParam:          sg.SecurityParam,
+	}
+	if alonzo != nil {
+		var ag alonzoGenesis
+		err = json.Unmarshal(alonzo, &ag)
+		if err != nil {
+			return ProtocolParameters{}, GenesisParameters{}, fmt.Errorf("ParseGenesis: alonzo: %v", err)
+		}
+		pp.PriceMem = ag.ExecutionPrices.PrMem
+		pp.PriceStep = ag.ExecutionPrices.PrSteps
+		pp.MaxTxExMem = ag.MaxTxExUnits.Memory
+		pp.MaxTxExSteps = ag.MaxTxExUnits.Steps
+		pp.MaxBlockExMem = ag.MaxBlockExUnits.Memory
+		pp.MaxBlockExSteps = ag.MaxBlockExUnits.Steps
+		pp.MaxValSize = ag.MaxValueSize
+		pp.CollateralPercent = ag.CollateralPercentage
+		pp.MaxCollateralInuts = ag.MaxCollateralInputs
+		pp.CoinsPerUtxoWord = ag.LovelacePerUTxOWord
+		// Babbage translates the cost per word into a cost per byte.
+		pp.CoinsPerUtxoByte = ag.LovelacePerUTxOWord / 8
+		pp.CostModels, err = ParseCostModels(ag.CostModels)
+		if err != nil {
+			return ProtocolParameters{}, GenesisParameters{}, fmt.Errorf("ParseGenesis: alonzo: %v", err)
+		}
+	}
+	if conway != nil {
+		var cg conwayGenesis
+		err = json.Unmarshal(conway, &cg)
+		if err != nil {
+			return ProtocolParameters{}, GenesisParameters{}, fmt.Errorf("ParseGenesis: conway: %v", err)
+		}
+		pp.PoolVotingThresholds = cg.PoolVotingThresholds
+		pp.DRepVotingThresholds = cg.DRepVotingThresholds
+		pp.CommitteeMinSize = cg.CommitteeMinSize
+		pp.CommitteeMaxTermLength = cg.CommitteeMaxTermLength
+		pp.GovActionLifetime = cg.GovActionLifetime
+		pp.GovActionDeposit = cg.GovActionDeposit
+		pp.DRepDeposit = cg.DRepDeposit
+		pp.DRepActivity = cg.DRepActivity
+		pp.MinFeeRefScriptCostPerByte = cg.MinFeeRefScriptCostPerByte
+		if len(cg.PlutusV3CostModel) > 0 {
+			v3, err := ParseCostModels(map[string]json.RawMessage{PLUTUS_V3: cg.PlutusV3CostModel})
+			if err != nil {
+				return ProtocolParameters{}, GenesisParameters{}, fmt.Errorf("ParseGenesis: conway: %v", err)
+			}
+			if pp.CostModels == nil {
+				pp.CostModels = make(map[string][]int64)
+			}
+			pp.CostModels[PLUTUS_V3] = v3[PLUTUS_V3]
+		}
+	}
+	return pp, gp, nil
+}
+
+/*
+*
+
+	LoadGenesis reads the genesis files of a network, see
+	ParseGenesis. Empty paths are skipped for the Alonzo and
+	Conway genesis.
+
+	Params:
+		shelleyPath (string): The path of the Shelley genesis.
+		alonzoPath (string): The path of the Alonzo genesis.
+		conwayPath (string): The path of the Conway genesis.
+
+	Returns:
+		ProtocolParameters: The protocol parameters.
+		GenesisParameters: The genesis parameters.
+		error: An error if one of the files cannot be read or is invalid.
+*/
+func LoadGenesis(shelleyPath string, alonzoPath string, conwayPath string) (ProtocolParameters, GenesisParameters, error) {
+	files := make([][]byte, 3)
+	for i, path := range []string{shelleyPath, alonzoPath, conwayPath} {
+		if path == "" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return ProtocolParameters{}, GenesisParameters{}, err
+		}
+		files[i] = data
+	}
+	return ParseGenesis(files[0], files[1], files[2])
+}
@@ -0,0 +1,248 @@
+package Base
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/Salvionied/apollo/serialization/Rational"
+)
+
+const (
+	PLUTUS_V1 = "PlutusV1"
+	PLUTUS_V2 = "PlutusV2"
+	PLUTUS_V3 = "PlutusV3"
+)
+
+/*
+*
+
+	PoolVotingThresholds are the stake pool voting thresholds
+	introduced in Conway.
+*/
+type PoolVotingThresholds struct {
+	MotionNoConfidence    Rational.Rational `json:"motionNoConfidence"`
+	CommitteeNormal       Rational.Rational `json:"committeeNormal"`
+	CommitteeNoConfidence Rational.Rational `json:"committeeNoConfidence"`
+	HardForkInitiation    Rational.Rational `json:"hardForkInitiation"`
+	PPSecurityGroup       Rational.Rational `json:"ppSecurityGroup"`
+}
+
+/*
+*
+
+	DRepVotingThresholds are the delegated representative
+	voting thresholds introduced in Conway.
+*/
+type DRepVotingThresholds struct {
+	MotionNoConfidence    Rational.Rational `json:"motionNoConfidence"`
+	CommitteeNormal       Rational.Rational `json:"committeeNormal"`
+	CommitteeNoConfidence Rational.Rational `json:"committeeNoConfidence"`
+	UpdateToConstitution  Rational.Rational `json:"updateToConstitution"`
+	HardForkInitiation    Rational.Rational `json:"hardForkInitiation"`
+	PPNetworkGroup        Rational.Rational `json:"ppNetworkGroup"`
+	PPEconomicGroup       Rational.Rational `json:"ppEconomicGroup"`
+	PPTechnicalGroup      Rational.Rational `json:"ppTechnicalGroup"`
+	PPGovGroup            Rational.Rational `json:"ppGovGroup"`
+	TreasuryWithdrawal    Rational.Rational `json:"treasuryWithdrawal"`
+}
+
+/*
+*
+
+	CostModel returns the cost model of a Plutus language.
+
+	Params:
+		language (string): PLUTUS_V1, PLUTUS_V2 or PLUTUS_V3.
+
+	Returns:
+		[]int64: The cost model, nil if unknown.
+*/
+func (p ProtocolParameters) CostModel(language string) []int64 {
+	return p.CostModels[language]
+}
+
+var integerFields = func() map[string]bool {
+	fields := make(map[string]bool)
+	t := reflect.TypeOf(ProtocolParameters{})
+	for i := 0; i < t.NumField(); i++ {
+		switch t.Field(i).Type.Kind() {
+		case reflect.Int, reflect.Int64:
+			name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+			fields[name] = true
+		}
+	}
+	return fields
+}()
+
+/*
+*
+
+	UnmarshalJSON decodes the protocol parameters in the
+	Blockfrost layout, which serialises big integers as
+	strings and flattens the Conway voting thresholds.
+
+	Params:
+		data ([]byte): The JSON encoding.
+
+	Returns:
+		error: An error if the decoding fails.
+*/
+func (p *ProtocolParameters) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	err := json.Unmarshal(data, &fields)
+	if err != nil {
+		return err
+	}
+	if _, ok := fields["coins_per_utxo_byte"]; !ok {
+		if size, ok := fields["coins_per_utxo_size"]; ok {
+			fields["coins_per_utxo_byte"] = size
+		}
+	}
+	normalized := make(map[string]json.RawMessage, len(fields))
+	for key, value := range fields {
+		if integerFields[key] && len(value) > 0 && value[0] == '"' {
+			var quoted string
+			err = json.Unmarshal(value, &quoted)
+			if err != nil {
+				return err
+			}
+			if quoted == "" {
+				continue
+			}
+			value = json.RawMessage(quoted)
+		}
+		normalized[key] = value
+	}
+	if costModels, ok := normalized["cost_models_raw"]; ok {
+		delete(normalized, "cost_models_raw")
+		var raw map[string]json.RawMessage
+		err = json.Unmarshal(costModels, &raw)
+		if err != nil {
+			return fmt.Errorf("ProtocolParameters: UnmarshalJSON: %v", err)
+		}
+		p.CostModels, err = ParseCostModels(raw)
+		if err != nil {
+			return fmt.Errorf("ProtocolParameters: UnmarshalJSON: %v", err)
+		}
+	}
+	encoded, err := json.Marshal(normalized)
+	if err != nil {
+		return err
+	}
+	type plain ProtocolParameters
+	res := plain{CostModels: p.CostModels}
+	err = json.Unmarshal(encoded, &res)
+	if err != nil {
+		return fmt.Errorf("ProtocolParameters: UnmarshalJSON: %v", err)
+	}
+	parsed := ProtocolParameters(res)
+	for key, target := range parsed.flatThresholds() {
+		value, ok := fields[key]
+		if !ok {
+			continue
+		}
+		err = target.UnmarshalJSON(value)
+		if err != nil {
+			return fmt.Errorf("ProtocolParameters: UnmarshalJSON: %s: %v", key, err)
+		}
+	}
+	*p = parsed
+	return nil
+}
+
+func (p *ProtocolParameters) flatThresholds() map[string]*Rational.Rational {
+	return map[string]*Rational.Rational{
+		"pvt_motion_no_confidence":    &p.PoolVotingThresholds.MotionNoConfidence,
+		"pvt_committee_normal":        &p.PoolVotingThresholds.CommitteeNormal,
+		"pvt_committee_no_confidence": &p.PoolVotingThresholds.CommitteeNoConfidence,
+		"pvt_hard_fork_initiation":    &p.PoolVotingThresholds.HardForkInitiation,
+		"pvt_p_p_security_group":      &p.PoolVotingThresholds.PPSecurityGroup,
+		"pvtpp_security_group":        &p.PoolVotingThresholds.PPSecurityGroup,
+		"dvt_motion_no_confidence":    &p.DRepVotingThresholds.MotionNoConfidence,
+		"dvt_committee_normal":        &p.DRepVotingThresholds.CommitteeNormal,
+		"dvt_committee_no_confidence": &p.DRepVotingThresholds.CommitteeNoConfidence,
+		"dvt_update_to_constitution":  &p.DRepVotingThresholds.UpdateToConstitution,
+		"dvt_hard_fork_initiation":    &p.DRepVotingThresholds.HardForkInitiation,
+		"dvt_p_p_network_group":       &p.DRepVotingThresholds.PPNetworkGroup,
+		"dvt_p_p_economic_group":      &p.DRepVotingThresholds.PPEconomicGroup,
+		"dvt_p_p_technical_group":     &p.DRepVotingThresholds.PPTechnicalGroup,
+		"dvt_p_p_gov_group":           &p.DRepVotingThresholds.PPGovGroup,
+		"dvt_treasury_withdrawal":     &p.DRepVotingThresholds.TreasuryWithdrawal,
+	}
+}
+
+/*
+*
+
+	CostModelLanguage normalises the name a backend gives to
+	a Plutus language ("PlutusV2", "PlutusScriptV2", "plutus_v2",
+	"plutus:v2") to PLUTUS_V1, PLUTUS_V2 or PLUTUS_V3.
+
+	Params:
+		name (string): The name of the language.
+
+	Returns:
+		string: The normalised name.
+		error: An error if the language is unknown.
+*/
+func CostModelLanguage(name string) (string, error) {
+	switch strings.NewReplacer(":", "", "_", "", "script", "").Replace(strings.ToLower(name)) {
+	case "plutusv1":
+		return PLUTUS_V1, nil
+	case "plutusv2":
+		return PLUTUS_V2, nil
+	case "plutusv3":
+		return PLUTUS_V3, nil
+	}
+	return "", fmt.Errorf("unknown plutus language %s", name)
+}
+
+/*
+*
+
+	ParseCostModels reads cost models given either as arrays,
+	or as maps from parameter name to value as in the Alonzo
+	genesis, in which case the values are ordered by name.
+
+	Params:
+		raw (map[string]json.RawMessage): The cost models by language name.
+
+	Returns:
+		map[string][]int64: The cost models keyed by PLUTUS_V1, PLUTUS_V2 and PLUTUS_V3.
+		error: An error if a language or a cost model is invalid.
+*/
+func ParseCostModels(raw map[string]json.RawMessage) (map[string][]int64, error) {
+	costModels := make(map[string][]int64, len(raw))
+	for name, value := range raw {
+		if string(value) == "null" {
+			continue
+		}
+		language, err := CostModelLanguage(name)
+		if err != nil {
+			return nil, err
+		}
+		var values []int64
+		err = json.Unmarshal(value, &values)
+		if err != nil {
+			var named map[string]int64
+			err = json.Unmarshal(value, &named)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cost model %s: %v", name, err)
+			}
+			keys := make([]string, 0, len(named))
+			for key := range named {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			values = make([]int64, 0, len(keys))
+			for _, key := range keys {
+				values = append(values, named[key])
+			}
+		}
+		costModels[language] = values
+	}
+	return costModels, nil
+}
@@ -4,10 +4,10 @@ import (
 	"bytes"
 	"context"
 	"encoding/hex"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"strconv"
 	"strings"
@@ -19,6 +19,7 @@ import (
 	"github.com/Salvionied/apollo/serialization/Asset"
 	"github.com/Salvionied/apollo/serialization/AssetName"
 	"github.com/Salvionied/apollo/serialization/MultiAsset"
+	"github.com/Salvionied/apollo/serialization/NativeScript"
 	"github.com/Salvionied/apollo/serialization/PlutusData"
 	"github.com/Salvionied/apollo/serialization/Policy"
 	"github.com/Salvionied/apollo/serialization/Redeemer"
@@ -29,24 +30,77 @@ import (
 	"github.com/Salvionied/apollo/serialization/Value"
 	"github.com/Salvionied/apollo/txBuilding/Backend/Base"
 	"github.com/Salvionied/apollo/txBuilding/Backend/Cache"
+	"github.com/Salvionied/apollo/txBuilding/Backend/HttpClient"
 
 	"github.com/Salvionied/cbor/v2"
 )
 
+// The free tier of Blockfrost allows bursts of 500 requests,
+// refilled at 10 requests per second.
+const (
+	BLOCKFROST_RATE_LIMIT = 10
+	BLOCKFROST_BURST      = 500
+)
+
 type BlockFrostChainContext struct {
 	client                    *http.Client
 	_epoch_info               Base.Epoch
-	_epoch                    int
 	_Network                  int
 	_genesis_param            Base.GenesisParameters
 	_protocol_param           Base.ProtocolParameters
 	_baseUrl                  string
 	_projectId                string
 	ctx                       context.Context
+	cache                     Cache.Cache
+	namespace                 Cache.Namespace
 	CustomSubmissionEndpoints []string
+
+	// the epochs the parameters were fetched in, behind
+	// _epoch_info until a fetch in the new epoch succeeds
+	_genesis_epoch  int
+	_protocol_epoch int
+}
+
+/*
+*
+
+	NewBlockfrostChainContext creates a Blockfrost context,
+	use New to get the error of its initialization.
+
+	Params:
+		baseUrl (string): The url of the Blockfrost api.
+		network (int): The network.
+		projectId (string): The Blockfrost project id.
+		opts (...HttpClient.Option): The http client options.
+
+	Returns:
+		BlockFrostChainContext: The context.
+*/
+func NewBlockfrostChainContext(baseUrl string, network int, projectId string, opts ...HttpClient.Option) BlockFrostChainContext {
+	bfc, _ := New(baseUrl, network, projectId, opts...)
+	return bfc
 }
 
-func NewBlockfrostChainContext(baseUrl string, network int, projectId string) BlockFrostChainContext {
+/*
+*
+
+	New creates a Blockfrost context and fetches the current
+	epoch, genesis and protocol parameters. Requests are
+	retried following HttpClient.DEFAULT_RETRY_POLICY and
+	limited to BLOCKFROST_RATE_LIMIT per second unless
+	overridden by the options.
+
+	Params:
+		baseUrl (string): The url of the Blockfrost api.
+		network (int): The network.
+		projectId (string): The Blockfrost project id.
+		opts (...HttpClient.Option): The http client options.
+
+	Returns:
+		BlockFrostChainContext: The context.
+		error: The error of the initial requests.
+*/
+func New(baseUrl string, network int, projectId string, opts ...HttpClient.Option) (BlockFrostChainContext, error) {
 	ctx := context.Background()
 	file, err := ioutil.ReadFile("config.ini")
 	var cse []string
@@ -55,245 +109,302 @@ func NewBlockfrostChainContext(baseUrl string, network int, projectId string) Bl
 	} else {
 		cse = []string{}
 	}
-	// latest_epochs, err := api.Epoch(ctx)
-	// if err != nil {
-	// 	log.Fatal(err, "LATEST EPOCH")
-	// }
+	config := HttpClient.NewConfig(HttpClient.Config{
+		Retry:           HttpClient.DEFAULT_RETRY_POLICY,
+		Limiter:         HttpClient.NewRateLimiter(BLOCKFROST_RATE_LIMIT, BLOCKFROST_BURST),
+		IdempotentPaths: []string{"/utils/txs/evaluate"},
+		BaseUrl:         baseUrl,
+	}, opts...)
 
-	bfc := BlockFrostChainContext{client: &http.Client{}, _Network: network, _baseUrl: baseUrl, _projectId: projectId, ctx: ctx, CustomSubmissionEndpoints: cse}
-	bfc.Init()
-	return bfc
+	bfc := BlockFrostChainContext{client: config.HTTPClient(), _Network: network, _baseUrl: config.BaseUrl, _projectId: projectId, ctx: ctx, CustomSubmissionEndpoints: cse}
+	// the url tells apart the testnets sharing a network id
+	bfc.cache = Cache.DEFAULT_CACHE
+	bfc.namespace = Cache.Namespace{Backend: "blockfrost " + config.BaseUrl, Network: network}
+	err = bfc.Init()
+	return bfc, err
 }
-func (bfc *BlockFrostChainContext) Init() {
-	latest_epochs := bfc.LatestEpoch()
+
+func (bfc *BlockFrostChainContext) Init() error {
+	latest_epochs, err := bfc.LatestEpoch()
+	if err != nil {
+		return err
+	}
 	bfc._epoch_info = latest_epochs
 	//Init Genesis
-	params := bfc.GenesisParams()
+	params, err := bfc.GenesisParams()
+	if err != nil {
+		return err
+	}
 	bfc._genesis_param = params
+	bfc._genesis_epoch = latest_epochs.Epoch
 	//init epoch
-	latest_params := bfc.LatestEpochParams()
+	latest_params, err := bfc.LatestEpochParams()
+	if err != nil {
+		return err
+	}
 	bfc._protocol_param = latest_params
+	bfc._protocol_epoch = latest_epochs.Epoch
+	return nil
+}
+
+func (bfc *BlockFrostChainContext) request(method string, url string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(bfc.ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("project_id", bfc._projectId)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/cbor")
+	}
+	res, err := bfc.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := HttpClient.CheckResponse(res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (bfc *BlockFrostChainContext) get(path string, out any) error {
+	res, err := bfc.request("GET", bfc._baseUrl+path, nil)
+	if err != nil {
+		return err
+	}
+	return HttpClient.DecodeJSON(res, out)
 }
 
 func (bfc *BlockFrostChainContext) GetUtxoFromRef(txHash string, index int) *UTxO.UTxO {
-	txOuts := bfc.TxOuts(txHash)
+	txOuts, err := bfc.TxOuts(txHash)
+	if err != nil {
+		return nil
+	}
 	for _, txOut := range txOuts {
 		if txOut.OutputIndex == index {
+			if txOut.ReferenceScriptHash != "" {
+				txOut.ReferenceScript = bfc.referenceScript(txOut.ReferenceScriptHash)
+			}
 			return txOut.ToUTxO(txHash)
 		}
 	}
 	return nil
 }
 
-func (bfc *BlockFrostChainContext) TxOuts(txHash string) []Base.Output {
-	req, _ := http.NewRequest("GET", fmt.Sprintf("%s/v0/txs/%s/utxos", bfc._baseUrl, txHash), nil)
-	req.Header.Set("project_id", bfc._projectId)
-	res, err := bfc.client.Do(req)
-	if err != nil {
-		log.Fatal(err, "REQUEST PROTOCOL")
-	}
-	body, err := ioutil.ReadAll(res.Body)
+func (bfc *BlockFrostChainContext) TxOuts(txHash string) ([]Base.Output, error) {
 	var response Base.TxUtxos
-	err = json.Unmarshal(body, &response)
+	err := bfc.get(fmt.Sprintf("/v0/txs/%s/utxos", txHash), &response)
 	if err != nil {
-		log.Fatal(err, "UNMARSHAL PROTOCOL")
+		return nil, err
 	}
-	return response.Outputs
+	return response.Outputs, nil
+}
 
+func (bfc *BlockFrostChainContext) LatestBlock() (Base.Block, error) {
+	var response Base.Block
+	err := bfc.get("/v0/blocks/latest", &response)
+	return response, err
 }
 
-func (bfc *BlockFrostChainContext) LatestBlock() Base.Block {
-	req, _ := http.NewRequest("GET", fmt.Sprintf("%s/v0/blocks/latest", bfc._baseUrl), nil)
-	req.Header.Set("project_id", bfc._projectId)
-	res, err := bfc.client.Do(req)
-	if err != nil {
-		log.Fatal(err, "REQUEST PROTOCOL")
+func (bfc *BlockFrostChainContext) LatestEpoch() (Base.Epoch, error) {
+	var response Base.Epoch
+	err := bfc.get("/v0/epochs/latest", &response)
+	return response, err
+}
+
+// cached serves a value that never changes once published, the
+// parameters of an epoch or the genesis, from the cache of the
+// context, fetching it from path when it is missing.
+func cached[T any](bfc *BlockFrostChainContext, key []string, path string) (T, error) {
+	cacheKey := bfc.namespace.Key(key...)
+	res, found, err := Cache.Load[T](bfc.cache, cacheKey)
+	if err == nil && found {
+		return res, nil
 	}
-	body, err := ioutil.ReadAll(res.Body)
-	var response Base.Block
-	err = json.Unmarshal(body, &response)
+	var response T
+	err = bfc.get(path, &response)
 	if err != nil {
-		log.Fatal(err, "UNMARSHAL PROTOCOL")
+		return response, err
 	}
-	return response
+	// a failure to cache only costs a request next time
+	_ = Cache.Save(bfc.cache, cacheKey, response, 0)
+	return response, nil
 }
 
-func (bfc *BlockFrostChainContext) LatestEpoch() Base.Epoch {
-	res := Base.Epoch{}
-	found := Cache.Get[Base.Epoch]("latest_epoch", &res)
-	timest := time.Time{}
-	foundTime := Cache.Get[time.Time]("latest_epoch_time", &timest)
-	if !found || !foundTime || time.Since(timest) > 5*time.Minute {
-		req, _ := http.NewRequest("GET", fmt.Sprintf("%s/v0/epochs/latest", bfc._baseUrl), nil)
-		req.Header.Set("project_id", bfc._projectId)
-		res, err := bfc.client.Do(req)
-		if err != nil {
-			log.Fatal(err, "REQUEST PROTOCOL")
-		}
-		body, err := ioutil.ReadAll(res.Body)
-		var response Base.Epoch
-		err = json.Unmarshal(body, &response)
-		if err != nil {
-			log.Fatal(err, "UNMARSHAL PROTOCOL")
-		}
-		Cache.Set("latest_epoch", response)
-		now := time.Now()
-		Cache.Set("latest_epoch_time", now)
-		return response
-	} else {
-		return res
-	}
-}
-func (bfc *BlockFrostChainContext) AddressUtxos(address string, gather bool) []Base.AddressUTXO {
-	if gather {
-		var i = 1
-		result := make([]Base.AddressUTXO, 0)
-		for {
-			req, _ := http.NewRequest("GET", fmt.Sprintf("%s/v0/addresses/%s/utxos?page=%s", bfc._baseUrl, address, fmt.Sprint(i)), nil)
-			req.Header.Set("project_id", bfc._projectId)
-			res, err := bfc.client.Do(req)
-			if err != nil {
-				log.Fatal(err, "REQUEST PROTOCOL")
-			}
-			body, err := ioutil.ReadAll(res.Body)
-			var response []Base.AddressUTXO
-			err = json.Unmarshal(body, &response)
-			if len(response) == 0 {
-				break
-			}
-			if err != nil {
-				log.Fatal(err, "UNMARSHAL PROTOCOL")
-			}
-			result = append(result, response...)
-			i++
+/*
+*
+
+	AddressUtxos fetches the UTxOs of an address, Blockfrost
+	answers 404 for addresses never used on chain.
+
+	Params:
+		address (string): The bech32 address.
+		gather (bool): Whether to fetch every page or only the first one.
+
+	Returns:
+		[]Base.AddressUTXO: The UTxOs of the address.
+		error: The error of the requests.
+*/
+func (bfc *BlockFrostChainContext) AddressUtxos(address string, gather bool) ([]Base.AddressUTXO, error) {
+	result := make([]Base.AddressUTXO, 0)
+	for page := 1; ; page++ {
+		var response []Base.AddressUTXO
+		err := bfc.get(fmt.Sprintf("/v0/addresses/%s/utxos?page=%d", address, page), &response)
+		if errors.Is(err, HttpClient.ErrNotFound) {
+			break
 		}
-		return result
-	} else {
-		req, _ := http.NewRequest("GET", fmt.Sprintf("%s/v0/addresses/%s/utxos", bfc._baseUrl, address), nil)
-		req.Header.Set("project_id", bfc._projectId)
-		res, err := bfc.client.Do(req)
 		if err != nil {
-			log.Fatal(err, "REQUEST PROTOCOL")
+			return nil, err
 		}
-		body, err := ioutil.ReadAll(res.Body)
-		var response []Base.AddressUTXO
-		err = json.Unmarshal(body, &response)
-		if err != nil {
-			log.Fatal(err, "UNMARSHAL PROTOCOL")
+		result = append(result, response...)
+		if !gather || len(response) == 0 {
+			break
 		}
-		return response
 	}
+	return result, nil
 }
 
-func (bfc *BlockFrostChainContext) LatestEpochParams() Base.ProtocolParameters {
-	pm := Base.ProtocolParameters{}
-	found := Cache.Get[Base.ProtocolParameters]("latest_epoch_params", &pm)
-	timest := time.Time{}
-	foundTime := Cache.Get[time.Time]("latest_epoch_params_time", &timest)
-	if !found || !foundTime || time.Since(timest) > 5*time.Minute {
-		req, _ := http.NewRequest("GET", fmt.Sprintf("%s/v0/epochs/latest/parameters", bfc._baseUrl), nil)
-		req.Header.Set("project_id", bfc._projectId)
-		res, err := bfc.client.Do(req)
-		if err != nil {
-			log.Fatal(err, "REQUEST PROTOCOL")
-		}
-		body, err := ioutil.ReadAll(res.Body)
-		var response = Base.ProtocolParameters{}
-		err = json.Unmarshal(body, &response)
-		if err != nil {
-			log.Fatal(err, "UNMARSHAL PROTOCOL")
-		}
-		Cache.Set("latest_epoch_params", response)
-		now := time.Now()
-		Cache.Set("latest_epoch_params_time", now)
-		return response
-	} else {
-		return pm
+/*
+*
+
+	LatestEpochParams fetches the protocol parameters of the
+	current epoch, cached under the number of the epoch.
+
+	Returns:
+		Base.ProtocolParameters: The protocol parameters.
+		error: The error of the requests.
+*/
+func (bfc *BlockFrostChainContext) LatestEpochParams() (Base.ProtocolParameters, error) {
+	if err := bfc.checkEpoch(); err != nil {
+		return Base.ProtocolParameters{}, err
 	}
+	epoch := bfc._epoch_info.Epoch
+	return cached[Base.ProtocolParameters](bfc,
+		[]string{"epoch", fmt.Sprint(epoch), "protocol_params"},
+		fmt.Sprintf("/v0/epochs/%d/parameters", epoch))
 }
 
-func (bfc *BlockFrostChainContext) GenesisParams() Base.GenesisParameters {
-	gp := Base.GenesisParameters{}
-	found := Cache.Get[Base.GenesisParameters]("genesis_params", &gp)
-	timestamp := ""
-	foundTime := Cache.Get[string]("genesis_params_time", &timestamp)
-	timest := time.Time{}
-	if timestamp != "" {
-		timest, _ = time.Parse(time.RFC3339, timestamp)
-	}
-	if !found || !foundTime || time.Since(timest) > 5*time.Minute {
-		req, _ := http.NewRequest("GET", fmt.Sprintf("%s/v0/Genesis", bfc._baseUrl), nil)
-		req.Header.Set("project_id", bfc._projectId)
-		res, err := bfc.client.Do(req)
-		if err != nil {
-			log.Fatal(err, "REQUEST PROTOCOL")
-		}
-		body, err := ioutil.ReadAll(res.Body)
-		var response = Base.GenesisParameters{}
-		err = json.Unmarshal(body, &response)
-		if err != nil {
-			log.Fatal(err, "UNMARSHAL PROTOCOL")
-		}
-		Cache.Set("genesis_params", response)
-		now := time.Now()
-		Cache.Set("genesis_params_time", now)
-		return response
-	} else {
+func (bfc *BlockFrostChainContext) GenesisParams() (Base.GenesisParameters, error) {
+	return cached[Base.GenesisParameters](bfc, []string{"genesis_params"}, "/v0/Genesis")
+}
 
-		return gp
+// checkEpoch fetches the latest epoch once the known one has
+// ended. The values depending on the epoch are refreshed by
+// their getters, so that a failed fetch is retried next time.
+func (bfc *BlockFrostChainContext) checkEpoch() error {
+	if bfc._epoch_info.EndTime > int(time.Now().Unix()) {
+		return nil
 	}
-}
-func (bfc *BlockFrostChainContext) _CheckEpochAndUpdate() bool {
-	if bfc._epoch_info.EndTime <= int(time.Now().Unix()) {
-		latest_epochs := bfc.LatestEpoch()
-		bfc._epoch_info = latest_epochs
-		return true
+	latest_epochs, err := bfc.LatestEpoch()
+	if err != nil {
+		return err
 	}
-	return false
+	bfc._epoch_info = latest_epochs
+	return nil
 }
 
 func (bfc *BlockFrostChainContext) Network() int {
 	return bfc._Network
 }
 
+// LoadsWalletUtxos implements Base.WalletUtxoLoader.
+func (bfc *BlockFrostChainContext) LoadsWalletUtxos() bool {
+	return true
+}
+
+/*
+*
+
+	WithContext implements Base.ContextBinder, the requests of
+	the copy being made with ctx. The copy keeps its own epoch,
+	the parameters it fetches being shared through the cache.
+
+	Params:
+		ctx (context.Context): The context of the requests.
+
+	Returns:
+		Base.ChainContext: The bound context.
+*/
+func (bfc *BlockFrostChainContext) WithContext(ctx context.Context) Base.ChainContext {
+	bound := *bfc
+	bound.ctx = ctx
+	return &bound
+}
+
 func (bfc *BlockFrostChainContext) Epoch() int {
-	if bfc._CheckEpochAndUpdate() {
-		new_epoch := bfc.LatestEpoch()
-		bfc._epoch = new_epoch.Epoch
-	}
-	return bfc._epoch
+	_ = bfc.checkEpoch()
+	return bfc._epoch_info.Epoch
 }
 
 func (bfc *BlockFrostChainContext) LastBlockSlot() int {
-	block := bfc.LatestBlock()
+	block, _ := bfc.LatestBlock()
 	return block.Slot
 }
 
 func (bfc *BlockFrostChainContext) GetGenesisParams() Base.GenesisParameters {
-	if bfc._CheckEpochAndUpdate() {
-		params := bfc.GenesisParams()
-		bfc._genesis_param = params
+	_ = bfc.checkEpoch()
+	if bfc._genesis_epoch != bfc._epoch_info.Epoch {
+		params, err := bfc.GenesisParams()
+		if err == nil {
+			bfc._genesis_param = params
+			bfc._genesis_epoch = bfc._epoch_info.Epoch
+		}
 	}
 	return bfc._genesis_param
 }
 
 func (bfc *BlockFrostChainContext) GetProtocolParams() Base.ProtocolParameters {
-	if bfc._CheckEpochAndUpdate() {
-		latest_params := bfc.LatestEpochParams()
-		bfc._protocol_param = latest_params
+	_ = bfc.checkEpoch()
+	if bfc._protocol_epoch != bfc._epoch_info.Epoch {
+		latest_params, err := bfc.LatestEpochParams()
+		if err == nil {
+			bfc._protocol_param = latest_params
+			bfc._protocol_epoch = bfc._epoch_info.Epoch
+		}
 	}
 	return bfc._protocol_param
 }
 
 func (bfc *BlockFrostChainContext) MaxTxFee() int {
 	protocol_param := bfc.GetProtocolParams()
-	maxTxExSteps, _ := strconv.Atoi(protocol_param.MaxTxExSteps)
-	maxTxExMem, _ := strconv.Atoi(protocol_param.MaxTxExMem)
-	return Base.Fee(bfc, protocol_param.MaxTxSize, maxTxExSteps, maxTxExMem)
+	return Base.Fee(bfc, protocol_param.MaxTxSize, int(protocol_param.MaxTxExSteps), int(protocol_param.MaxTxExMem))
 }
 
+/*
+*
+
+	Utxos fetches the UTxOs of an address, dropping the errors
+	of the requests: use FetchUtxos to tell a failure from an
+	empty address.
+
+	Params:
+		address (Address.Address): The address.
+
+	Returns:
+		[]UTxO.UTxO: The UTxOs of the address, empty on failure.
+*/
 func (bfc *BlockFrostChainContext) Utxos(address Address.Address) []UTxO.UTxO {
-	results := bfc.AddressUtxos(address.String(), true)
+	utxos, _ := bfc.FetchUtxos(address)
+	return utxos
+}
+
+/*
+*
+
+	FetchUtxos fetches the UTxOs of an address, as Utxos
+	but returning the errors instead of an empty list.
+
+	Params:
+		address (Address.Address): The address.
+
+	Returns:
+		[]UTxO.UTxO: The UTxOs of the address.
+		error: The error of the requests or of the decoding of a datum.
+*/
+func (bfc *BlockFrostChainContext) FetchUtxos(address Address.Address) ([]UTxO.UTxO, error) {
+	results, err := bfc.AddressUtxos(address.String(), true)
+	if err != nil {
+		return nil, err
+	}
 	utxos := make([]UTxO.UTxO, 0)
 	for _, result := range results {
 		decodedTxId, _ := hex.DecodeString(result.TxHash)
@@ -335,7 +446,7 @@ func (bfc *BlockFrostChainContext) Utxos(address Address.Address) []UTxO.UTxO {
 			var x PlutusData.PlutusData
 			err := cbor.Unmarshal(decoded, &x)
 			if err != nil {
-				log.Fatal(err)
+				return nil, fmt.Errorf("decoding the inline datum of %s#%d: %w", result.TxHash, result.OutputIndex, err)
 			}
 			l := PlutusData.DatumOptionInline(&x)
 
@@ -354,81 +465,65 @@ func (bfc *BlockFrostChainContext) Utxos(address Address.Address) []UTxO.UTxO {
 		}
 		utxos = append(utxos, UTxO.UTxO{Input: tx_in, Output: tx_out})
 	}
-	return utxos
+	return utxos, nil
 }
-func (bfc *BlockFrostChainContext) SpecialSubmitTx(tx Transaction.Transaction, logger chan string) serialization.TransactionId {
-	txBytes, _ := cbor.Marshal(tx)
-	if bfc.CustomSubmissionEndpoints != nil {
-		logger <- ("Custom Submission Endpoints Found, submitting...")
-		for _, endpoint := range bfc.CustomSubmissionEndpoints {
+
+// submitToCustomEndpoints forwards the transaction to the custom
+// submission endpoints, their failures do not prevent the
+// submission to Blockfrost.
+func (bfc *BlockFrostChainContext) submitToCustomEndpoints(txBytes []byte, logger chan string) {
+	for _, endpoint := range bfc.CustomSubmissionEndpoints {
+		if strings.TrimSpace(endpoint) == "" {
+			continue
+		}
+		if logger != nil {
 			logger <- fmt.Sprint("TRYING WITH:", endpoint)
-			req, _ := http.NewRequest("POST", endpoint, bytes.NewBuffer(txBytes))
-			req.Header.Set("project_id", bfc._projectId)
-			req.Header.Set("Content-Type", "application/cbor")
-			res, err := bfc.client.Do(req)
-			if err != nil {
-				log.Fatal(err, "REQUEST PROTOCOL")
+		}
+		res, err := bfc.request("POST", endpoint, bytes.NewReader(txBytes))
+		if err != nil {
+			if logger != nil {
+				logger <- fmt.Sprint("ERROR:", err)
 			}
-			body, err := ioutil.ReadAll(res.Body)
-			var response any
-			err = json.Unmarshal(body, &response)
+			continue
+		}
+		var response any
+		err = HttpClient.DecodeJSON(res, &response)
+		if logger != nil {
 			if err != nil {
-				log.Fatal(err, "UNMARSHAL PROTOCOL")
+				logger <- fmt.Sprint("ERROR:", err)
+			} else {
+				logger <- fmt.Sprint("RESPONSE:", response)
 			}
-			logger <- fmt.Sprint("RESPONSE:", response)
 		}
 	}
-	req, _ := http.NewRequest("POST", fmt.Sprintf("%s/v0/tx/submit", bfc._baseUrl), bytes.NewBuffer(txBytes))
-	req.Header.Set("project_id", bfc._projectId)
-	req.Header.Set("Content-Type", "application/cbor")
-	res, err := bfc.client.Do(req)
-	if err != nil {
-		log.Fatal(err, "REQUEST PROTOCOL")
-	}
-	body, err := ioutil.ReadAll(res.Body)
-	var response any
-	err = json.Unmarshal(body, &response)
-	if err != nil {
-		log.Fatal(err, "UNMARSHAL PROTOCOL")
+}
+
+func (bfc *BlockFrostChainContext) SpecialSubmitTx(tx Transaction.Transaction, logger chan string) (serialization.TransactionId, error) {
+	if len(bfc.CustomSubmissionEndpoints) > 0 {
+		logger <- ("Custom Submission Endpoints Found, submitting...")
 	}
-	hash, _ := tx.TransactionBody.Hash()
-	return serialization.TransactionId{Payload: hash}
+	return bfc.submit(tx, logger)
 }
+
 func (bfc *BlockFrostChainContext) SubmitTx(tx Transaction.Transaction) (serialization.TransactionId, error) {
-	txBytes, _ := cbor.Marshal(tx)
-	if bfc.CustomSubmissionEndpoints != nil {
-		for _, endpoint := range bfc.CustomSubmissionEndpoints {
-			req, _ := http.NewRequest("POST", endpoint, bytes.NewBuffer(txBytes))
-			req.Header.Set("project_id", bfc._projectId)
-			req.Header.Set("Content-Type", "application/cbor")
-			res, err := bfc.client.Do(req)
-			if err != nil {
-				log.Fatal(err, "REQUEST PROTOCOL")
-			}
-			body, err := ioutil.ReadAll(res.Body)
-			var response any
-			err = json.Unmarshal(body, &response)
-			if err != nil {
-				log.Fatal(err, "UNMARSHAL PROTOCOL")
-			}
-		}
+	return bfc.submit(tx, nil)
+}
+
+func (bfc *BlockFrostChainContext) submit(tx Transaction.Transaction, logger chan string) (serialization.TransactionId, error) {
+	txBytes, err := cbor.Marshal(tx)
+	if err != nil {
+		return serialization.TransactionId{}, err
 	}
-	req, _ := http.NewRequest("POST", fmt.Sprintf("%s/v0/tx/submit", bfc._baseUrl), bytes.NewBuffer(txBytes))
-	req.Header.Set("project_id", bfc._projectId)
-	req.Header.Set("Content-Type", "application/cbor")
-	res, err := bfc.client.Do(req)
+	bfc.submitToCustomEndpoints(txBytes, logger)
+	res, err := bfc.request("POST", fmt.Sprintf("%s/v0/tx/submit", bfc._baseUrl), bytes.NewReader(txBytes))
 	if err != nil {
-		log.Fatal(err, "REQUEST PROTOCOL")
+		return serialization.TransactionId{}, fmt.Errorf("error submitting tx: %w", err)
 	}
-	body, err := ioutil.ReadAll(res.Body)
 	var response any
-	err = json.Unmarshal(body, &response)
+	err = HttpClient.DecodeJSON(res, &response)
 	if err != nil {
 		return serialization.TransactionId{}, err
 	}
-	if res.Status != "200 OK" {
-		return serialization.TransactionId{}, fmt.Errorf("error submitting tx: %v", response)
-	}
 	hash, err := tx.TransactionBody.Hash()
 	if err != nil {
 		return serialization.TransactionId{}, err
@@ -445,31 +540,205 @@ type ExecutionResult struct {
 }
 
 func (bfc *BlockFrostChainContext) EvaluateTx(tx []byte) map[string]Redeemer.ExecutionUnits {
-	encoded := hex.EncodeToString(tx)
-	req, _ := http.NewRequest("POST", fmt.Sprintf("%s/v0/utils/txs/evaluate", bfc._baseUrl), strings.NewReader(encoded))
-	req.Header.Set("project_id", bfc._projectId)
-	req.Header.Set("Content-Type", "application/cbor")
-	res, err := bfc.client.Do(req)
+	final_result, err := bfc.FetchEvaluation(tx)
 	if err != nil {
-		log.Fatal(err, "REQUEST PROTOCOL")
+		return make(map[string]Redeemer.ExecutionUnits, 0)
 	}
-	body, err := ioutil.ReadAll(res.Body)
-	var x any
-	err = json.Unmarshal(body, &x)
+	return final_result
+}
+
+/*
+*
+
+	FetchEvaluation evaluates the scripts of a transaction, as
+	EvaluateTx but returning the error instead of an empty map.
+
+	Params:
+		tx ([]byte): The cbor of the transaction.
+
+	Returns:
+		map[string]Redeemer.ExecutionUnits: The execution units by redeemer.
+		error: The error of the request.
+*/
+func (bfc *BlockFrostChainContext) FetchEvaluation(tx []byte) (map[string]Redeemer.ExecutionUnits, error) {
+	encoded := hex.EncodeToString(tx)
+	res, err := bfc.request("POST", fmt.Sprintf("%s/v0/utils/txs/evaluate", bfc._baseUrl), strings.NewReader(encoded))
 	if err != nil {
-		log.Fatal(err, "UNMARSHAL PROTOCOL")
+		return nil, err
 	}
 	var response ExecutionResult
-	err = json.Unmarshal(body, &response)
+	err = HttpClient.DecodeJSON(res, &response)
 	if err != nil {
-		log.Fatal(err, "UNMARSHAL PROTOCOL")
+		return nil, err
 	}
 	final_result := make(map[string]Redeemer.ExecutionUnits, 0)
 	for k, v := range response.Result.Result {
 
 		final_result[k] = Redeemer.ExecutionUnits{Steps: int64(v["steps"]), Mem: int64(v["memory"])}
 	}
-	return final_result
+	return final_result, nil
+}
+
+type BlockfrostScript struct {
+	Type string `json:"type"`
+}
+
+var BLOCKFROST_SCRIPT_TYPES = map[string]uint64{
+	"timelock": 0,
+	"plutusV1": 1,
+	"plutusV2": 2,
+	"plutusV3": 3,
+}
+
+/*
+*
+
+	referenceScript fetches the script with the given hash so
+	that it can be attached to the output carrying it.
+
+	Params:
+		scriptHash (string): The hash of the script.
+
+	Returns:
+		*PlutusData.ScriptRef: The script or nil if it cannot be fetched.
+*/
+func (bfc *BlockFrostChainContext) referenceScript(scriptHash string) *PlutusData.ScriptRef {
+	scriptRef, err := bfc.GetScript(scriptHash)
+	if err != nil {
+		return nil
+	}
+	return scriptRef
+}
+
+// BlockfrostNativeScript is the JSON form of a native script
+// served by /scripts/{hash}/json.
+type BlockfrostNativeScript struct {
+	Type     string                   `json:"type"`
+	KeyHash  string                   `json:"keyHash"`
+	Required int                      `json:"required"`
+	Slot     int64                    `json:"slot"`
+	Scripts  []BlockfrostNativeScript `json:"scripts"`
+}
+
+type BlockfrostScriptJson struct {
+	Json *BlockfrostNativeScript `json:"json"`
+}
+
+/*
+*
+
+	ToNativeScript converts the JSON form of a native script.
+
+	Returns:
+		NativeScript.NativeScript: The native script.
+		error: An error if the script type is unknown.
+*/
+func (bns BlockfrostNativeScript) ToNativeScript() (NativeScript.NativeScript, error) {
+	scripts := make([]NativeScript.NativeScript, 0, len(bns.Scripts))
+	for _, script := range bns.Scripts {
+		nativeScript, err := script.ToNativeScript()
+		if err != nil {
+			return NativeScript.NativeScript{}, err
+		}
+		scripts = append(scripts, nativeScript)
+	}
+	switch bns.Type {
+	case "sig":
+		keyHash, err := hex.DecodeString(bns.KeyHash)
+		if err != nil {
+			return NativeScript.NativeScript{}, fmt.Errorf("invalid key hash: %w", err)
+		}
+		return NativeScript.NewScriptPubKey(keyHash), nil
+	case "all":
+		return NativeScript.NewScriptAll(scripts), nil
+	case "any":
+		return NativeScript.NewScriptAny(scripts), nil
+	case "atLeast":
+		return NativeScript.NewScriptNofK(scripts, bns.Required), nil
+	case "after":
+		return NativeScript.NewInvalidBefore(bns.Slot), nil
+	case "before":
+		return NativeScript.NewInvalidHereafter(bns.Slot), nil
+	default:
+		return NativeScript.NativeScript{}, fmt.Errorf("unknown native script type %q", bns.Type)
+	}
+}
+
+/*
+*
+
+	GetScript fetches a script by its hash together with its
+	language. Native scripts are rebuilt from their JSON form.
+
+	Params:
+		scriptHash (string): The hex encoded hash of the script.
+
+	Returns:
+		*PlutusData.ScriptRef: The script.
+		error: The error of the request, matching Base.ErrScriptNotFound
+			if Blockfrost does not know the script.
+*/
+func (bfc *BlockFrostChainContext) GetScript(scriptHash string) (*PlutusData.ScriptRef, error) {
+	var script BlockfrostScript
+	err := bfc.get(fmt.Sprintf("/v0/scripts/%s", scriptHash), &script)
+	if errors.Is(err, HttpClient.ErrNotFound) {
+		return nil, fmt.Errorf("%w: %s: %w", Base.ErrScriptNotFound, scriptHash, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	scriptType, ok := BLOCKFROST_SCRIPT_TYPES[script.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown script type %q", script.Type)
+	}
+	if scriptType == 0 {
+		var response BlockfrostScriptJson
+		err = bfc.get(fmt.Sprintf("/v0/scripts/%s/json", scriptHash), &response)
+		if err != nil {
+			return nil, err
+		}
+		if response.Json == nil {
+			return nil, fmt.Errorf("%w: %s: no json", Base.ErrScriptNotFound, scriptHash)
+		}
+		nativeScript, err := response.Json.ToNativeScript()
+		if err != nil {
+			return nil, err
+		}
+		encoded, err := cbor.Marshal(&nativeScript)
+		if err != nil {
+			return nil, err
+		}
+		scriptRef := PlutusData.NewScriptRef(0, encoded)
+		if err := Base.CheckScriptHash(&scriptRef, scriptHash); err != nil {
+			return nil, err
+		}
+		return &scriptRef, nil
+	}
+	scriptCbor, err := bfc.FetchContractCbor(scriptHash)
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := hex.DecodeString(scriptCbor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid script hex: %w", err)
+	}
+	if len(decoded) == 0 {
+		return nil, fmt.Errorf("%w: %s: no cbor", Base.ErrScriptNotFound, scriptHash)
+	}
+	// Depending on how the script was serialised the bytes may be
+	// wrapped in an extra byte string: keep the form matching the hash.
+	var unwrapped []byte
+	if cbor.Unmarshal(decoded, &unwrapped) == nil {
+		candidate := PlutusData.NewScriptRef(scriptType, unwrapped)
+		if Base.CheckScriptHash(&candidate, scriptHash) == nil {
+			return &candidate, nil
+		}
+	}
+	scriptRef := PlutusData.NewScriptRef(scriptType, decoded)
+	if err := Base.CheckScriptHash(&scriptRef, scriptHash); err != nil {
+		return nil, err
+	}
+	return &scriptRef, nil
 }
 
 type BlockfrostContractCbor struct {
@@ -477,17 +746,54 @@ type BlockfrostContractCbor struct {
 }
 
 func (bfc *BlockFrostChainContext) GetContractCbor(scriptHash string) string {
-	req, _ := http.NewRequest("GET", fmt.Sprintf("%s/v0/scripts/%s/cbor", bfc._baseUrl, scriptHash), nil)
-	req.Header.Set("project_id", bfc._projectId)
-	res, err := bfc.client.Do(req)
+	scriptCbor, _ := bfc.FetchContractCbor(scriptHash)
+	return scriptCbor
+}
+
+/*
+*
+
+	FetchContractCbor fetches the cbor of a script, as
+	GetContractCbor but returning the error instead of an
+	empty string.
+
+	Params:
+		scriptHash (string): The hash of the script.
+
+	Returns:
+		string: The hex encoded cbor of the script.
+		error: The error of the request.
+*/
+func (bfc *BlockFrostChainContext) FetchContractCbor(scriptHash string) (string, error) {
+	var response BlockfrostContractCbor
+	err := bfc.get(fmt.Sprintf("/v0/scripts/%s/cbor", scriptHash), &response)
 	if err != nil {
-		log.Fatal(err, "REQUEST PROTOCOL")
+		return "", err
 	}
-	body, err := ioutil.ReadAll(res.Body)
+	return response.Cbor, nil
+}
+
+/*
+*
+
+	ResolveDatum fetches a datum by its hash.
+
+	Params:
+		datumHash (string): The hex encoded hash of the datum.
+
+	Returns:
+		*PlutusData.PlutusData: The datum.
+		error: The error of the request, matching Base.ErrDatumNotFound
+			if Blockfrost does not know the datum.
+*/
+func (bfc *BlockFrostChainContext) ResolveDatum(datumHash string) (*PlutusData.PlutusData, error) {
 	var response BlockfrostContractCbor
-	err = json.Unmarshal(body, &response)
+	err := bfc.get(fmt.Sprintf("/v0/scripts/datum/%s/cbor", datumHash), &response)
+	if errors.Is(err, HttpClient.ErrNotFound) {
+		return nil, fmt.Errorf("%w: %s: %w", Base.ErrDatumNotFound, datumHash, err)
+	}
 	if err != nil {
-		log.Fatal(err, "UNMARSHAL PROTOCOL")
+		return nil, err
 	}
-	return response.Cbor
+	return Base.DecodeDatum(response.Cbor)
 }
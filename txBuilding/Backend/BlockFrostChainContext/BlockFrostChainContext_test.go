@@ -0,0 +1,345 @@
+package BlockFrostChainContext_test
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Salvionied/apollo/observability"
+	"github.com/Salvionied/apollo/serialization/Address"
+	"github.com/Salvionied/apollo/serialization/NativeScript"
+	"github.com/Salvionied/apollo/serialization/PlutusData"
+	"github.com/Salvionied/apollo/serialization/Transaction"
+	"github.com/Salvionied/apollo/txBuilding/Backend/Base"
+	"github.com/Salvionied/apollo/txBuilding/Backend/BlockFrostChainContext"
+	"github.com/Salvionied/apollo/txBuilding/Backend/HttpClient"
+)
+
+const testAddress = "addr1qy99jvml0vafzdpy6lm6z52qrczjvs4k362gmr9v4hrrwgqk4xvegxwvtfsu5ck6s83h346nsgf6xu26dwzce9yvd8ysd2seyu"
+
+// fakeBlockfrost serves the given routes, failing each route the
+// number of times given in faults before answering.
+type fakeBlockfrost struct {
+	mu     sync.Mutex
+	routes map[string]string
+	faults map[string][]int
+	hits   map[string]int
+}
+
+func newFakeBlockfrost() *fakeBlockfrost {
+	return &fakeBlockfrost{
+		routes: map[string]string{
+			"/v0/epochs/latest":         fmt.Sprintf(`{"epoch":500,"end_time":%d}`, time.Now().Add(time.Hour).Unix()),
+			"/v0/Genesis":               `{"network_magic":764824073}`,
+			"/v0/epochs/500/parameters": `{"min_fee_a":44,"min_fee_b":155381}`,
+		},
+		faults: map[string][]int{},
+		hits:   map[string]int{},
+	}
+}
+
+func (f *fakeBlockfrost) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	path := r.URL.Path
+	if r.URL.RawQuery != "" {
+		path += "?" + r.URL.RawQuery
+	}
+	f.hits[path]++
+	if r.Header.Get("project_id") != "project" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if faults := f.faults[path]; len(faults) > 0 {
+		f.faults[path] = faults[1:]
+		if faults[0] == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "0")
+		}
+		w.WriteHeader(faults[0])
+		w.Write([]byte(`{"status_code":` + fmt.Sprint(faults[0]) + `,"error":"fault","message":"injected"}`))
+		return
+	}
+	body, ok := f.routes[path]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"status_code":404,"error":"Not Found","message":"The requested component has not been found."}`))
+		return
+	}
+	w.Write([]byte(body))
+}
+
+func (f *fakeBlockfrost) hitsOf(path string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.hits[path]
+}
+
+func newContext(t *testing.T, fake *fakeBlockfrost) (BlockFrostChainContext.BlockFrostChainContext, *httptest.Server) {
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	bfc, err := BlockFrostChainContext.New(server.URL, 0, "project",
+		HttpClient.WithRetryPolicy(HttpClient.RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bfc, server
+}
+
+func TestNewRetriesTransientFailures(t *testing.T) {
+	fake := newFakeBlockfrost()
+	fake.faults["/v0/epochs/latest"] = []int{http.StatusBadGateway, http.StatusTooManyRequests}
+	bfc, _ := newContext(t, fake)
+	if fake.hitsOf("/v0/epochs/latest") != 3 {
+		t.Errorf("expected 3 attempts, got %d", fake.hitsOf("/v0/epochs/latest"))
+	}
+	if bfc.GetProtocolParams().MinFeeConstant != 155381 {
+		t.Errorf("unexpected protocol parameters %+v", bfc.GetProtocolParams())
+	}
+}
+
+func TestParamsKeyedByEpoch(t *testing.T) {
+	fake := newFakeBlockfrost()
+	_, server := newContext(t, fake)
+	fake.mu.Lock()
+	fake.routes["/v0/epochs/latest"] = fmt.Sprintf(`{"epoch":501,"end_time":%d}`, time.Now().Add(time.Hour).Unix())
+	fake.routes["/v0/epochs/501/parameters"] = `{"min_fee_a":44,"min_fee_b":200000}`
+	fake.mu.Unlock()
+	// a context created in the new epoch shares the cache of the first one
+	bfc, err := BlockFrostChainContext.New(server.URL, 0, "project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bfc.GetProtocolParams().MinFeeConstant != 200000 {
+		t.Errorf("expected the parameters of epoch 501, got %+v", bfc.GetProtocolParams())
+	}
+	if fake.hitsOf("/v0/epochs/latest") != 2 || fake.hitsOf("/v0/epochs/500/parameters") != 1 {
+		t.Error("expected the epoch to be fetched by each context and the parameters once per epoch")
+	}
+}
+
+func TestParamsRetriedAfterFailedEpochChange(t *testing.T) {
+	fake := newFakeBlockfrost()
+	// the epoch has ended: every call checks for the next one
+	fake.routes["/v0/epochs/latest"] = fmt.Sprintf(`{"epoch":500,"end_time":%d}`, time.Now().Add(-time.Minute).Unix())
+	bfc, _ := newContext(t, fake)
+	fake.mu.Lock()
+	fake.routes["/v0/epochs/latest"] = fmt.Sprintf(`{"epoch":501,"end_time":%d}`, time.Now().Add(time.Hour).Unix())
+	fake.routes["/v0/epochs/501/parameters"] = `{"min_fee_a":44,"min_fee_b":200000}`
+	fake.faults["/v0/epochs/501/parameters"] = []int{500, 500, 500, 500}
+	fake.mu.Unlock()
+
+	if bfc.GetProtocolParams().MinFeeConstant != 155381 {
+		t.Errorf("expected the parameters of epoch 500 while 501 fails, got %+v", bfc.GetProtocolParams())
+	}
+	if bfc.GetProtocolParams().MinFeeConstant != 200000 {
+		t.Errorf("expected the parameters of epoch 501 once the backend recovers, got %+v", bfc.GetProtocolParams())
+	}
+	if bfc.Epoch() != 501 {
+		t.Errorf("expected epoch 501, got %d", bfc.Epoch())
+	}
+}
+
+func TestNewReturnsErrors(t *testing.T) {
+	fake := newFakeBlockfrost()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	_, err := BlockFrostChainContext.New(server.URL, 0, "wrong project")
+	var apiErr *HttpClient.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden {
+		t.Errorf("expected a 403 APIError, got %v", err)
+	}
+
+	server.Close()
+	_, err = BlockFrostChainContext.New(server.URL, 0, "project",
+		HttpClient.WithRetryPolicy(HttpClient.RetryPolicy{MaxRetries: 1, BaseDelay: time.Millisecond}))
+	if err == nil {
+		t.Error("expected an error from an unreachable backend")
+	}
+}
+
+func TestAddressUtxos(t *testing.T) {
+	fake := newFakeBlockfrost()
+	bfc, _ := newContext(t, fake)
+
+	// Blockfrost answers 404 for addresses never used on chain
+	utxos, err := bfc.AddressUtxos(testAddress, true)
+	if err != nil || len(utxos) != 0 {
+		t.Errorf("expected no UTxO, got %v %v", utxos, err)
+	}
+
+	page := func(n int) string { return fmt.Sprintf("/v0/addresses/%s/utxos?page=%d", testAddress, n) }
+	utxo := `{"tx_hash":"%064x","output_index":0,"amount":[{"unit":"lovelace","quantity":"2000000"}]}`
+	fake.routes[page(1)] = "[" + fmt.Sprintf(utxo, 1) + "," + fmt.Sprintf(utxo, 2) + "]"
+	fake.routes[page(2)] = "[" + fmt.Sprintf(utxo, 3) + "]"
+	fake.routes[page(3)] = "[]"
+	fake.faults[page(2)] = []int{http.StatusServiceUnavailable}
+	addr, _ := Address.DecodeAddress(testAddress)
+	res, err := bfc.FetchUtxos(addr)
+	if err != nil || len(res) != 3 {
+		t.Fatalf("expected 3 UTxOs, got %d %v", len(res), err)
+	}
+	if res[2].Output.GetAmount().GetCoin() != 2_000_000 {
+		t.Errorf("unexpected UTxO %v", res[2])
+	}
+
+	fake.routes[page(1)] = "not json"
+	_, err = bfc.FetchUtxos(addr)
+	var decodeErr *HttpClient.DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Errorf("expected a DecodeError, got %v", err)
+	}
+	if utxos := bfc.Utxos(addr); len(utxos) != 0 {
+		t.Errorf("expected no UTxO on error, got %v", utxos)
+	}
+}
+
+func TestSubmitTxErrors(t *testing.T) {
+	fake := newFakeBlockfrost()
+	bfc, _ := newContext(t, fake)
+	fake.faults["/v0/tx/submit"] = []int{http.StatusTooManyRequests, http.StatusBadRequest}
+	_, err := bfc.SubmitTx(Transaction.Transaction{})
+	var apiErr *HttpClient.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest || !strings.Contains(err.Error(), "injected") {
+		t.Fatalf("expected a 400 APIError, got %v", err)
+	}
+	if fake.hitsOf("/v0/tx/submit") != 2 {
+		t.Errorf("expected the rate limited submission to be retried once, got %d attempts", fake.hitsOf("/v0/tx/submit"))
+	}
+
+	fake.faults["/v0/tx/submit"] = []int{http.StatusInternalServerError}
+	_, err = bfc.SubmitTx(Transaction.Transaction{})
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("expected a 500 APIError, got %v", err)
+	}
+	if fake.hitsOf("/v0/tx/submit") != 3 {
+		t.Errorf("a submission must not be retried after a server error")
+	}
+}
+
+func TestEvaluationAndScripts(t *testing.T) {
+	fake := newFakeBlockfrost()
+	bfc, _ := newContext(t, fake)
+	fake.routes["/v0/utils/txs/evaluate"] = `{"result":{"EvaluationResult":{"spend:0":{"memory":10,"steps":20}}}}`
+	fake.faults["/v0/utils/txs/evaluate"] = []int{http.StatusInternalServerError}
+	units, err := bfc.FetchEvaluation([]byte{0x80})
+	if err != nil || units["spend:0"].Mem != 10 || units["spend:0"].Steps != 20 {
+		t.Errorf("unexpected evaluation %v %v", units, err)
+	}
+
+	if _, err := bfc.FetchContractCbor("00"); !errors.Is(err, HttpClient.ErrNotFound) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+	if bfc.GetContractCbor("00") != "" || bfc.GetUtxoFromRef("00", 0) != nil {
+		t.Error("expected empty results for missing objects")
+	}
+}
+
+func TestWithContext(t *testing.T) {
+	fake := newFakeBlockfrost()
+	fake.routes["/v0/utils/txs/evaluate"] = `{"result":{"EvaluationResult":{"spend:0":{"memory":10,"steps":20}}}}`
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	recorder := &observability.Recorder{}
+	bfc, err := BlockFrostChainContext.New(server.URL, 0, "project", HttpClient.WithInstrumentation(recorder))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, span := recorder.Start(context.Background(), "apollo.updateExUnits")
+	if _, err := Base.FetchEvaluation(Base.WithContext(&bfc, ctx), []byte{0x80}); err != nil {
+		t.Fatal(err)
+	}
+	span.End(nil)
+	requests := recorder.Named("http.request")
+	if len(requests) == 0 || requests[len(requests)-1].Parent != "apollo.updateExUnits" {
+		t.Errorf("expected the request to run in the bound span, got %v", requests)
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Base.FetchEvaluation(Base.WithContext(&bfc, cancelled), []byte{0x80}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the request to be cancelled, got %v", err)
+	}
+	if _, err := bfc.FetchEvaluation([]byte{0x80}); err != nil {
+		t.Errorf("expected the context itself to be left unbound, got %v", err)
+	}
+}
+
+func TestResolveDatum(t *testing.T) {
+	fake := newFakeBlockfrost()
+	bfc, _ := newContext(t, fake)
+	hash := "923918e403bf43c34b4ef6b48eb2ee04babed17320d8d1b9ff9ad086e86f44ec"
+	fake.routes["/v0/scripts/datum/"+hash+"/cbor"] = `{"cbor":"d87980"}`
+	var _ Base.DatumResolver = &bfc
+	datum, err := bfc.ResolveDatum(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved, _ := PlutusData.PlutusDataHash(datum); hex.EncodeToString(resolved.Payload) != hash {
+		t.Errorf("unexpected datum %v", datum)
+	}
+	if _, err := bfc.ResolveDatum("00"); !errors.Is(err, Base.ErrDatumNotFound) || !errors.Is(err, HttpClient.ErrNotFound) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
+
+func TestGetScript(t *testing.T) {
+	fake := newFakeBlockfrost()
+	bfc, _ := newContext(t, fake)
+	var _ Base.ScriptResolver = &bfc
+
+	plutus := PlutusData.NewScriptRef(2, []byte{0x4e, 0x4d, 0x01, 0x00})
+	plutusHash, _ := plutus.Hash()
+	hash := hex.EncodeToString(plutusHash[:])
+	fake.routes["/v0/scripts/"+hash] = `{"type":"plutusV2"}`
+	// served wrapped in an extra byte string
+	fake.routes["/v0/scripts/"+hash+"/cbor"] = `{"cbor":"444e4d0100"}`
+	script, err := bfc.GetScript(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if script.Type() != 2 || hex.EncodeToString(script.Script.Script) != "4e4d0100" {
+		t.Errorf("unexpected script %d %x", script.Type(), script.Script.Script)
+	}
+
+	keyHash := strings.Repeat("ab", 28)
+	decodedKeyHash, _ := hex.DecodeString(keyHash)
+	native := NativeScript.NewScriptAll([]NativeScript.NativeScript{
+		NativeScript.NewScriptPubKey(decodedKeyHash),
+		NativeScript.NewInvalidHereafter(1000),
+	})
+	nativeHash, _ := native.Hash()
+	hash = hex.EncodeToString(nativeHash[:])
+	fake.routes["/v0/scripts/"+hash] = `{"type":"timelock"}`
+	fake.routes["/v0/scripts/"+hash+"/json"] = `{"json":{"type":"all","scripts":[{"type":"sig","keyHash":"` + keyHash + `"},{"type":"before","slot":1000}]}}`
+	script, err = bfc.GetScript(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved, _ := script.Hash(); script.Type() != 0 || hex.EncodeToString(resolved[:]) != hash {
+		t.Errorf("unexpected native script %d %x", script.Type(), script.Script.Script)
+	}
+
+	other := strings.Repeat("cd", 28)
+	fake.routes["/v0/scripts/"+other] = `{"type":"plutusV2"}`
+	fake.routes["/v0/scripts/"+other+"/cbor"] = `{"cbor":"444e4d0100"}`
+	if _, err := bfc.GetScript(other); !errors.Is(err, Base.ErrScriptHashMismatch) {
+		t.Errorf("expected a hash mismatch for a plutus script, got %v", err)
+	}
+	fake.routes["/v0/scripts/"+other] = `{"type":"timelock"}`
+	fake.routes["/v0/scripts/"+other+"/json"] = fake.routes["/v0/scripts/"+hash+"/json"]
+	if _, err := bfc.GetScript(other); !errors.Is(err, Base.ErrScriptHashMismatch) {
+		t.Errorf("expected a hash mismatch for a native script, got %v", err)
+	}
+
+	if _, err := bfc.GetScript("00"); !errors.Is(err, Base.ErrScriptNotFound) || !errors.Is(err, HttpClient.ErrNotFound) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
@@ -3,26 +3,131 @@ package Cache
 import (
 	"encoding/json"
 	"fmt"
-	"os"
+	"strings"
+	"time"
 )
 
-func Get[T any](key string, val interface{}) bool {
-	dat, err := os.ReadFile(fmt.Sprintf("./tmp/%s.txt", key))
-	if err != nil {
-		return false
+/*
+*
+
+	Cache stores chain data by key until it expires.
+	Implementations are safe for concurrent use.
+*/
+type Cache interface {
+	// Get returns the value stored at key, false if it is
+	// missing or expired.
+	Get(key string) ([]byte, bool, error)
+	// Set stores the value at key for ttl, forever if ttl is 0.
+	Set(key string, value []byte, ttl time.Duration) error
+	Delete(key string) error
+	// DeletePrefix deletes every key starting with prefix.
+	DeletePrefix(prefix string) error
+}
+
+// DEFAULT_CACHE is the in memory cache shared by the contexts
+// not given a cache of their own.
+var DEFAULT_CACHE Cache = NewMemoryCache(DEFAULT_MAX_ENTRIES)
+
+/*
+*
+
+	Namespace scopes the keys of a cache to a backend and a
+	network, so that contexts of different networks sharing
+	a cache never read each other's data.
+*/
+type Namespace struct {
+	Backend string
+	Network int
+}
+
+/*
+*
+
+	Key builds a key in the namespace.
+
+	Params:
+		parts (...string): The parts of the key.
+
+	Returns:
+		string: The key, of the form backend/network/part/...
+*/
+func (n Namespace) Key(parts ...string) string {
+	return fmt.Sprintf("%s/%d/%s", n.Backend, n.Network, strings.Join(parts, "/"))
+}
+
+/*
+*
+
+	Load reads and decodes a JSON value from a cache.
+
+	Params:
+		c (Cache): The cache.
+		key (string): The key of the value.
+
+	Returns:
+		T: The value.
+		bool: false if the key is missing or expired.
+		error: An error if the cache cannot be read or the value decoded.
+*/
+func Load[T any](c Cache, key string) (T, bool, error) {
+	var value T
+	encoded, found, err := c.Get(key)
+	if err != nil || !found {
+		return value, false, err
 	}
-	err = json.Unmarshal(dat, &val)
+	err = json.Unmarshal(encoded, &value)
 	if err != nil {
+		return value, false, fmt.Errorf("decoding cache entry %s: %w", key, err)
 	}
-	return true
+	return value, true, nil
 }
 
-func Set[T any](key string, value T) {
-	val, err := json.Marshal(value)
+/*
+*
+
+	Save encodes a value as JSON and stores it in a cache.
+
+	Params:
+		c (Cache): The cache.
+		key (string): The key of the value.
+		value (T): The value.
+		ttl (time.Duration): How long the value is kept, 0 for ever.
+
+	Returns:
+		error: An error if the value cannot be encoded or stored.
+*/
+func Save[T any](c Cache, key string, value T, ttl time.Duration) error {
+	encoded, err := json.Marshal(value)
 	if err != nil {
+		return fmt.Errorf("encoding cache entry %s: %w", key, err)
 	}
-	err = os.WriteFile(fmt.Sprintf("./tmp/%s.txt", key), val, 0644)
+	return c.Set(key, encoded, ttl)
+}
+
+// LEGACY_DIR is the directory of the deprecated Get and Set,
+// "" for the directory NewDiskCache uses by default.
+var LEGACY_DIR = ""
+
+// Deprecated: Get reads from a DiskCache in LEGACY_DIR and
+// ignores errors, use a Cache.
+func Get[T any](key string, val interface{}) bool {
+	c, err := NewDiskCache(LEGACY_DIR)
 	if err != nil {
+		return false
+	}
+	dat, found, err := c.Get(key)
+	if err != nil || !found {
+		return false
 	}
+	return json.Unmarshal(dat, &val) == nil
+}
 
+// Deprecated: Set writes to a DiskCache in LEGACY_DIR and
+// ignores errors, use a Cache.
+func Set[T any](key string, value T) {
+	c, err := NewDiskCache(LEGACY_DIR)
+	if err != nil {
+		return
+	}
+	_ = Save(c, key, value, 0)
 }
@@ -0,0 +1,364 @@
+package Cache_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Salvionied/apollo/serialization"
+	"github.com/Salvionied/apollo/serialization/Address"
+	"github.com/Salvionied/apollo/serialization/PlutusData"
+	"github.com/Salvionied/apollo/serialization/Transaction"
+	"github.com/Salvionied/apollo/serialization/UTxO"
+	"github.com/Salvionied/apollo/txBuilding/Backend/Base"
+	"github.com/Salvionied/apollo/txBuilding/Backend/Cache"
+	"github.com/Salvionied/apollo/txBuilding/Backend/FixedChainContext"
+)
+
+const testAddress = "addr1qy99jvml0vafzdpy6lm6z52qrczjvs4k362gmr9v4hrrwgqk4xvegxwvtfsu5ck6s83h346nsgf6xu26dwzce9yvd8ysd2seyu"
+
+func testCaches(t *testing.T) map[string]Cache.Cache {
+	disk, err := Cache.NewDiskCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]Cache.Cache{"memory": Cache.NewMemoryCache(16), "disk": disk}
+}
+
+func TestCacheTTLAndPrefix(t *testing.T) {
+	for name, cache := range testCaches(t) {
+		if err := cache.Set("a/1", []byte("one"), 0); err != nil {
+			t.Fatal(err)
+		}
+		cache.Set("a/2", []byte("two"), 30*time.Millisecond)
+		cache.Set("b/1", []byte("three"), 0)
+		if value, found, err := cache.Get("a/2"); err != nil || !found || string(value) != "two" {
+			t.Errorf("%s: unexpected entry %q %v %v", name, value, found, err)
+		}
+		time.Sleep(40 * time.Millisecond)
+		if _, found, _ := cache.Get("a/2"); found {
+			t.Errorf("%s: expected the entry to expire", name)
+		}
+		if err := cache.DeletePrefix("a/"); err != nil {
+			t.Fatal(err)
+		}
+		if _, found, _ := cache.Get("a/1"); found {
+			t.Errorf("%s: expected the prefix to be deleted", name)
+		}
+		if value, found, _ := cache.Get("b/1"); !found || string(value) != "three" {
+			t.Errorf("%s: expected other prefixes to be kept", name)
+		}
+		cache.Delete("b/1")
+		if _, found, _ := cache.Get("b/1"); found {
+			t.Errorf("%s: expected the entry to be deleted", name)
+		}
+	}
+}
+
+func TestMemoryCacheEviction(t *testing.T) {
+	cache := Cache.NewMemoryCache(2)
+	cache.Set("a", []byte("a"), 0)
+	cache.Set("b", []byte("b"), 0)
+	cache.Get("a")
+	cache.Set("c", []byte("c"), 0)
+	if _, found, _ := cache.Get("b"); found {
+		t.Error("expected the least recently used entry to be evicted")
+	}
+	if _, found, _ := cache.Get("a"); !found {
+		t.Error("expected the recently read entry to be kept")
+	}
+	if cache.Len() != 2 {
+		t.Errorf("expected 2 entries, got %d", cache.Len())
+	}
+}
+
+func TestDiskCachePersistence(t *testing.T) {
+	dir := t.TempDir()
+	cache, _ := Cache.NewDiskCache(dir)
+	err := Cache.Save(cache, "params", Base.ProtocolParameters{MinFeeCoefficient: 44}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reopened, _ := Cache.NewDiskCache(dir)
+	params, found, err := Cache.Load[Base.ProtocolParameters](reopened, "params")
+	if err != nil || !found || params.MinFeeCoefficient != 44 {
+		t.Errorf("unexpected params %v %v %v", params.MinFeeCoefficient, found, err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	os.WriteFile(files[0], []byte("{"), 0o600)
+	if _, _, err := reopened.Get("params"); err == nil {
+		t.Error("expected an error for a corrupted entry")
+	}
+}
+
+func TestNamespaces(t *testing.T) {
+	cache := Cache.NewMemoryCache(0)
+	mainnet := Cache.Namespace{Backend: "blockfrost", Network: 0}
+	preprod := Cache.Namespace{Backend: "blockfrost", Network: 1}
+	Cache.Save(cache, mainnet.Key("params"), 1, 0)
+	if _, found, _ := Cache.Load[int](cache, preprod.Key("params")); found {
+		t.Error("networks must not share entries")
+	}
+	if value, found, _ := Cache.Load[int](cache, mainnet.Key("params")); !found || value != 1 {
+		t.Error("expected the mainnet entry")
+	}
+}
+
+func TestConcurrentAccess(t *testing.T) {
+	for name, cache := range testCaches(t) {
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < 50; j++ {
+					key := fmt.Sprintf("key/%d", j%5)
+					cache.Set(key, []byte(key), time.Minute)
+					if value, found, err := cache.Get(key); err != nil || (found && string(value) != key) {
+						t.Errorf("%s: unexpected entry %q %v", name, value, err)
+					}
+					if j%10 == 0 {
+						cache.DeletePrefix("key/")
+					}
+				}
+			}(i)
+		}
+		wg.Wait()
+	}
+}
+
+// countingContext counts the calls reaching the wrapped context.
+type countingContext struct {
+	FixedChainContext.FixedChainContext
+	mu      sync.Mutex
+	epoch   int
+	network int
+	calls   map[string]int
+	// failing makes the wrapped context fail as the legacy
+	// contexts do, with zero values.
+	failing bool
+	// bound is the last context the calls were bound to.
+	bound context.Context
+}
+
+func (c *countingContext) WithContext(ctx context.Context) Base.ChainContext {
+	c.bound = ctx
+	return c
+}
+
+func newCountingContext(network int) *countingContext {
+	return &countingContext{
+		FixedChainContext: FixedChainContext.InitFixedChainContext(),
+		epoch:             300,
+		network:           network,
+		calls:             map[string]int{},
+	}
+}
+
+func (c *countingContext) count(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls[name]++
+}
+
+func (c *countingContext) Network() int {
+	return c.network
+}
+
+func (c *countingContext) Epoch() int {
+	c.count("Epoch")
+	return c.epoch
+}
+
+func (c *countingContext) GetProtocolParams() Base.ProtocolParameters {
+	c.count("GetProtocolParams")
+	if c.failing {
+		return Base.ProtocolParameters{}
+	}
+	return c.FixedChainContext.GetProtocolParams()
+}
+
+func (c *countingContext) Utxos(address Address.Address) []UTxO.UTxO {
+	utxos, _ := c.FetchUtxos(address)
+	return utxos
+}
+
+func (c *countingContext) FetchUtxos(address Address.Address) ([]UTxO.UTxO, error) {
+	c.count("Utxos")
+	if c.failing {
+		return nil, errors.New("backend down")
+	}
+	return c.FixedChainContext.Utxos(address), nil
+}
+
+func (c *countingContext) GetUtxoFromRef(txHash string, txIndex int) *UTxO.UTxO {
+	c.count("GetUtxoFromRef")
+	addr, _ := Address.DecodeAddress(testAddress)
+	utxos := c.FixedChainContext.Utxos(addr)
+	return &utxos[txIndex]
+}
+
+func (c *countingContext) GetContractCbor(scriptHash string) string {
+	c.count("GetContractCbor")
+	return "4e4d01000033222220051200120011"
+}
+
+func (c *countingContext) ResolveDatum(datumHash string) (*PlutusData.PlutusData, error) {
+	c.count("ResolveDatum")
+	if datumHash != "923918e403bf43c34b4ef6b48eb2ee04babed17320d8d1b9ff9ad086e86f44ec" {
+		return nil, Base.ErrDatumNotFound
+	}
+	return &PlutusData.PlutusData{TagNr: 121, PlutusDataType: PlutusData.PlutusArray, Value: PlutusData.PlutusIndefArray{}}, nil
+}
+
+func (c *countingContext) SubmitTx(tx Transaction.Transaction) (serialization.TransactionId, error) {
+	return serialization.TransactionId{}, nil
+}
+
+func TestCachedChainContext(t *testing.T) {
+	cache := Cache.NewMemoryCache(0)
+	inner := newCountingContext(0)
+	cc := Cache.NewCachedChainContext(inner, cache, "fixed", Cache.Options{EpochTTL: time.Nanosecond, UtxoTTL: time.Minute, OnError: func(key string, err error) { t.Errorf("%s: %v", key, err) }})
+	var _ Base.ChainContext = cc
+
+	expected := inner.FixedChainContext.GetProtocolParams()
+	for i := 0; i < 3; i++ {
+		params := cc.GetProtocolParams()
+		if params.MinFee(300, 1000, 1000) != expected.MinFee(300, 1000, 1000) ||
+			params.MinCollateral(200_000) != expected.MinCollateral(200_000) ||
+			params.RefScriptFee(1000) != expected.RefScriptFee(1000) {
+			t.Fatalf("cached params differ: %+v", params)
+		}
+	}
+	if inner.calls["GetProtocolParams"] != 1 {
+		t.Errorf("expected one fetch of the params, got %d", inner.calls["GetProtocolParams"])
+	}
+	if cc.MaxTxFee() != Base.Fee(inner.FixedChainContext, expected.MaxTxSize, int(expected.MaxTxExSteps), int(expected.MaxTxExMem)) {
+		t.Error("unexpected max fee")
+	}
+
+	inner.epoch = 301
+	cc.GetProtocolParams()
+	if inner.calls["GetProtocolParams"] != 2 {
+		t.Errorf("expected the params to be fetched again in a new epoch, got %d fetches", inner.calls["GetProtocolParams"])
+	}
+
+	for i := 0; i < 2; i++ {
+		if cc.GetContractCbor("hash") == "" || cc.GetUtxoFromRef("00", 1) == nil {
+			t.Fatal("expected cached values")
+		}
+	}
+	addr, _ := Address.DecodeAddress(testAddress)
+	utxo := cc.GetUtxoFromRef("00", 1)
+	if !utxo.EqualTo(inner.FixedChainContext.Utxos(addr)[1]) {
+		t.Errorf("the cached UTxO differs: %v", utxo)
+	}
+	if inner.calls["GetContractCbor"] != 1 || inner.calls["GetUtxoFromRef"] != 1 {
+		t.Errorf("expected one fetch of the script and of the UTxO, got %v", inner.calls)
+	}
+
+	cc.Utxos(addr)
+	if utxos := cc.Utxos(addr); len(utxos) != 2 || inner.calls["Utxos"] != 1 {
+		t.Errorf("expected the UTxOs to be cached, got %d UTxOs after %d fetches", len(utxos), inner.calls["Utxos"])
+	}
+	cc.SubmitTx(Transaction.Transaction{})
+	cc.Utxos(addr)
+	if inner.calls["Utxos"] != 2 {
+		t.Error("expected the UTxOs to be invalidated by a submission")
+	}
+
+	// another network sharing the cache does not see these entries
+	other := Cache.NewCachedChainContext(newCountingContext(1), cache, "fixed", Cache.Options{})
+	other.GetContractCbor("hash")
+	if other.Network() != 1 || inner.calls["GetContractCbor"] != 1 {
+		t.Error("unexpected sharing between networks")
+	}
+	cc.Invalidate()
+	cc.GetContractCbor("hash")
+	if inner.calls["GetContractCbor"] != 2 {
+		t.Error("expected Invalidate to drop the entries")
+	}
+}
+
+func TestCachedWithContext(t *testing.T) {
+	inner := newCountingContext(0)
+	cc := Cache.NewCachedChainContext(inner, Cache.NewMemoryCache(0), "fixed", Cache.Options{})
+	cc.GetProtocolParams()
+
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "phase")
+	bound := Base.WithContext(cc, ctx)
+	if inner.bound != ctx {
+		t.Error("expected the wrapped context to be bound")
+	}
+	// the bound copy shares the cache and the epoch of cc
+	bound.GetProtocolParams()
+	if inner.calls["GetProtocolParams"] != 1 || inner.calls["Epoch"] != 1 {
+		t.Errorf("expected the bound copy to hit the cache, got %v", inner.calls)
+	}
+}
+
+func TestCachedResolveDatum(t *testing.T) {
+	inner := newCountingContext(0)
+	cc := Cache.NewCachedChainContext(inner, Cache.NewMemoryCache(0), "fixed", Cache.Options{})
+	var _ Base.DatumResolver = cc
+	hash := "923918e403bf43c34b4ef6b48eb2ee04babed17320d8d1b9ff9ad086e86f44ec"
+	for i := 0; i < 2; i++ {
+		datum, err := cc.ResolveDatum(hash)
+		if err != nil || datum.TagNr != 121 {
+			t.Fatalf("unexpected datum %v %v", datum, err)
+		}
+	}
+	if inner.calls["ResolveDatum"] != 1 {
+		t.Errorf("expected one fetch of the datum, got %d", inner.calls["ResolveDatum"])
+	}
+	if _, err := cc.ResolveDatum("00"); !errors.Is(err, Base.ErrDatumNotFound) {
+		t.Errorf("expected the error of the wrapped context, got %v", err)
+	}
+
+	fixed := Cache.NewCachedChainContext(FixedChainContext.InitFixedChainContext(), Cache.NewMemoryCache(0), "fixed", Cache.Options{})
+	if _, err := fixed.ResolveDatum(hash); !errors.Is(err, Base.ErrNotSupported) {
+		t.Errorf("expected the context not to support datums, got %v", err)
+	}
+}
+
+func TestCachedFailuresNotCached(t *testing.T) {
+	inner := newCountingContext(0)
+	inner.failing = true
+	cc := Cache.NewCachedChainContext(inner, Cache.NewMemoryCache(0), "fixed", Cache.Options{UtxoTTL: time.Minute})
+	addr, _ := Address.DecodeAddress(testAddress)
+	if params := cc.GetProtocolParams(); params.MinFeeConstant != 0 {
+		t.Fatalf("unexpected params %+v", params)
+	}
+	if _, err := cc.FetchUtxos(addr); err == nil {
+		t.Fatal("expected the error of the wrapped context")
+	}
+
+	inner.failing = false
+	if cc.GetProtocolParams().MinFeeConstant == 0 || inner.calls["GetProtocolParams"] != 2 {
+		t.Errorf("the failed fetch of the params was cached, %d fetches", inner.calls["GetProtocolParams"])
+	}
+	if utxos, err := cc.FetchUtxos(addr); err != nil || len(utxos) != 2 || inner.calls["Utxos"] != 2 {
+		t.Errorf("the failed fetch of the UTxOs was cached, %d fetches", inner.calls["Utxos"])
+	}
+}
+
+func TestLegacyGetSet(t *testing.T) {
+	previous := Cache.LEGACY_DIR
+	Cache.LEGACY_DIR = t.TempDir()
+	defer func() { Cache.LEGACY_DIR = previous }()
+	Cache.Set("latest_epoch", Base.Epoch{Epoch: 500})
+	var epoch Base.Epoch
+	if !Cache.Get[Base.Epoch]("latest_epoch", &epoch) || epoch.Epoch != 500 {
+		t.Errorf("unexpected epoch %+v", epoch)
+	}
+	if _, err := os.Stat("tmp"); err == nil {
+		t.Error("the legacy functions must not write to ./tmp")
+	}
+}
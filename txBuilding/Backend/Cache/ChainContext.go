@@ -0,0 +1,400 @@
+package Cache
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/Salvionied/apollo/serialization"
+	"github.com/Salvionied/apollo/serialization/Address"
+	"github.com/Salvionied/apollo/serialization/PlutusData"
+	"github.com/Salvionied/apollo/serialization/Redeemer"
+	"github.com/Salvionied/apollo/serialization/Transaction"
+	"github.com/Salvionied/apollo/serialization/UTxO"
+	"github.com/Salvionied/apollo/txBuilding/Backend/Base"
+	"github.com/Salvionied/cbor/v2"
+)
+
+const (
+	DEFAULT_PARAMS_TTL = 24 * time.Hour
+	DEFAULT_SCRIPT_TTL = 24 * time.Hour
+	DEFAULT_EPOCH_TTL  = time.Minute
+)
+
+/*
+*
+
+	Options sets how long each kind of chain data is cached.
+	Zero values select the defaults, except UtxoTTL.
+*/
+type Options struct {
+	// ParamsTTL bounds the protocol and genesis parameters,
+	// which are also dropped when the epoch changes.
+	ParamsTTL time.Duration
+	// ScriptTTL bounds the scripts, the datums and the UTxOs
+	// fetched by reference.
+	ScriptTTL time.Duration
+	// EpochTTL is how long the epoch is trusted before asking
+	// the wrapped context again.
+	EpochTTL time.Duration
+	// UtxoTTL bounds the UTxOs of addresses, 0 disables their caching.
+	// They are dropped whenever a transaction is submitted.
+	UtxoTTL time.Duration
+	// OnError is called with the cache errors, which otherwise
+	// only fall back to the wrapped context.
+	OnError func(key string, err error)
+}
+
+/*
+*
+
+	CachedChainContext wraps a Base.ChainContext, serving its
+	parameters, scripts and reference UTxOs from a Cache.
+	Keys are scoped by backend and network and the epoch bound
+	data is invalidated when the epoch changes.
+*/
+type CachedChainContext struct {
+	context   Base.ChainContext
+	cache     Cache
+	namespace Namespace
+	options   Options
+	epoch     *epochState
+}
+
+// epochState is the last epoch seen, shared by the bound copies.
+type epochState struct {
+	mu        sync.Mutex
+	epoch     int
+	checkedAt time.Time
+}
+
+/*
+*
+
+	NewCachedChainContext wraps a chain context with a cache.
+
+	Params:
+		cc (Base.ChainContext): The wrapped context.
+		cache (Cache): The cache, it may be shared between contexts.
+		backend (string): The name of the backend, part of every key.
+		options (Options): The time to live of the cached data.
+
+	Returns:
+		*CachedChainContext: The caching context.
+*/
+func NewCachedChainContext(cc Base.ChainContext, cache Cache, backend string, options Options) *CachedChainContext {
+	if options.ParamsTTL <= 0 {
+		options.ParamsTTL = DEFAULT_PARAMS_TTL
+	}
+	if options.ScriptTTL <= 0 {
+		options.ScriptTTL = DEFAULT_SCRIPT_TTL
+	}
+	if options.EpochTTL <= 0 {
+		options.EpochTTL = DEFAULT_EPOCH_TTL
+	}
+	return &CachedChainContext{
+		context:   cc,
+		cache:     cache,
+		namespace: Namespace{Backend: backend, Network: cc.Network()},
+		options:   options,
+		epoch:     &epochState{},
+	}
+}
+
+/*
+*
+
+	WithContext implements Base.ContextBinder for the wrapped
+	context, the cache being shared with c.
+
+	Params:
+		ctx (context.Context): The context of the calls.
+
+	Returns:
+		Base.ChainContext: The bound context.
+*/
+func (c *CachedChainContext) WithContext(ctx context.Context) Base.ChainContext {
+	bound := *c
+	bound.context = Base.WithContext(c.context, ctx)
+	return &bound
+}
+
+func (c *CachedChainContext) reportError(key string, err error) {
+	if err != nil && c.options.OnError != nil {
+		c.options.OnError(key, err)
+	}
+}
+
+/*
+*
+
+	Invalidate drops every cached entry of the backend and network.
+
+	Returns:
+		error: The error of the cache.
+*/
+func (c *CachedChainContext) Invalidate() error {
+	return c.cache.DeletePrefix(c.namespace.Key())
+}
+
+func (c *CachedChainContext) Network() int {
+	return c.context.Network()
+}
+
+// LoadsWalletUtxos implements Base.WalletUtxoLoader for the wrapped context.
+func (c *CachedChainContext) LoadsWalletUtxos() bool {
+	loader, ok := c.context.(Base.WalletUtxoLoader)
+	return ok && loader.LoadsWalletUtxos()
+}
+
+func (c *CachedChainContext) Epoch() int {
+	state := c.epoch
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if !state.checkedAt.IsZero() && time.Since(state.checkedAt) < c.options.EpochTTL {
+		return state.epoch
+	}
+	epoch := c.context.Epoch()
+	if !state.checkedAt.IsZero() && epoch != state.epoch {
+		key := c.namespace.Key("epoch")
+		c.reportError(key, c.cache.DeletePrefix(key))
+		key = c.namespace.Key("utxos")
+		c.reportError(key, c.cache.DeletePrefix(key))
+	}
+	state.epoch = epoch
+	state.checkedAt = time.Now()
+	return epoch
+}
+
+// cached serves a value from the cache, fetching it on a miss.
+// The wrapped contexts return a zero value when they fail, so
+// a zero value is returned without being cached.
+func cached[T any](c *CachedChainContext, key string, ttl time.Duration, fetch func() T) T {
+	value, found, err := Load[T](c.cache, key)
+	c.reportError(key, err)
+	if found {
+		return value
+	}
+	value = fetch()
+	if reflect.ValueOf(&value).Elem().IsZero() {
+		return value
+	}
+	c.reportError(key, Save(c.cache, key, value, ttl))
+	return value
+}
+
+func (c *CachedChainContext) GetProtocolParams() Base.ProtocolParameters {
+	key := c.namespace.Key("epoch", fmt.Sprint(c.Epoch()), "protocol_params")
+	return cached(c, key, c.options.ParamsTTL, c.context.GetProtocolParams)
+}
+
+func (c *CachedChainContext) GetGenesisParams() Base.GenesisParameters {
+	key := c.namespace.Key("epoch", fmt.Sprint(c.Epoch()), "genesis_params")
+	return cached(c, key, c.options.ParamsTTL, c.context.GetGenesisParams)
+}
+
+func (c *CachedChainContext) MaxTxFee() int {
+	protocol_param := c.GetProtocolParams()
+	return Base.Fee(c, protocol_param.MaxTxSize, int(protocol_param.MaxTxExSteps), int(protocol_param.MaxTxExMem))
+}
+
+func (c *CachedChainContext) LastBlockSlot() int {
+	return c.context.LastBlockSlot()
+}
+
+func (c *CachedChainContext) Utxos(address Address.Address) []UTxO.UTxO {
+	utxos, err := c.FetchUtxos(address)
+	if err != nil {
+		return make([]UTxO.UTxO, 0)
+	}
+	return utxos
+}
+
+/*
+*
+
+	FetchUtxos returns the UTxOs of an address, from the cache
+	when UtxoTTL is set. Only the UTxOs fetched without error
+	are cached, and an empty list is never cached since it may
+	be the result of a failing legacy context.
+
+	Params:
+		address (Address.Address): The address.
+
+	Returns:
+		[]UTxO.UTxO: The UTxOs.
+		error: The error of the wrapped context.
+*/
+func (c *CachedChainContext) FetchUtxos(address Address.Address) ([]UTxO.UTxO, error) {
+	if c.options.UtxoTTL <= 0 {
+		return Base.FetchUtxos(c.context, address)
+	}
+	key := c.namespace.Key("utxos", address.String())
+	encoded, found, err := c.cache.Get(key)
+	c.reportError(key, err)
+	if found {
+		var utxos []UTxO.UTxO
+		err = cbor.Unmarshal(encoded, &utxos)
+		if err == nil {
+			return utxos, nil
+		}
+		c.reportError(key, err)
+	}
+	utxos, err := Base.FetchUtxos(c.context, address)
+	if err != nil {
+		return nil, err
+	}
+	if len(utxos) == 0 {
+		return utxos, nil
+	}
+	encoded, err = cbor.Marshal(utxos)
+	if err == nil {
+		err = c.cache.Set(key, encoded, c.options.UtxoTTL)
+	}
+	c.reportError(key, err)
+	return utxos, nil
+}
+
+func (c *CachedChainContext) SubmitTx(tx Transaction.Transaction) (serialization.TransactionId, error) {
+	txId, err := c.context.SubmitTx(tx)
+	if err == nil && c.options.UtxoTTL > 0 {
+		key := c.namespace.Key("utxos")
+		c.reportError(key, c.cache.DeletePrefix(key))
+	}
+	return txId, err
+}
+
+func (c *CachedChainContext) EvaluateTx(tx []uint8) map[string]Redeemer.ExecutionUnits {
+	return c.context.EvaluateTx(tx)
+}
+
+// FetchEvaluation implements Base.EvaluationFetcher, evaluations are never cached.
+func (c *CachedChainContext) FetchEvaluation(tx []byte) (map[string]Redeemer.ExecutionUnits, error) {
+	return Base.FetchEvaluation(c.context, tx)
+}
+
+func (c *CachedChainContext) GetUtxoFromRef(txHash string, txIndex int) *UTxO.UTxO {
+	key := c.namespace.Key("utxo", txHash, fmt.Sprint(txIndex))
+	encoded, found, err := c.cache.Get(key)
+	c.reportError(key, err)
+	if found {
+		var utxo UTxO.UTxO
+		err = cbor.Unmarshal(encoded, &utxo)
+		if err == nil {
+			return &utxo
+		}
+		c.reportError(key, err)
+	}
+	utxo := c.context.GetUtxoFromRef(txHash, txIndex)
+	if utxo == nil {
+		return nil
+	}
+	encoded, err = cbor.Marshal(utxo)
+	if err == nil {
+		err = c.cache.Set(key, encoded, c.options.ScriptTTL)
+	}
+	c.reportError(key, err)
+	return utxo
+}
+
+func (c *CachedChainContext) GetContractCbor(scriptHash string) string {
+	key := c.namespace.Key("script", scriptHash)
+	encoded, found, err := c.cache.Get(key)
+	c.reportError(key, err)
+	if found {
+		return string(encoded)
+	}
+	script := c.context.GetContractCbor(scriptHash)
+	if script != "" {
+		c.reportError(key, c.cache.Set(key, []byte(script), c.options.ScriptTTL))
+	}
+	return script
+}
+
+/*
+*
+
+	ResolveDatum returns a datum from the cache, resolving it
+	through the wrapped context when it implements
+	Base.DatumResolver.
+
+	Params:
+		datumHash (string): The hex encoded hash of the datum.
+
+	Returns:
+		*PlutusData.PlutusData: The datum.
+		error: The error of the wrapped context, Base.ErrNotSupported
+			if it cannot resolve datums.
+*/
+func (c *CachedChainContext) ResolveDatum(datumHash string) (*PlutusData.PlutusData, error) {
+	key := c.namespace.Key("datum", datumHash)
+	encoded, found, err := c.cache.Get(key)
+	c.reportError(key, err)
+	if found {
+		var datum PlutusData.PlutusData
+		err = cbor.Unmarshal(encoded, &datum)
+		if err == nil {
+			return &datum, nil
+		}
+		c.reportError(key, err)
+	}
+	resolver, ok := c.context.(Base.DatumResolver)
+	if !ok {
+		return nil, Base.ErrNotSupported
+	}
+	datum, err := resolver.ResolveDatum(datumHash)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err = cbor.Marshal(datum)
+	if err == nil {
+		err = c.cache.Set(key, encoded, c.options.ScriptTTL)
+	}
+	c.reportError(key, err)
+	return datum, nil
+}
+
+/*
+*
+
+	GetScript returns a typed script from the cache, fetching it
+	through the wrapped context when it implements
+	Base.ScriptResolver.
+
+	Params:
+		scriptHash (string): The hex encoded hash of the script.
+
+	Returns:
+		*PlutusData.ScriptRef: The script.
+		error: The error of the wrapped context, Base.ErrNotSupported
+			if it cannot fetch typed scripts.
+*/
+func (c *CachedChainContext) GetScript(scriptHash string) (*PlutusData.ScriptRef, error) {
+	key := c.namespace.Key("script_ref", scriptHash)
+	encoded, found, err := c.cache.Get(key)
+	c.reportError(key, err)
+	if found {
+		var scriptRef PlutusData.ScriptRef
+		err = cbor.Unmarshal(encoded, &scriptRef)
+		if err == nil {
+			return &scriptRef, nil
+		}
+		c.reportError(key, err)
+	}
+	resolver, ok := c.context.(Base.ScriptResolver)
+	if !ok {
+		return nil, Base.ErrNotSupported
+	}
+	scriptRef, err := resolver.GetScript(scriptHash)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err = cbor.Marshal(scriptRef)
+	if err == nil {
+		err = c.cache.Set(key, encoded, c.options.ScriptTTL)
+	}
+	c.reportError(key, err)
+	return scriptRef, nil
+}
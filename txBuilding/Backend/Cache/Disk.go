@@ -0,0 +1,159 @@
+package Cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+type diskEntry struct {
+	Key       string    `json:"key"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Value     []byte    `json:"value"`
+}
+
+/*
+*
+
+	DiskCache is a Cache keeping one file per entry in a
+	directory, so that it survives restarts. Files are
+	replaced atomically, several processes may share the
+	directory.
+*/
+type DiskCache struct {
+	mu  sync.RWMutex
+	dir string
+}
+
+/*
+*
+
+	NewDiskCache creates a cache in a directory, creating it
+	if needed.
+
+	Params:
+		dir (string): The directory of the cache, "" for an
+			apollo directory in the user cache directory.
+
+	Returns:
+		*DiskCache: The cache.
+		error: An error if the directory cannot be created.
+*/
+func NewDiskCache(dir string) (*DiskCache, error) {
+	if dir == "" {
+		userDir, err := os.UserCacheDir()
+		if err != nil {
+			return nil, err
+		}
+		dir = filepath.Join(userDir, "apollo")
+	}
+	err := os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return nil, err
+	}
+	return &DiskCache{dir: dir}, nil
+}
+
+func (c *DiskCache) path(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(hash[:])+".json")
+}
+
+func (c *DiskCache) read(path string) (diskEntry, bool, error) {
+	var entry diskEntry
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return entry, false, nil
+	}
+	if err != nil {
+		return entry, false, err
+	}
+	err = json.Unmarshal(content, &entry)
+	if err != nil {
+		return entry, false, fmt.Errorf("corrupted cache file %s: %w", path, err)
+	}
+	return entry, true, nil
+}
+
+func (c *DiskCache) Get(key string) ([]byte, bool, error) {
+	c.mu.RLock()
+	entry, found, err := c.read(c.path(key))
+	c.mu.RUnlock()
+	if err != nil || !found {
+		return nil, false, err
+	}
+	if entry.Key != key {
+		return nil, false, nil
+	}
+	if !entry.ExpiresAt.IsZero() && !time.Now().Before(entry.ExpiresAt) {
+		// left for the next Set to replace
+		return nil, false, nil
+	}
+	return entry.Value, true, nil
+}
+
+func (c *DiskCache) Set(key string, value []byte, ttl time.Duration) error {
+	entry := diskEntry{Key: key, Value: value}
+	if ttl > 0 {
+		entry.ExpiresAt = time.Now().Add(ttl)
+	}
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	file, err := os.CreateTemp(c.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), c.path(key))
+	}
+	if err != nil {
+		os.Remove(file.Name())
+	}
+	return err
+}
+
+func (c *DiskCache) Delete(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	err := os.Remove(c.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (c *DiskCache) DeletePrefix(prefix string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	files, err := filepath.Glob(filepath.Join(c.dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, path := range files {
+		entry, found, err := c.read(path)
+		if err != nil || !found || !strings.HasPrefix(entry.Key, prefix) {
+			continue
+		}
+		err = os.Remove(path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,696 @@
+package FailoverChainContext
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Salvionied/apollo/serialization"
+	"github.com/Salvionied/apollo/serialization/Address"
+	"github.com/Salvionied/apollo/serialization/PlutusData"
+	"github.com/Salvionied/apollo/serialization/Redeemer"
+	"github.com/Salvionied/apollo/serialization/Transaction"
+	"github.com/Salvionied/apollo/serialization/UTxO"
+	"github.com/Salvionied/apollo/txBuilding/Backend/Base"
+	"github.com/Salvionied/apollo/txBuilding/Backend/HttpClient"
+	"golang.org/x/exp/slog"
+)
+
+const (
+	DEFAULT_FAILURE_THRESHOLD = 1
+	DEFAULT_COOLDOWN          = 30 * time.Second
+)
+
+var (
+	ErrNoBackends = errors.New("no backends")
+	ErrNoQuorum   = errors.New("backends do not agree")
+	errNoAnswer   = errors.New("no answer")
+)
+
+/*
+*
+
+	Backend is a named chain context combined by a
+	FailoverChainContext.
+*/
+type Backend struct {
+	Name    string
+	Context Base.ChainContext
+}
+
+/*
+*
+
+	Options sets how the backends are combined.
+	Zero values select the defaults.
+*/
+type Options struct {
+	// Quorum is the number of backends that must return the same
+	// UTxOs and protocol parameters, 0 or 1 only asks the primary.
+	Quorum int
+	// FailureThreshold is the number of consecutive failures
+	// after which a backend is only asked when the healthy ones fail.
+	FailureThreshold int
+	// Cooldown is how long an unhealthy backend is skipped
+	// before it is tried again.
+	Cooldown time.Duration
+	// OnError is called with the errors the ChainContext methods
+	// cannot return, such as ErrNoQuorum, which are logged with
+	// slog.Default() when nil. Use the Fetch methods to get them.
+	OnError func(method string, err error)
+}
+
+/*
+*
+
+	Health is the state of a backend as seen by the
+	FailoverChainContext.
+*/
+type Health struct {
+	Name                string
+	Healthy             bool
+	Successes           int
+	Failures            int
+	ConsecutiveFailures int
+	LastError           error
+	LastSuccess         time.Time
+	LastFailure         time.Time
+}
+
+/*
+*
+
+	The error returning reads of a backend, used instead of
+	the ChainContext methods when implemented, as by the
+	Blockfrost and Maestro contexts.
+*/
+type contractFetcher interface {
+	FetchContractCbor(scriptHash string) (string, error)
+}
+
+/*
+*
+
+	FailoverChainContext is a Base.ChainContext combining
+	several backends. Reads go to the first healthy backend
+	and fail over to the next ones, submissions are broadcast
+	to every backend.
+
+	A backend fails a read when it panics, returns an error
+	from its Fetch methods or returns empty protocol
+	parameters or slot.
+*/
+type FailoverChainContext struct {
+	backends []Backend
+	options  Options
+	state    *healthState
+}
+
+// healthState is the health of the backends, shared by the bound copies.
+type healthState struct {
+	mu     sync.Mutex
+	health []Health
+}
+
+/*
+*
+
+	NewFailoverChainContext combines backends, the first one
+	being the primary.
+
+	Params:
+		backends ([]Backend): The backends, in order of preference.
+		options (Options): The quorum and health settings.
+
+	Returns:
+		*FailoverChainContext: The combined context.
+		error: An error if there are no backends, two share a
+			name or the quorum cannot be reached.
+*/
+func NewFailoverChainContext(backends []Backend, options Options) (*FailoverChainContext, error) {
+	if len(backends) == 0 {
+		return nil, ErrNoBackends
+	}
+	if options.Quorum > len(backends) {
+		return nil, fmt.Errorf("quorum of %d with %d backends", options.Quorum, len(backends))
+	}
+	if options.FailureThreshold <= 0 {
+		options.FailureThreshold = DEFAULT_FAILURE_THRESHOLD
+	}
+	if options.Cooldown <= 0 {
+		options.Cooldown = DEFAULT_COOLDOWN
+	}
+	health := make([]Health, len(backends))
+	names := make(map[string]bool)
+	for i, backend := range backends {
+		if backend.Context == nil {
+			return nil, fmt.Errorf("backend %s has no context", backend.Name)
+		}
+		if names[backend.Name] {
+			return nil, fmt.Errorf("duplicate backend %s", backend.Name)
+		}
+		names[backend.Name] = true
+		health[i] = Health{Name: backend.Name, Healthy: true}
+	}
+	return &FailoverChainContext{
+		backends: append([]Backend{}, backends...),
+		options:  options,
+		state:    &healthState{health: health},
+	}, nil
+}
+
+/*
+*
+
+	WithContext implements Base.ContextBinder, binding each
+	backend, the health of the backends being shared with fcc.
+
+	Params:
+		ctx (context.Context): The context of the calls.
+
+	Returns:
+		Base.ChainContext: The bound context.
+*/
+func (fcc *FailoverChainContext) WithContext(ctx context.Context) Base.ChainContext {
+	bound := *fcc
+	bound.backends = make([]Backend, len(fcc.backends))
+	for i, backend := range fcc.backends {
+		bound.backends[i] = Backend{Name: backend.Name, Context: Base.WithContext(backend.Context, ctx)}
+	}
+	return &bound
+}
+
+/*
+*
+
+	Health returns the state of every backend.
+
+	Returns:
+		[]Health: The health of the backends, in order.
+*/
+func (fcc *FailoverChainContext) Health() []Health {
+	fcc.state.mu.Lock()
+	defer fcc.state.mu.Unlock()
+	return append([]Health{}, fcc.state.health...)
+}
+
+/*
+*
+
+	order returns the indexes of the backends to ask: the
+	healthy ones and those past their cooldown first, the
+	others last.
+*/
+func (fcc *FailoverChainContext) order() []int {
+	fcc.state.mu.Lock()
+	defer fcc.state.mu.Unlock()
+	available := make([]int, 0, len(fcc.backends))
+	skipped := make([]int, 0)
+	for i, health := range fcc.state.health {
+		if health.Healthy || time.Since(health.LastFailure) >= fcc.options.Cooldown {
+			available = append(available, i)
+		} else {
+			skipped = append(skipped, i)
+		}
+	}
+	return append(available, skipped...)
+}
+
+func (fcc *FailoverChainContext) reportError(method string, err error) {
+	if fcc.options.OnError != nil {
+		fcc.options.OnError(method, err)
+		return
+	}
+	slog.Default().Warn("failover chain context", "method", method, "error", err)
+}
+
+func (fcc *FailoverChainContext) record(index int, err error) {
+	fcc.state.mu.Lock()
+	defer fcc.state.mu.Unlock()
+	health := &fcc.state.health[index]
+	if err == nil {
+		health.Successes++
+		health.ConsecutiveFailures = 0
+		health.Healthy = true
+		health.LastSuccess = time.Now()
+		return
+	}
+	health.Failures++
+	health.ConsecutiveFailures++
+	health.LastError = err
+	health.LastFailure = time.Now()
+	if health.ConsecutiveFailures >= fcc.options.FailureThreshold {
+		health.Healthy = false
+	}
+}
+
+/*
+*
+
+	attempt calls a backend, recording its health. A call
+	returning errNoAnswer, such as a missing script, is
+	neither a success nor a failure.
+*/
+func attempt[T any](fcc *FailoverChainContext, index int, call func(Base.ChainContext) (T, error)) (value T, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+		if !errors.Is(err, errNoAnswer) {
+			fcc.record(index, err)
+		}
+		if err != nil {
+			err = fmt.Errorf("%s: %w", fcc.backends[index].Name, err)
+		}
+	}()
+	return call(fcc.backends[index].Context)
+}
+
+func first[T any](fcc *FailoverChainContext, call func(Base.ChainContext) (T, error)) (T, error) {
+	var errs []error
+	for _, index := range fcc.order() {
+		value, err := attempt(fcc, index, call)
+		if err == nil {
+			return value, nil
+		}
+		errs = append(errs, err)
+	}
+	var zero T
+	return zero, errors.Join(errs...)
+}
+
+/*
+*
+
+	quorum asks the backends concurrently and returns the
+	value on which Options.Quorum of them agree, preferring
+	the one of the earliest backend. Without agreement the
+	value of the most backends is returned with ErrNoQuorum.
+*/
+func quorum[T any](fcc *FailoverChainContext, call func(Base.ChainContext) (T, error), fingerprint func(T) string) (T, error) {
+	if fcc.options.Quorum <= 1 {
+		return first(fcc, call)
+	}
+	order := fcc.order()
+	values := make([]T, len(order))
+	errs := make([]error, len(order))
+	var wg sync.WaitGroup
+	for position, index := range order {
+		wg.Add(1)
+		go func(position int, index int) {
+			defer wg.Done()
+			values[position], errs[position] = attempt(fcc, index, call)
+		}(position, index)
+	}
+	wg.Wait()
+
+	groups := make(map[string][]int)
+	keys := make([]string, 0)
+	for position := range order {
+		if errs[position] != nil {
+			continue
+		}
+		key := fingerprint(values[position])
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], position)
+	}
+	if len(keys) == 0 {
+		var zero T
+		return zero, errors.Join(errs...)
+	}
+	best := keys[0]
+	for _, key := range keys[1:] {
+		if len(groups[key]) > len(groups[best]) {
+			best = key
+		}
+	}
+	value := values[groups[best][0]]
+	if len(groups[best]) < fcc.options.Quorum {
+		names := make([]string, 0, len(keys))
+		for _, key := range keys {
+			group := make([]string, 0, len(groups[key]))
+			for _, position := range groups[key] {
+				group = append(group, fcc.backends[order[position]].Name)
+			}
+			names = append(names, strings.Join(group, ","))
+		}
+		return value, fmt.Errorf("%w: %d of %d needed, answers from [%s]", ErrNoQuorum, len(groups[best]), fcc.options.Quorum, strings.Join(names, "] ["))
+	}
+	return value, nil
+}
+
+func utxosFingerprint(utxos []UTxO.UTxO) string {
+	keys := make([]string, len(utxos))
+	for i, utxo := range utxos {
+		keys[i] = fmt.Sprintf("%s:%d", utxo.GetKey(), utxo.Output.GetAmount().GetCoin())
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ";")
+}
+
+/*
+*
+
+	paramsFingerprint covers the parameters used to build
+	transactions, backends differing in the others they report.
+*/
+func paramsFingerprint(params Base.ProtocolParameters) string {
+	return fmt.Sprint(
+		params.MinFeeConstant,
+		params.MinFeeCoefficient,
+		params.MaxTxSize,
+		params.MaxValSize,
+		params.KeyDeposits,
+		params.PoolDeposits,
+		params.CoinsPerUtxoByte,
+		params.CollateralPercent,
+		params.MaxCollateralInuts,
+		params.MaxTxExMem,
+		params.MaxTxExSteps,
+		params.ProtocolMajorVersion,
+		params.ScriptFee(1_000_000, 1_000_000_000),
+		params.RefScriptFee(100_000),
+	)
+}
+
+/*
+*
+
+	FetchUtxos returns the UTxOs of an address, checking them
+	against the quorum.
+
+	Params:
+		address (Address.Address): The address.
+
+	Returns:
+		[]UTxO.UTxO: The UTxOs.
+		error: An error if every backend failed, or ErrNoQuorum
+			along with the UTxOs of the most backends.
+*/
+func (fcc *FailoverChainContext) FetchUtxos(address Address.Address) ([]UTxO.UTxO, error) {
+	return quorum(fcc, func(cc Base.ChainContext) ([]UTxO.UTxO, error) {
+		return Base.FetchUtxos(cc, address)
+	}, utxosFingerprint)
+}
+
+/*
+*
+
+	FetchProtocolParams returns the protocol parameters,
+	checking them against the quorum.
+
+	Returns:
+		Base.ProtocolParameters: The protocol parameters.
+		error: An error if every backend failed, or ErrNoQuorum
+			along with the parameters of the most backends.
+*/
+func (fcc *FailoverChainContext) FetchProtocolParams() (Base.ProtocolParameters, error) {
+	return quorum(fcc, func(cc Base.ChainContext) (Base.ProtocolParameters, error) {
+		params := cc.GetProtocolParams()
+		if params.MinFeeCoefficient == 0 {
+			return params, errors.New("empty protocol parameters")
+		}
+		return params, nil
+	}, paramsFingerprint)
+}
+
+/*
+*
+
+	FetchEvaluation evaluates a transaction on the first
+	backend able to.
+
+	Params:
+		tx ([]byte): The CBOR of the transaction.
+
+	Returns:
+		map[string]Redeemer.ExecutionUnits: The execution units by redeemer.
+		error: An error if every backend failed.
+*/
+func (fcc *FailoverChainContext) FetchEvaluation(tx []byte) (map[string]Redeemer.ExecutionUnits, error) {
+	return first(fcc, func(cc Base.ChainContext) (map[string]Redeemer.ExecutionUnits, error) {
+		return Base.FetchEvaluation(cc, tx)
+	})
+}
+
+/*
+*
+
+	FetchContractCbor returns the CBOR of a script from the
+	first backend knowing it.
+
+	Params:
+		scriptHash (string): The hash of the script.
+
+	Returns:
+		string: The hex encoded CBOR of the script.
+		error: An error if no backend knows the script.
+*/
+func (fcc *FailoverChainContext) FetchContractCbor(scriptHash string) (string, error) {
+	return first(fcc, func(cc Base.ChainContext) (string, error) {
+		if fetcher, ok := cc.(contractFetcher); ok {
+			script, err := fetcher.FetchContractCbor(scriptHash)
+			if errors.Is(err, HttpClient.ErrNotFound) {
+				return "", errNoAnswer
+			}
+			return script, err
+		}
+		script := cc.GetContractCbor(scriptHash)
+		if script == "" {
+			return "", errNoAnswer
+		}
+		return script, nil
+	})
+}
+
+/*
+*
+
+	ResolveDatum returns a datum from the first backend knowing
+	it, among the ones implementing Base.DatumResolver.
+
+	Params:
+		datumHash (string): The hex encoded hash of the datum.
+
+	Returns:
+		*PlutusData.PlutusData: The datum.
+		error: An error if no backend knows the datum,
+			Base.ErrNotSupported if none can resolve datums.
+*/
+func (fcc *FailoverChainContext) ResolveDatum(datumHash string) (*PlutusData.PlutusData, error) {
+	supported := false
+	for _, backend := range fcc.backends {
+		if _, ok := backend.Context.(Base.DatumResolver); ok {
+			supported = true
+		}
+	}
+	if !supported {
+		return nil, Base.ErrNotSupported
+	}
+	return first(fcc, func(cc Base.ChainContext) (*PlutusData.PlutusData, error) {
+		resolver, ok := cc.(Base.DatumResolver)
+		if !ok {
+			return nil, errNoAnswer
+		}
+		datum, err := resolver.ResolveDatum(datumHash)
+		if errors.Is(err, Base.ErrDatumNotFound) {
+			return nil, errNoAnswer
+		}
+		return datum, err
+	})
+}
+
+/*
+*
+
+	GetScript returns a typed script from the first backend
+	knowing it, among the ones implementing Base.ScriptResolver.
+
+	Params:
+		scriptHash (string): The hex encoded hash of the script.
+
+	Returns:
+		*PlutusData.ScriptRef: The script.
+		error: An error if no backend knows the script,
+			Base.ErrNotSupported if none can fetch typed scripts.
+*/
+func (fcc *FailoverChainContext) GetScript(scriptHash string) (*PlutusData.ScriptRef, error) {
+	supported := false
+	for _, backend := range fcc.backends {
+		if _, ok := backend.Context.(Base.ScriptResolver); ok {
+			supported = true
+		}
+	}
+	if !supported {
+		return nil, Base.ErrNotSupported
+	}
+	return first(fcc, func(cc Base.ChainContext) (*PlutusData.ScriptRef, error) {
+		resolver, ok := cc.(Base.ScriptResolver)
+		if !ok {
+			return nil, errNoAnswer
+		}
+		scriptRef, err := resolver.GetScript(scriptHash)
+		if errors.Is(err, Base.ErrScriptNotFound) || errors.Is(err, Base.ErrNotSupported) {
+			return nil, errNoAnswer
+		}
+		return scriptRef, err
+	})
+}
+
+/*
+*
+
+	GetProtocolParams returns the protocol parameters as
+	FetchProtocolParams, reporting its error to Options.OnError.
+	Without quorum the parameters of the most backends are
+	returned, empty ones only when every backend failed.
+
+	Returns:
+		Base.ProtocolParameters: The protocol parameters.
+*/
+func (fcc *FailoverChainContext) GetProtocolParams() Base.ProtocolParameters {
+	params, err := fcc.FetchProtocolParams()
+	if err != nil {
+		fcc.reportError("GetProtocolParams", err)
+	}
+	return params
+}
+
+func (fcc *FailoverChainContext) GetGenesisParams() Base.GenesisParameters {
+	genesis, err := first(fcc, func(cc Base.ChainContext) (Base.GenesisParameters, error) {
+		genesis := cc.GetGenesisParams()
+		if genesis == (Base.GenesisParameters{}) {
+			return genesis, errNoAnswer
+		}
+		return genesis, nil
+	})
+	if err != nil {
+		return Base.GenesisParameters{}
+	}
+	return genesis
+}
+
+func (fcc *FailoverChainContext) Network() int {
+	return fcc.backends[0].Context.Network()
+}
+
+// LoadsWalletUtxos implements Base.WalletUtxoLoader, true if any
+// backend loads the wallet UTxOs.
+func (fcc *FailoverChainContext) LoadsWalletUtxos() bool {
+	for _, backend := range fcc.backends {
+		if loader, ok := backend.Context.(Base.WalletUtxoLoader); ok && loader.LoadsWalletUtxos() {
+			return true
+		}
+	}
+	return false
+}
+
+func (fcc *FailoverChainContext) Epoch() int {
+	epoch, _ := first(fcc, func(cc Base.ChainContext) (int, error) {
+		return cc.Epoch(), nil
+	})
+	return epoch
+}
+
+func (fcc *FailoverChainContext) MaxTxFee() int {
+	protocol_param := fcc.GetProtocolParams()
+	return Base.Fee(fcc, protocol_param.MaxTxSize, int(protocol_param.MaxTxExSteps), int(protocol_param.MaxTxExMem))
+}
+
+func (fcc *FailoverChainContext) LastBlockSlot() int {
+	slot, _ := first(fcc, func(cc Base.ChainContext) (int, error) {
+		slot := cc.LastBlockSlot()
+		if slot == 0 {
+			return 0, errors.New("empty slot")
+		}
+		return slot, nil
+	})
+	return slot
+}
+
+/*
+*
+
+	Utxos returns the UTxOs of an address as FetchUtxos,
+	reporting its error to Options.OnError. Without quorum the
+	UTxOs of the most backends are returned, none only when
+	every backend failed.
+
+	Params:
+		address (Address.Address): The address.
+
+	Returns:
+		[]UTxO.UTxO: The UTxOs.
+*/
+func (fcc *FailoverChainContext) Utxos(address Address.Address) []UTxO.UTxO {
+	utxos, err := fcc.FetchUtxos(address)
+	if err != nil {
+		fcc.reportError("Utxos", err)
+	}
+	return utxos
+}
+
+/*
+*
+
+	SubmitTx broadcasts a transaction to every backend, so that
+	it reaches the chain as long as one of them is up.
+
+	Params:
+		tx (Transaction.Transaction): The transaction.
+
+	Returns:
+		serialization.TransactionId: The id returned by the first backend accepting it.
+		error: The errors of every backend if none accepted it.
+*/
+func (fcc *FailoverChainContext) SubmitTx(tx Transaction.Transaction) (serialization.TransactionId, error) {
+	type result struct {
+		txId serialization.TransactionId
+		err  error
+	}
+	results := make(chan result, len(fcc.backends))
+	for index := range fcc.backends {
+		go func(index int) {
+			txId, err := attempt(fcc, index, func(cc Base.ChainContext) (serialization.TransactionId, error) {
+				return cc.SubmitTx(tx)
+			})
+			results <- result{txId, err}
+		}(index)
+	}
+	errs := make([]error, 0, len(fcc.backends))
+	for range fcc.backends {
+		result := <-results
+		if result.err == nil {
+			return result.txId, nil
+		}
+		errs = append(errs, result.err)
+	}
+	return serialization.TransactionId{}, fmt.Errorf("error submitting tx: %w", errors.Join(errs...))
+}
+
+func (fcc *FailoverChainContext) EvaluateTx(tx []uint8) map[string]Redeemer.ExecutionUnits {
+	evaluation, _ := fcc.FetchEvaluation(tx)
+	return evaluation
+}
+
+func (fcc *FailoverChainContext) GetUtxoFromRef(txHash string, txIndex int) *UTxO.UTxO {
+	utxo, _ := first(fcc, func(cc Base.ChainContext) (*UTxO.UTxO, error) {
+		utxo := cc.GetUtxoFromRef(txHash, txIndex)
+		if utxo == nil {
+			return nil, errNoAnswer
+		}
+		return utxo, nil
+	})
+	return utxo
+}
+
+func (fcc *FailoverChainContext) GetContractCbor(scriptHash string) string {
+	script, _ := fcc.FetchContractCbor(scriptHash)
+	return script
+}
@@ -0,0 +1,354 @@
+package FailoverChainContext_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Salvionied/apollo/serialization"
+	"github.com/Salvionied/apollo/serialization/Address"
+	"github.com/Salvionied/apollo/serialization/PlutusData"
+	"github.com/Salvionied/apollo/serialization/Transaction"
+	"github.com/Salvionied/apollo/serialization/UTxO"
+	"github.com/Salvionied/apollo/txBuilding/Backend/Base"
+	"github.com/Salvionied/apollo/txBuilding/Backend/FailoverChainContext"
+	"github.com/Salvionied/apollo/txBuilding/Backend/FixedChainContext"
+)
+
+const testAddress = "addr1qy99jvml0vafzdpy6lm6z52qrczjvs4k362gmr9v4hrrwgqk4xvegxwvtfsu5ck6s83h346nsgf6xu26dwzce9yvd8ysd2seyu"
+
+// fakeContext is a backend which can be taken down or lag behind.
+type fakeContext struct {
+	FixedChainContext.FixedChainContext
+	mu        sync.Mutex
+	down      bool
+	stale     bool
+	script    string
+	datum     *PlutusData.PlutusData
+	scriptRef *PlutusData.ScriptRef
+	submitErr error
+	calls     map[string]int
+}
+
+func newFakeContext() *fakeContext {
+	return &fakeContext{FixedChainContext: FixedChainContext.InitFixedChainContext(), calls: map[string]int{}}
+}
+
+func (f *fakeContext) call(name string) (bool, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls[name]++
+	return f.down, f.stale
+}
+
+func (f *fakeContext) count(name string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[name]
+}
+
+func (f *fakeContext) setDown(down bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.down = down
+}
+
+func (f *fakeContext) ResolveDatum(datumHash string) (*PlutusData.PlutusData, error) {
+	if down, _ := f.call("ResolveDatum"); down {
+		return nil, errors.New("down")
+	}
+	if f.datum == nil {
+		return nil, Base.ErrDatumNotFound
+	}
+	return f.datum, nil
+}
+
+func (f *fakeContext) GetScript(scriptHash string) (*PlutusData.ScriptRef, error) {
+	if down, _ := f.call("GetScript"); down {
+		return nil, errors.New("down")
+	}
+	if f.scriptRef == nil {
+		return nil, Base.ErrScriptNotFound
+	}
+	return f.scriptRef, nil
+}
+
+func (f *fakeContext) GetProtocolParams() Base.ProtocolParameters {
+	down, stale := f.call("GetProtocolParams")
+	if down {
+		return Base.ProtocolParameters{}
+	}
+	params := f.FixedChainContext.GetProtocolParams()
+	if stale {
+		params.MinFeeConstant++
+	}
+	return params
+}
+
+func (f *fakeContext) FetchUtxos(address Address.Address) ([]UTxO.UTxO, error) {
+	down, stale := f.call("Utxos")
+	if down {
+		return nil, errors.New("connection refused")
+	}
+	utxos := f.FixedChainContext.Utxos(address)
+	if stale {
+		return utxos[:1], nil
+	}
+	return utxos, nil
+}
+
+func (f *fakeContext) Utxos(address Address.Address) []UTxO.UTxO {
+	utxos, _ := f.FetchUtxos(address)
+	return utxos
+}
+
+func (f *fakeContext) LastBlockSlot() int {
+	if down, _ := f.call("LastBlockSlot"); down {
+		panic("backend crashed")
+	}
+	return f.FixedChainContext.LastBlockSlot()
+}
+
+func (f *fakeContext) GetContractCbor(scriptHash string) string {
+	f.call("GetContractCbor")
+	return f.script
+}
+
+func (f *fakeContext) SubmitTx(tx Transaction.Transaction) (serialization.TransactionId, error) {
+	f.call("SubmitTx")
+	return serialization.TransactionId{Payload: []byte{1}}, f.submitErr
+}
+
+// boundContext is a fakeContext whose calls run in a context.
+type boundContext struct {
+	*fakeContext
+	ctx context.Context
+}
+
+func (f *fakeContext) WithContext(ctx context.Context) Base.ChainContext {
+	return &boundContext{fakeContext: f, ctx: ctx}
+}
+
+func (b *boundContext) FetchUtxos(address Address.Address) ([]UTxO.UTxO, error) {
+	if err := b.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return b.fakeContext.FetchUtxos(address)
+}
+
+func newContext(t *testing.T, options FailoverChainContext.Options, backends ...*fakeContext) *FailoverChainContext.FailoverChainContext {
+	named := make([]FailoverChainContext.Backend, len(backends))
+	for i, backend := range backends {
+		named[i] = FailoverChainContext.Backend{Name: string(rune('a' + i)), Context: backend}
+	}
+	fcc, err := FailoverChainContext.NewFailoverChainContext(named, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fcc
+}
+
+func TestNewFailoverChainContext(t *testing.T) {
+	backend := FailoverChainContext.Backend{Name: "a", Context: newFakeContext()}
+	cases := map[string][]FailoverChainContext.Backend{
+		"no backends": nil,
+		"duplicate":   {backend, backend},
+		"no context":  {{Name: "a"}},
+	}
+	for name, backends := range cases {
+		if _, err := FailoverChainContext.NewFailoverChainContext(backends, FailoverChainContext.Options{}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if _, err := FailoverChainContext.NewFailoverChainContext([]FailoverChainContext.Backend{backend}, FailoverChainContext.Options{Quorum: 2}); err == nil {
+		t.Error("expected an error for an unreachable quorum")
+	}
+}
+
+func TestFailover(t *testing.T) {
+	primary, secondary := newFakeContext(), newFakeContext()
+	fcc := newContext(t, FailoverChainContext.Options{Cooldown: 50 * time.Millisecond}, primary, secondary)
+	var _ Base.ChainContext = fcc
+	addr, _ := Address.DecodeAddress(testAddress)
+
+	primary.setDown(true)
+	if utxos := fcc.Utxos(addr); len(utxos) != 2 {
+		t.Fatalf("expected the UTxOs of the secondary, got %d", len(utxos))
+	}
+	if fcc.LastBlockSlot() != 2000 || fcc.GetProtocolParams().MinFeeCoefficient == 0 {
+		t.Error("expected the reads to fail over")
+	}
+	health := fcc.Health()
+	if health[0].Healthy || health[0].Failures != 1 || health[0].LastError == nil || !health[1].Healthy || health[1].Successes != 3 {
+		t.Fatalf("unexpected health %+v", health)
+	}
+
+	// the primary is skipped during its cooldown
+	fcc.Utxos(addr)
+	if primary.count("Utxos") != 1 {
+		t.Errorf("expected the unhealthy primary to be skipped, got %d calls", primary.count("Utxos"))
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	primary.setDown(false)
+	fcc.Utxos(addr)
+	if primary.count("Utxos") != 2 || !fcc.Health()[0].Healthy {
+		t.Errorf("expected the primary to recover after its cooldown, got %+v", fcc.Health()[0])
+	}
+
+	primary.setDown(true)
+	secondary.setDown(true)
+	if fcc.LastBlockSlot() != 0 {
+		t.Error("expected no slot when every backend is down")
+	}
+	if _, err := fcc.FetchUtxos(addr); err == nil || !strings.Contains(err.Error(), "a: connection refused") || !strings.Contains(err.Error(), "b: connection refused") {
+		t.Errorf("expected the errors of every backend, got %v", err)
+	}
+}
+
+func TestWithContext(t *testing.T) {
+	primary, secondary := newFakeContext(), newFakeContext()
+	fcc := newContext(t, FailoverChainContext.Options{}, primary, secondary)
+	addr, _ := Address.DecodeAddress(testAddress)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Base.FetchUtxos(Base.WithContext(fcc, cancelled), addr); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the backends to run in the bound context, got %v", err)
+	}
+	// the bound copy shares the health of the backends
+	if health := fcc.Health(); health[0].Failures != 1 || health[1].Failures != 1 {
+		t.Errorf("expected the failures of the bound copy, got %+v", health)
+	}
+	if utxos, err := fcc.FetchUtxos(addr); err != nil || len(utxos) != 2 {
+		t.Errorf("expected the context itself to be left unbound, got %v", err)
+	}
+}
+
+func TestQuorum(t *testing.T) {
+	addr, _ := Address.DecodeAddress(testAddress)
+	stale, good, other := newFakeContext(), newFakeContext(), newFakeContext()
+	stale.stale = true
+
+	fcc := newContext(t, FailoverChainContext.Options{Quorum: 2}, stale, good, other)
+	utxos, err := fcc.FetchUtxos(addr)
+	if err != nil || len(utxos) != 2 {
+		t.Errorf("expected the UTxOs agreed on, got %d UTxOs and %v", len(utxos), err)
+	}
+	params, err := fcc.FetchProtocolParams()
+	if err != nil || params.MinFeeConstant != good.FixedChainContext.GetProtocolParams().MinFeeConstant {
+		t.Errorf("expected the params agreed on, got %v", err)
+	}
+	if stale.count("Utxos") != 1 || good.count("Utxos") != 1 || other.count("Utxos") != 1 {
+		t.Error("expected every backend to be asked")
+	}
+
+	fcc = newContext(t, FailoverChainContext.Options{Quorum: 3}, stale, good, other)
+	utxos, err = fcc.FetchUtxos(addr)
+	if !errors.Is(err, FailoverChainContext.ErrNoQuorum) || len(utxos) != 2 {
+		t.Errorf("expected ErrNoQuorum with the majority UTxOs, got %d UTxOs and %v", len(utxos), err)
+	}
+
+	other.setDown(true)
+	var reported []string
+	fcc = newContext(t, FailoverChainContext.Options{Quorum: 2, OnError: func(method string, err error) {
+		if errors.Is(err, FailoverChainContext.ErrNoQuorum) {
+			reported = append(reported, method)
+		}
+	}}, stale, good, other)
+	if _, err := fcc.FetchUtxos(addr); !errors.Is(err, FailoverChainContext.ErrNoQuorum) {
+		t.Errorf("expected ErrNoQuorum with a backend down, got %v", err)
+	}
+	// the legacy methods report the error and fall back to an answer of a backend
+	if utxos := fcc.Utxos(addr); len(utxos) != 1 {
+		t.Errorf("expected the UTxOs of the earliest backend, got %d", len(utxos))
+	}
+	if params := fcc.GetProtocolParams(); params.MinFeeCoefficient == 0 {
+		t.Error("expected the parameters of a backend, got empty ones")
+	}
+	if strings.Join(reported, ",") != "Utxos,GetProtocolParams" {
+		t.Errorf("expected ErrNoQuorum to be reported, got %v", reported)
+	}
+}
+
+func TestSubmitTx(t *testing.T) {
+	failing, accepting := newFakeContext(), newFakeContext()
+	failing.submitErr = errors.New("mempool full")
+	fcc := newContext(t, FailoverChainContext.Options{}, failing, accepting)
+	txId, err := fcc.SubmitTx(Transaction.Transaction{})
+	if err != nil || len(txId.Payload) != 1 {
+		t.Fatalf("expected the submission to succeed, got %v", err)
+	}
+	if accepting.count("SubmitTx") != 1 {
+		t.Error("expected the transaction to reach every backend")
+	}
+
+	accepting.submitErr = errors.New("invalid")
+	_, err = fcc.SubmitTx(Transaction.Transaction{})
+	if err == nil || !strings.Contains(err.Error(), "mempool full") || !strings.Contains(err.Error(), "invalid") {
+		t.Errorf("expected the errors of every backend, got %v", err)
+	}
+	if failing.count("SubmitTx") != 2 {
+		t.Error("expected unhealthy backends to still be sent transactions")
+	}
+}
+
+func TestMissingScript(t *testing.T) {
+	primary, secondary := newFakeContext(), newFakeContext()
+	secondary.script = "4e4d01000033222220051200120011"
+	fcc := newContext(t, FailoverChainContext.Options{}, primary, secondary)
+	if fcc.GetContractCbor("hash") != secondary.script {
+		t.Fatal("expected the script of the secondary")
+	}
+	if health := fcc.Health()[0]; !health.Healthy || health.Failures != 0 {
+		t.Errorf("a missing script is not a failure, got %+v", health)
+	}
+	secondary.script = ""
+	if _, err := fcc.FetchContractCbor("hash"); err == nil {
+		t.Error("expected an error for an unknown script")
+	}
+}
+
+func TestResolveDatum(t *testing.T) {
+	primary, secondary := newFakeContext(), newFakeContext()
+	secondary.datum = &PlutusData.PlutusData{TagNr: 121, PlutusDataType: PlutusData.PlutusArray, Value: PlutusData.PlutusIndefArray{}}
+	fcc := newContext(t, FailoverChainContext.Options{}, primary, secondary)
+	var _ Base.DatumResolver = fcc
+	if datum, err := fcc.ResolveDatum("hash"); err != nil || datum != secondary.datum {
+		t.Fatal("expected the datum of the secondary", datum, err)
+	}
+	if health := fcc.Health()[0]; !health.Healthy || health.Failures != 0 {
+		t.Errorf("a missing datum is not a failure, got %+v", health)
+	}
+
+	fixed, _ := FailoverChainContext.NewFailoverChainContext([]FailoverChainContext.Backend{
+		{Name: "fixed", Context: FixedChainContext.InitFixedChainContext()},
+	}, FailoverChainContext.Options{})
+	if _, err := fixed.ResolveDatum("hash"); !errors.Is(err, Base.ErrNotSupported) {
+		t.Errorf("expected no backend to support datums, got %v", err)
+	}
+}
+
+func TestGetScript(t *testing.T) {
+	primary, secondary := newFakeContext(), newFakeContext()
+	script := PlutusData.NewScriptRef(3, []byte{0x01})
+	secondary.scriptRef = &script
+	fcc := newContext(t, FailoverChainContext.Options{}, primary, secondary)
+	var _ Base.ScriptResolver = fcc
+	if resolved, err := fcc.GetScript("hash"); err != nil || resolved != secondary.scriptRef {
+		t.Fatal("expected the script of the secondary", resolved, err)
+	}
+	if health := fcc.Health()[0]; !health.Healthy || health.Failures != 0 {
+		t.Errorf("a missing script is not a failure, got %+v", health)
+	}
+
+	fixed, _ := FailoverChainContext.NewFailoverChainContext([]FailoverChainContext.Backend{
+		{Name: "fixed", Context: FixedChainContext.InitFixedChainContext()},
+	}, FailoverChainContext.Options{})
+	if _, err := fixed.GetScript("hash"); !errors.Is(err, Base.ErrNotSupported) {
+		t.Errorf("expected no backend to support typed scripts, got %v", err)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/Salvionied/apollo/serialization/AssetName"
 	"github.com/Salvionied/apollo/serialization/MultiAsset"
 	"github.com/Salvionied/apollo/serialization/Policy"
+	"github.com/Salvionied/apollo/serialization/Rational"
 	"github.com/Salvionied/apollo/serialization/Redeemer"
 	"github.com/Salvionied/apollo/serialization/Transaction"
 	"github.com/Salvionied/apollo/serialization/TransactionInput"
@@ -43,30 +44,33 @@ type FixedChainContext struct {
 
 func InitFixedChainContext() FixedChainContext {
 	return FixedChainContext{ProtocolParams: Base.ProtocolParameters{
-		MinFeeConstant:        155381,
-		MinFeeCoefficient:     44,
-		MaxBlockSize:          73728,
-		MaxTxSize:             16384,
-		MaxBlockHeaderSize:    1100,
-		KeyDeposits:           "2000000",
-		PoolDeposits:          "500000000",
-		PooolInfluence:        0.3,
-		TreasuryExpansion:     0.2,
-		DecentralizationParam: 0,
-		ExtraEntropy:          "",
-		ProtocolMajorVersion:  6,
-		ProtocolMinorVersion:  0,
-		MinUtxo:               "1000000",
-		MinPoolCost:           "340000000",
-		PriceMem:              0.0577,
-		PriceStep:             0.0000721,
-		MaxTxExMem:            "10000000",
-		MaxTxExSteps:          "10000000000",
-		MaxBlockExMem:         "500000000",
-		MaxBlockExSteps:       "40000000000",
-		MaxValSize:            "5000",
-		CoinsPerUtxoWord:      "34482",
-		//CoinsPerUtxoByte:      "4310",
+		MinFeeConstant:             155381,
+		MinFeeCoefficient:          44,
+		MaxBlockSize:               73728,
+		MaxTxSize:                  16384,
+		MaxBlockHeaderSize:         1100,
+		KeyDeposits:                2000000,
+		PoolDeposits:               500000000,
+		PooolInfluence:             Rational.New(3, 10),
+		TreasuryExpansion:          Rational.New(2, 10),
+		DecentralizationParam:      Rational.Rational{},
+		ExtraEntropy:               "",
+		ProtocolMajorVersion:       6,
+		ProtocolMinorVersion:       0,
+		MinUtxo:                    1000000,
+		MinPoolCost:                340000000,
+		PriceMem:                   Rational.New(577, 10000),
+		PriceStep:                  Rational.New(721, 10000000),
+		MinFeeRefScriptCostPerByte: Rational.New(15, 1),
+		MaxTxExMem:                 10000000,
+		MaxTxExSteps:               10000000000,
+		MaxBlockExMem:              500000000,
+		MaxBlockExSteps:            40000000000,
+		MaxValSize:                 5000,
+		CollateralPercent:          150,
+		MaxCollateralInuts:         3,
+		CoinsPerUtxoWord:           34482,
+		//CoinsPerUtxoByte:      4310,
 	},
 		GenesisParams: Base.GenesisParameters{
 			ActiveSlotsCoefficient: 0.05,
@@ -125,6 +129,10 @@ func (f FixedChainContext) Utxos(address Address.Address) []UTxO.UTxO {
 	return []UTxO.UTxO{{Input: tx_in1, Output: tx_out1}, {Input: tx_in2, Output: tx_out2}}
 }
 
+func (f FixedChainContext) FetchUtxos(address Address.Address) ([]UTxO.UTxO, error) {
+	return f.Utxos(address), nil
+}
+
 func (f FixedChainContext) SubmitTx(tx Transaction.Transaction) (serialization.TransactionId, error) {
 	return serialization.TransactionId{}, nil
 }
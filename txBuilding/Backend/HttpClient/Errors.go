@@ -0,0 +1,142 @@
+package HttpClient
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// MAX_ERROR_BODY is the number of bytes of an error response kept in an APIError.
+const MAX_ERROR_BODY = 4096
+
+var (
+	ErrRateLimited = errors.New("rate limited")
+	ErrNotFound    = errors.New("not found")
+)
+
+/*
+*
+
+	APIError is returned by CheckResponse when a backend
+	answers with a non 2xx status.
+	It matches ErrRateLimited for 429 and ErrNotFound for 404
+	with errors.Is.
+*/
+type APIError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("unexpected status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("unexpected status %d: %s", e.StatusCode, e.Body)
+}
+
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	}
+	return false
+}
+
+/*
+*
+
+	HTTPStatus returns the status of the response.
+
+	Returns:
+		int: The HTTP status code.
+*/
+func (e *APIError) HTTPStatus() int {
+	return e.StatusCode
+}
+
+/*
+*
+
+	Temporary reports whether the request may succeed if retried later.
+
+	Returns:
+		bool: true for rate limiting, timeouts and server errors.
+*/
+func (e *APIError) Temporary() bool {
+	return retryableStatus(e.StatusCode)
+}
+
+/*
+*
+
+	DecodeError is returned when the body of a successful
+	response cannot be decoded.
+*/
+type DecodeError struct {
+	URL  string
+	Body string
+	Err  error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("decoding response of %s: %v", e.URL, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+func successStatus(status int) bool {
+	return status >= 200 && status <= 299
+}
+
+func retryableStatus(status int) bool {
+	switch status {
+	case http.StatusRequestTimeout,
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func newAPIError(req *http.Request, res *http.Response, now time.Time) *APIError {
+	defer res.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(res.Body, MAX_ERROR_BODY))
+	return &APIError{
+		Method:     req.Method,
+		URL:        req.URL.String(),
+		StatusCode: res.StatusCode,
+		Body:       string(body),
+		RetryAfter: parseRetryAfter(res.Header.Get("Retry-After"), now),
+	}
+}
+
+// parseRetryAfter reads a Retry-After header given either in
+// seconds or as an HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
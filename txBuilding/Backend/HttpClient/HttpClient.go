@@ -0,0 +1,400 @@
+package HttpClient
+
+import (
+	"encoding/json"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Salvionied/apollo/observability"
+	"golang.org/x/exp/slog"
+)
+
+/*
+*
+
+	RetryPolicy bounds the retries of a request. The delay
+	before retry n is drawn between half and all of
+	BaseDelay * 2^n, capped at MaxDelay, unless the backend
+	asks for a longer one with Retry-After. A Retry-After past
+	MaxDelay is not waited for: the response is returned and
+	CheckResponse reports the delay in APIError.RetryAfter.
+*/
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+var DEFAULT_RETRY_POLICY = RetryPolicy{
+	MaxRetries: 4,
+	BaseDelay:  250 * time.Millisecond,
+	MaxDelay:   10 * time.Second,
+}
+
+/*
+*
+
+	Backoff returns the jittered delay before a retry.
+
+	Params:
+		attempt (int): The number of attempts already failed, minus one.
+
+	Returns:
+		time.Duration: The delay before the next attempt.
+*/
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	delay := p.MaxDelay
+	if attempt < 32 && p.BaseDelay<<uint(attempt) < p.MaxDelay {
+		delay = p.BaseDelay << uint(attempt)
+	}
+	if delay <= 0 {
+		return 0
+	}
+	half := int64(delay / 2)
+	return time.Duration(half + rand.Int63n(int64(delay)-half+1))
+}
+
+/*
+*
+
+	Transport is an http.RoundTripper applying the rate limiter
+	before every attempt and retrying failed requests.
+
+	Requests rejected with 429 are always retried, since the
+	backend did not process them. Network errors and server
+	errors are only retried for idempotent requests: GET, HEAD
+	and OPTIONS, and POST to a path ending with one of
+	IdempotentPaths. Like any http.RoundTripper, it does not
+	turn a status into an error: once the retries are exhausted
+	the last response is returned and the caller checks its
+	status, with CheckResponse for instance.
+
+	Each attempt is run in an http.request span when
+	Instrumentation is set.
+*/
+type Transport struct {
+	Base            http.RoundTripper
+	Retry           RetryPolicy
+	Limiter         *RateLimiter
+	IdempotentPaths []string
+	Instrumentation observability.Instrumentation
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base == nil {
+		return http.DefaultTransport
+	}
+	return t.Base
+}
+
+func (t *Transport) idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	for _, path := range t.IdempotentPaths {
+		if strings.HasSuffix(req.URL.Path, path) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Transport) roundTrip(req *http.Request, attempt int) (*http.Response, error) {
+	if t.Instrumentation == nil {
+		return t.base().RoundTrip(req)
+	}
+	_, span := t.Instrumentation.Start(req.Context(), "http.request",
+		slog.String("http.method", req.Method),
+		slog.String("http.host", req.URL.Host),
+		slog.String("http.path", req.URL.Path),
+		slog.Int("http.attempt", attempt),
+	)
+	res, err := t.base().RoundTrip(req)
+	if err != nil {
+		span.End(err)
+		return res, err
+	}
+	span.SetAttributes(slog.Int("http.status_code", res.StatusCode))
+	if !successStatus(res.StatusCode) {
+		span.End(&APIError{Method: req.Method, URL: req.URL.String(), StatusCode: res.StatusCode})
+	} else {
+		span.End(nil)
+	}
+	return res, err
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		if err := t.Limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+		attemptReq := req
+		if attempt > 0 && req.Body != nil && req.Body != http.NoBody {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq = req.Clone(ctx)
+			attemptReq.Body = body
+		}
+		res, err := t.roundTrip(attemptReq, attempt)
+		retryable := false
+		var wait time.Duration
+		if err != nil {
+			retryable = t.idempotent(req) && ctx.Err() == nil
+		} else if !successStatus(res.StatusCode) {
+			retryable = res.StatusCode == http.StatusTooManyRequests ||
+				(retryableStatus(res.StatusCode) && t.idempotent(req))
+			wait = parseRetryAfter(res.Header.Get("Retry-After"), time.Now())
+		} else {
+			return res, nil
+		}
+		replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+		if !retryable || !replayable || attempt >= t.Retry.MaxRetries || wait > t.Retry.MaxDelay {
+			return res, err
+		}
+		if res != nil {
+			io.Copy(io.Discard, io.LimitReader(res.Body, MAX_ERROR_BODY))
+			res.Body.Close()
+		}
+		if wait == 0 {
+			wait = t.Retry.Backoff(attempt)
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+/*
+*
+
+	Config holds the settings of the client of an HTTP backend.
+*/
+type Config struct {
+	// Client is the client whose transport is wrapped, a new
+	// one is used when nil.
+	Client *http.Client
+	Retry  RetryPolicy
+	// Limiter is the client side rate limiter, nil to disable it.
+	Limiter *RateLimiter
+	// IdempotentPaths are the POST endpoints safe to retry.
+	IdempotentPaths []string
+	// BaseUrl overrides the url of the backend when not empty.
+	BaseUrl string
+	// Instrumentation receives the attempts, nil to disable it.
+	Instrumentation observability.Instrumentation
+}
+
+type Option func(*Config)
+
+/*
+*
+
+	WithHTTPClient uses a custom client, for instance to set a
+	timeout, a proxy or TLS settings. Its transport is wrapped,
+	the client itself is not modified.
+
+	Params:
+		client (*http.Client): The client to use.
+
+	Returns:
+		Option: The option.
+*/
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Config) {
+		c.Client = client
+	}
+}
+
+/*
+*
+
+	WithRetryPolicy sets the retry policy.
+
+	Params:
+		policy (RetryPolicy): The retry policy, MaxRetries 0 disables retries.
+
+	Returns:
+		Option: The option.
+*/
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Config) {
+		c.Retry = policy
+	}
+}
+
+/*
+*
+
+	WithRateLimit sets the client side rate limit.
+
+	Params:
+		rate (float64): The number of requests per second, 0 disables the limit.
+		burst (int): The number of requests allowed at once.
+
+	Returns:
+		Option: The option.
+*/
+func WithRateLimit(rate float64, burst int) Option {
+	return func(c *Config) {
+		c.Limiter = NewRateLimiter(rate, burst)
+	}
+}
+
+/*
+*
+
+	WithRateLimiter shares a rate limiter, for instance between
+	several contexts using the same project id.
+
+	Params:
+		limiter (*RateLimiter): The rate limiter.
+
+	Returns:
+		Option: The option.
+*/
+func WithRateLimiter(limiter *RateLimiter) Option {
+	return func(c *Config) {
+		c.Limiter = limiter
+	}
+}
+
+/*
+*
+
+	WithBaseUrl overrides the url of the backend.
+
+	Params:
+		baseUrl (string): The url of the backend.
+
+	Returns:
+		Option: The option.
+*/
+func WithBaseUrl(baseUrl string) Option {
+	return func(c *Config) {
+		c.BaseUrl = baseUrl
+	}
+}
+
+/*
+*
+
+	WithInstrumentation runs every attempt in a span, with its
+	method, path and HTTP status.
+
+	Params:
+		instrumentation (observability.Instrumentation): The instrumentation.
+
+	Returns:
+		Option: The option.
+*/
+func WithInstrumentation(instrumentation observability.Instrumentation) Option {
+	return func(c *Config) {
+		c.Instrumentation = instrumentation
+	}
+}
+
+/*
+*
+
+	NewConfig applies options over the defaults of a backend.
+
+	Params:
+		defaults (Config): The defaults of the backend.
+		opts (...Option): The options.
+
+	Returns:
+		Config: The resulting config.
+*/
+func NewConfig(defaults Config, opts ...Option) Config {
+	config := defaults
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return config
+}
+
+/*
+*
+
+	HTTPClient returns a client retrying and rate limiting the
+	requests according to the config.
+
+	Returns:
+		*http.Client: The client.
+*/
+func (c Config) HTTPClient() *http.Client {
+	client := &http.Client{}
+	if c.Client != nil {
+		*client = *c.Client
+	}
+	client.Transport = &Transport{
+		Base:            client.Transport,
+		Retry:           c.Retry,
+		Limiter:         c.Limiter,
+		IdempotentPaths: c.IdempotentPaths,
+		Instrumentation: c.Instrumentation,
+	}
+	return client
+}
+
+/*
+*
+
+	CheckResponse turns a non 2xx response into an error,
+	closing its body.
+
+	Params:
+		res (*http.Response): The response.
+
+	Returns:
+		error: An *APIError for a non 2xx status, nil otherwise.
+*/
+func CheckResponse(res *http.Response) error {
+	if successStatus(res.StatusCode) {
+		return nil
+	}
+	req := res.Request
+	if req == nil {
+		req = &http.Request{URL: &url.URL{}}
+	}
+	return newAPIError(req, res, time.Now())
+}
+
+/*
+*
+
+	DecodeJSON decodes the body of a response and closes it.
+
+	Params:
+		res (*http.Response): The response.
+		out (any): The value to decode into.
+
+	Returns:
+		error: A *DecodeError if the body cannot be read or decoded.
+*/
+func DecodeJSON(res *http.Response, out any) error {
+	defer res.Body.Close()
+	url := ""
+	if res.Request != nil {
+		url = res.Request.URL.String()
+	}
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return &DecodeError{URL: url, Err: err}
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		if len(body) > MAX_ERROR_BODY {
+			body = body[:MAX_ERROR_BODY]
+		}
+		return &DecodeError{URL: url, Body: string(body), Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+package HttpClient_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Salvionied/apollo/observability"
+	"github.com/Salvionied/apollo/txBuilding/Backend/HttpClient"
+)
+
+var fastRetry = HttpClient.RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+// faultyServer fails the first failures requests with status.
+func faultyServer(failures int32, status int, header map[string]string) (*httptest.Server, *int32) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) <= failures {
+			for k, v := range header {
+				w.Header().Set(k, v)
+			}
+			w.WriteHeader(status)
+			w.Write([]byte(`{"message":"injected fault"}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	return server, &hits
+}
+
+// checked returns the error of a request, or the status of its
+// response: the transport itself does not fail on a status.
+func checked(t *testing.T, res *http.Response, err error) error {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("a non 2xx response must not be an error of the transport, got %v", err)
+	}
+	return HttpClient.CheckResponse(res)
+}
+
+func newClient(opts ...HttpClient.Option) *http.Client {
+	return HttpClient.NewConfig(HttpClient.Config{Retry: fastRetry}, opts...).HTTPClient()
+}
+
+func TestRetryServerErrors(t *testing.T) {
+	server, hits := faultyServer(2, http.StatusBadGateway, nil)
+	defer server.Close()
+	res, err := newClient().Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body map[string]bool
+	if err := HttpClient.DecodeJSON(res, &body); err != nil || !body["ok"] {
+		t.Errorf("unexpected body %v %v", body, err)
+	}
+	if *hits != 3 {
+		t.Errorf("expected 3 attempts, got %d", *hits)
+	}
+}
+
+func TestRetriesExhausted(t *testing.T) {
+	server, hits := faultyServer(10, http.StatusServiceUnavailable, nil)
+	defer server.Close()
+	res, err := newClient().Get(server.URL)
+	err = checked(t, res, err)
+	var apiErr *HttpClient.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable || !apiErr.Temporary() {
+		t.Fatalf("expected a 503 APIError, got %v", err)
+	}
+	if !strings.Contains(apiErr.Body, "injected fault") {
+		t.Errorf("expected the body in the error, got %q", apiErr.Body)
+	}
+	if *hits != 4 {
+		t.Errorf("expected 4 attempts, got %d", *hits)
+	}
+}
+
+func TestNoRetryForPost(t *testing.T) {
+	server, hits := faultyServer(1, http.StatusInternalServerError, nil)
+	defer server.Close()
+	res, err := newClient().Post(server.URL+"/tx/submit", "application/cbor", strings.NewReader("tx"))
+	err = checked(t, res, err)
+	var apiErr *HttpClient.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("expected a 500 APIError, got %v", err)
+	}
+	if *hits != 1 {
+		t.Errorf("a submission must not be retried after a server error, got %d attempts", *hits)
+	}
+
+	server, hits = faultyServer(1, http.StatusInternalServerError, nil)
+	defer server.Close()
+	client := newClient(func(c *HttpClient.Config) { c.IdempotentPaths = []string{"/evaluate"} })
+	res, err = client.Post(server.URL+"/utils/txs/evaluate", "application/cbor", strings.NewReader("tx"))
+	if err = checked(t, res, err); err != nil || *hits != 2 {
+		t.Errorf("expected the idempotent post to be retried, got %v after %d attempts", err, *hits)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	server, hits := faultyServer(1, http.StatusTooManyRequests, map[string]string{"Retry-After": "1"})
+	defer server.Close()
+	start := time.Now()
+	// rate limited requests are retried whatever their method
+	_, err := newClient(HttpClient.WithRetryPolicy(HttpClient.RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Second})).
+		Post(server.URL, "application/cbor", strings.NewReader("tx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("Retry-After was not respected, retried after %v", elapsed)
+	}
+	if *hits != 2 {
+		t.Errorf("expected 2 attempts, got %d", *hits)
+	}
+
+	date := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	server, _ = faultyServer(1, http.StatusTooManyRequests, map[string]string{"Retry-After": date})
+	defer server.Close()
+	res, err := newClient(HttpClient.WithRetryPolicy(HttpClient.RetryPolicy{})).Get(server.URL)
+	err = checked(t, res, err)
+	var apiErr *HttpClient.APIError
+	if !errors.Is(err, HttpClient.ErrRateLimited) || !errors.As(err, &apiErr) {
+		t.Fatalf("expected a rate limited error, got %v", err)
+	}
+	if apiErr.RetryAfter < 59*time.Minute || apiErr.RetryAfter > time.Hour {
+		t.Errorf("unexpected Retry-After %v", apiErr.RetryAfter)
+	}
+
+	// a delay past MaxDelay is reported instead of waited for
+	server, hits = faultyServer(1, http.StatusTooManyRequests, map[string]string{"Retry-After": "86400"})
+	defer server.Close()
+	start = time.Now()
+	res, err = newClient().Get(server.URL)
+	err = checked(t, res, err)
+	if !errors.As(err, &apiErr) || apiErr.RetryAfter != 24*time.Hour {
+		t.Fatalf("expected the Retry-After in the error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second || *hits != 1 {
+		t.Errorf("expected no retry, got %d attempts in %v", *hits, elapsed)
+	}
+}
+
+func TestRetryNetworkErrors(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) == 1 {
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	if _, err := newClient().Get(server.URL); err != nil || hits != 2 {
+		t.Errorf("expected the dropped connection to be retried, got %v after %d attempts", err, hits)
+	}
+}
+
+func TestDecodeError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html>maintenance</html>`))
+	}))
+	defer server.Close()
+	res, err := newClient().Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out map[string]any
+	err = HttpClient.DecodeJSON(res, &out)
+	var decodeErr *HttpClient.DecodeError
+	if !errors.As(err, &decodeErr) || decodeErr.Body != "<html>maintenance</html>" {
+		t.Errorf("expected a DecodeError, got %v", err)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	server, hits := faultyServer(0, http.StatusOK, nil)
+	defer server.Close()
+	client := newClient(HttpClient.WithRateLimit(20, 2))
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		if _, err := client.Get(server.URL); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 2 requests from the burst, then 4 at 20 per second
+	if elapsed := time.Since(start); elapsed < 180*time.Millisecond {
+		t.Errorf("rate limit not applied, 6 requests took %v", elapsed)
+	}
+	if *hits != 6 {
+		t.Errorf("expected 6 requests, got %d", *hits)
+	}
+	if HttpClient.NewRateLimiter(0, 10) != nil {
+		t.Error("a zero rate disables the limiter")
+	}
+}
+
+type countingTransport struct {
+	calls int32
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&c.calls, 1)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestCustomHTTPClient(t *testing.T) {
+	server, _ := faultyServer(1, http.StatusInternalServerError, nil)
+	defer server.Close()
+	transport := &countingTransport{}
+	custom := &http.Client{Transport: transport, Timeout: time.Second}
+	client := newClient(HttpClient.WithHTTPClient(custom))
+	if _, err := client.Get(server.URL); err != nil {
+		t.Fatal(err)
+	}
+	if transport.calls != 2 {
+		t.Errorf("expected the custom transport to be used for both attempts, got %d calls", transport.calls)
+	}
+	if custom.Transport != transport || client.Timeout != time.Second {
+		t.Error("the custom client must be copied, not modified")
+	}
+}
+
+func TestInstrumentation(t *testing.T) {
+	server, _ := faultyServer(1, http.StatusServiceUnavailable, nil)
+	defer server.Close()
+	recorder := &observability.Recorder{}
+	res, err := newClient(HttpClient.WithInstrumentation(recorder)).Get(server.URL + "/epochs/latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	spans := recorder.Named("http.request")
+	if len(spans) != 2 {
+		t.Fatalf("expected a span per attempt, got %d", len(spans))
+	}
+	for i, expected := range []int64{http.StatusServiceUnavailable, http.StatusOK} {
+		status, _ := spans[i].Attr("http.status_code")
+		attempt, _ := spans[i].Attr("http.attempt")
+		path, _ := spans[i].Attr("http.path")
+		if status.Int64() != expected || attempt.Int64() != int64(i) || path.String() != "/epochs/latest" {
+			t.Errorf("unexpected attempt %d: %+v", i, spans[i])
+		}
+	}
+	if spans[0].Err == nil || spans[1].Err != nil {
+		t.Error("expected only the failed attempt to report an error")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := HttpClient.RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt, max := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		max *= time.Millisecond
+		for i := 0; i < 20; i++ {
+			delay := policy.Backoff(attempt)
+			if delay < max/2 || delay > max {
+				t.Fatalf("attempt %d: delay %v outside [%v, %v]", attempt, delay, max/2, max)
+			}
+		}
+	}
+	if policy.Backoff(100) > time.Second {
+		t.Error("the delay must be capped")
+	}
+}
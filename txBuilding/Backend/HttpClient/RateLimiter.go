@@ -0,0 +1,111 @@
+package HttpClient
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+/*
+*
+
+	RateLimiter is a token bucket shared by every request of
+	a client: it holds up to burst tokens, refilled at rate
+	tokens per second, and each request takes one.
+*/
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+/*
+*
+
+	NewRateLimiter creates a full token bucket.
+
+	Params:
+		rate (float64): The number of requests allowed per second.
+		burst (int): The number of requests allowed at once.
+
+	Returns:
+		*RateLimiter: The rate limiter, nil if rate is not positive.
+*/
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// reserve takes a token, possibly in advance, and returns how
+// long the caller has to wait before using it.
+func (l *RateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+func (l *RateLimiter) cancel() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens++
+}
+
+/*
+*
+
+	Wait blocks until a request is allowed.
+	A nil RateLimiter never blocks.
+
+	Params:
+		ctx (context.Context): The context of the request.
+
+	Returns:
+		error: The error of the context if it is done first.
+*/
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return ctx.Err()
+	}
+	wait := l.reserve()
+	if wait <= 0 {
+		return nil
+	}
+	if err := sleep(ctx, wait); err != nil {
+		l.cancel()
+		return err
+	}
+	return nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
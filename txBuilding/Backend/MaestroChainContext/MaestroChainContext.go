@@ -2,22 +2,26 @@ package MaestroChainContext
 
 import (
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
-	"strconv"
-	"strings"
+	"net/http"
 	"time"
 
 	"github.com/Salvionied/apollo/serialization"
 	"github.com/Salvionied/apollo/serialization/Address"
+	"github.com/Salvionied/apollo/serialization/PlutusData"
+	"github.com/Salvionied/apollo/serialization/Rational"
 	"github.com/Salvionied/apollo/serialization/Redeemer"
 	"github.com/Salvionied/apollo/serialization/Transaction"
 	"github.com/Salvionied/apollo/serialization/TransactionInput"
 	"github.com/Salvionied/apollo/serialization/TransactionOutput"
 	"github.com/Salvionied/apollo/serialization/UTxO"
 	"github.com/Salvionied/apollo/txBuilding/Backend/Base"
+	"github.com/Salvionied/apollo/txBuilding/Backend/HttpClient"
 	"github.com/Salvionied/cbor/v2"
 	"github.com/maestro-org/go-sdk/client"
+	"github.com/maestro-org/go-sdk/models"
 	"github.com/maestro-org/go-sdk/utils"
 )
 
@@ -31,7 +35,27 @@ type MaestroChainContext struct {
 	latestUpdate    time.Time
 }
 
-func NewMaestroChainContext(network int, projectId string) (MaestroChainContext, error) {
+// The evaluation endpoint is a POST but has no side effect.
+var MAESTRO_IDEMPOTENT_PATHS = []string{"/transactions/evaluate"}
+
+/*
+*
+
+	NewMaestroChainContext creates a Maestro context and fetches
+	the current epoch and protocol parameters. Requests are
+	retried following HttpClient.DEFAULT_RETRY_POLICY, the
+	client side rate limit is disabled unless set by the options.
+
+	Params:
+		network (int): The network.
+		projectId (string): The Maestro api key.
+		opts (...HttpClient.Option): The http client options.
+
+	Returns:
+		MaestroChainContext: The context.
+		error: An error if the network is invalid or the initial requests fail.
+*/
+func NewMaestroChainContext(network int, projectId string, opts ...HttpClient.Option) (MaestroChainContext, error) {
 	networkString := "mainnet"
 	if network == 0 {
 		networkString = "mainnet"
@@ -45,26 +69,67 @@ func NewMaestroChainContext(network int, projectId string) (MaestroChainContext,
 		return MaestroChainContext{}, fmt.Errorf("Invalid network")
 	}
 	maestroClient := client.NewClient(projectId, networkString)
+	config := HttpClient.NewConfig(HttpClient.Config{
+		Client:          maestroClient.HTTPClient,
+		Retry:           HttpClient.DEFAULT_RETRY_POLICY,
+		IdempotentPaths: MAESTRO_IDEMPOTENT_PATHS,
+		BaseUrl:         maestroClient.BaseUrl,
+	}, opts...)
+	maestroClient.HTTPClient = config.HTTPClient()
+	maestroClient.HTTPClient.Transport = statusTransport{maestroClient.HTTPClient.Transport}
+	maestroClient.BaseUrl = config.BaseUrl
 	mcc := MaestroChainContext{
 		client: maestroClient, _Network: network,
 	}
-	mcc.Init()
-	return mcc, nil
+	err := mcc.Init()
+	return mcc, err
+}
+
+// statusTransport turns non 2xx responses into an
+// *HttpClient.APIError, since the Maestro client only keeps
+// their message.
+type statusTransport struct {
+	base http.RoundTripper
+}
+
+func (t statusTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := HttpClient.CheckResponse(res); err != nil {
+		return nil, err
+	}
+	return res, nil
 }
-func (mcc *MaestroChainContext) Init() {
-	latest_epochs := mcc.LatestEpoch()
+
+func (mcc *MaestroChainContext) Init() error {
+	latest_epochs, err := mcc.LatestEpoch()
+	if err != nil {
+		return err
+	}
 	mcc._epoch_info = latest_epochs
-	params := mcc.GenesisParams()
+	params, err := mcc.GenesisParams()
+	if err != nil {
+		return err
+	}
 	mcc._genesis_param = params
-	latest_params := mcc.LatestEpochParams()
+	latest_params, err := mcc.LatestEpochParams()
+	if err != nil {
+		return err
+	}
 	mcc._protocol_param = latest_params
+	return nil
 }
 
-func (mcc *MaestroChainContext) LatestBlock() Base.Block {
+func (mcc *MaestroChainContext) LatestBlock() (Base.Block, error) {
 	latestBlock := Base.Block{}
-	latestBlockFromApi, _ := mcc.client.LatestBlock()
+	latestBlockFromApi, err := mcc.client.LatestBlock()
+	if err != nil {
+		return latestBlock, err
+	}
 	if latestBlockFromApi == nil {
-		return latestBlock
+		return latestBlock, nil
 	} else {
 		tmpTime, _ := time.Parse("2006-01-02 15:04:05", latestBlockFromApi.Data.Timestamp)
 		latestBlock.Time = int(tmpTime.Unix())
@@ -83,14 +148,14 @@ func (mcc *MaestroChainContext) LatestBlock() Base.Block {
 		latestBlock.NextBlock = latestBlockFromApi.Data.Hash
 		latestBlock.Confirmations = int(latestBlockFromApi.Data.Confirmations)
 	}
-	return latestBlock
+	return latestBlock, nil
 }
 
-func (mcc *MaestroChainContext) LatestEpoch() Base.Epoch {
+func (mcc *MaestroChainContext) LatestEpoch() (Base.Epoch, error) {
 	epoch := Base.Epoch{}
 	latestEpoch, err := mcc.client.CurrentEpoch()
 	if err != nil {
-		return epoch
+		return epoch, err
 	}
 	epoch.ActiveStake = ""
 	epoch.BlockCount = int(latestEpoch.Data.BlkCount)
@@ -99,27 +164,23 @@ func (mcc *MaestroChainContext) LatestEpoch() Base.Epoch {
 	epoch.FirstBlockTime = int(latestEpoch.Data.StartTime)
 	epoch.StartTime = int(latestEpoch.Data.StartTime)
 	epoch.TxCount = int(latestEpoch.Data.TxCount)
-	return epoch
+	return epoch, nil
 
 }
 
-func parseMaestroFloat(floatString string) float32 {
-	if floatString == "" {
-		return 0
+func parseMaestroRational(value string) Rational.Rational {
+	parsed, err := Rational.Parse(value)
+	if err != nil {
+		return Rational.Rational{}
 	}
-	splitString := strings.Split(floatString, "/")
-	top := splitString[0]
-	bottom := splitString[1]
-	topFloat, _ := strconv.ParseFloat(top, 32)
-	bottomFloat, _ := strconv.ParseFloat(bottom, 32)
-	return float32(topFloat / bottomFloat)
+	return parsed
 }
 
-func (mcc *MaestroChainContext) LatestEpochParams() Base.ProtocolParameters {
+func (mcc *MaestroChainContext) LatestEpochParams() (Base.ProtocolParameters, error) {
 	protocolParams := Base.ProtocolParameters{}
 	ppFromApi, err := mcc.client.ProtocolParameters()
 	if err != nil {
-		log.Fatal(err)
+		return protocolParams, err
 	}
 	// Map ALL the fields
 	protocolParams.MinFeeConstant = int(ppFromApi.Data.MinFeeConstant.LovelaceAmount.Lovelace)
@@ -127,40 +188,59 @@ func (mcc *MaestroChainContext) LatestEpochParams() Base.ProtocolParameters {
 	protocolParams.MaxTxSize = int(ppFromApi.Data.MaxTransactionSize.Bytes)
 	protocolParams.MaxBlockSize = int(ppFromApi.Data.MaxBlockBodySize.Bytes)
 	protocolParams.MaxBlockHeaderSize = int(ppFromApi.Data.MaxBlockHeaderSize.Bytes)
-	protocolParams.KeyDeposits = fmt.Sprint(ppFromApi.Data.StakeCredentialDeposit.LovelaceAmount.Lovelace)
-	protocolParams.PoolDeposits = fmt.Sprint(ppFromApi.Data.StakePoolDeposit.LovelaceAmount.Lovelace)
-	parsedPoolInfl, _ := strconv.ParseFloat(ppFromApi.Data.StakePoolPledgeInfluence, 32)
-	protocolParams.PooolInfluence = float32(parsedPoolInfl)
-	monExp, _ := strconv.ParseFloat(ppFromApi.Data.MonetaryExpansion, 32)
-	protocolParams.MonetaryExpansion = float32(monExp)
-	tresExp, _ := strconv.ParseFloat(ppFromApi.Data.TreasuryExpansion, 32)
-	protocolParams.TreasuryExpansion = float32(tresExp)
-	protocolParams.DecentralizationParam = 0
+	protocolParams.KeyDeposits = ppFromApi.Data.StakeCredentialDeposit.LovelaceAmount.Lovelace
+	protocolParams.PoolDeposits = ppFromApi.Data.StakePoolDeposit.LovelaceAmount.Lovelace
+	protocolParams.PooolInfluence = parseMaestroRational(ppFromApi.Data.StakePoolPledgeInfluence)
+	protocolParams.MonetaryExpansion = parseMaestroRational(ppFromApi.Data.MonetaryExpansion)
+	protocolParams.TreasuryExpansion = parseMaestroRational(ppFromApi.Data.TreasuryExpansion)
+	protocolParams.DecentralizationParam = Rational.Rational{}
 	protocolParams.ExtraEntropy = ""
 	protocolParams.ProtocolMajorVersion = int(ppFromApi.Data.ProtocolVersion.Major)
 	protocolParams.ProtocolMinorVersion = int(ppFromApi.Data.ProtocolVersion.Minor)
 	//CHECK HERE
 	//protocolParams.MinUtxo = ppFromApi.Data.
-	protocolParams.MinPoolCost = fmt.Sprint(ppFromApi.Data.MinStakePoolCost.LovelaceAmount.Lovelace)
-	protocolParams.PriceMem = parseMaestroFloat(ppFromApi.Data.ScriptExecutionPrices.Memory)
-	protocolParams.PriceStep = parseMaestroFloat(ppFromApi.Data.ScriptExecutionPrices.Steps)
-	protocolParams.MaxTxExMem = fmt.Sprint(ppFromApi.Data.MaxExecutionUnitsPerTransaction.Memory)
-	protocolParams.MaxTxExSteps = fmt.Sprint(ppFromApi.Data.MaxExecutionUnitsPerTransaction.Steps)
-	protocolParams.MaxBlockExMem = fmt.Sprint(ppFromApi.Data.MaxExecutionUnitsPerBlock.Memory)
-	protocolParams.MaxBlockExSteps = fmt.Sprint(ppFromApi.Data.MaxExecutionUnitsPerBlock.Steps)
-	protocolParams.MaxValSize = fmt.Sprint(ppFromApi.Data.MaxValueSize.Bytes)
+	protocolParams.MinPoolCost = ppFromApi.Data.MinStakePoolCost.LovelaceAmount.Lovelace
+	protocolParams.PriceMem = parseMaestroRational(ppFromApi.Data.ScriptExecutionPrices.Memory)
+	protocolParams.PriceStep = parseMaestroRational(ppFromApi.Data.ScriptExecutionPrices.Steps)
+	protocolParams.MaxTxExMem = ppFromApi.Data.MaxExecutionUnitsPerTransaction.Memory
+	protocolParams.MaxTxExSteps = ppFromApi.Data.MaxExecutionUnitsPerTransaction.Steps
+	protocolParams.MaxBlockExMem = ppFromApi.Data.MaxExecutionUnitsPerBlock.Memory
+	protocolParams.MaxBlockExSteps = ppFromApi.Data.MaxExecutionUnitsPerBlock.Steps
+	protocolParams.MaxValSize = int(ppFromApi.Data.MaxValueSize.Bytes)
 	protocolParams.CollateralPercent = int(ppFromApi.Data.CollateralPercentage)
 	protocolParams.MaxCollateralInuts = int(ppFromApi.Data.MaxCollateralInputs)
-	protocolParams.CoinsPerUtxoByte = fmt.Sprint(ppFromApi.Data.MinUtxoDepositCoefficient)
-	protocolParams.CoinsPerUtxoWord = "0"
-	//protocolParams.CostModels = ppFromApi.Data.CostModels
-	return protocolParams
+	protocolParams.CoinsPerUtxoByte = ppFromApi.Data.MinUtxoDepositCoefficient
+	protocolParams.CoinsPerUtxoWord = 0
+	// The sdk does not expose min_fee_reference_scripts yet, use the
+	// value shared by every public network since the Chang hard fork.
+	protocolParams.MinFeeRefScriptCostPerByte = Rational.New(15, 1)
+	protocolParams.NOpt = ppFromApi.Data.DesiredNumberOfStakePools
+	protocolParams.CostModels = maestroCostModels(ppFromApi.Data.PlutusCostModels)
+	// The Conway governance parameters are not exposed by the sdk yet.
+	return protocolParams, nil
+}
+
+func maestroCostModels(costModels any) map[string][]int64 {
+	encoded, err := json.Marshal(costModels)
+	if err != nil {
+		return nil
+	}
+	var raw map[string]json.RawMessage
+	err = json.Unmarshal(encoded, &raw)
+	if err != nil {
+		return nil
+	}
+	res, err := Base.ParseCostModels(raw)
+	if err != nil {
+		return nil
+	}
+	return res
 }
 
-func (mcc *MaestroChainContext) GenesisParams() Base.GenesisParameters {
+func (mcc *MaestroChainContext) GenesisParams() (Base.GenesisParameters, error) {
 	genesisParams := Base.GenesisParameters{}
 	// NO GENESIS PARAMS IN MAESTRO
-	return genesisParams
+	return genesisParams, nil
 }
 
 func (mcc *MaestroChainContext) Network() int {
@@ -169,44 +249,48 @@ func (mcc *MaestroChainContext) Network() int {
 
 func (mcc *MaestroChainContext) Epoch() int {
 	if time.Since(mcc.latestUpdate) > time.Minute*5 {
-		new_epoch := mcc.LatestEpoch()
-		mcc._epoch = new_epoch.Epoch
+		new_epoch, err := mcc.LatestEpoch()
+		if err == nil {
+			mcc._epoch = new_epoch.Epoch
+		}
 	}
 	return mcc._epoch
 }
 
 func (mcc *MaestroChainContext) LastBlockSlot() int {
-	block := mcc.LatestBlock()
+	block, _ := mcc.LatestBlock()
 	return block.Slot
 }
 
 func (mcc *MaestroChainContext) GetGenesisParams() Base.GenesisParameters {
 	if time.Since(mcc.latestUpdate) > time.Minute*5 {
-		params := mcc.GenesisParams()
-		mcc._genesis_param = params
+		params, err := mcc.GenesisParams()
+		if err == nil {
+			mcc._genesis_param = params
+		}
 	}
 	return mcc._genesis_param
 }
 
 func (mcc *MaestroChainContext) GetProtocolParams() Base.ProtocolParameters {
 	if time.Since(mcc.latestUpdate) > time.Minute*5 {
-		latest_params := mcc.LatestEpochParams()
-		mcc._protocol_param = latest_params
-		mcc.latestUpdate = time.Now()
+		latest_params, err := mcc.LatestEpochParams()
+		if err == nil {
+			mcc._protocol_param = latest_params
+			mcc.latestUpdate = time.Now()
+		}
 	}
 	return mcc._protocol_param
 }
 
 func (mcc *MaestroChainContext) MaxTxFee() int {
 	protocol_param := mcc.GetProtocolParams()
-	maxTxExSteps, _ := strconv.Atoi(protocol_param.MaxTxExSteps)
-	maxTxExMem, _ := strconv.Atoi(protocol_param.MaxTxExMem)
-	return Base.Fee(mcc, protocol_param.MaxTxSize, maxTxExSteps, maxTxExMem)
+	return Base.Fee(mcc, protocol_param.MaxTxSize, int(protocol_param.MaxTxExSteps), int(protocol_param.MaxTxExMem))
 }
-func (mcc *MaestroChainContext) TxOuts(txHash string) []Base.Output {
+func (mcc *MaestroChainContext) TxOuts(txHash string) ([]Base.Output, error) {
 	tx, err := mcc.client.TransactionDetails(txHash)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	outputs := make([]Base.Output, 0)
 	for idx, txOut := range tx.Data.Outputs {
@@ -225,7 +309,7 @@ func (mcc *MaestroChainContext) TxOuts(txHash string) []Base.Output {
 		}
 		outputs = append(outputs, output)
 	}
-	return outputs
+	return outputs, nil
 }
 func (mcc *MaestroChainContext) GetUtxoFromRef(txHash string, index int) *UTxO.UTxO {
 	var utxo *UTxO.UTxO
@@ -239,7 +323,6 @@ func (mcc *MaestroChainContext) GetUtxoFromRef(txHash string, index int) *UTxO.U
 	output := TransactionOutput.TransactionOutput{}
 	err = cbor.Unmarshal(decodedCbor, &output)
 	if err != nil {
-		log.Fatal(err)
 		return nil
 	}
 	decodedHash, _ := hex.DecodeString(txHash)
@@ -252,13 +335,13 @@ func (mcc *MaestroChainContext) GetUtxoFromRef(txHash string, index int) *UTxO.U
 	}
 	return utxo
 }
-func (mcc *MaestroChainContext) AddressUtxos(address string, gather bool) []Base.AddressUTXO {
+func (mcc *MaestroChainContext) AddressUtxos(address string, gather bool) ([]Base.AddressUTXO, error) {
 	addressUtxos := make([]Base.AddressUTXO, 0)
 	params := utils.NewParameters()
 	params.ResolveDatums()
 	utxosAtAddressAtApi, err := mcc.client.UtxosAtAddress(address, params)
 	if err != nil {
-		return addressUtxos
+		return nil, err
 	}
 
 	for _, maestroUtxo := range utxosAtAddressAtApi.Data {
@@ -283,7 +366,7 @@ func (mcc *MaestroChainContext) AddressUtxos(address string, gather bool) []Base
 			params.Cursor(utxosAtAddressAtApi.NextCursor)
 			utxosAtAddressAtApi, err = mcc.client.UtxosAtAddress(address, params)
 			if err != nil {
-				return addressUtxos
+				return nil, err
 			}
 			for _, maestroUtxo := range utxosAtAddressAtApi.Data {
 				assets := make([]Base.AddressAmount, 0)
@@ -304,17 +387,52 @@ func (mcc *MaestroChainContext) AddressUtxos(address string, gather bool) []Base
 		}
 	}
 
-	return addressUtxos
+	return addressUtxos, nil
 
 }
+
+/*
+*
+
+	Utxos fetches the UTxOs of an address, dropping the errors
+	of the requests: use FetchUtxos to tell a failure from an
+	empty address.
+
+	Params:
+		address (Address.Address): The address.
+
+	Returns:
+		[]UTxO.UTxO: The UTxOs of the address, empty on failure.
+*/
 func (mcc *MaestroChainContext) Utxos(address Address.Address) []UTxO.UTxO {
+	utxos, err := mcc.FetchUtxos(address)
+	if err != nil {
+		return make([]UTxO.UTxO, 0)
+	}
+	return utxos
+}
+
+/*
+*
+
+	FetchUtxos fetches the UTxOs of an address, as Utxos
+	but returning the errors instead of an empty list.
+
+	Params:
+		address (Address.Address): The address.
+
+	Returns:
+		[]UTxO.UTxO: The UTxOs of the address.
+		error: The error of the requests or of the decoding of an output.
+*/
+func (mcc *MaestroChainContext) FetchUtxos(address Address.Address) ([]UTxO.UTxO, error) {
 	utxos := make([]UTxO.UTxO, 0)
 	params := utils.NewParameters()
 	params.WithCbor()
 	params.ResolveDatums()
 	utxosAtAddressAtApi, err := mcc.client.UtxosAtAddress(address.String(), params)
 	if err != nil {
-		return utxos
+		return nil, err
 	}
 
 	for _, maestroUtxo := range utxosAtAddressAtApi.Data {
@@ -328,8 +446,7 @@ func (mcc *MaestroChainContext) Utxos(address Address.Address) []UTxO.UTxO {
 		decodedCbor, _ := hex.DecodeString(maestroUtxo.TxOutCbor)
 		err = cbor.Unmarshal(decodedCbor, &output)
 		if err != nil {
-			log.Fatal(err)
-			return nil
+			return nil, fmt.Errorf("decoding the output %s#%d: %w", maestroUtxo.TxHash, maestroUtxo.Index, err)
 		}
 		utxo.Output = output
 		utxos = append(utxos, utxo)
@@ -339,7 +456,7 @@ func (mcc *MaestroChainContext) Utxos(address Address.Address) []UTxO.UTxO {
 		params.Cursor(utxosAtAddressAtApi.NextCursor)
 		utxosAtAddressAtApi, err = mcc.client.UtxosAtAddress(address.String(), params)
 		if err != nil {
-			return utxos
+			return nil, err
 		}
 		for _, maestroUtxo := range utxosAtAddressAtApi.Data {
 			utxo := UTxO.UTxO{}
@@ -352,15 +469,14 @@ func (mcc *MaestroChainContext) Utxos(address Address.Address) []UTxO.UTxO {
 			decodedCbor, _ := hex.DecodeString(maestroUtxo.TxOutCbor)
 			err = cbor.Unmarshal(decodedCbor, &output)
 			if err != nil {
-				log.Fatal(err)
-				return nil
+				return nil, fmt.Errorf("decoding the output %s#%d: %w", maestroUtxo.TxHash, maestroUtxo.Index, err)
 			}
 			utxo.Output = output
 			utxos = append(utxos, utxo)
 		}
 	}
 
-	return utxos
+	return utxos, nil
 }
 
 func (mcc *MaestroChainContext) SubmitTx(tx Transaction.Transaction) (serialization.TransactionId, error) {
@@ -371,8 +487,7 @@ func (mcc *MaestroChainContext) SubmitTx(tx Transaction.Transaction) (serializat
 	txHex := hex.EncodeToString(txBytes)
 	resp, err := mcc.client.SubmitTx(txHex)
 	if err != nil {
-		log.Fatal(err)
-		return serialization.TransactionId{}, err
+		return serialization.TransactionId{}, fmt.Errorf("error submitting tx: %w", err)
 	}
 	decodedResponseHash, _ := hex.DecodeString(resp.Data)
 	return serialization.TransactionId{Payload: []byte(decodedResponseHash)}, nil
@@ -387,11 +502,32 @@ type ExecutionResult struct {
 }
 
 func (mcc *MaestroChainContext) EvaluateTx(tx []byte) map[string]Redeemer.ExecutionUnits {
+	final_result, err := mcc.FetchEvaluation(tx)
+	if err != nil {
+		return make(map[string]Redeemer.ExecutionUnits)
+	}
+	return final_result
+}
+
+/*
+*
+
+	FetchEvaluation evaluates the scripts of a transaction, as
+	EvaluateTx but returning the error instead of an empty map.
+
+	Params:
+		tx ([]byte): The cbor of the transaction.
+
+	Returns:
+		map[string]Redeemer.ExecutionUnits: The execution units by redeemer.
+		error: The error of the request.
+*/
+func (mcc *MaestroChainContext) FetchEvaluation(tx []byte) (map[string]Redeemer.ExecutionUnits, error) {
 	final_result := make(map[string]Redeemer.ExecutionUnits)
 	encodedTx := hex.EncodeToString(tx)
 	evaluation, err := mcc.client.EvaluateTx(encodedTx)
 	if err != nil {
-		return final_result
+		return nil, err
 	}
 	for _, eval := range evaluation {
 		final_result[eval.RedeemerTag+":"+fmt.Sprint(eval.RedeemerIndex)] = Redeemer.ExecutionUnits{
@@ -399,18 +535,112 @@ func (mcc *MaestroChainContext) EvaluateTx(tx []byte) map[string]Redeemer.Execut
 			Steps: eval.ExUnits.Steps,
 		}
 	}
-	return final_result
+	return final_result, nil
 }
 
 func (mcc *MaestroChainContext) GetContractCbor(scriptHash string) string {
+	scriptCbor, _ := mcc.FetchContractCbor(scriptHash)
+	return scriptCbor
+}
+
+/*
+*
+
+	FetchContractCbor fetches the cbor of a script, as
+	GetContractCbor but returning the error instead of an
+	empty string.
+
+	Params:
+		scriptHash (string): The hash of the script.
+
+	Returns:
+		string: The hex encoded cbor of the script.
+		error: The error of the request.
+*/
+func (mcc *MaestroChainContext) FetchContractCbor(scriptHash string) (string, error) {
 	res, err := mcc.client.ScriptByHash(scriptHash)
 	if err != nil {
-		return ""
+		return "", err
 	}
 	scCborBytes := res.Data.Bytes
 	bytes := []byte{}
 	decodedBytes, _ := hex.DecodeString(scCborBytes)
 	_ = cbor.Unmarshal(decodedBytes, &bytes)
-	return hex.EncodeToString(bytes)
+	return hex.EncodeToString(bytes), nil
+
+}
+
+/*
+*
 
+	ResolveDatum fetches a datum by its hash.
+
+	Params:
+		datumHash (string): The hex encoded hash of the datum.
+
+	Returns:
+		*PlutusData.PlutusData: The datum.
+		error: The error of the request, matching Base.ErrDatumNotFound
+			if Maestro does not know the datum.
+*/
+func (mcc *MaestroChainContext) ResolveDatum(datumHash string) (*PlutusData.PlutusData, error) {
+	res, err := mcc.client.DatumFromHash(datumHash)
+	if errors.Is(err, HttpClient.ErrNotFound) {
+		return nil, fmt.Errorf("%w: %s: %w", Base.ErrDatumNotFound, datumHash, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Base.DecodeDatum(res.Data.Bytes)
+}
+
+var MAESTRO_SCRIPT_TYPES = map[models.ScriptVersion]uint64{
+	"native":        0,
+	models.PlutusV1: 1,
+	models.PlutusV2: 2,
+	models.PlutusV3: 3,
+}
+
+/*
+*
+
+	GetScript fetches a script by its hash together with its
+	language.
+
+	Params:
+		scriptHash (string): The hex encoded hash of the script.
+
+	Returns:
+		*PlutusData.ScriptRef: The script.
+		error: The error of the request, matching Base.ErrScriptNotFound
+			if Maestro does not know the script.
+*/
+func (mcc *MaestroChainContext) GetScript(scriptHash string) (*PlutusData.ScriptRef, error) {
+	res, err := mcc.client.ScriptByHash(scriptHash)
+	if errors.Is(err, HttpClient.ErrNotFound) {
+		return nil, fmt.Errorf("%w: %s: %w", Base.ErrScriptNotFound, scriptHash, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	scriptType, ok := MAESTRO_SCRIPT_TYPES[res.Data.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown script type %q", res.Data.Type)
+	}
+	decoded, err := hex.DecodeString(res.Data.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid script hex: %w", err)
+	}
+	if scriptType != 0 {
+		// Plutus scripts are served wrapped in a byte string.
+		var unwrapped []byte
+		if cbor.Unmarshal(decoded, &unwrapped) == nil {
+			decoded = unwrapped
+		}
+	}
+	scriptRef := PlutusData.NewScriptRef(scriptType, decoded)
+	if err := Base.CheckScriptHash(&scriptRef, scriptHash); err != nil {
+		return nil, err
+	}
+	return &scriptRef, nil
 }
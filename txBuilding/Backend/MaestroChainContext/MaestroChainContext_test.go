@@ -1,5 +1,22 @@
 package MaestroChainContext_test
 
+import (
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Salvionied/apollo/serialization/Address"
+	"github.com/Salvionied/apollo/serialization/PlutusData"
+	"github.com/Salvionied/apollo/txBuilding/Backend/Base"
+	"github.com/Salvionied/apollo/txBuilding/Backend/HttpClient"
+	"github.com/Salvionied/apollo/txBuilding/Backend/MaestroChainContext"
+)
+
 // func TestNewContext(t *testing.T) {
 // 	mcc, _ := MaestroChainContext.NewMaestroChainContext(
 // 		0, APIKEY,
@@ -100,3 +117,130 @@ package MaestroChainContext_test
 // 	t.Error(newDt)
 
 // }
+
+const testAddress = "addr1qy99jvml0vafzdpy6lm6z52qrczjvs4k362gmr9v4hrrwgqk4xvegxwvtfsu5ck6s83h346nsgf6xu26dwzce9yvd8ysd2seyu"
+
+var fastRetry = HttpClient.WithRetryPolicy(HttpClient.RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond})
+
+// fakeMaestro serves the given routes, failing each route with
+// the statuses in faults before answering.
+type fakeMaestro struct {
+	mu     sync.Mutex
+	routes map[string]string
+	faults map[string][]int
+	hits   map[string]int
+}
+
+func newFakeMaestro() *fakeMaestro {
+	return &fakeMaestro{
+		routes: map[string]string{
+			"/epochs/current":      `{"data":{"epoch_no":500,"start_time":1700000000},"last_updated":{}}`,
+			"/protocol-parameters": `{"data":{"min_fee_coefficient":44,"collateral_percentage":150},"last_updated":{}}`,
+		},
+		faults: map[string][]int{},
+		hits:   map[string]int{},
+	}
+}
+
+func (f *fakeMaestro) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.hits[r.URL.Path]++
+	if faults := f.faults[r.URL.Path]; len(faults) > 0 {
+		f.faults[r.URL.Path] = faults[1:]
+		w.WriteHeader(faults[0])
+		w.Write([]byte(`{"code":` + strconv.Itoa(faults[0]) + `,"message":"injected"}`))
+		return
+	}
+	body, ok := f.routes[r.URL.Path]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Write([]byte(body))
+}
+
+func (f *fakeMaestro) hitsOf(path string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.hits[path]
+}
+
+func TestMaestroRetries(t *testing.T) {
+	fake := newFakeMaestro()
+	fake.faults["/protocol-parameters"] = []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	mcc, err := MaestroChainContext.NewMaestroChainContext(0, "key", HttpClient.WithBaseUrl(server.URL), fastRetry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fake.hitsOf("/protocol-parameters") != 3 {
+		t.Errorf("expected 3 attempts, got %d", fake.hitsOf("/protocol-parameters"))
+	}
+	params := mcc.GetProtocolParams()
+	if params.MinFeeCoefficient != 44 || params.CollateralPercent != 150 {
+		t.Errorf("unexpected protocol parameters %+v", params)
+	}
+
+	path := "/addresses/" + testAddress + "/utxos"
+	fake.routes[path] = `{"data":[],"last_updated":{},"next_cursor":""}`
+	fake.faults[path] = []int{http.StatusBadGateway}
+	addr, _ := Address.DecodeAddress(testAddress)
+	utxos, err := mcc.FetchUtxos(addr)
+	if err != nil || len(utxos) != 0 || fake.hitsOf(path) != 2 {
+		t.Errorf("expected the request to be retried, got %v %v after %d attempts", utxos, err, fake.hitsOf(path))
+	}
+}
+
+func TestMaestroErrors(t *testing.T) {
+	fake := newFakeMaestro()
+	fake.faults["/protocol-parameters"] = []int{http.StatusUnauthorized}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	_, err := MaestroChainContext.NewMaestroChainContext(0, "key", HttpClient.WithBaseUrl(server.URL), fastRetry)
+	var apiErr *HttpClient.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected a 401 APIError, got %v", err)
+	}
+
+	mcc, err := MaestroChainContext.NewMaestroChainContext(0, "key", HttpClient.WithBaseUrl(server.URL), fastRetry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := "/addresses/" + testAddress + "/utxos"
+	fake.faults[path] = []int{500, 500, 500, 500}
+	addr, _ := Address.DecodeAddress(testAddress)
+	if _, err := mcc.FetchUtxos(addr); !errors.As(err, &apiErr) || apiErr.StatusCode != 500 {
+		t.Errorf("expected a 500 APIError, got %v", err)
+	}
+	if utxos := mcc.Utxos(addr); len(utxos) != 0 {
+		t.Errorf("expected no UTxO on error, got %v", utxos)
+	}
+	if _, err := mcc.FetchContractCbor("00"); !errors.Is(err, HttpClient.ErrNotFound) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
+
+func TestMaestroResolveDatum(t *testing.T) {
+	fake := newFakeMaestro()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	mcc, err := MaestroChainContext.NewMaestroChainContext(0, "key", HttpClient.WithBaseUrl(server.URL), fastRetry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := "923918e403bf43c34b4ef6b48eb2ee04babed17320d8d1b9ff9ad086e86f44ec"
+	fake.routes["/data/"+hash] = `{"data":{"bytes":"d87980","json":{"constructor":0,"fields":[]}},"last_updated":{}}`
+	var _ Base.DatumResolver = &mcc
+	datum, err := mcc.ResolveDatum(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved, _ := PlutusData.PlutusDataHash(datum); hex.EncodeToString(resolved.Payload) != hash {
+		t.Errorf("unexpected datum %v", datum)
+	}
+	if _, err := mcc.ResolveDatum("00"); !errors.Is(err, Base.ErrDatumNotFound) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
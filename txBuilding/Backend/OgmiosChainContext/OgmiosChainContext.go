@@ -18,6 +18,7 @@ import (
 	"github.com/Salvionied/apollo/serialization/MultiAsset"
 	"github.com/Salvionied/apollo/serialization/PlutusData"
 	"github.com/Salvionied/apollo/serialization/Policy"
+	"github.com/Salvionied/apollo/serialization/Rational"
 	"github.com/Salvionied/apollo/serialization/Redeemer"
 	"github.com/Salvionied/apollo/serialization/Transaction"
 	"github.com/Salvionied/apollo/serialization/TransactionInput"
@@ -133,13 +134,32 @@ func scriptRef_OgmigoToApollo(script json.RawMessage) (*PlutusData.ScriptRef, er
 	if len(script) == 0 {
 		return nil, nil
 	}
-	var ref PlutusData.ScriptRef
-	if err := json.Unmarshal(script, &ref); err != nil {
+	var ogmiosScript struct {
+		Language string `json:"language"`
+		Cbor     string `json:"cbor"`
+	}
+	if err := json.Unmarshal(script, &ogmiosScript); err != nil {
 		return nil, err
 	}
+	scriptType, ok := OGMIOS_SCRIPT_LANGUAGES[ogmiosScript.Language]
+	if !ok {
+		return nil, fmt.Errorf("unknown script language %s", ogmiosScript.Language)
+	}
+	decoded, err := hex.DecodeString(ogmiosScript.Cbor)
+	if err != nil {
+		return nil, err
+	}
+	ref := PlutusData.NewScriptRef(scriptType, decoded)
 	return &ref, nil
 }
 
+var OGMIOS_SCRIPT_LANGUAGES = map[string]uint64{
+	"native":    0,
+	"plutus:v1": 1,
+	"plutus:v2": 2,
+	"plutus:v3": 3,
+}
+
 func Utxo_OgmigoToApollo(u statequery.Utxo) UTxO.UTxO {
 	txHashRaw, err := hex.DecodeString(u.Transaction.ID)
 	if err != nil {
@@ -331,8 +351,8 @@ type Bytes struct {
 }
 
 type Prices struct {
-	Memory float64 `json:"memory"`
-	Cpu    float64 `json:"cpu"`
+	Memory Rational.Rational `json:"memory"`
+	Cpu    Rational.Rational `json:"cpu"`
 }
 
 func parseFraction(s string) (int64, int64, error) {
@@ -364,12 +384,12 @@ func (p *Prices) UnmarshalJSON(b []byte) error {
 	if err != nil {
 		return err
 	}
-	p.Memory = float64(mn) / float64(md)
+	p.Memory = Rational.New(uint64(mn), uint64(md))
 	cn, cd, err := parseFraction(x.Cpu)
 	if err != nil {
 		return err
 	}
-	p.Cpu = float64(cn) / float64(cd)
+	p.Cpu = Rational.New(uint64(cn), uint64(cd))
 	return nil
 }
 
@@ -384,44 +404,82 @@ type ExUnits struct {
 	Memory uint64 `json:"memory"`
 }
 
+type MinFeeReferenceScripts struct {
+	Range      uint64            `json:"range"`
+	Base       Rational.Rational `json:"base"`
+	Multiplier Rational.Rational `json:"multiplier"`
+}
+
+type CommitteeThresholds struct {
+	Default             Rational.Rational `json:"default"`
+	StateOfNoConfidence Rational.Rational `json:"stateOfNoConfidence"`
+}
+
+type StakePoolVotingThresholds struct {
+	NoConfidence             Rational.Rational   `json:"noConfidence"`
+	ConstitutionalCommittee  CommitteeThresholds `json:"constitutionalCommittee"`
+	HardForkInitiation       Rational.Rational   `json:"hardForkInitiation"`
+	ProtocolParametersUpdate struct {
+		Security Rational.Rational `json:"security"`
+	} `json:"protocolParametersUpdate"`
+}
+
+type DRepVotingThresholds struct {
+	NoConfidence             Rational.Rational   `json:"noConfidence"`
+	ConstitutionalCommittee  CommitteeThresholds `json:"constitutionalCommittee"`
+	Constitution             Rational.Rational   `json:"constitution"`
+	HardForkInitiation       Rational.Rational   `json:"hardForkInitiation"`
+	ProtocolParametersUpdate struct {
+		Network    Rational.Rational `json:"network"`
+		Economic   Rational.Rational `json:"economic"`
+		Technical  Rational.Rational `json:"technical"`
+		Governance Rational.Rational `json:"governance"`
+	} `json:"protocolParametersUpdate"`
+	TreasuryWithdrawals Rational.Rational `json:"treasuryWithdrawals"`
+}
+
 type OgmiosProtocolParameters struct {
-	MinFeeConstant                  Lovelace `json:"minFeeConstant"`
-	MinFeeCoefficient               uint64   `json:"minFeeCoefficient"`
-	MaxBlockSize                    Bytes    `json:"maxBlockBodySize"`
-	MaxTxSize                       Bytes    `json:"maxTransactionSize"`
-	MaxBlockHeaderSize              Bytes    `json:"maxBlockHeaderSize"`
-	KeyDeposits                     Lovelace `json:"stakeCredentialDeposit"`
-	PoolDeposits                    Lovelace `json:"stakePoolDeposit"`
-	PoolInfluence                   string   `json:"stakePoolPledgeInfluence"`
-	MonetaryExpansion               string   `json:"monetaryExpansion"`
-	TreasuryExpansion               string   `json:"treasuryExpansion"`
-	ExtraEntropy                    string   `json:"extraEntropy"`
-	MaxValSize                      Bytes    `json:"maxValueSize"`
-	ScriptExecutionPrices           Prices   `json:"scriptExecutionPrices"`
-	MinUtxoDepositCoefficient       uint64   `json:"minUtxoDepositCoefficient"`
-	MinUtxoDepositConstant          uint64   `json:"minUtxoDepositConstant"`
-	MinStakePoolCost                Lovelace `json:"minStakePoolCost"`
-	MaxExecutionUnitsPerTransaction ExUnits  `json:"maxExecutionUnitsPerTransaction"`
-	MaxExecutionUnitsPerBlock       ExUnits  `json:"maxExecutionUnitsPerBlock"`
-	CollateralPercentage            uint64   `json:"collateralPercentage"`
-	MaxCollateralInputs             uint64   `json:"maxCollateralInputs"`
-	Version                         Version  `json:"version"`
-}
-
-func ratio(s string) float32 {
-	n, d, ok := strings.Cut(s, "/")
-	if !ok {
-		return 0
-	}
-	num, err := strconv.Atoi(n)
-	if err != nil {
-		return 0
-	}
-	den, err := strconv.Atoi(d)
-	if err != nil {
-		return 0
-	}
-	return float32(num) / float32(den)
+	MinFeeConstant                  Lovelace                   `json:"minFeeConstant"`
+	MinFeeCoefficient               uint64                     `json:"minFeeCoefficient"`
+	MaxBlockSize                    Bytes                      `json:"maxBlockBodySize"`
+	MaxTxSize                       Bytes                      `json:"maxTransactionSize"`
+	MaxBlockHeaderSize              Bytes                      `json:"maxBlockHeaderSize"`
+	KeyDeposits                     Lovelace                   `json:"stakeCredentialDeposit"`
+	PoolDeposits                    Lovelace                   `json:"stakePoolDeposit"`
+	PoolInfluence                   string                     `json:"stakePoolPledgeInfluence"`
+	MonetaryExpansion               string                     `json:"monetaryExpansion"`
+	TreasuryExpansion               string                     `json:"treasuryExpansion"`
+	ExtraEntropy                    string                     `json:"extraEntropy"`
+	MaxValSize                      Bytes                      `json:"maxValueSize"`
+	ScriptExecutionPrices           Prices                     `json:"scriptExecutionPrices"`
+	MinUtxoDepositCoefficient       uint64                     `json:"minUtxoDepositCoefficient"`
+	MinUtxoDepositConstant          uint64                     `json:"minUtxoDepositConstant"`
+	MinStakePoolCost                Lovelace                   `json:"minStakePoolCost"`
+	MaxExecutionUnitsPerTransaction ExUnits                    `json:"maxExecutionUnitsPerTransaction"`
+	MaxExecutionUnitsPerBlock       ExUnits                    `json:"maxExecutionUnitsPerBlock"`
+	CollateralPercentage            uint64                     `json:"collateralPercentage"`
+	MaxCollateralInputs             uint64                     `json:"maxCollateralInputs"`
+	Version                         Version                    `json:"version"`
+	MinFeeReferenceScripts          MinFeeReferenceScripts     `json:"minFeeReferenceScripts"`
+	StakePoolRetirementEpochBound   uint64                     `json:"stakePoolRetirementEpochBound"`
+	DesiredNumberOfStakePools       uint64                     `json:"desiredNumberOfStakePools"`
+	PlutusCostModels                map[string]json.RawMessage `json:"plutusCostModels"`
+	StakePoolVotingThresholds       StakePoolVotingThresholds  `json:"stakePoolVotingThresholds"`
+	DRepVotingThresholds            DRepVotingThresholds       `json:"delegateRepresentativeVotingThresholds"`
+	CommitteeMinSize                uint64                     `json:"constitutionalCommitteeMinSize"`
+	CommitteeMaxTermLength          uint64                     `json:"constitutionalCommitteeMaxTermLength"`
+	GovActionLifetime               uint64                     `json:"governanceActionLifetime"`
+	GovActionDeposit                Lovelace                   `json:"governanceActionDeposit"`
+	DRepDeposit                     Lovelace                   `json:"delegateRepresentativeDeposit"`
+	DRepMaxIdleTime                 uint64                     `json:"delegateRepresentativeMaxIdleTime"`
+}
+
+func ratio(s string) Rational.Rational {
+	n, d, err := parseFraction(s)
+	if err != nil || n < 0 || d <= 0 {
+		return Rational.Rational{}
+	}
+	return Rational.New(uint64(n), uint64(d))
 }
 
 func (occ *OgmiosChainContext) LatestEpochParams() Base.ProtocolParameters {
@@ -436,37 +494,73 @@ func (occ *OgmiosChainContext) LatestEpochParams() Base.ProtocolParameters {
 		log.Fatal(err, "OgmiosChainContext: LatestEpochParams: failed to parse protocol parameters")
 	}
 
+	costModels, err := Base.ParseCostModels(ogmiosParams.PlutusCostModels)
+	if err != nil {
+		log.Fatal(err, "OgmiosChainContext: LatestEpochParams: failed to parse cost models")
+	}
+	pools := ogmiosParams.StakePoolVotingThresholds
+	dreps := ogmiosParams.DRepVotingThresholds
+
 	return Base.ProtocolParameters{
 		MinFeeConstant:     int(ogmiosParams.MinFeeConstant.Lovelace),
 		MinFeeCoefficient:  int(ogmiosParams.MinFeeCoefficient),
 		MaxBlockSize:       int(ogmiosParams.MaxBlockSize.Bytes),
 		MaxTxSize:          int(ogmiosParams.MaxTxSize.Bytes),
 		MaxBlockHeaderSize: int(ogmiosParams.MaxBlockHeaderSize.Bytes),
-		KeyDeposits:        strconv.FormatUint(ogmiosParams.KeyDeposits.Lovelace, 10),
-		PoolDeposits:       strconv.FormatUint(ogmiosParams.PoolDeposits.Lovelace, 10),
+		KeyDeposits:        int64(ogmiosParams.KeyDeposits.Lovelace),
+		PoolDeposits:       int64(ogmiosParams.PoolDeposits.Lovelace),
+		MaxEpoch:           int64(ogmiosParams.StakePoolRetirementEpochBound),
+		NOpt:               int64(ogmiosParams.DesiredNumberOfStakePools),
 		PooolInfluence:     ratio(ogmiosParams.PoolInfluence),
 		MonetaryExpansion:  ratio(ogmiosParams.MonetaryExpansion),
 		TreasuryExpansion:  ratio(ogmiosParams.TreasuryExpansion),
 		// Unsure if ogmios reports this, but it's 0 on mainnet and
 		// preview
-		DecentralizationParam: 0,
-		ExtraEntropy:          ogmiosParams.ExtraEntropy,
-		MinUtxo:               strconv.FormatUint(ogmiosParams.MinUtxoDepositConstant, 10),
-		ProtocolMajorVersion:  int(ogmiosParams.Version.Major),
-		ProtocolMinorVersion:  int(ogmiosParams.Version.Minor),
-		MinPoolCost:           strconv.FormatUint(ogmiosParams.MinStakePoolCost.Lovelace, 10),
-		PriceMem:              float32(ogmiosParams.ScriptExecutionPrices.Memory),
-		PriceStep:             float32(ogmiosParams.ScriptExecutionPrices.Cpu),
-		MaxTxExMem:            strconv.FormatUint(ogmiosParams.MaxExecutionUnitsPerTransaction.Memory, 10),
-		MaxTxExSteps:          strconv.FormatUint(ogmiosParams.MaxExecutionUnitsPerTransaction.Cpu, 10),
-		MaxBlockExMem:         strconv.FormatUint(ogmiosParams.MaxExecutionUnitsPerBlock.Memory, 10),
-		MaxBlockExSteps:       strconv.FormatUint(ogmiosParams.MaxExecutionUnitsPerBlock.Cpu, 10),
-		MaxValSize:            strconv.FormatUint(ogmiosParams.MaxValSize.Bytes, 10),
-		CollateralPercent:     int(ogmiosParams.CollateralPercentage),
-		MaxCollateralInuts:    int(ogmiosParams.MaxCollateralInputs),
-		//CoinsPerUtxoByte:      strconv.FormatUint(ogmiosParams.MinUtxoDepositCoefficient, 10),
+		DecentralizationParam:      Rational.Rational{},
+		ExtraEntropy:               ogmiosParams.ExtraEntropy,
+		MinUtxo:                    int64(ogmiosParams.MinUtxoDepositConstant),
+		ProtocolMajorVersion:       int(ogmiosParams.Version.Major),
+		ProtocolMinorVersion:       int(ogmiosParams.Version.Minor),
+		MinPoolCost:                int64(ogmiosParams.MinStakePoolCost.Lovelace),
+		PriceMem:                   ogmiosParams.ScriptExecutionPrices.Memory,
+		PriceStep:                  ogmiosParams.ScriptExecutionPrices.Cpu,
+		MaxTxExMem:                 int64(ogmiosParams.MaxExecutionUnitsPerTransaction.Memory),
+		MaxTxExSteps:               int64(ogmiosParams.MaxExecutionUnitsPerTransaction.Cpu),
+		MaxBlockExMem:              int64(ogmiosParams.MaxExecutionUnitsPerBlock.Memory),
+		MaxBlockExSteps:            int64(ogmiosParams.MaxExecutionUnitsPerBlock.Cpu),
+		MaxValSize:                 int(ogmiosParams.MaxValSize.Bytes),
+		CollateralPercent:          int(ogmiosParams.CollateralPercentage),
+		MaxCollateralInuts:         int(ogmiosParams.MaxCollateralInputs),
+		MinFeeRefScriptCostPerByte: ogmiosParams.MinFeeReferenceScripts.Base,
+		CoinsPerUtxoByte:           int64(ogmiosParams.MinUtxoDepositCoefficient),
 		// PerUtxoWord is deprecated https://cips.cardano.org/cips/cip55/
-		CoinsPerUtxoWord: strconv.FormatUint(ogmiosParams.MinUtxoDepositCoefficient, 10),
+		CoinsPerUtxoWord: int64(ogmiosParams.MinUtxoDepositCoefficient),
+		CostModels:       costModels,
+		PoolVotingThresholds: Base.PoolVotingThresholds{
+			MotionNoConfidence:    pools.NoConfidence,
+			CommitteeNormal:       pools.ConstitutionalCommittee.Default,
+			CommitteeNoConfidence: pools.ConstitutionalCommittee.StateOfNoConfidence,
+			HardForkInitiation:    pools.HardForkInitiation,
+			PPSecurityGroup:       pools.ProtocolParametersUpdate.Security,
+		},
+		DRepVotingThresholds: Base.DRepVotingThresholds{
+			MotionNoConfidence:    dreps.NoConfidence,
+			CommitteeNormal:       dreps.ConstitutionalCommittee.Default,
+			CommitteeNoConfidence: dreps.ConstitutionalCommittee.StateOfNoConfidence,
+			UpdateToConstitution:  dreps.Constitution,
+			HardForkInitiation:    dreps.HardForkInitiation,
+			PPNetworkGroup:        dreps.ProtocolParametersUpdate.Network,
+			PPEconomicGroup:       dreps.ProtocolParametersUpdate.Economic,
+			PPTechnicalGroup:      dreps.ProtocolParametersUpdate.Technical,
+			PPGovGroup:            dreps.ProtocolParametersUpdate.Governance,
+			TreasuryWithdrawal:    dreps.TreasuryWithdrawals,
+		},
+		CommitteeMinSize:       int64(ogmiosParams.CommitteeMinSize),
+		CommitteeMaxTermLength: int64(ogmiosParams.CommitteeMaxTermLength),
+		GovActionLifetime:      int64(ogmiosParams.GovActionLifetime),
+		GovActionDeposit:       int64(ogmiosParams.GovActionDeposit.Lovelace),
+		DRepDeposit:            int64(ogmiosParams.DRepDeposit.Lovelace),
+		DRepActivity:           int64(ogmiosParams.DRepMaxIdleTime),
 	}
 }
 
@@ -520,9 +614,24 @@ func (occ *OgmiosChainContext) GetProtocolParams() Base.ProtocolParameters {
 
 func (occ *OgmiosChainContext) MaxTxFee() int {
 	protocol_param := occ.GetProtocolParams()
-	maxTxExSteps, _ := strconv.Atoi(protocol_param.MaxTxExSteps)
-	maxTxExMem, _ := strconv.Atoi(protocol_param.MaxTxExMem)
-	return Base.Fee(occ, protocol_param.MaxTxSize, maxTxExSteps, maxTxExMem)
+	return Base.Fee(occ, protocol_param.MaxTxSize, int(protocol_param.MaxTxExSteps), int(protocol_param.MaxTxExMem))
+}
+
+/*
+*
+
+	FetchUtxos returns the UTxOs of an address. The requests to
+	Kupo exit on failure, see AddressUtxos, so no error is returned.
+
+	Params:
+		address (Address.Address): The address.
+
+	Returns:
+		[]UTxO.UTxO: The UTxOs.
+		error: Always nil.
+*/
+func (occ *OgmiosChainContext) FetchUtxos(address Address.Address) ([]UTxO.UTxO, error) {
+	return occ.Utxos(address), nil
 }
 
 // Copied from blockfrost context def since it just calls AddressUtxos and then
@@ -612,11 +721,32 @@ func (occ *OgmiosChainContext) SubmitTx(tx Transaction.Transaction) (serializati
 }
 
 func (occ *OgmiosChainContext) EvaluateTx(tx []byte) map[string]Redeemer.ExecutionUnits {
+	final_result, err := occ.FetchEvaluation(tx)
+	if err != nil {
+		log.Fatal(err, "OgmiosChainContext: EvaluateTx: Error evaluating tx")
+	}
+	return final_result
+}
+
+/*
+*
+
+	FetchEvaluation evaluates the scripts of a transaction, as
+	EvaluateTx but returning the error instead of exiting.
+
+	Params:
+		tx ([]byte): The CBOR of the transaction.
+
+	Returns:
+		map[string]Redeemer.ExecutionUnits: The execution units by redeemer.
+		error: The error of the evaluation.
+*/
+func (occ *OgmiosChainContext) FetchEvaluation(tx []byte) (map[string]Redeemer.ExecutionUnits, error) {
 	final_result := make(map[string]Redeemer.ExecutionUnits)
 	ctx := context.Background()
 	eval, err := occ.ogmigo.EvaluateTx(ctx, hex.EncodeToString(tx))
 	if err != nil {
-		log.Fatal(err, "OgmiosChainContext: EvaluateTx: Error evaluating tx")
+		return nil, fmt.Errorf("OgmiosChainContext: FetchEvaluation: %w", err)
 	}
 	for _, e := range eval {
 		final_result[e.Validator] = Redeemer.ExecutionUnits{
@@ -624,7 +754,31 @@ func (occ *OgmiosChainContext) EvaluateTx(tx []byte) map[string]Redeemer.Executi
 			Steps: int64(e.Budget.Cpu),
 		}
 	}
-	return final_result
+	return final_result, nil
+}
+
+/*
+*
+
+	ResolveDatum fetches a datum by its hash from Kupo.
+
+	Params:
+		datumHash (string): The hex encoded hash of the datum.
+
+	Returns:
+		*PlutusData.PlutusData: The datum.
+		error: The error of the request, matching Base.ErrDatumNotFound
+			if Kupo does not know the datum.
+*/
+func (occ *OgmiosChainContext) ResolveDatum(datumHash string) (*PlutusData.PlutusData, error) {
+	datum, err := occ.kugo.Datum(context.Background(), datumHash)
+	if err != nil {
+		return nil, fmt.Errorf("OgmiosChainContext: ResolveDatum: kupo datum request failed: %w", err)
+	}
+	if datum == "" {
+		return nil, fmt.Errorf("%w: %s", Base.ErrDatumNotFound, datumHash)
+	}
+	return Base.DecodeDatum(datum)
 }
 
 // This is unused
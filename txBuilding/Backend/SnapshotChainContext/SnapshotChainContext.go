@@ -0,0 +1,541 @@
+package SnapshotChainContext
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Salvionied/apollo/serialization"
+	"github.com/Salvionied/apollo/serialization/Address"
+	"github.com/Salvionied/apollo/serialization/PlutusData"
+	"github.com/Salvionied/apollo/serialization/Redeemer"
+	"github.com/Salvionied/apollo/serialization/Transaction"
+	"github.com/Salvionied/apollo/serialization/TransactionInput"
+	"github.com/Salvionied/apollo/serialization/TransactionOutput"
+	"github.com/Salvionied/apollo/serialization/UTxO"
+	"github.com/Salvionied/apollo/txBuilding/Backend/Base"
+	"github.com/Salvionied/cbor/v2"
+)
+
+const SNAPSHOT_VERSION = 1
+
+/*
+*
+
+	SnapshotUtxo is a UTxO as stored in a snapshot, with its
+	output kept as CBOR so that it is restored byte for byte.
+*/
+type SnapshotUtxo struct {
+	Address string `json:"address"`
+	TxHash  string `json:"tx_hash"`
+	Index   int    `json:"index"`
+	Output  string `json:"output_cbor"`
+}
+
+/*
+*
+
+	Snapshot is the state of a chain context needed to build
+	transactions offline.
+*/
+type Snapshot struct {
+	Version        int                     `json:"version"`
+	CreatedAt      time.Time               `json:"created_at"`
+	Network        int                     `json:"network"`
+	Epoch          int                     `json:"epoch"`
+	LastBlockSlot  int                     `json:"last_block_slot"`
+	MaxTxFee       int                     `json:"max_tx_fee"`
+	ProtocolParams Base.ProtocolParameters `json:"protocol_params"`
+	GenesisParams  Base.GenesisParameters  `json:"genesis_params"`
+	Utxos          []SnapshotUtxo          `json:"utxos"`
+	References     []SnapshotUtxo          `json:"references"`
+	Scripts        map[string]string       `json:"scripts"`
+	Datums         map[string]string       `json:"datums"`
+}
+
+/*
+*
+
+	ExportOptions selects the state to include in a snapshot.
+*/
+type ExportOptions struct {
+	// Addresses whose UTxOs are exported.
+	Addresses []Address.Address
+	// References are the reference inputs (or any other UTxO)
+	// the transactions will use.
+	References []TransactionInput.TransactionInput
+	// ScriptHashes are the scripts served by GetContractCbor.
+	ScriptHashes []string
+	// Datums are the datums the transactions will need.
+	Datums []PlutusData.PlutusData
+}
+
+func toSnapshotUtxo(utxo UTxO.UTxO) (SnapshotUtxo, error) {
+	encoded, err := cbor.Marshal(&utxo.Output)
+	if err != nil {
+		return SnapshotUtxo{}, err
+	}
+	address := utxo.Output.GetAddress()
+	return SnapshotUtxo{
+		Address: address.String(),
+		TxHash:  hex.EncodeToString(utxo.Input.TransactionId),
+		Index:   utxo.Input.Index,
+		Output:  hex.EncodeToString(encoded),
+	}, nil
+}
+
+/*
+*
+
+	ToUTxO decodes the stored UTxO.
+
+	Returns:
+		UTxO.UTxO: The decoded UTxO.
+		error: An error if the stored UTxO is invalid.
+*/
+func (su SnapshotUtxo) ToUTxO() (UTxO.UTxO, error) {
+	txHash, err := hex.DecodeString(su.TxHash)
+	if err != nil {
+		return UTxO.UTxO{}, err
+	}
+	encoded, err := hex.DecodeString(su.Output)
+	if err != nil {
+		return UTxO.UTxO{}, err
+	}
+	output := TransactionOutput.TransactionOutput{}
+	err = cbor.Unmarshal(encoded, &output)
+	if err != nil {
+		return UTxO.UTxO{}, err
+	}
+	return UTxO.UTxO{
+		Input:  TransactionInput.TransactionInput{TransactionId: txHash, Index: su.Index},
+		Output: output,
+	}, nil
+}
+
+/*
+*
+
+	Export reads from a chain context everything needed to
+	reproduce a transaction build offline.
+
+	Params:
+		cc (Base.ChainContext): The online chain context.
+		options (ExportOptions): The addresses, references, scripts and datums to include.
+
+	Returns:
+		Snapshot: The snapshot of the chain context.
+		error: An error if a reference cannot be resolved or a value cannot be encoded.
+*/
+func Export(cc Base.ChainContext, options ExportOptions) (Snapshot, error) {
+	snapshot := Snapshot{
+		Version:        SNAPSHOT_VERSION,
+		CreatedAt:      time.Now().UTC(),
+		Network:        cc.Network(),
+		Epoch:          cc.Epoch(),
+		LastBlockSlot:  cc.LastBlockSlot(),
+		MaxTxFee:       cc.MaxTxFee(),
+		ProtocolParams: cc.GetProtocolParams(),
+		GenesisParams:  cc.GetGenesisParams(),
+		Utxos:          make([]SnapshotUtxo, 0),
+		References:     make([]SnapshotUtxo, 0),
+		Scripts:        make(map[string]string),
+		Datums:         make(map[string]string),
+	}
+	for _, address := range options.Addresses {
+		utxos, err := Base.FetchUtxos(cc, address)
+		if err != nil {
+			return Snapshot{}, fmt.Errorf("Export: fetching the UTxOs of %s: %w", address.String(), err)
+		}
+		for _, utxo := range utxos {
+			su, err := toSnapshotUtxo(utxo)
+			if err != nil {
+				return Snapshot{}, fmt.Errorf("Export: %v", err)
+			}
+			snapshot.Utxos = append(snapshot.Utxos, su)
+		}
+	}
+	for _, input := range options.References {
+		utxo := cc.GetUtxoFromRef(hex.EncodeToString(input.TransactionId), input.Index)
+		if utxo == nil {
+			return Snapshot{}, fmt.Errorf("Export: reference %s not found", input.String())
+		}
+		su, err := toSnapshotUtxo(*utxo)
+		if err != nil {
+			return Snapshot{}, fmt.Errorf("Export: %v", err)
+		}
+		snapshot.References = append(snapshot.References, su)
+	}
+	for _, scriptHash := range options.ScriptHashes {
+		snapshot.Scripts[scriptHash] = cc.GetContractCbor(scriptHash)
+	}
+	for _, datum := range options.Datums {
+		datum := datum
+		hash, err := PlutusData.PlutusDataHash(&datum)
+		if err != nil {
+			return Snapshot{}, fmt.Errorf("Export: %v", err)
+		}
+		encoded, err := cbor.Marshal(&datum)
+		if err != nil {
+			return Snapshot{}, fmt.Errorf("Export: %v", err)
+		}
+		snapshot.Datums[hex.EncodeToString(hash.Payload)] = hex.EncodeToString(encoded)
+	}
+	return snapshot, nil
+}
+
+/*
+*
+
+	Save writes the snapshot to a JSON file.
+
+	Params:
+		path (string): The path of the file.
+
+	Returns:
+		error: An error if the file cannot be written.
+*/
+func (s Snapshot) Save(path string) error {
+	encoded, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, encoded, 0600)
+}
+
+/*
+*
+
+	LoadSnapshot reads a snapshot written by Save.
+
+	Params:
+		path (string): The path of the file.
+
+	Returns:
+		Snapshot: The snapshot.
+		error: An error if the file cannot be read or has an unsupported version.
+*/
+func LoadSnapshot(path string) (Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	snapshot := Snapshot{}
+	err = json.Unmarshal(data, &snapshot)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("LoadSnapshot: %v", err)
+	}
+	if snapshot.Version != SNAPSHOT_VERSION {
+		return Snapshot{}, fmt.Errorf("LoadSnapshot: unsupported snapshot version %d", snapshot.Version)
+	}
+	return snapshot, nil
+}
+
+/*
+*
+
+	SnapshotChainContext is a chain context served from a
+	snapshot, for building and signing transactions on an
+	air-gapped machine. Submitted transactions are written
+	to OutputDir and their effects applied to the UTxO set,
+	so that chained transactions can be built too.
+
+	There is no script evaluator offline: execution units
+	must be set on the redeemers.
+*/
+type SnapshotChainContext struct {
+	snapshot   Snapshot
+	utxos      []UTxO.UTxO
+	references map[string]UTxO.UTxO
+	OutputDir  string
+}
+
+/*
+*
+
+	NewSnapshotChainContext creates a chain context from a snapshot.
+
+	Params:
+		snapshot (Snapshot): The snapshot to serve.
+		outputDir (string): The directory where submitted transactions are written.
+
+	Returns:
+		SnapshotChainContext: The chain context.
+		error: An error if a UTxO of the snapshot is invalid.
+*/
+func NewSnapshotChainContext(snapshot Snapshot, outputDir string) (SnapshotChainContext, error) {
+	scc := SnapshotChainContext{
+		snapshot:   snapshot,
+		utxos:      make([]UTxO.UTxO, 0, len(snapshot.Utxos)),
+		references: make(map[string]UTxO.UTxO),
+		OutputDir:  outputDir,
+	}
+	for _, su := range snapshot.Utxos {
+		utxo, err := su.ToUTxO()
+		if err != nil {
+			return SnapshotChainContext{}, fmt.Errorf("NewSnapshotChainContext: %v", err)
+		}
+		scc.utxos = append(scc.utxos, utxo)
+		scc.references[utxo.GetKey()] = utxo
+	}
+	for _, su := range snapshot.References {
+		utxo, err := su.ToUTxO()
+		if err != nil {
+			return SnapshotChainContext{}, fmt.Errorf("NewSnapshotChainContext: %v", err)
+		}
+		scc.references[utxo.GetKey()] = utxo
+	}
+	return scc, nil
+}
+
+/*
+*
+
+	LoadSnapshotChainContext loads a snapshot file and creates
+	a chain context from it.
+
+	Params:
+		path (string): The path of the snapshot.
+		outputDir (string): The directory where submitted transactions are written.
+
+	Returns:
+		SnapshotChainContext: The chain context.
+		error: An error if the snapshot cannot be loaded.
+*/
+func LoadSnapshotChainContext(path string, outputDir string) (SnapshotChainContext, error) {
+	snapshot, err := LoadSnapshot(path)
+	if err != nil {
+		return SnapshotChainContext{}, err
+	}
+	return NewSnapshotChainContext(snapshot, outputDir)
+}
+
+func (scc *SnapshotChainContext) GetProtocolParams() Base.ProtocolParameters {
+	return scc.snapshot.ProtocolParams
+}
+
+func (scc *SnapshotChainContext) GetGenesisParams() Base.GenesisParameters {
+	return scc.snapshot.GenesisParams
+}
+
+func (scc *SnapshotChainContext) Network() int {
+	return scc.snapshot.Network
+}
+
+// LoadsWalletUtxos implements Base.WalletUtxoLoader.
+func (scc *SnapshotChainContext) LoadsWalletUtxos() bool {
+	return true
+}
+
+func (scc *SnapshotChainContext) Epoch() int {
+	return scc.snapshot.Epoch
+}
+
+func (scc *SnapshotChainContext) MaxTxFee() int {
+	return scc.snapshot.MaxTxFee
+}
+
+func (scc *SnapshotChainContext) LastBlockSlot() int {
+	return scc.snapshot.LastBlockSlot
+}
+
+func (scc *SnapshotChainContext) Utxos(address Address.Address) []UTxO.UTxO {
+	utxos := make([]UTxO.UTxO, 0)
+	for _, utxo := range scc.utxos {
+		utxoAddress := utxo.Output.GetAddress()
+		if utxoAddress.String() == address.String() {
+			utxos = append(utxos, utxo.Clone())
+		}
+	}
+	return utxos
+}
+
+// FetchUtxos returns the recorded UTxOs of an address, it never fails.
+func (scc *SnapshotChainContext) FetchUtxos(address Address.Address) ([]UTxO.UTxO, error) {
+	return scc.Utxos(address), nil
+}
+
+func (scc *SnapshotChainContext) GetUtxoFromRef(txHash string, txIndex int) *UTxO.UTxO {
+	decoded, _ := hex.DecodeString(txHash)
+	key := UTxO.UTxO{Input: TransactionInput.TransactionInput{TransactionId: decoded, Index: txIndex}}.GetKey()
+	utxo, ok := scc.references[key]
+	if !ok {
+		return nil
+	}
+	res := utxo.Clone()
+	return &res
+}
+
+func (scc *SnapshotChainContext) GetContractCbor(scriptHash string) string {
+	return scc.snapshot.Scripts[scriptHash]
+}
+
+/*
+*
+
+	GetDatum returns a datum stored in the snapshot.
+
+	Params:
+		datumHash (string): The hex encoded hash of the datum.
+
+	Returns:
+		*PlutusData.PlutusData: The datum or nil if it is not in the snapshot.
+*/
+func (scc *SnapshotChainContext) GetDatum(datumHash string) *PlutusData.PlutusData {
+	encoded, ok := scc.snapshot.Datums[datumHash]
+	if !ok {
+		return nil
+	}
+	decoded, err := hex.DecodeString(encoded)
+	if err != nil {
+		return nil
+	}
+	datum := PlutusData.PlutusData{}
+	err = cbor.Unmarshal(decoded, &datum)
+	if err != nil {
+		return nil
+	}
+	return &datum
+}
+
+/*
+*
+
+	ResolveDatum returns a datum stored in the snapshot.
+
+	Params:
+		datumHash (string): The hex encoded hash of the datum.
+
+	Returns:
+		*PlutusData.PlutusData: The datum.
+		error: An error matching Base.ErrDatumNotFound if it is not in the snapshot.
+*/
+func (scc *SnapshotChainContext) ResolveDatum(datumHash string) (*PlutusData.PlutusData, error) {
+	datum := scc.GetDatum(datumHash)
+	if datum == nil {
+		return nil, fmt.Errorf("%w: %s is not in the snapshot", Base.ErrDatumNotFound, datumHash)
+	}
+	return datum, nil
+}
+
+/*
+*
+
+	GetScript returns a script stored in the snapshot. The
+	snapshot only keeps the cbor of the scripts, their language
+	is the one whose hash matches.
+
+	Params:
+		scriptHash (string): The hex encoded hash of the script.
+
+	Returns:
+		*PlutusData.ScriptRef: The script.
+		error: An error matching Base.ErrScriptNotFound if it is not in the snapshot.
+*/
+func (scc *SnapshotChainContext) GetScript(scriptHash string) (*PlutusData.ScriptRef, error) {
+	encoded, ok := scc.snapshot.Scripts[scriptHash]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is not in the snapshot", Base.ErrScriptNotFound, scriptHash)
+	}
+	decoded, err := hex.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid script hex: %w", err)
+	}
+	candidates := [][]byte{decoded}
+	var unwrapped []byte
+	if cbor.Unmarshal(decoded, &unwrapped) == nil {
+		candidates = append(candidates, unwrapped)
+	}
+	for _, candidate := range candidates {
+		for scriptType := uint64(0); scriptType <= 3; scriptType++ {
+			scriptRef := PlutusData.NewScriptRef(scriptType, candidate)
+			hash, err := scriptRef.Hash()
+			if err == nil && hex.EncodeToString(hash[:]) == scriptHash {
+				return &scriptRef, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("the script stored for %s does not match its hash", scriptHash)
+}
+
+/*
+*
+
+	EvaluateTx cannot run scripts offline and returns no
+	execution units.
+*/
+func (scc *SnapshotChainContext) EvaluateTx(tx []uint8) map[string]Redeemer.ExecutionUnits {
+	return map[string]Redeemer.ExecutionUnits{}
+}
+
+type textEnvelope struct {
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	CborHex     string `json:"cborHex"`
+}
+
+/*
+*
+
+	SubmitTx writes the transaction to OutputDir as a
+	cardano-cli text envelope named after its id, ready to be
+	submitted from an online machine, and updates the UTxO set.
+
+	Params:
+		tx (Transaction.Transaction): The signed transaction.
+
+	Returns:
+		serialization.TransactionId: The id of the transaction.
+		error: An error if the transaction cannot be written.
+*/
+func (scc *SnapshotChainContext) SubmitTx(tx Transaction.Transaction) (serialization.TransactionId, error) {
+	if scc.OutputDir == "" {
+		return serialization.TransactionId{}, errors.New("SubmitTx: no output directory")
+	}
+	encoded, err := tx.Bytes()
+	if err != nil {
+		return serialization.TransactionId{}, err
+	}
+	txId := tx.Id()
+	envelope, err := json.MarshalIndent(textEnvelope{
+		Type:        "Tx ConwayEra",
+		Description: "Ledger Cddl Format",
+		CborHex:     hex.EncodeToString(encoded),
+	}, "", "    ")
+	if err != nil {
+		return serialization.TransactionId{}, err
+	}
+	path := filepath.Join(scc.OutputDir, hex.EncodeToString(txId.Payload)+".signed")
+	err = os.WriteFile(path, envelope, 0600)
+	if err != nil {
+		return serialization.TransactionId{}, err
+	}
+	scc.apply(tx, txId)
+	return txId, nil
+}
+
+func (scc *SnapshotChainContext) apply(tx Transaction.Transaction, txId serialization.TransactionId) {
+	spent := make(map[string]bool)
+	for _, input := range tx.TransactionBody.Inputs {
+		spent[UTxO.UTxO{Input: input}.GetKey()] = true
+	}
+	utxos := make([]UTxO.UTxO, 0, len(scc.utxos))
+	for _, utxo := range scc.utxos {
+		if !spent[utxo.GetKey()] {
+			utxos = append(utxos, utxo)
+		}
+	}
+	for idx, output := range tx.TransactionBody.Outputs {
+		utxo := UTxO.UTxO{
+			Input:  TransactionInput.TransactionInput{TransactionId: txId.Payload, Index: idx},
+			Output: output,
+		}
+		utxos = append(utxos, utxo)
+		scc.references[utxo.GetKey()] = utxo
+	}
+	scc.utxos = utxos
+}
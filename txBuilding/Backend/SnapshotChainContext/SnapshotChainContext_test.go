@@ -0,0 +1,100 @@
+package SnapshotChainContext_test
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Salvionied/apollo"
+	"github.com/Salvionied/apollo/serialization/Address"
+	"github.com/Salvionied/apollo/serialization/PlutusData"
+	"github.com/Salvionied/apollo/txBuilding/Backend/Base"
+	"github.com/Salvionied/apollo/txBuilding/Backend/FixedChainContext"
+	"github.com/Salvionied/apollo/txBuilding/Backend/SnapshotChainContext"
+)
+
+const userAddress = "addr1qymaeeefs9ff08cdplm3lvkscavm9x9vd7nmc44e9rlur08k3pj2xw9w3mvp7cg3fkzhed4zzhywdpd2t3pmc8u8nn8qm5ur5w"
+const receiverAddress = "addr1qxajla3qcrwckzkur8n0lt02rg2sepw3kgkstckmzrz4ccfm3j9pqrqkea3tns46e3qy2w42vl8dvvue8u45amzm3rjqvv2nxh"
+
+func buildTx(t *testing.T, cc Base.ChainContext) []byte {
+	address, _ := Address.DecodeAddress(userAddress)
+	built, err := apollo.New(cc).
+		AddLoadedUTxOs(cc.Utxos(address)...).
+		AddInputAddressFromBech32(userAddress).
+		PayToAddressBech32(receiverAddress, 2_000_000).
+		SetTtl(1000).
+		Complete()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := built.GetTx().Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return encoded
+}
+
+func TestSnapshotReplay(t *testing.T) {
+	cc := FixedChainContext.InitFixedChainContext()
+	address, _ := Address.DecodeAddress(userAddress)
+	datum := PlutusData.PlutusData{TagNr: 121, PlutusDataType: PlutusData.PlutusArray, Value: PlutusData.PlutusIndefArray{}}
+	snapshot, err := SnapshotChainContext.Export(cc, SnapshotChainContext.ExportOptions{
+		Addresses: []Address.Address{address},
+		Datums:    []PlutusData.PlutusData{datum},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "snapshot.json")
+	err = snapshot.Save(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scc, err := SnapshotChainContext.LoadSnapshotChainContext(path, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scc.LastBlockSlot() != cc.LastBlockSlot() || scc.Network() != cc.Network() ||
+		scc.GetProtocolParams().MinFeeConstant != cc.GetProtocolParams().MinFeeConstant {
+		t.Error("Invalid chain state")
+	}
+	if len(scc.Utxos(address)) != 2 {
+		t.Error("Invalid utxos", scc.Utxos(address))
+	}
+	datumHash, _ := PlutusData.PlutusDataHash(&datum)
+	if scc.GetDatum(hex.EncodeToString(datumHash.Payload)) == nil {
+		t.Error("Datum not found")
+	}
+
+	online := buildTx(t, cc)
+	offline := buildTx(t, &scc)
+	if hex.EncodeToString(online) != hex.EncodeToString(offline) {
+		t.Error("Offline build differs", hex.EncodeToString(online), hex.EncodeToString(offline))
+	}
+
+	built, _ := apollo.New(&scc).
+		AddLoadedUTxOs(scc.Utxos(address)...).
+		AddInputAddressFromBech32(userAddress).
+		PayToAddressBech32(receiverAddress, 2_000_000).
+		SetTtl(1000).
+		Complete()
+	txId, err := built.Submit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, hex.EncodeToString(txId.Payload)+".signed"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	envelope := map[string]string{}
+	_ = json.Unmarshal(data, &envelope)
+	if envelope["cborHex"] != hex.EncodeToString(offline) || envelope["type"] != "Tx ConwayEra" {
+		t.Error("Invalid envelope", string(data))
+	}
+	if scc.GetUtxoFromRef(hex.EncodeToString(txId.Payload), 0) == nil {
+		t.Error("Submitted outputs must be spendable")
+	}
+}
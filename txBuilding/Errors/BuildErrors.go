@@ -0,0 +1,174 @@
+package Errors
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Salvionied/apollo/serialization/UTxO"
+)
+
+/*
+*
+
+	Kind tells which part of a transaction could not be built.
+*/
+type Kind string
+
+const (
+	// INPUTS: the UTxOs cannot cover the outputs, burns, fee
+	// and the minimum lovelace of the change.
+	INPUTS Kind = "inputs"
+	// COLLATERAL: no collateral covering the fee can be found.
+	COLLATERAL Kind = "collateral"
+	// OUTPUT_SIZE: the value of an output exceeds maxValSize.
+	OUTPUT_SIZE Kind = "output_size"
+	// TX_SIZE: the transaction exceeds maxTxSize.
+	TX_SIZE Kind = "tx_size"
+)
+
+var (
+	ErrInsufficientInputs = errors.New("insufficient inputs")
+	ErrNoCollateral       = errors.New("no collateral")
+	ErrOutputTooLarge     = errors.New("output too large")
+	ErrTxTooLarge         = errors.New("transaction too large")
+)
+
+/*
+*
+
+	Reasons for which a UTxO was not used.
+*/
+const (
+	// EXCLUDED_USED: already spent by this builder or a
+	// previous transaction built with it.
+	EXCLUDED_USED = "used"
+	// EXCLUDED_SCRIPT_LOCKED: locked by a script, so it cannot
+	// be used as collateral.
+	EXCLUDED_SCRIPT_LOCKED = "script_locked"
+	// EXCLUDED_COLLATERAL_LIMIT: beyond the maxCollateralInputs
+	// best collateral candidates.
+	EXCLUDED_COLLATERAL_LIMIT = "max_collateral_inputs"
+	// EXCLUDED_BELOW_MIN_ADA: holds less lovelace than the
+	// minimum its assets add to the collateral return, so it
+	// cannot be used as collateral.
+	EXCLUDED_BELOW_MIN_ADA = "below_min_ada"
+)
+
+/*
+*
+
+	Shortfall is the amount of a unit missing to build a
+	transaction.
+*/
+type Shortfall struct {
+	// PolicyId and AssetName, hex encoded, are empty for lovelace.
+	PolicyId  string `json:"policy_id,omitempty"`
+	AssetName string `json:"asset_name,omitempty"`
+	Required  int64  `json:"required"`
+	Available int64  `json:"available"`
+}
+
+/*
+*
+
+	Unit returns the unit of the shortfall.
+
+	Returns:
+		string: lovelace or the policy id followed by the hex asset name.
+*/
+func (s Shortfall) Unit() string {
+	if s.PolicyId == "" {
+		return "lovelace"
+	}
+	return s.PolicyId + s.AssetName
+}
+
+/*
+*
+
+	Missing returns the amount to add.
+
+	Returns:
+		int64: The required amount minus the available one.
+*/
+func (s Shortfall) Missing() int64 {
+	return s.Required - s.Available
+}
+
+/*
+*
+
+	ExcludedUTxO is a UTxO which was not used, and why.
+*/
+type ExcludedUTxO struct {
+	UTxO   UTxO.UTxO `json:"utxo"`
+	Reason string    `json:"reason"`
+}
+
+/*
+*
+
+	BuildError explains why a transaction could not be built,
+	with what is missing, the UTxOs that were considered and
+	the ones left out. errors.Is matches it with the sentinel
+	error of its Kind.
+*/
+type BuildError struct {
+	Kind Kind   `json:"kind"`
+	Msg  string `json:"message"`
+	// Shortfall lists the missing amount of each unit.
+	Shortfall []Shortfall `json:"shortfall,omitempty"`
+	// Considered are the UTxOs the selection could use.
+	Considered []UTxO.UTxO `json:"considered,omitempty"`
+	// Excluded are the loaded UTxOs it could not use.
+	Excluded []ExcludedUTxO `json:"excluded,omitempty"`
+	// EstimatedFee is the fee estimate when the build failed.
+	EstimatedFee int64 `json:"estimated_fee"`
+	// Output is the index of the output too large, -1 otherwise.
+	Output int `json:"output"`
+	// Err is the underlying error, if any.
+	Err error `json:"-"`
+}
+
+/*
+*
+
+	Error returns the message of the error followed by the
+	shortfall of each unit.
+
+	Returns:
+		string: The error message.
+*/
+func (e *BuildError) Error() string {
+	msg := e.Msg
+	if len(e.Shortfall) > 0 {
+		missing := make([]string, 0, len(e.Shortfall))
+		for _, shortfall := range e.Shortfall {
+			missing = append(missing, fmt.Sprintf("%d %s", shortfall.Missing(), shortfall.Unit()))
+		}
+		msg = fmt.Sprintf("%s, missing %s", msg, strings.Join(missing, ", "))
+	}
+	if e.Err != nil {
+		msg = fmt.Sprintf("%s, %v", msg, e.Err)
+	}
+	return msg
+}
+
+func (e *BuildError) Unwrap() error {
+	return e.Err
+}
+
+func (e *BuildError) Is(target error) bool {
+	switch target {
+	case ErrInsufficientInputs:
+		return e.Kind == INPUTS
+	case ErrNoCollateral:
+		return e.Kind == COLLATERAL
+	case ErrOutputTooLarge:
+		return e.Kind == OUTPUT_SIZE
+	case ErrTxTooLarge:
+		return e.Kind == TX_SIZE
+	}
+	return false
+}
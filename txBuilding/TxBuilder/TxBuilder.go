@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"reflect"
 	"sort"
-	"strconv"
 
 	"github.com/Salvionied/apollo/serialization"
 	"github.com/Salvionied/apollo/serialization/Address"
@@ -220,7 +219,7 @@ func (tb *TransactionBuilder) _AddingAssetMakeOutputOverflow(
 	policyId Policy.PolicyId,
 	assetName AssetName.AssetName,
 	amount int64,
-	maxValSize string) bool {
+	maxValSize int) bool {
 	attemptAssets := tempAssets.Clone()
 	attemptAssets.Add(Asset.Asset[int64]{assetName: amount})
 	attemptMultiAsset := MultiAsset.MultiAsset[int64]{policyId: attemptAssets}
@@ -234,11 +233,10 @@ func (tb *TransactionBuilder) _AddingAssetMakeOutputOverflow(
 
 	attemptAmount.SetLovelace(requiredLovelace)
 	bytes, _ := cbor.Marshal(attemptAmount)
-	maxValSz, _ := strconv.Atoi(maxValSize)
-	return len(bytes) > maxValSz
+	return len(bytes) > maxValSize
 }
 
-func (tb *TransactionBuilder) _pack_multiassets_for_change(ChangeAddress Address.Address, ChangeEstimator Value.Value, maxValSize string) []MultiAsset.MultiAsset[int64] {
+func (tb *TransactionBuilder) _pack_multiassets_for_change(ChangeAddress Address.Address, ChangeEstimator Value.Value, maxValSize int) []MultiAsset.MultiAsset[int64] {
 	multiAssetArray := make([]MultiAsset.MultiAsset[int64], 0)
 	base_coin := Value.PureLovelaceValue(ChangeEstimator.GetCoin())
 	output := TransactionOutput.SimpleTransactionOutput(ChangeAddress, base_coin)
@@ -275,8 +273,7 @@ func (tb *TransactionBuilder) _pack_multiassets_for_change(ChangeAddress Address
 		required_lovelace := Utils.MinLovelacePostAlonzo(TransactionOutput.SimpleTransactionOutput(ChangeAddress, updatedAmount), tb.Context)
 		updatedAmount.SetLovelace(required_lovelace)
 		cbor, _ := cbor.Marshal(updatedAmount)
-		maxValSz, _ := strconv.Atoi(maxValSize)
-		if len(cbor) > maxValSz {
+		if len(cbor) > maxValSize {
 			output.SetAmount(oldAmount)
 			break
 		}
@@ -729,7 +726,11 @@ func (tb *TransactionBuilder) Build(changeAddress *Address.Address, mergeChange
 		additionalAmount := Value.Value{}
 		if tb.LoadedUtxos == nil {
 			for _, address := range tb.InputAddresses {
-				for _, utxo := range tb.Context.Utxos(address) {
+				utxos, err := Base.FetchUtxos(tb.Context, address)
+				if err != nil {
+					return TransactionBody.TransactionBody{}, err
+				}
+				for _, utxo := range utxos {
 					if !Utils.Contains(selectedUtxos, utxo) &&
 						!Utils.Contains(tb.ExcludedInputs, utxo) &&
 						len(utxo.Output.GetDatumHash().Payload) == 0 {